@@ -0,0 +1,275 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"os"
+
+	"github.com/yeying-community/webdav/internal/domain/user"
+	"github.com/yeying-community/webdav/internal/infrastructure/storage"
+	"go.uber.org/zap"
+	"golang.org/x/net/webdav"
+)
+
+// errQuotaExceeded 超出存储配额
+var errQuotaExceeded = errors.New("storage quota exceeded")
+
+// quotaRequest 单次写请求的配额上下文
+//
+// 用量按请求写入或删除的字节数增量更新，只统计受影响的子树，不遍历整个文件系统。
+type quotaRequest struct {
+	key        string
+	fileSystem webdav.FileSystem
+	method     string
+	target     string       // PUT、DELETE 的路径，COPY、MOVE 的目标路径（文件系统内）
+	replaced   int64        // 请求前 target 的大小，即被覆盖或删除的字节数
+	copied     int64        // COPY 源的大小
+	remaining  int64        // 剩余可用字节
+	body       *quotaReader // PUT 的请求体
+}
+
+// quotaLimit 计算用户生效的配额，0 表示不限
+func (s *WebDAVService) quotaLimit(u *user.User) int64 {
+	return u.QuotaLimit(s.defaultQuota)
+}
+
+// quotaFileSystem 包装文件系统，在 PROPFIND 中提供配额属性
+func (s *WebDAVService) quotaFileSystem(u *user.User, fileSystem webdav.FileSystem) webdav.FileSystem {
+	return storage.NewQuotaFileSystem(fileSystem, func(ctx context.Context) (int64, int64, error) {
+		used, err := s.usage.Usage(ctx, u.Directory, fileSystem)
+		if err != nil {
+			return 0, 0, err
+		}
+		return used, s.quotaLimit(u), nil
+	})
+}
+
+// prepareQuota 写请求前检查配额并记录受影响路径的大小
+//
+// 返回 nil 表示不需要跟踪该请求：请求不影响用量，或用户没有配额限制且还没有统计过用量。
+// 不限配额的用户在 PROPFIND 统计过用量后同样按增量更新，使 quota-used-bytes 保持准确。
+func (s *WebDAVService) prepareQuota(ctx context.Context, u *user.User, r *http.Request, fileSystem webdav.FileSystem) (*quotaRequest, error) {
+	limit := s.quotaLimit(u)
+	if limit <= 0 && !s.usage.Tracked(u.Directory) {
+		return nil, nil
+	}
+
+	reqPath, ok := s.stripPrefix(r.URL.Path)
+	if !ok {
+		return nil, nil
+	}
+
+	q := &quotaRequest{
+		key:        u.Directory,
+		fileSystem: fileSystem,
+		method:     r.Method,
+		target:     reqPath,
+	}
+
+	var err error
+	switch r.Method {
+	case http.MethodPut:
+		if q.replaced, err = fileSize(ctx, fileSystem, reqPath); err != nil {
+			return nil, err
+		}
+	case http.MethodDelete:
+		// 删除不会增加用量，无需检查配额
+		if q.replaced, err = storage.TreeSize(ctx, fileSystem, reqPath); err != nil {
+			return nil, err
+		}
+		return q, nil
+	case "COPY", "MOVE":
+		destination, err := parseDestination(r)
+		if err != nil {
+			return nil, nil
+		}
+		destPath, ok := s.stripPrefix(destination)
+		if !ok {
+			return nil, nil
+		}
+		q.target = destPath
+
+		if r.Header.Get("Overwrite") == "F" {
+			// 目标已存在时请求会以 412 失败，不影响用量
+			if _, err := fileSystem.Stat(ctx, destPath); err == nil {
+				return nil, nil
+			} else if !os.IsNotExist(err) {
+				return nil, err
+			}
+		} else if q.replaced, err = storage.TreeSize(ctx, fileSystem, destPath); err != nil {
+			return nil, err
+		}
+
+		// 同一文件系统内移动不会增加用量
+		if r.Method == "MOVE" {
+			return q, nil
+		}
+
+		// Depth: 0 只复制集合本身
+		if r.Header.Get("Depth") == "0" {
+			q.copied, err = fileSize(ctx, fileSystem, reqPath)
+		} else {
+			q.copied, err = storage.TreeSize(ctx, fileSystem, reqPath)
+		}
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, nil
+	}
+
+	// 不限配额时只跟踪用量，不限制写入
+	if limit <= 0 {
+		q.remaining = math.MaxInt64
+		return q, nil
+	}
+
+	used, err := s.usage.Usage(ctx, u.Directory, fileSystem)
+	if err != nil {
+		return nil, err
+	}
+	q.remaining = limit - used
+	if q.remaining < 0 {
+		q.remaining = 0
+	}
+
+	// 预估本次请求带来的增量
+	var delta int64
+	switch r.Method {
+	case http.MethodPut:
+		if r.ContentLength >= 0 {
+			delta = r.ContentLength - q.replaced
+		}
+		// 被覆盖文件的空间在写入时可复用
+		q.remaining += q.replaced
+	case "COPY":
+		delta = q.copied - q.replaced
+	}
+
+	if delta > 0 && used+delta > limit {
+		return nil, errQuotaExceeded
+	}
+
+	return q, nil
+}
+
+// finishQuota 写请求完成后根据响应状态更新用量
+func (s *WebDAVService) finishQuota(ctx context.Context, q *quotaRequest, status int) {
+	succeeded := status == 0 || (status >= 200 && status < 300)
+
+	var delta int64
+	switch {
+	case q.method == http.MethodPut && q.body != nil && q.body.exceeded:
+		// 超出配额的文件已被删除，原文件在写入时已被截断
+		delta = -q.replaced
+	case q.method == http.MethodPut && succeeded:
+		delta = q.body.read - q.replaced
+	case q.method == http.MethodPut:
+		// 写入中途失败时文件可能已被截断或部分写入
+		size, err := fileSize(ctx, q.fileSystem, q.target)
+		if err != nil {
+			s.logger.Warn("failed to measure usage after write",
+				zap.String("path", q.target),
+				zap.Error(err))
+			s.usage.Invalidate(q.key)
+			return
+		}
+		delta = size - q.replaced
+	case succeeded && q.method == "COPY":
+		delta = q.copied - q.replaced
+	case succeeded:
+		// DELETE、MOVE 删除或替换了 target
+		delta = -q.replaced
+	case status >= http.StatusInternalServerError:
+		// 复制、移动或删除可能只完成了一部分，重新统计
+		s.usage.Invalidate(q.key)
+		return
+	default:
+		// 请求被拒绝，文件系统未修改
+		return
+	}
+
+	s.usage.Add(q.key, delta)
+}
+
+// fileSize 文件的大小，不存在或为目录时返回 0
+func fileSize(ctx context.Context, fileSystem webdav.FileSystem, name string) (int64, error) {
+	info, err := fileSystem.Stat(ctx, name)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	if info.IsDir() {
+		return 0, nil
+	}
+	return info.Size(), nil
+}
+
+// quotaReader 统计并限制 PUT 写入字节数的请求体
+type quotaReader struct {
+	io.ReadCloser
+	remaining int64
+	read      int64
+	exceeded  bool
+}
+
+// Read 读取请求体，超出配额时返回错误
+func (r *quotaReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.read += int64(n)
+	r.remaining -= int64(n)
+	if r.remaining < 0 {
+		r.exceeded = true
+		return n, errQuotaExceeded
+	}
+	return n, err
+}
+
+// quotaResponseWriter 超出配额时将响应改写为 507 Insufficient Storage
+type quotaResponseWriter struct {
+	http.ResponseWriter
+	reader      *quotaReader
+	wroteHeader bool
+	rewritten   bool
+}
+
+// WriteHeader 写入状态码
+func (w *quotaResponseWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+
+	if w.reader.exceeded {
+		w.rewritten = true
+		w.ResponseWriter.Header().Del("ETag")
+		http.Error(w.ResponseWriter, http.StatusText(http.StatusInsufficientStorage), http.StatusInsufficientStorage)
+		return
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write 写入响应体
+func (w *quotaResponseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.rewritten {
+		return len(b), nil
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// removePartial 删除超出配额而写入中断的文件
+func (s *WebDAVService) removePartial(ctx context.Context, fileSystem webdav.FileSystem, name string) {
+	if err := fileSystem.RemoveAll(ctx, name); err != nil && !os.IsNotExist(err) {
+		s.logger.Warn("failed to remove partial upload",
+			zap.String("path", name),
+			zap.Error(err))
+	}
+}
@@ -0,0 +1,391 @@
+package service
+
+import (
+	"context"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/yeying-community/webdav/internal/domain/user"
+	"github.com/yeying-community/webdav/internal/infrastructure/config"
+	"github.com/yeying-community/webdav/internal/infrastructure/lock"
+	"github.com/yeying-community/webdav/internal/infrastructure/permission"
+	"github.com/yeying-community/webdav/internal/infrastructure/storage"
+	"github.com/yeying-community/webdav/internal/interface/http/middleware"
+	"go.uber.org/zap"
+	"golang.org/x/net/webdav"
+)
+
+// listingDriver 记录目录遍历次数的存储驱动
+type listingDriver struct {
+	storage.Driver
+	mu       sync.Mutex
+	listings map[string]int
+}
+
+func (d *listingDriver) FileSystem(ctx context.Context, root string) (webdav.FileSystem, error) {
+	fs, err := d.Driver.FileSystem(ctx, root)
+	if err != nil {
+		return nil, err
+	}
+	return &listingFS{FileSystem: fs, driver: d}, nil
+}
+
+// count 目录被列出的次数
+func (d *listingDriver) count(name string) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.listings[name]
+}
+
+func (d *listingDriver) reset() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.listings = make(map[string]int)
+}
+
+type listingFS struct {
+	webdav.FileSystem
+	driver *listingDriver
+}
+
+func (fs *listingFS) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (webdav.File, error) {
+	f, err := fs.FileSystem.OpenFile(ctx, name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	return &listingFile{File: f, name: name, driver: fs.driver}, nil
+}
+
+type listingFile struct {
+	webdav.File
+	name   string
+	driver *listingDriver
+}
+
+func (f *listingFile) Readdir(count int) ([]os.FileInfo, error) {
+	f.driver.mu.Lock()
+	f.driver.listings[f.name]++
+	f.driver.mu.Unlock()
+	return f.File.Readdir(count)
+}
+
+// newQuotaService 创建配额测试用的服务，defaultQuota 为空表示不限
+func newQuotaService(t *testing.T, u *user.User, defaultQuota string) (*WebDAVService, *listingDriver, webdav.FileSystem, *storage.UsageTracker) {
+	t.Helper()
+
+	cfg := &config.Config{}
+	cfg.WebDAV.Prefix = testPrefix
+	cfg.WebDAV.DefaultQuota = defaultQuota
+
+	driver := &listingDriver{Driver: storage.NewMemoryDriver(), listings: make(map[string]int)}
+	fs, err := driver.FileSystem(context.Background(), u.Directory)
+	if err != nil {
+		t.Fatalf("FileSystem: %v", err)
+	}
+
+	logger := zap.NewNop()
+	usage := storage.NewUsageTracker(time.Hour)
+	s := NewWebDAVService(cfg, driver, permission.NewWebDAVChecker(driver, testPrefix, nil, logger), usage, nil,
+		lock.NewManager(lock.NewMemoryStore(), time.Minute, logger), logger)
+	return s, driver, fs, usage
+}
+
+// quotaStep 一次 WebDAV 请求及请求后的预期状态
+type quotaStep struct {
+	method  string
+	target  string
+	body    string
+	chunked bool // 长度未知的上传
+	header  map[string]string
+	want    int
+	used    int64
+}
+
+func (st quotaStep) serve(s *WebDAVService, u *user.User) int {
+	var body io.Reader
+	if st.body != "" {
+		body = strings.NewReader(st.body)
+		if st.chunked {
+			body = io.MultiReader(body)
+		}
+	}
+	r := httptest.NewRequest(st.method, testPrefix+st.target, body)
+	if st.chunked {
+		r.ContentLength = -1
+	}
+	for k, v := range st.header {
+		r.Header.Set(k, v)
+	}
+	r = r.WithContext(context.WithValue(r.Context(), middleware.UserContextKey, u))
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	return w.Code
+}
+
+func TestQuotaTracksUsageIncrementally(t *testing.T) {
+	u := user.NewUser("alice", "/alice")
+	u.Permissions = user.FullPermissions()
+	u.Quota = 100
+
+	dest := func(p string) map[string]string { return map[string]string{"Destination": testPrefix + p} }
+	bytes := func(n int) string { return strings.Repeat("x", n) }
+
+	steps := []quotaStep{
+		{method: "MKCOL", target: "/dir", want: http.StatusCreated, used: 0},
+		{method: "PUT", target: "/dir/a", body: bytes(40), want: http.StatusCreated, used: 40},
+		{method: "PUT", target: "/dir/a", body: bytes(30), want: http.StatusCreated, used: 30},
+		{method: "PUT", target: "/b", body: bytes(80), want: http.StatusInsufficientStorage, used: 30},
+		// 覆盖文件时可复用原文件的空间
+		{method: "PUT", target: "/dir/a", body: bytes(100), want: http.StatusCreated, used: 100},
+		{method: "PUT", target: "/dir/a", body: bytes(20), want: http.StatusCreated, used: 20},
+		{method: "COPY", target: "/dir", header: dest("/copy"), want: http.StatusCreated, used: 40},
+		{method: "PUT", target: "/dir/b", body: bytes(10), want: http.StatusCreated, used: 50},
+		// 覆盖目标时只计算差值
+		{method: "COPY", target: "/dir", header: dest("/copy"), want: http.StatusNoContent, used: 60},
+		{method: "COPY", target: "/dir", header: dest("/copy2"), want: http.StatusCreated, used: 90},
+		{method: "COPY", target: "/dir", header: dest("/copy3"), want: http.StatusInsufficientStorage, used: 90},
+		{method: "COPY", target: "/dir", header: map[string]string{"Destination": testPrefix + "/empty", "Depth": "0"}, want: http.StatusCreated, used: 90},
+		{method: "COPY", target: "/dir", header: map[string]string{"Destination": testPrefix + "/copy", "Overwrite": "F"}, want: http.StatusPreconditionFailed, used: 90},
+		// 移动不增加用量，覆盖的目标被释放
+		{method: "MOVE", target: "/copy2", header: dest("/moved"), want: http.StatusCreated, used: 90},
+		{method: "MOVE", target: "/moved", header: dest("/copy"), want: http.StatusPreconditionFailed, used: 90},
+		{method: "MOVE", target: "/moved", header: map[string]string{"Destination": testPrefix + "/copy", "Overwrite": "T"}, want: http.StatusNoContent, used: 60},
+		{method: "DELETE", target: "/copy", want: http.StatusNoContent, used: 30},
+		{method: "DELETE", target: "/missing", want: http.StatusNotFound, used: 30},
+		// 长度未知的上传在超出配额时中断并删除
+		{method: "PUT", target: "/stream", body: bytes(60), chunked: true, want: http.StatusCreated, used: 90},
+		{method: "PUT", target: "/big", body: bytes(20), chunked: true, want: http.StatusInsufficientStorage, used: 90},
+		{method: "PUT", target: "/stream", body: bytes(70), chunked: true, want: http.StatusCreated, used: 100},
+		{method: "DELETE", target: "/dir", want: http.StatusNoContent, used: 70},
+	}
+
+	s, driver, fs, usage := newQuotaService(t, u, "")
+
+	for i, st := range steps {
+		if i == 1 {
+			// 首次写请求统计一次用量，之后不再遍历整个文件系统
+			driver.reset()
+		}
+		if got := st.serve(s, u); got != st.want {
+			t.Fatalf("step %d %s %s: status = %d, want %d", i, st.method, st.target, got, st.want)
+		}
+
+		used, err := usage.Usage(context.Background(), u.Directory, fs)
+		if err != nil {
+			t.Fatalf("Usage: %v", err)
+		}
+		actual, err := storage.TreeSize(context.Background(), fs, "/")
+		if err != nil {
+			t.Fatalf("TreeSize: %v", err)
+		}
+		if used != st.used || actual != st.used {
+			t.Fatalf("step %d %s %s: tracked %d, actual %d, want %d", i, st.method, st.target, used, actual, st.used)
+		}
+		driver.reset()
+	}
+
+	if _, err := fs.Stat(context.Background(), "/big"); !os.IsNotExist(err) {
+		t.Fatalf("partial upload should be removed: %v", err)
+	}
+}
+
+func TestQuotaWalksOnlyAffectedPaths(t *testing.T) {
+	u := user.NewUser("alice", "/alice")
+	u.Permissions = user.FullPermissions()
+	u.Quota = 1 << 20
+
+	s, driver, fs, _ := newQuotaService(t, u, "")
+	for _, st := range []quotaStep{
+		{method: "MKCOL", target: "/src", want: http.StatusCreated},
+		{method: "MKCOL", target: "/other", want: http.StatusCreated},
+		{method: "PUT", target: "/src/a", body: "aaaa", want: http.StatusCreated},
+		{method: "PUT", target: "/other/b", body: "bbbb", want: http.StatusCreated},
+	} {
+		if got := st.serve(s, u); got != st.want {
+			t.Fatalf("setup %s %s = %d", st.method, st.target, got)
+		}
+	}
+
+	tests := []struct {
+		name string
+		step quotaStep
+	}{
+		{name: "put", step: quotaStep{method: "PUT", target: "/src/c", body: "cc", want: http.StatusCreated}},
+		{name: "copy", step: quotaStep{method: "COPY", target: "/src", header: map[string]string{"Destination": testPrefix + "/dst"}, want: http.StatusCreated}},
+		{name: "move", step: quotaStep{method: "MOVE", target: "/dst", header: map[string]string{"Destination": testPrefix + "/moved"}, want: http.StatusCreated}},
+		{name: "delete", step: quotaStep{method: "DELETE", target: "/moved", want: http.StatusNoContent}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			driver.reset()
+			if got := tt.step.serve(s, u); got != tt.step.want {
+				t.Fatalf("status = %d, want %d", got, tt.step.want)
+			}
+			if n := driver.count("/"); n != 0 {
+				t.Fatalf("root listed %d times", n)
+			}
+			if n := driver.count("/other"); n != 0 {
+				t.Fatalf("unrelated directory listed %d times", n)
+			}
+		})
+	}
+
+	if _, err := fs.Stat(context.Background(), "/src/c"); err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+}
+
+func TestQuotaUnlimitedSkipsMeasurement(t *testing.T) {
+	tests := []struct {
+		name         string
+		quota        int64
+		defaultQuota string
+	}{
+		{name: "no default quota", quota: 0, defaultQuota: ""},
+		{name: "user without limit", quota: -1, defaultQuota: "1KB"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := user.NewUser("alice", "/alice")
+			u.Permissions = user.FullPermissions()
+			u.Quota = tt.quota
+
+			s, driver, _, _ := newQuotaService(t, u, tt.defaultQuota)
+			steps := []quotaStep{
+				{method: "MKCOL", target: "/dir", want: http.StatusCreated},
+				{method: "PUT", target: "/dir/a", body: strings.Repeat("x", 4096), want: http.StatusCreated},
+				{method: "COPY", target: "/dir", header: map[string]string{"Destination": testPrefix + "/copy"}, want: http.StatusCreated},
+				{method: "DELETE", target: "/copy", want: http.StatusNoContent},
+			}
+			for _, st := range steps {
+				if got := st.serve(s, u); got != st.want {
+					t.Fatalf("%s %s = %d, want %d", st.method, st.target, got, st.want)
+				}
+			}
+
+			// COPY 本身需要列出源目录，除此之外不应统计任何目录
+			if n := driver.count("/"); n != 0 {
+				t.Fatalf("root listed %d times", n)
+			}
+			if n := driver.count("/copy"); n != 0 {
+				t.Fatalf("deleted directory listed %d times", n)
+			}
+		})
+	}
+}
+
+// quotaProps PROPFIND 返回的配额属性，available 为空表示未返回可用字节
+type quotaProps struct {
+	used      string
+	available string
+}
+
+// propfindQuota 以 PROPFIND 读取根集合的配额属性
+func propfindQuota(t *testing.T, s *WebDAVService, u *user.User) quotaProps {
+	t.Helper()
+
+	body := `<?xml version="1.0"?><D:propfind xmlns:D="DAV:"><D:prop><D:quota-used-bytes/><D:quota-available-bytes/></D:prop></D:propfind>`
+	r := httptest.NewRequest("PROPFIND", testPrefix+"/", strings.NewReader(body))
+	r.Header.Set("Depth", "0")
+	r = r.WithContext(context.WithValue(r.Context(), middleware.UserContextKey, u))
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	if w.Code != http.StatusMultiStatus {
+		t.Fatalf("PROPFIND = %d, want 207", w.Code)
+	}
+
+	var ms struct {
+		Propstats []struct {
+			Used      *string `xml:"prop>quota-used-bytes"`
+			Available *string `xml:"prop>quota-available-bytes"`
+			Status    string  `xml:"status"`
+		} `xml:"response>propstat"`
+	}
+	if err := xml.Unmarshal(w.Body.Bytes(), &ms); err != nil {
+		t.Fatalf("decode PROPFIND response: %v", err)
+	}
+
+	var props quotaProps
+	for _, ps := range ms.Propstats {
+		if !strings.Contains(ps.Status, " 200 ") {
+			continue
+		}
+		if ps.Used != nil {
+			props.used = *ps.Used
+		}
+		if ps.Available != nil {
+			props.available = *ps.Available
+		}
+	}
+	return props
+}
+
+func TestQuotaUnlimitedReportsUsage(t *testing.T) {
+	tests := []struct {
+		name         string
+		quota        int64
+		defaultQuota string
+	}{
+		{name: "no default quota", quota: 0, defaultQuota: ""},
+		{name: "user without limit", quota: -1, defaultQuota: "1KB"},
+	}
+
+	dest := func(p string) map[string]string { return map[string]string{"Destination": testPrefix + p} }
+	bytes := func(n int) string { return strings.Repeat("x", n) }
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := user.NewUser("alice", "/alice")
+			u.Permissions = user.FullPermissions()
+			u.Quota = tt.quota
+
+			s, driver, _, _ := newQuotaService(t, u, tt.defaultQuota)
+			if got := (quotaStep{method: "PUT", target: "/a", body: bytes(5)}).serve(s, u); got != http.StatusCreated {
+				t.Fatalf("PUT = %d", got)
+			}
+
+			// 不限配额时不返回可用字节
+			if got := propfindQuota(t, s, u); got != (quotaProps{used: "5"}) {
+				t.Fatalf("PROPFIND = %+v, want used 5", got)
+			}
+
+			steps := []quotaStep{
+				{method: "MKCOL", target: "/dir", want: http.StatusCreated, used: 5},
+				// 超过默认配额的上传不受限制
+				{method: "PUT", target: "/dir/b", body: bytes(2000), want: http.StatusCreated, used: 2005},
+				{method: "PUT", target: "/a", body: bytes(2), want: http.StatusCreated, used: 2002},
+				{method: "PUT", target: "/stream", body: bytes(30), chunked: true, want: http.StatusCreated, used: 2032},
+				{method: "COPY", target: "/dir", header: dest("/copy"), want: http.StatusCreated, used: 4032},
+				{method: "MOVE", target: "/copy", header: dest("/moved"), want: http.StatusCreated, used: 4032},
+				{method: "MOVE", target: "/stream", header: map[string]string{"Destination": testPrefix + "/a", "Overwrite": "T"}, want: http.StatusNoContent, used: 4030},
+				{method: "DELETE", target: "/moved", want: http.StatusNoContent, used: 2030},
+			}
+			for i, st := range steps {
+				driver.reset()
+				if got := st.serve(s, u); got != st.want {
+					t.Fatalf("step %d %s %s: status = %d, want %d", i, st.method, st.target, got, st.want)
+				}
+				want := quotaProps{used: strconv.FormatInt(st.used, 10)}
+				if got := propfindQuota(t, s, u); got != want {
+					t.Fatalf("step %d %s %s: PROPFIND = %+v, want %+v", i, st.method, st.target, got, want)
+				}
+
+				// 用量按增量更新，不重新遍历整个文件系统
+				if n := driver.count("/"); n != 0 {
+					t.Fatalf("step %d %s %s: root listed %d times", i, st.method, st.target, n)
+				}
+			}
+		})
+	}
+}
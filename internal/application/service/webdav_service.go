@@ -5,36 +5,64 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
 	"os"
-	"path/filepath"
+	"path"
+	"strings"
 
 	"github.com/yeying-community/webdav/internal/domain/permission"
 	"github.com/yeying-community/webdav/internal/domain/user"
 	"github.com/yeying-community/webdav/internal/infrastructure/config"
+	"github.com/yeying-community/webdav/internal/infrastructure/lock"
+	"github.com/yeying-community/webdav/internal/infrastructure/storage"
 	"github.com/yeying-community/webdav/internal/interface/http/middleware"
 	"go.uber.org/zap"
 	"golang.org/x/net/webdav"
 )
 
+// errInvalidDestination Destination 请求头无效
+var errInvalidDestination = errors.New("invalid destination header")
+
 // WebDAVService WebDAV 服务
 type WebDAVService struct {
 	config          *config.Config
+	storage         storage.Driver
 	permissionCheck permission.Checker
+	usage           *storage.UsageTracker
+	deadProps       storage.PropStore
+	defaultQuota    int64
 	logger          *zap.Logger
-	lockSystem      webdav.LockSystem
+	locks           *lock.Manager
 }
 
 // NewWebDAVService 创建 WebDAV 服务
 func NewWebDAVService(
 	cfg *config.Config,
+	storageDriver storage.Driver,
 	permissionCheck permission.Checker,
+	usage *storage.UsageTracker,
+	deadProps storage.PropStore,
+	locks *lock.Manager,
 	logger *zap.Logger,
 ) *WebDAVService {
+	// 配置已通过校验，此处解析失败时按不限处理
+	defaultQuota, err := config.ParseByteSize(cfg.WebDAV.DefaultQuota)
+	if err != nil {
+		logger.Warn("invalid default quota, treating as unlimited",
+			zap.String("default_quota", cfg.WebDAV.DefaultQuota),
+			zap.Error(err))
+		defaultQuota = 0
+	}
+
 	return &WebDAVService{
 		config:          cfg,
+		storage:         storageDriver,
 		permissionCheck: permissionCheck,
+		usage:           usage,
+		deadProps:       deadProps,
+		defaultQuota:    defaultQuota,
 		logger:          logger,
-		lockSystem:      webdav.NewMemLS(),
+		locks:           locks,
 	}
 }
 
@@ -48,28 +76,28 @@ func (s *WebDAVService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 获取用户目录
-	userDir := s.getUserDirectory(u)
-
-	// 确保目录存在
-	if err := s.ensureDirectory(userDir); err != nil {
-		s.logger.Error("failed to ensure directory",
-			zap.String("directory", userDir),
+	// 获取用户文件系统
+	fileSystem, err := s.storage.FileSystem(r.Context(), u.Directory)
+	if err != nil {
+		s.logger.Error("failed to open user file system",
+			zap.String("driver", s.storage.Name()),
+			zap.String("directory", u.Directory),
 			zap.Error(err))
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
 
-	// 创建 WebDAV 处理器
-	handler := &webdav.Handler{
-		Prefix:     s.config.WebDAV.Prefix,
-		FileSystem: webdav.Dir(userDir),
-		LockSystem: s.lockSystem,
-		Logger:     s.createLogger(u.Username),
+	// 死属性随资源保存、移动和删除
+	if s.deadProps != nil {
+		fileSystem = storage.NewDeadPropsFileSystem(fileSystem, s.deadProps, u.Directory)
 	}
 
 	// 检查权限
-	if err := s.checkPermission(r.Context(), u, r); err != nil {
+	if err := s.checkPermission(r.Context(), u, r, fileSystem); err != nil {
+		if errors.Is(err, errInvalidDestination) {
+			http.Error(w, "Bad Request", http.StatusBadRequest)
+			return
+		}
 		s.logger.Warn("permission denied",
 			zap.String("username", u.Username),
 			zap.String("method", r.Method),
@@ -84,8 +112,119 @@ func (s *WebDAVService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("X-Content-Type-Options", "nosniff")
 	}
 
+	// 检查配额
+	quota, err := s.prepareQuota(r.Context(), u, r, fileSystem)
+	if err != nil {
+		if errors.Is(err, errQuotaExceeded) {
+			s.logger.Warn("quota exceeded",
+				zap.String("username", u.Username),
+				zap.String("method", r.Method),
+				zap.String("path", r.URL.Path))
+			http.Error(w, http.StatusText(http.StatusInsufficientStorage), http.StatusInsufficientStorage)
+			return
+		}
+		s.logger.Error("failed to check quota",
+			zap.String("username", u.Username),
+			zap.String("path", r.URL.Path),
+			zap.Error(err))
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	// PROPFIND/PROPPATCH 需要返回 RFC 4331 配额属性
+	handlerFS := fileSystem
+	if r.Method == "PROPFIND" || r.Method == "PROPPATCH" {
+		handlerFS = s.quotaFileSystem(u, fileSystem)
+	}
+
+	// 创建 WebDAV 处理器
+	handler := &webdav.Handler{
+		Prefix:     s.config.WebDAV.Prefix,
+		FileSystem: handlerFS,
+		LockSystem: s.locks.ForRequest(u.Username, u.Directory, r.Method == "LOCK"),
+		Logger:     s.createLogger(u.Username),
+	}
+
+	// 上传时统计写入字节数，并在写入过程中限制字节数（长度未知或与实际不符时）
+	if quota != nil && r.Method == http.MethodPut {
+		quota.body = &quotaReader{ReadCloser: r.Body, remaining: quota.remaining}
+		r.Body = quota.body
+		w = &quotaResponseWriter{ResponseWriter: w, reader: quota.body}
+	}
+
+	// webdav 处理器只复制文件的死属性，目录的死属性在复制成功后补齐；
+	// 配额按请求是否成功更新用量
+	copyProps := s.deadProps != nil && r.Method == "COPY"
+	var status *statusWriter
+	if copyProps || quota != nil {
+		status = &statusWriter{ResponseWriter: w}
+		w = status
+	}
+
 	// 处理请求
 	handler.ServeHTTP(w, r)
+
+	if copyProps && (status.status == http.StatusCreated || status.status == http.StatusNoContent) {
+		s.copyDeadProps(r.Context(), u, r)
+	}
+
+	if quota != nil && quota.body != nil && quota.body.exceeded {
+		s.logger.Warn("quota exceeded during upload",
+			zap.String("username", u.Username),
+			zap.String("path", r.URL.Path))
+		s.removePartial(r.Context(), fileSystem, quota.target)
+	}
+
+	if quota != nil {
+		s.finishQuota(r.Context(), quota, status.status)
+	}
+}
+
+// copyDeadProps 复制 COPY 请求源资源的死属性到目标
+func (s *WebDAVService) copyDeadProps(ctx context.Context, u *user.User, r *http.Request) {
+	src, ok := s.stripPrefix(r.URL.Path)
+	if !ok {
+		return
+	}
+	destination, err := parseDestination(r)
+	if err != nil {
+		return
+	}
+	dst, ok := s.stripPrefix(destination)
+	if !ok {
+		return
+	}
+
+	recursive := r.Header.Get("Depth") != "0"
+	if err := s.deadProps.Copy(ctx, u.Directory, src, dst, recursive); err != nil {
+		s.logger.Warn("failed to copy dead props",
+			zap.String("username", u.Username),
+			zap.String("source", src),
+			zap.String("destination", dst),
+			zap.Error(err))
+	}
+}
+
+// statusWriter 记录响应状态码
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+// WriteHeader 写入状态码
+func (w *statusWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write 写入响应体
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
 }
 
 // createLogger 创建 WebDAV 日志记录器
@@ -165,6 +304,11 @@ func isClientError(err error) bool {
 		return false
 	}
 
+	// 资源被其他锁占用
+	if errors.Is(err, webdav.ErrLocked) {
+		return true
+	}
+
 	errMsg := err.Error()
 	return contains(errMsg, "invalid") ||
 		contains(errMsg, "bad request") ||
@@ -203,49 +347,164 @@ func toLower(s string) string {
 	return string(b)
 }
 
-// getUserDirectory 获取用户目录
-func (s *WebDAVService) getUserDirectory(u *user.User) string {
-	// 如果用户有自定义目录，使用用户目录
-	if u.Directory != "" {
-		// 如果是绝对路径，直接使用
-		if filepath.IsAbs(u.Directory) {
-			return u.Directory
+// checkPermission 检查权限
+func (s *WebDAVService) checkPermission(ctx context.Context, u *user.User, r *http.Request, fileSystem webdav.FileSystem) error {
+	// 检查源路径所需的全部操作
+	operations := permission.MapHTTPMethodToOperations(r.Method)
+	if r.Method == "LOCK" {
+		operations = []permission.Operation{permission.MapLockOperation(s.exists(ctx, fileSystem, r.URL.Path))}
+	}
+	for _, operation := range operations {
+		if err := s.permissionCheck.Check(ctx, u, r.URL.Path, operation); err != nil {
+			return err
 		}
-		// 否则拼接到基础目录
-		return filepath.Join(s.config.WebDAV.Directory, u.Directory)
 	}
 
-	// 使用基础目录
-	return s.config.WebDAV.Directory
+	if !permission.RequiresDestination(r.Method) {
+		return nil
+	}
+
+	// COPY/MOVE 还需检查目标路径的创建或更新权限
+	destination, err := parseDestination(r)
+	if err != nil {
+		return err
+	}
+
+	exists := s.exists(ctx, fileSystem, destination)
+	if err := s.permissionCheck.Check(ctx, u, destination, permission.MapDestinationOperation(exists)); err != nil {
+		return err
+	}
+
+	return s.checkTree(ctx, u, r, fileSystem, destination, exists)
+}
+
+// exists 请求路径是否已存在
+func (s *WebDAVService) exists(ctx context.Context, fileSystem webdav.FileSystem, p string) bool {
+	reqPath, ok := s.stripPrefix(p)
+	if !ok {
+		return false
+	}
+	_, err := fileSystem.Stat(ctx, reqPath)
+	return err == nil
+}
+
+// checkTree 检查 COPY/MOVE 集合中每个子路径的权限
+//
+// 规则可以单独限制子路径：只检查根路径时，拒绝读取的子文件会被复制到可读的位置，
+// 受删除保护的子文件会随 MOVE 被移走。用户没有规则时子路径与根路径的权限相同，不需要遍历。
+func (s *WebDAVService) checkTree(ctx context.Context, u *user.User, r *http.Request, fileSystem webdav.FileSystem, destination string, destinationExists bool) error {
+	if len(u.Rules) == 0 {
+		return nil
+	}
+
+	// 覆盖已存在的目标时会先删除目标，目标中的子路径需要删除权限
+	if destinationExists && r.Header.Get("Overwrite") != "F" {
+		err := s.walkDescendants(ctx, fileSystem, destination, func(rel string) error {
+			return s.permissionCheck.CheckRules(ctx, u, path.Join(destination, rel), permission.OperationDelete)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// Depth: 0 的 COPY 只复制集合本身
+	if r.Method == "COPY" && r.Header.Get("Depth") == "0" {
+		return nil
+	}
+
+	// 源中的子路径需要与源相同的权限，复制到目标后为新建
+	operations := permission.MapHTTPMethodToOperations(r.Method)
+	return s.walkDescendants(ctx, fileSystem, r.URL.Path, func(rel string) error {
+		for _, operation := range operations {
+			if err := s.permissionCheck.CheckRules(ctx, u, path.Join(r.URL.Path, rel), operation); err != nil {
+				return err
+			}
+		}
+		return s.permissionCheck.CheckRules(ctx, u, path.Join(destination, rel), permission.OperationCreate)
+	})
 }
 
-// ensureDirectory 确保目录存在
-func (s *WebDAVService) ensureDirectory(dir string) error {
-	info, err := os.Stat(dir)
+// walkDescendants 遍历请求路径下的全部子路径，fn 的参数为相对于 root 的路径；root 不是目录时不遍历
+func (s *WebDAVService) walkDescendants(ctx context.Context, fileSystem webdav.FileSystem, root string, fn func(rel string) error) error {
+	name, ok := s.stripPrefix(root)
+	if !ok {
+		return nil
+	}
+
+	info, err := fileSystem.Stat(ctx, name)
 	if err != nil {
 		if os.IsNotExist(err) {
-			// 创建目录
-			if err := os.MkdirAll(dir, 0755); err != nil {
-				return fmt.Errorf("failed to create directory: %w", err)
-			}
-			s.logger.Info("directory created", zap.String("directory", dir))
 			return nil
 		}
-		return fmt.Errorf("failed to stat directory: %w", err)
+		return err
 	}
-
 	if !info.IsDir() {
-		return fmt.Errorf("path is not a directory: %s", dir)
+		return nil
+	}
+
+	return walkTree(ctx, fileSystem, name, "", fn)
+}
+
+// walkTree 递归遍历目录 name 的子项，rel 为 name 相对于遍历起点的路径
+func walkTree(ctx context.Context, fileSystem webdav.FileSystem, name, rel string, fn func(rel string) error) error {
+	f, err := fileSystem.OpenFile(ctx, name, os.O_RDONLY, 0)
+	if err != nil {
+		return err
+	}
+	children, err := f.Readdir(0)
+	f.Close()
+	if err != nil {
+		return err
+	}
+
+	for _, child := range children {
+		childRel := rel + "/" + child.Name()
+		if err := fn(childRel); err != nil {
+			return err
+		}
+		if child.IsDir() {
+			if err := walkTree(ctx, fileSystem, path.Join(name, child.Name()), childRel, fn); err != nil {
+				return err
+			}
+		}
 	}
 
 	return nil
 }
 
-// checkPermission 检查权限
-func (s *WebDAVService) checkPermission(ctx context.Context, u *user.User, r *http.Request) error {
-	// 映射 HTTP 方法到操作
-	operation := permission.MapHTTPMethodToOperation(r.Method)
+// parseDestination 解析 Destination 请求头中的路径
+//
+// 返回规范化后的路径：webdav 处理器会解析其中的 . 和 .. 段，权限检查必须基于解析后的路径，
+// 否则 /public/../private 会以 /public 的规则通过检查。
+func parseDestination(r *http.Request) (string, error) {
+	header := r.Header.Get("Destination")
+	if header == "" {
+		return "", fmt.Errorf("%w: missing", errInvalidDestination)
+	}
 
-	// 检查权限
-	return s.permissionCheck.Check(ctx, u, r.URL.Path, operation)
+	u, err := url.Parse(header)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", errInvalidDestination, err)
+	}
+
+	if u.Path == "" {
+		return "", fmt.Errorf("%w: empty path", errInvalidDestination)
+	}
+
+	return path.Clean("/" + u.Path), nil
+}
+
+// stripPrefix 去除 WebDAV 前缀，返回文件系统内的路径
+func (s *WebDAVService) stripPrefix(p string) (string, bool) {
+	prefix := strings.TrimSuffix(s.config.WebDAV.Prefix, "/")
+	if prefix == "" {
+		return p, true
+	}
+	if p == prefix {
+		return "/", true
+	}
+	if r := strings.TrimPrefix(p, prefix); len(r) < len(p) && strings.HasPrefix(r, "/") {
+		return r, true
+	}
+	return "", false
 }
@@ -0,0 +1,266 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yeying-community/webdav/internal/domain/user"
+	"github.com/yeying-community/webdav/internal/infrastructure/config"
+	"github.com/yeying-community/webdav/internal/infrastructure/lock"
+	"github.com/yeying-community/webdav/internal/infrastructure/permission"
+	"github.com/yeying-community/webdav/internal/infrastructure/storage"
+	"github.com/yeying-community/webdav/internal/interface/http/middleware"
+	"go.uber.org/zap"
+	"golang.org/x/net/webdav"
+)
+
+const testPrefix = "/dav"
+
+// newTestService 创建使用内存存储的 WebDAV 服务，files 中以 / 结尾的路径为目录
+func newTestService(t *testing.T, u *user.User, files ...string) (*WebDAVService, webdav.FileSystem) {
+	t.Helper()
+
+	cfg := &config.Config{}
+	cfg.WebDAV.Prefix = testPrefix
+
+	driver := storage.NewMemoryDriver()
+	fs, err := driver.FileSystem(context.Background(), u.Directory)
+	if err != nil {
+		t.Fatalf("FileSystem: %v", err)
+	}
+	for _, name := range files {
+		if strings.HasSuffix(name, "/") {
+			if err := fs.Mkdir(context.Background(), name, 0755); err != nil {
+				t.Fatalf("Mkdir %s: %v", name, err)
+			}
+			continue
+		}
+		f, err := fs.OpenFile(context.Background(), name, os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			t.Fatalf("create %s: %v", name, err)
+		}
+		f.Write([]byte(name))
+		f.Close()
+	}
+
+	logger := zap.NewNop()
+	checker := permission.NewWebDAVChecker(driver, testPrefix, nil, logger)
+	locks := lock.NewManager(lock.NewMemoryStore(), time.Minute, logger)
+	s := NewWebDAVService(cfg, driver, checker, storage.NewUsageTracker(time.Hour), nil, locks, logger)
+	return s, fs
+}
+
+// serve 以用户 u 发送 WebDAV 请求
+func serve(s *WebDAVService, u *user.User, method, target string, header map[string]string) int {
+	r := httptest.NewRequest(method, testPrefix+target, nil)
+	for k, v := range header {
+		r.Header.Set(k, v)
+	}
+	r = r.WithContext(context.WithValue(r.Context(), middleware.UserContextKey, u))
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	return w.Code
+}
+
+func newRuleUser(rules ...*user.Rule) *user.User {
+	u := user.NewUser("alice", "/alice")
+	u.Permissions = user.FullPermissions()
+	u.Rules = rules
+	return u
+}
+
+func TestCopyMoveChecksDescendants(t *testing.T) {
+	denyRead := &user.Rule{Path: "/a/secret", Deny: true, Permissions: user.ParsePermissions("R")}
+	denyDelete := &user.Rule{Path: "/a/keep", Deny: true, Permissions: user.ParsePermissions("D")}
+	denyCreate := &user.Rule{Path: "**/*.exe", Glob: true, Deny: true, Permissions: user.ParsePermissions("C")}
+	protected := &user.Rule{Path: "/b/keep", Deny: true, Permissions: user.ParsePermissions("D")}
+	readOnly := &user.Rule{Path: "/private", Permissions: user.ParsePermissions("R")}
+
+	tests := []struct {
+		name    string
+		rules   []*user.Rule
+		files   []string
+		method  string
+		source  string // 默认为 /a
+		header  map[string]string
+		want    int
+		created string // 请求成功时应存在的路径
+		kept    string // 请求被拒绝时应保留的路径
+		absent  string // 请求被拒绝时不应存在的路径
+	}{
+		{
+			name:   "copy with read-denied child",
+			rules:  []*user.Rule{denyRead},
+			files:  []string{"/a/", "/a/secret", "/a/public"},
+			method: "COPY",
+			want:   http.StatusForbidden,
+			kept:   "/a/secret",
+		},
+		{
+			name:    "copy depth 0 skips children",
+			rules:   []*user.Rule{denyRead},
+			files:   []string{"/a/", "/a/secret"},
+			method:  "COPY",
+			header:  map[string]string{"Depth": "0"},
+			want:    http.StatusCreated,
+			created: "/b",
+		},
+		{
+			name:   "move with delete-protected child",
+			rules:  []*user.Rule{denyDelete},
+			files:  []string{"/a/", "/a/keep", "/a/other"},
+			method: "MOVE",
+			want:   http.StatusForbidden,
+			kept:   "/a/keep",
+		},
+		{
+			name:   "copy creates denied child under destination",
+			rules:  []*user.Rule{denyCreate},
+			files:  []string{"/a/", "/a/sub/", "/a/sub/tool.exe"},
+			method: "COPY",
+			want:   http.StatusForbidden,
+			kept:   "/a/sub/tool.exe",
+		},
+		{
+			name:   "overwrite removes protected destination child",
+			rules:  []*user.Rule{protected},
+			files:  []string{"/a/", "/a/file", "/b/", "/b/keep"},
+			method: "COPY",
+			want:   http.StatusForbidden,
+			kept:   "/b/keep",
+		},
+		{
+			name:    "overwrite disabled leaves destination alone",
+			rules:   []*user.Rule{protected},
+			files:   []string{"/a/", "/a/file", "/b/", "/b/keep"},
+			method:  "COPY",
+			header:  map[string]string{"Overwrite": "F"},
+			want:    http.StatusPreconditionFailed,
+			created: "/b/keep",
+		},
+		{
+			name:    "unrelated rules allow move",
+			rules:   []*user.Rule{{Path: "/other", Deny: true}},
+			files:   []string{"/a/", "/a/one", "/a/sub/", "/a/sub/two"},
+			method:  "MOVE",
+			want:    http.StatusCreated,
+			created: "/b/sub/two",
+		},
+		{
+			name:   "dot segments in destination are resolved before the check",
+			rules:  []*user.Rule{readOnly},
+			files:  []string{"/public/", "/public/f", "/private/"},
+			method: "MOVE",
+			source: "/public/f",
+			header: map[string]string{"Destination": "http://example.com" + testPrefix + "/public/../private/f"},
+			want:   http.StatusForbidden,
+			kept:   "/public/f",
+			absent: "/private/f",
+		},
+		{
+			name:   "encoded dot segments in destination",
+			rules:  []*user.Rule{readOnly},
+			files:  []string{"/public/", "/public/f", "/private/"},
+			method: "COPY",
+			source: "/public/f",
+			header: map[string]string{"Destination": testPrefix + "/public/%2e%2e/private/f"},
+			want:   http.StatusForbidden,
+			kept:   "/public/f",
+			absent: "/private/f",
+		},
+		{
+			name:    "dot segments within the allowed tree",
+			rules:   []*user.Rule{readOnly},
+			files:   []string{"/public/", "/public/sub/", "/public/f"},
+			method:  "MOVE",
+			source:  "/public/f",
+			header:  map[string]string{"Destination": testPrefix + "/public/sub/../g"},
+			want:    http.StatusCreated,
+			created: "/public/g",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := newRuleUser(tt.rules...)
+			s, fs := newTestService(t, u, tt.files...)
+
+			header := map[string]string{"Destination": testPrefix + "/b"}
+			for k, v := range tt.header {
+				header[k] = v
+			}
+
+			source := tt.source
+			if source == "" {
+				source = "/a"
+			}
+			if got := serve(s, u, tt.method, source, header); got != tt.want {
+				t.Fatalf("%s status = %d, want %d", tt.method, got, tt.want)
+			}
+			if tt.created != "" {
+				if _, err := fs.Stat(context.Background(), tt.created); err != nil {
+					t.Errorf("%s should exist: %v", tt.created, err)
+				}
+			}
+			if tt.kept != "" {
+				if _, err := fs.Stat(context.Background(), tt.kept); err != nil {
+					t.Errorf("%s should be kept: %v", tt.kept, err)
+				}
+				if strings.HasPrefix(tt.kept, "/a/") {
+					if _, err := fs.Stat(context.Background(), "/b"+strings.TrimPrefix(tt.kept, "/a")); err == nil {
+						t.Errorf("%s should not be copied", tt.kept)
+					}
+				}
+			}
+			if tt.absent != "" {
+				if _, err := fs.Stat(context.Background(), tt.absent); err == nil {
+					t.Errorf("%s should not be created", tt.absent)
+				}
+			}
+		})
+	}
+}
+
+func TestLockRequiresCreateForMissingTarget(t *testing.T) {
+	const lockInfo = `<?xml version="1.0" encoding="utf-8"?>
+<D:lockinfo xmlns:D="DAV:"><D:lockscope><D:exclusive/></D:lockscope><D:locktype><D:write/></D:locktype></D:lockinfo>`
+
+	tests := []struct {
+		name        string
+		permissions string
+		target      string
+		want        int
+	}{
+		{name: "update only on missing target", permissions: "RU", target: "/new", want: http.StatusForbidden},
+		{name: "update only on existing target", permissions: "RU", target: "/existing", want: http.StatusOK},
+		{name: "create on missing target", permissions: "RC", target: "/new", want: http.StatusCreated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := user.NewUser("alice", "/alice")
+			u.Permissions = user.ParsePermissions(tt.permissions)
+			s, fs := newTestService(t, u, "/existing")
+
+			r := httptest.NewRequest("LOCK", testPrefix+tt.target, strings.NewReader(lockInfo))
+			r = r.WithContext(context.WithValue(r.Context(), middleware.UserContextKey, u))
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, r)
+
+			if w.Code != tt.want {
+				t.Fatalf("LOCK status = %d, want %d", w.Code, tt.want)
+			}
+			if tt.want == http.StatusForbidden {
+				if _, err := fs.Stat(context.Background(), tt.target); err == nil {
+					t.Errorf("%s should not be created", tt.target)
+				}
+			}
+		})
+	}
+}
@@ -1,33 +1,60 @@
 package container
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"time"
 
 	"github.com/yeying-community/webdav/internal/application/service"
 	"github.com/yeying-community/webdav/internal/domain/auth"
+	"github.com/yeying-community/webdav/internal/domain/user"
 	infraAuth "github.com/yeying-community/webdav/internal/infrastructure/auth"
 	"github.com/yeying-community/webdav/internal/infrastructure/config"
+	"github.com/yeying-community/webdav/internal/infrastructure/crypto"
+	"github.com/yeying-community/webdav/internal/infrastructure/lock"
 	"github.com/yeying-community/webdav/internal/infrastructure/logger"
 	"github.com/yeying-community/webdav/internal/infrastructure/permission"
+	"github.com/yeying-community/webdav/internal/infrastructure/ratelimit"
 	"github.com/yeying-community/webdav/internal/infrastructure/repository"
+	"github.com/yeying-community/webdav/internal/infrastructure/storage"
 	"github.com/yeying-community/webdav/internal/interface/http"
 	"github.com/yeying-community/webdav/internal/interface/http/handler"
+	"github.com/yeying-community/webdav/internal/interface/http/middleware"
 	"go.uber.org/zap"
-	"golang.org/x/net/webdav"
 )
 
+// usageRescanInterval 存储用量重新统计的间隔
+const usageRescanInterval = 10 * time.Minute
+
 // Container 依赖注入容器
 type Container struct {
 	Config *config.Config
 	Logger *zap.Logger
 
 	// Repositories
-	UserRepo *repository.MemoryUserRepository
+	UserRepo user.Repository
+
+	// Storage
+	Storage   storage.Driver
+	Locks     *lock.Manager
+	DeadProps storage.PropStore
 
 	// Authenticators
 	Authenticators []auth.Authenticator
 	BasicAuth      *infraAuth.BasicAuthenticator
+	LDAPAuth       *infraAuth.LDAPAuthenticator
 	Web3Auth       *infraAuth.Web3Authenticator
+	JWTManager     *infraAuth.JWTManager
+	OIDCAuth       *infraAuth.OIDCAuthenticator
+	CertAuth       *infraAuth.CertificateAuthenticator
+	DigestAuth     *infraAuth.DigestAuthenticator
+	ContractWallet *crypto.ContractWalletVerifier
+	Registrar      *infraAuth.WalletRegistrar
+	TokenGates     *crypto.TokenGateVerifier
+	PasswordHasher *crypto.PasswordHasher
+	Revocations    infraAuth.RevocationStore
+	AuthLimits     *middleware.AuthLimits
 
 	// Services
 	WebDAVService *service.WebDAVService
@@ -35,6 +62,13 @@ type Container struct {
 	// Handlers
 	HealthHandler *handler.HealthHandler
 	Web3Handler   *handler.Web3Handler
+	JWKSHandler   *handler.JWKSHandler
+	OIDCHandler   *handler.OIDCHandler
+	AppPasswords  *handler.AppPasswordHandler
+	TwoFactor     *handler.TwoFactorHandler
+	Identities    *handler.IdentityHandler
+	AdminHandler  *handler.AdminHandler
+	LockHandler   *handler.LockHandler
 	WebDAVHandler *handler.WebDAVHandler
 
 	// HTTP
@@ -94,57 +128,282 @@ func (c *Container) initLogger() error {
 
 // initRepositories 初始化仓储
 func (c *Container) initRepositories() error {
-	c.UserRepo = repository.NewMemoryUserRepository(c.Config.Users)
+	usersCfg := c.Config.Storage.Users
+
+	switch usersCfg.Driver {
+	case "", "memory":
+		c.UserRepo = repository.NewMemoryUserRepository(c.Config.Users)
+
+	case "sqlite":
+		repo, err := repository.NewSQLiteUserRepository(usersCfg.Path)
+		if err != nil {
+			return fmt.Errorf("failed to open sqlite user repository: %w", err)
+		}
+		c.UserRepo = repo
+
+		imported, err := repo.ImportConfigUsers(context.Background(), c.Config.Users)
+		if err != nil {
+			return fmt.Errorf("failed to import config users: %w", err)
+		}
+		if imported > 0 {
+			c.Logger.Info("config users imported",
+				zap.String("path", usersCfg.Path),
+				zap.Int("count", imported))
+		}
+
+	default:
+		return fmt.Errorf("unsupported users driver: %s", usersCfg.Driver)
+	}
+
+	users, err := c.UserRepo.List(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to list users: %w", err)
+	}
 
 	c.Logger.Info("repositories initialized",
-		zap.Int("users", len(c.Config.Users)))
+		zap.String("users_driver", usersCfg.Driver),
+		zap.Int("users", len(users)))
 
 	return nil
 }
 
 // initAuthenticators 初始化认证器
 func (c *Container) initAuthenticators() error {
+	c.PasswordHasher = crypto.NewPasswordHasherWithConfig(c.Config.Security.PasswordHash)
+
 	// Basic 认证器
 	c.BasicAuth = infraAuth.NewBasicAuthenticator(
 		c.UserRepo,
+		c.PasswordHasher,
 		c.Config.Security.NoPassword,
+		c.digestRealm(),
 		c.Logger,
 	)
-	c.Authenticators = append(c.Authenticators, c.BasicAuth)
+
+	// LDAP 认证器，接管 Basic 凭证，目录中不存在的用户回退到本地账号
+	if c.Config.LDAP.Enabled {
+		ldapAuth, err := infraAuth.NewLDAPAuthenticator(
+			c.Config.LDAP,
+			c.UserRepo,
+			c.BasicAuth,
+			c.Logger,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to create ldap authenticator: %w", err)
+		}
+		c.LDAPAuth = ldapAuth
+		c.Authenticators = append(c.Authenticators, c.LDAPAuth)
+
+		c.Logger.Info("ldap authentication enabled",
+			zap.String("url", c.Config.LDAP.URL),
+			zap.Bool("start_tls", c.Config.LDAP.StartTLS),
+			zap.Bool("fallback", c.Config.LDAP.Fallback))
+	} else {
+		c.Authenticators = append(c.Authenticators, c.BasicAuth)
+	}
+
+	// OIDC 认证器，需排在 Web3 认证器之前：两者都接受 Bearer 令牌，
+	// OIDC 认证器只处理签发者为身份提供方的令牌
+	if c.Config.OIDC.Enabled {
+		c.OIDCAuth = infraAuth.NewOIDCAuthenticator(
+			c.Config.OIDC,
+			c.UserRepo,
+			nil,
+			c.Logger,
+		)
+		c.Authenticators = append(c.Authenticators, c.OIDCAuth)
+
+		c.Logger.Info("oidc authentication enabled",
+			zap.String("issuer", c.Config.OIDC.Issuer),
+			zap.Bool("auto_provision", c.Config.OIDC.AutoProvision))
+	}
 
 	// Web3 认证器
 	if c.Config.Web3.Enabled {
+		// 合约钱包验证（EIP-1271 / ERC-6492）
+		if rpcCfg := c.Config.Web3.RPC; rpcCfg.URL != "" {
+			verifier, err := crypto.NewContractWalletVerifier(rpcCfg.URL, rpcCfg.Timeout)
+			if err != nil {
+				return fmt.Errorf("failed to create contract wallet verifier: %w", err)
+			}
+			c.ContractWallet = verifier
+			c.checkRPCChainID()
+		}
+
+		// 链上持有条件规则（ERC-20 / ERC-721）
+		if rpcCfg := c.Config.Web3.TokenGateRPC(); rpcCfg.URL != "" {
+			verifier, err := crypto.NewTokenGateVerifier(rpcCfg.URL, rpcCfg.Timeout, c.Config.Web3.TokenGate.CacheTTL)
+			if err != nil {
+				return fmt.Errorf("failed to create token gate verifier: %w", err)
+			}
+			c.TokenGates = verifier
+		}
+
+		// JWT 签名密钥
+		jwtManager, err := infraAuth.NewJWTManager(c.Config.Web3)
+		if err != nil {
+			return fmt.Errorf("failed to create jwt manager: %w", err)
+		}
+		c.JWTManager = jwtManager
+
+		// 令牌吊销列表（刷新令牌轮换和注销）
+		revocations, err := infraAuth.NewRevocationStore(c.Config.Storage.Tokens)
+		if err != nil {
+			return fmt.Errorf("failed to create token revocation store: %w", err)
+		}
+		c.Revocations = revocations
+
 		c.Web3Auth = infraAuth.NewWeb3Authenticator(
 			c.UserRepo,
-			c.Config.Web3.JWTSecret,
-			c.Config.Web3.TokenExpiration,
+			c.Config.Web3,
+			c.JWTManager,
+			c.ContractWallet,
+			c.Revocations,
 			c.Logger,
 		)
 		c.Authenticators = append(c.Authenticators, c.Web3Auth)
 
 		c.Logger.Info("web3 authentication enabled",
-			zap.Duration("token_expiration", c.Config.Web3.TokenExpiration))
+			zap.Duration("token_expiration", c.Config.Web3.TokenExpiration),
+			zap.Duration("refresh_token_expiration", c.Config.Web3.RefreshTokenExpiration),
+			zap.String("tokens_driver", c.Config.Storage.Tokens.Driver),
+			zap.Int("signing_keys", len(c.Config.Web3.SigningKeys)))
 	}
 
+	// 客户端证书认证器
+	if clientCerts := c.Config.Server.ClientCerts; clientCerts.CAFile != "" {
+		c.CertAuth = infraAuth.NewCertificateAuthenticator(clientCerts, c.UserRepo, c.Logger)
+		c.Authenticators = append(c.Authenticators, c.CertAuth)
+
+		c.Logger.Info("client certificate authentication enabled",
+			zap.String("mode", clientCerts.Mode),
+			zap.Strings("map_by", clientCerts.MapBy),
+			zap.Strings("required_paths", clientCerts.RequiredPaths),
+			zap.Int("required_users", len(clientCerts.RequiredUsers)))
+	}
+
+	// Digest 认证器
+	if c.Config.Digest.Enabled {
+		digestAuth, err := infraAuth.NewDigestAuthenticator(c.Config.Digest, c.UserRepo, c.Logger)
+		if err != nil {
+			return fmt.Errorf("failed to create digest authenticator: %w", err)
+		}
+		c.DigestAuth = digestAuth
+		c.Authenticators = append(c.Authenticators, c.DigestAuth)
+
+		imported, err := c.DigestAuth.ImportConfigPasswords(context.Background(), c.Config.Users)
+		if err != nil {
+			return fmt.Errorf("failed to import digest credentials: %w", err)
+		}
+
+		c.Logger.Info("digest authentication enabled",
+			zap.String("realm", c.Config.Digest.Realm),
+			zap.Strings("algorithms", c.Config.Digest.Algorithms),
+			zap.Int("config_users", imported))
+	}
+
+	c.initAuthLimits()
+
 	c.Logger.Info("authenticators initialized",
 		zap.Int("count", len(c.Authenticators)))
 
 	return nil
 }
 
+// digestRealm 启用 Digest 认证时设置密码需要同时保存摘要的 realm
+func (c *Container) digestRealm() string {
+	if !c.Config.Digest.Enabled {
+		return ""
+	}
+	return c.Config.Digest.Realm
+}
+
+// initAuthLimits 初始化认证失败锁定和请求限流
+func (c *Container) initAuthLimits() {
+	security := c.Config.Security
+	c.AuthLimits = &middleware.AuthLimits{BehindProxy: security.BehindProxy}
+
+	if bf := security.BruteForce; bf.Enabled {
+		c.AuthLimits.IPThrottle = ratelimit.NewThrottle(bf.IPThreshold, bf.BaseDelay, bf.MaxDelay, bf.ResetAfter)
+		c.AuthLimits.UsernameThrottle = ratelimit.NewThrottle(bf.UsernameThreshold, bf.BaseDelay, bf.MaxDelay, bf.ResetAfter)
+
+		c.Logger.Info("brute-force protection enabled",
+			zap.Int("ip_threshold", bf.IPThreshold),
+			zap.Int("username_threshold", bf.UsernameThreshold),
+			zap.Duration("max_delay", bf.MaxDelay))
+	}
+
+	if rl := security.RateLimit; rl.Enabled {
+		c.AuthLimits.RateLimiter = ratelimit.NewLimiter(rl.RequestsPerSecond, rl.Burst)
+
+		c.Logger.Info("rate limiting enabled",
+			zap.Float64("requests_per_second", rl.RequestsPerSecond),
+			zap.Int("burst", rl.Burst))
+	}
+}
+
+// checkRPCChainID 检查 RPC 节点的链 ID 是否与 SIWE 配置一致
+func (c *Container) checkRPCChainID() {
+	ctx, cancel := context.WithTimeout(context.Background(), c.Config.Web3.RPC.Timeout)
+	defer cancel()
+
+	chainID, err := c.ContractWallet.ChainID(ctx)
+	if err != nil {
+		c.Logger.Warn("failed to query rpc chain id", zap.Error(err))
+		return
+	}
+
+	if chainID != c.Config.Web3.SIWE.ChainID {
+		c.Logger.Warn("rpc chain id does not match siwe chain id",
+			zap.Int64("rpc_chain_id", chainID),
+			zap.Int64("siwe_chain_id", c.Config.Web3.SIWE.ChainID))
+	}
+}
+
 // initServices 初始化服务
 func (c *Container) initServices() error {
+	// 存储驱动
+	storageDriver, err := storage.NewDriver(c.Config.WebDAV, c.Logger)
+	if err != nil {
+		return fmt.Errorf("failed to create storage driver: %w", err)
+	}
+	c.Storage = storageDriver
+
+	// 锁存储
+	lockCfg := c.Config.Storage.Locks
+	lockStore, err := lock.NewStore(lockCfg)
+	if err != nil {
+		return fmt.Errorf("failed to create lock store: %w", err)
+	}
+	c.Locks = lock.NewManager(lockStore, lockCfg.SweepInterval, c.Logger)
+
+	// 死属性存储
+	deadProps, err := storage.NewPropStore(c.Config.WebDAV, c.Storage, c.Logger)
+	if err != nil {
+		return fmt.Errorf("failed to create dead props store: %w", err)
+	}
+	c.DeadProps = deadProps
+
 	// WebDAV 服务
-	fileSystem := webdav.Dir(c.Config.WebDAV.Directory)
-	permissionChecker := permission.NewWebDAVChecker(fileSystem, c.Logger)
+	var tokenGates permission.TokenGateVerifier
+	if c.TokenGates != nil {
+		tokenGates = c.TokenGates
+	}
+	permissionChecker := permission.NewWebDAVChecker(c.Storage, c.Config.WebDAV.Prefix, tokenGates, c.Logger)
 
 	c.WebDAVService = service.NewWebDAVService(
 		c.Config,
+		c.Storage,
 		permissionChecker,
+		storage.NewUsageTracker(usageRescanInterval),
+		c.DeadProps,
+		c.Locks,
 		c.Logger,
 	)
 
-	c.Logger.Info("services initialized")
+	c.Logger.Info("services initialized",
+		zap.String("storage_driver", c.Storage.Name()),
+		zap.String("locks_driver", lockCfg.Driver))
 
 	return nil
 }
@@ -156,13 +415,43 @@ func (c *Container) initHandlers() error {
 
 	// Web3 处理器
 	if c.Web3Auth != nil {
+		if registration := c.Config.Web3.Registration; registration.Enabled {
+			c.Registrar = infraAuth.NewWalletRegistrar(registration, c.UserRepo, c.Storage, c.Logger)
+			c.Logger.Info("wallet self-registration enabled",
+				zap.String("directory", registration.Directory),
+				zap.Int("allowlist", len(registration.Allowlist)),
+				zap.Int("invite_codes", len(registration.InviteCodes)))
+		}
+
 		c.Web3Handler = handler.NewWeb3Handler(
 			c.Web3Auth,
 			c.UserRepo,
+			c.Registrar,
 			c.Logger,
 		)
+		c.JWKSHandler = handler.NewJWKSHandler(c.JWTManager, c.Logger)
+	}
+
+	// OIDC 处理器
+	if c.OIDCAuth != nil {
+		c.OIDCHandler = handler.NewOIDCHandler(c.OIDCAuth, c.Logger)
 	}
 
+	// 应用密码处理器
+	c.AppPasswords = handler.NewAppPasswordHandler(c.UserRepo, c.Logger)
+
+	// 两步验证处理器
+	c.TwoFactor = handler.NewTwoFactorHandler(c.UserRepo, c.Config.Security.TOTPIssuer, c.Logger)
+
+	// 登录身份处理器
+	c.Identities = handler.NewIdentityHandler(c.UserRepo, c.Web3Auth, c.Logger)
+
+	// 用户管理处理器
+	c.AdminHandler = handler.NewAdminHandler(c.UserRepo, c.PasswordHasher, c.digestRealm(), c.Logger)
+
+	// 锁管理处理器
+	c.LockHandler = handler.NewLockHandler(c.Locks, c.Logger)
+
 	// WebDAV 处理器
 	c.WebDAVHandler = handler.NewWebDAVHandler(c.WebDAVService, c.Logger)
 
@@ -173,12 +462,27 @@ func (c *Container) initHandlers() error {
 
 // initHTTP 初始化 HTTP
 func (c *Container) initHTTP() error {
+	// 未启用客户端证书时不能传入 nil 指针，否则接口值不为 nil
+	var certMatcher middleware.CertificateMatcher
+	if c.CertAuth != nil {
+		certMatcher = c.CertAuth
+	}
+
 	// 路由器
 	c.Router = http.NewRouter(
 		c.Config,
 		c.Authenticators,
+		c.AuthLimits,
+		certMatcher,
 		c.HealthHandler,
 		c.Web3Handler,
+		c.JWKSHandler,
+		c.OIDCHandler,
+		c.AppPasswords,
+		c.TwoFactor,
+		c.Identities,
+		c.AdminHandler,
+		c.LockHandler,
 		c.WebDAVHandler,
 		c.Logger,
 	)
@@ -193,6 +497,60 @@ func (c *Container) initHTTP() error {
 
 // Close 关闭容器
 func (c *Container) Close() error {
+	if closer, ok := c.UserRepo.(io.Closer); ok {
+		if err := closer.Close(); err != nil && c.Logger != nil {
+			c.Logger.Warn("failed to close user repository", zap.Error(err))
+		}
+	}
+
+	if c.ContractWallet != nil {
+		c.ContractWallet.Close()
+	}
+
+	if c.TokenGates != nil {
+		c.TokenGates.Close()
+	}
+
+	if c.LDAPAuth != nil {
+		c.LDAPAuth.Close()
+	}
+
+	if c.Revocations != nil {
+		if err := c.Revocations.Close(); err != nil && c.Logger != nil {
+			c.Logger.Warn("failed to close token revocation store", zap.Error(err))
+		}
+	}
+
+	if c.AuthLimits != nil {
+		if c.AuthLimits.IPThrottle != nil {
+			c.AuthLimits.IPThrottle.Close()
+		}
+		if c.AuthLimits.UsernameThrottle != nil {
+			c.AuthLimits.UsernameThrottle.Close()
+		}
+		if c.AuthLimits.RateLimiter != nil {
+			c.AuthLimits.RateLimiter.Close()
+		}
+	}
+
+	if c.Locks != nil {
+		if err := c.Locks.Close(); err != nil && c.Logger != nil {
+			c.Logger.Warn("failed to close lock store", zap.Error(err))
+		}
+	}
+
+	if c.DeadProps != nil {
+		if err := c.DeadProps.Close(); err != nil && c.Logger != nil {
+			c.Logger.Warn("failed to close dead props store", zap.Error(err))
+		}
+	}
+
+	if c.Storage != nil {
+		if err := c.Storage.Close(); err != nil && c.Logger != nil {
+			c.Logger.Warn("failed to close storage driver", zap.Error(err))
+		}
+	}
+
 	if c.Logger != nil {
 		c.Logger.Info("closing container")
 		_ = c.Logger.Sync()
@@ -2,6 +2,8 @@ package auth
 
 import (
 	"context"
+	"crypto/x509"
+
 	"github.com/yeying-community/webdav/internal/domain/user"
 )
 
@@ -21,6 +23,7 @@ type Authenticator interface {
 type BasicCredentials struct {
 	Username string
 	Password string
+	OTP      string // 两步验证码或恢复码，启用两步验证的用户使用账号密码时需要
 }
 
 // BearerCredentials Bearer Token 凭证
@@ -28,3 +31,26 @@ type BearerCredentials struct {
 	Token string
 }
 
+// CertificateCredentials 客户端证书凭证（TLS 握手中已验证）
+type CertificateCredentials struct {
+	Certificate *x509.Certificate
+}
+
+// DigestCredentials HTTP Digest 认证凭证（RFC 7616）
+type DigestCredentials struct {
+	Username  string
+	Realm     string
+	Nonce     string
+	URI       string
+	Response  string
+	Algorithm string
+	QOP       string
+	NC        string
+	CNonce    string
+	Opaque    string
+	UserHash  bool
+
+	// Method 和 RequestURI 来自请求本身，用于计算 HA2 和校验 uri 参数
+	Method     string
+	RequestURI string
+}
@@ -4,11 +4,26 @@ import (
 	"time"
 )
 
+const (
+	// ChallengeFormatSIWE EIP-4361 / CAIP-122 文本消息，钱包用 personal_sign 签名
+	ChallengeFormatSIWE = "siwe"
+
+	// ChallengeFormatEIP712 EIP-712 类型化数据，钱包用 eth_signTypedData_v4 签名
+	ChallengeFormatEIP712 = "eip712"
+)
+
 // Challenge Web3 认证挑战
 type Challenge struct {
 	Nonce     string
-	Message   string
-	Address   string
+	Format    string // siwe、eip712
+	Message   string // 待签名的文本消息，eip712 格式为 eth_signTypedData_v4 的 JSON 参数
+	Digest    string // eip712 格式的签名摘要（十六进制）
+	Statement string
+	Address   string // 钱包标识，以太坊为小写地址，其他链为 CAIP-10 账户标识
+	Domain    string // 消息绑定的站点域名
+	URI       string // 消息绑定的站点 URI
+	ChainID   string // CAIP-2 链标识
+	IssuedAt  time.Time
 	ExpiresAt time.Time
 }
 
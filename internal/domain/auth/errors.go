@@ -20,4 +20,22 @@ var (
 
 	// ErrInvalidChallenge 无效挑战信息
 	ErrInvalidChallenge = errors.New("invalid challenge")
+
+	// ErrTokenRevoked token 已被吊销
+	ErrTokenRevoked = errors.New("token revoked")
+
+	// ErrStaleNonce Digest 认证的 nonce 已过期，响应本身正确，客户端应使用新 nonce 重试
+	ErrStaleNonce = errors.New("stale nonce")
+
+	// ErrRegistrationNotAllowed 钱包不在注册名单中且未提供邀请码
+	ErrRegistrationNotAllowed = errors.New("registration not allowed")
+
+	// ErrInvalidInviteCode 无效的邀请码
+	ErrInvalidInviteCode = errors.New("invalid invite code")
+
+	// ErrUnsupportedChain 未启用的链
+	ErrUnsupportedChain = errors.New("unsupported chain")
+
+	// ErrUnsupportedFormat 不支持的挑战格式
+	ErrUnsupportedFormat = errors.New("unsupported challenge format")
 )
@@ -8,10 +8,18 @@ import (
 type Token struct {
 	Value     string
 	Address   string
+	ID        string // jti，用于吊销
+	Family    string // 同一次登录签发的令牌共享 family，登出时整体吊销
 	ExpiresAt time.Time
 	IssuedAt  time.Time
 }
 
+// TokenPair 访问令牌和刷新令牌
+type TokenPair struct {
+	Access  *Token
+	Refresh *Token
+}
+
 // IsExpired 是否过期
 func (t *Token) IsExpired() bool {
 	return time.Now().After(t.ExpiresAt)
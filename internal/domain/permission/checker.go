@@ -9,5 +9,8 @@ import (
 type Checker interface {
 	// Check 检查用户是否有权限执行操作
 	Check(ctx context.Context, user *user.User, path string, operation Operation) error
+
+	// CheckRules 只按用户的规则检查，不检查路径本身的状态（如父目录是否存在）
+	CheckRules(ctx context.Context, user *user.User, path string, operation Operation) error
 }
 
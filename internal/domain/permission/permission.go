@@ -10,22 +10,60 @@ const (
 	OperationDelete Operation = "DELETE"
 )
 
-// MapHTTPMethodToOperation 映射 HTTP 方法到操作
+// MapHTTPMethodToOperation 映射 HTTP 方法到操作（对源路径的主要操作）
 func MapHTTPMethodToOperation(method string) Operation {
 	switch method {
-	case "GET", "HEAD", "OPTIONS", "PROPFIND":
+	case "GET", "HEAD", "OPTIONS", "PROPFIND", "COPY":
 		return OperationRead
-	case "PUT", "PATCH", "PROPPATCH":
+	case "PUT", "PATCH", "PROPPATCH", "LOCK", "UNLOCK":
 		return OperationWrite
 	case "POST", "MKCOL":
 		return OperationCreate
-	case "DELETE":
+	case "DELETE", "MOVE":
 		return OperationDelete
 	default:
 		return OperationRead
 	}
 }
 
+// MapHTTPMethodToOperations 映射 HTTP 方法到源路径所需的全部操作
+//
+// COPY 需要读取源；MOVE 需要读取并删除源。目标路径的权限见 MapDestinationOperation。
+// LOCK 按目标是否存在区分，见 MapLockOperation。
+func MapHTTPMethodToOperations(method string) []Operation {
+	switch method {
+	case "MOVE":
+		return []Operation{OperationRead, OperationDelete}
+	default:
+		return []Operation{MapHTTPMethodToOperation(method)}
+	}
+}
+
+// RequiresDestination HTTP 方法是否带有 Destination 目标
+func RequiresDestination(method string) bool {
+	return method == "COPY" || method == "MOVE"
+}
+
+// MapDestinationOperation 映射 COPY/MOVE 目标路径所需的操作
+//
+// 目标已存在时为覆盖（更新），否则为创建。
+func MapDestinationOperation(exists bool) Operation {
+	if exists {
+		return OperationWrite
+	}
+	return OperationCreate
+}
+
+// MapLockOperation 映射 LOCK 所需的操作
+//
+// 锁定不存在的路径会创建空资源，需要创建权限；已存在时为更新。
+func MapLockOperation(exists bool) Operation {
+	if exists {
+		return OperationWrite
+	}
+	return OperationCreate
+}
+
 // MapOperationToPermission 映射操作到权限字符串
 func MapOperationToPermission(op Operation) string {
 	switch op {
@@ -0,0 +1,166 @@
+package user
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+)
+
+// AppPasswordPrefix 应用密码前缀，用于与账号密码区分
+const AppPasswordPrefix = "wdp_"
+
+var (
+	ErrAppPasswordNotFound = errors.New("app password not found")
+	ErrInvalidAppPassword  = errors.New("invalid app password")
+)
+
+// AppPassword 应用密码 / API 令牌
+//
+// 明文只在创建时返回一次，仓储中只保存 SHA-256 摘要。
+// 可以限制为只读或只能访问指定路径（按路径段前缀匹配）。
+type AppPassword struct {
+	ID        string
+	Name      string
+	Hash      string
+	ReadOnly  bool
+	Paths     []string
+	ExpiresAt *time.Time
+	CreatedAt time.Time
+}
+
+// Scope 通过应用密码认证的会话的访问范围
+type Scope struct {
+	AppPasswordID string
+	ReadOnly      bool
+	Paths         []string
+}
+
+// NewAppPassword 创建应用密码，返回应用密码和明文
+func NewAppPassword(name string, readOnly bool, paths []string, expiresAt *time.Time) (*AppPassword, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, "", ErrInvalidAppPassword
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, "", err
+	}
+	token := AppPasswordPrefix + base64.RawURLEncoding.EncodeToString(secret)
+
+	cleaned := make([]string, 0, len(paths))
+	for _, p := range paths {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		if !strings.HasPrefix(p, "/") {
+			p = "/" + p
+		}
+		cleaned = append(cleaned, p)
+	}
+
+	return &AppPassword{
+		ID:        generateID(),
+		Name:      name,
+		Hash:      HashAppPassword(token),
+		ReadOnly:  readOnly,
+		Paths:     cleaned,
+		ExpiresAt: expiresAt,
+		CreatedAt: time.Now(),
+	}, token, nil
+}
+
+// HashAppPassword 计算应用密码摘要
+//
+// 应用密码是 256 位随机值，不需要慢哈希；确定性的摘要可以直接用于查找。
+func HashAppPassword(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// IsAppPassword 是否为应用密码格式
+func IsAppPassword(password string) bool {
+	return strings.HasPrefix(password, AppPasswordPrefix)
+}
+
+// IsExpired 是否已过期
+func (p *AppPassword) IsExpired() bool {
+	return p.ExpiresAt != nil && time.Now().After(*p.ExpiresAt)
+}
+
+// Scope 应用密码对应的访问范围
+func (p *AppPassword) Scope() *Scope {
+	return &Scope{
+		AppPasswordID: p.ID,
+		ReadOnly:      p.ReadOnly,
+		Paths:         append([]string(nil), p.Paths...),
+	}
+}
+
+// IsRestricted 是否限制了权限或路径
+func (s *Scope) IsRestricted() bool {
+	return s.ReadOnly || len(s.Paths) > 0
+}
+
+// Allows 访问范围是否允许该操作
+func (s *Scope) Allows(path string, requiredPerm string) bool {
+	if s.ReadOnly && !strings.EqualFold(requiredPerm, "R") && !strings.EqualFold(requiredPerm, "READ") {
+		return false
+	}
+
+	if len(s.Paths) == 0 {
+		return true
+	}
+	for _, prefix := range s.Paths {
+		if matchPrefix(prefix, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// FindAppPassword 根据明文查找未过期的应用密码
+func (u *User) FindAppPassword(token string) (*AppPassword, bool) {
+	hash := HashAppPassword(token)
+	for _, p := range u.AppPasswords {
+		if p.Hash == hash && !p.IsExpired() {
+			return p, true
+		}
+	}
+	return nil, false
+}
+
+// AddAppPassword 添加应用密码
+func (u *User) AddAppPassword(p *AppPassword) {
+	u.AppPasswords = append(u.AppPasswords, p)
+	u.UpdatedAt = time.Now()
+}
+
+// RemoveAppPassword 删除应用密码
+func (u *User) RemoveAppPassword(id string) error {
+	for i, p := range u.AppPasswords {
+		if p.ID == id {
+			u.AppPasswords = append(u.AppPasswords[:i], u.AppPasswords[i+1:]...)
+			u.UpdatedAt = time.Now()
+			return nil
+		}
+	}
+	return ErrAppPasswordNotFound
+}
+
+// WithScope 返回限定访问范围的用户副本
+//
+// 受限的应用密码不继承管理员角色。
+func (u *User) WithScope(scope *Scope) *User {
+	c := u.Clone()
+	c.Scope = scope
+	if scope.IsRestricted() {
+		c.Role = RoleUser
+	}
+	return c
+}
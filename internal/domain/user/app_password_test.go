@@ -0,0 +1,164 @@
+package user
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUserWithScope(t *testing.T) {
+	tests := []struct {
+		name     string
+		readOnly bool
+		paths    []string
+		role     string
+		wantRole string
+		// 路径与权限到期望结果
+		access map[[2]string]bool
+	}{
+		{
+			name: "unrestricted keeps admin role", role: RoleAdmin, wantRole: RoleAdmin,
+			access: map[[2]string]bool{
+				{"/docs/a.txt", "R"}: true,
+				{"/docs/a.txt", "U"}: true,
+				{"/other", "D"}:      true,
+			},
+		},
+		{
+			name: "read-only", readOnly: true, role: RoleUser, wantRole: RoleUser,
+			access: map[[2]string]bool{
+				{"/docs/a.txt", "R"}:    true,
+				{"/docs/a.txt", "read"}: true,
+				{"/docs/a.txt", "C"}:    false,
+				{"/docs/a.txt", "U"}:    false,
+				{"/docs/a.txt", "D"}:    false,
+			},
+		},
+		{
+			name: "path restriction", paths: []string{"/docs", "/photos/2024/"}, role: RoleUser, wantRole: RoleUser,
+			access: map[[2]string]bool{
+				{"/docs", "R"}:              true,
+				{"/docs/a.txt", "U"}:        true,
+				{"/photos/2024/b.jpg", "D"}: true,
+				{"/docs2/a.txt", "R"}:       false,
+				{"/photos/2023/b.jpg", "R"}: false,
+				{"/", "R"}:                  false,
+			},
+		},
+		{
+			name: "read-only drops admin role", readOnly: true, role: RoleAdmin, wantRole: RoleUser,
+			access: map[[2]string]bool{
+				{"/docs/a.txt", "R"}: true,
+				{"/docs/a.txt", "U"}: false,
+			},
+		},
+		{
+			name: "path restriction drops admin role", paths: []string{"/docs"}, role: RoleAdmin, wantRole: RoleUser,
+			access: map[[2]string]bool{
+				{"/docs/a.txt", "U"}:  true,
+				{"/other/a.txt", "R"}: false,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := NewUser("alice", "/alice")
+			u.Role = tt.role
+			u.Permissions = ParsePermissions("CRUD")
+
+			p, _, err := NewAppPassword("sync", tt.readOnly, tt.paths, nil)
+			if err != nil {
+				t.Fatalf("NewAppPassword: %v", err)
+			}
+			scoped := u.WithScope(p.Scope())
+
+			if scoped.Role != tt.wantRole {
+				t.Fatalf("Role = %q, want %q", scoped.Role, tt.wantRole)
+			}
+			for k, want := range tt.access {
+				if got := scoped.CanAccess(k[0], k[1]); got != want {
+					t.Errorf("CanAccess(%q, %q) = %v, want %v", k[0], k[1], got, want)
+				}
+			}
+
+			// 原用户不受影响
+			if u.Scope != nil || u.Role != tt.role {
+				t.Fatal("WithScope modified the original user")
+			}
+		})
+	}
+}
+
+func TestUserWithScopeKeepsUserRules(t *testing.T) {
+	u := NewUser("alice", "/alice")
+	u.Permissions = ParsePermissions("CRUD")
+	u.Rules = []*Rule{{Path: "/docs/locked", Permissions: ParsePermissions("R")}}
+
+	p, _, err := NewAppPassword("sync", false, []string{"/docs"}, nil)
+	if err != nil {
+		t.Fatalf("NewAppPassword: %v", err)
+	}
+	scoped := u.WithScope(p.Scope())
+
+	// 访问范围只能进一步收紧用户自身的规则
+	if scoped.CanAccess("/docs/locked/a.txt", "U") {
+		t.Fatal("scope granted a permission the user's rules deny")
+	}
+	if !scoped.CanAccess("/docs/open/a.txt", "U") {
+		t.Fatal("scope denied a permission within its paths")
+	}
+}
+
+func TestFindAppPassword(t *testing.T) {
+	past := time.Now().Add(-time.Minute)
+	future := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name      string
+		expiresAt *time.Time
+		want      bool
+	}{
+		{name: "no expiry", expiresAt: nil, want: true},
+		{name: "not yet expired", expiresAt: &future, want: true},
+		{name: "expired", expiresAt: &past, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := NewUser("alice", "/alice")
+			p, token, err := NewAppPassword("sync", false, nil, tt.expiresAt)
+			if err != nil {
+				t.Fatalf("NewAppPassword: %v", err)
+			}
+			u.AddAppPassword(p)
+
+			found, ok := u.FindAppPassword(token)
+			if ok != tt.want {
+				t.Fatalf("FindAppPassword ok = %v, want %v", ok, tt.want)
+			}
+			if ok && found.ID != p.ID {
+				t.Fatalf("FindAppPassword = %q, want %q", found.ID, p.ID)
+			}
+			if _, ok := u.FindAppPassword(token + "x"); ok {
+				t.Fatal("FindAppPassword accepted a different token")
+			}
+		})
+	}
+}
+
+func TestNewAppPasswordPaths(t *testing.T) {
+	p, token, err := NewAppPassword(" sync ", true, []string{"docs", " /photos ", ""}, nil)
+	if err != nil {
+		t.Fatalf("NewAppPassword: %v", err)
+	}
+	if p.Name != "sync" || !IsAppPassword(token) || p.Hash != HashAppPassword(token) {
+		t.Fatalf("app password = %+v", p)
+	}
+	if len(p.Paths) != 2 || p.Paths[0] != "/docs" || p.Paths[1] != "/photos" {
+		t.Fatalf("Paths = %q, want [/docs /photos]", p.Paths)
+	}
+
+	if _, _, err := NewAppPassword("  ", false, nil, nil); err != ErrInvalidAppPassword {
+		t.Fatalf("err = %v, want ErrInvalidAppPassword", err)
+	}
+}
@@ -0,0 +1,61 @@
+package user
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidFingerprint   = errors.New("invalid certificate fingerprint")
+	ErrDuplicateFingerprint = errors.New("certificate fingerprint already exists")
+)
+
+// CertificateFingerprint 计算证书（DER 编码）的 SHA-256 指纹，小写十六进制
+func CertificateFingerprint(der []byte) string {
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:])
+}
+
+// NormalizeFingerprint 规范化指纹：去掉冒号和空格并转为小写，必须是 SHA-256 指纹
+func NormalizeFingerprint(fingerprint string) (string, error) {
+	fingerprint = strings.ToLower(strings.NewReplacer(":", "", " ", "").Replace(fingerprint))
+	if len(fingerprint) != sha256.Size*2 {
+		return "", ErrInvalidFingerprint
+	}
+	if _, err := hex.DecodeString(fingerprint); err != nil {
+		return "", ErrInvalidFingerprint
+	}
+	return fingerprint, nil
+}
+
+// SetCertificates 设置客户端证书指纹
+func (u *User) SetCertificates(fingerprints []string) error {
+	normalized := make([]string, 0, len(fingerprints))
+	seen := make(map[string]bool, len(fingerprints))
+	for _, fp := range fingerprints {
+		fp, err := NormalizeFingerprint(fp)
+		if err != nil {
+			return err
+		}
+		if !seen[fp] {
+			seen[fp] = true
+			normalized = append(normalized, fp)
+		}
+	}
+	u.Certificates = normalized
+	u.UpdatedAt = time.Now()
+	return nil
+}
+
+// HasCertificate 是否登记了该指纹的客户端证书
+func (u *User) HasCertificate(fingerprint string) bool {
+	for _, fp := range u.Certificates {
+		if fp == fingerprint {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,74 @@
+package user
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"time"
+)
+
+const (
+	// DigestSHA256 RFC 7616 SHA-256 摘要算法
+	DigestSHA256 = "SHA-256"
+
+	// DigestMD5 RFC 2617 MD5 摘要算法，用于不支持 SHA-256 的旧客户端
+	DigestMD5 = "MD5"
+)
+
+// DigestHA1 HTTP Digest 认证的 HA1 = H(username:realm:password)
+//
+// HA1 与明文密码等价，只在启用 Digest 认证时保存。
+type DigestHA1 struct {
+	Realm  string
+	MD5    string
+	SHA256 string
+}
+
+// NewDigestHA1 计算用户在 realm 下的 HA1
+func NewDigestHA1(username, realm, password string) *DigestHA1 {
+	data := username + ":" + realm + ":" + password
+	return &DigestHA1{
+		Realm:  realm,
+		MD5:    DigestHash(DigestMD5, data),
+		SHA256: DigestHash(DigestSHA256, data),
+	}
+}
+
+// Get 获取指定算法的 HA1，不支持的算法返回空字符串
+func (d *DigestHA1) Get(algorithm string) string {
+	switch algorithm {
+	case DigestMD5:
+		return d.MD5
+	case DigestSHA256:
+		return d.SHA256
+	default:
+		return ""
+	}
+}
+
+// DigestHash 按 Digest 算法计算十六进制摘要
+func DigestHash(algorithm, data string) string {
+	var h hash.Hash
+	switch algorithm {
+	case DigestMD5:
+		h = md5.New()
+	case DigestSHA256:
+		h = sha256.New()
+	default:
+		return ""
+	}
+	h.Write([]byte(data))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// SetDigestPassword 根据明文密码设置 Digest HA1
+func (u *User) SetDigestPassword(realm, password string) {
+	u.Digest = NewDigestHA1(u.Username, realm, password)
+	u.UpdatedAt = time.Now()
+}
+
+// HasDigest 是否有指定 realm 下的 HA1
+func (u *User) HasDigest(realm string) bool {
+	return u.Digest != nil && u.Digest.Realm == realm
+}
@@ -0,0 +1,115 @@
+package user
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrIdentityNotFound  = errors.New("identity not found")
+	ErrDuplicateIdentity = errors.New("identity already linked")
+	ErrLastSignInMethod  = errors.New("cannot remove the last sign-in method")
+)
+
+const (
+	// IdentityWallet 钱包，Subject 为钱包标识（见 WalletID）
+	IdentityWallet = "wallet"
+
+	// IdentityOIDC OIDC 身份，Subject 为 issuer 和 sub 的组合（预留）
+	IdentityOIDC = "oidc"
+)
+
+// Identity 关联到用户的登录身份
+//
+// 主钱包地址保存在 WalletAddress，其他钱包和外部身份保存在 Identities，
+// 任意一个关联的钱包都可以签名登录同一个用户。
+type Identity struct {
+	Provider  string
+	Subject   string
+	CreatedAt time.Time
+}
+
+// WalletAddresses 用户的所有钱包地址，主钱包在前
+func (u *User) WalletAddresses() []string {
+	primary := NormalizeWalletID(u.WalletAddress)
+	addresses := make([]string, 0, len(u.Identities)+1)
+	if primary != "" {
+		addresses = append(addresses, primary)
+	}
+	for _, identity := range u.Identities {
+		if identity.Provider == IdentityWallet && identity.Subject != primary {
+			addresses = append(addresses, identity.Subject)
+		}
+	}
+	return addresses
+}
+
+// HasWallet 钱包地址是否为用户的主钱包或已关联的钱包
+func (u *User) HasWallet(address string) bool {
+	address = NormalizeWalletID(address)
+	for _, existing := range u.WalletAddresses() {
+		if existing == address {
+			return true
+		}
+	}
+	return false
+}
+
+// LinkWallet 关联钱包，address 为 0x 地址或 CAIP-10 账户标识；用户还没有主钱包时设为主钱包
+func (u *User) LinkWallet(address string) error {
+	if !IsValidWalletID(address) {
+		return ErrInvalidAddress
+	}
+	if u.HasWallet(address) {
+		return ErrDuplicateIdentity
+	}
+
+	address = NormalizeWalletID(address)
+	if !u.HasWalletAddress() {
+		return u.SetWalletAddress(address)
+	}
+
+	u.Identities = append(u.Identities, &Identity{
+		Provider:  IdentityWallet,
+		Subject:   address,
+		CreatedAt: time.Now(),
+	})
+	u.UpdatedAt = time.Now()
+	return nil
+}
+
+// UnlinkWallet 取消关联钱包地址
+//
+// 移除主钱包时最早关联的钱包成为主钱包。用户没有密码、证书或其他钱包时不能移除最后一个钱包。
+func (u *User) UnlinkWallet(address string) error {
+	if !u.HasWallet(address) {
+		return ErrIdentityNotFound
+	}
+	if len(u.WalletAddresses()) == 1 && !u.HasPassword() && len(u.Certificates) == 0 {
+		return ErrLastSignInMethod
+	}
+
+	address = NormalizeWalletID(address)
+	identities := make([]*Identity, 0, len(u.Identities))
+	for _, identity := range u.Identities {
+		if identity.Provider == IdentityWallet && identity.Subject == address {
+			continue
+		}
+		identities = append(identities, identity)
+	}
+	u.Identities = identities
+
+	if NormalizeWalletID(u.WalletAddress) == address {
+		u.WalletAddress = ""
+		for i, identity := range u.Identities {
+			if identity.Provider == IdentityWallet {
+				u.WalletAddress = identity.Subject
+				u.Identities = append(u.Identities[:i], u.Identities[i+1:]...)
+				break
+			}
+		}
+	}
+
+	u.UpdatedAt = time.Now()
+	return nil
+}
@@ -10,9 +10,20 @@ type Repository interface {
 	// FindByWalletAddress 根据钱包地址查找用户
 	FindByWalletAddress(ctx context.Context, address string) (*User, error)
 	
+	// FindByAppPassword 根据应用密码摘要查找用户
+	FindByAppPassword(ctx context.Context, hash string) (*User, error)
+	
+	// FindByCertificate 根据客户端证书指纹查找用户
+	FindByCertificate(ctx context.Context, fingerprint string) (*User, error)
+	
 	// Save 保存用户
 	Save(ctx context.Context, user *User) error
 	
+	// UseTOTPStep 记录用户最近一次通过验证的 TOTP 时间步，step 不大于已记录的值时返回 ErrOTPReused
+	//
+	// 检查和记录是原子的，同一验证码不能被并发请求重复使用；Save 不会覆盖已记录的时间步。
+	UseTOTPStep(ctx context.Context, userID string, step int64) error
+	
 	// Delete 删除用户
 	Delete(ctx context.Context, username string) error
 	
@@ -0,0 +1,106 @@
+package user
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// patternCache 已编译的规则模式缓存（key 为 "regex:" 或 "glob:" 加模式）
+var patternCache sync.Map
+
+// cachedPattern 缓存的编译结果
+type cachedPattern struct {
+	re  *regexp.Regexp
+	err error
+}
+
+// compileRegex 编译并缓存正则规则
+func compileRegex(pattern string) (*regexp.Regexp, error) {
+	return compileCached("regex:"+pattern, func() (*regexp.Regexp, error) {
+		return regexp.Compile(pattern)
+	})
+}
+
+// compileGlob 编译并缓存 glob 规则
+func compileGlob(pattern string) (*regexp.Regexp, error) {
+	return compileCached("glob:"+pattern, func() (*regexp.Regexp, error) {
+		expr, err := globToRegex(pattern)
+		if err != nil {
+			return nil, err
+		}
+		return regexp.Compile(expr)
+	})
+}
+
+// compileCached 从缓存获取编译结果，不存在时编译
+func compileCached(key string, compile func() (*regexp.Regexp, error)) (*regexp.Regexp, error) {
+	if v, ok := patternCache.Load(key); ok {
+		p := v.(*cachedPattern)
+		return p.re, p.err
+	}
+
+	re, err := compile()
+	v, _ := patternCache.LoadOrStore(key, &cachedPattern{re: re, err: err})
+	p := v.(*cachedPattern)
+	return p.re, p.err
+}
+
+// globToRegex 将 glob 模式转换为锚定的正则表达式
+//
+// 支持 *（不跨越 /）、**（跨越任意层级，"**/" 可匹配零层）、? 和 [...] 字符类。
+// 不以 / 开头的模式视为从根目录开始。
+func globToRegex(pattern string) (string, error) {
+	if !strings.HasPrefix(pattern, "/") {
+		pattern = "/" + pattern
+	}
+
+	var b strings.Builder
+	b.WriteString("^")
+
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch c {
+		case '*':
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				i++
+				if i+1 < len(pattern) && pattern[i+1] == '/' {
+					i++
+					b.WriteString("(?:.*/)?")
+				} else {
+					b.WriteString(".*")
+				}
+			} else {
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				return "", fmt.Errorf("unterminated character class")
+			}
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += end + 1
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	b.WriteString("$")
+	return b.String(), nil
+}
+
+// matchPrefix 按路径段前缀匹配
+func matchPrefix(prefix, path string) bool {
+	prefix = "/" + strings.Trim(prefix, "/")
+	if prefix == "/" {
+		return true
+	}
+	return path == prefix || strings.HasPrefix(path, prefix+"/")
+}
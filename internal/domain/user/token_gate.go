@@ -0,0 +1,76 @@
+package user
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+const (
+	// TokenERC20 同质化代币，按余额判断
+	TokenERC20 = "erc20"
+
+	// TokenERC721 NFT，按持有数量或指定 token 的所有权判断
+	TokenERC721 = "erc721"
+)
+
+// TokenGate 规则的链上持有条件
+//
+// 带有持有条件的规则只对钱包满足条件的用户生效。数量均为最小单位的十进制整数。
+type TokenGate struct {
+	Standard   string // erc20、erc721
+	Contract   string // 合约地址
+	MinBalance string // 最小余额或持有数量，为空时为 1
+	TokenID    string // erc721 指定 token，设置后检查该 token 的所有者
+}
+
+// Validate 验证持有条件
+func (g *TokenGate) Validate() error {
+	switch strings.ToLower(g.Standard) {
+	case TokenERC20:
+		if g.TokenID != "" {
+			return fmt.Errorf("%w: token_id is only valid for erc721", ErrInvalidRule)
+		}
+	case TokenERC721:
+		if g.TokenID != "" {
+			if id, ok := new(big.Int).SetString(g.TokenID, 10); !ok || id.Sign() < 0 {
+				return fmt.Errorf("%w: invalid token_id %q", ErrInvalidRule, g.TokenID)
+			}
+			if g.MinBalance != "" {
+				return fmt.Errorf("%w: min_balance and token_id are mutually exclusive", ErrInvalidRule)
+			}
+		}
+	default:
+		return fmt.Errorf("%w: unsupported token standard %q", ErrInvalidRule, g.Standard)
+	}
+
+	if !IsValidAddress(g.Contract) {
+		return fmt.Errorf("%w: invalid token contract %q", ErrInvalidRule, g.Contract)
+	}
+	if g.MinBalance != "" {
+		if balance, ok := new(big.Int).SetString(g.MinBalance, 10); !ok || balance.Sign() <= 0 {
+			return fmt.Errorf("%w: min_balance must be a positive integer", ErrInvalidRule)
+		}
+	}
+	return nil
+}
+
+// Threshold 最小余额
+func (g *TokenGate) Threshold() *big.Int {
+	if balance, ok := new(big.Int).SetString(g.MinBalance, 10); ok {
+		return balance
+	}
+	return big.NewInt(1)
+}
+
+// ID 指定的 token，未指定时返回 nil
+func (g *TokenGate) ID() *big.Int {
+	if g.TokenID == "" {
+		return nil
+	}
+	id, ok := new(big.Int).SetString(g.TokenID, 10)
+	if !ok {
+		return nil
+	}
+	return id
+}
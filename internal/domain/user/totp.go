@@ -0,0 +1,69 @@
+package user
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+)
+
+// recoveryCodeCount 每次生成的恢复码数量
+const recoveryCodeCount = 10
+
+var (
+	ErrTwoFactorRequired = errors.New("two-factor authentication code required")
+	ErrInvalidOTP        = errors.New("invalid two-factor authentication code")
+	ErrOTPReused         = errors.New("two-factor authentication code already used")
+)
+
+// TOTP 基于时间的一次性密码（RFC 6238）
+//
+// 注册时先生成 Secret，用户用验证器扫码并提交一次验证码确认后才启用。
+// 恢复码只保存摘要，每个只能使用一次。
+type TOTP struct {
+	Secret        string // Base32 编码的共享密钥
+	Enabled       bool
+	RecoveryCodes []string // 恢复码摘要
+	EnabledAt     *time.Time
+}
+
+// HasTwoFactor 是否启用了两步验证
+func (u *User) HasTwoFactor() bool {
+	return u.TOTP != nil && u.TOTP.Enabled
+}
+
+// NewRecoveryCodes 生成恢复码，返回明文（形如 xxxxx-xxxxx）和摘要
+func NewRecoveryCodes() (codes []string, hashes []string, err error) {
+	for i := 0; i < recoveryCodeCount; i++ {
+		b := make([]byte, 5)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		code := hex.EncodeToString(b)
+		code = code[:5] + "-" + code[5:]
+		codes = append(codes, code)
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+	return codes, hashes, nil
+}
+
+// UseRecoveryCode 使用恢复码，匹配时从列表中移除
+func (t *TOTP) UseRecoveryCode(code string) bool {
+	hash := hashRecoveryCode(code)
+	for i, h := range t.RecoveryCodes {
+		if h == hash {
+			t.RecoveryCodes = append(t.RecoveryCodes[:i], t.RecoveryCodes[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// hashRecoveryCode 计算恢复码摘要，忽略大小写、空格和连字符
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
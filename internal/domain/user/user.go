@@ -1,7 +1,11 @@
 package user
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"regexp"
 	"strings"
 	"time"
 )
@@ -13,19 +17,42 @@ var (
 	ErrInvalidAddress    = errors.New("invalid wallet address")
 	ErrDuplicateUsername = errors.New("username already exists")
 	ErrDuplicateAddress  = errors.New("wallet address already exists")
+	ErrInvalidRole       = errors.New("invalid role")
+	ErrInvalidRule       = errors.New("invalid rule")
+)
+
+// addressPattern 以太坊地址格式（钱包或合约）
+var addressPattern = regexp.MustCompile(`^0x[0-9a-fA-F]{40}$`)
+
+const (
+	// RoleUser 普通用户
+	RoleUser = "user"
+
+	// RoleAdmin 管理员，可通过管理 API 管理用户
+	RoleAdmin = "admin"
 )
 
 // User 用户领域模型
 type User struct {
 	ID            string
 	Username      string
-	Password      string // 加密后的密码
-	WalletAddress string // 以太坊钱包地址
+	Password      string     // 加密后的密码
+	Digest        *DigestHA1 // HTTP Digest 认证摘要，nil 表示不能使用 Digest 认证
+	WalletAddress string     // 主钱包，以太坊为 0x 地址，其他链为 CAIP-10 账户标识
 	Directory     string
+	Role          string
+	Quota         int64 // 存储配额（字节），0 表示使用默认配额，负数表示不限
 	Permissions   *Permissions
 	Rules         []*Rule
+	AppPasswords  []*AppPassword
+	TOTP          *TOTP       // 两步验证，nil 表示未注册
+	Certificates  []string    // 客户端证书 SHA-256 指纹
+	Identities    []*Identity // 主钱包以外关联的身份
 	CreatedAt     time.Time
 	UpdatedAt     time.Time
+
+	// Scope 通过应用密码认证时的访问范围，不持久化
+	Scope *Scope
 }
 
 // Permissions 权限
@@ -37,10 +64,18 @@ type Permissions struct {
 }
 
 // Rule 权限规则
+//
+// 匹配方式：默认按路径段前缀匹配（/priv 匹配 /priv 和 /priv/a，不匹配 /private2）；
+// Regex 为 true 时按正则表达式匹配；Glob 为 true 时按 glob 模式匹配（* 不跨越 /，** 跨越任意层级）。
+// Deny 为 true 时为拒绝规则，拒绝其列出的权限（未列出任何权限时拒绝全部权限）。
+// Token 不为空时规则只对钱包满足链上持有条件的用户生效。
 type Rule struct {
 	Path        string
 	Permissions *Permissions
 	Regex       bool
+	Glob        bool
+	Deny        bool
+	Token       *TokenGate
 }
 
 // NewUser 创建新用户
@@ -50,6 +85,7 @@ func NewUser(username, directory string) *User {
 		ID:          generateID(),
 		Username:    username,
 		Directory:   directory,
+		Role:        RoleUser,
 		Permissions: DefaultPermissions(),
 		Rules:       make([]*Rule, 0),
 		CreatedAt:   now,
@@ -58,21 +94,99 @@ func NewUser(username, directory string) *User {
 }
 
 // SetPassword 设置密码
+//
+// 旧密码的 Digest HA1 随之失效，需要时由调用方使用 SetDigestPassword 重新设置。
 func (u *User) SetPassword(hashedPassword string) {
 	u.Password = hashedPassword
+	u.Digest = nil
 	u.UpdatedAt = time.Now()
 }
 
-// SetWalletAddress 设置钱包地址
+// SetWalletAddress 设置主钱包，address 为 0x 地址或 CAIP-10 账户标识
 func (u *User) SetWalletAddress(address string) error {
 	if address == "" {
 		return ErrInvalidAddress
 	}
-	u.WalletAddress = strings.ToLower(address)
+	u.WalletAddress = NormalizeWalletID(address)
 	u.UpdatedAt = time.Now()
 	return nil
 }
 
+// SetRole 设置角色
+func (u *User) SetRole(role string) error {
+	if !IsValidRole(role) {
+		return ErrInvalidRole
+	}
+	u.Role = role
+	u.UpdatedAt = time.Now()
+	return nil
+}
+
+// IsAdmin 是否为管理员
+func (u *User) IsAdmin() bool {
+	return u.Role == RoleAdmin
+}
+
+// Clone 深拷贝用户，用于在保存前修改而不影响仓储中的实例
+func (u *User) Clone() *User {
+	c := *u
+	if u.Permissions != nil {
+		perms := *u.Permissions
+		c.Permissions = &perms
+	}
+	c.Rules = make([]*Rule, 0, len(u.Rules))
+	for _, rule := range u.Rules {
+		r := *rule
+		if rule.Permissions != nil {
+			perms := *rule.Permissions
+			r.Permissions = &perms
+		}
+		if rule.Token != nil {
+			token := *rule.Token
+			r.Token = &token
+		}
+		c.Rules = append(c.Rules, &r)
+	}
+	c.AppPasswords = make([]*AppPassword, 0, len(u.AppPasswords))
+	for _, password := range u.AppPasswords {
+		p := *password
+		p.Paths = append([]string(nil), password.Paths...)
+		if password.ExpiresAt != nil {
+			expiresAt := *password.ExpiresAt
+			p.ExpiresAt = &expiresAt
+		}
+		c.AppPasswords = append(c.AppPasswords, &p)
+	}
+	c.Certificates = append([]string(nil), u.Certificates...)
+	c.Identities = make([]*Identity, 0, len(u.Identities))
+	for _, identity := range u.Identities {
+		i := *identity
+		c.Identities = append(c.Identities, &i)
+	}
+	if u.Digest != nil {
+		digest := *u.Digest
+		c.Digest = &digest
+	}
+	if u.TOTP != nil {
+		totp := *u.TOTP
+		totp.RecoveryCodes = append([]string(nil), u.TOTP.RecoveryCodes...)
+		c.TOTP = &totp
+	}
+	return &c
+}
+
+// QuotaLimit 计算生效的配额（字节），0 表示不限
+func (u *User) QuotaLimit(defaultQuota int64) int64 {
+	switch {
+	case u.Quota < 0:
+		return 0
+	case u.Quota > 0:
+		return u.Quota
+	default:
+		return defaultQuota
+	}
+}
+
 // HasPassword 是否设置了密码
 func (u *User) HasPassword() bool {
 	return u.Password != ""
@@ -83,19 +197,59 @@ func (u *User) HasWalletAddress() bool {
 	return u.WalletAddress != ""
 }
 
+// IsValidAddress 是否为 0x 开头的 40 位十六进制地址
+func IsValidAddress(address string) bool {
+	return addressPattern.MatchString(address)
+}
+
 // CanAccess 检查是否可以访问路径
+//
+// 规则按顺序匹配，第一条匹配的允许规则决定结果；匹配的拒绝规则若包含所需权限则直接拒绝，
+// 否则继续匹配后续规则。没有规则匹配时使用用户默认权限。
+// 通过应用密码认证时，还需在应用密码的访问范围之内。带有链上持有条件的规则被忽略。
 func (u *User) CanAccess(path string, requiredPerm string) bool {
+	return u.CanAccessWith(path, requiredPerm, nil)
+}
+
+// CanAccessWith 检查是否可以访问路径，holds 判断用户是否满足规则的链上持有条件
+//
+// 带有持有条件的规则只在 holds 返回 true 时参与匹配，holds 只对路径匹配的规则调用。
+func (u *User) CanAccessWith(path string, requiredPerm string, holds func(*TokenGate) bool) bool {
+	if u.Scope != nil && !u.Scope.Allows(path, requiredPerm) {
+		return false
+	}
+
 	// 先检查规则
 	for _, rule := range u.Rules {
-		if rule.Matches(path) {
-			return rule.HasPermission(requiredPerm)
+		if !rule.Matches(path) {
+			continue
+		}
+		if rule.Token != nil && (holds == nil || !holds(rule.Token)) {
+			continue
 		}
+
+		if rule.Deny {
+			if rule.Denies(requiredPerm) {
+				return false
+			}
+			continue
+		}
+
+		return rule.HasPermission(requiredPerm)
 	}
 
 	// 使用默认权限
+	if u.Permissions == nil {
+		return false
+	}
 	return u.Permissions.Has(requiredPerm)
 }
 
+// IsValidRole 是否为有效角色
+func IsValidRole(role string) bool {
+	return role == RoleUser || role == RoleAdmin
+}
+
 // DefaultPermissions 默认权限（只读）
 func DefaultPermissions() *Permissions {
 	return &Permissions{
@@ -165,19 +319,73 @@ func (p *Permissions) Has(perm string) bool {
 
 // Matches 规则是否匹配路径
 func (r *Rule) Matches(path string) bool {
-	if r.Regex {
-		// TODO: 实现正则匹配
-		return false
+	switch {
+	case r.Regex:
+		re, err := compileRegex(r.Path)
+		if err != nil {
+			return false
+		}
+		return re.MatchString(path)
+	case r.Glob:
+		re, err := compileGlob(r.Path)
+		if err != nil {
+			return false
+		}
+		return re.MatchString(path)
+	default:
+		return matchPrefix(r.Path, path)
 	}
-	return strings.HasPrefix(path, r.Path)
 }
 
 // HasPermission 规则是否有权限
 func (r *Rule) HasPermission(perm string) bool {
+	if r.Permissions == nil {
+		return false
+	}
+	return r.Permissions.Has(perm)
+}
+
+// Denies 拒绝规则是否拒绝该权限
+func (r *Rule) Denies(perm string) bool {
+	if !r.Deny {
+		return false
+	}
+	// 未列出权限的拒绝规则拒绝全部权限
+	if r.Permissions == nil || r.Permissions.String() == "" {
+		return true
+	}
 	return r.Permissions.Has(perm)
 }
 
-// generateID 生成用户 ID
+// Validate 验证规则
+func (r *Rule) Validate() error {
+	if r.Path == "" {
+		return fmt.Errorf("%w: path is required", ErrInvalidRule)
+	}
+	if r.Regex && r.Glob {
+		return fmt.Errorf("%w: regex and glob are mutually exclusive", ErrInvalidRule)
+	}
+	if r.Token != nil {
+		if err := r.Token.Validate(); err != nil {
+			return err
+		}
+	}
+	if r.Regex {
+		if _, err := compileRegex(r.Path); err != nil {
+			return fmt.Errorf("%w: invalid regex %q: %v", ErrInvalidRule, r.Path, err)
+		}
+	}
+	if r.Glob {
+		if _, err := compileGlob(r.Path); err != nil {
+			return fmt.Errorf("%w: invalid glob %q: %v", ErrInvalidRule, r.Path, err)
+		}
+	}
+	return nil
+}
+
+// generateID 生成用户 ID（时间戳 + 随机后缀，避免同一秒内创建的用户冲突）
 func generateID() string {
-	return time.Now().Format("20060102150405")
+	suffix := make([]byte, 4)
+	_, _ = rand.Read(suffix)
+	return time.Now().Format("20060102150405") + "-" + hex.EncodeToString(suffix)
 }
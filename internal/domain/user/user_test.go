@@ -0,0 +1,265 @@
+package user
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestUserCanAccess(t *testing.T) {
+	allow := func(path, perms string) *Rule {
+		return &Rule{Path: path, Permissions: ParsePermissions(perms)}
+	}
+	deny := func(path, perms string) *Rule {
+		return &Rule{Path: path, Permissions: ParsePermissions(perms), Deny: true}
+	}
+	regex := func(r *Rule) *Rule {
+		r.Regex = true
+		return r
+	}
+	glob := func(r *Rule) *Rule {
+		r.Glob = true
+		return r
+	}
+
+	tests := []struct {
+		name     string
+		defaults string
+		rules    []*Rule
+		path     string
+		perm     string
+		want     bool
+	}{
+		// 首个匹配的规则生效
+		{
+			name:  "first match wins over later broader rule",
+			rules: []*Rule{allow("/docs/public", "R"), allow("/docs", "CRUD")},
+			path:  "/docs/public/a.txt", perm: "U", want: false,
+		},
+		{
+			name:  "later rule applies when first does not match",
+			rules: []*Rule{allow("/docs/public", "R"), allow("/docs", "CRUD")},
+			path:  "/docs/private/a.txt", perm: "U", want: true,
+		},
+		{
+			name:     "matching allow rule without permission does not fall through",
+			defaults: "CRUD",
+			rules:    []*Rule{allow("/readonly", "R")},
+			path:     "/readonly/a.txt", perm: "D", want: false,
+		},
+
+		// 拒绝规则
+		{
+			name:     "deny without permissions denies everything",
+			defaults: "CRUD",
+			rules:    []*Rule{deny("/secret", "")},
+			path:     "/secret/key", perm: "R", want: false,
+		},
+		{
+			name:     "deny with listed permissions denies only those",
+			defaults: "CRUD",
+			rules:    []*Rule{deny("/archive", "UD")},
+			path:     "/archive/2024.tar", perm: "D", want: false,
+		},
+		{
+			name:     "deny with listed permissions falls through for others",
+			defaults: "CRUD",
+			rules:    []*Rule{deny("/archive", "UD")},
+			path:     "/archive/2024.tar", perm: "R", want: true,
+		},
+		{
+			name:  "deny falls through to the next matching rule",
+			rules: []*Rule{deny("/shared", "D"), allow("/shared", "CRU")},
+			path:  "/shared/a.txt", perm: "U", want: true,
+		},
+		{
+			name:  "deny before allow wins",
+			rules: []*Rule{deny("/shared/locked", ""), allow("/shared", "CRUD")},
+			path:  "/shared/locked/a.txt", perm: "R", want: false,
+		},
+
+		// 按路径段的前缀匹配
+		{
+			name:     "prefix matches the path itself",
+			defaults: "CRUD",
+			rules:    []*Rule{deny("/priv", "")},
+			path:     "/priv", perm: "R", want: false,
+		},
+		{
+			name:     "prefix matches descendants",
+			defaults: "CRUD",
+			rules:    []*Rule{deny("/priv", "")},
+			path:     "/priv/a.txt", perm: "R", want: false,
+		},
+		{
+			name:     "prefix does not match sibling with same leading characters",
+			defaults: "CRUD",
+			rules:    []*Rule{deny("/priv", "")},
+			path:     "/private2/a.txt", perm: "R", want: true,
+		},
+		{
+			name:     "prefix with trailing slash",
+			defaults: "CRUD",
+			rules:    []*Rule{deny("/priv/", "")},
+			path:     "/priv/a.txt", perm: "R", want: false,
+		},
+		{
+			name:     "root prefix matches everything",
+			defaults: "CRUD",
+			rules:    []*Rule{allow("/", "R")},
+			path:     "/any/where", perm: "U", want: false,
+		},
+
+		// 正则
+		{
+			name:     "regex matches",
+			defaults: "CRUD",
+			rules:    []*Rule{regex(deny(`\.bak$`, "R"))},
+			path:     "/data/db.bak", perm: "R", want: false,
+		},
+		{
+			name:     "regex does not match",
+			defaults: "CRUD",
+			rules:    []*Rule{regex(deny(`\.bak$`, "R"))},
+			path:     "/data/db.bak.txt", perm: "R", want: true,
+		},
+		{
+			name:  "anchored regex grants access",
+			rules: []*Rule{regex(allow(`^/users/[0-9]+/`, "CRUD"))},
+			path:  "/users/42/notes.md", perm: "C", want: true,
+		},
+
+		// glob：* 不跨越 /，** 跨越任意层级
+		{
+			name:     "single star matches within one segment",
+			defaults: "CRUD",
+			rules:    []*Rule{glob(deny("/tmp/*.tmp", ""))},
+			path:     "/tmp/a.tmp", perm: "R", want: false,
+		},
+		{
+			name:     "single star does not cross segments",
+			defaults: "CRUD",
+			rules:    []*Rule{glob(deny("/tmp/*.tmp", ""))},
+			path:     "/tmp/sub/a.tmp", perm: "R", want: true,
+		},
+		{
+			name:     "double star crosses segments",
+			defaults: "CRUD",
+			rules:    []*Rule{glob(deny("**/*.tmp", ""))},
+			path:     "/a/b/c/x.tmp", perm: "R", want: false,
+		},
+		{
+			name:     "double star slash matches zero segments",
+			defaults: "CRUD",
+			rules:    []*Rule{glob(deny("**/*.tmp", ""))},
+			path:     "/x.tmp", perm: "R", want: false,
+		},
+		{
+			name:     "question mark matches one character",
+			defaults: "CRUD",
+			rules:    []*Rule{glob(deny("/log?.txt", ""))},
+			path:     "/log10.txt", perm: "R", want: true,
+		},
+		{
+			name:     "character class",
+			defaults: "CRUD",
+			rules:    []*Rule{glob(deny("/[!a]*.txt", ""))},
+			path:     "/b.txt", perm: "R", want: false,
+		},
+
+		// 默认权限
+		{
+			name:     "falls through to default permissions",
+			defaults: "R",
+			rules:    []*Rule{allow("/uploads", "CRUD")},
+			path:     "/other/a.txt", perm: "R", want: true,
+		},
+		{
+			name:     "default permissions deny missing permission",
+			defaults: "R",
+			rules:    []*Rule{allow("/uploads", "CRUD")},
+			path:     "/other/a.txt", perm: "C", want: false,
+		},
+		{
+			name: "no rules and no permissions",
+			path: "/a.txt", perm: "R", want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := NewUser("alice", "/alice")
+			u.Permissions = ParsePermissions(tt.defaults)
+			u.Rules = tt.rules
+
+			if got := u.CanAccess(tt.path, tt.perm); got != tt.want {
+				t.Errorf("CanAccess(%q, %q) = %v, want %v", tt.path, tt.perm, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUserCanAccessTokenGate(t *testing.T) {
+	gate := &TokenGate{Standard: TokenERC20, Contract: "0x1111111111111111111111111111111111111111"}
+	u := NewUser("alice", "/alice")
+	u.Permissions = ParsePermissions("R")
+	u.Rules = []*Rule{{Path: "/members", Permissions: ParsePermissions("CRUD"), Token: gate}}
+
+	tests := []struct {
+		name  string
+		holds func(*TokenGate) bool
+		want  bool
+	}{
+		{name: "no verifier", holds: nil, want: false},
+		{name: "holder", holds: func(*TokenGate) bool { return true }, want: true},
+		{name: "non-holder", holds: func(*TokenGate) bool { return false }, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := u.CanAccessWith("/members/a.txt", "U", tt.holds); got != tt.want {
+				t.Errorf("CanAccessWith = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRuleValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    *Rule
+		wantErr bool
+	}{
+		{name: "prefix", rule: &Rule{Path: "/docs"}},
+		{name: "regex", rule: &Rule{Path: `^/a/[0-9]+$`, Regex: true}},
+		{name: "glob", rule: &Rule{Path: "**/*.tmp", Glob: true}},
+		{name: "empty path", rule: &Rule{Path: ""}, wantErr: true},
+		{name: "invalid regex", rule: &Rule{Path: "(unclosed", Regex: true}, wantErr: true},
+		{name: "invalid glob", rule: &Rule{Path: "/a/[bc", Glob: true}, wantErr: true},
+		{name: "regex and glob", rule: &Rule{Path: "/a", Regex: true, Glob: true}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.rule.Validate()
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidRule) {
+					t.Fatalf("Validate() = %v, want ErrInvalidRule", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Validate() = %v", err)
+			}
+		})
+	}
+}
+
+func TestInvalidRuleNeverMatches(t *testing.T) {
+	u := NewUser("alice", "/alice")
+	u.Permissions = ParsePermissions("CRUD")
+	u.Rules = []*Rule{{Path: "(unclosed", Regex: true, Deny: true}}
+
+	if !u.CanAccess("/(unclosed", "R") {
+		t.Error("invalid regex rule should not match")
+	}
+}
@@ -0,0 +1,94 @@
+package user
+
+import (
+	"regexp"
+	"strings"
+)
+
+const (
+	// ChainEthereum 以太坊及其他 EVM 链
+	ChainEthereum = "ethereum"
+
+	// ChainSolana Solana
+	ChainSolana = "solana"
+
+	// ChainBitcoin 比特币
+	ChainBitcoin = "bitcoin"
+)
+
+const (
+	// NamespaceEIP155 EVM 链的 CAIP-2 命名空间
+	NamespaceEIP155 = "eip155"
+
+	// NamespaceSolana Solana 的 CAIP-2 命名空间
+	NamespaceSolana = "solana"
+
+	// NamespaceBIP122 比特币的 CAIP-2 命名空间
+	NamespaceBIP122 = "bip122"
+)
+
+// accountIDPattern CAIP-10 账户标识格式：namespace:reference:address
+var accountIDPattern = regexp.MustCompile(`^([-a-z0-9]{3,8}):([-_a-zA-Z0-9]{1,32}):([-.%a-zA-Z0-9]{1,128})$`)
+
+// AccountID CAIP-10 链上账户标识
+type AccountID struct {
+	Namespace string // CAIP-2 命名空间，如 eip155、solana、bip122
+	Reference string // CAIP-2 链标识，如 1
+	Address   string
+}
+
+// ParseAccountID 解析 CAIP-10 账户标识
+func ParseAccountID(s string) (AccountID, bool) {
+	m := accountIDPattern.FindStringSubmatch(s)
+	if m == nil {
+		return AccountID{}, false
+	}
+	return AccountID{Namespace: m[1], Reference: m[2], Address: m[3]}, true
+}
+
+// String CAIP-10 格式
+func (a AccountID) String() string {
+	return a.Namespace + ":" + a.Reference + ":" + a.Address
+}
+
+// WalletID 用户仓储中的钱包标识
+//
+// EVM 地址在所有 EVM 链上相同，保存为小写的 0x 地址（与只支持以太坊时的记录兼容）；
+// 其他链保存为 CAIP-10 账户标识，如 solana:5eykt4UsFv8P8NJdTREpY1vzqKqZKvdp:<address>。
+func WalletID(namespace, reference, address string) string {
+	if namespace == NamespaceEIP155 {
+		return strings.ToLower(address)
+	}
+	return AccountID{Namespace: namespace, Reference: reference, Address: address}.String()
+}
+
+// NormalizeWalletID 规范化钱包标识，0x 地址和 eip155 账户转为小写地址，其他标识原样返回
+//
+// 非 EVM 地址（如 base58）区分大小写，由调用方按链的规则规范化后再组成标识。
+func NormalizeWalletID(id string) string {
+	id = strings.TrimSpace(id)
+	if IsValidAddress(id) {
+		return strings.ToLower(id)
+	}
+	if account, ok := ParseAccountID(id); ok && account.Namespace == NamespaceEIP155 && IsValidAddress(account.Address) {
+		return strings.ToLower(account.Address)
+	}
+	return id
+}
+
+// IsValidWalletID 是否为 0x 地址或 CAIP-10 账户标识
+func IsValidWalletID(id string) bool {
+	if IsValidAddress(id) {
+		return true
+	}
+	_, ok := ParseAccountID(id)
+	return ok
+}
+
+// WalletAddressOf 钱包标识中的链上地址
+func WalletAddressOf(id string) string {
+	if account, ok := ParseAccountID(id); ok {
+		return account.Address
+	}
+	return id
+}
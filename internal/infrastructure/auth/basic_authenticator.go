@@ -11,23 +11,35 @@ import (
 )
 
 // BasicAuthenticator Basic 认证器
+//
+// 除账号密码外也接受应用密码：Basic 认证的密码字段，或以 Bearer 方式直接携带。
+// 启用两步验证的用户使用账号密码时还需提供验证码。
 type BasicAuthenticator struct {
 	userRepo       user.Repository
 	passwordHasher *crypto.PasswordHasher
+	twoFactor      *twoFactor
 	noPassword     bool
+	digestRealm    string
 	logger         *zap.Logger
 }
 
 // NewBasicAuthenticator 创建 Basic 认证器
+//
+// 密码登录成功后，以过时算法或参数保存的密码按 passwordHasher 的配置重新哈希；
+// digestRealm 非空时为还没有 Digest 摘要的用户补全摘要。
 func NewBasicAuthenticator(
 	userRepo user.Repository,
+	passwordHasher *crypto.PasswordHasher,
 	noPassword bool,
+	digestRealm string,
 	logger *zap.Logger,
 ) *BasicAuthenticator {
 	return &BasicAuthenticator{
 		userRepo:       userRepo,
-		passwordHasher: crypto.NewPasswordHasher(),
+		passwordHasher: passwordHasher,
+		twoFactor:      newTwoFactor(userRepo, logger),
 		noPassword:     noPassword,
+		digestRealm:    digestRealm,
 		logger:         logger,
 	}
 }
@@ -39,6 +51,10 @@ func (a *BasicAuthenticator) Name() string {
 
 // Authenticate 认证用户
 func (a *BasicAuthenticator) Authenticate(ctx context.Context, credentials interface{}) (*user.User, error) {
+	if bearer, ok := credentials.(*auth.BearerCredentials); ok && user.IsAppPassword(bearer.Token) {
+		return a.authenticateToken(ctx, bearer.Token)
+	}
+	
 	creds, ok := credentials.(*auth.BasicCredentials)
 	if !ok {
 		return nil, fmt.Errorf("invalid credentials type")
@@ -55,6 +71,16 @@ func (a *BasicAuthenticator) Authenticate(ctx context.Context, credentials inter
 		return nil, fmt.Errorf("failed to find user: %w", err)
 	}
 	
+	// 应用密码
+	if user.IsAppPassword(creds.Password) {
+		if password, ok := u.FindAppPassword(creds.Password); ok {
+			a.logger.Info("user authenticated via app password",
+				zap.String("username", u.Username),
+				zap.String("app_password", password.Name))
+			return u.WithScope(password.Scope()), nil
+		}
+	}
+	
 	// 如果启用了无密码模式，直接返回
 	if a.noPassword {
 		a.logger.Info("user authenticated (no password mode)",
@@ -76,15 +102,91 @@ func (a *BasicAuthenticator) Authenticate(ctx context.Context, credentials inter
 		return nil, user.ErrInvalidPassword
 	}
 	
+	// 两步验证（应用密码不需要）
+	if err := a.twoFactor.verify(ctx, u, creds.OTP); err != nil {
+		return nil, err
+	}
+	
+	a.upgradeCredentials(ctx, u, creds.Password)
+	
 	a.logger.Info("user authenticated via basic auth",
 		zap.String("username", u.Username))
 	
 	return u, nil
 }
 
+// upgradeCredentials 重新哈希过时的密码并补全 Digest 摘要，失败不影响本次认证
+func (a *BasicAuthenticator) upgradeCredentials(ctx context.Context, u *user.User, password string) {
+	rehash := a.passwordHasher.NeedsRehash(u.Password)
+	saveDigest := a.digestRealm != "" && !u.HasDigest(a.digestRealm)
+	if !rehash && !saveDigest {
+		return
+	}
+	
+	updated := u.Clone()
+	if rehash {
+		hashed, err := a.passwordHasher.Hash(password)
+		if err != nil {
+			a.logger.Warn("failed to rehash password",
+				zap.String("username", u.Username),
+				zap.Error(err))
+			return
+		}
+		// SetPassword 会清除 Digest 摘要，重新哈希不改变密码，保留原摘要
+		digest := updated.Digest
+		updated.SetPassword(hashed)
+		updated.Digest = digest
+	}
+	if saveDigest {
+		updated.SetDigestPassword(a.digestRealm, password)
+	}
+	
+	if err := a.userRepo.Save(ctx, updated); err != nil {
+		a.logger.Warn("failed to save upgraded credentials",
+			zap.String("username", u.Username),
+			zap.Error(err))
+		return
+	}
+	
+	a.logger.Info("user credentials upgraded",
+		zap.String("username", u.Username),
+		zap.Bool("rehashed", rehash),
+		zap.Bool("digest", saveDigest))
+}
+
 // CanHandle 是否可以处理该凭证
 func (a *BasicAuthenticator) CanHandle(credentials interface{}) bool {
-	_, ok := credentials.(*auth.BasicCredentials)
-	return ok
+	switch creds := credentials.(type) {
+	case *auth.BasicCredentials:
+		return true
+	case *auth.BearerCredentials:
+		return user.IsAppPassword(creds.Token)
+	default:
+		return false
+	}
+}
+
+// authenticateToken 以 Bearer 方式携带的应用密码认证
+func (a *BasicAuthenticator) authenticateToken(ctx context.Context, token string) (*user.User, error) {
+	u, err := a.userRepo.FindByAppPassword(ctx, user.HashAppPassword(token))
+	if err != nil {
+		if err == user.ErrUserNotFound {
+			return nil, auth.ErrInvalidToken
+		}
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+	
+	password, ok := u.FindAppPassword(token)
+	if !ok {
+		a.logger.Warn("app password expired",
+			zap.String("username", u.Username))
+		return nil, auth.ErrTokenExpired
+	}
+	
+	a.logger.Info("user authenticated via app password",
+		zap.String("username", u.Username),
+		zap.String("app_password", password.Name))
+	
+	return u.WithScope(password.Scope()), nil
 }
 
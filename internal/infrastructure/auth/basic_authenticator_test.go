@@ -0,0 +1,141 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yeying-community/webdav/internal/domain/auth"
+	"github.com/yeying-community/webdav/internal/domain/user"
+	"github.com/yeying-community/webdav/internal/infrastructure/config"
+	"github.com/yeying-community/webdav/internal/infrastructure/crypto"
+	"github.com/yeying-community/webdav/internal/infrastructure/repository"
+	"go.uber.org/zap"
+)
+
+func TestBasicAuthenticatorUpgradesLegacyHash(t *testing.T) {
+	// 由 openssl passwd -apr1 和 openssl sha1 生成，密码分别为 correct horse 和 secret
+	tests := []struct {
+		name     string
+		hash     string
+		password string
+	}{
+		{name: "apr1", hash: "$apr1$r31Y7ZyV$vxKjPfozOm7Hcs3nYN1po0", password: "correct horse"},
+		{name: "ssha", hash: "{SSHA}+peAPwH7og7bRdqLIyCCVNKugb1TQUxUc2FsdA==", password: "secret"},
+	}
+
+	cfg := config.DefaultConfig().Security.PasswordHash
+	cfg.Argon2id = config.Argon2idConfig{Memory: 1024, Iterations: 1, Parallelism: 1}
+	hasher := crypto.NewPasswordHasherWithConfig(cfg)
+
+	for _, tt := range tests {
+		for name, newRepo := range twoFactorRepos {
+			t.Run(tt.name+"/"+name, func(t *testing.T) {
+				ctx := context.Background()
+				repo := newRepo(t)
+				u := user.NewUser("alice", "/alice")
+				u.Password = tt.hash
+				if err := repo.Save(ctx, u); err != nil {
+					t.Fatalf("Save: %v", err)
+				}
+				a := NewBasicAuthenticator(repo, hasher, false, "", zap.NewNop())
+
+				stored := func() string {
+					t.Helper()
+					u, err := repo.FindByUsername(ctx, "alice")
+					if err != nil {
+						t.Fatalf("FindByUsername: %v", err)
+					}
+					return u.Password
+				}
+
+				// 登录失败不修改哈希
+				_, err := a.Authenticate(ctx, &auth.BasicCredentials{Username: "alice", Password: "wrong"})
+				if !errors.Is(err, user.ErrInvalidPassword) {
+					t.Fatalf("err = %v, want ErrInvalidPassword", err)
+				}
+				if got := stored(); got != tt.hash {
+					t.Fatalf("password = %q after a failed login, want unchanged", got)
+				}
+
+				// 登录成功后按首选算法重新哈希
+				if _, err := a.Authenticate(ctx, &auth.BasicCredentials{Username: "alice", Password: tt.password}); err != nil {
+					t.Fatalf("Authenticate: %v", err)
+				}
+				upgraded := stored()
+				if !strings.HasPrefix(upgraded, "{argon2id}") || hasher.NeedsRehash(upgraded) {
+					t.Fatalf("password = %q, want an argon2id hash with the current parameters", upgraded)
+				}
+
+				// 新哈希仍可登录，且不再重复升级
+				if _, err := a.Authenticate(ctx, &auth.BasicCredentials{Username: "alice", Password: tt.password}); err != nil {
+					t.Fatalf("Authenticate after upgrade: %v", err)
+				}
+				if got := stored(); got != upgraded {
+					t.Fatal("password rehashed again after the upgrade")
+				}
+			})
+		}
+	}
+}
+
+func TestBasicAuthenticatorAppPasswords(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoryUserRepository(nil)
+	hasher := crypto.NewPasswordHasher()
+
+	past := time.Now().Add(-time.Minute)
+	scoped, scopedToken, _ := user.NewAppPassword("sync", true, []string{"/docs"}, nil)
+	expired, expiredToken, _ := user.NewAppPassword("old", false, nil, &past)
+
+	u := user.NewUser("alice", "/alice")
+	u.Role = user.RoleAdmin
+	u.Password, _ = hasher.Hash("secret")
+	u.AddAppPassword(scoped)
+	u.AddAppPassword(expired)
+	if err := repo.Save(ctx, u); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	a := NewBasicAuthenticator(repo, hasher, false, "", zap.NewNop())
+
+	tests := []struct {
+		name    string
+		creds   interface{}
+		wantErr error
+	}{
+		{name: "basic", creds: &auth.BasicCredentials{Username: "alice", Password: scopedToken}},
+		{name: "bearer", creds: &auth.BearerCredentials{Token: scopedToken}},
+		// 过期的应用密码不能作为账号密码使用
+		{name: "expired basic", creds: &auth.BasicCredentials{Username: "alice", Password: expiredToken}, wantErr: user.ErrInvalidPassword},
+		{name: "expired bearer", creds: &auth.BearerCredentials{Token: expiredToken}, wantErr: auth.ErrTokenExpired},
+		{name: "unknown bearer", creds: &auth.BearerCredentials{Token: user.AppPasswordPrefix + "unknown"}, wantErr: auth.ErrInvalidToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := a.Authenticate(ctx, tt.creds)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Authenticate: %v", err)
+			}
+
+			// 受限的应用密码只读、限定路径且不继承管理员角色
+			if got.Scope == nil || got.Scope.AppPasswordID != scoped.ID {
+				t.Fatalf("Scope = %+v, want the app password scope", got.Scope)
+			}
+			if got.IsAdmin() {
+				t.Fatal("restricted app password kept the admin role")
+			}
+			if !got.CanAccess("/docs/a.txt", "R") || got.CanAccess("/docs/a.txt", "U") || got.CanAccess("/other/a.txt", "R") {
+				t.Fatal("app password scope not applied")
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package auth
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+
+	"github.com/yeying-community/webdav/internal/domain/auth"
+	"github.com/yeying-community/webdav/internal/domain/user"
+	"github.com/yeying-community/webdav/internal/infrastructure/config"
+	"go.uber.org/zap"
+)
+
+// CertificateAuthenticator 客户端证书认证器
+//
+// 证书已由 TLS 握手按 client_certs.ca_file 验证，这里只负责映射到用户：
+// fingerprint 按用户登记的证书指纹查找；san 和 cn 把证书中的邮箱、DNS 名称或通用名称当作用户名。
+type CertificateAuthenticator struct {
+	userRepo user.Repository
+	mapBy    []string
+	logger   *zap.Logger
+}
+
+// NewCertificateAuthenticator 创建客户端证书认证器
+func NewCertificateAuthenticator(cfg config.ClientCertConfig, userRepo user.Repository, logger *zap.Logger) *CertificateAuthenticator {
+	return &CertificateAuthenticator{
+		userRepo: userRepo,
+		mapBy:    cfg.MapBy,
+		logger:   logger,
+	}
+}
+
+// Name 认证器名称
+func (a *CertificateAuthenticator) Name() string {
+	return "certificate"
+}
+
+// CanHandle 是否可以处理该凭证
+func (a *CertificateAuthenticator) CanHandle(credentials interface{}) bool {
+	_, ok := credentials.(*auth.CertificateCredentials)
+	return ok
+}
+
+// Authenticate 认证用户
+func (a *CertificateAuthenticator) Authenticate(ctx context.Context, credentials interface{}) (*user.User, error) {
+	creds, ok := credentials.(*auth.CertificateCredentials)
+	if !ok || creds.Certificate == nil {
+		return nil, fmt.Errorf("invalid credentials type")
+	}
+	cert := creds.Certificate
+
+	for _, source := range a.mapBy {
+		u, err := a.lookup(ctx, source, cert)
+		if errors.Is(err, user.ErrUserNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to find user: %w", err)
+		}
+
+		a.logger.Info("user authenticated via client certificate",
+			zap.String("username", u.Username),
+			zap.String("subject", cert.Subject.String()),
+			zap.String("mapped_by", source))
+
+		return u, nil
+	}
+
+	a.logger.Warn("client certificate does not map to any user",
+		zap.String("subject", cert.Subject.String()),
+		zap.String("fingerprint", user.CertificateFingerprint(cert.Raw)))
+
+	return nil, user.ErrUserNotFound
+}
+
+// lookup 按映射方式查找用户
+func (a *CertificateAuthenticator) lookup(ctx context.Context, source string, cert *x509.Certificate) (*user.User, error) {
+	switch source {
+	case "fingerprint":
+		return a.userRepo.FindByCertificate(ctx, user.CertificateFingerprint(cert.Raw))
+	case "san":
+		for _, name := range certificateSANs(cert) {
+			u, err := a.userRepo.FindByUsername(ctx, name)
+			if !errors.Is(err, user.ErrUserNotFound) {
+				return u, err
+			}
+		}
+		return nil, user.ErrUserNotFound
+	case "cn":
+		if cert.Subject.CommonName == "" {
+			return nil, user.ErrUserNotFound
+		}
+		return a.userRepo.FindByUsername(ctx, cert.Subject.CommonName)
+	default:
+		return nil, user.ErrUserNotFound
+	}
+}
+
+// Matches 证书是否属于该用户，按配置的映射方式判断
+func (a *CertificateAuthenticator) Matches(cert *x509.Certificate, u *user.User) bool {
+	for _, source := range a.mapBy {
+		switch source {
+		case "fingerprint":
+			if u.HasCertificate(user.CertificateFingerprint(cert.Raw)) {
+				return true
+			}
+		case "san":
+			for _, name := range certificateSANs(cert) {
+				if name == u.Username {
+					return true
+				}
+			}
+		case "cn":
+			if cert.Subject.CommonName != "" && cert.Subject.CommonName == u.Username {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// certificateSANs 证书中可作为用户名的 SAN：邮箱和 DNS 名称
+func certificateSANs(cert *x509.Certificate) []string {
+	names := make([]string, 0, len(cert.EmailAddresses)+len(cert.DNSNames))
+	names = append(names, cert.EmailAddresses...)
+	names = append(names, cert.DNSNames...)
+	return names
+}
@@ -0,0 +1,127 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/yeying-community/webdav/internal/domain/auth"
+	"github.com/yeying-community/webdav/internal/domain/user"
+	"github.com/yeying-community/webdav/internal/infrastructure/config"
+	"github.com/yeying-community/webdav/internal/infrastructure/repository"
+	"go.uber.org/zap"
+)
+
+// newClientCertificate 生成自签名客户端证书
+func newClientCertificate(t *testing.T, cn string, emails ...string) *x509.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:   big.NewInt(1),
+		Subject:        pkix.Name{CommonName: cn},
+		EmailAddresses: emails,
+		NotBefore:      time.Now().Add(-time.Hour),
+		NotAfter:       time.Now().Add(time.Hour),
+		ExtKeyUsage:    []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("CreateCertificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("ParseCertificate: %v", err)
+	}
+	return cert
+}
+
+func TestCertificateAuthenticator(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoryUserRepository(nil)
+
+	registered := newClientCertificate(t, "laptop")
+	alice := user.NewUser("alice", "/alice")
+	if err := alice.SetCertificates([]string{user.CertificateFingerprint(registered.Raw)}); err != nil {
+		t.Fatalf("SetCertificates: %v", err)
+	}
+	for _, u := range []*user.User{alice, user.NewUser("bob@example.com", "/bob"), user.NewUser("carol", "/carol")} {
+		if err := repo.Save(ctx, u); err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+	}
+
+	bySAN := newClientCertificate(t, "carol", "bob@example.com")
+	byCN := newClientCertificate(t, "carol")
+	unknown := newClientCertificate(t, "mallory", "mallory@example.com")
+
+	tests := []struct {
+		name     string
+		mapBy    []string
+		cert     *x509.Certificate
+		wantUser string
+	}{
+		{name: "registered fingerprint", mapBy: []string{"fingerprint", "san", "cn"}, cert: registered, wantUser: "alice"},
+		{name: "san before cn", mapBy: []string{"fingerprint", "san", "cn"}, cert: bySAN, wantUser: "bob@example.com"},
+		{name: "cn before san", mapBy: []string{"cn", "san"}, cert: bySAN, wantUser: "carol"},
+		{name: "common name", mapBy: []string{"fingerprint", "san", "cn"}, cert: byCN, wantUser: "carol"},
+		{name: "unknown fingerprint", mapBy: []string{"fingerprint", "san", "cn"}, cert: unknown},
+		// 未启用的映射方式不参与查找
+		{name: "fingerprint only", mapBy: []string{"fingerprint"}, cert: byCN},
+		{name: "cn only ignores fingerprint", mapBy: []string{"cn"}, cert: registered},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := NewCertificateAuthenticator(config.ClientCertConfig{MapBy: tt.mapBy}, repo, zap.NewNop())
+
+			u, err := a.Authenticate(ctx, &auth.CertificateCredentials{Certificate: tt.cert})
+			if tt.wantUser == "" {
+				if !errors.Is(err, user.ErrUserNotFound) {
+					t.Fatalf("err = %v, want ErrUserNotFound", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Authenticate: %v", err)
+			}
+			if u.Username != tt.wantUser {
+				t.Fatalf("user = %q, want %q", u.Username, tt.wantUser)
+			}
+			if !a.Matches(tt.cert, u) {
+				t.Fatalf("Matches(%q) = false for the authenticated user", u.Username)
+			}
+		})
+	}
+}
+
+func TestCertificateAuthenticatorMatches(t *testing.T) {
+	registered := newClientCertificate(t, "laptop")
+	other := newClientCertificate(t, "alice")
+	alice := user.NewUser("alice", "/alice")
+	if err := alice.SetCertificates([]string{user.CertificateFingerprint(registered.Raw)}); err != nil {
+		t.Fatalf("SetCertificates: %v", err)
+	}
+
+	a := NewCertificateAuthenticator(config.ClientCertConfig{MapBy: []string{"fingerprint"}}, nil, zap.NewNop())
+	if !a.Matches(registered, alice) {
+		t.Fatal("registered certificate should match")
+	}
+	// 仅按指纹映射时，通用名称相同的其他证书不属于该用户
+	if a.Matches(other, alice) {
+		t.Fatal("unregistered certificate should not match")
+	}
+	if a.Matches(registered, user.NewUser("bob", "/bob")) {
+		t.Fatal("certificate registered by alice should not match bob")
+	}
+}
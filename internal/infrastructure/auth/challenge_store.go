@@ -3,12 +3,14 @@ package auth
 import (
 	"crypto/rand"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
-	"strings"
+	"strconv"
 	"sync"
 	"time"
 	
 	"github.com/yeying-community/webdav/internal/domain/auth"
+	"github.com/yeying-community/webdav/internal/infrastructure/crypto"
 )
 
 // ChallengeStore 挑战存储
@@ -29,21 +31,104 @@ func NewChallengeStore() *ChallengeStore {
 	return store
 }
 
-// Create 创建挑战
-func (s *ChallengeStore) Create(address string, expiresIn time.Duration) (*auth.Challenge, error) {
+// Create 创建挑战，消息采用 EIP-4361（Sign-In with Ethereum）/ CAIP-122 格式
+//
+// walletID 为挑战的存储键（见 user.WalletID），address 为消息中的地址。
+func (s *ChallengeStore) Create(walletID, address string, params SIWEParams, expiresIn time.Duration) (*auth.Challenge, error) {
 	nonce, err := generateNonce()
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate nonce: %w", err)
 	}
 	
-	now := time.Now()
-	message := buildChallengeMessage(address, nonce, now)
+	now := time.Now().UTC().Truncate(time.Second)
+	expiresAt := now.Add(expiresIn)
+	
+	message := &SIWEMessage{
+		Domain:         params.Domain,
+		Blockchain:     params.Blockchain,
+		Address:        address,
+		Statement:      params.Statement,
+		URI:            params.URI,
+		Version:        siweVersion,
+		ChainID:        params.ChainID,
+		Nonce:          nonce,
+		IssuedAt:       now,
+		ExpirationTime: &expiresAt,
+		Resources:      params.Resources,
+	}
+	
+	challenge := &auth.Challenge{
+		Nonce:     nonce,
+		Format:    auth.ChallengeFormatSIWE,
+		Message:   message.String(),
+		Statement: params.Statement,
+		Address:   walletID,
+		Domain:    params.Domain,
+		URI:       params.URI,
+		ChainID:   params.ChainID,
+		IssuedAt:  now,
+		ExpiresAt: expiresAt,
+	}
+	
+	s.Store(challenge)
+	
+	return challenge, nil
+}
+
+// CreateTypedData 创建 EIP-712 类型化数据格式的挑战，只用于以太坊
+//
+// 域名称为站点域名，scope 取自 params.Resources，未配置时为站点 URI；scope 由服务端固定，客户端不能指定。
+func (s *ChallengeStore) CreateTypedData(walletID, address string, params SIWEParams, expiresIn time.Duration) (*auth.Challenge, error) {
+	chainID, err := strconv.ParseInt(params.ChainID, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid chain id %q: %w", params.ChainID, err)
+	}
+	
+	nonce, err := generateNonce()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	
+	now := time.Now().UTC().Truncate(time.Second)
+	expiresAt := now.Add(expiresIn)
+	
+	scope := params.Resources
+	if len(scope) == 0 {
+		scope = []string{params.URI}
+	}
+	
+	data := &crypto.SignInTypedData{
+		Domain: crypto.EIP712Domain{
+			Name:    params.Domain,
+			Version: siweVersion,
+			ChainID: chainID,
+		},
+		Wallet:         address,
+		URI:            params.URI,
+		Statement:      params.Statement,
+		Nonce:          nonce,
+		IssuedAt:       now.Format(time.RFC3339),
+		ExpirationTime: expiresAt.Format(time.RFC3339),
+		Scope:          scope,
+	}
+	
+	message, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode typed data: %w", err)
+	}
 	
 	challenge := &auth.Challenge{
 		Nonce:     nonce,
-		Message:   message,
-		Address:   strings.ToLower(address),
-		ExpiresAt: now.Add(expiresIn),
+		Format:    auth.ChallengeFormatEIP712,
+		Message:   string(message),
+		Digest:    data.Hash().Hex(),
+		Statement: params.Statement,
+		Address:   walletID,
+		Domain:    params.Domain,
+		URI:       params.URI,
+		ChainID:   params.ChainID,
+		IssuedAt:  now,
+		ExpiresAt: expiresAt,
 	}
 	
 	s.Store(challenge)
@@ -56,17 +141,15 @@ func (s *ChallengeStore) Store(challenge *auth.Challenge) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	
-	key := strings.ToLower(challenge.Address)
-	s.challenges[key] = challenge
+	s.challenges[challenge.Address] = challenge
 }
 
-// Get 获取挑战
-func (s *ChallengeStore) Get(address string) (*auth.Challenge, bool) {
+// Get 获取钱包的挑战
+func (s *ChallengeStore) Get(walletID string) (*auth.Challenge, bool) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	
-	key := strings.ToLower(address)
-	challenge, ok := s.challenges[key]
+	challenge, ok := s.challenges[walletID]
 	
 	if !ok {
 		return nil, false
@@ -80,13 +163,33 @@ func (s *ChallengeStore) Get(address string) (*auth.Challenge, bool) {
 	return challenge, true
 }
 
+// Take 取出并删除钱包的挑战
+//
+// 读取和删除在同一把锁内完成，并发的验证请求中只有一个能取到挑战；
+// 验证失败时挑战也已作废，客户端需要重新获取。
+func (s *ChallengeStore) Take(walletID string) (*auth.Challenge, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	challenge, ok := s.challenges[walletID]
+	if !ok {
+		return nil, false
+	}
+	delete(s.challenges, walletID)
+	
+	if challenge.IsExpired() {
+		return nil, false
+	}
+	
+	return challenge, true
+}
+
 // Delete 删除挑战
-func (s *ChallengeStore) Delete(address string) {
+func (s *ChallengeStore) Delete(walletID string) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	
-	key := strings.ToLower(address)
-	delete(s.challenges, key)
+	delete(s.challenges, walletID)
 }
 
 // cleanupExpired 清理过期挑战
@@ -114,19 +217,3 @@ func generateNonce() (string, error) {
 	}
 	return hex.EncodeToString(bytes), nil
 }
-
-// buildChallengeMessage 构建挑战消息
-func buildChallengeMessage(address, nonce string, timestamp time.Time) string {
-	return fmt.Sprintf(
-		"Welcome to WebDAV!\n\n"+
-			"Sign this message to authenticate.\n\n"+
-			"Address: %s\n"+
-			"Nonce: %s\n"+
-			"Timestamp: %s\n\n"+
-			"This request will not trigger a blockchain transaction or cost any gas fees.",
-		address,
-		nonce,
-		timestamp.Format(time.RFC3339),
-	)
-}
-
@@ -0,0 +1,49 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/yeying-community/webdav/internal/domain/auth"
+)
+
+func TestChallengeStoreTake(t *testing.T) {
+	s := NewChallengeStore()
+	params := SIWEParams{Domain: "dav.example.com", URI: "https://dav.example.com", ChainID: "1"}
+
+	created, err := s.Create("0xabc", "0xabc", params, time.Minute)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	// 只能取出一次
+	taken, ok := s.Take("0xabc")
+	if !ok || taken.Nonce != created.Nonce {
+		t.Fatalf("Take = %v, %v", taken, ok)
+	}
+	if _, ok := s.Take("0xabc"); ok {
+		t.Fatal("Take should not return a challenge twice")
+	}
+	if _, ok := s.Get("0xabc"); ok {
+		t.Fatal("Get should not find a taken challenge")
+	}
+
+	// 新的挑战替换旧的挑战
+	first, _ := s.Create("0xabc", "0xabc", params, time.Minute)
+	second, _ := s.Create("0xabc", "0xabc", params, time.Minute)
+	if taken, ok := s.Take("0xabc"); !ok || taken.Nonce != second.Nonce || taken.Nonce == first.Nonce {
+		t.Fatalf("Take = %v, %v, want the latest challenge", taken, ok)
+	}
+
+	// 过期的挑战取出时同样被删除
+	s.Store(&auth.Challenge{Nonce: "expired", Address: "0xdef", ExpiresAt: time.Now().Add(-time.Second)})
+	if _, ok := s.Take("0xdef"); ok {
+		t.Fatal("Take should not return an expired challenge")
+	}
+	s.mu.RLock()
+	_, stored := s.challenges["0xdef"]
+	s.mu.RUnlock()
+	if stored {
+		t.Fatal("expired challenge should be removed by Take")
+	}
+}
@@ -0,0 +1,246 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/yeying-community/webdav/internal/domain/auth"
+	"github.com/yeying-community/webdav/internal/domain/user"
+	"github.com/yeying-community/webdav/internal/infrastructure/config"
+	"github.com/yeying-community/webdav/internal/infrastructure/crypto"
+	"go.uber.org/zap"
+)
+
+// digestSessSuffix 会话变体算法后缀，HA1 再与 nonce 和 cnonce 组合
+const digestSessSuffix = "-sess"
+
+// DigestAuthenticator HTTP Digest 认证器（RFC 7616）
+//
+// 用户的 HA1 在设置密码或密码登录时保存。只支持 qop=auth，不支持 userhash；
+// 启用两步验证的用户无法携带验证码，只能使用 Basic 认证或应用密码。
+type DigestAuthenticator struct {
+	cfg            config.DigestConfig
+	userRepo       user.Repository
+	nonces         *digestNonceStore
+	opaque         string
+	passwordHasher *crypto.PasswordHasher
+	logger         *zap.Logger
+}
+
+// NewDigestAuthenticator 创建 Digest 认证器
+func NewDigestAuthenticator(
+	cfg config.DigestConfig,
+	userRepo user.Repository,
+	logger *zap.Logger,
+) (*DigestAuthenticator, error) {
+	nonces, err := newDigestNonceStore(cfg.NonceTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	opaque := make([]byte, 16)
+	if _, err := rand.Read(opaque); err != nil {
+		return nil, fmt.Errorf("failed to generate opaque: %w", err)
+	}
+
+	return &DigestAuthenticator{
+		cfg:            cfg,
+		userRepo:       userRepo,
+		nonces:         nonces,
+		opaque:         hex.EncodeToString(opaque),
+		passwordHasher: crypto.NewPasswordHasher(),
+		logger:         logger,
+	}, nil
+}
+
+// Name 认证器名称
+func (a *DigestAuthenticator) Name() string {
+	return "digest"
+}
+
+// CanHandle 是否可以处理该凭证
+func (a *DigestAuthenticator) CanHandle(credentials interface{}) bool {
+	_, ok := credentials.(*auth.DigestCredentials)
+	return ok
+}
+
+// Authenticate 认证用户
+func (a *DigestAuthenticator) Authenticate(ctx context.Context, credentials interface{}) (*user.User, error) {
+	creds, ok := credentials.(*auth.DigestCredentials)
+	if !ok {
+		return nil, fmt.Errorf("invalid credentials type")
+	}
+
+	algorithm, sess, err := a.algorithm(creds.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.validateParams(creds); err != nil {
+		return nil, err
+	}
+	nc, err := strconv.ParseUint(creds.NC, 16, 64)
+	if err != nil || len(creds.NC) != 8 {
+		return nil, fmt.Errorf("%w: invalid nc", auth.ErrInvalidCredentials)
+	}
+
+	u, err := a.userRepo.FindByUsername(ctx, creds.Username)
+	if err != nil {
+		if errors.Is(err, user.ErrUserNotFound) {
+			a.logger.Debug("user not found",
+				zap.String("username", creds.Username))
+			return nil, user.ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+
+	if !u.HasDigest(a.cfg.Realm) {
+		a.logger.Warn("user has no digest credentials; set the password again or log in with basic auth once",
+			zap.String("username", u.Username))
+		return nil, user.ErrInvalidPassword
+	}
+
+	ha1 := u.Digest.Get(algorithm)
+	if sess {
+		ha1 = user.DigestHash(algorithm, ha1+":"+creds.Nonce+":"+creds.CNonce)
+	}
+	ha2 := user.DigestHash(algorithm, creds.Method+":"+creds.URI)
+	expected := user.DigestHash(algorithm,
+		ha1+":"+creds.Nonce+":"+creds.NC+":"+creds.CNonce+":"+creds.QOP+":"+ha2)
+
+	if subtle.ConstantTimeCompare([]byte(expected), []byte(strings.ToLower(creds.Response))) != 1 {
+		a.logger.Warn("digest response verification failed",
+			zap.String("username", u.Username))
+		return nil, user.ErrInvalidPassword
+	}
+
+	// 响应正确后再检查 nonce，过期时客户端可以凭 stale=true 直接重试
+	err = a.nonces.Use(creds.Nonce, nc)
+	if err == nil && creds.Opaque != a.opaque {
+		err = fmt.Errorf("%w: opaque mismatch", auth.ErrStaleNonce)
+	}
+	if err != nil {
+		a.logger.Debug("digest nonce rejected",
+			zap.String("username", u.Username),
+			zap.Error(err))
+		return nil, err
+	}
+
+	if u.HasTwoFactor() {
+		return nil, user.ErrTwoFactorRequired
+	}
+
+	a.logger.Info("user authenticated via digest auth",
+		zap.String("username", u.Username),
+		zap.String("algorithm", creds.Algorithm))
+
+	return u, nil
+}
+
+// Challenges 生成 WWW-Authenticate 质询，每个启用的算法一个
+func (a *DigestAuthenticator) Challenges(stale bool) []string {
+	nonce, err := a.nonces.Issue()
+	if err != nil {
+		a.logger.Error("failed to issue digest nonce", zap.Error(err))
+		return nil
+	}
+
+	challenges := make([]string, 0, len(a.cfg.Algorithms))
+	for _, algorithm := range a.cfg.Algorithms {
+		challenge := fmt.Sprintf(`Digest realm="%s", qop="auth", algorithm=%s, nonce="%s", opaque="%s"`,
+			a.cfg.Realm, algorithm, nonce, a.opaque)
+		if stale {
+			challenge += ", stale=true"
+		}
+		challenges = append(challenges, challenge)
+	}
+	return challenges
+}
+
+// ImportConfigPasswords 为配置文件中以明文设置密码的用户补全 Digest 摘要，返回补全数量
+//
+// 只处理仓储中密码仍与配置一致的用户，运行时修改过的密码不会被覆盖。
+func (a *DigestAuthenticator) ImportConfigPasswords(ctx context.Context, userConfigs []config.UserConfig) (int, error) {
+	imported := 0
+	for _, cfg := range userConfigs {
+		if cfg.Password == "" || crypto.IsPasswordHash(cfg.Password) {
+			continue
+		}
+
+		u, err := a.userRepo.FindByUsername(ctx, cfg.Username)
+		if errors.Is(err, user.ErrUserNotFound) {
+			continue
+		}
+		if err != nil {
+			return imported, err
+		}
+		if u.HasDigest(a.cfg.Realm) || a.passwordHasher.Verify(u.Password, cfg.Password) != nil {
+			continue
+		}
+
+		updated := u.Clone()
+		updated.SetDigestPassword(a.cfg.Realm, cfg.Password)
+		if err := a.userRepo.Save(ctx, updated); err != nil {
+			return imported, fmt.Errorf("failed to save digest credentials for %s: %w", u.Username, err)
+		}
+		imported++
+	}
+
+	return imported, nil
+}
+
+// algorithm 解析算法参数，返回基础算法和是否为会话变体；未指定时按 RFC 2617 使用 MD5
+func (a *DigestAuthenticator) algorithm(value string) (string, bool, error) {
+	if value == "" {
+		value = user.DigestMD5
+	}
+
+	base := value
+	sess := false
+	if len(value) > len(digestSessSuffix) && strings.EqualFold(value[len(value)-len(digestSessSuffix):], digestSessSuffix) {
+		base = value[:len(value)-len(digestSessSuffix)]
+		sess = true
+	}
+
+	for _, enabled := range a.cfg.Algorithms {
+		if strings.EqualFold(base, enabled) {
+			return enabled, sess, nil
+		}
+	}
+	return "", false, fmt.Errorf("%w: unsupported digest algorithm %q", auth.ErrInvalidCredentials, value)
+}
+
+// validateParams 校验与用户无关的参数
+func (a *DigestAuthenticator) validateParams(creds *auth.DigestCredentials) error {
+	switch {
+	case creds.Realm != a.cfg.Realm:
+		return fmt.Errorf("%w: realm mismatch", auth.ErrInvalidCredentials)
+	case creds.QOP != "auth":
+		return fmt.Errorf("%w: unsupported qop %q", auth.ErrInvalidCredentials, creds.QOP)
+	case creds.UserHash:
+		return fmt.Errorf("%w: userhash is not supported", auth.ErrInvalidCredentials)
+	case creds.Nonce == "" || creds.CNonce == "" || creds.Response == "":
+		return fmt.Errorf("%w: missing digest parameters", auth.ErrInvalidCredentials)
+	case !digestURIMatches(creds.URI, creds.RequestURI):
+		return fmt.Errorf("%w: uri does not match request", auth.ErrInvalidCredentials)
+	}
+	return nil
+}
+
+// digestURIMatches uri 参数是否指向当前请求，部分客户端发送绝对 URI
+func digestURIMatches(uri, requestURI string) bool {
+	if uri == requestURI {
+		return true
+	}
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		return false
+	}
+	return parsed.RequestURI() == requestURI
+}
@@ -0,0 +1,221 @@
+package auth
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/yeying-community/webdav/internal/domain/auth"
+	"github.com/yeying-community/webdav/internal/domain/user"
+	"github.com/yeying-community/webdav/internal/infrastructure/config"
+	"github.com/yeying-community/webdav/internal/infrastructure/repository"
+	"go.uber.org/zap"
+)
+
+// RFC 7616 3.9.1 示例
+const (
+	rfcDigestUsername = "Mufasa"
+	rfcDigestPassword = "Circle of Life"
+	rfcDigestRealm    = "http-auth@example.org"
+	rfcDigestURI      = "/dir/index.html"
+	rfcDigestNonce    = "7ypf/xlj9XXwfDPEoM4URrv/xwf94BcCAzFZH4GiTo0v"
+	rfcDigestCNonce   = "f2/wE4q74E6zIJEtWaHKaf5wv/H5QzzpXusqGemxURZJ"
+	rfcDigestOpaque   = "FQhe/qaU925kfnzjCev0ciny7QMkPqMAFRtzCUYo5tdS"
+)
+
+// digestClientResponse 按 RFC 7616 独立计算客户端的 response（qop=auth）
+func digestClientResponse(algorithm, username, realm, password, method, uri, nonce, nc, cnonce string) string {
+	newHash := md5.New
+	if algorithm == user.DigestSHA256 {
+		newHash = sha256.New
+	}
+	h := func(s string) string {
+		var d hash.Hash = newHash()
+		d.Write([]byte(s))
+		return hex.EncodeToString(d.Sum(nil))
+	}
+
+	ha1 := h(username + ":" + realm + ":" + password)
+	ha2 := h(method + ":" + uri)
+	return h(ha1 + ":" + nonce + ":" + nc + ":" + cnonce + ":auth:" + ha2)
+}
+
+// newTestDigestAuthenticator 创建 Digest 认证器并保存设置了 Digest 摘要的用户
+func newTestDigestAuthenticator(t *testing.T, ttl time.Duration) *DigestAuthenticator {
+	t.Helper()
+
+	repo := repository.NewMemoryUserRepository(nil)
+	u := user.NewUser(rfcDigestUsername, "/mufasa")
+	u.SetDigestPassword(rfcDigestRealm, rfcDigestPassword)
+	if err := repo.Save(context.Background(), u); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	a, err := NewDigestAuthenticator(config.DigestConfig{
+		Enabled:    true,
+		Realm:      rfcDigestRealm,
+		Algorithms: []string{user.DigestSHA256, user.DigestMD5},
+		NonceTTL:   ttl,
+	}, repo, zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewDigestAuthenticator: %v", err)
+	}
+	return a
+}
+
+// issueNonce 从质询中取出服务端签发的 nonce
+func issueNonce(t *testing.T, a *DigestAuthenticator) string {
+	t.Helper()
+
+	challenges := a.Challenges(false)
+	if len(challenges) == 0 {
+		t.Fatal("no digest challenges")
+	}
+	m := regexp.MustCompile(`nonce="([^"]+)"`).FindStringSubmatch(challenges[0])
+	if m == nil {
+		t.Fatalf("no nonce in %q", challenges[0])
+	}
+	return m[1]
+}
+
+// digestCredentials 客户端用正确密码构造的凭证
+func digestCredentials(a *DigestAuthenticator, algorithm, nonce, nc string) *auth.DigestCredentials {
+	return &auth.DigestCredentials{
+		Username:   rfcDigestUsername,
+		Realm:      rfcDigestRealm,
+		Nonce:      nonce,
+		URI:        rfcDigestURI,
+		Response:   digestClientResponse(algorithm, rfcDigestUsername, rfcDigestRealm, rfcDigestPassword, "GET", rfcDigestURI, nonce, nc, rfcDigestCNonce),
+		Algorithm:  algorithm,
+		QOP:        "auth",
+		NC:         nc,
+		CNonce:     rfcDigestCNonce,
+		Opaque:     a.opaque,
+		Method:     "GET",
+		RequestURI: rfcDigestURI,
+	}
+}
+
+func TestDigestKnownAnswers(t *testing.T) {
+	tests := []struct {
+		algorithm string
+		response  string
+	}{
+		{algorithm: user.DigestMD5, response: "8ca523f5e9506fed4657c9700eebdbec"},
+		{algorithm: user.DigestSHA256, response: "753927fa0e85d155564e2e272a28d1802ca10daf4496794697cf8db5856cb6c1"},
+	}
+
+	a := newTestDigestAuthenticator(t, time.Minute)
+	for _, tt := range tests {
+		t.Run(tt.algorithm, func(t *testing.T) {
+			got := digestClientResponse(tt.algorithm, rfcDigestUsername, rfcDigestRealm, rfcDigestPassword, "GET", rfcDigestURI, rfcDigestNonce, "00000001", rfcDigestCNonce)
+			if got != tt.response {
+				t.Fatalf("client response = %s, want %s", got, tt.response)
+			}
+
+			creds := &auth.DigestCredentials{
+				Username:   rfcDigestUsername,
+				Realm:      rfcDigestRealm,
+				Nonce:      rfcDigestNonce,
+				URI:        rfcDigestURI,
+				Response:   tt.response,
+				Algorithm:  tt.algorithm,
+				QOP:        "auth",
+				NC:         "00000001",
+				CNonce:     rfcDigestCNonce,
+				Opaque:     rfcDigestOpaque,
+				Method:     "GET",
+				RequestURI: rfcDigestURI,
+			}
+
+			// 示例的 response 通过校验，nonce 不是本服务器签发的，客户端应使用新 nonce 重试
+			if _, err := a.Authenticate(context.Background(), creds); !errors.Is(err, auth.ErrStaleNonce) {
+				t.Fatalf("RFC response: err = %v, want ErrStaleNonce", err)
+			}
+
+			creds.Response = tt.response[:len(tt.response)-1] + "0"
+			if _, err := a.Authenticate(context.Background(), creds); !errors.Is(err, user.ErrInvalidPassword) {
+				t.Fatalf("tampered response: err = %v, want ErrInvalidPassword", err)
+			}
+		})
+	}
+}
+
+func TestDigestAuthenticate(t *testing.T) {
+	ctx := context.Background()
+
+	for _, algorithm := range []string{user.DigestMD5, user.DigestSHA256} {
+		t.Run(algorithm, func(t *testing.T) {
+			a := newTestDigestAuthenticator(t, time.Minute)
+			nonce := issueNonce(t, a)
+
+			u, err := a.Authenticate(ctx, digestCredentials(a, algorithm, nonce, "00000001"))
+			if err != nil {
+				t.Fatalf("Authenticate: %v", err)
+			}
+			if u.Username != rfcDigestUsername {
+				t.Fatalf("user = %q", u.Username)
+			}
+
+			// 同一 nonce 的 nc 不能重复使用，乱序但未使用过的 nc 可以
+			if _, err := a.Authenticate(ctx, digestCredentials(a, algorithm, nonce, "00000001")); !errors.Is(err, auth.ErrStaleNonce) {
+				t.Fatalf("replayed nc: err = %v, want ErrStaleNonce", err)
+			}
+			if _, err := a.Authenticate(ctx, digestCredentials(a, algorithm, nonce, "00000003")); err != nil {
+				t.Fatalf("nc 3: %v", err)
+			}
+			if _, err := a.Authenticate(ctx, digestCredentials(a, algorithm, nonce, "00000002")); err != nil {
+				t.Fatalf("out-of-order nc 2: %v", err)
+			}
+			if _, err := a.Authenticate(ctx, digestCredentials(a, algorithm, nonce, "00000002")); !errors.Is(err, auth.ErrStaleNonce) {
+				t.Fatalf("replayed nc 2: err = %v, want ErrStaleNonce", err)
+			}
+
+			// 错误的密码
+			creds := digestCredentials(a, algorithm, nonce, "00000004")
+			creds.Response = digestClientResponse(algorithm, rfcDigestUsername, rfcDigestRealm, "wrong", "GET", rfcDigestURI, nonce, "00000004", rfcDigestCNonce)
+			if _, err := a.Authenticate(ctx, creds); !errors.Is(err, user.ErrInvalidPassword) {
+				t.Fatalf("wrong password: err = %v, want ErrInvalidPassword", err)
+			}
+
+			// 与请求不符的 uri
+			creds = digestCredentials(a, algorithm, nonce, "00000005")
+			creds.RequestURI = "/other"
+			if _, err := a.Authenticate(ctx, creds); !errors.Is(err, auth.ErrInvalidCredentials) {
+				t.Fatalf("uri mismatch: err = %v, want ErrInvalidCredentials", err)
+			}
+		})
+	}
+}
+
+func TestDigestStaleNonce(t *testing.T) {
+	ctx := context.Background()
+
+	// 过期的 nonce
+	a := newTestDigestAuthenticator(t, time.Nanosecond)
+	nonce := issueNonce(t, a)
+	time.Sleep(10 * time.Millisecond)
+	if _, err := a.Authenticate(ctx, digestCredentials(a, user.DigestSHA256, nonce, "00000001")); !errors.Is(err, auth.ErrStaleNonce) {
+		t.Fatalf("expired nonce: err = %v, want ErrStaleNonce", err)
+	}
+
+	// 其他实例（如重启前）签发的 nonce
+	other := newTestDigestAuthenticator(t, time.Minute)
+	nonce = issueNonce(t, other)
+	if _, err := a.Authenticate(ctx, digestCredentials(a, user.DigestSHA256, nonce, "00000001")); !errors.Is(err, auth.ErrStaleNonce) {
+		t.Fatalf("foreign nonce: err = %v, want ErrStaleNonce", err)
+	}
+
+	// 过期时质询带 stale=true
+	for _, challenge := range a.Challenges(true) {
+		if !regexp.MustCompile(`, stale=true$`).MatchString(challenge) {
+			t.Fatalf("challenge %q lacks stale=true", challenge)
+		}
+	}
+}
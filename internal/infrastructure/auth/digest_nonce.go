@@ -0,0 +1,171 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/yeying-community/webdav/internal/domain/auth"
+)
+
+const (
+	// digestNonceRandomSize nonce 中随机部分的字节数
+	digestNonceRandomSize = 12
+
+	// digestNonceMACSize nonce 中 HMAC 部分的字节数
+	digestNonceMACSize = 16
+
+	// digestNonceWindow 允许乱序到达的 nc 窗口，客户端并发请求时 nc 可能乱序
+	digestNonceWindow = 64
+)
+
+var (
+	// errInvalidNonce nonce 不是本服务器签发的
+	errInvalidNonce = errors.New("invalid digest nonce")
+
+	// errNonceReplayed nc 已经使用过或早于窗口
+	errNonceReplayed = errors.New("digest nonce count replayed")
+)
+
+// digestNonceUsage 已使用 nonce 的 nc 记录
+type digestNonceUsage struct {
+	maxNC     uint64
+	seen      uint64 // 以 maxNC 为第 0 位的窗口位图
+	expiresAt time.Time
+}
+
+// digestNonceStore Digest 认证 nonce 存储
+//
+// nonce 由签发时间、随机值和 HMAC 组成，签发时不保存状态；
+// 只有通过认证的 nonce 才记录已使用的 nc，用于拒绝重放。
+type digestNonceStore struct {
+	key  []byte
+	ttl  time.Duration
+	used map[string]*digestNonceUsage
+	mu   sync.Mutex
+}
+
+// newDigestNonceStore 创建 nonce 存储
+func newDigestNonceStore(ttl time.Duration) (*digestNonceStore, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce key: %w", err)
+	}
+
+	store := &digestNonceStore{
+		key:  key,
+		ttl:  ttl,
+		used: make(map[string]*digestNonceUsage),
+	}
+
+	// 启动清理协程
+	go store.cleanupExpired()
+
+	return store, nil
+}
+
+// Issue 签发新的 nonce
+func (s *digestNonceStore) Issue() (string, error) {
+	payload := make([]byte, 8+digestNonceRandomSize)
+	binary.BigEndian.PutUint64(payload, uint64(time.Now().Unix()))
+	if _, err := rand.Read(payload[8:]); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(append(payload, s.sign(payload)...)), nil
+}
+
+// Use 校验 nonce 并记录 nc
+//
+// nonce 无效、过期或 nc 重复时返回 ErrStaleNonce，调用方应在验证响应后再调用。
+func (s *digestNonceStore) Use(nonce string, nc uint64) error {
+	// 服务重启后旧 nonce 无法通过校验，同样让客户端重试
+	issuedAt, err := s.verify(nonce)
+	if err != nil {
+		return fmt.Errorf("%w: %v", auth.ErrStaleNonce, err)
+	}
+
+	expiresAt := issuedAt.Add(s.ttl)
+	if time.Now().After(expiresAt) {
+		return auth.ErrStaleNonce
+	}
+	if nc == 0 {
+		return errNonceReplayed
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	usage, ok := s.used[nonce]
+	if !ok {
+		usage = &digestNonceUsage{expiresAt: expiresAt}
+		s.used[nonce] = usage
+	}
+
+	switch {
+	case nc > usage.maxNC:
+		shift := nc - usage.maxNC
+		if shift >= digestNonceWindow {
+			usage.seen = 0
+		} else {
+			usage.seen <<= shift
+		}
+		usage.seen |= 1
+		usage.maxNC = nc
+	case usage.maxNC-nc >= digestNonceWindow:
+		return fmt.Errorf("%w: %v", auth.ErrStaleNonce, errNonceReplayed)
+	default:
+		bit := uint64(1) << (usage.maxNC - nc)
+		if usage.seen&bit != 0 {
+			return fmt.Errorf("%w: %v", auth.ErrStaleNonce, errNonceReplayed)
+		}
+		usage.seen |= bit
+	}
+
+	return nil
+}
+
+// verify 校验 nonce 的 HMAC，返回签发时间
+func (s *digestNonceStore) verify(nonce string) (time.Time, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(nonce)
+	if err != nil || len(raw) != 8+digestNonceRandomSize+digestNonceMACSize {
+		return time.Time{}, errInvalidNonce
+	}
+
+	payload, mac := raw[:8+digestNonceRandomSize], raw[8+digestNonceRandomSize:]
+	if !hmac.Equal(mac, s.sign(payload)) {
+		return time.Time{}, errInvalidNonce
+	}
+
+	return time.Unix(int64(binary.BigEndian.Uint64(payload)), 0), nil
+}
+
+// sign 计算 nonce 的 HMAC
+func (s *digestNonceStore) sign(payload []byte) []byte {
+	h := hmac.New(sha256.New, s.key)
+	h.Write(payload)
+	return h.Sum(nil)[:digestNonceMACSize]
+}
+
+// cleanupExpired 清理过期 nonce 的 nc 记录
+func (s *digestNonceStore) cleanupExpired() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		s.mu.Lock()
+		now := time.Now()
+		for nonce, usage := range s.used {
+			if now.After(usage.expiresAt) {
+				delete(s.used, nonce)
+			}
+		}
+		s.mu.Unlock()
+	}
+}
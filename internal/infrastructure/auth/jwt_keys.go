@@ -0,0 +1,188 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/yeying-community/webdav/internal/infrastructure/config"
+)
+
+// minRSAKeyBits RS256 密钥的最小长度
+const minRSAKeyBits = 2048
+
+// SigningKey JWT 签名密钥
+type SigningKey struct {
+	ID     string
+	Method jwt.SigningMethod
+
+	// privateKey 签名密钥，仅用于验证的密钥为 nil
+	privateKey interface{}
+
+	// publicKey 验证密钥，HMAC 密钥与 privateKey 相同
+	publicKey interface{}
+}
+
+// CanSign 是否可以用于签发令牌
+func (k *SigningKey) CanSign() bool {
+	return k.privateKey != nil
+}
+
+// newHMACKey 创建 HS256 密钥
+func newHMACKey(secret string) *SigningKey {
+	return &SigningKey{
+		Method:     jwt.SigningMethodHS256,
+		privateKey: []byte(secret),
+		publicKey:  []byte(secret),
+	}
+}
+
+// LoadSigningKeys 从 PEM 文件加载签名密钥
+func LoadSigningKeys(cfgs []config.SigningKeyConfig) ([]*SigningKey, error) {
+	keys := make([]*SigningKey, 0, len(cfgs))
+	for _, cfg := range cfgs {
+		key, err := loadSigningKey(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("signing key %q: %w", cfg.ID, err)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// loadSigningKey 加载单个签名密钥并检查与算法是否匹配
+func loadSigningKey(cfg config.SigningKeyConfig) (*SigningKey, error) {
+	key := &SigningKey{ID: cfg.ID}
+
+	var (
+		privatePEM, publicPEM []byte
+		err                   error
+	)
+	if cfg.PrivateKeyFile != "" {
+		if privatePEM, err = os.ReadFile(cfg.PrivateKeyFile); err != nil {
+			return nil, fmt.Errorf("failed to read private key: %w", err)
+		}
+	}
+	if cfg.PublicKeyFile != "" {
+		if publicPEM, err = os.ReadFile(cfg.PublicKeyFile); err != nil {
+			return nil, fmt.Errorf("failed to read public key: %w", err)
+		}
+	}
+
+	switch cfg.Algorithm {
+	case "RS256":
+		key.Method = jwt.SigningMethodRS256
+		var public *rsa.PublicKey
+		if privatePEM != nil {
+			private, err := jwt.ParseRSAPrivateKeyFromPEM(privatePEM)
+			if err != nil {
+				return nil, err
+			}
+			key.privateKey = private
+			public = &private.PublicKey
+		} else {
+			if public, err = jwt.ParseRSAPublicKeyFromPEM(publicPEM); err != nil {
+				return nil, err
+			}
+		}
+		if public.N.BitLen() < minRSAKeyBits {
+			return nil, fmt.Errorf("rsa key must be at least %d bits", minRSAKeyBits)
+		}
+		key.publicKey = public
+
+	case "ES256":
+		key.Method = jwt.SigningMethodES256
+		var public *ecdsa.PublicKey
+		if privatePEM != nil {
+			private, err := jwt.ParseECPrivateKeyFromPEM(privatePEM)
+			if err != nil {
+				return nil, err
+			}
+			key.privateKey = private
+			public = &private.PublicKey
+		} else {
+			if public, err = jwt.ParseECPublicKeyFromPEM(publicPEM); err != nil {
+				return nil, err
+			}
+		}
+		if public.Curve != elliptic.P256() {
+			return nil, fmt.Errorf("ES256 requires a P-256 key, got %s", public.Curve.Params().Name)
+		}
+		key.publicKey = public
+
+	case "EdDSA":
+		key.Method = jwt.SigningMethodEdDSA
+		if privatePEM != nil {
+			private, err := jwt.ParseEdPrivateKeyFromPEM(privatePEM)
+			if err != nil {
+				return nil, err
+			}
+			key.privateKey = private
+			key.publicKey = private.(ed25519.PrivateKey).Public()
+		} else {
+			if key.publicKey, err = jwt.ParseEdPublicKeyFromPEM(publicPEM); err != nil {
+				return nil, err
+			}
+		}
+
+	default:
+		return nil, fmt.Errorf("unsupported algorithm: %s", cfg.Algorithm)
+	}
+
+	return key, nil
+}
+
+// JWK JSON Web Key（RFC 7517）公钥
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Crv string `json:"crv,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKSet JSON Web Key Set
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWK 导出公钥，对称密钥返回 false
+func (k *SigningKey) JWK() (JWK, bool) {
+	jwk := JWK{Kid: k.ID, Use: "sig", Alg: k.Method.Alg()}
+
+	switch public := k.publicKey.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64URL(public.N.Bytes())
+		jwk.E = base64URL(big.NewInt(int64(public.E)).Bytes())
+	case *ecdsa.PublicKey:
+		size := (public.Curve.Params().BitSize + 7) / 8
+		jwk.Kty = "EC"
+		jwk.Crv = public.Curve.Params().Name
+		jwk.X = base64URL(public.X.FillBytes(make([]byte, size)))
+		jwk.Y = base64URL(public.Y.FillBytes(make([]byte, size)))
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64URL(public)
+	default:
+		return JWK{}, false
+	}
+
+	return jwk, true
+}
+
+// base64URL 无填充的 base64url 编码
+func base64URL(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
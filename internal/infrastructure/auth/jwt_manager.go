@@ -1,45 +1,141 @@
 package auth
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"fmt"
-	"strings"
 	"time"
 	
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/yeying-community/webdav/internal/domain/auth"
+	"github.com/yeying-community/webdav/internal/domain/user"
+	"github.com/yeying-community/webdav/internal/infrastructure/config"
+)
+
+// TokenType 令牌类型
+type TokenType string
+
+const (
+	// TokenTypeAccess 访问令牌
+	TokenTypeAccess TokenType = "access"
+	
+	// TokenTypeRefresh 刷新令牌
+	TokenTypeRefresh TokenType = "refresh"
 )
 
 // JWTManager JWT 管理器
 type JWTManager struct {
-	secret     []byte
-	expiration time.Duration
-	issuer     string
+	signer            *SigningKey
+	keys              map[string]*SigningKey // kid -> 验证密钥，HS256 密钥的 kid 为空
+	publicKeys        []*SigningKey
+	expiration        time.Duration
+	refreshExpiration time.Duration
+	issuer            string
 }
 
 // Claims JWT 声明
 type Claims struct {
-	Address string `json:"address"`
+	Address string    `json:"address"`
+	Type    TokenType `json:"typ"`
+	Family  string    `json:"fid"`
 	jwt.RegisteredClaims
 }
 
 // NewJWTManager 创建 JWT 管理器
-func NewJWTManager(secret string, expiration time.Duration) *JWTManager {
-	return &JWTManager{
-		secret:     []byte(secret),
-		expiration: expiration,
-		issuer:     "webdav-server",
+//
+// 配置了 signing_keys 时使用第一个带私钥的密钥签发令牌，其余密钥只用于验证；
+// 同时配置的 jwt_secret 用于继续验证切换前签发的 HS256 令牌。
+func NewJWTManager(cfg config.Web3Config) (*JWTManager, error) {
+	keys, err := LoadSigningKeys(cfg.SigningKeys)
+	if err != nil {
+		return nil, err
+	}
+	
+	m := &JWTManager{
+		keys:              make(map[string]*SigningKey),
+		expiration:        cfg.TokenExpiration,
+		refreshExpiration: cfg.RefreshTokenExpiration,
+		issuer:            "webdav-server",
+	}
+	
+	for _, key := range keys {
+		m.keys[key.ID] = key
+		m.publicKeys = append(m.publicKeys, key)
+		if m.signer == nil && key.CanSign() {
+			m.signer = key
+		}
+	}
+	
+	if cfg.JWTSecret != "" {
+		hmacKey := newHMACKey(cfg.JWTSecret)
+		m.keys[hmacKey.ID] = hmacKey
+		if m.signer == nil {
+			m.signer = hmacKey
+		}
 	}
+	
+	if m.signer == nil {
+		return nil, errors.New("no jwt signing key configured")
+	}
+	
+	return m, nil
+}
+
+// JWKS 返回所有非对称验证密钥的公钥集合
+func (m *JWTManager) JWKS() *JWKSet {
+	set := &JWKSet{Keys: []JWK{}}
+	for _, key := range m.publicKeys {
+		if jwk, ok := key.JWK(); ok {
+			set.Keys = append(set.Keys, jwk)
+		}
+	}
+	return set
 }
 
-// Generate 生成 JWT
-func (m *JWTManager) Generate(address string) (*auth.Token, error) {
+// GeneratePair 生成访问令牌和刷新令牌，family 为空时开始新的登录会话
+func (m *JWTManager) GeneratePair(address, family string) (*auth.TokenPair, error) {
+	if family == "" {
+		var err error
+		if family, err = generateTokenID(); err != nil {
+			return nil, err
+		}
+	}
+	
+	access, err := m.generate(address, TokenTypeAccess, family, m.expiration)
+	if err != nil {
+		return nil, err
+	}
+	
+	refresh, err := m.generate(address, TokenTypeRefresh, family, m.refreshExpiration)
+	if err != nil {
+		return nil, err
+	}
+	
+	return &auth.TokenPair{Access: access, Refresh: refresh}, nil
+}
+
+// RefreshExpiration 刷新令牌有效期
+func (m *JWTManager) RefreshExpiration() time.Duration {
+	return m.refreshExpiration
+}
+
+// generate 生成 JWT
+func (m *JWTManager) generate(address string, tokenType TokenType, family string, expiration time.Duration) (*auth.Token, error) {
+	id, err := generateTokenID()
+	if err != nil {
+		return nil, err
+	}
+	
 	now := time.Now()
-	expiresAt := now.Add(m.expiration)
+	expiresAt := now.Add(expiration)
 	
 	claims := Claims{
-		Address: strings.ToLower(address),
+		Address: user.NormalizeWalletID(address),
+		Type:    tokenType,
+		Family:  family,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        id,
 			ExpiresAt: jwt.NewNumericDate(expiresAt),
 			IssuedAt:  jwt.NewNumericDate(now),
 			NotBefore: jwt.NewNumericDate(now),
@@ -48,8 +144,11 @@ func (m *JWTManager) Generate(address string) (*auth.Token, error) {
 		},
 	}
 	
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString(m.secret)
+	token := jwt.NewWithClaims(m.signer.Method, claims)
+	if m.signer.ID != "" {
+		token.Header["kid"] = m.signer.ID
+	}
+	tokenString, err := token.SignedString(m.signer.privateKey)
 	if err != nil {
 		return nil, fmt.Errorf("failed to sign token: %w", err)
 	}
@@ -57,32 +156,61 @@ func (m *JWTManager) Generate(address string) (*auth.Token, error) {
 	return &auth.Token{
 		Value:     tokenString,
 		Address:   address,
+		ID:        id,
+		Family:    family,
 		ExpiresAt: expiresAt,
 		IssuedAt:  now,
 	}, nil
 }
 
-// Verify 验证 JWT
-func (m *JWTManager) Verify(tokenString string) (string, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		// 验证签名方法
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return m.secret, nil
-	})
+// Verify 验证指定类型的 JWT
+func (m *JWTManager) Verify(tokenString string, tokenType TokenType) (*Claims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, m.keyFunc, jwt.WithIssuer(m.issuer))
 	
 	if err != nil {
 		if errors.Is(err, jwt.ErrTokenExpired) {
-			return "", auth.ErrTokenExpired
+			return nil, auth.ErrTokenExpired
 		}
-		return "", fmt.Errorf("%w: %v", auth.ErrInvalidToken, err)
+		return nil, fmt.Errorf("%w: %v", auth.ErrInvalidToken, err)
+	}
+	
+	claims, ok := token.Claims.(*Claims)
+	if !ok || !token.Valid {
+		return nil, auth.ErrInvalidToken
+	}
+	
+	// 旧版本签发的令牌没有类型，视为访问令牌
+	if claims.Type == "" {
+		claims.Type = TokenTypeAccess
+	}
+	if claims.Type != tokenType {
+		return nil, fmt.Errorf("%w: unexpected token type %q", auth.ErrInvalidToken, claims.Type)
 	}
 	
-	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
-		return claims.Address, nil
+	return claims, nil
+}
+
+// keyFunc 根据 kid 选择验证密钥
+func (m *JWTManager) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := m.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key: %q", kid)
+	}
+	
+	// 验证签名方法，防止算法混淆
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 	}
 	
-	return "", auth.ErrInvalidToken
+	return key.publicKey, nil
 }
 
+// generateTokenID 生成令牌 ID
+func generateTokenID() (string, error) {
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", fmt.Errorf("failed to generate token id: %w", err)
+	}
+	return hex.EncodeToString(bytes), nil
+}
@@ -0,0 +1,310 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/yeying-community/webdav/internal/domain/auth"
+	"github.com/yeying-community/webdav/internal/infrastructure/config"
+)
+
+const testWalletAddress = "0x1111111111111111111111111111111111111111"
+
+// writeKeyPEM 将私钥写入 PEM 文件，返回私钥和公钥文件路径
+func writeKeyPEM(t *testing.T, name string, private interface{}) (string, string) {
+	t.Helper()
+
+	privateDER, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		t.Fatalf("MarshalPKCS8PrivateKey: %v", err)
+	}
+	var public interface{}
+	switch k := private.(type) {
+	case *rsa.PrivateKey:
+		public = &k.PublicKey
+	case *ecdsa.PrivateKey:
+		public = &k.PublicKey
+	case ed25519.PrivateKey:
+		public = k.Public()
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(public)
+	if err != nil {
+		t.Fatalf("MarshalPKIXPublicKey: %v", err)
+	}
+
+	dir := t.TempDir()
+	privatePath := filepath.Join(dir, name+".key")
+	publicPath := filepath.Join(dir, name+".pub")
+	if err := os.WriteFile(privatePath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(publicPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return privatePath, publicPath
+}
+
+func newRSAKey(t *testing.T, bits int) *rsa.PrivateKey {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, bits)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	return key
+}
+
+func newJWTManagerForTest(t *testing.T, secret string, keys ...config.SigningKeyConfig) *JWTManager {
+	t.Helper()
+
+	m, err := NewJWTManager(config.Web3Config{
+		JWTSecret:              secret,
+		SigningKeys:            keys,
+		TokenExpiration:        time.Hour,
+		RefreshTokenExpiration: 24 * time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("NewJWTManager: %v", err)
+	}
+	return m
+}
+
+func issueAccessToken(t *testing.T, m *JWTManager) string {
+	t.Helper()
+
+	pair, err := m.GeneratePair(testWalletAddress, "")
+	if err != nil {
+		t.Fatalf("GeneratePair: %v", err)
+	}
+	return pair.Access.Value
+}
+
+func tokenKeyID(t *testing.T, token string) string {
+	t.Helper()
+
+	parsed, _, err := jwt.NewParser().ParseUnverified(token, &Claims{})
+	if err != nil {
+		t.Fatalf("ParseUnverified: %v", err)
+	}
+	kid, _ := parsed.Header["kid"].(string)
+	return kid
+}
+
+func TestJWTKeyRotation(t *testing.T) {
+	const secret = "legacy-secret-legacy-secret-legacy"
+
+	oldPrivate, oldPublic := writeKeyPEM(t, "old", newRSAKey(t, 2048))
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newPrivate, _ := writeKeyPEM(t, "new", ecKey)
+
+	oldKey := config.SigningKeyConfig{ID: "2025", Algorithm: "RS256", PrivateKeyFile: oldPrivate}
+	oldVerifyOnly := config.SigningKeyConfig{ID: "2025", Algorithm: "RS256", PublicKeyFile: oldPublic}
+	newKey := config.SigningKeyConfig{ID: "2026", Algorithm: "ES256", PrivateKeyFile: newPrivate}
+
+	legacy := newJWTManagerForTest(t, secret)
+	before := newJWTManagerForTest(t, "", oldKey)
+	during := newJWTManagerForTest(t, secret, newKey, oldVerifyOnly)
+	after := newJWTManagerForTest(t, "", newKey)
+
+	legacyToken := issueAccessToken(t, legacy)
+	oldToken := issueAccessToken(t, before)
+	newToken := issueAccessToken(t, during)
+
+	if kid := tokenKeyID(t, legacyToken); kid != "" {
+		t.Fatalf("HS256 token kid = %q, want none", kid)
+	}
+	if kid := tokenKeyID(t, oldToken); kid != "2025" {
+		t.Fatalf("old token kid = %q, want 2025", kid)
+	}
+	// 轮换期间使用新密钥签发
+	if kid := tokenKeyID(t, newToken); kid != "2026" {
+		t.Fatalf("new token kid = %q, want 2026", kid)
+	}
+
+	tests := []struct {
+		name    string
+		manager *JWTManager
+		token   string
+		valid   bool
+	}{
+		{name: "old key before rotation", manager: before, token: oldToken, valid: true},
+		{name: "old kid during grace window", manager: during, token: oldToken, valid: true},
+		{name: "legacy hs256 during grace window", manager: during, token: legacyToken, valid: true},
+		{name: "new kid during grace window", manager: during, token: newToken, valid: true},
+		{name: "new kid before rotation", manager: before, token: newToken, valid: false},
+		{name: "old kid after grace window", manager: after, token: oldToken, valid: false},
+		{name: "legacy hs256 after grace window", manager: after, token: legacyToken, valid: false},
+		{name: "new kid after grace window", manager: after, token: newToken, valid: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := tt.manager.Verify(tt.token, TokenTypeAccess)
+			if !tt.valid {
+				if !errors.Is(err, auth.ErrInvalidToken) {
+					t.Fatalf("Verify = %v, want ErrInvalidToken", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Verify: %v", err)
+			}
+			if claims.Address != testWalletAddress {
+				t.Fatalf("Address = %q", claims.Address)
+			}
+		})
+	}
+
+	// 轮换期间 JWKS 同时发布新旧公钥，不发布对称密钥
+	jwks := during.JWKS()
+	var kids []string
+	for _, k := range jwks.Keys {
+		kids = append(kids, k.Kid)
+	}
+	if strings.Join(kids, ",") != "2026,2025" {
+		t.Fatalf("JWKS kids = %v, want [2026 2025]", kids)
+	}
+	if len(after.JWKS().Keys) != 1 || len(legacy.JWKS().Keys) != 0 {
+		t.Fatalf("unexpected JWKS sizes: after=%d legacy=%d", len(after.JWKS().Keys), len(legacy.JWKS().Keys))
+	}
+
+	// 发布的 RSA 公钥可以验证旧令牌
+	rsaJWK := jwks.Keys[1]
+	n, _ := base64.RawURLEncoding.DecodeString(rsaJWK.N)
+	e, _ := base64.RawURLEncoding.DecodeString(rsaJWK.E)
+	public := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	if _, err := jwt.Parse(oldToken, func(*jwt.Token) (interface{}, error) { return public, nil }); err != nil {
+		t.Fatalf("old token does not verify with published JWK: %v", err)
+	}
+}
+
+func TestJWTRejectsForgedTokens(t *testing.T) {
+	rsaKey := newRSAKey(t, 2048)
+	private, publicPath := writeKeyPEM(t, "rsa", rsaKey)
+	m := newJWTManagerForTest(t, "", config.SigningKeyConfig{ID: "k1", Algorithm: "RS256", PrivateKeyFile: private})
+
+	claims := Claims{
+		Address: testWalletAddress,
+		Type:    TokenTypeAccess,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "webdav-server",
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	}
+	sign := func(method jwt.SigningMethod, kid string, key interface{}) string {
+		token := jwt.NewWithClaims(method, claims)
+		token.Header["kid"] = kid
+		signed, err := token.SignedString(key)
+		if err != nil {
+			t.Fatalf("SignedString: %v", err)
+		}
+		return signed
+	}
+	publicPEM, err := os.ReadFile(publicPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		// 用公钥作为 HMAC 密钥伪造令牌（算法混淆）
+		{name: "hs256 with public key", token: sign(jwt.SigningMethodHS256, "k1", publicPEM)},
+		{name: "other rsa key", token: sign(jwt.SigningMethodRS256, "k1", newRSAKey(t, 2048))},
+		{name: "unknown kid", token: sign(jwt.SigningMethodRS256, "k2", rsaKey)},
+		{name: "missing kid", token: sign(jwt.SigningMethodRS256, "", rsaKey)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := m.Verify(tt.token, TokenTypeAccess); !errors.Is(err, auth.ErrInvalidToken) {
+				t.Fatalf("Verify = %v, want ErrInvalidToken", err)
+			}
+		})
+	}
+
+	if _, err := m.Verify(sign(jwt.SigningMethodRS256, "k1", rsaKey), TokenTypeAccess); err != nil {
+		t.Fatalf("control token: %v", err)
+	}
+}
+
+func TestLoadSigningKeys(t *testing.T) {
+	rsaPrivate, rsaPublic := writeKeyPEM(t, "rsa", newRSAKey(t, 2048))
+	weakPrivate, _ := writeKeyPEM(t, "weak", newRSAKey(t, 1024))
+	p256, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	p256Private, _ := writeKeyPEM(t, "p256", p256)
+	p384, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	p384Private, _ := writeKeyPEM(t, "p384", p384)
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	edPrivate, edPublic := writeKeyPEM(t, "ed", edKey)
+
+	tests := []struct {
+		name    string
+		cfg     config.SigningKeyConfig
+		canSign bool
+		wantErr bool
+	}{
+		{name: "rsa private", cfg: config.SigningKeyConfig{Algorithm: "RS256", PrivateKeyFile: rsaPrivate}, canSign: true},
+		{name: "rsa public only", cfg: config.SigningKeyConfig{Algorithm: "RS256", PublicKeyFile: rsaPublic}},
+		{name: "rsa too short", cfg: config.SigningKeyConfig{Algorithm: "RS256", PrivateKeyFile: weakPrivate}, wantErr: true},
+		{name: "es256", cfg: config.SigningKeyConfig{Algorithm: "ES256", PrivateKeyFile: p256Private}, canSign: true},
+		{name: "es256 with p-384 key", cfg: config.SigningKeyConfig{Algorithm: "ES256", PrivateKeyFile: p384Private}, wantErr: true},
+		{name: "eddsa", cfg: config.SigningKeyConfig{Algorithm: "EdDSA", PrivateKeyFile: edPrivate}, canSign: true},
+		{name: "eddsa public only", cfg: config.SigningKeyConfig{Algorithm: "EdDSA", PublicKeyFile: edPublic}},
+		{name: "algorithm mismatch", cfg: config.SigningKeyConfig{Algorithm: "ES256", PrivateKeyFile: rsaPrivate}, wantErr: true},
+		{name: "unsupported algorithm", cfg: config.SigningKeyConfig{Algorithm: "HS512", PrivateKeyFile: rsaPrivate}, wantErr: true},
+		{name: "missing file", cfg: config.SigningKeyConfig{Algorithm: "RS256", PrivateKeyFile: filepath.Join(t.TempDir(), "missing")}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.ID = "kid"
+			keys, err := LoadSigningKeys([]config.SigningKeyConfig{tt.cfg})
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("LoadSigningKeys should fail")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadSigningKeys: %v", err)
+			}
+			if keys[0].CanSign() != tt.canSign {
+				t.Fatalf("CanSign = %v, want %v", keys[0].CanSign(), tt.canSign)
+			}
+			if _, ok := keys[0].JWK(); !ok {
+				t.Fatal("asymmetric key should have a JWK")
+			}
+		})
+	}
+}
+
+func TestNewJWTManagerRequiresSigningKey(t *testing.T) {
+	_, publicPath := writeKeyPEM(t, "rsa", newRSAKey(t, 2048))
+
+	// 只有验证密钥时无法签发令牌
+	_, err := NewJWTManager(config.Web3Config{
+		SigningKeys: []config.SigningKeyConfig{{ID: "old", Algorithm: "RS256", PublicKeyFile: publicPath}},
+	})
+	if err == nil {
+		t.Fatal("NewJWTManager should fail without a signing key")
+	}
+}
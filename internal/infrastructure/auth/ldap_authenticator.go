@@ -0,0 +1,354 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/yeying-community/webdav/internal/domain/auth"
+	"github.com/yeying-community/webdav/internal/domain/user"
+	"github.com/yeying-community/webdav/internal/infrastructure/config"
+	"go.uber.org/zap"
+)
+
+// errLDAPUnavailable 目录服务不可用（连接、服务账号绑定或搜索失败）
+var errLDAPUnavailable = errors.New("ldap directory unavailable")
+
+// ldapEntry 目录中找到并验证过密码的用户
+type ldapEntry struct {
+	DN       string
+	Username string
+	Groups   map[string]bool // 组 DN 和组名，均为小写
+}
+
+// LDAPAuthenticator LDAP 认证器
+//
+// 先用服务账号按 user_filter 搜索用户条目，再以该条目的 DN 和用户密码绑定验证。
+// 目录中不存在的用户（或目录不可用时）回退到本地账号认证。
+// 应用密码总是由本地账号认证器验证。
+type LDAPAuthenticator struct {
+	cfg       config.LDAPConfig
+	userRepo  user.Repository
+	local     auth.Authenticator
+	fallback  auth.Authenticator
+	pool      *ldapPool
+	twoFactor *twoFactor
+	logger    *zap.Logger
+}
+
+// NewLDAPAuthenticator 创建 LDAP 认证器
+//
+// local 为本地账号认证器，为 nil 时不接受应用密码；cfg.Fallback 为 false 时不回退。
+func NewLDAPAuthenticator(
+	cfg config.LDAPConfig,
+	userRepo user.Repository,
+	local auth.Authenticator,
+	logger *zap.Logger,
+) (*LDAPAuthenticator, error) {
+	dial, err := NewLDAPDialer(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return NewLDAPAuthenticatorWithDialer(cfg, dial, userRepo, local, logger), nil
+}
+
+// NewLDAPAuthenticatorWithDialer 使用指定的连接函数创建 LDAP 认证器
+func NewLDAPAuthenticatorWithDialer(
+	cfg config.LDAPConfig,
+	dial LDAPDialer,
+	userRepo user.Repository,
+	local auth.Authenticator,
+	logger *zap.Logger,
+) *LDAPAuthenticator {
+	fallback := local
+	if !cfg.Fallback {
+		fallback = nil
+	}
+	return &LDAPAuthenticator{
+		cfg:       cfg,
+		userRepo:  userRepo,
+		local:     local,
+		fallback:  fallback,
+		pool:      newLDAPPool(dial, cfg.PoolSize),
+		twoFactor: newTwoFactor(userRepo, logger),
+		logger:    logger,
+	}
+}
+
+// Name 认证器名称
+func (a *LDAPAuthenticator) Name() string {
+	return "ldap"
+}
+
+// CanHandle 是否可以处理该凭证
+func (a *LDAPAuthenticator) CanHandle(credentials interface{}) bool {
+	if _, ok := credentials.(*auth.BasicCredentials); ok {
+		return true
+	}
+	return a.local != nil && a.local.CanHandle(credentials)
+}
+
+// Authenticate 认证用户
+func (a *LDAPAuthenticator) Authenticate(ctx context.Context, credentials interface{}) (*user.User, error) {
+	creds, ok := credentials.(*auth.BasicCredentials)
+	if !ok || user.IsAppPassword(creds.Password) {
+		if a.local == nil {
+			return nil, fmt.Errorf("invalid credentials type")
+		}
+		return a.local.Authenticate(ctx, credentials)
+	}
+
+	// 空密码的简单绑定在多数目录中是匿名绑定，会被误认为成功
+	if creds.Password == "" {
+		return nil, user.ErrInvalidPassword
+	}
+
+	entry, err := a.bind(creds.Username, creds.Password)
+	switch {
+	case err == nil:
+	case errors.Is(err, user.ErrUserNotFound):
+		if a.fallback != nil {
+			a.logger.Debug("user not in ldap directory, trying local account",
+				zap.String("username", creds.Username))
+			return a.fallback.Authenticate(ctx, credentials)
+		}
+		return nil, err
+	case errors.Is(err, errLDAPUnavailable):
+		a.logger.Warn("ldap directory unavailable", zap.Error(err))
+		if a.fallback != nil {
+			return a.fallback.Authenticate(ctx, credentials)
+		}
+		return nil, err
+	default:
+		a.logger.Warn("ldap authentication failed",
+			zap.String("username", creds.Username),
+			zap.Error(err))
+		return nil, err
+	}
+
+	u, err := a.resolveUser(ctx, entry, creds.OTP)
+	if err != nil {
+		return nil, err
+	}
+
+	a.logger.Info("user authenticated via ldap",
+		zap.String("username", u.Username),
+		zap.String("dn", entry.DN))
+
+	return u, nil
+}
+
+// Close 关闭连接池
+func (a *LDAPAuthenticator) Close() {
+	a.pool.Close()
+}
+
+// bind 搜索用户条目并以用户身份绑定验证密码
+func (a *LDAPAuthenticator) bind(username, password string) (entry *ldapEntry, err error) {
+	conn, err := a.pool.Get()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errLDAPUnavailable, err)
+	}
+
+	// 匿名搜索时连接在用户绑定后不能再复用
+	broken := a.cfg.BindDN == ""
+	defer func() {
+		if errors.Is(err, errLDAPUnavailable) {
+			broken = true
+		}
+		a.pool.Put(conn, broken)
+	}()
+
+	if err := a.bindService(conn); err != nil {
+		return nil, err
+	}
+
+	result, err := conn.Search(ldap.NewSearchRequest(
+		a.cfg.BaseDN,
+		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases,
+		2, int(a.cfg.Timeout.Seconds()), false,
+		strings.ReplaceAll(a.cfg.UserFilter, "{username}", ldap.EscapeFilter(username)),
+		a.userAttributes(),
+		nil,
+	))
+	if err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject) {
+			return nil, user.ErrUserNotFound
+		}
+		return nil, fmt.Errorf("%w: user search failed: %v", errLDAPUnavailable, err)
+	}
+
+	switch len(result.Entries) {
+	case 0:
+		return nil, user.ErrUserNotFound
+	case 1:
+	default:
+		return nil, fmt.Errorf("ldap user filter matched %d entries for %q", len(result.Entries), username)
+	}
+	found := result.Entries[0]
+
+	// 以用户身份绑定验证密码
+	if err := conn.Bind(found.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, user.ErrInvalidPassword
+		}
+		return nil, fmt.Errorf("%w: user bind failed: %v", errLDAPUnavailable, err)
+	}
+
+	entry = &ldapEntry{
+		DN:       found.DN,
+		Username: username,
+		Groups:   make(map[string]bool),
+	}
+	if a.cfg.UsernameAttribute != "" {
+		if canonical := found.GetAttributeValue(a.cfg.UsernameAttribute); canonical != "" {
+			entry.Username = canonical
+		}
+	}
+	entry.Username = strings.ToLower(entry.Username)
+
+	if a.cfg.MemberOfAttribute != "" {
+		for _, groupDN := range found.GetAttributeValues(a.cfg.MemberOfAttribute) {
+			addGroup(entry.Groups, groupDN, "")
+		}
+	}
+
+	if a.cfg.GroupBaseDN != "" {
+		if err := a.searchGroups(conn, entry); err != nil {
+			return nil, err
+		}
+	}
+
+	return entry, nil
+}
+
+// bindService 以服务账号绑定，未配置服务账号时使用连接的匿名状态
+func (a *LDAPAuthenticator) bindService(conn LDAPConn) error {
+	if a.cfg.BindDN == "" {
+		return nil
+	}
+	if err := conn.Bind(a.cfg.BindDN, a.cfg.BindPassword); err != nil {
+		return fmt.Errorf("%w: service bind failed: %v", errLDAPUnavailable, err)
+	}
+	return nil
+}
+
+// searchGroups 搜索用户所属的组
+func (a *LDAPAuthenticator) searchGroups(conn LDAPConn, entry *ldapEntry) error {
+	// 用户自身可能没有读取组的权限，切换回服务账号
+	if err := a.bindService(conn); err != nil {
+		return err
+	}
+
+	filter := strings.NewReplacer(
+		"{dn}", ldap.EscapeFilter(entry.DN),
+		"{username}", ldap.EscapeFilter(entry.Username),
+	).Replace(a.cfg.GroupFilter)
+
+	result, err := conn.Search(ldap.NewSearchRequest(
+		a.cfg.GroupBaseDN,
+		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases,
+		0, int(a.cfg.Timeout.Seconds()), false,
+		filter,
+		[]string{a.cfg.GroupNameAttribute},
+		nil,
+	))
+	if err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject) {
+			return nil
+		}
+		return fmt.Errorf("%w: group search failed: %v", errLDAPUnavailable, err)
+	}
+
+	for _, group := range result.Entries {
+		addGroup(entry.Groups, group.DN, group.GetAttributeValue(a.cfg.GroupNameAttribute))
+	}
+
+	return nil
+}
+
+// userAttributes 搜索用户时读取的属性
+func (a *LDAPAuthenticator) userAttributes() []string {
+	attributes := []string{"dn"}
+	if a.cfg.UsernameAttribute != "" {
+		attributes = append(attributes, a.cfg.UsernameAttribute)
+	}
+	if a.cfg.MemberOfAttribute != "" {
+		attributes = append(attributes, a.cfg.MemberOfAttribute)
+	}
+	return attributes
+}
+
+// resolveUser 查找或创建本地用户，验证两步验证码并应用组映射
+func (a *LDAPAuthenticator) resolveUser(ctx context.Context, entry *ldapEntry, otp string) (*user.User, error) {
+	u, err := a.userRepo.FindByUsername(ctx, entry.Username)
+	if errors.Is(err, user.ErrUserNotFound) {
+		var created bool
+		u, created, err = provisionUser(ctx, a.userRepo, entry.Username, a.cfg.Directory, a.cfg.DefaultPermissions)
+		if created {
+			a.logger.Info("ldap user provisioned",
+				zap.String("username", u.Username),
+				zap.String("directory", u.Directory))
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve ldap user: %w", err)
+	}
+
+	if err := a.twoFactor.verify(ctx, u, otp); err != nil {
+		return nil, err
+	}
+
+	return a.applyGroups(u, entry.Groups), nil
+}
+
+// applyGroups 根据组映射追加权限、规则和管理员角色
+//
+// 组规则按配置顺序排在用户自身规则之前。映射只作用于本次请求，不写回仓储，
+// 目录中的组变更立即生效。
+func (a *LDAPAuthenticator) applyGroups(u *user.User, groups map[string]bool) *user.User {
+	var matched []config.LDAPGroupConfig
+	for _, group := range a.cfg.Groups {
+		if groups[strings.ToLower(group.Name)] {
+			matched = append(matched, group)
+		}
+	}
+	if len(matched) == 0 {
+		return u
+	}
+
+	mapped := u.Clone()
+	if mapped.Permissions == nil {
+		mapped.Permissions = &user.Permissions{}
+	}
+
+	var rules []*user.Rule
+	for _, group := range matched {
+		grantPermissions(mapped.Permissions, group.Permissions)
+		if group.Admin {
+			mapped.Role = user.RoleAdmin
+		}
+		for _, ruleCfg := range group.Rules {
+			rules = append(rules, ruleCfg.ToRule())
+		}
+	}
+	mapped.Rules = append(rules, mapped.Rules...)
+
+	return mapped
+}
+
+// addGroup 记录组的 DN 和组名；未提供组名时使用 DN 的第一个 RDN 值
+func addGroup(groups map[string]bool, dn, name string) {
+	groups[strings.ToLower(dn)] = true
+
+	if name == "" {
+		if parsed, err := ldap.ParseDN(dn); err == nil && len(parsed.RDNs) > 0 && len(parsed.RDNs[0].Attributes) > 0 {
+			name = parsed.RDNs[0].Attributes[0].Value
+		}
+	}
+	if name != "" {
+		groups[strings.ToLower(name)] = true
+	}
+}
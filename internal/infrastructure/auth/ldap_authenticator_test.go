@@ -0,0 +1,348 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/yeying-community/webdav/internal/domain/auth"
+	"github.com/yeying-community/webdav/internal/domain/user"
+	"github.com/yeying-community/webdav/internal/infrastructure/config"
+	"github.com/yeying-community/webdav/internal/infrastructure/crypto"
+	"github.com/yeying-community/webdav/internal/infrastructure/repository"
+	"go.uber.org/zap"
+)
+
+const (
+	testLDAPBaseDN    = "dc=example,dc=com"
+	testLDAPServiceDN = "cn=service,dc=example,dc=com"
+)
+
+// fakeDirectory 目录服务替身
+//
+// 过滤器只支持等值和存在（attr=*）条件的合取，值按 ldap.EscapeFilter 转义后比较。
+type fakeDirectory struct {
+	mu        sync.Mutex
+	entries   map[string]*fakeEntry // DN -> 条目
+	down      bool
+	dials     int
+	userBinds int
+}
+
+type fakeEntry struct {
+	password   string
+	attributes map[string][]string
+}
+
+func newFakeDirectory() *fakeDirectory {
+	d := &fakeDirectory{entries: make(map[string]*fakeEntry)}
+	d.add(testLDAPServiceDN, "service-secret", nil)
+	return d
+}
+
+func (d *fakeDirectory) add(dn, password string, attributes map[string][]string) {
+	d.entries[strings.ToLower(dn)] = &fakeEntry{password: password, attributes: attributes}
+}
+
+func (d *fakeDirectory) dial() (LDAPConn, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.down {
+		return nil, errors.New("connection refused")
+	}
+	d.dials++
+	return &fakeLDAPConn{dir: d}, nil
+}
+
+type fakeLDAPConn struct {
+	dir    *fakeDirectory
+	bound  string
+	closed bool
+}
+
+func (c *fakeLDAPConn) Bind(username, password string) error {
+	c.dir.mu.Lock()
+	defer c.dir.mu.Unlock()
+
+	if !strings.EqualFold(username, testLDAPServiceDN) {
+		c.dir.userBinds++
+	}
+	entry, ok := c.dir.entries[strings.ToLower(username)]
+	if !ok || entry.password != password {
+		return ldap.NewError(ldap.LDAPResultInvalidCredentials, errors.New("invalid credentials"))
+	}
+	c.bound = username
+	return nil
+}
+
+var filterTerm = regexp.MustCompile(`\(([A-Za-z]+)=([^()]*)\)`)
+
+func (c *fakeLDAPConn) Search(request *ldap.SearchRequest) (*ldap.SearchResult, error) {
+	c.dir.mu.Lock()
+	defer c.dir.mu.Unlock()
+
+	if c.bound == "" {
+		return nil, ldap.NewError(ldap.LDAPResultInsufficientAccessRights, errors.New("anonymous search denied"))
+	}
+
+	terms := filterTerm.FindAllStringSubmatch(request.Filter, -1)
+	result := &ldap.SearchResult{}
+	for dn, entry := range c.dir.entries {
+		if !strings.HasSuffix(dn, ","+strings.ToLower(request.BaseDN)) {
+			continue
+		}
+		if matchesTerms(dn, entry, terms) {
+			e := &ldap.Entry{DN: dn}
+			for _, name := range request.Attributes {
+				if values, ok := entry.attributes[name]; ok {
+					e.Attributes = append(e.Attributes, &ldap.EntryAttribute{Name: name, Values: values})
+				}
+			}
+			result.Entries = append(result.Entries, e)
+		}
+	}
+	return result, nil
+}
+
+func matchesTerms(dn string, entry *fakeEntry, terms [][]string) bool {
+	for _, term := range terms {
+		attribute, want := term[1], term[2]
+		values := entry.attributes[attribute]
+		if attribute == "dn" {
+			values = []string{dn}
+		}
+		if want == "*" {
+			if len(values) == 0 && attribute != "objectClass" {
+				return false
+			}
+			continue
+		}
+		matched := false
+		for _, v := range values {
+			if strings.EqualFold(ldap.EscapeFilter(v), want) {
+				matched = true
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *fakeLDAPConn) Close() error {
+	c.closed = true
+	return nil
+}
+
+func (c *fakeLDAPConn) IsClosing() bool {
+	return c.closed
+}
+
+// newTestDirectory 包含 alice（属于 editors 组）和 carol（属于 admins 组）的目录
+func newTestDirectory() *fakeDirectory {
+	d := newFakeDirectory()
+	d.add("uid=alice,ou=people,dc=example,dc=com", "alice-ldap", map[string][]string{
+		"uid":      {"Alice"},
+		"memberOf": {"cn=editors,ou=groups,dc=example,dc=com"},
+	})
+	d.add("uid=carol,ou=people,dc=example,dc=com", "carol-ldap", map[string][]string{
+		"uid": {"carol"},
+	})
+	d.add("cn=admins,ou=groups,dc=example,dc=com", "", map[string][]string{
+		"cn":     {"admins"},
+		"member": {"uid=carol,ou=people,dc=example,dc=com"},
+	})
+	return d
+}
+
+func newTestLDAPAuthenticator(t *testing.T, dir *fakeDirectory, configure func(*config.LDAPConfig)) (*LDAPAuthenticator, user.Repository) {
+	t.Helper()
+
+	cfg := config.LDAPConfig{
+		Enabled:            true,
+		BindDN:             testLDAPServiceDN,
+		BindPassword:       "service-secret",
+		BaseDN:             "ou=people," + testLDAPBaseDN,
+		UserFilter:         "(&(objectClass=*)(uid={username}))",
+		UsernameAttribute:  "uid",
+		MemberOfAttribute:  "memberOf",
+		GroupBaseDN:        "ou=groups," + testLDAPBaseDN,
+		GroupFilter:        "(member={dn})",
+		GroupNameAttribute: "cn",
+		Timeout:            time.Second,
+		PoolSize:           2,
+		Fallback:           true,
+		Directory:          "/ldap/{username}",
+		DefaultPermissions: "R",
+		Groups: []config.LDAPGroupConfig{
+			{Name: "editors", Permissions: "CU", Rules: []config.RuleConfig{{Path: "/archive", Deny: true}}},
+			{Name: "cn=admins,ou=groups,dc=example,dc=com", Admin: true},
+		},
+	}
+	if configure != nil {
+		configure(&cfg)
+	}
+
+	repo := repository.NewMemoryUserRepository(nil)
+	hasher := crypto.NewPasswordHasher()
+	hashed, err := hasher.Hash("bob-local")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	bob := user.NewUser("bob", "/bob")
+	bob.SetPassword(hashed)
+	if err := repo.Save(context.Background(), bob); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	local := NewBasicAuthenticator(repo, hasher, false, "", zap.NewNop())
+	a := NewLDAPAuthenticatorWithDialer(cfg, dir.dial, repo, local, zap.NewNop())
+	t.Cleanup(a.Close)
+	return a, repo
+}
+
+func TestLDAPAuthenticate(t *testing.T) {
+	tests := []struct {
+		name      string
+		configure func(*config.LDAPConfig)
+		down      bool
+		username  string
+		password  string
+		want      string
+		wantErr   error
+	}{
+		{name: "directory user", username: "alice", password: "alice-ldap", want: "alice"},
+		{name: "canonical username", username: "ALICE", password: "alice-ldap", want: "alice"},
+		{name: "wrong password", username: "alice", password: "wrong", wantErr: user.ErrInvalidPassword},
+		{name: "empty password", username: "alice", password: "", wantErr: user.ErrInvalidPassword},
+		{name: "filter injection", username: "*", password: "alice-ldap", wantErr: user.ErrUserNotFound},
+		{name: "local user via fallback", username: "bob", password: "bob-local", want: "bob"},
+		{
+			name:      "local user without fallback",
+			configure: func(cfg *config.LDAPConfig) { cfg.Fallback = false },
+			username:  "bob", password: "bob-local",
+			wantErr: user.ErrUserNotFound,
+		},
+		{name: "directory down falls back", down: true, username: "bob", password: "bob-local", want: "bob"},
+		{
+			name:      "directory down without fallback",
+			configure: func(cfg *config.LDAPConfig) { cfg.Fallback = false },
+			down:      true,
+			username:  "alice", password: "alice-ldap",
+			wantErr: errLDAPUnavailable,
+		},
+		{
+			name:      "service account rejected",
+			configure: func(cfg *config.LDAPConfig) { cfg.BindPassword = "wrong" },
+			username:  "alice", password: "alice-ldap",
+			wantErr: user.ErrUserNotFound, // 回退到本地账号，alice 没有本地账号
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := newTestDirectory()
+			dir.down = tt.down
+			a, _ := newTestLDAPAuthenticator(t, dir, tt.configure)
+
+			u, err := a.Authenticate(context.Background(), &auth.BasicCredentials{Username: tt.username, Password: tt.password})
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Authenticate = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Authenticate: %v", err)
+			}
+			if u.Username != tt.want {
+				t.Fatalf("authenticated as %q, want %q", u.Username, tt.want)
+			}
+		})
+	}
+}
+
+func TestLDAPEmptyPasswordNeverBinds(t *testing.T) {
+	dir := newTestDirectory()
+	a, _ := newTestLDAPAuthenticator(t, dir, nil)
+
+	if _, err := a.Authenticate(context.Background(), &auth.BasicCredentials{Username: "alice"}); !errors.Is(err, user.ErrInvalidPassword) {
+		t.Fatalf("Authenticate = %v, want ErrInvalidPassword", err)
+	}
+	if dir.userBinds != 0 {
+		t.Fatalf("empty password triggered %d user binds", dir.userBinds)
+	}
+}
+
+func TestLDAPProvisionAndGroups(t *testing.T) {
+	ctx := context.Background()
+	dir := newTestDirectory()
+	a, repo := newTestLDAPAuthenticator(t, dir, nil)
+
+	// memberOf 中的组按组名匹配，组规则排在用户规则之前
+	alice, err := a.Authenticate(ctx, &auth.BasicCredentials{Username: "alice", Password: "alice-ldap"})
+	if err != nil {
+		t.Fatalf("Authenticate alice: %v", err)
+	}
+	if alice.Directory != "/ldap/alice" {
+		t.Fatalf("Directory = %q, want /ldap/alice", alice.Directory)
+	}
+	if !alice.Permissions.Read || !alice.Permissions.Create || !alice.Permissions.Update || alice.Permissions.Delete {
+		t.Fatalf("Permissions = %+v, want CRU", alice.Permissions)
+	}
+	if alice.IsAdmin() {
+		t.Fatal("alice should not be admin")
+	}
+	if alice.CanAccess("/archive/a.txt", "R") || !alice.CanAccess("/docs/a.txt", "C") {
+		t.Fatal("editors group rules not applied")
+	}
+
+	// 通过组搜索找到的组按 DN 匹配
+	carol, err := a.Authenticate(ctx, &auth.BasicCredentials{Username: "carol", Password: "carol-ldap"})
+	if err != nil {
+		t.Fatalf("Authenticate carol: %v", err)
+	}
+	if !carol.IsAdmin() || carol.Permissions.Create {
+		t.Fatalf("carol = role %q permissions %+v, want admin with R", carol.Role, carol.Permissions)
+	}
+
+	// 组映射不写回仓储
+	stored, err := repo.FindByUsername(ctx, "alice")
+	if err != nil {
+		t.Fatalf("FindByUsername: %v", err)
+	}
+	if stored.Permissions.Create || len(stored.Rules) != 0 {
+		t.Fatalf("group mapping persisted: %+v", stored)
+	}
+
+	// 离开组后立即失去组权限
+	dir.entries["uid=alice,ou=people,dc=example,dc=com"].attributes["memberOf"] = nil
+	alice, err = a.Authenticate(ctx, &auth.BasicCredentials{Username: "alice", Password: "alice-ldap"})
+	if err != nil {
+		t.Fatalf("Authenticate alice again: %v", err)
+	}
+	if alice.Permissions.Create || len(alice.Rules) != 0 {
+		t.Fatalf("stale group mapping: %+v", alice)
+	}
+}
+
+func TestLDAPReusesConnections(t *testing.T) {
+	dir := newTestDirectory()
+	a, _ := newTestLDAPAuthenticator(t, dir, nil)
+
+	for i := 0; i < 5; i++ {
+		if _, err := a.Authenticate(context.Background(), &auth.BasicCredentials{Username: "alice", Password: "alice-ldap"}); err != nil {
+			t.Fatalf("Authenticate: %v", err)
+		}
+	}
+	if dir.dials != 1 {
+		t.Fatalf("dialed %d times, want pooled connection", dir.dials)
+	}
+}
@@ -0,0 +1,123 @@
+package auth
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/yeying-community/webdav/internal/infrastructure/config"
+)
+
+// LDAPConn LDAP 连接，*ldap.Conn 满足该接口
+type LDAPConn interface {
+	Bind(username, password string) error
+	Search(request *ldap.SearchRequest) (*ldap.SearchResult, error)
+	Close() error
+	IsClosing() bool
+}
+
+// LDAPDialer 建立 LDAP 连接
+type LDAPDialer func() (LDAPConn, error)
+
+// NewLDAPDialer 根据配置创建连接函数，ldap:// 连接在 start_tls 时升级为 TLS
+func NewLDAPDialer(cfg config.LDAPConfig) (LDAPDialer, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ldap url: %w", err)
+	}
+
+	tlsConfig := &tls.Config{
+		ServerName:         u.Hostname(),
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+		MinVersion:         tls.VersionTLS12,
+	}
+	if cfg.CAFile != "" {
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ca_file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in ca_file")
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	return func() (LDAPConn, error) {
+		conn, err := ldap.DialURL(cfg.URL,
+			ldap.DialWithDialer(&net.Dialer{Timeout: cfg.Timeout}),
+			ldap.DialWithTLSConfig(tlsConfig))
+		if err != nil {
+			return nil, err
+		}
+		conn.SetTimeout(cfg.Timeout)
+
+		if cfg.StartTLS && u.Scheme == "ldap" {
+			if err := conn.StartTLS(tlsConfig); err != nil {
+				conn.Close()
+				return nil, fmt.Errorf("starttls failed: %w", err)
+			}
+		}
+
+		return conn, nil
+	}, nil
+}
+
+// ldapPool LDAP 连接池，复用空闲连接
+type ldapPool struct {
+	dial LDAPDialer
+	idle chan LDAPConn
+}
+
+// newLDAPPool 创建连接池，size 为空闲连接数上限
+func newLDAPPool(dial LDAPDialer, size int) *ldapPool {
+	return &ldapPool{
+		dial: dial,
+		idle: make(chan LDAPConn, size),
+	}
+}
+
+// Get 获取连接，没有可用的空闲连接时新建
+func (p *ldapPool) Get() (LDAPConn, error) {
+	for {
+		select {
+		case conn := <-p.idle:
+			if conn.IsClosing() {
+				continue
+			}
+			return conn, nil
+		default:
+			return p.dial()
+		}
+	}
+}
+
+// Put 归还连接，连接出错或池已满时关闭
+func (p *ldapPool) Put(conn LDAPConn, broken bool) {
+	if broken || conn.IsClosing() {
+		conn.Close()
+		return
+	}
+
+	select {
+	case p.idle <- conn:
+	default:
+		conn.Close()
+	}
+}
+
+// Close 关闭所有空闲连接
+func (p *ldapPool) Close() {
+	for {
+		select {
+		case conn := <-p.idle:
+			conn.Close()
+		default:
+			return
+		}
+	}
+}
@@ -0,0 +1,386 @@
+package auth
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/yeying-community/webdav/internal/domain/auth"
+	"github.com/yeying-community/webdav/internal/domain/user"
+	"github.com/yeying-community/webdav/internal/infrastructure/config"
+	"go.uber.org/zap"
+	"golang.org/x/oauth2"
+)
+
+const (
+	// oidcDiscoveryTimeout 发现文档请求超时
+	oidcDiscoveryTimeout = 10 * time.Second
+
+	// oidcStateTTL 授权码流程中 state 的有效期
+	oidcStateTTL = 10 * time.Minute
+)
+
+// OIDCLogin 授权码流程完成后的登录结果
+type OIDCLogin struct {
+	User         *user.User
+	IDToken      string // 可作为 Bearer 令牌访问 WebDAV
+	ExpiresAt    time.Time
+	RefreshToken string // 身份提供方下发的刷新令牌，可能为空
+}
+
+// OIDCAuthenticator OpenID Connect 认证器
+//
+// 校验身份提供方签发的 Bearer 令牌（ID Token 或 JWT 格式的访问令牌），
+// 并支持浏览器授权码登录流程。发现文档在首次使用时加载，身份提供方暂时不可用不影响启动。
+type OIDCAuthenticator struct {
+	cfg        config.OIDCConfig
+	userRepo   user.Repository
+	httpClient *http.Client
+	states     *oidcStateStore
+	logger     *zap.Logger
+
+	mu       sync.Mutex
+	provider *oidc.Provider
+	verifier *oidc.IDTokenVerifier
+}
+
+// NewOIDCAuthenticator 创建 OIDC 认证器
+//
+// httpClient 用于访问身份提供方，为 nil 时使用 http.DefaultClient。
+func NewOIDCAuthenticator(
+	cfg config.OIDCConfig,
+	userRepo user.Repository,
+	httpClient *http.Client,
+	logger *zap.Logger,
+) *OIDCAuthenticator {
+	return &OIDCAuthenticator{
+		cfg:        cfg,
+		userRepo:   userRepo,
+		httpClient: httpClient,
+		states:     newOIDCStateStore(),
+		logger:     logger,
+	}
+}
+
+// Name 认证器名称
+func (a *OIDCAuthenticator) Name() string {
+	return "oidc"
+}
+
+// CanHandle 是否可以处理该凭证
+//
+// 只处理签发者为配置的身份提供方的 JWT，其余 Bearer 令牌交给后续认证器。
+func (a *OIDCAuthenticator) CanHandle(credentials interface{}) bool {
+	creds, ok := credentials.(*auth.BearerCredentials)
+	if !ok {
+		return false
+	}
+	return unverifiedIssuer(creds.Token) == a.cfg.Issuer
+}
+
+// Authenticate 认证用户
+func (a *OIDCAuthenticator) Authenticate(ctx context.Context, credentials interface{}) (*user.User, error) {
+	creds, ok := credentials.(*auth.BearerCredentials)
+	if !ok {
+		return nil, fmt.Errorf("invalid credentials type")
+	}
+
+	verifier, err := a.getVerifier(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	idToken, err := verifier.Verify(a.clientContext(ctx), creds.Token)
+	if err != nil {
+		a.logger.Debug("oidc token verification failed", zap.Error(err))
+		var expired *oidc.TokenExpiredError
+		if errors.As(err, &expired) {
+			return nil, auth.ErrTokenExpired
+		}
+		return nil, fmt.Errorf("%w: %v", auth.ErrInvalidToken, err)
+	}
+
+	return a.resolveUser(ctx, idToken)
+}
+
+// AuthCodeURL 开始授权码流程，返回身份提供方的授权地址和 state
+func (a *OIDCAuthenticator) AuthCodeURL(ctx context.Context, redirectURL string) (string, string, error) {
+	provider, err := a.getProvider(ctx)
+	if err != nil {
+		return "", "", err
+	}
+
+	state, err := a.states.Create(redirectURL)
+	if err != nil {
+		return "", "", err
+	}
+
+	authURL := a.oauth2Config(provider, redirectURL).AuthCodeURL(state.State,
+		oidc.Nonce(state.Nonce),
+		oauth2.S256ChallengeOption(state.Verifier))
+
+	return authURL, state.State, nil
+}
+
+// Exchange 完成授权码流程：用授权码换取令牌并映射到用户
+func (a *OIDCAuthenticator) Exchange(ctx context.Context, stateValue, code string) (*OIDCLogin, error) {
+	state, ok := a.states.Take(stateValue)
+	if !ok {
+		return nil, auth.ErrChallengeExpired
+	}
+
+	provider, err := a.getProvider(ctx)
+	if err != nil {
+		return nil, err
+	}
+	verifier, err := a.getVerifier(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx = a.clientContext(ctx)
+	token, err := a.oauth2Config(provider, state.RedirectURL).Exchange(ctx, code, oauth2.VerifierOption(state.Verifier))
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange authorization code: %w", err)
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok || rawIDToken == "" {
+		return nil, fmt.Errorf("%w: token response has no id_token", auth.ErrInvalidToken)
+	}
+
+	idToken, err := verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", auth.ErrInvalidToken, err)
+	}
+	if idToken.Nonce != state.Nonce {
+		return nil, fmt.Errorf("%w: nonce mismatch", auth.ErrInvalidToken)
+	}
+
+	u, err := a.resolveUser(ctx, idToken)
+	if err != nil {
+		return nil, err
+	}
+
+	a.logger.Info("user authenticated via oidc code flow",
+		zap.String("username", u.Username),
+		zap.String("subject", idToken.Subject))
+
+	return &OIDCLogin{
+		User:         u,
+		IDToken:      rawIDToken,
+		ExpiresAt:    idToken.Expiry,
+		RefreshToken: token.RefreshToken,
+	}, nil
+}
+
+// resolveUser 根据令牌声明查找或创建用户，并应用组映射
+func (a *OIDCAuthenticator) resolveUser(ctx context.Context, idToken *oidc.IDToken) (*user.User, error) {
+	var claims map[string]interface{}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("%w: %v", auth.ErrInvalidToken, err)
+	}
+
+	username, err := a.usernameFromClaims(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	u, err := a.userRepo.FindByUsername(ctx, username)
+	if errors.Is(err, user.ErrUserNotFound) && a.cfg.AutoProvision {
+		u, err = a.provision(ctx, username)
+	}
+	if err != nil {
+		if errors.Is(err, user.ErrUserNotFound) {
+			a.logger.Debug("oidc user not found",
+				zap.String("username", username))
+		}
+		return nil, err
+	}
+
+	u = a.applyGroups(u, groupsFromClaims(claims[a.cfg.GroupsClaim]))
+
+	a.logger.Debug("user authenticated via oidc",
+		zap.String("username", u.Username),
+		zap.String("subject", idToken.Subject))
+
+	return u, nil
+}
+
+// usernameFromClaims 从声明中取得用户名
+func (a *OIDCAuthenticator) usernameFromClaims(claims map[string]interface{}) (string, error) {
+	username, _ := claims[a.cfg.UsernameClaim].(string)
+	username = strings.TrimSpace(username)
+	if username == "" {
+		return "", fmt.Errorf("%w: missing %s claim", auth.ErrInvalidToken, a.cfg.UsernameClaim)
+	}
+
+	// 邮箱作为用户名时要求已验证，且不区分大小写
+	if a.cfg.UsernameClaim == "email" {
+		if verified, ok := claims["email_verified"].(bool); ok && !verified {
+			return "", fmt.Errorf("%w: email not verified", auth.ErrInvalidToken)
+		}
+		username = strings.ToLower(username)
+	}
+
+	return username, nil
+}
+
+// provision 自动创建首次登录的用户
+func (a *OIDCAuthenticator) provision(ctx context.Context, username string) (*user.User, error) {
+	u, created, err := provisionUser(ctx, a.userRepo, username, a.cfg.Directory, a.cfg.DefaultPermissions)
+	if err != nil {
+		return nil, err
+	}
+
+	if created {
+		a.logger.Info("oidc user provisioned",
+			zap.String("username", username),
+			zap.String("directory", u.Directory))
+	}
+
+	return u, nil
+}
+
+// applyGroups 根据组追加权限和管理员角色
+//
+// 组映射只作用于本次请求，不写回仓储，身份提供方中的组变更立即生效。
+func (a *OIDCAuthenticator) applyGroups(u *user.User, groups []string) *user.User {
+	if len(groups) == 0 || (len(a.cfg.GroupPermissions) == 0 && len(a.cfg.AdminGroups) == 0) {
+		return u
+	}
+
+	mapped := u.Clone()
+	if mapped.Permissions == nil {
+		mapped.Permissions = &user.Permissions{}
+	}
+
+	for _, group := range groups {
+		if perms, ok := a.cfg.GroupPermissions[group]; ok {
+			grantPermissions(mapped.Permissions, perms)
+		}
+		for _, adminGroup := range a.cfg.AdminGroups {
+			if group == adminGroup {
+				mapped.Role = user.RoleAdmin
+			}
+		}
+	}
+
+	return mapped
+}
+
+// getProvider 获取身份提供方，首次调用时加载发现文档
+func (a *OIDCAuthenticator) getProvider(ctx context.Context) (*oidc.Provider, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.provider != nil {
+		return a.provider, nil
+	}
+
+	ctx, cancel := context.WithTimeout(a.clientContext(ctx), oidcDiscoveryTimeout)
+	defer cancel()
+
+	provider, err := oidc.NewProvider(ctx, a.cfg.Issuer)
+	if err != nil {
+		a.logger.Warn("oidc discovery failed",
+			zap.String("issuer", a.cfg.Issuer),
+			zap.Error(err))
+		return nil, fmt.Errorf("oidc discovery failed: %w", err)
+	}
+
+	audience := a.cfg.Audience
+	if audience == "" {
+		audience = a.cfg.ClientID
+	}
+
+	a.provider = provider
+	a.verifier = provider.Verifier(&oidc.Config{ClientID: audience})
+
+	a.logger.Info("oidc provider discovered",
+		zap.String("issuer", a.cfg.Issuer))
+
+	return provider, nil
+}
+
+// getVerifier 获取令牌验证器
+func (a *OIDCAuthenticator) getVerifier(ctx context.Context) (*oidc.IDTokenVerifier, error) {
+	if _, err := a.getProvider(ctx); err != nil {
+		return nil, err
+	}
+	return a.verifier, nil
+}
+
+// oauth2Config 授权码流程的 OAuth2 配置
+func (a *OIDCAuthenticator) oauth2Config(provider *oidc.Provider, redirectURL string) *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     a.cfg.ClientID,
+		ClientSecret: a.cfg.ClientSecret,
+		Endpoint:     provider.Endpoint(),
+		RedirectURL:  redirectURL,
+		Scopes:       a.cfg.Scopes,
+	}
+}
+
+// clientContext 在上下文中设置访问身份提供方的 HTTP 客户端
+func (a *OIDCAuthenticator) clientContext(ctx context.Context) context.Context {
+	if a.httpClient == nil {
+		return ctx
+	}
+	return oidc.ClientContext(ctx, a.httpClient)
+}
+
+// RedirectURL 回调地址，未配置时根据请求来源生成
+func (a *OIDCAuthenticator) RedirectURL(origin RequestOrigin) string {
+	if a.cfg.RedirectURL != "" {
+		return a.cfg.RedirectURL
+	}
+	return origin.URI() + "/api/auth/oidc/callback"
+}
+
+// unverifiedIssuer 读取 JWT 中未经验证的 iss 声明，仅用于选择认证器
+func unverifiedIssuer(token string) string {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return ""
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return ""
+	}
+
+	var claims struct {
+		Issuer string `json:"iss"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return ""
+	}
+
+	return claims.Issuer
+}
+
+// groupsFromClaims 解析组声明，支持字符串数组和单个字符串
+func groupsFromClaims(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		groups := make([]string, 0, len(v))
+		for _, item := range v {
+			if group, ok := item.(string); ok {
+				groups = append(groups, group)
+			}
+		}
+		return groups
+	default:
+		return nil
+	}
+}
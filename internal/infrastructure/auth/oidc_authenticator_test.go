@@ -0,0 +1,386 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/yeying-community/webdav/internal/domain/auth"
+	"github.com/yeying-community/webdav/internal/domain/user"
+	"github.com/yeying-community/webdav/internal/infrastructure/config"
+	"github.com/yeying-community/webdav/internal/infrastructure/repository"
+	"go.uber.org/zap"
+)
+
+const testOIDCClientID = "webdav"
+
+// fakeIssuer 身份提供方替身，提供发现文档、JWKS 和令牌端点
+type fakeIssuer struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	kid    string
+
+	mu      sync.Mutex
+	idToken string // 令牌端点返回的 id_token
+}
+
+func newFakeIssuer(t *testing.T) *fakeIssuer {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	f := &fakeIssuer{key: key, kid: "test-key"}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]interface{}{
+			"issuer":                                f.server.URL,
+			"authorization_endpoint":                f.server.URL + "/authorize",
+			"token_endpoint":                        f.server.URL + "/token",
+			"jwks_uri":                              f.server.URL + "/jwks",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": f.kid,
+				"use": "sig",
+				"alg": "RS256",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("code") != "good-code" || r.FormValue("code_verifier") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			writeJSON(w, map[string]string{"error": "invalid_grant"})
+			return
+		}
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		writeJSON(w, map[string]interface{}{
+			"access_token":  "opaque-access-token",
+			"token_type":    "Bearer",
+			"expires_in":    3600,
+			"refresh_token": "idp-refresh-token",
+			"id_token":      f.idToken,
+		})
+	})
+
+	f.server = httptest.NewServer(mux)
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// claims 有效令牌的默认声明
+func (f *fakeIssuer) claims(username string) jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"iss":                f.server.URL,
+		"sub":                "subject-" + username,
+		"aud":                testOIDCClientID,
+		"iat":                now.Unix(),
+		"exp":                now.Add(time.Hour).Unix(),
+		"preferred_username": username,
+	}
+}
+
+// sign 使用签发者的密钥签名令牌
+func (f *fakeIssuer) sign(t *testing.T, claims jwt.MapClaims) string {
+	t.Helper()
+	return signRS256(t, f.key, f.kid, claims)
+}
+
+func signRS256(t *testing.T, key *rsa.PrivateKey, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("SignedString: %v", err)
+	}
+	return signed
+}
+
+func newTestOIDCAuthenticator(t *testing.T, issuer *fakeIssuer, configure func(*config.OIDCConfig)) (*OIDCAuthenticator, user.Repository) {
+	t.Helper()
+
+	cfg := config.OIDCConfig{
+		Enabled:       true,
+		Issuer:        issuer.server.URL,
+		ClientID:      testOIDCClientID,
+		ClientSecret:  "secret",
+		Scopes:        []string{"openid", "profile"},
+		UsernameClaim: "preferred_username",
+		GroupsClaim:   "groups",
+	}
+	if configure != nil {
+		configure(&cfg)
+	}
+
+	repo := repository.NewMemoryUserRepository(nil)
+	alice := user.NewUser("alice", "/alice")
+	alice.Permissions = user.ParsePermissions("R")
+	if err := repo.Save(context.Background(), alice); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	return NewOIDCAuthenticator(cfg, repo, issuer.server.Client(), zap.NewNop()), repo
+}
+
+func TestOIDCAuthenticateBearer(t *testing.T) {
+	issuer := newFakeIssuer(t)
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		token   func() string
+		wantErr error
+	}{
+		{
+			name:  "valid token",
+			token: func() string { return issuer.sign(t, issuer.claims("alice")) },
+		},
+		{
+			name: "audience in list",
+			token: func() string {
+				c := issuer.claims("alice")
+				c["aud"] = []string{"other-client", testOIDCClientID}
+				return issuer.sign(t, c)
+			},
+		},
+		{
+			name: "wrong audience",
+			token: func() string {
+				c := issuer.claims("alice")
+				c["aud"] = "other-client"
+				return issuer.sign(t, c)
+			},
+			wantErr: auth.ErrInvalidToken,
+		},
+		{
+			name: "wrong issuer",
+			token: func() string {
+				c := issuer.claims("alice")
+				c["iss"] = "https://evil.example.com"
+				return issuer.sign(t, c)
+			},
+			wantErr: auth.ErrInvalidToken,
+		},
+		{
+			name: "expired token",
+			token: func() string {
+				c := issuer.claims("alice")
+				c["iat"] = time.Now().Add(-2 * time.Hour).Unix()
+				c["exp"] = time.Now().Add(-time.Hour).Unix()
+				return issuer.sign(t, c)
+			},
+			wantErr: auth.ErrTokenExpired,
+		},
+		{
+			name:    "signed by unknown key",
+			token:   func() string { return signRS256(t, otherKey, issuer.kid, issuer.claims("alice")) },
+			wantErr: auth.ErrInvalidToken,
+		},
+		{
+			name: "missing username claim",
+			token: func() string {
+				c := issuer.claims("alice")
+				delete(c, "preferred_username")
+				return issuer.sign(t, c)
+			},
+			wantErr: auth.ErrInvalidToken,
+		},
+		{
+			name:    "unknown user without auto provisioning",
+			token:   func() string { return issuer.sign(t, issuer.claims("mallory")) },
+			wantErr: user.ErrUserNotFound,
+		},
+	}
+
+	a, _ := newTestOIDCAuthenticator(t, issuer, nil)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := a.Authenticate(context.Background(), &auth.BearerCredentials{Token: tt.token()})
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Authenticate = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Authenticate: %v", err)
+			}
+			if u.Username != "alice" {
+				t.Fatalf("authenticated as %q, want alice", u.Username)
+			}
+		})
+	}
+}
+
+func TestOIDCCanHandle(t *testing.T) {
+	issuer := newFakeIssuer(t)
+	a, _ := newTestOIDCAuthenticator(t, issuer, nil)
+
+	foreign := issuer.claims("alice")
+	foreign["iss"] = "https://other.example.com"
+
+	tests := []struct {
+		name        string
+		credentials interface{}
+		want        bool
+	}{
+		{name: "token from issuer", credentials: &auth.BearerCredentials{Token: issuer.sign(t, issuer.claims("alice"))}, want: true},
+		{name: "token from other issuer", credentials: &auth.BearerCredentials{Token: issuer.sign(t, foreign)}, want: false},
+		{name: "opaque token", credentials: &auth.BearerCredentials{Token: "opaque"}, want: false},
+		{name: "basic credentials", credentials: &auth.BasicCredentials{Username: "alice", Password: "x"}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := a.CanHandle(tt.credentials); got != tt.want {
+				t.Fatalf("CanHandle = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOIDCProvisionAndGroups(t *testing.T) {
+	issuer := newFakeIssuer(t)
+	a, repo := newTestOIDCAuthenticator(t, issuer, func(cfg *config.OIDCConfig) {
+		cfg.AutoProvision = true
+		cfg.Directory = "/oidc/{username}"
+		cfg.DefaultPermissions = "R"
+		cfg.GroupPermissions = map[string]string{"editors": "CU"}
+		cfg.AdminGroups = []string{"admins"}
+	})
+
+	claims := issuer.claims("bob")
+	claims["groups"] = []string{"editors", "admins"}
+
+	u, err := a.Authenticate(context.Background(), &auth.BearerCredentials{Token: issuer.sign(t, claims)})
+	if err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	if u.Directory != "/oidc/bob" {
+		t.Fatalf("Directory = %q, want /oidc/bob", u.Directory)
+	}
+	if !u.Permissions.Read || !u.Permissions.Create || !u.Permissions.Update || u.Permissions.Delete {
+		t.Fatalf("Permissions = %+v, want CRU", u.Permissions)
+	}
+	if u.Role != user.RoleAdmin {
+		t.Fatalf("Role = %q, want admin", u.Role)
+	}
+
+	// 组映射只作用于本次请求
+	stored, err := repo.FindByUsername(context.Background(), "bob")
+	if err != nil {
+		t.Fatalf("FindByUsername: %v", err)
+	}
+	if stored.Permissions.Create || stored.Role == user.RoleAdmin {
+		t.Fatalf("group mapping persisted: %+v", stored)
+	}
+}
+
+func TestOIDCCodeFlow(t *testing.T) {
+	const redirectURL = "https://dav.example.com/api/auth/oidc/callback"
+
+	tests := []struct {
+		name    string
+		nonce   func(actual string) string
+		code    string
+		wantErr bool
+	}{
+		{name: "valid code", nonce: func(n string) string { return n }, code: "good-code"},
+		{name: "nonce mismatch", nonce: func(string) string { return "replayed" }, code: "good-code", wantErr: true},
+		{name: "rejected code", nonce: func(n string) string { return n }, code: "bad-code", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			issuer := newFakeIssuer(t)
+			a, _ := newTestOIDCAuthenticator(t, issuer, nil)
+
+			authURL, state, err := a.AuthCodeURL(ctx, redirectURL)
+			if err != nil {
+				t.Fatalf("AuthCodeURL: %v", err)
+			}
+			parsed, err := url.Parse(authURL)
+			if err != nil {
+				t.Fatalf("parse auth url: %v", err)
+			}
+			query := parsed.Query()
+			if query.Get("state") != state || query.Get("redirect_uri") != redirectURL || query.Get("code_challenge_method") != "S256" {
+				t.Fatalf("unexpected auth url %s", authURL)
+			}
+
+			claims := issuer.claims("alice")
+			claims["nonce"] = tt.nonce(query.Get("nonce"))
+			issuer.mu.Lock()
+			issuer.idToken = issuer.sign(t, claims)
+			issuer.mu.Unlock()
+
+			login, err := a.Exchange(ctx, state, tt.code)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Exchange should fail")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Exchange: %v", err)
+			}
+			if login.User.Username != "alice" || login.RefreshToken != "idp-refresh-token" {
+				t.Fatalf("unexpected login %+v", login)
+			}
+
+			// ID Token 可以作为 Bearer 令牌使用
+			if _, err := a.Authenticate(ctx, &auth.BearerCredentials{Token: login.IDToken}); err != nil {
+				t.Fatalf("Authenticate id token: %v", err)
+			}
+
+			// state 只能使用一次
+			if _, err := a.Exchange(ctx, state, tt.code); !errors.Is(err, auth.ErrChallengeExpired) {
+				t.Fatalf("reused state = %v, want ErrChallengeExpired", err)
+			}
+		})
+	}
+}
+
+func TestOIDCIssuerUnavailable(t *testing.T) {
+	issuer := newFakeIssuer(t)
+	a, _ := newTestOIDCAuthenticator(t, issuer, nil)
+	token := issuer.sign(t, issuer.claims("alice"))
+	issuer.server.Close()
+
+	if _, err := a.Authenticate(context.Background(), &auth.BearerCredentials{Token: token}); err == nil {
+		t.Fatal("Authenticate should fail when discovery is unavailable")
+	}
+}
@@ -0,0 +1,97 @@
+package auth
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// oidcState 授权码流程中等待回调的登录请求
+type oidcState struct {
+	State       string
+	Nonce       string
+	Verifier    string // PKCE code_verifier
+	RedirectURL string
+	ExpiresAt   time.Time
+}
+
+// oidcStateStore 授权码流程 state 存储
+type oidcStateStore struct {
+	states map[string]*oidcState
+	mu     sync.Mutex
+}
+
+// newOIDCStateStore 创建 state 存储
+func newOIDCStateStore() *oidcStateStore {
+	store := &oidcStateStore{
+		states: make(map[string]*oidcState),
+	}
+
+	// 启动清理协程
+	go store.cleanupExpired()
+
+	return store
+}
+
+// Create 创建新的 state
+func (s *oidcStateStore) Create(redirectURL string) (*oidcState, error) {
+	state, err := generateNonce()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate state: %w", err)
+	}
+	nonce, err := generateNonce()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	entry := &oidcState{
+		State:       state,
+		Nonce:       nonce,
+		Verifier:    oauth2.GenerateVerifier(),
+		RedirectURL: redirectURL,
+		ExpiresAt:   time.Now().Add(oidcStateTTL),
+	}
+
+	s.mu.Lock()
+	s.states[state] = entry
+	s.mu.Unlock()
+
+	return entry, nil
+}
+
+// Take 取出并删除 state，每个 state 只能使用一次
+func (s *oidcStateStore) Take(state string) (*oidcState, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.states[state]
+	if !ok {
+		return nil, false
+	}
+	delete(s.states, state)
+
+	if time.Now().After(entry.ExpiresAt) {
+		return nil, false
+	}
+
+	return entry, true
+}
+
+// cleanupExpired 清理过期 state
+func (s *oidcStateStore) cleanupExpired() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		s.mu.Lock()
+		now := time.Now()
+		for key, entry := range s.states {
+			if now.After(entry.ExpiresAt) {
+				delete(s.states, key)
+			}
+		}
+		s.mu.Unlock()
+	}
+}
@@ -0,0 +1,43 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/yeying-community/webdav/internal/domain/user"
+)
+
+// provisionUser 为外部身份源（OIDC、LDAP）首次登录的用户创建本地用户
+//
+// 目录模板中的 {username} 替换为用户名。并发登录时用户可能已被其他请求创建，
+// 此时返回已有用户，created 为 false。
+func provisionUser(ctx context.Context, repo user.Repository, username, directoryTemplate, permissions string) (u *user.User, created bool, err error) {
+	if strings.ContainsAny(username, `/\`) || username == "." || username == ".." {
+		return nil, false, user.ErrInvalidUsername
+	}
+
+	directory := strings.ReplaceAll(directoryTemplate, "{username}", username)
+	u = user.NewUser(username, directory)
+	u.Permissions = user.ParsePermissions(permissions)
+
+	if err := repo.Save(ctx, u); err != nil {
+		if errors.Is(err, user.ErrDuplicateUsername) {
+			u, err = repo.FindByUsername(ctx, username)
+			return u, false, err
+		}
+		return nil, false, fmt.Errorf("failed to provision user: %w", err)
+	}
+
+	return u, true, nil
+}
+
+// grantPermissions 向 dst 追加权限字符串（CRUD）中的权限
+func grantPermissions(dst *user.Permissions, perms string) {
+	granted := user.ParsePermissions(perms)
+	dst.Create = dst.Create || granted.Create
+	dst.Read = dst.Read || granted.Read
+	dst.Update = dst.Update || granted.Update
+	dst.Delete = dst.Delete || granted.Delete
+}
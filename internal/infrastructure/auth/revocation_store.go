@@ -0,0 +1,245 @@
+package auth
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/yeying-community/webdav/internal/infrastructure/config"
+	bolt "go.etcd.io/bbolt"
+)
+
+// revocationCleanupInterval 过期吊销记录的清理间隔
+const revocationCleanupInterval = time.Hour
+
+// RevocationStore 令牌吊销列表
+//
+// 记录在令牌本身过期后不再需要，expiresAt 之后可以被清理。
+type RevocationStore interface {
+	// Revoke 吊销 key（jti 或 family）直到 expiresAt
+	Revoke(ctx context.Context, key string, expiresAt time.Time) error
+
+	// IsRevoked 是否已吊销
+	IsRevoked(ctx context.Context, key string) (bool, error)
+
+	// RevokeIfAbsent 原子地检查并吊销 key，返回调用前是否已被吊销
+	//
+	// 已吊销时不修改原有记录。用于只能使用一次的令牌，并发请求中只有一个会得到 false。
+	RevokeIfAbsent(ctx context.Context, key string, expiresAt time.Time) (bool, error)
+
+	// Close 关闭存储
+	Close() error
+}
+
+// NewRevocationStore 根据配置创建令牌吊销列表
+func NewRevocationStore(cfg config.TokenStorageConfig) (RevocationStore, error) {
+	switch cfg.Driver {
+	case "", "memory":
+		return NewMemoryRevocationStore(), nil
+	case "bolt":
+		return NewBoltRevocationStore(cfg.Path)
+	default:
+		return nil, fmt.Errorf("unsupported tokens driver: %s", cfg.Driver)
+	}
+}
+
+// MemoryRevocationStore 内存令牌吊销列表（重启后丢失）
+type MemoryRevocationStore struct {
+	entries map[string]time.Time
+	mu      sync.RWMutex
+	stop    chan struct{}
+}
+
+// NewMemoryRevocationStore 创建内存令牌吊销列表
+func NewMemoryRevocationStore() *MemoryRevocationStore {
+	store := &MemoryRevocationStore{
+		entries: make(map[string]time.Time),
+		stop:    make(chan struct{}),
+	}
+
+	// 启动清理协程
+	go store.cleanupExpired()
+
+	return store
+}
+
+// Revoke 吊销
+func (s *MemoryRevocationStore) Revoke(ctx context.Context, key string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if current, ok := s.entries[key]; !ok || expiresAt.After(current) {
+		s.entries[key] = expiresAt
+	}
+	return nil
+}
+
+// IsRevoked 是否已吊销
+func (s *MemoryRevocationStore) IsRevoked(ctx context.Context, key string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, ok := s.entries[key]
+	return ok, nil
+}
+
+// RevokeIfAbsent 未吊销时吊销，返回调用前是否已被吊销
+func (s *MemoryRevocationStore) RevokeIfAbsent(ctx context.Context, key string, expiresAt time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.entries[key]; ok {
+		return true, nil
+	}
+	s.entries[key] = expiresAt
+	return false, nil
+}
+
+// Close 停止清理协程
+func (s *MemoryRevocationStore) Close() error {
+	close(s.stop)
+	return nil
+}
+
+// cleanupExpired 清理过期记录
+func (s *MemoryRevocationStore) cleanupExpired() {
+	ticker := time.NewTicker(revocationCleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case now := <-ticker.C:
+			s.mu.Lock()
+			for key, expiresAt := range s.entries {
+				if now.After(expiresAt) {
+					delete(s.entries, key)
+				}
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+// revokedBucket 吊销记录数据桶
+var revokedBucket = []byte("revoked")
+
+// BoltRevocationStore BoltDB 令牌吊销列表（重启后保留）
+type BoltRevocationStore struct {
+	db   *bolt.DB
+	stop chan struct{}
+}
+
+// NewBoltRevocationStore 创建 BoltDB 令牌吊销列表
+func NewBoltRevocationStore(path string) (*BoltRevocationStore, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open token database: %w", err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(revokedBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize token database: %w", err)
+	}
+
+	store := &BoltRevocationStore{
+		db:   db,
+		stop: make(chan struct{}),
+	}
+
+	// 启动清理协程
+	go store.cleanupExpired()
+
+	return store, nil
+}
+
+// Revoke 吊销
+func (s *BoltRevocationStore) Revoke(ctx context.Context, key string, expiresAt time.Time) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(revokedBucket)
+		if current := bucket.Get([]byte(key)); len(current) == 8 {
+			if int64(binary.BigEndian.Uint64(current)) >= expiresAt.Unix() {
+				return nil
+			}
+		}
+
+		value := make([]byte, 8)
+		binary.BigEndian.PutUint64(value, uint64(expiresAt.Unix()))
+		return bucket.Put([]byte(key), value)
+	})
+}
+
+// IsRevoked 是否已吊销
+func (s *BoltRevocationStore) IsRevoked(ctx context.Context, key string) (bool, error) {
+	revoked := false
+	err := s.db.View(func(tx *bolt.Tx) error {
+		revoked = tx.Bucket(revokedBucket).Get([]byte(key)) != nil
+		return nil
+	})
+	return revoked, err
+}
+
+// RevokeIfAbsent 未吊销时吊销，返回调用前是否已被吊销
+func (s *BoltRevocationStore) RevokeIfAbsent(ctx context.Context, key string, expiresAt time.Time) (bool, error) {
+	revoked := false
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(revokedBucket)
+		if bucket.Get([]byte(key)) != nil {
+			revoked = true
+			return nil
+		}
+
+		value := make([]byte, 8)
+		binary.BigEndian.PutUint64(value, uint64(expiresAt.Unix()))
+		return bucket.Put([]byte(key), value)
+	})
+	return revoked, err
+}
+
+// Close 停止清理协程并关闭数据库
+func (s *BoltRevocationStore) Close() error {
+	close(s.stop)
+	return s.db.Close()
+}
+
+// cleanupExpired 清理过期记录
+func (s *BoltRevocationStore) cleanupExpired() {
+	ticker := time.NewTicker(revocationCleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case now := <-ticker.C:
+			_ = s.db.Update(func(tx *bolt.Tx) error {
+				bucket := tx.Bucket(revokedBucket)
+
+				var expired [][]byte
+				err := bucket.ForEach(func(k, v []byte) error {
+					if len(v) == 8 && now.Unix() > int64(binary.BigEndian.Uint64(v)) {
+						expired = append(expired, append([]byte(nil), k...))
+					}
+					return nil
+				})
+				if err != nil {
+					return err
+				}
+
+				for _, k := range expired {
+					if err := bucket.Delete(k); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+		}
+	}
+}
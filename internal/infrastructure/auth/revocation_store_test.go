@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// revocationStores 需要覆盖的吊销列表实现
+var revocationStores = map[string]func(t *testing.T) RevocationStore{
+	"memory": func(t *testing.T) RevocationStore {
+		return NewMemoryRevocationStore()
+	},
+	"bolt": func(t *testing.T) RevocationStore {
+		s, err := NewBoltRevocationStore(filepath.Join(t.TempDir(), "tokens.db"))
+		if err != nil {
+			t.Fatalf("NewBoltRevocationStore: %v", err)
+		}
+		return s
+	},
+}
+
+func TestRevokeIfAbsent(t *testing.T) {
+	for name, newStore := range revocationStores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			s := newStore(t)
+			defer s.Close()
+			expiresAt := time.Now().Add(time.Hour)
+
+			revoked, err := s.RevokeIfAbsent(ctx, "jti:a", expiresAt)
+			if err != nil || revoked {
+				t.Fatalf("first RevokeIfAbsent = %v, %v, want false", revoked, err)
+			}
+			if ok, _ := s.IsRevoked(ctx, "jti:a"); !ok {
+				t.Fatal("key should be revoked")
+			}
+			revoked, err = s.RevokeIfAbsent(ctx, "jti:a", expiresAt)
+			if err != nil || !revoked {
+				t.Fatalf("second RevokeIfAbsent = %v, %v, want true", revoked, err)
+			}
+
+			// 已通过 Revoke 吊销的 key 同样视为已吊销
+			if err := s.Revoke(ctx, "family:b", expiresAt); err != nil {
+				t.Fatalf("Revoke: %v", err)
+			}
+			if revoked, _ := s.RevokeIfAbsent(ctx, "family:b", expiresAt); !revoked {
+				t.Fatal("RevokeIfAbsent should report a key revoked by Revoke")
+			}
+		})
+	}
+}
+
+func TestRevokeIfAbsentConcurrent(t *testing.T) {
+	for name, newStore := range revocationStores {
+		t.Run(name, func(t *testing.T) {
+			s := newStore(t)
+			defer s.Close()
+
+			var (
+				wg    sync.WaitGroup
+				mu    sync.Mutex
+				first int
+			)
+			for i := 0; i < 16; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					revoked, err := s.RevokeIfAbsent(context.Background(), "jti:c", time.Now().Add(time.Hour))
+					if err != nil {
+						t.Errorf("RevokeIfAbsent: %v", err)
+					}
+					if !revoked {
+						mu.Lock()
+						first++
+						mu.Unlock()
+					}
+				}()
+			}
+			wg.Wait()
+
+			if first != 1 {
+				t.Fatalf("%d callers saw the key as absent, want 1", first)
+			}
+		})
+	}
+}
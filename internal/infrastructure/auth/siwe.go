@@ -0,0 +1,310 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// siweHeaderInfix 消息首行中域名和区块链名称之间的部分
+	siweHeaderInfix = " wants you to sign in with your "
+
+	// siweHeaderSuffix 消息首行后缀
+	siweHeaderSuffix = " account:"
+
+	// siweEthereum EIP-4361 的区块链名称
+	siweEthereum = "Ethereum"
+)
+
+// siweVersion EIP-4361 消息版本
+const siweVersion = "1"
+
+var (
+	// ErrInvalidSIWEMessage 无效的 SIWE 消息
+	ErrInvalidSIWEMessage = errors.New("invalid siwe message")
+
+	// siweAddressPattern 以太坊地址格式
+	siweAddressPattern = regexp.MustCompile(`^0x[0-9a-fA-F]{40}$`)
+
+	// caipAddressPattern CAIP-10 允许的账户地址字符
+	caipAddressPattern = regexp.MustCompile(`^[-.%a-zA-Z0-9]{1,128}$`)
+
+	// caipReferencePattern CAIP-2 链标识格式
+	caipReferencePattern = regexp.MustCompile(`^[-_a-zA-Z0-9]{1,32}$`)
+
+	// siweBlockchainPattern 区块链名称格式
+	siweBlockchainPattern = regexp.MustCompile(`^[A-Za-z0-9]+$`)
+
+	// siweNoncePattern nonce 格式（至少 8 位字母数字）
+	siweNoncePattern = regexp.MustCompile(`^[A-Za-z0-9]{8,}$`)
+)
+
+// SIWEMessage Sign-In with Ethereum（EIP-4361）消息
+//
+// 其他链使用 CAIP-122 定义的相同格式，首行中的区块链名称和 Chain ID 随链变化。
+type SIWEMessage struct {
+	Scheme         string
+	Domain         string
+	Blockchain     string // 为空时为 Ethereum
+	Address        string
+	Statement      string
+	URI            string
+	Version        string
+	ChainID        string // CAIP-2 链标识，EVM 链为十进制链 ID
+	Nonce          string
+	IssuedAt       time.Time
+	ExpirationTime *time.Time
+	NotBefore      *time.Time
+	RequestID      string
+	Resources      []string
+}
+
+// String 按 EIP-4361 / CAIP-122 格式生成待签名消息
+func (m *SIWEMessage) String() string {
+	var b strings.Builder
+
+	if m.Scheme != "" {
+		b.WriteString(m.Scheme + "://")
+	}
+	b.WriteString(m.Domain + siweHeaderInfix + m.blockchain() + siweHeaderSuffix + "\n")
+	b.WriteString(m.Address + "\n\n")
+	if m.Statement != "" {
+		b.WriteString(m.Statement + "\n")
+	}
+	b.WriteString("\n")
+
+	b.WriteString("URI: " + m.URI + "\n")
+	b.WriteString("Version: " + m.Version + "\n")
+	b.WriteString("Chain ID: " + m.ChainID + "\n")
+	b.WriteString("Nonce: " + m.Nonce + "\n")
+	b.WriteString("Issued At: " + m.IssuedAt.UTC().Format(time.RFC3339))
+	if m.ExpirationTime != nil {
+		b.WriteString("\nExpiration Time: " + m.ExpirationTime.UTC().Format(time.RFC3339))
+	}
+	if m.NotBefore != nil {
+		b.WriteString("\nNot Before: " + m.NotBefore.UTC().Format(time.RFC3339))
+	}
+	if m.RequestID != "" {
+		b.WriteString("\nRequest ID: " + m.RequestID)
+	}
+	if len(m.Resources) > 0 {
+		b.WriteString("\nResources:")
+		for _, resource := range m.Resources {
+			b.WriteString("\n- " + resource)
+		}
+	}
+
+	return b.String()
+}
+
+// blockchain 消息首行中的区块链名称
+func (m *SIWEMessage) blockchain() string {
+	if m.Blockchain == "" {
+		return siweEthereum
+	}
+	return m.Blockchain
+}
+
+// ParseSIWEMessage 解析 EIP-4361 / CAIP-122 消息
+func ParseSIWEMessage(message string) (*SIWEMessage, error) {
+	lines := strings.Split(message, "\n")
+	m := &SIWEMessage{}
+
+	// 首行：[scheme://]domain wants you to sign in with your <Blockchain> account:
+	header := lines[0]
+	infix := strings.LastIndex(header, siweHeaderInfix)
+	if infix < 0 || !strings.HasSuffix(header, siweHeaderSuffix) {
+		return nil, fmt.Errorf("%w: missing header", ErrInvalidSIWEMessage)
+	}
+	m.Blockchain = strings.TrimSuffix(header[infix+len(siweHeaderInfix):], siweHeaderSuffix)
+	if !siweBlockchainPattern.MatchString(m.Blockchain) {
+		return nil, fmt.Errorf("%w: invalid blockchain", ErrInvalidSIWEMessage)
+	}
+	ethereum := m.Blockchain == siweEthereum
+	authority := header[:infix]
+	if i := strings.Index(authority, "://"); i >= 0 {
+		m.Scheme = authority[:i]
+		authority = authority[i+3:]
+	}
+	if authority == "" || strings.ContainsAny(authority, " /") {
+		return nil, fmt.Errorf("%w: invalid domain", ErrInvalidSIWEMessage)
+	}
+	m.Domain = authority
+
+	if len(lines) < 4 {
+		return nil, fmt.Errorf("%w: message too short", ErrInvalidSIWEMessage)
+	}
+
+	// 地址，以太坊为 0x 地址，其他链只检查字符集，由签名验证器校验格式
+	if ethereum && !siweAddressPattern.MatchString(lines[1]) || !caipAddressPattern.MatchString(lines[1]) {
+		return nil, fmt.Errorf("%w: invalid address", ErrInvalidSIWEMessage)
+	}
+	m.Address = lines[1]
+
+	if lines[2] != "" {
+		return nil, fmt.Errorf("%w: expected empty line after address", ErrInvalidSIWEMessage)
+	}
+
+	// 可选的声明
+	i := 3
+	if lines[i] != "" {
+		m.Statement = lines[i]
+		i++
+		if i >= len(lines) || lines[i] != "" {
+			return nil, fmt.Errorf("%w: expected empty line after statement", ErrInvalidSIWEMessage)
+		}
+	}
+	i++
+
+	p := &siweFieldParser{lines: lines, pos: i}
+
+	var err error
+	if m.URI, err = p.required("URI"); err != nil {
+		return nil, err
+	}
+	if _, err := url.ParseRequestURI(m.URI); err != nil {
+		return nil, fmt.Errorf("%w: invalid uri", ErrInvalidSIWEMessage)
+	}
+
+	if m.Version, err = p.required("Version"); err != nil {
+		return nil, err
+	}
+	if m.Version != siweVersion {
+		return nil, fmt.Errorf("%w: unsupported version %q", ErrInvalidSIWEMessage, m.Version)
+	}
+
+	if m.ChainID, err = p.required("Chain ID"); err != nil {
+		return nil, err
+	}
+	if !caipReferencePattern.MatchString(m.ChainID) {
+		return nil, fmt.Errorf("%w: invalid chain id", ErrInvalidSIWEMessage)
+	}
+	if ethereum {
+		if id, err := strconv.ParseInt(m.ChainID, 10, 64); err != nil || id <= 0 {
+			return nil, fmt.Errorf("%w: invalid chain id", ErrInvalidSIWEMessage)
+		}
+	}
+
+	if m.Nonce, err = p.required("Nonce"); err != nil {
+		return nil, err
+	}
+	if !siweNoncePattern.MatchString(m.Nonce) {
+		return nil, fmt.Errorf("%w: invalid nonce", ErrInvalidSIWEMessage)
+	}
+
+	issuedAt, err := p.required("Issued At")
+	if err != nil {
+		return nil, err
+	}
+	if m.IssuedAt, err = parseSIWETime(issuedAt); err != nil {
+		return nil, err
+	}
+
+	if value, ok := p.optional("Expiration Time"); ok {
+		t, err := parseSIWETime(value)
+		if err != nil {
+			return nil, err
+		}
+		m.ExpirationTime = &t
+	}
+
+	if value, ok := p.optional("Not Before"); ok {
+		t, err := parseSIWETime(value)
+		if err != nil {
+			return nil, err
+		}
+		m.NotBefore = &t
+	}
+
+	if value, ok := p.optional("Request ID"); ok {
+		m.RequestID = value
+	}
+
+	if p.pos < len(lines) && lines[p.pos] == "Resources:" {
+		p.pos++
+		for p.pos < len(lines) && strings.HasPrefix(lines[p.pos], "- ") {
+			resource := strings.TrimPrefix(lines[p.pos], "- ")
+			if _, err := url.ParseRequestURI(resource); err != nil {
+				return nil, fmt.Errorf("%w: invalid resource", ErrInvalidSIWEMessage)
+			}
+			m.Resources = append(m.Resources, resource)
+			p.pos++
+		}
+	}
+
+	if p.pos != len(lines) {
+		return nil, fmt.Errorf("%w: unexpected content at line %d", ErrInvalidSIWEMessage, p.pos+1)
+	}
+
+	return m, nil
+}
+
+// siweFieldParser 按顺序解析 "Key: value" 字段
+type siweFieldParser struct {
+	lines []string
+	pos   int
+}
+
+// required 解析必填字段
+func (p *siweFieldParser) required(key string) (string, error) {
+	value, ok := p.optional(key)
+	if !ok {
+		return "", fmt.Errorf("%w: missing %s", ErrInvalidSIWEMessage, key)
+	}
+	return value, nil
+}
+
+// optional 解析可选字段
+func (p *siweFieldParser) optional(key string) (string, bool) {
+	if p.pos >= len(p.lines) {
+		return "", false
+	}
+
+	prefix := key + ": "
+	if !strings.HasPrefix(p.lines[p.pos], prefix) {
+		return "", false
+	}
+
+	value := strings.TrimPrefix(p.lines[p.pos], prefix)
+	p.pos++
+	return value, true
+}
+
+// parseSIWETime 解析 RFC 3339 时间
+func parseSIWETime(value string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: invalid timestamp %q", ErrInvalidSIWEMessage, value)
+	}
+	return t, nil
+}
+
+// SIWEParams 生成 SIWE 消息的站点和链参数
+type SIWEParams struct {
+	Domain     string
+	URI        string
+	Statement  string
+	Blockchain string
+	ChainID    string
+	Resources  []string
+}
+
+// RequestOrigin 认证请求的来源站点
+type RequestOrigin struct {
+	Host   string
+	Secure bool
+}
+
+// URI 来源站点的 URI
+func (o RequestOrigin) URI() string {
+	scheme := "http"
+	if o.Secure {
+		scheme = "https"
+	}
+	return scheme + "://" + o.Host
+}
@@ -0,0 +1,72 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/yeying-community/webdav/internal/domain/user"
+	"github.com/yeying-community/webdav/internal/infrastructure/crypto"
+	"go.uber.org/zap"
+)
+
+// twoFactor 账号密码登录的两步验证
+//
+// 启用两步验证的用户用账号密码认证时必须同时提供验证码或恢复码。
+// 每个验证码只能使用一次，不能像密码一样随每个请求重复发送：X-OTP 只适合单次的
+// API 调用（如创建应用密码），WebDAV 客户端必须使用应用密码。
+type twoFactor struct {
+	userRepo user.Repository
+	totp     *crypto.TOTP
+	logger   *zap.Logger
+}
+
+// newTwoFactor 创建两步验证
+func newTwoFactor(userRepo user.Repository, logger *zap.Logger) *twoFactor {
+	return &twoFactor{
+		userRepo: userRepo,
+		totp:     crypto.NewTOTP(""),
+		logger:   logger,
+	}
+}
+
+// verify 验证第二因素，未启用两步验证的用户直接通过
+func (f *twoFactor) verify(ctx context.Context, u *user.User, code string) error {
+	if !u.HasTwoFactor() {
+		return nil
+	}
+	if code == "" {
+		return user.ErrTwoFactorRequired
+	}
+
+	// 验证码在有效期内只能使用一次，记录的时间步之前的验证码一律拒绝
+	if step, ok := f.totp.ValidateStep(u.TOTP.Secret, code); ok {
+		err := f.userRepo.UseTOTPStep(ctx, u.ID, step)
+		if errors.Is(err, user.ErrOTPReused) {
+			f.logger.Warn("two-factor code reused",
+				zap.String("username", u.Username))
+			return user.ErrInvalidOTP
+		}
+		if err != nil {
+			return fmt.Errorf("failed to record two-factor code: %w", err)
+		}
+		return nil
+	}
+
+	// 恢复码只能使用一次，使用后写回仓储
+	updated := u.Clone()
+	if !updated.TOTP.UseRecoveryCode(code) {
+		f.logger.Warn("two-factor verification failed",
+			zap.String("username", u.Username))
+		return user.ErrInvalidOTP
+	}
+	if err := f.userRepo.Save(ctx, updated); err != nil {
+		return fmt.Errorf("failed to consume recovery code: %w", err)
+	}
+
+	f.logger.Warn("recovery code used",
+		zap.String("username", u.Username),
+		zap.Int("remaining", len(updated.TOTP.RecoveryCodes)))
+
+	return nil
+}
@@ -0,0 +1,185 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pquerna/otp/totp"
+	"github.com/yeying-community/webdav/internal/domain/auth"
+	"github.com/yeying-community/webdav/internal/domain/user"
+	"github.com/yeying-community/webdav/internal/infrastructure/crypto"
+	"github.com/yeying-community/webdav/internal/infrastructure/repository"
+	"go.uber.org/zap"
+)
+
+// twoFactorRepos 需要覆盖的用户仓储实现
+var twoFactorRepos = map[string]func(t *testing.T) user.Repository{
+	"memory": func(t *testing.T) user.Repository {
+		return repository.NewMemoryUserRepository(nil)
+	},
+	"sqlite": func(t *testing.T) user.Repository {
+		repo, err := repository.NewSQLiteUserRepository(filepath.Join(t.TempDir(), "users.db"))
+		if err != nil {
+			t.Fatalf("NewSQLiteUserRepository: %v", err)
+		}
+		t.Cleanup(func() { repo.Close() })
+		return repo
+	},
+}
+
+// saveTwoFactorUser 保存启用了两步验证的用户，返回 TOTP 密钥和一个恢复码
+func saveTwoFactorUser(t *testing.T, repo user.Repository, username string) (string, string) {
+	t.Helper()
+
+	hash, err := crypto.NewPasswordHasher().Hash("secret")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	key, err := crypto.NewTOTP("WebDAV").Generate(username)
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	codes, hashes, err := user.NewRecoveryCodes()
+	if err != nil {
+		t.Fatalf("NewRecoveryCodes: %v", err)
+	}
+
+	u := user.NewUser(username, "/"+username)
+	u.Password = hash
+	u.TOTP = &user.TOTP{Secret: key.Secret, Enabled: true, RecoveryCodes: hashes}
+	if err := repo.Save(context.Background(), u); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	return key.Secret, codes[0]
+}
+
+// otpAt 指定时间的验证码
+func otpAt(t *testing.T, secret string, at time.Time) string {
+	t.Helper()
+
+	code, err := totp.GenerateCode(secret, at)
+	if err != nil {
+		t.Fatalf("GenerateCode: %v", err)
+	}
+	return code
+}
+
+func TestTwoFactorRejectsReplayedCodes(t *testing.T) {
+	for name, newRepo := range twoFactorRepos {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			repo := newRepo(t)
+			secret, recovery := saveTwoFactorUser(t, repo, "alice")
+			a := NewBasicAuthenticator(repo, crypto.NewPasswordHasher(), false, "", zap.NewNop())
+
+			// 登录前读取的用户，之后用于模拟其他修改整体保存
+			stale, err := repo.FindByUsername(ctx, "alice")
+			if err != nil {
+				t.Fatalf("FindByUsername: %v", err)
+			}
+
+			login := func(otp string) error {
+				_, err := a.Authenticate(ctx, &auth.BasicCredentials{Username: "alice", Password: "secret", OTP: otp})
+				return err
+			}
+
+			now := time.Now()
+			current := otpAt(t, secret, now)
+			steps := []struct {
+				name    string
+				otp     string
+				before  func()
+				wantErr error
+			}{
+				{name: "missing code", otp: "", wantErr: user.ErrTwoFactorRequired},
+				{name: "wrong code", otp: "000000x", wantErr: user.ErrInvalidOTP},
+				{name: "current code", otp: current},
+				{name: "replayed code", otp: current, wantErr: user.ErrInvalidOTP},
+				{name: "earlier code", otp: otpAt(t, secret, now.Add(-30*time.Second)), wantErr: user.ErrInvalidOTP},
+				{
+					name: "replay after saving a stale user",
+					otp:  current,
+					before: func() {
+						if err := repo.Save(ctx, stale.Clone()); err != nil {
+							t.Fatalf("Save: %v", err)
+						}
+					},
+					wantErr: user.ErrInvalidOTP,
+				},
+				{name: "later code", otp: otpAt(t, secret, now.Add(30*time.Second))},
+				{name: "current code after later code", otp: current, wantErr: user.ErrInvalidOTP},
+				{name: "recovery code", otp: recovery},
+				{name: "reused recovery code", otp: recovery, wantErr: user.ErrInvalidOTP},
+			}
+
+			for _, st := range steps {
+				if st.before != nil {
+					st.before()
+				}
+				err := login(st.otp)
+				if st.wantErr == nil && err != nil {
+					t.Fatalf("%s: Authenticate: %v", st.name, err)
+				}
+				if st.wantErr != nil && !errors.Is(err, st.wantErr) {
+					t.Fatalf("%s: err = %v, want %v", st.name, err, st.wantErr)
+				}
+			}
+		})
+	}
+}
+
+func TestTwoFactorConcurrentReplay(t *testing.T) {
+	for name, newRepo := range twoFactorRepos {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			repo := newRepo(t)
+			secret, _ := saveTwoFactorUser(t, repo, "bob")
+			a := NewBasicAuthenticator(repo, crypto.NewPasswordHasher(), false, "", zap.NewNop())
+
+			code := otpAt(t, secret, time.Now())
+			creds := &auth.BasicCredentials{Username: "bob", Password: "secret", OTP: code}
+
+			var (
+				wg       sync.WaitGroup
+				mu       sync.Mutex
+				accepted int
+			)
+			for i := 0; i < 8; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					_, err := a.Authenticate(ctx, creds)
+					if err != nil && !errors.Is(err, user.ErrInvalidOTP) {
+						t.Errorf("Authenticate: %v", err)
+					}
+					if err == nil {
+						mu.Lock()
+						accepted++
+						mu.Unlock()
+					}
+				}()
+			}
+			wg.Wait()
+
+			// 同一验证码只能被一个请求使用
+			if accepted != 1 {
+				t.Fatalf("accepted = %d, want 1", accepted)
+			}
+		})
+	}
+}
+
+func TestUseTOTPStepUnknownUser(t *testing.T) {
+	for name, newRepo := range twoFactorRepos {
+		t.Run(name, func(t *testing.T) {
+			err := newRepo(t).UseTOTPStep(context.Background(), "missing", 1)
+			if !errors.Is(err, user.ErrUserNotFound) {
+				t.Fatalf("err = %v, want ErrUserNotFound", err)
+			}
+		})
+	}
+}
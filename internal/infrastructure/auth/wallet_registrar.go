@@ -0,0 +1,114 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/yeying-community/webdav/internal/domain/auth"
+	"github.com/yeying-community/webdav/internal/domain/user"
+	"github.com/yeying-community/webdav/internal/infrastructure/config"
+	"github.com/yeying-community/webdav/internal/infrastructure/storage"
+	"go.uber.org/zap"
+)
+
+// WalletRegistrar 钱包自助注册
+//
+// 用户名为链上地址（以太坊为小写地址），目录由模板生成并在注册时创建。
+type WalletRegistrar struct {
+	cfg       config.RegistrationConfig
+	userRepo  user.Repository
+	storage   storage.Driver
+	allowlist map[string]bool
+	logger    *zap.Logger
+}
+
+// NewWalletRegistrar 创建钱包注册器
+func NewWalletRegistrar(
+	cfg config.RegistrationConfig,
+	userRepo user.Repository,
+	storageDriver storage.Driver,
+	logger *zap.Logger,
+) *WalletRegistrar {
+	allowlist := make(map[string]bool, len(cfg.Allowlist))
+	for _, address := range cfg.Allowlist {
+		allowlist[user.NormalizeWalletID(address)] = true
+	}
+
+	return &WalletRegistrar{
+		cfg:       cfg,
+		userRepo:  userRepo,
+		storage:   storageDriver,
+		allowlist: allowlist,
+		logger:    logger,
+	}
+}
+
+// CanRegister 钱包是否可能注册成功（在名单中，或可以凭邀请码注册）
+func (r *WalletRegistrar) CanRegister(walletID string) bool {
+	return r.isOpen() || r.allowlist[user.NormalizeWalletID(walletID)] || len(r.cfg.InviteCodes) > 0
+}
+
+// Check 检查钱包是否允许注册
+func (r *WalletRegistrar) Check(walletID, inviteCode string) error {
+	if r.isOpen() || r.allowlist[user.NormalizeWalletID(walletID)] {
+		return nil
+	}
+	if inviteCode == "" {
+		return auth.ErrRegistrationNotAllowed
+	}
+	for _, code := range r.cfg.InviteCodes {
+		if subtle.ConstantTimeCompare([]byte(code), []byte(inviteCode)) == 1 {
+			return nil
+		}
+	}
+	return auth.ErrInvalidInviteCode
+}
+
+// Register 为已验证签名的钱包创建用户并创建用户目录
+//
+// 并发注册同一地址时返回已创建的用户。
+func (r *WalletRegistrar) Register(ctx context.Context, walletID, inviteCode string) (*user.User, error) {
+	walletID = user.NormalizeWalletID(walletID)
+	if !user.IsValidWalletID(walletID) {
+		return nil, user.ErrInvalidAddress
+	}
+	if err := r.Check(walletID, inviteCode); err != nil {
+		return nil, err
+	}
+
+	address := user.WalletAddressOf(walletID)
+	u := user.NewUser(address, strings.ReplaceAll(r.cfg.Directory, "{address}", address))
+	u.WalletAddress = walletID
+	u.Permissions = user.ParsePermissions(r.cfg.DefaultPermissions)
+
+	if err := r.userRepo.Save(ctx, u); err != nil {
+		if errors.Is(err, user.ErrDuplicateAddress) {
+			return r.userRepo.FindByWalletAddress(ctx, walletID)
+		}
+		return nil, fmt.Errorf("failed to register wallet: %w", err)
+	}
+
+	// 存储驱动在打开文件系统时创建根目录
+	if _, err := r.storage.FileSystem(ctx, u.Directory); err != nil {
+		r.logger.Warn("failed to create user directory",
+			zap.String("username", u.Username),
+			zap.String("directory", u.Directory),
+			zap.Error(err))
+	}
+
+	r.logger.Info("wallet registered",
+		zap.String("username", u.Username),
+		zap.String("directory", u.Directory),
+		zap.String("permissions", r.cfg.DefaultPermissions),
+		zap.Bool("invited", !r.isOpen() && !r.allowlist[walletID]))
+
+	return u, nil
+}
+
+// isOpen 未配置名单和邀请码时任何钱包都可以注册
+func (r *WalletRegistrar) isOpen() bool {
+	return len(r.cfg.Allowlist) == 0 && len(r.cfg.InviteCodes) == 0
+}
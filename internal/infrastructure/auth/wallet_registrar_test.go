@@ -0,0 +1,204 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/yeying-community/webdav/internal/domain/auth"
+	"github.com/yeying-community/webdav/internal/domain/user"
+	"github.com/yeying-community/webdav/internal/infrastructure/config"
+	"github.com/yeying-community/webdav/internal/infrastructure/repository"
+	"github.com/yeying-community/webdav/internal/infrastructure/storage"
+	"go.uber.org/zap"
+)
+
+const (
+	// 名单中的以太坊地址，配置中使用校验和大小写
+	allowedAddress = "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"
+	otherAddress   = "0xfb6916095ca1df60bb79ce92ce3ea74c37c5d359"
+	solanaAccount  = "solana:5eykt4UsFv8P8NJdTREpY1vzqKqZKvdp:7EcDhSYGxXyscszYEp35KHN8vvw3svAuLKTzXwCFLtV"
+)
+
+// newTestRegistrar 创建使用内存仓储和本地存储的注册器，返回存储根目录
+func newTestRegistrar(t *testing.T, cfg config.RegistrationConfig) (*WalletRegistrar, user.Repository, string) {
+	t.Helper()
+
+	baseDir := t.TempDir()
+	repo := repository.NewMemoryUserRepository(nil)
+	r := NewWalletRegistrar(cfg, repo, storage.NewLocalDriver(baseDir, zap.NewNop()), zap.NewNop())
+	return r, repo, baseDir
+}
+
+func TestWalletRegistrarCheck(t *testing.T) {
+	allowlist := []string{allowedAddress, solanaAccount}
+	invites := []string{"welcome-2024", "friends"}
+
+	tests := []struct {
+		name        string
+		allowlist   []string
+		inviteCodes []string
+		wallet      string
+		invite      string
+		want        error
+		canRegister bool
+	}{
+		// 未配置名单和邀请码时开放注册
+		{name: "open", wallet: otherAddress, want: nil, canRegister: true},
+
+		{name: "allowlisted", allowlist: allowlist, wallet: allowedAddress, canRegister: true},
+		{name: "allowlisted lowercase", allowlist: allowlist, wallet: "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed", canRegister: true},
+		{name: "allowlisted eip155 account", allowlist: allowlist, wallet: "eip155:1:" + allowedAddress, canRegister: true},
+		{name: "allowlisted solana account", allowlist: allowlist, wallet: solanaAccount, canRegister: true},
+		{name: "not allowlisted", allowlist: allowlist, wallet: otherAddress, want: auth.ErrRegistrationNotAllowed},
+		// base58 地址区分大小写
+		{name: "solana address case differs", allowlist: allowlist, wallet: "solana:5eykt4UsFv8P8NJdTREpY1vzqKqZKvdp:7ecdhsygxxyscszyep35khn8vvw3svaulktzxwcfltv", want: auth.ErrRegistrationNotAllowed},
+		{name: "invite code ignored for allowlisted wallet", allowlist: allowlist, wallet: allowedAddress, invite: "wrong", canRegister: true},
+
+		{name: "missing invite code", inviteCodes: invites, wallet: otherAddress, want: auth.ErrRegistrationNotAllowed, canRegister: true},
+		{name: "wrong invite code", inviteCodes: invites, wallet: otherAddress, invite: "welcome", want: auth.ErrInvalidInviteCode, canRegister: true},
+		{name: "valid invite code", inviteCodes: invites, wallet: otherAddress, invite: "friends", canRegister: true},
+
+		{name: "allowlist and invite codes", allowlist: allowlist, inviteCodes: invites, wallet: otherAddress, invite: "welcome-2024", canRegister: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _, _ := newTestRegistrar(t, config.RegistrationConfig{
+				Directory:   "wallets/{address}",
+				Allowlist:   tt.allowlist,
+				InviteCodes: tt.inviteCodes,
+			})
+
+			if err := r.Check(tt.wallet, tt.invite); !errors.Is(err, tt.want) {
+				t.Fatalf("Check = %v, want %v", err, tt.want)
+			}
+			if got := r.CanRegister(tt.wallet); got != tt.canRegister {
+				t.Fatalf("CanRegister = %v, want %v", got, tt.canRegister)
+			}
+		})
+	}
+}
+
+func TestWalletRegistrarRegister(t *testing.T) {
+	tests := []struct {
+		name      string
+		directory string
+		wallet    string
+		username  string
+		walletID  string
+		wantDir   string
+	}{
+		{
+			name:      "ethereum address is lowercased",
+			directory: "wallets/{address}",
+			wallet:    allowedAddress,
+			username:  "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed",
+			walletID:  "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed",
+			wantDir:   "wallets/0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed",
+		},
+		{
+			name:      "eip155 account uses the address",
+			directory: "wallets/{address}",
+			wallet:    "eip155:137:" + allowedAddress,
+			username:  "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed",
+			walletID:  "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed",
+			wantDir:   "wallets/0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed",
+		},
+		{
+			name:      "solana address keeps its case",
+			directory: "wallets/{address}",
+			wallet:    solanaAccount,
+			username:  "7EcDhSYGxXyscszYEp35KHN8vvw3svAuLKTzXwCFLtV",
+			walletID:  solanaAccount,
+			wantDir:   "wallets/7EcDhSYGxXyscszYEp35KHN8vvw3svAuLKTzXwCFLtV",
+		},
+		{
+			name:      "placeholder used more than once",
+			directory: "{address}/files-{address}",
+			wallet:    otherAddress,
+			username:  otherAddress,
+			walletID:  otherAddress,
+			wantDir:   otherAddress + "/files-" + otherAddress,
+		},
+		{
+			name:      "template without placeholder",
+			directory: "shared",
+			wallet:    otherAddress,
+			username:  otherAddress,
+			walletID:  otherAddress,
+			wantDir:   "shared",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			r, repo, baseDir := newTestRegistrar(t, config.RegistrationConfig{
+				Directory:          tt.directory,
+				DefaultPermissions: "CR",
+			})
+
+			u, err := r.Register(ctx, tt.wallet, "")
+			if err != nil {
+				t.Fatalf("Register: %v", err)
+			}
+			if u.Username != tt.username || u.WalletAddress != tt.walletID || u.Directory != tt.wantDir {
+				t.Fatalf("user = %s %s %s, want %s %s %s",
+					u.Username, u.WalletAddress, u.Directory, tt.username, tt.walletID, tt.wantDir)
+			}
+			if got := u.Permissions.String(); got != "CR" {
+				t.Fatalf("Permissions = %s, want CR", got)
+			}
+
+			saved, err := repo.FindByWalletAddress(ctx, tt.walletID)
+			if err != nil || saved.Username != tt.username {
+				t.Fatalf("FindByWalletAddress = %v, %v", saved, err)
+			}
+
+			// 注册时创建用户目录
+			info, err := os.Stat(filepath.Join(baseDir, filepath.FromSlash(tt.wantDir)))
+			if err != nil || !info.IsDir() {
+				t.Fatalf("user directory not created: %v", err)
+			}
+		})
+	}
+}
+
+func TestWalletRegistrarRegisterRejected(t *testing.T) {
+	tests := []struct {
+		name   string
+		wallet string
+		invite string
+		want   error
+	}{
+		{name: "invalid wallet", wallet: "not-a-wallet", want: user.ErrInvalidAddress},
+		{name: "not allowlisted", wallet: otherAddress, want: auth.ErrRegistrationNotAllowed},
+		{name: "wrong invite code", wallet: otherAddress, invite: "wrong", want: auth.ErrInvalidInviteCode},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			r, repo, baseDir := newTestRegistrar(t, config.RegistrationConfig{
+				Directory:   "wallets/{address}",
+				Allowlist:   []string{allowedAddress},
+				InviteCodes: []string{"friends"},
+			})
+
+			if _, err := r.Register(ctx, tt.wallet, tt.invite); !errors.Is(err, tt.want) {
+				t.Fatalf("Register = %v, want %v", err, tt.want)
+			}
+
+			// 拒绝时不创建用户和目录
+			if _, err := repo.FindByUsername(ctx, tt.wallet); !errors.Is(err, user.ErrUserNotFound) {
+				t.Fatalf("FindByUsername = %v, want ErrUserNotFound", err)
+			}
+			if _, err := os.Stat(filepath.Join(baseDir, "wallets")); !errors.Is(err, os.ErrNotExist) {
+				t.Fatalf("wallets directory exists after a rejected registration: %v", err)
+			}
+		})
+	}
+}
@@ -2,11 +2,15 @@ package auth
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 	
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/yeying-community/webdav/internal/domain/auth"
 	"github.com/yeying-community/webdav/internal/domain/user"
+	"github.com/yeying-community/webdav/internal/infrastructure/config"
 	"github.com/yeying-community/webdav/internal/infrastructure/crypto"
 	"go.uber.org/zap"
 )
@@ -16,22 +20,66 @@ type Web3Authenticator struct {
 	userRepo       user.Repository
 	jwtManager     *JWTManager
 	challengeStore *ChallengeStore
+	linkStore      *ChallengeStore // 关联钱包的挑战，与登录挑战分开存储
 	ethSigner      *crypto.EthereumSigner
+	signers        map[string]crypto.Signer // 允许登录的链
+	chains         []string
+	chainIDs       map[string]string // 各链的 CAIP-2 链标识
+	contractWallet *crypto.ContractWalletVerifier
+	revocations    RevocationStore
+	siwe           config.SIWEConfig
 	logger         *zap.Logger
 }
 
+// WalletAccount 解析后的钱包账户
+type WalletAccount struct {
+	Chain   string // 链名称
+	Address string // 规范化的链上地址，即登录消息中的地址
+	ChainID string // CAIP-2 链标识
+	ID      string // 用户仓储中的钱包标识（见 user.WalletID）
+	signer  crypto.Signer
+}
+
 // NewWeb3Authenticator 创建 Web3 认证器
 func NewWeb3Authenticator(
 	userRepo user.Repository,
-	jwtSecret string,
-	tokenExpiration time.Duration,
+	cfg config.Web3Config,
+	jwtManager *JWTManager,
+	contractWallet *crypto.ContractWalletVerifier,
+	revocations RevocationStore,
 	logger *zap.Logger,
 ) *Web3Authenticator {
+	ethSigner := crypto.NewEthereumSigner()
+	signers := make(map[string]crypto.Signer)
+	chainIDs := make(map[string]string)
+	var chains []string
+	for _, chain := range cfg.Chains {
+		var signer crypto.Signer = ethSigner
+		if chain != user.ChainEthereum {
+			s, err := crypto.NewSigner(chain)
+			if err != nil {
+				logger.Warn("skipping unsupported chain", zap.String("chain", chain))
+				continue
+			}
+			signer = s
+		}
+		signers[chain] = signer
+		chainIDs[chain] = cfg.ChainReference(chain)
+		chains = append(chains, chain)
+	}
+	
 	return &Web3Authenticator{
 		userRepo:       userRepo,
-		jwtManager:     NewJWTManager(jwtSecret, tokenExpiration),
+		jwtManager:     jwtManager,
 		challengeStore: NewChallengeStore(),
-		ethSigner:      crypto.NewEthereumSigner(),
+		linkStore:      NewChallengeStore(),
+		ethSigner:      ethSigner,
+		signers:        signers,
+		chains:         chains,
+		chainIDs:       chainIDs,
+		contractWallet: contractWallet,
+		revocations:    revocations,
+		siwe:           cfg.SIWE,
 		logger:         logger,
 	}
 }
@@ -49,12 +97,21 @@ func (a *Web3Authenticator) Authenticate(ctx context.Context, credentials interf
 	}
 	
 	// 验证 JWT
-	address, err := a.jwtManager.Verify(creds.Token)
+	claims, err := a.jwtManager.Verify(creds.Token, TokenTypeAccess)
 	if err != nil {
 		a.logger.Debug("jwt verification failed", zap.Error(err))
 		return nil, err
 	}
 	
+	// 检查吊销列表
+	if err := a.checkRevoked(ctx, claims); err != nil {
+		a.logger.Debug("access token revoked",
+			zap.String("jti", claims.ID),
+			zap.Error(err))
+		return nil, err
+	}
+	address := claims.Address
+	
 	// 查找用户
 	u, err := a.userRepo.FindByWalletAddress(ctx, address)
 	if err != nil {
@@ -79,62 +136,444 @@ func (a *Web3Authenticator) CanHandle(credentials interface{}) bool {
 	return ok
 }
 
-// CreateChallenge 创建挑战
-func (a *Web3Authenticator) CreateChallenge(address string) (*auth.Challenge, error) {
-	// 验证地址格式
-	if !a.ethSigner.IsValidAddress(address) {
-		return nil, fmt.Errorf("invalid ethereum address")
+// ResolveAccount 解析请求中的链和地址，chain 为空时使用第一个配置的链
+func (a *Web3Authenticator) ResolveAccount(chain, address string) (*WalletAccount, error) {
+	chain = strings.ToLower(strings.TrimSpace(chain))
+	if chain == "" && len(a.chains) > 0 {
+		chain = a.chains[0]
+	}
+	
+	signer, ok := a.signers[chain]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", auth.ErrUnsupportedChain, chain)
 	}
 	
-	// 创建挑战（5分钟有效期）
-	challenge, err := a.challengeStore.Create(address, 5*time.Minute)
+	address = strings.TrimSpace(address)
+	if !signer.IsValidAddress(address) {
+		return nil, fmt.Errorf("%w: invalid %s address", user.ErrInvalidAddress, chain)
+	}
+	address = signer.NormalizeAddress(address)
+	
+	return &WalletAccount{
+		Chain:   chain,
+		Address: address,
+		ChainID: a.chainIDs[chain],
+		ID:      user.WalletID(signer.Namespace(), a.chainIDs[chain], address),
+		signer:  signer,
+	}, nil
+}
+
+// CreateChallenge 创建登录挑战
+//
+// format 为 siwe（默认）或 eip712，eip712 只支持以太坊账户。
+func (a *Web3Authenticator) CreateChallenge(account *WalletAccount, format string, origin RequestOrigin) (*auth.Challenge, error) {
+	challenge, err := a.createChallenge(a.challengeStore, account, format, a.siweParams(account, origin))
 	if err != nil {
-		return nil, fmt.Errorf("failed to create challenge: %w", err)
+		return nil, err
 	}
 	
 	a.logger.Debug("challenge created",
-		zap.String("address", address),
+		zap.String("wallet", account.ID),
+		zap.String("domain", challenge.Domain),
+		zap.String("nonce", challenge.Nonce))
+	
+	return challenge, nil
+}
+
+// VerifySignature 校验挑战签名并生成访问令牌和刷新令牌
+//
+// SIWE 挑战的 message 为空时使用挑战中下发的消息；EIP-712 挑战忽略 message，
+// 签名必须覆盖挑战下发的类型化数据。
+func (a *Web3Authenticator) VerifySignature(ctx context.Context, account *WalletAccount, message, signature string, origin RequestOrigin) (*auth.TokenPair, error) {
+	if err := a.verifyChallenge(ctx, a.challengeStore, account, message, signature, "", origin); err != nil {
+		return nil, err
+	}
+	
+	// 生成 JWT，每次登录开始一个新的令牌家族
+	tokens, err := a.jwtManager.GeneratePair(account.ID, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+	
+	a.logger.Info("signature verified, token generated",
+		zap.String("wallet", account.ID),
+		zap.String("family", tokens.Access.Family))
+	
+	return tokens, nil
+}
+
+// CreateLinkChallenge 创建关联钱包的挑战
+//
+// 声明中写明目标用户，登录挑战的签名不能用于关联钱包，反之亦然。
+func (a *Web3Authenticator) CreateLinkChallenge(account *WalletAccount, username, format string, origin RequestOrigin) (*auth.Challenge, error) {
+	params := a.siweParams(account, origin)
+	params.Statement = linkStatement(username)
+	
+	challenge, err := a.createChallenge(a.linkStore, account, format, params)
+	if err != nil {
+		return nil, err
+	}
+	
+	a.logger.Debug("link challenge created",
+		zap.String("wallet", account.ID),
+		zap.String("username", username),
 		zap.String("nonce", challenge.Nonce))
 	
 	return challenge, nil
 }
 
-// VerifySignature 验证签名并生成 token
-func (a *Web3Authenticator) VerifySignature(ctx context.Context, address, signature string) (*auth.Token, error) {
-	// 验证地址格式
-	if !a.ethSigner.IsValidAddress(address) {
-		return nil, fmt.Errorf("invalid ethereum address")
+// VerifyLink 校验新钱包对关联挑战的签名，证明请求方控制该钱包
+func (a *Web3Authenticator) VerifyLink(ctx context.Context, account *WalletAccount, username, message, signature string, origin RequestOrigin) error {
+	return a.verifyChallenge(ctx, a.linkStore, account, message, signature, linkStatement(username), origin)
+}
+
+// createChallenge 按格式在 store 中创建挑战
+func (a *Web3Authenticator) createChallenge(store *ChallengeStore, account *WalletAccount, format string, params SIWEParams) (*auth.Challenge, error) {
+	var (
+		challenge *auth.Challenge
+		err       error
+	)
+	switch format {
+	case "", auth.ChallengeFormatSIWE:
+		challenge, err = store.Create(account.ID, account.Address, params, a.siwe.TTL)
+	case auth.ChallengeFormatEIP712:
+		if account.Chain != user.ChainEthereum {
+			return nil, fmt.Errorf("%w: eip712 is only supported on ethereum", auth.ErrUnsupportedFormat)
+		}
+		challenge, err = store.CreateTypedData(account.ID, account.Address, params, a.siwe.TTL)
+	default:
+		return nil, fmt.Errorf("%w: %q", auth.ErrUnsupportedFormat, format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create challenge: %w", err)
 	}
 	
-	// 获取挑战
-	challenge, ok := a.challengeStore.Get(address)
+	return challenge, nil
+}
+
+// verifyChallenge 校验挑战签名
+//
+// 挑战在校验前取出并删除，每个挑战只能提交一次，无论校验是否成功。
+// statement 不为空时挑战的声明必须与之一致。
+func (a *Web3Authenticator) verifyChallenge(ctx context.Context, store *ChallengeStore, account *WalletAccount, message, signature, statement string, origin RequestOrigin) error {
+	// 取出挑战
+	challenge, ok := store.Take(account.ID)
 	if !ok {
 		a.logger.Warn("challenge not found or expired",
-			zap.String("address", address))
-		return nil, auth.ErrChallengeExpired
+			zap.String("wallet", account.ID))
+		return auth.ErrChallengeExpired
+	}
+	
+	var err error
+	if challenge.Format == auth.ChallengeFormatEIP712 {
+		err = a.verifyTypedData(ctx, challenge, account, signature, statement, origin)
+	} else {
+		err = a.verifySIWE(ctx, challenge, account, message, signature, statement, origin)
+	}
+	if err != nil {
+		return err
+	}
+	
+	return nil
+}
+
+// verifySIWE 校验 SIWE 消息和签名，message 为空时使用挑战中下发的消息
+func (a *Web3Authenticator) verifySIWE(ctx context.Context, challenge *auth.Challenge, account *WalletAccount, message, signature, statement string, origin RequestOrigin) error {
+	if message == "" {
+		message = challenge.Message
+	}
+	
+	// 解析并校验 SIWE 消息
+	siweMessage, err := ParseSIWEMessage(message)
+	if err != nil {
+		a.logger.Warn("invalid siwe message",
+			zap.String("wallet", account.ID),
+			zap.Error(err))
+		return fmt.Errorf("%w: %v", auth.ErrInvalidChallenge, err)
+	}
+	
+	if err := a.validateSIWEMessage(siweMessage, challenge, account, origin, time.Now()); err != nil {
+		a.logger.Warn("siwe message rejected",
+			zap.String("wallet", account.ID),
+			zap.Error(err))
+		return err
+	}
+	if statement != "" && siweMessage.Statement != statement {
+		return fmt.Errorf("%w: statement mismatch", auth.ErrInvalidChallenge)
 	}
 	
 	// 验证签名
-	if err := a.ethSigner.VerifySignature(challenge.Message, signature, address); err != nil {
+	if err := a.verifySignature(ctx, account, message, signature); err != nil {
 		a.logger.Warn("signature verification failed",
-			zap.String("address", address),
+			zap.String("wallet", account.ID),
 			zap.Error(err))
-		return nil, auth.ErrInvalidSignature
+		return auth.ErrInvalidSignature
+	}
+	
+	return nil
+}
+
+// verifyTypedData 校验 EIP-712 挑战的签名
+//
+// 类型化数据由服务端生成并保存摘要，只需确认请求来源与挑战一致并验证摘要的签名。
+func (a *Web3Authenticator) verifyTypedData(ctx context.Context, challenge *auth.Challenge, account *WalletAccount, signature, statement string, origin RequestOrigin) error {
+	if domain := a.siweParams(account, origin).Domain; domain != challenge.Domain {
+		a.logger.Warn("typed data challenge rejected",
+			zap.String("wallet", account.ID),
+			zap.String("domain", domain))
+		return fmt.Errorf("%w: domain mismatch", auth.ErrInvalidChallenge)
+	}
+	if statement != "" && challenge.Statement != statement {
+		return fmt.Errorf("%w: statement mismatch", auth.ErrInvalidChallenge)
+	}
+	
+	if err := a.verifyHash(ctx, account.Address, common.HexToHash(challenge.Digest), signature); err != nil {
+		a.logger.Warn("typed data signature verification failed",
+			zap.String("wallet", account.ID),
+			zap.Error(err))
+		return auth.ErrInvalidSignature
+	}
+	
+	return nil
+}
+
+// linkStatement 关联钱包挑战的声明，把签名绑定到目标用户
+func linkStatement(username string) string {
+	return fmt.Sprintf("Link this wallet to the WebDAV account %q.", username)
+}
+
+// Refresh 使用刷新令牌换取新的令牌对
+//
+// 刷新令牌只能使用一次。已使用过的刷新令牌再次出现说明它可能已泄露，
+// 此时吊销整个令牌家族，迫使用户重新签名登录。
+func (a *Web3Authenticator) Refresh(ctx context.Context, refreshToken string) (*auth.TokenPair, error) {
+	claims, err := a.jwtManager.Verify(refreshToken, TokenTypeRefresh)
+	if err != nil {
+		a.logger.Debug("refresh token verification failed", zap.Error(err))
+		return nil, err
+	}
+	
+	// 检查并作废旧的刷新令牌必须是一步操作，否则并发请求可以用同一个令牌各换到一对新令牌
+	used, err := a.revocations.RevokeIfAbsent(ctx, jtiKey(claims.ID), claims.ExpiresAt.Time)
+	if err != nil {
+		return nil, fmt.Errorf("failed to revoke token: %w", err)
+	}
+	if used {
+		a.logger.Warn("refresh token reused, revoking token family",
+			zap.String("address", claims.Address),
+			zap.String("family", claims.Family))
+		if err := a.revokeFamily(ctx, claims.Family); err != nil {
+			return nil, err
+		}
+		return nil, auth.ErrTokenRevoked
+	}
+	
+	// 令牌本身刚被作废，这里只需检查家族
+	if claims.Family != "" {
+		revoked, err := a.revocations.IsRevoked(ctx, familyKey(claims.Family))
+		if err != nil {
+			return nil, fmt.Errorf("failed to check token revocation: %w", err)
+		}
+		if revoked {
+			return nil, auth.ErrTokenRevoked
+		}
 	}
 	
-	// 删除已使用的挑战
-	a.challengeStore.Delete(address)
+	// 钱包可能已被解绑或用户已被删除
+	if _, err := a.userRepo.FindByWalletAddress(ctx, claims.Address); err != nil {
+		if err == user.ErrUserNotFound {
+			return nil, user.ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
 	
-	// 生成 JWT
-	token, err := a.jwtManager.Generate(address)
+	tokens, err := a.jwtManager.GeneratePair(claims.Address, claims.Family)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate token: %w", err)
 	}
 	
-	a.logger.Info("signature verified, token generated",
+	a.logger.Debug("token refreshed",
+		zap.String("address", claims.Address),
+		zap.String("family", claims.Family))
+	
+	return tokens, nil
+}
+
+// Logout 注销登录，吊销令牌所在的整个令牌家族
+//
+// 可以同时传入访问令牌和刷新令牌，无效或已过期的令牌会被忽略。
+func (a *Web3Authenticator) Logout(ctx context.Context, accessToken, refreshToken string) error {
+	revoked := false
+	
+	for _, t := range []struct {
+		value     string
+		tokenType TokenType
+	}{
+		{accessToken, TokenTypeAccess},
+		{refreshToken, TokenTypeRefresh},
+	} {
+		if t.value == "" {
+			continue
+		}
+		
+		claims, err := a.jwtManager.Verify(t.value, t.tokenType)
+		if err != nil {
+			continue
+		}
+		
+		if err := a.revocations.Revoke(ctx, jtiKey(claims.ID), claims.ExpiresAt.Time); err != nil {
+			return fmt.Errorf("failed to revoke token: %w", err)
+		}
+		if err := a.revokeFamily(ctx, claims.Family); err != nil {
+			return err
+		}
+		
+		a.logger.Info("token family revoked",
+			zap.String("address", claims.Address),
+			zap.String("family", claims.Family))
+		revoked = true
+	}
+	
+	if !revoked {
+		return auth.ErrInvalidToken
+	}
+	
+	return nil
+}
+
+// checkRevoked 检查令牌及其所在家族是否已被吊销
+func (a *Web3Authenticator) checkRevoked(ctx context.Context, claims *Claims) error {
+	keys := []string{jtiKey(claims.ID)}
+	if claims.Family != "" {
+		keys = append(keys, familyKey(claims.Family))
+	}
+	
+	for _, key := range keys {
+		revoked, err := a.revocations.IsRevoked(ctx, key)
+		if err != nil {
+			return fmt.Errorf("failed to check token revocation: %w", err)
+		}
+		if revoked {
+			return auth.ErrTokenRevoked
+		}
+	}
+	
+	return nil
+}
+
+// revokeFamily 吊销令牌家族，记录保留到该家族最后一个刷新令牌过期
+func (a *Web3Authenticator) revokeFamily(ctx context.Context, family string) error {
+	if family == "" {
+		return nil
+	}
+	
+	expiresAt := time.Now().Add(a.jwtManager.RefreshExpiration())
+	if err := a.revocations.Revoke(ctx, familyKey(family), expiresAt); err != nil {
+		return fmt.Errorf("failed to revoke token family: %w", err)
+	}
+	
+	return nil
+}
+
+// jtiKey 单个令牌的吊销键
+func jtiKey(id string) string {
+	return "jti:" + id
+}
+
+// familyKey 令牌家族的吊销键
+func familyKey(family string) string {
+	return "fam:" + family
+}
+
+// verifySignature 验证消息签名
+func (a *Web3Authenticator) verifySignature(ctx context.Context, account *WalletAccount, message, signature string) error {
+	if account.Chain != user.ChainEthereum {
+		return account.signer.VerifySignature(message, signature, account.Address)
+	}
+	return a.verifyHash(ctx, account.Address, a.ethSigner.HashMessage(message), signature)
+}
+
+// verifyHash 验证以太坊摘要签名，ECDSA 验证失败时回退到合约钱包验证
+func (a *Web3Authenticator) verifyHash(ctx context.Context, address string, hash common.Hash, signature string) error {
+	err := a.ethSigner.VerifyHash(hash, signature, address)
+	if err == nil || a.contractWallet == nil {
+		return err
+	}
+	
+	if contractErr := a.contractWallet.VerifyHash(ctx, hash, signature, address); contractErr != nil {
+		if errors.Is(contractErr, crypto.ErrNotContractWallet) {
+			return err
+		}
+		return contractErr
+	}
+	
+	a.logger.Debug("signature verified via contract wallet",
 		zap.String("address", address))
 	
-	return token, nil
+	return nil
+}
+
+// siweParams 根据配置、账户所在的链和请求来源确定 SIWE 消息的参数
+//
+// 域名只取自配置：请求的 Host 由客户端控制，代理本站的钓鱼站点可借此让钱包显示自己的域名。
+// 未配置 URI 时只从请求中取协议。
+func (a *Web3Authenticator) siweParams(account *WalletAccount, origin RequestOrigin) SIWEParams {
+	params := SIWEParams{
+		Domain:     a.siwe.Domain,
+		URI:        a.siwe.URI,
+		Statement:  a.siwe.Statement,
+		Blockchain: account.signer.Blockchain(),
+		ChainID:    account.ChainID,
+		Resources:  a.siwe.Resources,
+	}
+	if params.URI == "" {
+		params.URI = RequestOrigin{Host: params.Domain, Secure: origin.Secure}.URI()
+	}
+	return params
+}
+
+// validateSIWEMessage 校验 SIWE 消息的每个字段
+func (a *Web3Authenticator) validateSIWEMessage(m *SIWEMessage, challenge *auth.Challenge, account *WalletAccount, origin RequestOrigin, now time.Time) error {
+	params := a.siweParams(account, origin)
+	
+	// 域名绑定：消息必须面向本站点，且与下发挑战时一致
+	if m.Domain != params.Domain || m.Domain != challenge.Domain {
+		return fmt.Errorf("%w: domain mismatch", auth.ErrInvalidChallenge)
+	}
+	if m.URI != challenge.URI {
+		return fmt.Errorf("%w: uri mismatch", auth.ErrInvalidChallenge)
+	}
+	
+	// 链和地址必须与请求一致，以太坊地址必须是 EIP-55 校验和形式
+	if m.blockchain() != params.Blockchain {
+		return fmt.Errorf("%w: blockchain mismatch", auth.ErrInvalidChallenge)
+	}
+	if m.Address != account.Address {
+		return fmt.Errorf("%w: address mismatch", auth.ErrInvalidChallenge)
+	}
+	
+	if m.ChainID != challenge.ChainID {
+		return fmt.Errorf("%w: chain id mismatch", auth.ErrInvalidChallenge)
+	}
+	
+	// nonce 必须是本次挑战下发的值，防止重放
+	if m.Nonce != challenge.Nonce {
+		return fmt.Errorf("%w: nonce mismatch", auth.ErrInvalidChallenge)
+	}
+	
+	// 时间窗口
+	skew := a.siwe.ClockSkew
+	if m.IssuedAt.After(now.Add(skew)) || m.IssuedAt.Before(challenge.IssuedAt.Add(-skew)) {
+		return fmt.Errorf("%w: issued-at outside challenge window", auth.ErrInvalidChallenge)
+	}
+	if m.ExpirationTime != nil && !now.Before(*m.ExpirationTime) {
+		return auth.ErrChallengeExpired
+	}
+	if m.NotBefore != nil && now.Add(skew).Before(*m.NotBefore) {
+		return fmt.Errorf("%w: message not yet valid", auth.ErrInvalidChallenge)
+	}
+	
+	return nil
 }
 
 // GetJWTManager 获取 JWT 管理器（用于其他地方验证 token）
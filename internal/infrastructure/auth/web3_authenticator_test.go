@@ -0,0 +1,447 @@
+package auth
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/ecdsa"
+	"github.com/mr-tron/base58"
+	"github.com/yeying-community/webdav/internal/domain/auth"
+	"github.com/yeying-community/webdav/internal/domain/user"
+	"github.com/yeying-community/webdav/internal/infrastructure/config"
+	"github.com/yeying-community/webdav/internal/infrastructure/repository"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/ripemd160"
+)
+
+var testOrigin = RequestOrigin{Host: "dav.example.com", Secure: true}
+
+// testWallet 测试用钱包，address 为链上地址，sign 对 SIWE 消息签名
+type testWallet struct {
+	chain   string
+	address string
+	sign    func(message string) string
+}
+
+func newTestWeb3Authenticator(t *testing.T) (*Web3Authenticator, user.Repository) {
+	t.Helper()
+
+	cfg := config.Web3Config{
+		JWTSecret:              "test-secret-test-secret-test-secret",
+		TokenExpiration:        time.Minute,
+		RefreshTokenExpiration: time.Hour,
+		SIWE: config.SIWEConfig{
+			Domain:    testOrigin.Host,
+			URI:       testOrigin.URI(),
+			ChainID:   1,
+			Statement: "Sign in to WebDAV.",
+			TTL:       time.Minute,
+			ClockSkew: time.Minute,
+		},
+		Chains: []string{user.ChainEthereum, user.ChainSolana, user.ChainBitcoin},
+	}
+	jwtManager, err := NewJWTManager(cfg)
+	if err != nil {
+		t.Fatalf("NewJWTManager: %v", err)
+	}
+
+	repo := repository.NewMemoryUserRepository(nil)
+	a := NewWeb3Authenticator(repo, cfg, jwtManager, nil, NewMemoryRevocationStore(), zap.NewNop())
+	return a, repo
+}
+
+func solanaWallet(seed string) *testWallet {
+	s := sha256.Sum256([]byte(seed))
+	key := ed25519.NewKeyFromSeed(s[:])
+	return &testWallet{
+		chain:   user.ChainSolana,
+		address: base58.Encode(key.Public().(ed25519.PublicKey)),
+		sign: func(message string) string {
+			return base58.Encode(ed25519.Sign(key, []byte(message)))
+		},
+	}
+}
+
+// bitcoinP2PKHWallet 传统 P2PKH 地址（base58check，区分大小写），BIP-137 签名
+func bitcoinP2PKHWallet(seed string) *testWallet {
+	s := sha256.Sum256([]byte(seed))
+	key, _ := btcec.PrivKeyFromBytes(s[:])
+
+	payload := append([]byte{0x00}, hash160Test(key.PubKey().SerializeCompressed())...)
+	checksum := doubleSHA256Test(payload)[:4]
+
+	return &testWallet{
+		chain:   user.ChainBitcoin,
+		address: base58.Encode(append(payload, checksum...)),
+		sign: func(message string) string {
+			buf := []byte("\x18Bitcoin Signed Message:\n")
+			if n := len(message); n < 0xfd {
+				buf = append(buf, byte(n))
+			} else {
+				buf = append(buf, 0xfd, byte(n), byte(n>>8))
+			}
+			buf = append(buf, message...)
+			return base64.StdEncoding.EncodeToString(ecdsa.SignCompact(key, doubleSHA256Test(buf), true))
+		},
+	}
+}
+
+func hash160Test(data []byte) []byte {
+	sum := sha256.Sum256(data)
+	h := ripemd160.New()
+	h.Write(sum[:])
+	return h.Sum(nil)
+}
+
+func doubleSHA256Test(data []byte) []byte {
+	first := sha256.Sum256(data)
+	second := sha256.Sum256(first[:])
+	return second[:]
+}
+
+func TestWeb3NonEthereumSession(t *testing.T) {
+	wallets := []*testWallet{
+		solanaWallet("solana"),
+		bitcoinP2PKHWallet("bitcoin"),
+	}
+
+	for _, w := range wallets {
+		t.Run(w.chain, func(t *testing.T) {
+			ctx := context.Background()
+			a, repo := newTestWeb3Authenticator(t)
+
+			account, err := a.ResolveAccount(w.chain, w.address)
+			if err != nil {
+				t.Fatalf("ResolveAccount: %v", err)
+			}
+			if account.ID == strings.ToLower(account.ID) {
+				t.Fatalf("wallet id %q should be mixed case", account.ID)
+			}
+
+			u := user.NewUser(w.chain+"-user", "/"+w.chain)
+			if err := u.SetWalletAddress(account.ID); err != nil {
+				t.Fatalf("SetWalletAddress: %v", err)
+			}
+			if err := repo.Save(ctx, u); err != nil {
+				t.Fatalf("Save: %v", err)
+			}
+
+			challenge, err := a.CreateChallenge(account, "", testOrigin)
+			if err != nil {
+				t.Fatalf("CreateChallenge: %v", err)
+			}
+			tokens, err := a.VerifySignature(ctx, account, "", w.sign(challenge.Message), testOrigin)
+			if err != nil {
+				t.Fatalf("VerifySignature: %v", err)
+			}
+
+			authenticated, err := a.Authenticate(ctx, &auth.BearerCredentials{Token: tokens.Access.Value})
+			if err != nil {
+				t.Fatalf("Authenticate: %v", err)
+			}
+			if authenticated.Username != u.Username {
+				t.Fatalf("authenticated as %q, want %q", authenticated.Username, u.Username)
+			}
+
+			refreshed, err := a.Refresh(ctx, tokens.Refresh.Value)
+			if err != nil {
+				t.Fatalf("Refresh: %v", err)
+			}
+			if _, err := a.Authenticate(ctx, &auth.BearerCredentials{Token: refreshed.Access.Value}); err != nil {
+				t.Fatalf("Authenticate refreshed token: %v", err)
+			}
+		})
+	}
+}
+
+func TestWeb3ChallengeSingleUse(t *testing.T) {
+	ctx := context.Background()
+	a, repo := newTestWeb3Authenticator(t)
+	w := solanaWallet("single-use")
+
+	account, err := a.ResolveAccount(w.chain, w.address)
+	if err != nil {
+		t.Fatalf("ResolveAccount: %v", err)
+	}
+	u := user.NewUser("alice", "/alice")
+	if err := u.SetWalletAddress(account.ID); err != nil {
+		t.Fatalf("SetWalletAddress: %v", err)
+	}
+	if err := repo.Save(ctx, u); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	// concurrently 并发提交同一签名，返回成功的次数
+	concurrently := func(verify func() error) int {
+		var (
+			wg       sync.WaitGroup
+			mu       sync.Mutex
+			accepted int
+		)
+		start := make(chan struct{})
+		for i := 0; i < 16; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				<-start
+				err := verify()
+				if err != nil && !errors.Is(err, auth.ErrChallengeExpired) {
+					t.Errorf("verify: %v", err)
+				}
+				if err == nil {
+					mu.Lock()
+					accepted++
+					mu.Unlock()
+				}
+			}()
+		}
+		close(start)
+		wg.Wait()
+		return accepted
+	}
+
+	t.Run("login", func(t *testing.T) {
+		challenge, err := a.CreateChallenge(account, "", testOrigin)
+		if err != nil {
+			t.Fatalf("CreateChallenge: %v", err)
+		}
+		signature := w.sign(challenge.Message)
+		accepted := concurrently(func() error {
+			_, err := a.VerifySignature(ctx, account, "", signature, testOrigin)
+			return err
+		})
+		if accepted != 1 {
+			t.Fatalf("accepted = %d, want 1", accepted)
+		}
+	})
+
+	t.Run("link", func(t *testing.T) {
+		challenge, err := a.CreateLinkChallenge(account, "alice", "", testOrigin)
+		if err != nil {
+			t.Fatalf("CreateLinkChallenge: %v", err)
+		}
+		signature := w.sign(challenge.Message)
+		accepted := concurrently(func() error {
+			return a.VerifyLink(ctx, account, "alice", "", signature, testOrigin)
+		})
+		if accepted != 1 {
+			t.Fatalf("accepted = %d, want 1", accepted)
+		}
+	})
+
+	t.Run("failed verification consumes the challenge", func(t *testing.T) {
+		challenge, err := a.CreateChallenge(account, "", testOrigin)
+		if err != nil {
+			t.Fatalf("CreateChallenge: %v", err)
+		}
+		forged := solanaWallet("someone else").sign(challenge.Message)
+		if _, err := a.VerifySignature(ctx, account, "", forged, testOrigin); err == nil {
+			t.Fatal("VerifySignature should reject a forged signature")
+		}
+		_, err = a.VerifySignature(ctx, account, "", w.sign(challenge.Message), testOrigin)
+		if !errors.Is(err, auth.ErrChallengeExpired) {
+			t.Fatalf("err = %v, want ErrChallengeExpired", err)
+		}
+	})
+}
+
+func TestWeb3DomainIgnoresRequestHost(t *testing.T) {
+	ctx := context.Background()
+	a, repo := newTestWeb3Authenticator(t)
+	a.siwe.URI = ""
+	w := solanaWallet("domain")
+
+	account, err := a.ResolveAccount(w.chain, w.address)
+	if err != nil {
+		t.Fatalf("ResolveAccount: %v", err)
+	}
+	u := user.NewUser("alice", "/alice")
+	if err := u.SetWalletAddress(account.ID); err != nil {
+		t.Fatalf("SetWalletAddress: %v", err)
+	}
+	if err := repo.Save(ctx, u); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	// 代理本站的钓鱼站点转发请求时，Host 为钓鱼站点的域名
+	phishing := RequestOrigin{Host: "dav-example.phish", Secure: true}
+	challenge, err := a.CreateChallenge(account, "", phishing)
+	if err != nil {
+		t.Fatalf("CreateChallenge: %v", err)
+	}
+	if challenge.Domain != testOrigin.Host || challenge.URI != testOrigin.URI() {
+		t.Fatalf("challenge bound to %q %q, want %q %q", challenge.Domain, challenge.URI, testOrigin.Host, testOrigin.URI())
+	}
+	if strings.Contains(challenge.Message, phishing.Host) {
+		t.Fatalf("message mentions the request host:\n%s", challenge.Message)
+	}
+
+	// 用请求的 Host 改写的消息不能通过验证
+	forged := strings.ReplaceAll(challenge.Message, testOrigin.Host, phishing.Host)
+	if _, err := a.VerifySignature(ctx, account, forged, w.sign(forged), phishing); !errors.Is(err, auth.ErrInvalidChallenge) {
+		t.Fatalf("err = %v, want ErrInvalidChallenge", err)
+	}
+}
+
+// web3Session 保存绑定钱包的用户并签名登录，返回令牌对
+func web3Session(t *testing.T, a *Web3Authenticator, repo user.Repository, w *testWallet, username string) *auth.TokenPair {
+	t.Helper()
+	ctx := context.Background()
+
+	account, err := a.ResolveAccount(w.chain, w.address)
+	if err != nil {
+		t.Fatalf("ResolveAccount: %v", err)
+	}
+	u := user.NewUser(username, "/"+username)
+	if err := u.SetWalletAddress(account.ID); err != nil {
+		t.Fatalf("SetWalletAddress: %v", err)
+	}
+	if err := repo.Save(ctx, u); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	challenge, err := a.CreateChallenge(account, "", testOrigin)
+	if err != nil {
+		t.Fatalf("CreateChallenge: %v", err)
+	}
+	tokens, err := a.VerifySignature(ctx, account, "", w.sign(challenge.Message), testOrigin)
+	if err != nil {
+		t.Fatalf("VerifySignature: %v", err)
+	}
+	return tokens
+}
+
+func TestWeb3RefreshRotation(t *testing.T) {
+	ctx := context.Background()
+	a, repo := newTestWeb3Authenticator(t)
+	first := web3Session(t, a, repo, solanaWallet("rotation"), "alice")
+
+	// 每次刷新都换发新的刷新令牌，旧令牌作废
+	second, err := a.Refresh(ctx, first.Refresh.Value)
+	if err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if second.Refresh.Value == first.Refresh.Value {
+		t.Fatal("refresh token was not rotated")
+	}
+	third, err := a.Refresh(ctx, second.Refresh.Value)
+	if err != nil {
+		t.Fatalf("Refresh rotated token: %v", err)
+	}
+	if _, err := a.Authenticate(ctx, &auth.BearerCredentials{Token: third.Access.Value}); err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+
+	// 重放已使用的刷新令牌，吊销整个家族
+	if _, err := a.Refresh(ctx, first.Refresh.Value); !errors.Is(err, auth.ErrTokenRevoked) {
+		t.Fatalf("reused refresh: err = %v, want ErrTokenRevoked", err)
+	}
+	if _, err := a.Refresh(ctx, third.Refresh.Value); !errors.Is(err, auth.ErrTokenRevoked) {
+		t.Fatalf("refresh after reuse: err = %v, want ErrTokenRevoked", err)
+	}
+	for name, token := range map[string]string{"first": first.Access.Value, "third": third.Access.Value} {
+		if _, err := a.Authenticate(ctx, &auth.BearerCredentials{Token: token}); !errors.Is(err, auth.ErrTokenRevoked) {
+			t.Fatalf("%s access token: err = %v, want ErrTokenRevoked", name, err)
+		}
+	}
+
+	// 其他家族不受影响
+	other := web3Session(t, a, repo, solanaWallet("other"), "bob")
+	if _, err := a.Refresh(ctx, other.Refresh.Value); err != nil {
+		t.Fatalf("Refresh other family: %v", err)
+	}
+}
+
+func TestWeb3RefreshConcurrentReuse(t *testing.T) {
+	ctx := context.Background()
+	a, repo := newTestWeb3Authenticator(t)
+	tokens := web3Session(t, a, repo, solanaWallet("concurrent"), "alice")
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		accepted int
+	)
+	start := make(chan struct{})
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			_, err := a.Refresh(ctx, tokens.Refresh.Value)
+			if err != nil && !errors.Is(err, auth.ErrTokenRevoked) {
+				t.Errorf("Refresh: %v", err)
+			}
+			if err == nil {
+				mu.Lock()
+				accepted++
+				mu.Unlock()
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	// 同一刷新令牌只能换发一次
+	if accepted != 1 {
+		t.Fatalf("accepted = %d, want 1", accepted)
+	}
+}
+
+func TestWeb3Logout(t *testing.T) {
+	tests := []struct {
+		name    string
+		access  bool
+		refresh bool
+	}{
+		{name: "access token", access: true},
+		{name: "refresh token", refresh: true},
+		{name: "both tokens", access: true, refresh: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			a, repo := newTestWeb3Authenticator(t)
+			tokens := web3Session(t, a, repo, solanaWallet("logout"), "alice")
+			refreshed, err := a.Refresh(ctx, tokens.Refresh.Value)
+			if err != nil {
+				t.Fatalf("Refresh: %v", err)
+			}
+
+			var access, refresh string
+			if tt.access {
+				access = refreshed.Access.Value
+			}
+			if tt.refresh {
+				refresh = refreshed.Refresh.Value
+			}
+			if err := a.Logout(ctx, access, refresh); err != nil {
+				t.Fatalf("Logout: %v", err)
+			}
+
+			// 任一令牌注销后，同一家族的令牌都不能再使用
+			if _, err := a.Authenticate(ctx, &auth.BearerCredentials{Token: refreshed.Access.Value}); !errors.Is(err, auth.ErrTokenRevoked) {
+				t.Fatalf("Authenticate: err = %v, want ErrTokenRevoked", err)
+			}
+			if _, err := a.Refresh(ctx, refreshed.Refresh.Value); !errors.Is(err, auth.ErrTokenRevoked) {
+				t.Fatalf("Refresh: err = %v, want ErrTokenRevoked", err)
+			}
+		})
+	}
+
+	t.Run("invalid tokens", func(t *testing.T) {
+		a, _ := newTestWeb3Authenticator(t)
+		if err := a.Logout(context.Background(), "not-a-token", ""); !errors.Is(err, auth.ErrInvalidToken) {
+			t.Fatalf("err = %v, want ErrInvalidToken", err)
+		}
+	})
+}
@@ -1,7 +1,10 @@
 package config
 
 import (
+	"strconv"
 	"time"
+
+	"github.com/yeying-community/webdav/internal/domain/user"
 )
 
 // Config 应用配置
@@ -9,44 +12,275 @@ type Config struct {
 	Server   ServerConfig   `yaml:"server"`
 	WebDAV   WebDAVConfig   `yaml:"webdav"`
 	Web3     Web3Config     `yaml:"web3"`
+	OIDC     OIDCConfig     `yaml:"oidc"`
+	LDAP     LDAPConfig     `yaml:"ldap"`
+	Digest   DigestConfig   `yaml:"digest"`
 	Security SecurityConfig `yaml:"security"`
 	CORS     CORSConfig     `yaml:"cors"`
 	Log      LogConfig      `yaml:"log"`
+	Storage  StorageConfig  `yaml:"storage"`
 	Users    []UserConfig   `yaml:"users"`
 }
 
 // ServerConfig 服务器配置
 type ServerConfig struct {
-	Address         string        `yaml:"address"`
-	Port            int           `yaml:"port"`
-	TLS             bool          `yaml:"tls"`
-	CertFile        string        `yaml:"cert_file"`
-	KeyFile         string        `yaml:"key_file"`
-	ReadTimeout     time.Duration `yaml:"read_timeout"`
-	WriteTimeout    time.Duration `yaml:"write_timeout"`
-	IdleTimeout     time.Duration `yaml:"idle_timeout"`
-	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+	Address         string           `yaml:"address"`
+	Port            int              `yaml:"port"`
+	TLS             bool             `yaml:"tls"`
+	CertFile        string           `yaml:"cert_file"`
+	KeyFile         string           `yaml:"key_file"`
+	ReadTimeout     time.Duration    `yaml:"read_timeout"`
+	WriteTimeout    time.Duration    `yaml:"write_timeout"`
+	IdleTimeout     time.Duration    `yaml:"idle_timeout"`
+	ShutdownTimeout time.Duration    `yaml:"shutdown_timeout"`
+	ClientCerts     ClientCertConfig `yaml:"client_certs"`
+}
+
+// ClientCertConfig 客户端证书（mTLS）配置，配置 ca_file 后启用
+type ClientCertConfig struct {
+	CAFile        string   `yaml:"ca_file"`        // 签发客户端证书的 CA
+	Mode          string   `yaml:"mode"`           // optional：有证书时验证；require：所有连接都必须提供证书
+	MapBy         []string `yaml:"map_by"`         // 证书映射到用户的方式及顺序：fingerprint, san, cn
+	RequiredUsers []string `yaml:"required_users"` // 必须使用本人证书的用户
+	RequiredPaths []string `yaml:"required_paths"` // 必须提供证书的路径前缀，与用户规则一样不含 webdav.prefix
 }
 
 // WebDAVConfig WebDAV 配置
 type WebDAVConfig struct {
-	Prefix      string `yaml:"prefix"`
-	Directory   string `yaml:"directory"`
-	NoSniff     bool   `yaml:"no_sniff"`
-	Permissions string `yaml:"permissions"`
+	Prefix       string              `yaml:"prefix"`
+	Directory    string              `yaml:"directory"`
+	NoSniff      bool                `yaml:"no_sniff"`
+	Permissions  string              `yaml:"permissions"`
+	DefaultQuota string              `yaml:"default_quota"` // 默认用户配额，如 "10GB"，为空表示不限
+	Storage      WebDAVStorageConfig `yaml:"storage"`
+	DeadProps    DeadPropsConfig     `yaml:"dead_props"`
+}
+
+// DeadPropsConfig 死属性（PROPPATCH 自定义属性）存储配置
+type DeadPropsConfig struct {
+	Driver string `yaml:"driver"` // auto, xattr, bolt, none
+	Path   string `yaml:"path"`   // bolt 数据库文件路径
+}
+
+// WebDAVStorageConfig WebDAV 存储后端配置
+type WebDAVStorageConfig struct {
+	Driver string   `yaml:"driver"` // local, s3, memory
+	S3     S3Config `yaml:"s3"`
+}
+
+// S3Config S3 兼容对象存储配置
+type S3Config struct {
+	Endpoint  string `yaml:"endpoint"`
+	Region    string `yaml:"region"`
+	Bucket    string `yaml:"bucket"`
+	Prefix    string `yaml:"prefix"`
+	AccessKey string `yaml:"access_key"`
+	SecretKey string `yaml:"secret_key"`
+	UseSSL    bool   `yaml:"use_ssl"`
+	PathStyle bool   `yaml:"path_style"`
+	PartSize  uint64 `yaml:"part_size"` // 分片上传大小（字节）
 }
 
 // Web3Config Web3 配置
 type Web3Config struct {
-	Enabled         bool          `yaml:"enabled"`
-	JWTSecret       string        `yaml:"jwt_secret"`
-	TokenExpiration time.Duration `yaml:"token_expiration"`
+	Enabled                bool               `yaml:"enabled"`
+	JWTSecret              string             `yaml:"jwt_secret"`               // HS256 密钥，配置了 signing_keys 时仅用于验证旧令牌
+	SigningKeys            []SigningKeyConfig `yaml:"signing_keys"`             // 非对称签名密钥，第一个带私钥的用于签发
+	TokenExpiration        time.Duration      `yaml:"token_expiration"`         // 访问令牌有效期
+	RefreshTokenExpiration time.Duration      `yaml:"refresh_token_expiration"` // 刷新令牌有效期，每次刷新都会轮换
+	SIWE                   SIWEConfig         `yaml:"siwe"`
+	Chains                 []string           `yaml:"chains"`    // 允许登录的链：ethereum、solana、bitcoin，第一个为默认链
+	ChainIDs               map[string]string  `yaml:"chain_ids"` // 非 EVM 链的 CAIP-2 链标识，默认为主网；以太坊使用 siwe.chain_id
+	RPC                    RPCConfig          `yaml:"rpc"`
+	TokenGate              TokenGateConfig    `yaml:"token_gate"`
+	Registration           RegistrationConfig `yaml:"registration"`
+}
+
+// RegistrationConfig 钱包自助注册配置
+//
+// 启用后未登记的钱包签名登录时自动创建用户。配置了 allowlist 或 invite_codes 时，
+// 地址在名单中或提供有效邀请码才能注册；两者都为空时任何钱包都可以注册。
+type RegistrationConfig struct {
+	Enabled            bool     `yaml:"enabled"`
+	Directory          string   `yaml:"directory"`           // 用户目录，{address} 替换为链上地址（以太坊为小写地址）
+	DefaultPermissions string   `yaml:"default_permissions"` // 注册用户的默认权限
+	Allowlist          []string `yaml:"allowlist"`           // 允许注册的钱包，以太坊为地址，其他链为 CAIP-10 账户标识
+	InviteCodes        []string `yaml:"invite_codes"`        // 邀请码，可重复使用
+}
+
+// defaultChainIDs 非 EVM 链主网的 CAIP-2 链标识
+var defaultChainIDs = map[string]string{
+	user.ChainSolana:  "5eykt4UsFv8P8NJdTREpY1vzqKqZKvdp",
+	user.ChainBitcoin: "000000000019d6689c085ae165831e93",
+}
+
+// ChainReference 链的 CAIP-2 链标识
+func (c Web3Config) ChainReference(chain string) string {
+	if chain == user.ChainEthereum {
+		return strconv.FormatInt(c.SIWE.ChainID, 10)
+	}
+	if reference := c.ChainIDs[chain]; reference != "" {
+		return reference
+	}
+	return defaultChainIDs[chain]
+}
+
+// TokenGateConfig 链上持有条件规则配置
+type TokenGateConfig struct {
+	RPC      RPCConfig     `yaml:"rpc"`       // 代币所在链的节点，为空时使用 web3.rpc
+	CacheTTL time.Duration `yaml:"cache_ttl"` // 余额和所有者查询结果的缓存时间
+}
+
+// TokenGateRPC 查询持有条件使用的 RPC 节点
+func (c Web3Config) TokenGateRPC() RPCConfig {
+	if c.TokenGate.RPC.URL != "" {
+		return c.TokenGate.RPC
+	}
+	return c.RPC
+}
+
+// SigningKeyConfig JWT 签名密钥配置
+//
+// 只配置公钥的密钥仅用于验证，用于密钥轮换期间继续接受旧密钥签发的令牌。
+type SigningKeyConfig struct {
+	ID             string `yaml:"kid"`
+	Algorithm      string `yaml:"algorithm"`        // RS256, ES256, EdDSA
+	PrivateKeyFile string `yaml:"private_key_file"` // PEM 格式私钥
+	PublicKeyFile  string `yaml:"public_key_file"`  // PEM 格式公钥，配置了私钥时可省略
+}
+
+// OIDCConfig OpenID Connect 登录配置
+type OIDCConfig struct {
+	Enabled            bool              `yaml:"enabled"`
+	Issuer             string            `yaml:"issuer"` // 通过 {issuer}/.well-known/openid-configuration 发现端点和 JWKS
+	ClientID           string            `yaml:"client_id"`
+	ClientSecret       string            `yaml:"client_secret"`
+	RedirectURL        string            `yaml:"redirect_url"` // 为空时使用请求来源 + /api/auth/oidc/callback
+	Scopes             []string          `yaml:"scopes"`
+	Audience           string            `yaml:"audience"`       // Bearer 令牌的 aud，为空时使用 client_id
+	UsernameClaim      string            `yaml:"username_claim"` // 映射为用户名的声明
+	GroupsClaim        string            `yaml:"groups_claim"`
+	AutoProvision      bool              `yaml:"auto_provision"`      // 首次登录时自动创建用户
+	Directory          string            `yaml:"directory"`           // 自动创建用户的目录，{username} 替换为用户名
+	DefaultPermissions string            `yaml:"default_permissions"` // 自动创建用户的默认权限
+	GroupPermissions   map[string]string `yaml:"group_permissions"`   // 组 -> 追加的权限
+	AdminGroups        []string          `yaml:"admin_groups"`        // 这些组的成员获得管理员角色
+}
+
+// LDAPConfig LDAP 认证配置
+type LDAPConfig struct {
+	Enabled            bool              `yaml:"enabled"`
+	URL                string            `yaml:"url"`                  // ldap:// 或 ldaps://
+	StartTLS           bool              `yaml:"start_tls"`            // ldap:// 连接升级为 TLS
+	InsecureSkipVerify bool              `yaml:"insecure_skip_verify"` // 跳过证书校验，仅用于测试
+	CAFile             string            `yaml:"ca_file"`              // 自定义 CA 证书（PEM）
+	BindDN             string            `yaml:"bind_dn"`              // 搜索用户时使用的服务账号，为空时匿名绑定
+	BindPassword       string            `yaml:"bind_password"`
+	BaseDN             string            `yaml:"base_dn"`
+	UserFilter         string            `yaml:"user_filter"`         // {username} 替换为转义后的用户名
+	UsernameAttribute  string            `yaml:"username_attribute"`  // 作为本地用户名的属性，统一大小写
+	MemberOfAttribute  string            `yaml:"member_of_attribute"` // 用户条目中记录所属组 DN 的属性
+	GroupBaseDN        string            `yaml:"group_base_dn"`       // 配置后按 group_filter 搜索组
+	GroupFilter        string            `yaml:"group_filter"`        // {dn} 和 {username} 替换为转义后的值
+	GroupNameAttribute string            `yaml:"group_name_attribute"`
+	Timeout            time.Duration     `yaml:"timeout"`
+	PoolSize           int               `yaml:"pool_size"`           // 空闲连接数上限
+	Fallback           bool              `yaml:"fallback"`            // 目录中不存在或目录不可用时回退到本地账号
+	Directory          string            `yaml:"directory"`           // 首次登录创建用户的目录，{username} 替换为用户名
+	DefaultPermissions string            `yaml:"default_permissions"` // 首次登录创建用户的默认权限
+	Groups             []LDAPGroupConfig `yaml:"groups"`
+}
+
+// LDAPGroupConfig LDAP 组到权限的映射
+//
+// Name 可以是组的完整 DN 或组名（group_name_attribute 的值，或 DN 的第一个 RDN 值）。
+type LDAPGroupConfig struct {
+	Name        string       `yaml:"name"`
+	Permissions string       `yaml:"permissions"` // 追加的权限
+	Admin       bool         `yaml:"admin"`       // 授予管理员角色
+	Rules       []RuleConfig `yaml:"rules"`       // 优先于用户自身规则
+}
+
+// DigestConfig HTTP Digest 认证配置（RFC 7616）
+//
+// 启用后设置密码时额外保存 H(username:realm:password)，该摘要与明文密码等价，
+// 修改 realm 后需要用户重新设置密码或使用密码登录一次。
+type DigestConfig struct {
+	Enabled    bool          `yaml:"enabled"`
+	Realm      string        `yaml:"realm"`
+	Algorithms []string      `yaml:"algorithms"` // SHA-256、MD5，按优先顺序发送质询
+	NonceTTL   time.Duration `yaml:"nonce_ttl"`  // nonce 有效期，过期后客户端使用新 nonce 重试
+}
+
+// RPCConfig 以太坊 JSON-RPC 节点配置
+type RPCConfig struct {
+	URL     string        `yaml:"url"` // 为空时不校验合约钱包签名
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// SIWEConfig Sign-In with Ethereum（EIP-4361）消息配置
+type SIWEConfig struct {
+	Domain    string        `yaml:"domain"` // 钱包中显示的站点 host[:port]，启用 Web3 时必填
+	URI       string        `yaml:"uri"`    // 为空时由 Domain 和请求的协议组成
+	ChainID   int64         `yaml:"chain_id"`
+	Statement string        `yaml:"statement"`
+	Resources []string      `yaml:"resources"`  // 消息中列出的资源，即类型化数据的 scope，由服务端固定
+	TTL       time.Duration `yaml:"ttl"`        // 挑战有效期
+	ClockSkew time.Duration `yaml:"clock_skew"` // 允许的时钟偏差
 }
 
 // SecurityConfig 安全配置
 type SecurityConfig struct {
-	NoPassword   bool `yaml:"no_password"`
-	BehindProxy  bool `yaml:"behind_proxy"`
+	NoPassword   bool               `yaml:"no_password"`
+	BehindProxy  bool               `yaml:"behind_proxy"` // 从 X-Forwarded-For / X-Real-IP 获取客户端 IP
+	BruteForce   BruteForceConfig   `yaml:"brute_force"`
+	RateLimit    RateLimitConfig    `yaml:"rate_limit"`
+	TOTPIssuer   string             `yaml:"totp_issuer"` // 两步验证在验证器应用中显示的名称
+	PasswordHash PasswordHashConfig `yaml:"password_hash"`
+}
+
+// PasswordHashConfig 密码哈希配置
+//
+// 新密码使用 algorithm 指定的算法；以其他算法或参数保存的密码在下次登录成功时重新哈希。
+type PasswordHashConfig struct {
+	Algorithm  string         `yaml:"algorithm"` // argon2id、scrypt、bcrypt
+	BcryptCost int            `yaml:"bcrypt_cost"`
+	Argon2id   Argon2idConfig `yaml:"argon2id"`
+	Scrypt     ScryptConfig   `yaml:"scrypt"`
+}
+
+// Argon2idConfig argon2id 参数
+type Argon2idConfig struct {
+	Memory      uint32 `yaml:"memory"` // KiB
+	Iterations  uint32 `yaml:"iterations"`
+	Parallelism uint8  `yaml:"parallelism"`
+}
+
+// ScryptConfig scrypt 参数
+type ScryptConfig struct {
+	N int `yaml:"n"` // CPU/内存开销，2 的幂
+	R int `yaml:"r"`
+	P int `yaml:"p"`
+}
+
+// BruteForceConfig 暴力破解防护配置
+//
+// 按客户端 IP 和用户名分别统计认证失败次数，达到阈值后锁定，
+// 锁定时长从 base_delay 开始每次失败翻倍，不超过 max_delay。
+type BruteForceConfig struct {
+	Enabled           bool          `yaml:"enabled"`
+	IPThreshold       int           `yaml:"ip_threshold"`       // 同一 IP 允许的连续失败次数
+	UsernameThreshold int           `yaml:"username_threshold"` // 同一用户名允许的连续失败次数
+	BaseDelay         time.Duration `yaml:"base_delay"`
+	MaxDelay          time.Duration `yaml:"max_delay"`
+	ResetAfter        time.Duration `yaml:"reset_after"` // 超过该时间没有失败则清零计数
+}
+
+// RateLimitConfig 请求限流配置，令牌桶按用户（未认证请求按 IP）划分
+type RateLimitConfig struct {
+	Enabled           bool    `yaml:"enabled"`
+	RequestsPerSecond float64 `yaml:"requests_per_second"`
+	Burst             int     `yaml:"burst"`
 }
 
 // CORSConfig CORS 配置
@@ -67,21 +301,71 @@ type LogConfig struct {
 	Outputs []string `yaml:"outputs"`
 }
 
+// StorageConfig 持久化存储配置
+type StorageConfig struct {
+	Users  UserStorageConfig  `yaml:"users"`
+	Locks  LockStorageConfig  `yaml:"locks"`
+	Tokens TokenStorageConfig `yaml:"tokens"`
+}
+
+// TokenStorageConfig 令牌吊销列表存储配置
+type TokenStorageConfig struct {
+	Driver string `yaml:"driver"` // memory, bolt
+	Path   string `yaml:"path"`   // bolt 数据库文件路径
+}
+
+// UserStorageConfig 用户存储配置
+type UserStorageConfig struct {
+	Driver string `yaml:"driver"` // memory, sqlite
+	Path   string `yaml:"path"`   // sqlite 数据库文件路径
+}
+
+// LockStorageConfig WebDAV 锁存储配置
+type LockStorageConfig struct {
+	Driver        string        `yaml:"driver"`         // memory, bolt, redis
+	Path          string        `yaml:"path"`           // bolt 数据库文件路径
+	SweepInterval time.Duration `yaml:"sweep_interval"` // 过期锁清理间隔
+	Redis         RedisConfig   `yaml:"redis"`
+}
+
+// RedisConfig Redis 协议共享存储配置
+type RedisConfig struct {
+	Address  string `yaml:"address"`
+	Password string `yaml:"password"`
+	DB       int    `yaml:"db"`
+	Prefix   string `yaml:"prefix"` // 键前缀，多个部署共用同一 Redis 时用于隔离
+}
+
 // UserConfig 用户配置
 type UserConfig struct {
 	Username      string       `yaml:"username"`
 	Password      string       `yaml:"password"`
-	WalletAddress string       `yaml:"wallet_address"`
+	WalletAddress string       `yaml:"wallet_address"` // 以太坊地址，其他链使用 CAIP-10 账户标识
+	Wallets       []string     `yaml:"wallets"`        // 关联的其他钱包，格式同 wallet_address，任意一个都可以签名登录
 	Directory     string       `yaml:"directory"`
+	Role          string       `yaml:"role"`  // user, admin
+	Quota         string       `yaml:"quota"` // 用户配额，如 "5GB"；"unlimited" 表示不受默认配额限制
 	Permissions   string       `yaml:"permissions"`
 	Rules         []RuleConfig `yaml:"rules"`
+	Certificates  []string     `yaml:"certificates"` // 客户端证书 SHA-256 指纹
 }
 
 // RuleConfig 规则配置
 type RuleConfig struct {
-	Path        string `yaml:"path"`
-	Permissions string `yaml:"permissions"`
-	Regex       bool   `yaml:"regex"`
+	Path        string           `yaml:"path"`
+	Permissions string           `yaml:"permissions"`
+	Regex       bool             `yaml:"regex"`
+	Glob        bool             `yaml:"glob"`
+	Deny        bool             `yaml:"deny"`
+	Token       *TokenRuleConfig `yaml:"token"` // 只对钱包满足链上持有条件的用户生效
+}
+
+// TokenRuleConfig 规则的链上持有条件
+type TokenRuleConfig struct {
+	Standard   string `yaml:"standard"`    // erc20、erc721
+	Contract   string `yaml:"contract"`    // 合约地址
+	MinBalance string `yaml:"min_balance"` // 最小余额（最小单位的十进制整数），默认 1
+	TokenID    string `yaml:"token_id"`    // erc721 指定 token
 }
 
 // DefaultConfig 默认配置
@@ -95,20 +379,114 @@ func DefaultConfig() *Config {
 			WriteTimeout:    30 * time.Second,
 			IdleTimeout:     60 * time.Second,
 			ShutdownTimeout: 10 * time.Second,
+			ClientCerts: ClientCertConfig{
+				Mode:  "optional",
+				MapBy: []string{"fingerprint", "san", "cn"},
+			},
 		},
 		WebDAV: WebDAVConfig{
 			Prefix:      "/",
 			Directory:   "/data",
 			NoSniff:     true,
 			Permissions: "R",
+			Storage: WebDAVStorageConfig{
+				Driver: "local",
+				S3: S3Config{
+					Region:   "us-east-1",
+					UseSSL:   true,
+					PartSize: 16 * 1024 * 1024,
+				},
+			},
+			DeadProps: DeadPropsConfig{
+				Driver: "auto",
+				Path:   "./webdav-props.db",
+			},
 		},
 		Web3: Web3Config{
-			Enabled:         false,
-			TokenExpiration: 24 * time.Hour,
+			Enabled:                false,
+			TokenExpiration:        15 * time.Minute,
+			RefreshTokenExpiration: 30 * 24 * time.Hour,
+			SIWE: SIWEConfig{
+				ChainID:   1,
+				Statement: "Sign in to WebDAV. This request will not trigger a blockchain transaction or cost any gas fees.",
+				TTL:       5 * time.Minute,
+				ClockSkew: time.Minute,
+			},
+			Chains: []string{user.ChainEthereum},
+			RPC: RPCConfig{
+				Timeout: 10 * time.Second,
+			},
+			TokenGate: TokenGateConfig{
+				RPC: RPCConfig{
+					Timeout: 10 * time.Second,
+				},
+				CacheTTL: 5 * time.Minute,
+			},
+			Registration: RegistrationConfig{
+				Enabled:            false,
+				Directory:          "wallets/{address}",
+				DefaultPermissions: "CRUD",
+			},
+		},
+		OIDC: OIDCConfig{
+			Enabled:            false,
+			Scopes:             []string{"openid", "email", "profile"},
+			UsernameClaim:      "email",
+			GroupsClaim:        "groups",
+			Directory:          "{username}",
+			DefaultPermissions: "R",
+		},
+		LDAP: LDAPConfig{
+			Enabled:            false,
+			StartTLS:           true,
+			UserFilter:         "(&(objectClass=person)(uid={username}))",
+			UsernameAttribute:  "uid",
+			MemberOfAttribute:  "memberOf",
+			GroupFilter:        "(&(objectClass=groupOfNames)(member={dn}))",
+			GroupNameAttribute: "cn",
+			Timeout:            10 * time.Second,
+			PoolSize:           4,
+			Fallback:           true,
+			Directory:          "{username}",
+			DefaultPermissions: "R",
+		},
+		Digest: DigestConfig{
+			Enabled:    false,
+			Realm:      "WebDAV",
+			Algorithms: []string{"SHA-256", "MD5"},
+			NonceTTL:   5 * time.Minute,
 		},
 		Security: SecurityConfig{
 			NoPassword:  false,
 			BehindProxy: false,
+			TOTPIssuer:  "WebDAV",
+			PasswordHash: PasswordHashConfig{
+				Algorithm:  "argon2id",
+				BcryptCost: 10,
+				Argon2id: Argon2idConfig{
+					Memory:      19 * 1024,
+					Iterations:  2,
+					Parallelism: 1,
+				},
+				Scrypt: ScryptConfig{
+					N: 1 << 15,
+					R: 8,
+					P: 1,
+				},
+			},
+			BruteForce: BruteForceConfig{
+				Enabled:           true,
+				IPThreshold:       20,
+				UsernameThreshold: 5,
+				BaseDelay:         time.Second,
+				MaxDelay:          15 * time.Minute,
+				ResetAfter:        time.Hour,
+			},
+			RateLimit: RateLimitConfig{
+				Enabled:           false,
+				RequestsPerSecond: 20,
+				Burst:             100,
+			},
 		},
 		CORS: CORSConfig{
 			Enabled:     false,
@@ -120,6 +498,25 @@ func DefaultConfig() *Config {
 			Colors:  true,
 			Outputs: []string{"stderr"},
 		},
+		Storage: StorageConfig{
+			Users: UserStorageConfig{
+				Driver: "memory",
+				Path:   "./webdav.db",
+			},
+			Locks: LockStorageConfig{
+				Driver:        "memory",
+				Path:          "./webdav-locks.db",
+				SweepInterval: time.Minute,
+				Redis: RedisConfig{
+					Address: "127.0.0.1:6379",
+					Prefix:  "webdav:",
+				},
+			},
+			Tokens: TokenStorageConfig{
+				Driver: "memory",
+				Path:   "./webdav-tokens.db",
+			},
+		},
 		Users: []UserConfig{},
 	}
 }
@@ -0,0 +1,32 @@
+package config
+
+import (
+	"strings"
+
+	"github.com/yeying-community/webdav/internal/domain/user"
+)
+
+// ToRule 转换为领域规则
+func (c RuleConfig) ToRule() *user.Rule {
+	return &user.Rule{
+		Path:        c.Path,
+		Permissions: user.ParsePermissions(c.Permissions),
+		Regex:       c.Regex,
+		Glob:        c.Glob,
+		Deny:        c.Deny,
+		Token:       c.Token.TokenGate(),
+	}
+}
+
+// TokenGate 转换为领域模型，未配置时返回 nil
+func (c *TokenRuleConfig) TokenGate() *user.TokenGate {
+	if c == nil {
+		return nil
+	}
+	return &user.TokenGate{
+		Standard:   strings.ToLower(c.Standard),
+		Contract:   strings.ToLower(c.Contract),
+		MinBalance: c.MinBalance,
+		TokenID:    c.TokenID,
+	}
+}
@@ -3,9 +3,17 @@ package config
 import (
 	"errors"
 	"fmt"
+	"net/url"
 	"os"
+	"regexp"
+	"strings"
+
+	"github.com/yeying-community/webdav/internal/domain/user"
 )
 
+// chainReferencePattern CAIP-2 链标识格式
+var chainReferencePattern = regexp.MustCompile(`^[-_a-zA-Z0-9]{1,32}$`)
+
 // Validator 配置验证器
 type Validator struct{}
 
@@ -28,6 +36,26 @@ func (v *Validator) Validate(config *Config) error {
 		return fmt.Errorf("web3 config: %w", err)
 	}
 
+	if err := v.validateOIDC(config); err != nil {
+		return fmt.Errorf("oidc config: %w", err)
+	}
+
+	if err := v.validateLDAP(config); err != nil {
+		return fmt.Errorf("ldap config: %w", err)
+	}
+
+	if err := v.validateDigest(config); err != nil {
+		return fmt.Errorf("digest config: %w", err)
+	}
+
+	if err := v.validateSecurity(config); err != nil {
+		return fmt.Errorf("security config: %w", err)
+	}
+
+	if err := v.validateStorage(config); err != nil {
+		return fmt.Errorf("storage config: %w", err)
+	}
+
 	if err := v.validateUsers(config); err != nil {
 		return fmt.Errorf("users config: %w", err)
 	}
@@ -35,6 +63,62 @@ func (v *Validator) Validate(config *Config) error {
 	return nil
 }
 
+// validateSecurity 验证安全配置
+func (v *Validator) validateSecurity(config *Config) error {
+	bf := config.Security.BruteForce
+	if bf.Enabled {
+		if bf.IPThreshold < 1 || bf.UsernameThreshold < 1 {
+			return errors.New("brute_force: ip_threshold and username_threshold must be at least 1")
+		}
+		if bf.BaseDelay <= 0 || bf.MaxDelay < bf.BaseDelay {
+			return errors.New("brute_force: base_delay must be positive and not greater than max_delay")
+		}
+		if bf.ResetAfter <= 0 {
+			return errors.New("brute_force: reset_after must be positive")
+		}
+	}
+
+	rl := config.Security.RateLimit
+	if rl.Enabled {
+		if rl.RequestsPerSecond <= 0 {
+			return errors.New("rate_limit: requests_per_second must be positive")
+		}
+		if rl.Burst < 1 {
+			return errors.New("rate_limit: burst must be at least 1")
+		}
+	}
+
+	if err := v.validatePasswordHash(config.Security.PasswordHash); err != nil {
+		return fmt.Errorf("password_hash: %w", err)
+	}
+
+	return nil
+}
+
+// validatePasswordHash 验证密码哈希配置
+func (v *Validator) validatePasswordHash(ph PasswordHashConfig) error {
+	switch ph.Algorithm {
+	case "argon2id":
+		if ph.Argon2id.Memory < 8*uint32(ph.Argon2id.Parallelism) || ph.Argon2id.Iterations < 1 || ph.Argon2id.Parallelism < 1 {
+			return errors.New("argon2id: iterations and parallelism must be at least 1 and memory at least 8 KiB per lane")
+		}
+	case "scrypt":
+		if ph.Scrypt.N < 2 || ph.Scrypt.N&(ph.Scrypt.N-1) != 0 {
+			return errors.New("scrypt: n must be a power of two greater than 1")
+		}
+		if ph.Scrypt.R < 1 || ph.Scrypt.P < 1 {
+			return errors.New("scrypt: r and p must be at least 1")
+		}
+	case "bcrypt":
+		if ph.BcryptCost < 4 || ph.BcryptCost > 31 {
+			return errors.New("bcrypt_cost must be between 4 and 31")
+		}
+	default:
+		return fmt.Errorf("unsupported algorithm: %s", ph.Algorithm)
+	}
+	return nil
+}
+
 // validateServer 验证服务器配置
 func (v *Validator) validateServer(config *Config) error {
 	if config.Server.Port < 1 || config.Server.Port > 65535 {
@@ -58,11 +142,101 @@ func (v *Validator) validateServer(config *Config) error {
 		}
 	}
 
+	if err := v.validateClientCerts(config); err != nil {
+		return fmt.Errorf("client_certs: %w", err)
+	}
+
+	return nil
+}
+
+// validateClientCerts 验证客户端证书配置
+func (v *Validator) validateClientCerts(config *Config) error {
+	cc := config.Server.ClientCerts
+	if cc.CAFile == "" {
+		if len(cc.RequiredUsers) > 0 || len(cc.RequiredPaths) > 0 {
+			return errors.New("ca_file is required when required_users or required_paths is set")
+		}
+		return nil
+	}
+
+	if !config.Server.TLS {
+		return errors.New("client certificates require tls")
+	}
+	if _, err := os.Stat(cc.CAFile); err != nil {
+		return fmt.Errorf("ca file not found: %w", err)
+	}
+
+	switch cc.Mode {
+	case "", "optional", "require":
+	default:
+		return fmt.Errorf("invalid mode %q (expected optional or require)", cc.Mode)
+	}
+
+	if len(cc.MapBy) == 0 {
+		return errors.New("map_by must list at least one of fingerprint, san, cn")
+	}
+	for _, source := range cc.MapBy {
+		switch source {
+		case "fingerprint", "san", "cn":
+		default:
+			return fmt.Errorf("invalid map_by entry %q (expected fingerprint, san or cn)", source)
+		}
+	}
+
+	for i, p := range cc.RequiredPaths {
+		if !strings.HasPrefix(p, "/") {
+			return fmt.Errorf("required_paths[%d]: path must start with '/'", i)
+		}
+	}
+
 	return nil
 }
 
 // validateWebDAV 验证 WebDAV 配置
 func (v *Validator) validateWebDAV(config *Config) error {
+	if _, err := ParseByteSize(config.WebDAV.DefaultQuota); err != nil {
+		return fmt.Errorf("default_quota: %w", err)
+	}
+
+	if err := v.validateDeadProps(config); err != nil {
+		return err
+	}
+
+	switch config.WebDAV.Storage.Driver {
+	case "", "local":
+		return v.validateLocalStorage(config)
+	case "memory":
+		return nil
+	case "s3":
+		return v.validateS3Storage(config)
+	default:
+		return fmt.Errorf("unsupported storage driver: %s", config.WebDAV.Storage.Driver)
+	}
+}
+
+// validateDeadProps 验证死属性存储配置
+func (v *Validator) validateDeadProps(config *Config) error {
+	deadProps := config.WebDAV.DeadProps
+
+	switch deadProps.Driver {
+	case "", "auto", "none":
+	case "xattr":
+		if driver := config.WebDAV.Storage.Driver; driver != "" && driver != "local" {
+			return errors.New("dead_props.driver xattr requires the local storage driver")
+		}
+	case "bolt":
+		if deadProps.Path == "" {
+			return errors.New("dead_props.path is required for bolt driver")
+		}
+	default:
+		return fmt.Errorf("unsupported dead_props driver: %s", deadProps.Driver)
+	}
+
+	return nil
+}
+
+// validateLocalStorage 验证本地存储配置
+func (v *Validator) validateLocalStorage(config *Config) error {
 	if config.WebDAV.Directory == "" {
 		return errors.New("directory is required")
 	}
@@ -79,15 +253,362 @@ func (v *Validator) validateWebDAV(config *Config) error {
 	return nil
 }
 
+// validateS3Storage 验证 S3 存储配置
+func (v *Validator) validateS3Storage(config *Config) error {
+	s3 := config.WebDAV.Storage.S3
+
+	if s3.Endpoint == "" {
+		return errors.New("s3 endpoint is required")
+	}
+	if s3.Bucket == "" {
+		return errors.New("s3 bucket is required")
+	}
+	// S3 要求除最后一片外每片至少 5MiB
+	if s3.PartSize != 0 && s3.PartSize < 5*1024*1024 {
+		return errors.New("s3 part_size must be at least 5MiB")
+	}
+
+	return nil
+}
+
+// validateSigningKeys 验证 JWT 签名密钥配置
+func (v *Validator) validateSigningKeys(config Web3Config) error {
+	if len(config.SigningKeys) == 0 {
+		return nil
+	}
+
+	ids := make(map[string]bool)
+	hasSigner := false
+	for i, key := range config.SigningKeys {
+		if key.ID == "" {
+			return fmt.Errorf("signing_keys[%d]: kid is required", i)
+		}
+		if ids[key.ID] {
+			return fmt.Errorf("signing_keys[%d]: duplicate kid %q", i, key.ID)
+		}
+		ids[key.ID] = true
+
+		switch key.Algorithm {
+		case "RS256", "ES256", "EdDSA":
+		default:
+			return fmt.Errorf("signing_keys[%d]: unsupported algorithm %q (must be RS256, ES256 or EdDSA)", i, key.Algorithm)
+		}
+
+		if key.PrivateKeyFile == "" && key.PublicKeyFile == "" {
+			return fmt.Errorf("signing_keys[%d]: private_key_file or public_key_file is required", i)
+		}
+		if key.PrivateKeyFile != "" {
+			hasSigner = true
+		}
+	}
+
+	if !hasSigner {
+		return errors.New("signing_keys: at least one key needs a private_key_file")
+	}
+
+	return nil
+}
+
 // validateWeb3 验证 Web3 配置
 func (v *Validator) validateWeb3(config *Config) error {
 	if config.Web3.Enabled {
-		if config.Web3.JWTSecret == "" {
-			return errors.New("jwt_secret is required when web3 is enabled")
+		if config.Web3.JWTSecret == "" && len(config.Web3.SigningKeys) == 0 {
+			return errors.New("jwt_secret or signing_keys is required when web3 is enabled")
 		}
-		if len(config.Web3.JWTSecret) < 32 {
+		if config.Web3.JWTSecret != "" && len(config.Web3.JWTSecret) < 32 {
 			return errors.New("jwt_secret must be at least 32 characters")
 		}
+		if err := v.validateSigningKeys(config.Web3); err != nil {
+			return err
+		}
+
+		if config.Web3.TokenExpiration <= 0 {
+			return errors.New("token_expiration must be positive")
+		}
+		if config.Web3.RefreshTokenExpiration < config.Web3.TokenExpiration {
+			return errors.New("refresh_token_expiration must not be shorter than token_expiration")
+		}
+
+		siwe := config.Web3.SIWE
+		if siwe.ChainID <= 0 {
+			return errors.New("siwe.chain_id must be positive")
+		}
+		if strings.Contains(siwe.Statement, "\n") {
+			return errors.New("siwe.statement must be a single line")
+		}
+		if siwe.Domain == "" {
+			return errors.New("siwe.domain is required when web3 is enabled")
+		}
+		if strings.ContainsAny(siwe.Domain, " /") {
+			return fmt.Errorf("siwe.domain must be a host[:port]: %q", siwe.Domain)
+		}
+		if siwe.URI != "" {
+			if _, err := url.ParseRequestURI(siwe.URI); err != nil {
+				return fmt.Errorf("siwe.uri: %w", err)
+			}
+		}
+		for _, resource := range siwe.Resources {
+			if _, err := url.ParseRequestURI(resource); err != nil {
+				return fmt.Errorf("siwe.resources: %w", err)
+			}
+		}
+		if siwe.TTL <= 0 {
+			return errors.New("siwe.ttl must be positive")
+		}
+		if siwe.ClockSkew < 0 {
+			return errors.New("siwe.clock_skew must not be negative")
+		}
+
+		if err := v.validateChains(config.Web3); err != nil {
+			return err
+		}
+
+		if rpcURL := config.Web3.RPC.URL; rpcURL != "" {
+			if _, err := url.ParseRequestURI(rpcURL); err != nil {
+				return fmt.Errorf("rpc.url: %w", err)
+			}
+			if config.Web3.RPC.Timeout <= 0 {
+				return errors.New("rpc.timeout must be positive")
+			}
+		}
+
+		tokenGate := config.Web3.TokenGate
+		if rpcURL := tokenGate.RPC.URL; rpcURL != "" {
+			if _, err := url.ParseRequestURI(rpcURL); err != nil {
+				return fmt.Errorf("token_gate.rpc.url: %w", err)
+			}
+			if tokenGate.RPC.Timeout <= 0 {
+				return errors.New("token_gate.rpc.timeout must be positive")
+			}
+		}
+		if tokenGate.CacheTTL < 0 {
+			return errors.New("token_gate.cache_ttl must not be negative")
+		}
+
+		if err := v.validateRegistration(config.Web3.Registration); err != nil {
+			return fmt.Errorf("registration: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// validateChains 验证允许登录的链和链标识
+func (v *Validator) validateChains(config Web3Config) error {
+	if len(config.Chains) == 0 {
+		return errors.New("chains must not be empty")
+	}
+
+	seen := make(map[string]bool)
+	for i, chain := range config.Chains {
+		switch chain {
+		case user.ChainEthereum, user.ChainSolana, user.ChainBitcoin:
+		default:
+			return fmt.Errorf("chains[%d]: unsupported chain: %q", i, chain)
+		}
+		if seen[chain] {
+			return fmt.Errorf("chains[%d]: duplicate chain: %s", i, chain)
+		}
+		seen[chain] = true
+	}
+
+	for chain, reference := range config.ChainIDs {
+		switch chain {
+		case user.ChainEthereum:
+			return errors.New("chain_ids: use siwe.chain_id for ethereum")
+		case user.ChainSolana, user.ChainBitcoin:
+		default:
+			return fmt.Errorf("chain_ids: unsupported chain: %q", chain)
+		}
+		if !chainReferencePattern.MatchString(reference) {
+			return fmt.Errorf("chain_ids.%s: invalid CAIP-2 reference: %q", chain, reference)
+		}
+	}
+
+	return nil
+}
+
+// validateRegistration 验证钱包自助注册配置
+func (v *Validator) validateRegistration(reg RegistrationConfig) error {
+	if !reg.Enabled {
+		return nil
+	}
+
+	// 不含 {address} 时所有注册用户共享同一目录
+	if !strings.Contains(reg.Directory, "{address}") {
+		return errors.New("directory must contain {address}")
+	}
+	if strings.Trim(strings.ToUpper(reg.DefaultPermissions), "CRUD") != "" {
+		return fmt.Errorf("invalid default_permissions: %q", reg.DefaultPermissions)
+	}
+	for i, address := range reg.Allowlist {
+		if !user.IsValidWalletID(address) {
+			return fmt.Errorf("allowlist[%d]: invalid wallet address: %s", i, address)
+		}
+	}
+	for i, code := range reg.InviteCodes {
+		if strings.TrimSpace(code) == "" {
+			return fmt.Errorf("invite_codes[%d]: must not be empty", i)
+		}
+	}
+
+	return nil
+}
+
+// validateOIDC 验证 OpenID Connect 配置
+func (v *Validator) validateOIDC(config *Config) error {
+	oidc := config.OIDC
+	if !oidc.Enabled {
+		return nil
+	}
+
+	if oidc.Issuer == "" {
+		return errors.New("issuer is required when oidc is enabled")
+	}
+	if _, err := url.ParseRequestURI(oidc.Issuer); err != nil {
+		return fmt.Errorf("issuer: %w", err)
+	}
+	if oidc.ClientID == "" {
+		return errors.New("client_id is required when oidc is enabled")
+	}
+	if oidc.RedirectURL != "" {
+		if _, err := url.ParseRequestURI(oidc.RedirectURL); err != nil {
+			return fmt.Errorf("redirect_url: %w", err)
+		}
+	}
+	if oidc.UsernameClaim == "" {
+		return errors.New("username_claim is required")
+	}
+	if oidc.AutoProvision && oidc.Directory == "" {
+		return errors.New("directory is required when auto_provision is enabled")
+	}
+
+	return nil
+}
+
+// validateLDAP 验证 LDAP 配置
+func (v *Validator) validateLDAP(config *Config) error {
+	ldap := config.LDAP
+	if !ldap.Enabled {
+		return nil
+	}
+
+	u, err := url.Parse(ldap.URL)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("invalid url: %q", ldap.URL)
+	}
+	switch u.Scheme {
+	case "ldap":
+	case "ldaps":
+		if ldap.StartTLS {
+			return errors.New("start_tls cannot be used with ldaps://")
+		}
+	default:
+		return fmt.Errorf("unsupported url scheme: %s", u.Scheme)
+	}
+
+	if ldap.CAFile != "" {
+		if _, err := os.Stat(ldap.CAFile); err != nil {
+			return fmt.Errorf("ca_file: %w", err)
+		}
+	}
+	if ldap.BaseDN == "" {
+		return errors.New("base_dn is required")
+	}
+	if !strings.Contains(ldap.UserFilter, "{username}") {
+		return errors.New("user_filter must contain {username}")
+	}
+	if ldap.GroupBaseDN != "" && ldap.GroupFilter == "" {
+		return errors.New("group_filter is required with group_base_dn")
+	}
+	if ldap.Timeout <= 0 {
+		return errors.New("timeout must be positive")
+	}
+	if ldap.PoolSize < 0 {
+		return errors.New("pool_size must not be negative")
+	}
+	if ldap.Directory == "" {
+		return errors.New("directory is required")
+	}
+
+	for i, group := range ldap.Groups {
+		if group.Name == "" {
+			return fmt.Errorf("groups[%d]: name is required", i)
+		}
+		for j, ruleCfg := range group.Rules {
+			if err := v.validateRule(config, ruleCfg); err != nil {
+				return fmt.Errorf("groups[%d].rules[%d]: %w", i, j, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateDigest 验证 HTTP Digest 认证配置
+func (v *Validator) validateDigest(config *Config) error {
+	digest := config.Digest
+	if !digest.Enabled {
+		return nil
+	}
+
+	if digest.Realm == "" || strings.ContainsAny(digest.Realm, "\"\\") {
+		return errors.New("realm must be non-empty and must not contain quotes or backslashes")
+	}
+	if len(digest.Algorithms) == 0 {
+		return errors.New("at least one algorithm is required")
+	}
+	for _, algorithm := range digest.Algorithms {
+		if algorithm != user.DigestSHA256 && algorithm != user.DigestMD5 {
+			return fmt.Errorf("unsupported algorithm: %s", algorithm)
+		}
+	}
+	if digest.NonceTTL <= 0 {
+		return errors.New("nonce_ttl must be positive")
+	}
+
+	return nil
+}
+
+// validateStorage 验证持久化存储配置
+func (v *Validator) validateStorage(config *Config) error {
+	switch config.Storage.Users.Driver {
+	case "", "memory":
+	case "sqlite":
+		if config.Storage.Users.Path == "" {
+			return errors.New("users.path is required for sqlite driver")
+		}
+	default:
+		return fmt.Errorf("unsupported users driver: %s", config.Storage.Users.Driver)
+	}
+
+	locks := config.Storage.Locks
+	switch locks.Driver {
+	case "", "memory":
+	case "bolt":
+		if locks.Path == "" {
+			return errors.New("locks.path is required for bolt driver")
+		}
+	case "redis":
+		if locks.Redis.Address == "" {
+			return errors.New("locks.redis.address is required for redis driver")
+		}
+	default:
+		return fmt.Errorf("unsupported locks driver: %s", locks.Driver)
+	}
+
+	if locks.SweepInterval < 0 {
+		return errors.New("locks.sweep_interval must not be negative")
+	}
+
+	switch config.Storage.Tokens.Driver {
+	case "", "memory":
+	case "bolt":
+		if config.Storage.Tokens.Path == "" {
+			return errors.New("tokens.path is required for bolt driver")
+		}
+	default:
+		return fmt.Errorf("unsupported tokens driver: %s", config.Storage.Tokens.Driver)
 	}
 
 	return nil
@@ -95,44 +616,99 @@ func (v *Validator) validateWeb3(config *Config) error {
 
 // validateUsers 验证用户配置
 func (v *Validator) validateUsers(config *Config) error {
-	if len(config.Users) == 0 {
+	// 持久化仓储中可能已有用户，仅内存仓储要求配置用户
+	if len(config.Users) == 0 && v.usesMemoryUsers(config) {
 		return errors.New("at least one user is required")
 	}
 
 	usernames := make(map[string]bool)
 	addresses := make(map[string]bool)
 
-	for i, user := range config.Users {
+	for i, userCfg := range config.Users {
 		// 检查用户名
-		if user.Username == "" {
+		if userCfg.Username == "" {
 			return fmt.Errorf("user[%d]: username is required", i)
 		}
-		if usernames[user.Username] {
-			return fmt.Errorf("user[%d]: duplicate username: %s", i, user.Username)
+		if usernames[userCfg.Username] {
+			return fmt.Errorf("user[%d]: duplicate username: %s", i, userCfg.Username)
 		}
-		usernames[user.Username] = true
+		usernames[userCfg.Username] = true
 
 		// 检查认证方式
-		hasPassword := user.Password != ""
-		hasWallet := user.WalletAddress != ""
+		hasPassword := userCfg.Password != ""
+		hasWallet := userCfg.WalletAddress != "" || len(userCfg.Wallets) > 0
 
 		if !hasPassword && !hasWallet && !config.Security.NoPassword {
 			return fmt.Errorf("user[%d]: must have password or wallet_address", i)
 		}
 
 		// 检查钱包地址唯一性
-		if hasWallet {
-			if addresses[user.WalletAddress] {
-				return fmt.Errorf("user[%d]: duplicate wallet_address: %s", i, user.WalletAddress)
+		if userCfg.WalletAddress != "" {
+			id := user.NormalizeWalletID(userCfg.WalletAddress)
+			if addresses[id] {
+				return fmt.Errorf("user[%d]: duplicate wallet_address: %s", i, userCfg.WalletAddress)
+			}
+			addresses[id] = true
+		}
+		for j, address := range userCfg.Wallets {
+			if !user.IsValidWalletID(address) {
+				return fmt.Errorf("user[%d]: wallets[%d]: invalid wallet address: %s", i, j, address)
+			}
+			id := user.NormalizeWalletID(address)
+			if addresses[id] {
+				return fmt.Errorf("user[%d]: wallets[%d]: duplicate wallet address: %s", i, j, address)
+			}
+			addresses[id] = true
+		}
+
+		// 检查客户端证书指纹
+		for j, fp := range userCfg.Certificates {
+			if _, err := user.NormalizeFingerprint(fp); err != nil {
+				return fmt.Errorf("user[%d]: certificates[%d]: %w", i, j, err)
 			}
-			addresses[user.WalletAddress] = true
 		}
 
 		// 检查目录
-		if user.Directory == "" {
+		if userCfg.Directory == "" {
 			return fmt.Errorf("user[%d]: directory is required", i)
 		}
+
+		// 检查配额
+		if userCfg.Quota != "unlimited" {
+			if _, err := ParseByteSize(userCfg.Quota); err != nil {
+				return fmt.Errorf("user[%d]: quota: %w", i, err)
+			}
+		}
+
+		// 检查角色
+		if userCfg.Role != "" && !user.IsValidRole(userCfg.Role) {
+			return fmt.Errorf("user[%d]: invalid role: %s", i, userCfg.Role)
+		}
+
+		// 检查规则（无效的正则和 glob 模式在启动时拒绝）
+		for j, ruleCfg := range userCfg.Rules {
+			if err := v.validateRule(config, ruleCfg); err != nil {
+				return fmt.Errorf("user[%d].rules[%d]: %w", i, j, err)
+			}
+		}
 	}
 
 	return nil
 }
+
+// validateRule 验证规则，带有链上持有条件的规则需要可用的 RPC 节点
+func (v *Validator) validateRule(config *Config, ruleCfg RuleConfig) error {
+	if err := ruleCfg.ToRule().Validate(); err != nil {
+		return err
+	}
+	if ruleCfg.Token != nil && (!config.Web3.Enabled || config.Web3.TokenGateRPC().URL == "") {
+		return errors.New("token rules require web3 to be enabled with web3.rpc.url or web3.token_gate.rpc.url")
+	}
+	return nil
+}
+
+// usesMemoryUsers 是否使用内存用户仓储
+func (v *Validator) usesMemoryUsers(config *Config) bool {
+	driver := config.Storage.Users.Driver
+	return driver == "" || driver == "memory"
+}
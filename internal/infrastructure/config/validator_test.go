@@ -0,0 +1,42 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateSIWEDomain(t *testing.T) {
+	tests := []struct {
+		name    string
+		enabled bool
+		domain  string
+		wantErr string
+	}{
+		{name: "configured domain", enabled: true, domain: "dav.example.com"},
+		{name: "domain with port", enabled: true, domain: "127.0.0.1:6065"},
+		// 不能回退到客户端控制的请求 Host
+		{name: "missing domain", enabled: true, domain: "", wantErr: "siwe.domain is required"},
+		{name: "domain with path", enabled: true, domain: "dav.example.com/login", wantErr: "siwe.domain must be a host[:port]"},
+		{name: "web3 disabled", enabled: false, domain: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.Web3.Enabled = tt.enabled
+			cfg.Web3.JWTSecret = "test-secret-test-secret-test-secret"
+			cfg.Web3.SIWE.Domain = tt.domain
+
+			err := NewValidator().validateWeb3(cfg)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("validateWeb3: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,585 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/ecdsa"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/mr-tron/base58"
+	"github.com/yeying-community/webdav/internal/domain/user"
+	"golang.org/x/crypto/ripemd160"
+)
+
+// bitcoinAddressType 比特币地址类型
+type bitcoinAddressType int
+
+const (
+	bitcoinP2PKH  bitcoinAddressType = iota // 1... / m... / n...
+	bitcoinP2SH                             // 3... / 2...，只支持 P2SH-P2WPKH
+	bitcoinP2WPKH                           // bc1q...
+	bitcoinP2TR                             // bc1p...
+)
+
+const (
+	// bitcoinMessagePrefix BIP-137 消息前缀（含长度字节）
+	bitcoinMessagePrefix = "\x18Bitcoin Signed Message:\n"
+
+	// bip322Tag BIP-322 消息哈希的标签
+	bip322Tag = "BIP0322-signed-message"
+
+	// sigHashAll SIGHASH_ALL
+	sigHashAll = 0x01
+
+	// sigHashDefault BIP-341 默认签名类型，等同于 SIGHASH_ALL
+	sigHashDefault = 0x00
+)
+
+var (
+	// bech32HRPs 支持的网络：主网、测试网、regtest
+	bech32HRPs = map[string]bool{"bc": true, "tb": true, "bcrt": true}
+
+	// base58Versions base58check 地址的版本字节
+	base58Versions = map[byte]bitcoinAddressType{
+		0x00: bitcoinP2PKH, // 主网
+		0x05: bitcoinP2SH,
+		0x6f: bitcoinP2PKH, // 测试网
+		0xc4: bitcoinP2SH,
+	}
+)
+
+// bitcoinAddress 解析后的比特币地址
+type bitcoinAddress struct {
+	kind    bitcoinAddressType
+	program []byte // 公钥哈希、脚本哈希或 taproot 输出公钥
+}
+
+// scriptPubKey 地址对应的锁定脚本
+func (a *bitcoinAddress) scriptPubKey() []byte {
+	switch a.kind {
+	case bitcoinP2PKH:
+		return p2pkhScript(a.program)
+	case bitcoinP2SH:
+		return append(append([]byte{0xa9, 0x14}, a.program...), 0x87)
+	case bitcoinP2WPKH:
+		return append([]byte{0x00, 0x14}, a.program...)
+	default:
+		return append([]byte{0x51, 0x20}, a.program...)
+	}
+}
+
+// BitcoinSigner 比特币签名验证器
+//
+// 支持 BIP-137 消息签名（P2PKH、P2SH-P2WPKH、P2WPKH，兼容 Electrum 等不区分地址类型的签名头）
+// 和 BIP-322 simple 签名（P2WPKH、P2TR key path）。签名为 base64 编码。
+type BitcoinSigner struct{}
+
+// NewBitcoinSigner 创建比特币签名验证器
+func NewBitcoinSigner() *BitcoinSigner {
+	return &BitcoinSigner{}
+}
+
+// Chain 链名称
+func (s *BitcoinSigner) Chain() string {
+	return user.ChainBitcoin
+}
+
+// Namespace CAIP-2 命名空间
+func (s *BitcoinSigner) Namespace() string {
+	return user.NamespaceBIP122
+}
+
+// Blockchain 登录消息中的区块链名称
+func (s *BitcoinSigner) Blockchain() string {
+	return "Bitcoin"
+}
+
+// IsValidAddress 验证地址格式
+func (s *BitcoinSigner) IsValidAddress(address string) bool {
+	_, err := parseBitcoinAddress(address)
+	return err == nil
+}
+
+// NormalizeAddress bech32 地址转为小写，base58 地址原样返回
+func (s *BitcoinSigner) NormalizeAddress(address string) string {
+	if addr, err := parseBitcoinAddress(address); err == nil && addr.kind >= bitcoinP2WPKH {
+		return strings.ToLower(address)
+	}
+	return address
+}
+
+// VerifySignature 验证 BIP-137 或 BIP-322 simple 签名
+func (s *BitcoinSigner) VerifySignature(message, signature, address string) error {
+	addr, err := parseBitcoinAddress(address)
+	if err != nil {
+		return err
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+
+	if len(sig) == 65 && sig[0] >= 27 && sig[0] <= 42 {
+		return verifyBIP137(message, sig, addr, address)
+	}
+	return verifyBIP322(message, sig, addr, address)
+}
+
+// verifyBIP137 从签名恢复公钥，检查公钥对应的地址
+func verifyBIP137(message string, sig []byte, addr *bitcoinAddress, address string) error {
+	// 签名头 35-42 表示隔离见证地址，恢复时换算为压缩公钥的签名头 31-34
+	compact := append([]byte{}, sig...)
+	if compact[0] >= 35 {
+		compact[0] = 31 + (compact[0]-27)&3
+	}
+
+	pubKey, compressed, err := ecdsa.RecoverCompact(compact, bitcoinMessageHash(message))
+	if err != nil {
+		return fmt.Errorf("failed to recover public key: %w", err)
+	}
+
+	var serialized []byte
+	if compressed {
+		serialized = pubKey.SerializeCompressed()
+	} else {
+		serialized = pubKey.SerializeUncompressed()
+	}
+
+	var program []byte
+	switch addr.kind {
+	case bitcoinP2PKH:
+		program = hash160(serialized)
+	case bitcoinP2SH:
+		if compressed {
+			program = hash160(append([]byte{0x00, 0x14}, hash160(serialized)...))
+		}
+	case bitcoinP2WPKH:
+		if compressed {
+			program = hash160(serialized)
+		}
+	default:
+		return fmt.Errorf("%w: taproot addresses require a BIP-322 signature", ErrInvalidSignature)
+	}
+
+	if program == nil || !bytes.Equal(program, addr.program) {
+		return fmt.Errorf("%w: expected %s", ErrSignatureMismatch, address)
+	}
+
+	return nil
+}
+
+// verifyBIP322 验证 BIP-322 simple 签名（base64 编码的见证数据）
+func verifyBIP322(message string, sig []byte, addr *bitcoinAddress, address string) error {
+	witness, err := parseWitness(sig)
+	if err != nil {
+		return err
+	}
+
+	toSpend := bip322ToSpend(message, addr.scriptPubKey())
+
+	switch addr.kind {
+	case bitcoinP2WPKH:
+		if len(witness) != 2 || len(witness[0]) < 2 || witness[0][len(witness[0])-1] != sigHashAll {
+			return fmt.Errorf("%w: invalid p2wpkh witness", ErrInvalidSignature)
+		}
+		if !bytes.Equal(hash160(witness[1]), addr.program) {
+			return fmt.Errorf("%w: expected %s", ErrSignatureMismatch, address)
+		}
+
+		pubKey, err := btcec.ParsePubKey(witness[1])
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+		}
+		signature, err := ecdsa.ParseDERSignature(witness[0][:len(witness[0])-1])
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+		}
+		if !signature.Verify(bip143SigHash(toSpend, addr.program), pubKey) {
+			return fmt.Errorf("%w: expected %s", ErrSignatureMismatch, address)
+		}
+
+	case bitcoinP2TR:
+		if len(witness) != 1 {
+			return fmt.Errorf("%w: only taproot key path signatures are supported", ErrInvalidSignature)
+		}
+		raw := witness[0]
+		hashType := byte(sigHashDefault)
+		if len(raw) == 65 {
+			hashType = raw[64]
+			if hashType != sigHashAll {
+				return fmt.Errorf("%w: unsupported sighash type", ErrInvalidSignature)
+			}
+			raw = raw[:64]
+		}
+
+		pubKey, err := schnorr.ParsePubKey(addr.program)
+		if err != nil {
+			return fmt.Errorf("invalid taproot output key: %w", err)
+		}
+		signature, err := schnorr.ParseSignature(raw)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+		}
+		if !signature.Verify(bip341SigHash(toSpend, addr.scriptPubKey(), hashType), pubKey) {
+			return fmt.Errorf("%w: expected %s", ErrSignatureMismatch, address)
+		}
+
+	default:
+		return fmt.Errorf("%w: BIP-322 signatures are only supported for p2wpkh and p2tr addresses", ErrInvalidSignature)
+	}
+
+	return nil
+}
+
+// bitcoinMessageHash BIP-137 消息哈希
+func bitcoinMessageHash(message string) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(bitcoinMessagePrefix)
+	writeVarBytes(&buf, []byte(message))
+	return doubleSHA256(buf.Bytes())
+}
+
+// bip322ToSpend 构造 BIP-322 的 to_spend 交易，返回交易 ID
+func bip322ToSpend(message string, scriptPubKey []byte) []byte {
+	msgHash := taggedHash(bip322Tag, []byte(message))
+	scriptSig := append([]byte{0x00, 0x20}, msgHash...)
+
+	var tx bytes.Buffer
+	writeUint32(&tx, 0) // version
+	writeVarInt(&tx, 1)
+	tx.Write(make([]byte, 32))
+	writeUint32(&tx, 0xffffffff)
+	writeVarBytes(&tx, scriptSig)
+	writeUint32(&tx, 0) // sequence
+	writeVarInt(&tx, 1)
+	writeUint64(&tx, 0) // value
+	writeVarBytes(&tx, scriptPubKey)
+	writeUint32(&tx, 0) // locktime
+
+	return doubleSHA256(tx.Bytes())
+}
+
+// bip322Outpoint to_sign 交易唯一输入花费的输出
+func bip322Outpoint(toSpend []byte) []byte {
+	return append(append([]byte{}, toSpend...), 0, 0, 0, 0)
+}
+
+// bip322Outputs to_sign 交易唯一的 OP_RETURN 输出
+func bip322Outputs() []byte {
+	var buf bytes.Buffer
+	writeUint64(&buf, 0)
+	writeVarBytes(&buf, []byte{0x6a})
+	return buf.Bytes()
+}
+
+// bip143SigHash to_sign 交易的 BIP-143 签名哈希（P2WPKH，SIGHASH_ALL）
+func bip143SigHash(toSpend, pubKeyHash []byte) []byte {
+	outpoint := bip322Outpoint(toSpend)
+
+	var buf bytes.Buffer
+	writeUint32(&buf, 0) // version
+	buf.Write(doubleSHA256(outpoint))
+	buf.Write(doubleSHA256([]byte{0, 0, 0, 0})) // sequences
+	buf.Write(outpoint)
+	writeVarBytes(&buf, p2pkhScript(pubKeyHash))
+	writeUint64(&buf, 0) // amount
+	writeUint32(&buf, 0) // sequence
+	buf.Write(doubleSHA256(bip322Outputs()))
+	writeUint32(&buf, 0) // locktime
+	writeUint32(&buf, sigHashAll)
+
+	return doubleSHA256(buf.Bytes())
+}
+
+// bip341SigHash to_sign 交易的 BIP-341 签名哈希（key path，SIGHASH_DEFAULT 或 SIGHASH_ALL）
+func bip341SigHash(toSpend, scriptPubKey []byte, hashType byte) []byte {
+	var scripts bytes.Buffer
+	writeVarBytes(&scripts, scriptPubKey)
+
+	var buf bytes.Buffer
+	buf.WriteByte(0x00) // epoch
+	buf.WriteByte(hashType)
+	writeUint32(&buf, 0) // version
+	writeUint32(&buf, 0) // locktime
+	buf.Write(singleSHA256(bip322Outpoint(toSpend)))
+	buf.Write(singleSHA256(make([]byte, 8))) // amounts
+	buf.Write(singleSHA256(scripts.Bytes()))
+	buf.Write(singleSHA256([]byte{0, 0, 0, 0})) // sequences
+	buf.Write(singleSHA256(bip322Outputs()))
+	buf.WriteByte(0x00)  // spend type: key path，无 annex
+	writeUint32(&buf, 0) // input index
+
+	return taggedHash("TapSighash", buf.Bytes())
+}
+
+// parseWitness 解析序列化的见证栈
+func parseWitness(data []byte) ([][]byte, error) {
+	r := bytes.NewReader(data)
+	count, err := readVarInt(r)
+	if err != nil || count == 0 || count > uint64(len(data)) {
+		return nil, fmt.Errorf("%w: invalid witness", ErrInvalidSignature)
+	}
+
+	witness := make([][]byte, 0, count)
+	for i := uint64(0); i < count; i++ {
+		size, err := readVarInt(r)
+		if err != nil || size > uint64(r.Len()) {
+			return nil, fmt.Errorf("%w: invalid witness", ErrInvalidSignature)
+		}
+		item := make([]byte, size)
+		if _, err := r.Read(item); err != nil && size > 0 {
+			return nil, fmt.Errorf("%w: invalid witness", ErrInvalidSignature)
+		}
+		witness = append(witness, item)
+	}
+	if r.Len() != 0 {
+		return nil, fmt.Errorf("%w: trailing witness data", ErrInvalidSignature)
+	}
+
+	return witness, nil
+}
+
+// parseBitcoinAddress 解析 base58check 或 bech32/bech32m 地址
+func parseBitcoinAddress(address string) (*bitcoinAddress, error) {
+	if addr, err := parseSegwitAddress(address); err == nil {
+		return addr, nil
+	}
+
+	decoded, err := base58.Decode(address)
+	if err != nil || len(decoded) != 25 {
+		return nil, fmt.Errorf("invalid bitcoin address: %s", address)
+	}
+	if !bytes.Equal(doubleSHA256(decoded[:21])[:4], decoded[21:]) {
+		return nil, fmt.Errorf("invalid bitcoin address checksum: %s", address)
+	}
+	kind, ok := base58Versions[decoded[0]]
+	if !ok {
+		return nil, fmt.Errorf("unsupported bitcoin address version: %s", address)
+	}
+
+	return &bitcoinAddress{kind: kind, program: decoded[1:21]}, nil
+}
+
+// parseSegwitAddress 解析隔离见证地址（BIP-173 / BIP-350）
+func parseSegwitAddress(address string) (*bitcoinAddress, error) {
+	hrp, data, checksum, err := decodeBech32(address)
+	if err != nil {
+		return nil, err
+	}
+	if !bech32HRPs[hrp] || len(data) < 1 {
+		return nil, errors.New("not a bitcoin segwit address")
+	}
+
+	version := data[0]
+	program, err := convertBits(data[1:], 5, 8, false)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case version == 0 && checksum == bech32Const && len(program) == 20:
+		return &bitcoinAddress{kind: bitcoinP2WPKH, program: program}, nil
+	case version == 1 && checksum == bech32mConst && len(program) == 32:
+		return &bitcoinAddress{kind: bitcoinP2TR, program: program}, nil
+	default:
+		return nil, errors.New("unsupported segwit address")
+	}
+}
+
+const (
+	bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+	bech32Const   = 1          // BIP-173
+	bech32mConst  = 0x2bc830a3 // BIP-350
+)
+
+// decodeBech32 解码 bech32/bech32m 字符串，返回 hrp、去掉校验和的数据和校验常量
+func decodeBech32(s string) (string, []byte, uint32, error) {
+	if len(s) > 90 {
+		return "", nil, 0, errors.New("bech32 string too long")
+	}
+	lower := strings.ToLower(s)
+	if s != lower && s != strings.ToUpper(s) {
+		return "", nil, 0, errors.New("bech32 string has mixed case")
+	}
+	s = lower
+
+	pos := strings.LastIndexByte(s, '1')
+	if pos < 1 || pos+7 > len(s) {
+		return "", nil, 0, errors.New("invalid bech32 separator position")
+	}
+
+	hrp := s[:pos]
+	data := make([]byte, 0, len(s)-pos-1)
+	for i := pos + 1; i < len(s); i++ {
+		v := strings.IndexByte(bech32Charset, s[i])
+		if v < 0 {
+			return "", nil, 0, errors.New("invalid bech32 character")
+		}
+		data = append(data, byte(v))
+	}
+
+	values := make([]byte, 0, len(hrp)*2+1+len(data))
+	for i := 0; i < len(hrp); i++ {
+		values = append(values, hrp[i]>>5)
+	}
+	values = append(values, 0)
+	for i := 0; i < len(hrp); i++ {
+		values = append(values, hrp[i]&31)
+	}
+	values = append(values, data...)
+
+	return hrp, data[:len(data)-6], bech32Polymod(values), nil
+}
+
+// bech32Polymod bech32 校验和
+func bech32Polymod(values []byte) uint32 {
+	generator := [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+	chk := uint32(1)
+	for _, v := range values {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i := 0; i < 5; i++ {
+			if (top>>uint(i))&1 == 1 {
+				chk ^= generator[i]
+			}
+		}
+	}
+	return chk
+}
+
+// convertBits 按位重新分组
+func convertBits(data []byte, from, to uint, pad bool) ([]byte, error) {
+	acc, bits := uint32(0), uint(0)
+	maxValue := uint32(1)<<to - 1
+	out := make([]byte, 0, len(data)*int(from)/int(to)+1)
+
+	for _, v := range data {
+		if uint32(v)>>from != 0 {
+			return nil, errors.New("invalid data range")
+		}
+		acc = acc<<from | uint32(v)
+		bits += from
+		for bits >= to {
+			bits -= to
+			out = append(out, byte(acc>>bits&maxValue))
+		}
+	}
+
+	if pad {
+		if bits > 0 {
+			out = append(out, byte(acc<<(to-bits)&maxValue))
+		}
+	} else if bits >= from || acc<<(to-bits)&maxValue != 0 {
+		return nil, errors.New("invalid padding")
+	}
+
+	return out, nil
+}
+
+// p2pkhScript P2PKH 锁定脚本，也是 P2WPKH 的 scriptCode
+func p2pkhScript(pubKeyHash []byte) []byte {
+	return append(append([]byte{0x76, 0xa9, 0x14}, pubKeyHash...), 0x88, 0xac)
+}
+
+// hash160 RIPEMD160(SHA256(data))
+func hash160(data []byte) []byte {
+	sum := sha256.Sum256(data)
+	h := ripemd160.New()
+	h.Write(sum[:])
+	return h.Sum(nil)
+}
+
+// singleSHA256 SHA256(data)
+func singleSHA256(data []byte) []byte {
+	sum := sha256.Sum256(data)
+	return sum[:]
+}
+
+// doubleSHA256 SHA256(SHA256(data))
+func doubleSHA256(data []byte) []byte {
+	first := sha256.Sum256(data)
+	second := sha256.Sum256(first[:])
+	return second[:]
+}
+
+// taggedHash BIP-340 标签哈希
+func taggedHash(tag string, data []byte) []byte {
+	tagHash := sha256.Sum256([]byte(tag))
+	h := sha256.New()
+	h.Write(tagHash[:])
+	h.Write(tagHash[:])
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+// writeUint32 写入小端 uint32
+func writeUint32(buf *bytes.Buffer, v uint32) {
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], v)
+	buf.Write(b[:])
+}
+
+// writeUint64 写入小端 uint64
+func writeUint64(buf *bytes.Buffer, v uint64) {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], v)
+	buf.Write(b[:])
+}
+
+// writeVarInt 写入 CompactSize 整数
+func writeVarInt(buf *bytes.Buffer, v uint64) {
+	switch {
+	case v < 0xfd:
+		buf.WriteByte(byte(v))
+	case v <= 0xffff:
+		buf.WriteByte(0xfd)
+		var b [2]byte
+		binary.LittleEndian.PutUint16(b[:], uint16(v))
+		buf.Write(b[:])
+	case v <= 0xffffffff:
+		buf.WriteByte(0xfe)
+		writeUint32(buf, uint32(v))
+	default:
+		buf.WriteByte(0xff)
+		writeUint64(buf, v)
+	}
+}
+
+// writeVarBytes 写入带长度前缀的字节串
+func writeVarBytes(buf *bytes.Buffer, data []byte) {
+	writeVarInt(buf, uint64(len(data)))
+	buf.Write(data)
+}
+
+// readVarInt 读取 CompactSize 整数
+func readVarInt(r *bytes.Reader) (uint64, error) {
+	prefix, err := r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+
+	var size int
+	switch prefix {
+	case 0xfd:
+		size = 2
+	case 0xfe:
+		size = 4
+	case 0xff:
+		size = 8
+	default:
+		return uint64(prefix), nil
+	}
+
+	b := make([]byte, 8)
+	if n, _ := r.Read(b[:size]); n != size {
+		return 0, errors.New("unexpected end of data")
+	}
+	return binary.LittleEndian.Uint64(b), nil
+}
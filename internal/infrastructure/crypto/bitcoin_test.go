@@ -0,0 +1,198 @@
+package crypto
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/ecdsa"
+	"github.com/mr-tron/base58"
+)
+
+// BIP-322 测试向量：https://github.com/bitcoin/bips/blob/master/bip-0322.mediawiki#test-vectors
+const (
+	bip322WIF     = "L3VFeEujGtevx9w18HD1fhRbCH67Az2dpCymeRE1SoPK6XQtaN2k"
+	bip322Address = "bc1q9vza2e8x573nczrlzms0wvx3gsqjx7vavgkx0l"
+)
+
+// bip322Key 解码测试向量中的 WIF 私钥
+func bip322Key(t *testing.T) *btcec.PrivateKey {
+	t.Helper()
+
+	decoded, err := base58.Decode(bip322WIF)
+	if err != nil || len(decoded) != 38 || decoded[0] != 0x80 {
+		t.Fatalf("invalid WIF: %v", err)
+	}
+	key, _ := btcec.PrivKeyFromBytes(decoded[1:33])
+	return key
+}
+
+// base58CheckAddress 版本字节加 hash160 的 base58check 地址
+func base58CheckAddress(version byte, program []byte) string {
+	payload := append([]byte{version}, program...)
+	return base58.Encode(append(payload, doubleSHA256(payload)[:4]...))
+}
+
+// bip137Sign BIP-137 签名，header 为签名头的基数（27 未压缩、31 P2PKH、35 P2SH-P2WPKH、39 P2WPKH）
+func bip137Sign(key *btcec.PrivateKey, message string, header byte) string {
+	sig := ecdsa.SignCompact(key, bitcoinMessageHash(message), header != 27)
+	sig[0] = header + (sig[0]-27)&3
+	return base64.StdEncoding.EncodeToString(sig)
+}
+
+func TestBIP322MessageHash(t *testing.T) {
+	tests := []struct {
+		message     string
+		messageHash string
+		toSpend     string
+	}{
+		{
+			message:     "",
+			messageHash: "c90c269c4f8fcbe6880f72a721ddfbf1914268a794cbb21cfafee13770ae19f1",
+			toSpend:     "c5680aa69bb8d860bf82d4e9cd3504b55dde018de765a91bb566283c545a99a7",
+		},
+		{
+			message:     "Hello World",
+			messageHash: "f0eb03b1a75ac6d9847f55c624a99169b5dccba2a31f5b23bea77ba270de0a7a",
+			toSpend:     "b79d196740ad5217771c1098fc4a4b51e0535c32236c71f1ea4d61a2d603352b",
+		},
+	}
+
+	addr, err := parseBitcoinAddress(bip322Address)
+	if err != nil {
+		t.Fatalf("parseBitcoinAddress: %v", err)
+	}
+	for _, tt := range tests {
+		if got := hex.EncodeToString(taggedHash(bip322Tag, []byte(tt.message))); got != tt.messageHash {
+			t.Errorf("message hash of %q = %s, want %s", tt.message, got, tt.messageHash)
+		}
+
+		// 交易 ID 按惯例以反序显示
+		txid := bip322ToSpend(tt.message, addr.scriptPubKey())
+		reversed := make([]byte, len(txid))
+		for i := range txid {
+			reversed[len(txid)-1-i] = txid[i]
+		}
+		if got := hex.EncodeToString(reversed); got != tt.toSpend {
+			t.Errorf("to_spend txid of %q = %s, want %s", tt.message, got, tt.toSpend)
+		}
+	}
+}
+
+func TestBitcoinVerifySignature(t *testing.T) {
+	key := bip322Key(t)
+	compressed := hash160(key.PubKey().SerializeCompressed())
+	uncompressed := hash160(key.PubKey().SerializeUncompressed())
+	nestedScript := hash160(append([]byte{0x00, 0x14}, compressed...))
+
+	legacy := base58CheckAddress(0x00, compressed)
+	legacyUncompressed := base58CheckAddress(0x00, uncompressed)
+	nested := base58CheckAddress(0x05, nestedScript)
+	other := base58CheckAddress(0x00, hash160([]byte("someone else")))
+
+	const message = "Hello World"
+	badHeader, _ := base64.StdEncoding.DecodeString(bip137Sign(key, message, 31))
+	badHeader[0] = 43
+
+	tests := []struct {
+		name      string
+		message   string
+		signature string
+		address   string
+		wantErr   error
+		wantAny   bool // 只要求返回错误
+	}{
+		// BIP-322 simple 测试向量
+		{
+			name:      "bip322 empty message",
+			message:   "",
+			signature: "AkcwRAIgM2gBAQqvZX15ZiysmKmQpDrG83avLIT492QBzLnQIxYCIBaTpOaD20qRlEylyxFSeEA2ba9YOixpX8z46TSDtS40ASECx/EgAxlkQpQ9hYjgGu6EBCPMVPwVIVJqO4XCsMvViHI=",
+			address:   bip322Address,
+		},
+		{
+			name:      "bip322 hello world",
+			message:   "Hello World",
+			signature: "AkcwRAIgZRfIY3p7/DoVTty6YZbWS71bc5Vct9p9Fia83eRmw2QCICK/ENGfwLtptFluMGs2KsqoNSk89pO7F29zJLUx9a/sASECx/EgAxlkQpQ9hYjgGu6EBCPMVPwVIVJqO4XCsMvViHI=",
+			address:   bip322Address,
+		},
+		{
+			name:      "bip322 uppercase address",
+			message:   "Hello World",
+			signature: "AkcwRAIgZRfIY3p7/DoVTty6YZbWS71bc5Vct9p9Fia83eRmw2QCICK/ENGfwLtptFluMGs2KsqoNSk89pO7F29zJLUx9a/sASECx/EgAxlkQpQ9hYjgGu6EBCPMVPwVIVJqO4XCsMvViHI=",
+			address:   "BC1Q9VZA2E8X573NCZRLZMS0WVX3GSQJX7VAVGKX0L",
+		},
+		{
+			name:      "bip322 signature for another message",
+			message:   "Hello World",
+			signature: "AkcwRAIgM2gBAQqvZX15ZiysmKmQpDrG83avLIT492QBzLnQIxYCIBaTpOaD20qRlEylyxFSeEA2ba9YOixpX8z46TSDtS40ASECx/EgAxlkQpQ9hYjgGu6EBCPMVPwVIVJqO4XCsMvViHI=",
+			address:   bip322Address,
+			wantErr:   ErrSignatureMismatch,
+		},
+		{
+			name:      "bip322 tampered message",
+			message:   "Hello World!",
+			signature: "AkcwRAIgZRfIY3p7/DoVTty6YZbWS71bc5Vct9p9Fia83eRmw2QCICK/ENGfwLtptFluMGs2KsqoNSk89pO7F29zJLUx9a/sASECx/EgAxlkQpQ9hYjgGu6EBCPMVPwVIVJqO4XCsMvViHI=",
+			address:   bip322Address,
+			wantErr:   ErrSignatureMismatch,
+		},
+		{
+			name:      "bip322 witness for another address",
+			message:   "Hello World",
+			signature: "AkcwRAIgZRfIY3p7/DoVTty6YZbWS71bc5Vct9p9Fia83eRmw2QCICK/ENGfwLtptFluMGs2KsqoNSk89pO7F29zJLUx9a/sASECx/EgAxlkQpQ9hYjgGu6EBCPMVPwVIVJqO4XCsMvViHI=",
+			address:   "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4",
+			wantErr:   ErrSignatureMismatch,
+		},
+
+		// BIP-137 签名头：27-30 未压缩 P2PKH，31-34 P2PKH，35-38 P2SH-P2WPKH，39-42 P2WPKH
+		{name: "bip137 legacy uncompressed", message: message, signature: bip137Sign(key, message, 27), address: legacyUncompressed},
+		{name: "bip137 legacy", message: message, signature: bip137Sign(key, message, 31), address: legacy},
+		{name: "bip137 p2sh-p2wpkh", message: message, signature: bip137Sign(key, message, 35), address: nested},
+		{name: "bip137 p2wpkh", message: message, signature: bip137Sign(key, message, 39), address: bip322Address},
+		// Electrum 等钱包对隔离见证地址也使用 31-34 的签名头
+		{name: "bip137 electrum header for p2wpkh", message: message, signature: bip137Sign(key, message, 31), address: bip322Address},
+		{name: "bip137 electrum header for p2sh-p2wpkh", message: message, signature: bip137Sign(key, message, 31), address: nested},
+		{name: "bip137 compressed key for uncompressed address", message: message, signature: bip137Sign(key, message, 31), address: legacyUncompressed, wantErr: ErrSignatureMismatch},
+		{name: "bip137 uncompressed key for p2wpkh", message: message, signature: bip137Sign(key, message, 27), address: bip322Address, wantErr: ErrSignatureMismatch},
+		{name: "bip137 wrong address", message: message, signature: bip137Sign(key, message, 31), address: other, wantErr: ErrSignatureMismatch},
+		{name: "bip137 tampered message", message: message + "!", signature: bip137Sign(key, message, 31), address: legacy, wantErr: ErrSignatureMismatch},
+		{name: "bip137 bad header byte", message: message, signature: base64.StdEncoding.EncodeToString(badHeader), address: legacy, wantAny: true},
+
+		// 格式错误的地址和签名
+		{name: "malformed base58", message: message, signature: bip137Sign(key, message, 31), address: legacy[:len(legacy)-1] + "0", wantAny: true},
+		{name: "base58 checksum", message: message, signature: bip137Sign(key, message, 31), address: flipLastChar(legacy), wantAny: true},
+		{name: "bech32 checksum", message: message, signature: bip137Sign(key, message, 39), address: flipLastChar(bip322Address), wantAny: true},
+		{name: "bech32 mixed case", message: message, signature: bip137Sign(key, message, 39), address: "bc1Q9vza2e8x573nczrlzms0wvx3gsqjx7vavgkx0l", wantAny: true},
+		{name: "invalid base64 signature", message: message, signature: "not base64!", address: legacy, wantErr: ErrInvalidSignature},
+	}
+
+	s := NewBitcoinSigner()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.VerifySignature(tt.message, tt.signature, tt.address)
+			switch {
+			case tt.wantAny:
+				if err == nil {
+					t.Fatal("VerifySignature should fail")
+				}
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+			case err != nil:
+				t.Fatalf("VerifySignature: %v", err)
+			}
+		})
+	}
+}
+
+// flipLastChar 改变地址的最后一个字符，使校验和失效
+func flipLastChar(address string) string {
+	last := address[len(address)-1]
+	replacement := byte('q')
+	if last == 'q' {
+		replacement = 'p'
+	}
+	return address[:len(address)-1] + string(replacement)
+}
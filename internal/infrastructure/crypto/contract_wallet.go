@@ -0,0 +1,235 @@
+package crypto
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+var (
+	// ErrNotContractWallet 地址没有部署合约代码
+	ErrNotContractWallet = errors.New("address is not a contract wallet")
+
+	// eip1271Selector isValidSignature(bytes32,bytes) 选择器，同时也是验证成功时的返回值
+	eip1271Selector = []byte{0x16, 0x26, 0xba, 0x7e}
+
+	// erc6492MagicSuffix ERC-6492 未部署合约签名的后缀
+	erc6492MagicSuffix = common.FromHex("0x6492649264926492649264926492649264926492649264926492649264926492")
+)
+
+// RPCCaller JSON-RPC 调用接口
+//
+// *rpc.Client 实现了该接口；测试中可以使用 rpc.DialInProc 连接进程内的替身节点。
+type RPCCaller interface {
+	CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error
+}
+
+// ContractWalletVerifier 合约钱包签名验证器（EIP-1271 / ERC-6492）
+type ContractWalletVerifier struct {
+	client  RPCCaller
+	signer  *EthereumSigner
+	timeout time.Duration
+}
+
+// NewContractWalletVerifier 创建连接到 JSON-RPC 节点的合约钱包签名验证器
+func NewContractWalletVerifier(rpcURL string, timeout time.Duration) (*ContractWalletVerifier, error) {
+	client, err := rpc.DialOptions(context.Background(), rpcURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial rpc endpoint: %w", err)
+	}
+
+	return NewContractWalletVerifierWithClient(client, timeout), nil
+}
+
+// NewContractWalletVerifierWithClient 使用已有的 RPC 客户端创建合约钱包签名验证器
+func NewContractWalletVerifierWithClient(client RPCCaller, timeout time.Duration) *ContractWalletVerifier {
+	return &ContractWalletVerifier{
+		client:  client,
+		signer:  NewEthereumSigner(),
+		timeout: timeout,
+	}
+}
+
+// ChainID 查询节点的链 ID
+func (v *ContractWalletVerifier) ChainID(ctx context.Context) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, v.timeout)
+	defer cancel()
+
+	var chainID hexutil.Big
+	if err := v.client.CallContext(ctx, &chainID, "eth_chainId"); err != nil {
+		return 0, fmt.Errorf("eth_chainId failed: %w", err)
+	}
+	return chainID.ToInt().Int64(), nil
+}
+
+// VerifySignature 通过合约验证个人签名消息（EIP-191）的签名
+//
+// 已部署的合约调用 EIP-1271 isValidSignature；带 ERC-6492 后缀的签名在合约
+// 未部署时先模拟执行工厂部署，再在同一模拟中调用 isValidSignature。
+func (v *ContractWalletVerifier) VerifySignature(ctx context.Context, message, signatureHex, address string) error {
+	return v.VerifyHash(ctx, v.signer.HashMessage(message), signatureHex, address)
+}
+
+// VerifyHash 通过合约验证对摘要（如 EIP-712 类型化数据）的签名
+func (v *ContractWalletVerifier) VerifyHash(ctx context.Context, hash common.Hash, signatureHex, address string) error {
+	signature, err := hex.DecodeString(strings.TrimPrefix(signatureHex, "0x"))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, v.timeout)
+	defer cancel()
+
+	account := common.HexToAddress(address)
+
+	code, err := v.codeAt(ctx, account)
+	if err != nil {
+		return err
+	}
+
+	if bytes.HasSuffix(signature, erc6492MagicSuffix) {
+		factory, factoryCalldata, inner, err := decodeERC6492(signature[:len(signature)-len(erc6492MagicSuffix)])
+		if err != nil {
+			return err
+		}
+		if len(code) > 0 {
+			return v.isValidSignature(ctx, account, hash, inner)
+		}
+		return v.simulateDeployAndValidate(ctx, factory, factoryCalldata, account, hash, inner)
+	}
+
+	if len(code) == 0 {
+		return ErrNotContractWallet
+	}
+
+	return v.isValidSignature(ctx, account, hash, signature)
+}
+
+// Close 关闭 RPC 连接
+func (v *ContractWalletVerifier) Close() {
+	if client, ok := v.client.(*rpc.Client); ok {
+		client.Close()
+	}
+}
+
+// codeAt 查询地址的合约代码
+func (v *ContractWalletVerifier) codeAt(ctx context.Context, account common.Address) ([]byte, error) {
+	var code hexutil.Bytes
+	if err := v.client.CallContext(ctx, &code, "eth_getCode", account, "latest"); err != nil {
+		return nil, fmt.Errorf("eth_getCode failed: %w", err)
+	}
+	return code, nil
+}
+
+// isValidSignature 调用合约的 EIP-1271 isValidSignature
+func (v *ContractWalletVerifier) isValidSignature(ctx context.Context, account common.Address, hash common.Hash, signature []byte) error {
+	call := map[string]interface{}{
+		"to":   account,
+		"data": hexutil.Bytes(encodeIsValidSignature(hash, signature)),
+	}
+
+	var result hexutil.Bytes
+	if err := v.client.CallContext(ctx, &result, "eth_call", call, "latest"); err != nil {
+		return fmt.Errorf("%w: isValidSignature reverted: %v", ErrSignatureMismatch, err)
+	}
+
+	return checkMagicValue(result)
+}
+
+// simulateDeployAndValidate 使用 eth_simulateV1 模拟部署合约后验证签名
+func (v *ContractWalletVerifier) simulateDeployAndValidate(ctx context.Context, factory common.Address, factoryCalldata []byte, account common.Address, hash common.Hash, signature []byte) error {
+	request := map[string]interface{}{
+		"blockStateCalls": []map[string]interface{}{{
+			"calls": []map[string]interface{}{
+				{"to": factory, "data": hexutil.Bytes(factoryCalldata)},
+				{"to": account, "data": hexutil.Bytes(encodeIsValidSignature(hash, signature))},
+			},
+		}},
+	}
+
+	var blocks []struct {
+		Calls []struct {
+			ReturnData hexutil.Bytes  `json:"returnData"`
+			Status     hexutil.Uint64 `json:"status"`
+		} `json:"calls"`
+	}
+	if err := v.client.CallContext(ctx, &blocks, "eth_simulateV1", request, "latest"); err != nil {
+		return fmt.Errorf("eth_simulateV1 failed: %w", err)
+	}
+
+	if len(blocks) != 1 || len(blocks[0].Calls) != 2 {
+		return fmt.Errorf("%w: unexpected simulation result", ErrSignatureMismatch)
+	}
+
+	deploy, validate := blocks[0].Calls[0], blocks[0].Calls[1]
+	if deploy.Status != 1 {
+		return fmt.Errorf("%w: counterfactual deployment failed", ErrSignatureMismatch)
+	}
+	if validate.Status != 1 {
+		return fmt.Errorf("%w: isValidSignature reverted", ErrSignatureMismatch)
+	}
+
+	return checkMagicValue(validate.ReturnData)
+}
+
+// encodeIsValidSignature ABI 编码 isValidSignature(bytes32,bytes) 调用数据
+func encodeIsValidSignature(hash common.Hash, signature []byte) []byte {
+	data := make([]byte, 0, 4+32*4+len(signature)+31)
+	data = append(data, eip1271Selector...)
+	data = append(data, hash.Bytes()...)
+	data = append(data, common.LeftPadBytes([]byte{0x40}, 32)...)
+	data = append(data, common.LeftPadBytes(bigEndian(uint64(len(signature))), 32)...)
+	data = append(data, signature...)
+	if rem := len(signature) % 32; rem != 0 {
+		data = append(data, make([]byte, 32-rem)...)
+	}
+	return data
+}
+
+// checkMagicValue 检查 isValidSignature 返回值
+func checkMagicValue(result []byte) error {
+	if len(result) < 4 || !bytes.Equal(result[:4], eip1271Selector) {
+		return fmt.Errorf("%w: contract rejected signature", ErrSignatureMismatch)
+	}
+	return nil
+}
+
+// decodeERC6492 解码 ERC-6492 包装：abi.encode(address factory, bytes factoryCalldata, bytes signature)
+func decodeERC6492(data []byte) (common.Address, []byte, []byte, error) {
+	addressType, _ := abi.NewType("address", "", nil)
+	bytesType, _ := abi.NewType("bytes", "", nil)
+	arguments := abi.Arguments{{Type: addressType}, {Type: bytesType}, {Type: bytesType}}
+
+	values, err := arguments.Unpack(data)
+	if err != nil {
+		return common.Address{}, nil, nil, fmt.Errorf("%w: malformed erc-6492 signature: %v", ErrInvalidSignature, err)
+	}
+
+	factory, ok1 := values[0].(common.Address)
+	calldata, ok2 := values[1].([]byte)
+	signature, ok3 := values[2].([]byte)
+	if !ok1 || !ok2 || !ok3 {
+		return common.Address{}, nil, nil, fmt.Errorf("%w: malformed erc-6492 signature", ErrInvalidSignature)
+	}
+
+	return factory, calldata, signature, nil
+}
+
+// bigEndian 将整数编码为大端字节
+func bigEndian(n uint64) []byte {
+	b := make([]byte, 8)
+	for i := 7; i >= 0; i-- {
+		b[i] = byte(n)
+		n >>= 8
+	}
+	return b
+}
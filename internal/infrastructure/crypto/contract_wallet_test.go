@@ -0,0 +1,384 @@
+package crypto
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// errReverted 合约执行回滚
+var errReverted = errors.New("execution reverted")
+
+// fakeContract 合约替身，返回 errReverted 表示调用回滚
+type fakeContract func(data []byte) ([]byte, error)
+
+// fakeNode 进程内的 JSON-RPC 节点替身，支持 eth_chainId、eth_getCode、eth_call 和 eth_simulateV1
+type fakeNode struct {
+	mu        sync.Mutex
+	contracts map[common.Address]fakeContract
+	// factories 工厂合约，调用后部署返回的地址
+	factories map[common.Address]func(calldata []byte) common.Address
+	// simulated 尚未部署的合约，只有在 eth_simulateV1 中被工厂部署后才可调用
+	simulated map[common.Address]fakeContract
+	calls     map[string]int
+}
+
+// newFakeNode 启动节点替身，返回节点和 RPC 地址
+func newFakeNode(t *testing.T) (*fakeNode, string) {
+	t.Helper()
+
+	n := &fakeNode{
+		contracts: make(map[common.Address]fakeContract),
+		factories: make(map[common.Address]func([]byte) common.Address),
+		simulated: make(map[common.Address]fakeContract),
+		calls:     make(map[string]int),
+	}
+	server := httptest.NewServer(n)
+	t.Cleanup(server.Close)
+	return n, server.URL
+}
+
+// deploy 在链上部署合约
+func (n *fakeNode) deploy(address common.Address, contract fakeContract) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.contracts[address] = contract
+}
+
+// callCount 方法被调用的次数
+func (n *fakeNode) callCount(method string) int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.calls[method]
+}
+
+type rpcCall struct {
+	To    common.Address `json:"to"`
+	Data  hexutil.Bytes  `json:"data"`
+	Input hexutil.Bytes  `json:"input"`
+}
+
+func (c *rpcCall) payload() []byte {
+	if len(c.Input) > 0 {
+		return c.Input
+	}
+	return c.Data
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (n *fakeNode) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ID     json.RawMessage   `json:"id"`
+		Method string            `json:"method"`
+		Params []json.RawMessage `json:"params"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	n.mu.Lock()
+	n.calls[req.Method]++
+	result, rpcErr := n.handle(req.Method, req.Params)
+	n.mu.Unlock()
+
+	resp := map[string]interface{}{"jsonrpc": "2.0", "id": req.ID}
+	if rpcErr != nil {
+		resp["error"] = rpcErr
+	} else {
+		resp["result"] = result
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (n *fakeNode) handle(method string, params []json.RawMessage) (interface{}, *rpcError) {
+	switch method {
+	case "eth_chainId":
+		return "0x1", nil
+
+	case "eth_getCode":
+		var address common.Address
+		if err := json.Unmarshal(params[0], &address); err != nil {
+			return nil, &rpcError{Code: -32602, Message: err.Error()}
+		}
+		if _, ok := n.contracts[address]; ok {
+			return "0x6000", nil
+		}
+		return "0x", nil
+
+	case "eth_call":
+		var call rpcCall
+		if err := json.Unmarshal(params[0], &call); err != nil {
+			return nil, &rpcError{Code: -32602, Message: err.Error()}
+		}
+		contract, ok := n.contracts[call.To]
+		if !ok {
+			// 调用没有代码的地址成功并返回空数据
+			return "0x", nil
+		}
+		out, err := contract(call.payload())
+		if err != nil {
+			return nil, &rpcError{Code: 3, Message: err.Error()}
+		}
+		return hexutil.Bytes(out), nil
+
+	case "eth_simulateV1":
+		var request struct {
+			BlockStateCalls []struct {
+				Calls []rpcCall `json:"calls"`
+			} `json:"blockStateCalls"`
+		}
+		if err := json.Unmarshal(params[0], &request); err != nil {
+			return nil, &rpcError{Code: -32602, Message: err.Error()}
+		}
+
+		// 模拟中部署的合约不写入链上状态
+		deployed := make(map[common.Address]bool)
+		type callResult struct {
+			ReturnData hexutil.Bytes  `json:"returnData"`
+			Status     hexutil.Uint64 `json:"status"`
+		}
+		var blocks []map[string][]callResult
+		for _, block := range request.BlockStateCalls {
+			var results []callResult
+			for _, call := range block.Calls {
+				if factory, ok := n.factories[call.To]; ok {
+					deployed[factory(call.payload())] = true
+					results = append(results, callResult{ReturnData: hexutil.Bytes{}, Status: 1})
+					continue
+				}
+				contract, ok := n.contracts[call.To]
+				if !ok && deployed[call.To] {
+					contract, ok = n.simulated[call.To]
+				}
+				if !ok {
+					results = append(results, callResult{ReturnData: hexutil.Bytes{}, Status: 1})
+					continue
+				}
+				out, err := contract(call.payload())
+				if err != nil {
+					results = append(results, callResult{ReturnData: hexutil.Bytes{}, Status: 0})
+					continue
+				}
+				results = append(results, callResult{ReturnData: out, Status: 1})
+			}
+			blocks = append(blocks, map[string][]callResult{"calls": results})
+		}
+		return blocks, nil
+	}
+
+	return nil, &rpcError{Code: -32601, Message: "method not found"}
+}
+
+// smartWallet 由 owner 签名授权的合约钱包（EIP-1271）
+func smartWallet(owner common.Address) fakeContract {
+	return func(data []byte) ([]byte, error) {
+		if len(data) < 100 || !bytes.Equal(data[:4], eip1271Selector) {
+			return nil, errReverted
+		}
+		hash := data[4:36]
+		length := new(big.Int).SetBytes(data[68:100]).Int64()
+		if int64(len(data)) < 100+length {
+			return nil, errReverted
+		}
+		signature := append([]byte{}, data[100:100+length]...)
+
+		rejected := common.RightPadBytes([]byte{0xff, 0xff, 0xff, 0xff}, 32)
+		if len(signature) != 65 {
+			return rejected, nil
+		}
+		if signature[64] >= 27 {
+			signature[64] -= 27
+		}
+		pub, err := crypto.SigToPub(hash, signature)
+		if err != nil || crypto.PubkeyToAddress(*pub) != owner {
+			return rejected, nil
+		}
+		return common.RightPadBytes(eip1271Selector, 32), nil
+	}
+}
+
+func testKey(t *testing.T, seed string) *ecdsa.PrivateKey {
+	t.Helper()
+
+	key, err := crypto.ToECDSA(crypto.Keccak256([]byte(seed)))
+	if err != nil {
+		t.Fatalf("ToECDSA: %v", err)
+	}
+	return key
+}
+
+// personalSign 对 EIP-191 个人消息签名
+func personalSign(t *testing.T, key *ecdsa.PrivateKey, message string) []byte {
+	t.Helper()
+
+	signature, err := crypto.Sign(NewEthereumSigner().HashMessage(message).Bytes(), key)
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	signature[64] += 27
+	return signature
+}
+
+// wrapERC6492 将签名包装为 ERC-6492 格式
+func wrapERC6492(t *testing.T, factory common.Address, calldata, signature []byte) []byte {
+	t.Helper()
+
+	addressType, _ := abi.NewType("address", "", nil)
+	bytesType, _ := abi.NewType("bytes", "", nil)
+	packed, err := abi.Arguments{{Type: addressType}, {Type: bytesType}, {Type: bytesType}}.Pack(factory, calldata, signature)
+	if err != nil {
+		t.Fatalf("Pack: %v", err)
+	}
+	return append(packed, erc6492MagicSuffix...)
+}
+
+func TestContractWalletVerifySignature(t *testing.T) {
+	const message = "dav.example.com wants you to sign in with your Ethereum account"
+
+	owner := testKey(t, "owner")
+	stranger := testKey(t, "stranger")
+	ownerAddress := crypto.PubkeyToAddress(owner.PublicKey)
+
+	deployed := common.HexToAddress("0x00000000000000000000000000000000000000a1")
+	counterfactual := common.HexToAddress("0x00000000000000000000000000000000000000a2")
+	reverting := common.HexToAddress("0x00000000000000000000000000000000000000a3")
+	factory := common.HexToAddress("0x00000000000000000000000000000000000000f1")
+	eoa := crypto.PubkeyToAddress(stranger.PublicKey)
+	deployCalldata := []byte("createAccount(owner)")
+
+	tests := []struct {
+		name      string
+		address   common.Address
+		signature []byte
+		wantErr   error
+	}{
+		{
+			name:      "deployed wallet accepts owner signature",
+			address:   deployed,
+			signature: personalSign(t, owner, message),
+		},
+		{
+			name:      "deployed wallet rejects other signer",
+			address:   deployed,
+			signature: personalSign(t, stranger, message),
+			wantErr:   ErrSignatureMismatch,
+		},
+		{
+			name:      "reverting wallet",
+			address:   reverting,
+			signature: personalSign(t, owner, message),
+			wantErr:   ErrSignatureMismatch,
+		},
+		{
+			name:      "address without code",
+			address:   eoa,
+			signature: personalSign(t, stranger, message),
+			wantErr:   ErrNotContractWallet,
+		},
+		{
+			name:      "counterfactual wallet accepts erc-6492 wrapper",
+			address:   counterfactual,
+			signature: wrapERC6492(t, factory, deployCalldata, personalSign(t, owner, message)),
+		},
+		{
+			name:      "counterfactual wallet rejects other signer",
+			address:   counterfactual,
+			signature: wrapERC6492(t, factory, deployCalldata, personalSign(t, stranger, message)),
+			wantErr:   ErrSignatureMismatch,
+		},
+		{
+			name:      "factory deploys a different account",
+			address:   counterfactual,
+			signature: wrapERC6492(t, factory, []byte("createAccount(other)"), personalSign(t, owner, message)),
+			wantErr:   ErrSignatureMismatch,
+		},
+		{
+			name:      "erc-6492 wrapper on deployed wallet",
+			address:   deployed,
+			signature: wrapERC6492(t, factory, deployCalldata, personalSign(t, owner, message)),
+		},
+		{
+			name:      "malformed erc-6492 wrapper",
+			address:   counterfactual,
+			signature: append([]byte{0x01, 0x02}, erc6492MagicSuffix...),
+			wantErr:   ErrInvalidSignature,
+		},
+	}
+
+	node, url := newFakeNode(t)
+	node.deploy(deployed, smartWallet(ownerAddress))
+	node.deploy(reverting, func([]byte) ([]byte, error) { return nil, errReverted })
+	node.factories[factory] = func(calldata []byte) common.Address {
+		if bytes.Equal(calldata, deployCalldata) {
+			return counterfactual
+		}
+		return common.HexToAddress("0x00000000000000000000000000000000000000b0")
+	}
+	node.simulated[counterfactual] = smartWallet(ownerAddress)
+
+	v, err := NewContractWalletVerifier(url, 5*time.Second)
+	if err != nil {
+		t.Fatalf("NewContractWalletVerifier: %v", err)
+	}
+	defer v.Close()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := v.VerifySignature(context.Background(), message, "0x"+hex.EncodeToString(tt.signature), tt.address.Hex())
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Fatalf("VerifySignature: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("VerifySignature = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// 模拟部署不改变链上状态
+	if code, err := v.codeAt(context.Background(), counterfactual); err != nil || len(code) != 0 {
+		t.Fatalf("counterfactual wallet should stay undeployed, code=%x err=%v", code, err)
+	}
+	if node.callCount("eth_simulateV1") == 0 {
+		t.Fatal("erc-6492 signatures should be verified via eth_simulateV1")
+	}
+}
+
+func TestContractWalletChainID(t *testing.T) {
+	_, url := newFakeNode(t)
+	v, err := NewContractWalletVerifier(url, 5*time.Second)
+	if err != nil {
+		t.Fatalf("NewContractWalletVerifier: %v", err)
+	}
+	defer v.Close()
+
+	chainID, err := v.ChainID(context.Background())
+	if err != nil {
+		t.Fatalf("ChainID: %v", err)
+	}
+	if chainID != 1 {
+		t.Fatalf("ChainID = %d, want 1", chainID)
+	}
+}
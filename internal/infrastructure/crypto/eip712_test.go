@@ -0,0 +1,81 @@
+package crypto
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+func TestSignInTypedDataHash(t *testing.T) {
+	// 期望值由 go-ethereum apitypes.TypedDataAndHash 计算
+	tests := []struct {
+		name string
+		data *SignInTypedData
+		want string
+	}{
+		{
+			name: "single resource",
+			data: &SignInTypedData{
+				Domain:         EIP712Domain{Name: "dav.example.com", Version: "1", ChainID: 1},
+				Wallet:         "0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826",
+				URI:            "https://dav.example.com",
+				Statement:      "Sign in to WebDAV.",
+				Nonce:          "32891756",
+				IssuedAt:       "2026-10-17T12:00:00Z",
+				ExpirationTime: "2026-10-17T12:05:00Z",
+				Scope:          []string{"https://dav.example.com"},
+			},
+			want: "0xe515ba7f0190ee9c407394db883e0dbe75784e3241afac55f486a505e3f24dae",
+		},
+		{
+			name: "several resources on another chain",
+			data: &SignInTypedData{
+				Domain:         EIP712Domain{Name: "127.0.0.1:6065", Version: "1", ChainID: 11155111},
+				Wallet:         "0x1111111111111111111111111111111111111111",
+				URI:            "http://127.0.0.1:6065",
+				Statement:      "",
+				Nonce:          "abc",
+				IssuedAt:       "2026-01-01T00:00:00Z",
+				ExpirationTime: "2026-01-01T00:05:00Z",
+				Scope:          []string{"ipfs://bafy", "https://dav.example.com/photos"},
+			},
+			want: "0x6dded141d8d886985e6d028dab8e85fe8e875408c96eb3a00156fb9f119e0eed",
+		},
+		{
+			name: "empty scope",
+			data: &SignInTypedData{
+				Domain: EIP712Domain{Name: "dav.example.com", Version: "1", ChainID: 1},
+				Wallet: "0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826",
+			},
+			want: "0xbc51b41402cee98f3e6c84c9beb0e745a24e9cd5695c5718c0b95a862f5faf15",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.data.Hash()
+			if got.Hex() != tt.want {
+				t.Fatalf("Hash = %s, want %s", got.Hex(), tt.want)
+			}
+
+			// 钱包收到的 eth_signTypedData_v4 参数由 go-ethereum 计算出同一摘要
+			encoded, err := json.Marshal(tt.data)
+			if err != nil {
+				t.Fatalf("Marshal: %v", err)
+			}
+			var typed apitypes.TypedData
+			if err := json.Unmarshal(encoded, &typed); err != nil {
+				t.Fatalf("Unmarshal: %v", err)
+			}
+			want, _, err := apitypes.TypedDataAndHash(typed)
+			if err != nil {
+				t.Fatalf("TypedDataAndHash: %v", err)
+			}
+			if got != common.BytesToHash(want) {
+				t.Fatalf("Hash = %s, go-ethereum = %x", got.Hex(), want)
+			}
+		})
+	}
+}
@@ -8,6 +8,7 @@ import (
 	
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/yeying-community/webdav/internal/domain/user"
 )
 
 var (
@@ -24,8 +25,29 @@ func NewEthereumSigner() *EthereumSigner {
 	return &EthereumSigner{}
 }
 
+// Chain 链名称
+func (s *EthereumSigner) Chain() string {
+	return user.ChainEthereum
+}
+
+// Namespace CAIP-2 命名空间
+func (s *EthereumSigner) Namespace() string {
+	return user.NamespaceEIP155
+}
+
+// Blockchain 登录消息中的区块链名称
+func (s *EthereumSigner) Blockchain() string {
+	return "Ethereum"
+}
+
 // VerifySignature 验证以太坊签名
 func (s *EthereumSigner) VerifySignature(message, signatureHex, expectedAddress string) error {
+	// 构建以太坊签名消息
+	return s.VerifyHash(s.HashMessage(message), signatureHex, expectedAddress)
+}
+
+// VerifyHash 验证对摘要（个人签名消息或 EIP-712 类型化数据）的 ECDSA 签名
+func (s *EthereumSigner) VerifyHash(hash common.Hash, signatureHex, expectedAddress string) error {
 	// 移除 0x 前缀
 	signatureHex = strings.TrimPrefix(signatureHex, "0x")
 	
@@ -44,9 +66,6 @@ func (s *EthereumSigner) VerifySignature(message, signatureHex, expectedAddress
 		signature[64] -= 27
 	}
 	
-	// 构建以太坊签名消息
-	hash := s.hashMessage(message)
-	
 	// 恢复公钥
 	pubKey, err := crypto.SigToPub(hash.Bytes(), signature)
 	if err != nil {
@@ -65,8 +84,8 @@ func (s *EthereumSigner) VerifySignature(message, signatureHex, expectedAddress
 	return nil
 }
 
-// hashMessage 哈希消息（以太坊签名消息格式）
-func (s *EthereumSigner) hashMessage(message string) common.Hash {
+// HashMessage 哈希消息（以太坊签名消息格式）
+func (s *EthereumSigner) HashMessage(message string) common.Hash {
 	prefix := fmt.Sprintf("\x19Ethereum Signed Message:\n%d", len(message))
 	return crypto.Keccak256Hash([]byte(prefix + message))
 }
@@ -76,3 +95,13 @@ func (s *EthereumSigner) IsValidAddress(address string) bool {
 	return common.IsHexAddress(address)
 }
 
+
+// NormalizeAddress 规范化为 EIP-55 校验和格式，SIWE 消息要求使用该格式
+func (s *EthereumSigner) NormalizeAddress(address string) string {
+	return s.ChecksumAddress(address)
+}
+
+// ChecksumAddress 返回 EIP-55 校验和格式的地址
+func (s *EthereumSigner) ChecksumAddress(address string) string {
+	return common.HexToAddress(address).Hex()
+}
@@ -1,13 +1,20 @@
 package crypto
 
 import (
+	"crypto/md5"
 	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
 	"encoding/base64"
 	"errors"
 	"fmt"
+	"math/bits"
 	"strings"
-	
+
+	"github.com/yeying-community/webdav/internal/infrastructure/config"
+	"golang.org/x/crypto/argon2"
 	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/scrypt"
 )
 
 var (
@@ -15,46 +22,163 @@ var (
 	ErrPasswordMismatch      = errors.New("password mismatch")
 )
 
+// 密码哈希方案
+const (
+	SchemeArgon2id = "argon2id"
+	SchemeScrypt   = "scrypt"
+	SchemeBcrypt   = "bcrypt"
+	SchemeSSHA     = "SSHA"
+	SchemeSHA      = "SHA"
+	SchemeAPR1     = "apr1"
+)
+
+const (
+	passwordSaltSize = 16
+	passwordKeySize  = 32
+)
+
 // PasswordHasher 密码哈希器
+//
+// 新密码按配置的算法哈希为 {scheme} 前缀的格式。验证时还接受从其他服务器迁移的
+// htpasswd / LDAP 格式：不带前缀的 bcrypt、argon2id、scrypt，{SSHA}、{SHA} 和 Apache $apr1$。
 type PasswordHasher struct {
-	cost int
+	cfg config.PasswordHashConfig
 }
 
-// NewPasswordHasher 创建密码哈希器
+// NewPasswordHasher 使用默认参数创建密码哈希器
 func NewPasswordHasher() *PasswordHasher {
-	return &PasswordHasher{
-		cost: bcrypt.DefaultCost,
-	}
+	return NewPasswordHasherWithConfig(config.DefaultConfig().Security.PasswordHash)
+}
+
+// NewPasswordHasherWithConfig 使用指定参数创建密码哈希器
+func NewPasswordHasherWithConfig(cfg config.PasswordHashConfig) *PasswordHasher {
+	return &PasswordHasher{cfg: cfg}
 }
 
 // Hash 哈希密码
 func (h *PasswordHasher) Hash(password string) (string, error) {
-	hash, err := bcrypt.GenerateFromPassword([]byte(password), h.cost)
-	if err != nil {
-		return "", fmt.Errorf("failed to hash password: %w", err)
+	switch h.cfg.Algorithm {
+	case SchemeBcrypt:
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), h.cfg.BcryptCost)
+		if err != nil {
+			return "", fmt.Errorf("failed to hash password: %w", err)
+		}
+		return "{bcrypt}" + string(hash), nil
+
+	case SchemeScrypt:
+		salt, err := randomSalt()
+		if err != nil {
+			return "", err
+		}
+		p := h.cfg.Scrypt
+		key, err := scrypt.Key([]byte(password), salt, p.N, p.R, p.P, passwordKeySize)
+		if err != nil {
+			return "", fmt.Errorf("failed to hash password: %w", err)
+		}
+		return fmt.Sprintf("{scrypt}$scrypt$ln=%d,r=%d,p=%d$%s$%s",
+			bits.TrailingZeros(uint(p.N)), p.R, p.P, encodeB64(salt), encodeB64(key)), nil
+
+	default:
+		salt, err := randomSalt()
+		if err != nil {
+			return "", err
+		}
+		p := h.cfg.Argon2id
+		key := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, passwordKeySize)
+		return fmt.Sprintf("{argon2id}$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+			argon2.Version, p.Memory, p.Iterations, p.Parallelism, encodeB64(salt), encodeB64(key)), nil
 	}
-	return "{bcrypt}" + string(hash), nil
 }
 
 // Verify 验证密码
 func (h *PasswordHasher) Verify(hashedPassword, password string) error {
-	// 移除前缀
-	if !strings.HasPrefix(hashedPassword, "{bcrypt}") {
+	scheme, encoded := parsePasswordScheme(hashedPassword)
+
+	var ok bool
+	switch scheme {
+	case SchemeBcrypt:
+		err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+		if err != nil && !errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return fmt.Errorf("failed to verify password: %w", err)
+		}
+		ok = err == nil
+
+	case SchemeArgon2id:
+		params, salt, key, err := parseArgon2id(encoded)
+		if err != nil {
+			return err
+		}
+		computed := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, uint32(len(key)))
+		ok = subtle.ConstantTimeCompare(computed, key) == 1
+
+	case SchemeScrypt:
+		params, salt, key, err := parseScrypt(encoded)
+		if err != nil {
+			return err
+		}
+		computed, err := scrypt.Key([]byte(password), salt, params.N, params.R, params.P, len(key))
+		if err != nil {
+			return fmt.Errorf("failed to verify password: %w", err)
+		}
+		ok = subtle.ConstantTimeCompare(computed, key) == 1
+
+	case SchemeSSHA:
+		raw, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(raw) <= sha1.Size {
+			return ErrInvalidPasswordFormat
+		}
+		sum := sha1.Sum(append([]byte(password), raw[sha1.Size:]...))
+		ok = subtle.ConstantTimeCompare(sum[:], raw[:sha1.Size]) == 1
+
+	case SchemeSHA:
+		sum := sha1.Sum([]byte(password))
+		ok = subtle.ConstantTimeCompare([]byte(base64.StdEncoding.EncodeToString(sum[:])), []byte(encoded)) == 1
+
+	case SchemeAPR1:
+		salt, _, found := strings.Cut(strings.TrimPrefix(encoded, "$apr1$"), "$")
+		if !found {
+			return ErrInvalidPasswordFormat
+		}
+		ok = subtle.ConstantTimeCompare([]byte(apr1Crypt(password, salt)), []byte(encoded)) == 1
+
+	default:
 		return ErrInvalidPasswordFormat
 	}
-	
-	hash := strings.TrimPrefix(hashedPassword, "{bcrypt}")
-	
-	if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)); err != nil {
-		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
-			return ErrPasswordMismatch
-		}
-		return fmt.Errorf("failed to verify password: %w", err)
+
+	if !ok {
+		return ErrPasswordMismatch
 	}
-	
 	return nil
 }
 
+// NeedsRehash 哈希是否使用了与当前配置不同的算法或参数
+func (h *PasswordHasher) NeedsRehash(hashedPassword string) bool {
+	scheme, encoded := parsePasswordScheme(hashedPassword)
+	if scheme != h.cfg.Algorithm {
+		return true
+	}
+
+	switch scheme {
+	case SchemeBcrypt:
+		cost, err := bcrypt.Cost([]byte(encoded))
+		return err != nil || cost != h.cfg.BcryptCost
+	case SchemeArgon2id:
+		params, _, key, err := parseArgon2id(encoded)
+		return err != nil || params != h.cfg.Argon2id || len(key) != passwordKeySize
+	case SchemeScrypt:
+		params, _, key, err := parseScrypt(encoded)
+		return err != nil || params != h.cfg.Scrypt || len(key) != passwordKeySize
+	default:
+		return true
+	}
+}
+
+// IsPasswordHash 是否为可识别的密码哈希格式，配置文件中的密码据此判断是否已哈希
+func IsPasswordHash(password string) bool {
+	scheme, _ := parsePasswordScheme(password)
+	return scheme != ""
+}
+
 // GenerateRandomPassword 生成随机密码
 func GenerateRandomPassword(length int) (string, error) {
 	bytes := make([]byte, length)
@@ -64,3 +188,181 @@ func GenerateRandomPassword(length int) (string, error) {
 	return base64.URLEncoding.EncodeToString(bytes)[:length], nil
 }
 
+// parsePasswordScheme 识别哈希方案，返回去掉 {scheme} 前缀后的哈希
+func parsePasswordScheme(hashed string) (string, string) {
+	if strings.HasPrefix(hashed, "{") {
+		end := strings.IndexByte(hashed, '}')
+		if end < 0 {
+			return "", ""
+		}
+		prefix, rest := hashed[1:end], hashed[end+1:]
+		switch {
+		case prefix == "bcrypt", prefix == "argon2id", prefix == "scrypt":
+			if scheme, _ := parsePasswordScheme(rest); scheme == prefix {
+				return prefix, rest
+			}
+		case strings.EqualFold(prefix, SchemeSSHA):
+			return SchemeSSHA, rest
+		case strings.EqualFold(prefix, SchemeSHA):
+			return SchemeSHA, rest
+		}
+		return "", ""
+	}
+
+	switch {
+	case strings.HasPrefix(hashed, "$2a$"), strings.HasPrefix(hashed, "$2b$"), strings.HasPrefix(hashed, "$2y$"):
+		return SchemeBcrypt, hashed
+	case strings.HasPrefix(hashed, "$argon2id$"):
+		return SchemeArgon2id, hashed
+	case strings.HasPrefix(hashed, "$scrypt$"):
+		return SchemeScrypt, hashed
+	case strings.HasPrefix(hashed, "$apr1$"):
+		return SchemeAPR1, hashed
+	}
+	return "", ""
+}
+
+// parseArgon2id 解析 PHC 格式：$argon2id$v=19$m=...,t=...,p=...$salt$hash
+func parseArgon2id(encoded string) (config.Argon2idConfig, []byte, []byte, error) {
+	var params config.Argon2idConfig
+
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != SchemeArgon2id {
+		return params, nil, nil, ErrInvalidPasswordFormat
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, ErrInvalidPasswordFormat
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		return params, nil, nil, ErrInvalidPasswordFormat
+	}
+	if params.Iterations < 1 || params.Parallelism < 1 {
+		return params, nil, nil, ErrInvalidPasswordFormat
+	}
+
+	salt, err := decodeB64(parts[4])
+	if err != nil {
+		return params, nil, nil, ErrInvalidPasswordFormat
+	}
+	key, err := decodeB64(parts[5])
+	if err != nil || len(key) == 0 {
+		return params, nil, nil, ErrInvalidPasswordFormat
+	}
+
+	return params, salt, key, nil
+}
+
+// parseScrypt 解析格式：$scrypt$ln=...,r=...,p=...$salt$hash
+func parseScrypt(encoded string) (config.ScryptConfig, []byte, []byte, error) {
+	var params config.ScryptConfig
+
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 5 || parts[1] != SchemeScrypt {
+		return params, nil, nil, ErrInvalidPasswordFormat
+	}
+
+	var ln int
+	if _, err := fmt.Sscanf(parts[2], "ln=%d,r=%d,p=%d", &ln, &params.R, &params.P); err != nil {
+		return params, nil, nil, ErrInvalidPasswordFormat
+	}
+	if ln < 1 || ln > 30 {
+		return params, nil, nil, ErrInvalidPasswordFormat
+	}
+	params.N = 1 << ln
+
+	salt, err := decodeB64(parts[3])
+	if err != nil {
+		return params, nil, nil, ErrInvalidPasswordFormat
+	}
+	key, err := decodeB64(parts[4])
+	if err != nil || len(key) == 0 {
+		return params, nil, nil, ErrInvalidPasswordFormat
+	}
+
+	return params, salt, key, nil
+}
+
+// apr1Alphabet crypt(3) 使用的 base64 字母表
+const apr1Alphabet = "./0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// apr1Crypt 计算 Apache htpasswd 的 $apr1$ 哈希（MD5-crypt 变体），盐最多 8 个字符
+func apr1Crypt(password, salt string) string {
+	const magic = "$apr1$"
+	if len(salt) > 8 {
+		salt = salt[:8]
+	}
+	pw := []byte(password)
+
+	alt := md5.Sum([]byte(password + salt + password))
+
+	d := md5.New()
+	d.Write([]byte(password + magic + salt))
+	for i := len(pw); i > 0; i -= md5.Size {
+		d.Write(alt[:min(i, md5.Size)])
+	}
+	for i := len(pw); i > 0; i >>= 1 {
+		if i&1 != 0 {
+			d.Write([]byte{0})
+		} else {
+			d.Write(pw[:1])
+		}
+	}
+	final := d.Sum(nil)
+
+	for i := 0; i < 1000; i++ {
+		r := md5.New()
+		if i&1 != 0 {
+			r.Write(pw)
+		} else {
+			r.Write(final)
+		}
+		if i%3 != 0 {
+			r.Write([]byte(salt))
+		}
+		if i%7 != 0 {
+			r.Write(pw)
+		}
+		if i&1 != 0 {
+			r.Write(final)
+		} else {
+			r.Write(pw)
+		}
+		final = r.Sum(nil)
+	}
+
+	var out strings.Builder
+	out.WriteString(magic + salt + "$")
+	encode := func(v uint, n int) {
+		for ; n > 0; n-- {
+			out.WriteByte(apr1Alphabet[v&0x3f])
+			v >>= 6
+		}
+	}
+	for _, g := range [][3]int{{0, 6, 12}, {1, 7, 13}, {2, 8, 14}, {3, 9, 15}, {4, 10, 5}} {
+		encode(uint(final[g[0]])<<16|uint(final[g[1]])<<8|uint(final[g[2]]), 4)
+	}
+	encode(uint(final[11]), 2)
+
+	return out.String()
+}
+
+// randomSalt 生成随机盐
+func randomSalt() ([]byte, error) {
+	salt := make([]byte, passwordSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+	return salt, nil
+}
+
+// encodeB64 PHC 格式使用的无填充 base64
+func encodeB64(b []byte) string {
+	return base64.RawStdEncoding.EncodeToString(b)
+}
+
+// decodeB64 解码无填充 base64
+func decodeB64(s string) ([]byte, error) {
+	return base64.RawStdEncoding.DecodeString(s)
+}
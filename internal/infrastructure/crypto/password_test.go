@@ -0,0 +1,162 @@
+package crypto
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/yeying-community/webdav/internal/infrastructure/config"
+)
+
+// testHashConfig 测试使用的低成本参数
+func testHashConfig(algorithm string) config.PasswordHashConfig {
+	return config.PasswordHashConfig{
+		Algorithm:  algorithm,
+		BcryptCost: 4,
+		Argon2id:   config.Argon2idConfig{Memory: 1024, Iterations: 1, Parallelism: 1},
+		Scrypt:     config.ScryptConfig{N: 1024, R: 8, P: 1},
+	}
+}
+
+// 以下向量由 openssl 生成：
+//
+//	openssl passwd -apr1 -salt r31Y7ZyV 'correct horse'
+//	printf secretSALTsalt | openssl sha1 -binary > h; (cat h; printf SALTsalt) | openssl base64
+//	printf secret | openssl sha1 -binary | openssl base64
+func TestPasswordKnownAnswers(t *testing.T) {
+	tests := []struct {
+		name     string
+		hash     string
+		password string
+	}{
+		{name: "apr1", hash: "$apr1$r31Y7ZyV$vxKjPfozOm7Hcs3nYN1po0", password: "correct horse"},
+		{name: "apr1 short salt", hash: "$apr1$abc$PZF73YJz5hJ9yyI.7OP.R.", password: "secret"},
+		// 密码长于 MD5 摘要，覆盖 alt 摘要的分段写入
+		{name: "apr1 long password", hash: "$apr1$12345678$RkSsHiO1RUdjaoav57ZaW/", password: "a much longer password than sixteen bytes"},
+		{name: "ssha", hash: "{SSHA}+peAPwH7og7bRdqLIyCCVNKugb1TQUxUc2FsdA==", password: "secret"},
+		{name: "ssha lowercase prefix", hash: "{ssha}+peAPwH7og7bRdqLIyCCVNKugb1TQUxUc2FsdA==", password: "secret"},
+		{name: "sha", hash: "{SHA}5en6G6MezRroT3XKqkdPOmY/BfQ=", password: "secret"},
+	}
+
+	h := NewPasswordHasherWithConfig(testHashConfig(SchemeArgon2id))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !IsPasswordHash(tt.hash) {
+				t.Fatalf("IsPasswordHash(%q) = false", tt.hash)
+			}
+			if err := h.Verify(tt.hash, tt.password); err != nil {
+				t.Fatalf("Verify: %v", err)
+			}
+			if err := h.Verify(tt.hash, tt.password+"x"); !errors.Is(err, ErrPasswordMismatch) {
+				t.Fatalf("Verify wrong password: err = %v, want ErrPasswordMismatch", err)
+			}
+			// 旧格式总是需要重新哈希
+			if !h.NeedsRehash(tt.hash) {
+				t.Fatal("NeedsRehash = false for a legacy hash")
+			}
+		})
+	}
+
+	if got := apr1Crypt("correct horse", "r31Y7ZyV"); got != "$apr1$r31Y7ZyV$vxKjPfozOm7Hcs3nYN1po0" {
+		t.Fatalf("apr1Crypt = %q", got)
+	}
+}
+
+func TestPasswordHashRoundTrip(t *testing.T) {
+	tests := []struct {
+		algorithm string
+		prefix    string
+	}{
+		{algorithm: SchemeArgon2id, prefix: "{argon2id}$argon2id$v=19$m=1024,t=1,p=1$"},
+		{algorithm: SchemeScrypt, prefix: "{scrypt}$scrypt$ln=10,r=8,p=1$"},
+		{algorithm: SchemeBcrypt, prefix: "{bcrypt}$2a$04$"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.algorithm, func(t *testing.T) {
+			h := NewPasswordHasherWithConfig(testHashConfig(tt.algorithm))
+			hash, err := h.Hash("secret")
+			if err != nil {
+				t.Fatalf("Hash: %v", err)
+			}
+			if !strings.HasPrefix(hash, tt.prefix) {
+				t.Fatalf("hash = %q, want prefix %q", hash, tt.prefix)
+			}
+			if err := h.Verify(hash, "secret"); err != nil {
+				t.Fatalf("Verify: %v", err)
+			}
+			if err := h.Verify(hash, "Secret"); !errors.Is(err, ErrPasswordMismatch) {
+				t.Fatalf("Verify wrong password: err = %v, want ErrPasswordMismatch", err)
+			}
+			if h.NeedsRehash(hash) {
+				t.Fatal("NeedsRehash = true for a hash with the current parameters")
+			}
+
+			// 不带前缀的格式同样可以验证
+			unprefixed := hash[strings.IndexByte(hash, '}')+1:]
+			if err := h.Verify(unprefixed, "secret"); err != nil {
+				t.Fatalf("Verify unprefixed: %v", err)
+			}
+
+			// 盐随机，相同密码的哈希不同
+			again, _ := h.Hash("secret")
+			if again == hash {
+				t.Fatal("Hash returned the same hash twice")
+			}
+		})
+	}
+}
+
+func TestPasswordNeedsRehash(t *testing.T) {
+	current := NewPasswordHasherWithConfig(testHashConfig(SchemeArgon2id))
+
+	stronger := testHashConfig(SchemeArgon2id)
+	stronger.Argon2id.Iterations = 2
+	oldArgon2id, _ := current.Hash("secret")
+	if !NewPasswordHasherWithConfig(stronger).NeedsRehash(oldArgon2id) {
+		t.Fatal("NeedsRehash = false after argon2id parameters changed")
+	}
+
+	bcryptHasher := NewPasswordHasherWithConfig(testHashConfig(SchemeBcrypt))
+	oldBcrypt, _ := bcryptHasher.Hash("secret")
+	if !current.NeedsRehash(oldBcrypt) {
+		t.Fatal("NeedsRehash = false after the algorithm changed")
+	}
+	costlier := testHashConfig(SchemeBcrypt)
+	costlier.BcryptCost = 5
+	if !NewPasswordHasherWithConfig(costlier).NeedsRehash(oldBcrypt) {
+		t.Fatal("NeedsRehash = false after bcrypt cost changed")
+	}
+
+	scryptHasher := NewPasswordHasherWithConfig(testHashConfig(SchemeScrypt))
+	oldScrypt, _ := scryptHasher.Hash("secret")
+	larger := testHashConfig(SchemeScrypt)
+	larger.Scrypt.N = 2048
+	if !NewPasswordHasherWithConfig(larger).NeedsRehash(oldScrypt) {
+		t.Fatal("NeedsRehash = false after scrypt parameters changed")
+	}
+}
+
+func TestPasswordInvalidFormat(t *testing.T) {
+	h := NewPasswordHasherWithConfig(testHashConfig(SchemeArgon2id))
+
+	for _, hash := range []string{
+		"",
+		"secret",
+		"{MD5}Xr4ilOzQ4PCOq3aQ0qbuaQ==",
+		"{argon2id}$scrypt$ln=10,r=8,p=1$c2FsdA$a2V5",
+		"$argon2id$v=18$m=1024,t=1,p=1$c2FsdA$a2V5",
+		"$argon2id$v=19$m=1024,t=0,p=1$c2FsdA$a2V5",
+		"$scrypt$ln=31,r=8,p=1$c2FsdA$a2V5",
+		"{SSHA}c2hvcnQ=",
+		"$apr1$nosalt",
+	} {
+		if err := h.Verify(hash, "secret"); !errors.Is(err, ErrInvalidPasswordFormat) {
+			t.Errorf("Verify(%q): err = %v, want ErrInvalidPasswordFormat", hash, err)
+		}
+	}
+
+	if IsPasswordHash("secret") || IsPasswordHash("{MD5}Xr4ilOzQ4PCOq3aQ0qbuaQ==") {
+		t.Fatal("IsPasswordHash accepted a plaintext or unsupported password")
+	}
+}
@@ -0,0 +1,42 @@
+package crypto
+
+import (
+	"fmt"
+
+	"github.com/yeying-community/webdav/internal/domain/user"
+)
+
+// Signer 钱包签名验证器，每条链一个实现
+type Signer interface {
+	// Chain 配置中使用的链名称，如 ethereum
+	Chain() string
+
+	// Namespace CAIP-2 命名空间，如 eip155
+	Namespace() string
+
+	// Blockchain 登录消息首行中的区块链名称，如 Ethereum
+	Blockchain() string
+
+	// IsValidAddress 验证地址格式
+	IsValidAddress(address string) bool
+
+	// NormalizeAddress 规范化地址，用于登录消息和钱包标识
+	NormalizeAddress(address string) string
+
+	// VerifySignature 验证地址对消息的签名
+	VerifySignature(message, signature, address string) error
+}
+
+// NewSigner 按链名称创建签名验证器
+func NewSigner(chain string) (Signer, error) {
+	switch chain {
+	case user.ChainEthereum:
+		return NewEthereumSigner(), nil
+	case user.ChainSolana:
+		return NewSolanaSigner(), nil
+	case user.ChainBitcoin:
+		return NewBitcoinSigner(), nil
+	default:
+		return nil, fmt.Errorf("unsupported chain: %s", chain)
+	}
+}
@@ -0,0 +1,93 @@
+package crypto
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/mr-tron/base58"
+	"github.com/yeying-community/webdav/internal/domain/user"
+)
+
+// SolanaSigner Solana 签名验证器
+//
+// 地址为 base58 编码的 ed25519 公钥，钱包（signMessage）直接对消息的 UTF-8 字节签名。
+type SolanaSigner struct{}
+
+// NewSolanaSigner 创建 Solana 签名验证器
+func NewSolanaSigner() *SolanaSigner {
+	return &SolanaSigner{}
+}
+
+// Chain 链名称
+func (s *SolanaSigner) Chain() string {
+	return user.ChainSolana
+}
+
+// Namespace CAIP-2 命名空间
+func (s *SolanaSigner) Namespace() string {
+	return user.NamespaceSolana
+}
+
+// Blockchain 登录消息中的区块链名称
+func (s *SolanaSigner) Blockchain() string {
+	return "Solana"
+}
+
+// IsValidAddress 验证地址格式
+func (s *SolanaSigner) IsValidAddress(address string) bool {
+	_, err := s.publicKey(address)
+	return err == nil
+}
+
+// NormalizeAddress base58 地址区分大小写，原样返回
+func (s *SolanaSigner) NormalizeAddress(address string) string {
+	return address
+}
+
+// VerifySignature 验证 ed25519 签名，签名可以是 base58、base64 或十六进制编码
+func (s *SolanaSigner) VerifySignature(message, signature, address string) error {
+	publicKey, err := s.publicKey(address)
+	if err != nil {
+		return err
+	}
+
+	sig, err := decodeSolanaSignature(signature)
+	if err != nil {
+		return err
+	}
+
+	if !ed25519.Verify(publicKey, []byte(message), sig) {
+		return fmt.Errorf("%w: expected %s", ErrSignatureMismatch, address)
+	}
+
+	return nil
+}
+
+// publicKey 解码地址中的公钥
+func (s *SolanaSigner) publicKey(address string) (ed25519.PublicKey, error) {
+	decoded, err := base58.Decode(address)
+	if err != nil || len(decoded) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid solana address: %s", address)
+	}
+	return ed25519.PublicKey(decoded), nil
+}
+
+// decodeSolanaSignature 解码签名，钱包适配器通常返回 base58，也接受 base64 和十六进制
+func decodeSolanaSignature(signature string) ([]byte, error) {
+	if sig, err := base58.Decode(signature); err == nil && len(sig) == ed25519.SignatureSize {
+		return sig, nil
+	}
+	if sig, err := base64.StdEncoding.DecodeString(signature); err == nil && len(sig) == ed25519.SignatureSize {
+		return sig, nil
+	}
+	if sig, err := hex.DecodeString(strings.TrimPrefix(signature, "0x")); err == nil {
+		if len(sig) != ed25519.SignatureSize {
+			return nil, ErrInvalidSignatureLength
+		}
+		return sig, nil
+	}
+	return nil, ErrInvalidSignature
+}
@@ -0,0 +1,90 @@
+package crypto
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/mr-tron/base58"
+)
+
+func TestSolanaVerifySignature(t *testing.T) {
+	// RFC 8032 7.1 TEST 1 和 TEST 2，Solana 地址即 base58 编码的公钥
+	vectors := []struct {
+		publicKey string
+		message   string
+		signature string
+	}{
+		{
+			publicKey: "d75a980182b10ab7d54bfed3c964073a0ee172f3daa62325af021a68f707511a",
+			message:   "",
+			signature: "e5564300c360ac729086e2cc806e828a84877f1eb8e5d974d873e065224901555fb8821590a33bacc61e39701cf9b46bd25bf5f0595bbe24655141438e7a100b",
+		},
+		{
+			publicKey: "3d4017c3e843895a92b70aa74d1b7ebc9c982ccf2ec4968cc0cd55f12af4660c",
+			message:   "72",
+			signature: "92a009a9f0d4cab8720e820b5f642540a2b27b5416503f8fb3762223ebdb69da085ac1e43e15996e458f3613d0f11d8c387b2eaeb4302aeeb00d291612bb0c00",
+		},
+	}
+
+	decode := func(s string) []byte {
+		b, err := hex.DecodeString(s)
+		if err != nil {
+			t.Fatalf("DecodeString: %v", err)
+		}
+		return b
+	}
+
+	first, second := vectors[0], vectors[1]
+	address := base58.Encode(decode(first.publicKey))
+	message := string(decode(first.message))
+	sig := decode(first.signature)
+	tampered := append([]byte{}, sig...)
+	tampered[0] ^= 0x01
+
+	tests := []struct {
+		name      string
+		message   string
+		signature string
+		address   string
+		wantErr   error
+		wantAny   bool // 只要求返回错误
+	}{
+		{name: "base58 signature", message: message, signature: base58.Encode(sig), address: address},
+		{name: "base64 signature", message: message, signature: base64.StdEncoding.EncodeToString(sig), address: address},
+		{name: "hex signature", message: message, signature: first.signature, address: address},
+		{name: "0x hex signature", message: message, signature: "0x" + first.signature, address: address},
+		{name: "second vector", message: string(decode(second.message)), signature: second.signature, address: base58.Encode(decode(second.publicKey))},
+		{name: "wrong address", message: message, signature: first.signature, address: base58.Encode(decode(second.publicKey)), wantErr: ErrSignatureMismatch},
+		{name: "tampered message", message: "\x72", signature: first.signature, address: address, wantErr: ErrSignatureMismatch},
+		{name: "tampered signature", message: message, signature: hex.EncodeToString(tampered), address: address, wantErr: ErrSignatureMismatch},
+		{name: "short hex signature", message: message, signature: first.signature[:126], address: address, wantErr: ErrInvalidSignatureLength},
+		{name: "undecodable signature", message: message, signature: "not a signature!", address: address, wantErr: ErrInvalidSignature},
+		{name: "malformed base58 address", message: message, signature: first.signature, address: "0" + address[1:], wantAny: true},
+		{name: "short address", message: message, signature: first.signature, address: base58.Encode(decode(first.publicKey)[:31]), wantAny: true},
+	}
+
+	s := NewSolanaSigner()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.VerifySignature(tt.message, tt.signature, tt.address)
+			switch {
+			case tt.wantAny:
+				if err == nil {
+					t.Fatal("VerifySignature should fail")
+				}
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+			case err != nil:
+				t.Fatalf("VerifySignature: %v", err)
+			}
+		})
+	}
+
+	if !s.IsValidAddress(address) || s.IsValidAddress("0"+address[1:]) {
+		t.Fatal("IsValidAddress does not match VerifySignature")
+	}
+}
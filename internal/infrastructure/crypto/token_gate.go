@@ -0,0 +1,199 @@
+package crypto
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/yeying-community/webdav/internal/domain/user"
+)
+
+var (
+	// balanceOfSelector balanceOf(address)，ERC-20 和 ERC-721 相同
+	balanceOfSelector = []byte{0x70, 0xa0, 0x82, 0x31}
+
+	// ownerOfSelector ownerOf(uint256)
+	ownerOfSelector = []byte{0x63, 0x52, 0x21, 0x1e}
+)
+
+// tokenQueryResult 缓存的链上查询结果
+type tokenQueryResult struct {
+	value     *big.Int       // balanceOf 的余额
+	owner     common.Address // ownerOf 的所有者，token 不存在时为零地址
+	expiresAt time.Time
+}
+
+// TokenGateVerifier 链上持有条件验证器（ERC-20 / ERC-721）
+//
+// 通过 eth_call 查询余额和所有者，结果按地址缓存 ttl，多条规则共享同一次查询。
+type TokenGateVerifier struct {
+	client  RPCCaller
+	timeout time.Duration
+	ttl     time.Duration
+	cache   map[string]*tokenQueryResult
+	mu      sync.Mutex
+}
+
+// NewTokenGateVerifier 创建连接到 JSON-RPC 节点的持有条件验证器
+func NewTokenGateVerifier(rpcURL string, timeout, ttl time.Duration) (*TokenGateVerifier, error) {
+	client, err := rpc.DialOptions(context.Background(), rpcURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial rpc endpoint: %w", err)
+	}
+
+	return NewTokenGateVerifierWithClient(client, timeout, ttl), nil
+}
+
+// NewTokenGateVerifierWithClient 使用已有的 RPC 客户端创建持有条件验证器
+func NewTokenGateVerifierWithClient(client RPCCaller, timeout, ttl time.Duration) *TokenGateVerifier {
+	v := &TokenGateVerifier{
+		client:  client,
+		timeout: timeout,
+		ttl:     ttl,
+		cache:   make(map[string]*tokenQueryResult),
+	}
+
+	// 启动清理协程
+	go v.cleanupExpired()
+
+	return v
+}
+
+// Holds 钱包是否满足持有条件
+func (v *TokenGateVerifier) Holds(ctx context.Context, address string, gate *user.TokenGate) (bool, error) {
+	if !common.IsHexAddress(address) {
+		return false, fmt.Errorf("invalid wallet address: %s", address)
+	}
+	account := common.HexToAddress(address)
+	contract := common.HexToAddress(gate.Contract)
+
+	if id := gate.ID(); id != nil && strings.EqualFold(gate.Standard, user.TokenERC721) {
+		result, err := v.query(ctx, "owner:"+contract.Hex()+":"+id.String(), func(ctx context.Context) (*tokenQueryResult, error) {
+			return v.ownerOf(ctx, contract, id)
+		})
+		if err != nil {
+			return false, err
+		}
+		return result.owner == account, nil
+	}
+
+	result, err := v.query(ctx, "balance:"+contract.Hex()+":"+account.Hex(), func(ctx context.Context) (*tokenQueryResult, error) {
+		return v.balanceOf(ctx, contract, account)
+	})
+	if err != nil {
+		return false, err
+	}
+	return result.value.Cmp(gate.Threshold()) >= 0, nil
+}
+
+// Close 关闭 RPC 连接
+func (v *TokenGateVerifier) Close() {
+	if client, ok := v.client.(*rpc.Client); ok {
+		client.Close()
+	}
+}
+
+// query 读取缓存，未命中时查询并缓存结果；查询失败不缓存
+func (v *TokenGateVerifier) query(ctx context.Context, key string, fetch func(context.Context) (*tokenQueryResult, error)) (*tokenQueryResult, error) {
+	v.mu.Lock()
+	cached, ok := v.cache[key]
+	v.mu.Unlock()
+	if ok && time.Now().Before(cached.expiresAt) {
+		return cached, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, v.timeout)
+	defer cancel()
+
+	result, err := fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result.expiresAt = time.Now().Add(v.ttl)
+
+	v.mu.Lock()
+	v.cache[key] = result
+	v.mu.Unlock()
+
+	return result, nil
+}
+
+// balanceOf 查询余额（ERC-20 为代币余额，ERC-721 为持有数量）
+func (v *TokenGateVerifier) balanceOf(ctx context.Context, contract, account common.Address) (*tokenQueryResult, error) {
+	data := append(append([]byte{}, balanceOfSelector...), common.LeftPadBytes(account.Bytes(), 32)...)
+
+	result, err := v.call(ctx, contract, data)
+	if err != nil {
+		return nil, fmt.Errorf("balanceOf failed: %w", err)
+	}
+	if len(result) < 32 {
+		return nil, fmt.Errorf("balanceOf returned %d bytes", len(result))
+	}
+
+	return &tokenQueryResult{value: new(big.Int).SetBytes(result[:32])}, nil
+}
+
+// ownerOf 查询 ERC-721 token 的所有者，token 不存在（调用回滚）时返回零地址
+func (v *TokenGateVerifier) ownerOf(ctx context.Context, contract common.Address, id *big.Int) (*tokenQueryResult, error) {
+	data := append(append([]byte{}, ownerOfSelector...), common.LeftPadBytes(id.Bytes(), 32)...)
+
+	result, err := v.call(ctx, contract, data)
+	if err != nil {
+		if isExecutionReverted(err) {
+			return &tokenQueryResult{}, nil
+		}
+		return nil, fmt.Errorf("ownerOf failed: %w", err)
+	}
+	if len(result) < 32 {
+		return nil, fmt.Errorf("ownerOf returned %d bytes", len(result))
+	}
+
+	return &tokenQueryResult{owner: common.BytesToAddress(result[:32])}, nil
+}
+
+// call 执行只读合约调用
+func (v *TokenGateVerifier) call(ctx context.Context, contract common.Address, data []byte) (hexutil.Bytes, error) {
+	call := map[string]interface{}{
+		"to":   contract,
+		"data": hexutil.Bytes(data),
+	}
+
+	var result hexutil.Bytes
+	if err := v.client.CallContext(ctx, &result, "eth_call", call, "latest"); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// cleanupExpired 清理过期的查询结果
+func (v *TokenGateVerifier) cleanupExpired() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		v.mu.Lock()
+		now := time.Now()
+		for key, result := range v.cache {
+			if now.After(result.expiresAt) {
+				delete(v.cache, key)
+			}
+		}
+		v.mu.Unlock()
+	}
+}
+
+// isExecutionReverted 是否为合约执行回滚（而不是网络或节点错误）
+func isExecutionReverted(err error) bool {
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) && rpcErr.ErrorCode() == 3 {
+		return true
+	}
+	return strings.Contains(err.Error(), "execution reverted")
+}
@@ -0,0 +1,223 @@
+package crypto
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/yeying-community/webdav/internal/domain/user"
+)
+
+// erc20Token 按地址返回余额的 ERC-20 合约替身
+func erc20Token(balances map[common.Address]*big.Int) fakeContract {
+	return func(data []byte) ([]byte, error) {
+		if len(data) != 36 || !bytes.Equal(data[:4], balanceOfSelector) {
+			return nil, errReverted
+		}
+		balance, ok := balances[common.BytesToAddress(data[4:36])]
+		if !ok {
+			balance = new(big.Int)
+		}
+		return common.LeftPadBytes(balance.Bytes(), 32), nil
+	}
+}
+
+// erc721Token ERC-721 合约替身，不存在的 token 调用 ownerOf 时回滚
+func erc721Token(owners map[int64]common.Address) fakeContract {
+	return func(data []byte) ([]byte, error) {
+		if len(data) != 36 {
+			return nil, errReverted
+		}
+		switch {
+		case bytes.Equal(data[:4], ownerOfSelector):
+			owner, ok := owners[new(big.Int).SetBytes(data[4:36]).Int64()]
+			if !ok {
+				return nil, errReverted
+			}
+			return common.LeftPadBytes(owner.Bytes(), 32), nil
+		case bytes.Equal(data[:4], balanceOfSelector):
+			holder := common.BytesToAddress(data[4:36])
+			count := int64(0)
+			for _, owner := range owners {
+				if owner == holder {
+					count++
+				}
+			}
+			return common.LeftPadBytes(big.NewInt(count).Bytes(), 32), nil
+		}
+		return nil, errReverted
+	}
+}
+
+func TestTokenGateHolds(t *testing.T) {
+	erc20 := common.HexToAddress("0x00000000000000000000000000000000000020c0")
+	erc721 := common.HexToAddress("0x0000000000000000000000000000000000721c00")
+	broken := common.HexToAddress("0x00000000000000000000000000000000000000bb")
+	alice := common.HexToAddress("0x000000000000000000000000000000000000a11c")
+	bob := common.HexToAddress("0x0000000000000000000000000000000000000b0b")
+
+	node, url := newFakeNode(t)
+	node.deploy(erc20, erc20Token(map[common.Address]*big.Int{
+		alice: new(big.Int).Mul(big.NewInt(5), big.NewInt(1e18)),
+		bob:   big.NewInt(1),
+	}))
+	node.deploy(erc721, erc721Token(map[int64]common.Address{7: alice, 8: alice, 9: bob}))
+	node.deploy(broken, func([]byte) ([]byte, error) { return []byte{0x01}, nil })
+
+	tests := []struct {
+		name    string
+		account common.Address
+		gate    *user.TokenGate
+		want    bool
+		wantErr bool
+	}{
+		{
+			name:    "erc20 default threshold",
+			account: bob,
+			gate:    &user.TokenGate{Standard: user.TokenERC20, Contract: erc20.Hex()},
+			want:    true,
+		},
+		{
+			name:    "erc20 balance at threshold",
+			account: alice,
+			gate:    &user.TokenGate{Standard: user.TokenERC20, Contract: erc20.Hex(), MinBalance: "5000000000000000000"},
+			want:    true,
+		},
+		{
+			name:    "erc20 balance below threshold",
+			account: alice,
+			gate:    &user.TokenGate{Standard: user.TokenERC20, Contract: erc20.Hex(), MinBalance: "5000000000000000001"},
+			want:    false,
+		},
+		{
+			name:    "erc20 no balance",
+			account: common.HexToAddress("0x0000000000000000000000000000000000000c0c"),
+			gate:    &user.TokenGate{Standard: user.TokenERC20, Contract: erc20.Hex()},
+			want:    false,
+		},
+		{
+			name:    "erc721 owns token",
+			account: alice,
+			gate:    &user.TokenGate{Standard: user.TokenERC721, Contract: erc721.Hex(), TokenID: "7"},
+			want:    true,
+		},
+		{
+			name:    "erc721 token owned by someone else",
+			account: alice,
+			gate:    &user.TokenGate{Standard: user.TokenERC721, Contract: erc721.Hex(), TokenID: "9"},
+			want:    false,
+		},
+		{
+			name:    "erc721 nonexistent token",
+			account: alice,
+			gate:    &user.TokenGate{Standard: user.TokenERC721, Contract: erc721.Hex(), TokenID: "404"},
+			want:    false,
+		},
+		{
+			name:    "erc721 holding count",
+			account: alice,
+			gate:    &user.TokenGate{Standard: user.TokenERC721, Contract: erc721.Hex(), MinBalance: "2"},
+			want:    true,
+		},
+		{
+			name:    "erc721 holding count below threshold",
+			account: bob,
+			gate:    &user.TokenGate{Standard: user.TokenERC721, Contract: erc721.Hex(), MinBalance: "2"},
+			want:    false,
+		},
+		{
+			name:    "malformed return data",
+			account: alice,
+			gate:    &user.TokenGate{Standard: user.TokenERC20, Contract: broken.Hex()},
+			wantErr: true,
+		},
+	}
+
+	v, err := NewTokenGateVerifier(url, 5*time.Second, time.Minute)
+	if err != nil {
+		t.Fatalf("NewTokenGateVerifier: %v", err)
+	}
+	defer v.Close()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := v.Holds(context.Background(), tt.account.Hex(), tt.gate)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Holds should fail")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Holds: %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("Holds = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := v.Holds(context.Background(), "not-an-address", tests[0].gate); err == nil {
+		t.Fatal("Holds should reject an invalid wallet address")
+	}
+}
+
+func TestTokenGateCache(t *testing.T) {
+	erc20 := common.HexToAddress("0x00000000000000000000000000000000000020c0")
+	alice := common.HexToAddress("0x000000000000000000000000000000000000a11c")
+	balances := map[common.Address]*big.Int{alice: big.NewInt(10)}
+
+	node, url := newFakeNode(t)
+	node.deploy(erc20, erc20Token(balances))
+
+	v, err := NewTokenGateVerifier(url, 5*time.Second, 50*time.Millisecond)
+	if err != nil {
+		t.Fatalf("NewTokenGateVerifier: %v", err)
+	}
+	defer v.Close()
+
+	ctx := context.Background()
+	low := &user.TokenGate{Standard: user.TokenERC20, Contract: erc20.Hex(), MinBalance: "5"}
+	high := &user.TokenGate{Standard: user.TokenERC20, Contract: erc20.Hex(), MinBalance: "10"}
+
+	// 同一地址的不同门槛共享一次查询
+	for _, gate := range []*user.TokenGate{low, high, low} {
+		if ok, err := v.Holds(ctx, alice.Hex(), gate); err != nil || !ok {
+			t.Fatalf("Holds(%s) = %v, %v", gate.MinBalance, ok, err)
+		}
+	}
+	if n := node.callCount("eth_call"); n != 1 {
+		t.Fatalf("eth_call count = %d, want 1", n)
+	}
+
+	// 缓存过期后重新查询
+	node.mu.Lock()
+	balances[alice] = big.NewInt(7)
+	node.mu.Unlock()
+	time.Sleep(100 * time.Millisecond)
+
+	if ok, err := v.Holds(ctx, alice.Hex(), high); err != nil || ok {
+		t.Fatalf("Holds after balance drop = %v, %v", ok, err)
+	}
+	if n := node.callCount("eth_call"); n != 2 {
+		t.Fatalf("eth_call count = %d, want 2", n)
+	}
+}
+
+func TestTokenGateNodeUnavailable(t *testing.T) {
+	_, url := newFakeNode(t)
+	v, err := NewTokenGateVerifier(url+"/missing", time.Second, time.Minute)
+	if err != nil {
+		t.Fatalf("NewTokenGateVerifier: %v", err)
+	}
+	defer v.Close()
+
+	gate := &user.TokenGate{Standard: user.TokenERC721, Contract: "0x0000000000000000000000000000000000721c00", TokenID: "1"}
+	// 节点错误不能被当作 token 不存在
+	if _, err := v.Holds(context.Background(), "0x000000000000000000000000000000000000a11c", gate); err == nil {
+		t.Fatal("Holds should fail when the node is unavailable")
+	}
+}
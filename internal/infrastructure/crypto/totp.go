@@ -0,0 +1,111 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"image/png"
+	"strings"
+	"time"
+
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+)
+
+// totpValidateOpts 验证参数：30 秒步长、6 位、允许前后各一个步长的时钟偏差
+var totpValidateOpts = totp.ValidateOpts{
+	Period:    30,
+	Skew:      1,
+	Digits:    otp.DigitsSix,
+	Algorithm: otp.AlgorithmSHA1,
+}
+
+// TOTP 基于时间的一次性密码生成与验证
+type TOTP struct {
+	issuer string
+}
+
+// TOTPKey 新生成的 TOTP 密钥
+type TOTPKey struct {
+	Secret string // Base32 编码
+	URI    string // otpauth:// 注册 URI
+}
+
+// NewTOTP 创建 TOTP，issuer 显示在验证器应用中
+func NewTOTP(issuer string) *TOTP {
+	return &TOTP{issuer: issuer}
+}
+
+// Generate 为账号生成新的密钥
+func (t *TOTP) Generate(account string) (*TOTPKey, error) {
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      t.issuer,
+		AccountName: account,
+		Period:      uint(totpValidateOpts.Period),
+		Digits:      totpValidateOpts.Digits,
+		Algorithm:   totpValidateOpts.Algorithm,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate totp key: %w", err)
+	}
+
+	return &TOTPKey{
+		Secret: key.Secret(),
+		URI:    key.URL(),
+	}, nil
+}
+
+// Validate 验证一次性密码
+func (t *TOTP) Validate(secret, code string) bool {
+	_, ok := t.ValidateStep(secret, code)
+	return ok
+}
+
+// ValidateStep 验证一次性密码并返回匹配的时间步，用于拒绝重放
+//
+// 多个时间步匹配时返回最新的一个。
+func (t *TOTP) ValidateStep(secret, code string) (int64, bool) {
+	return validateStepAt(secret, code, time.Now().UTC())
+}
+
+// validateStepAt 在指定时间验证一次性密码
+func validateStepAt(secret, code string, now time.Time) (int64, bool) {
+	code = strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+	if len(code) != totpValidateOpts.Digits.Length() {
+		return 0, false
+	}
+
+	period := int64(totpValidateOpts.Period)
+	current := now.Unix() / period
+	for step := current + int64(totpValidateOpts.Skew); step >= current-int64(totpValidateOpts.Skew); step-- {
+		expected, err := totp.GenerateCodeCustom(secret, time.Unix(step*period, 0).UTC(), totpValidateOpts)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// QRCode 将注册 URI 编码为 PNG 二维码的 data URL
+func (t *TOTP) QRCode(uri string, size int) (string, error) {
+	key, err := otp.NewKeyFromURL(uri)
+	if err != nil {
+		return "", err
+	}
+
+	img, err := key.Image(size, size)
+	if err != nil {
+		return "", fmt.Errorf("failed to render qr code: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return "", fmt.Errorf("failed to encode qr code: %w", err)
+	}
+
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
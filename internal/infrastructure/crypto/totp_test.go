@@ -0,0 +1,54 @@
+package crypto
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pquerna/otp/totp"
+)
+
+func TestTOTPValidateStep(t *testing.T) {
+	key, err := NewTOTP("WebDAV").Generate("alice")
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+
+	now := time.Unix(1_700_000_000, 0).UTC()
+	current := now.Unix() / 30
+
+	// codeAt 指定时间步的验证码
+	codeAt := func(step int64) string {
+		code, err := totp.GenerateCodeCustom(key.Secret, time.Unix(step*30, 0).UTC(), totpValidateOpts)
+		if err != nil {
+			t.Fatalf("GenerateCodeCustom: %v", err)
+		}
+		return code
+	}
+
+	tests := []struct {
+		name     string
+		code     string
+		wantStep int64
+		wantOK   bool
+	}{
+		{name: "current step", code: codeAt(current), wantStep: current, wantOK: true},
+		{name: "previous step within skew", code: codeAt(current - 1), wantStep: current - 1, wantOK: true},
+		{name: "next step within skew", code: codeAt(current + 1), wantStep: current + 1, wantOK: true},
+		{name: "spaces are ignored", code: " " + codeAt(current)[:3] + " " + codeAt(current)[3:], wantStep: current, wantOK: true},
+		{name: "outside skew", code: codeAt(current - 2), wantOK: false},
+		{name: "wrong length", code: codeAt(current)[:5], wantOK: false},
+		{name: "empty", code: "", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := validateStepAt(key.Secret, tt.code, now)
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && step != tt.wantStep {
+				t.Fatalf("step = %d, want %d", step, tt.wantStep)
+			}
+		})
+	}
+}
@@ -0,0 +1,216 @@
+package lock
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+	"golang.org/x/net/webdav"
+)
+
+// locksBucket 锁数据桶
+var locksBucket = []byte("locks")
+
+// BoltStore BoltDB 锁存储（单实例持久化，重启后保留）
+type BoltStore struct {
+	db *bolt.DB
+}
+
+// NewBoltStore 创建 BoltDB 锁存储
+func NewBoltStore(path string) (*BoltStore, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock database: %w", err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(locksBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize lock database: %w", err)
+	}
+
+	return &BoltStore{db: db}, nil
+}
+
+// Create 创建锁
+func (s *BoltStore) Create(ctx context.Context, lock *Lock, now time.Time) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(locksBucket)
+
+		var expired [][]byte
+		err := bucket.ForEach(func(k, v []byte) error {
+			existing, err := decodeLock(v)
+			if err != nil {
+				return err
+			}
+			if existing.Expired(now) {
+				expired = append(expired, k)
+				return nil
+			}
+			if conflicts(lock, existing) {
+				return webdav.ErrLocked
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, k := range expired {
+			if err := bucket.Delete(k); err != nil {
+				return err
+			}
+		}
+
+		return putLock(bucket, lock)
+	})
+}
+
+// Get 获取锁
+func (s *BoltStore) Get(ctx context.Context, token string, now time.Time) (*Lock, error) {
+	var lock *Lock
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		lock, err = getLock(tx.Bucket(locksBucket), token, now)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return lock, nil
+}
+
+// Refresh 刷新锁
+func (s *BoltStore) Refresh(ctx context.Context, token string, duration time.Duration, now time.Time) (*Lock, error) {
+	var lock *Lock
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(locksBucket)
+
+		var err error
+		lock, err = getLock(bucket, token, now)
+		if err != nil {
+			return err
+		}
+
+		lock.refresh(duration, now)
+		return putLock(bucket, lock)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return lock, nil
+}
+
+// Delete 删除锁
+func (s *BoltStore) Delete(ctx context.Context, token string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(locksBucket)
+		if bucket.Get([]byte(token)) == nil {
+			return ErrLockNotFound
+		}
+		return bucket.Delete([]byte(token))
+	})
+}
+
+// List 列出锁
+func (s *BoltStore) List(ctx context.Context, now time.Time) ([]*Lock, error) {
+	var locks []*Lock
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(locksBucket).ForEach(func(k, v []byte) error {
+			lock, err := decodeLock(v)
+			if err != nil {
+				return err
+			}
+			if !lock.Expired(now) {
+				locks = append(locks, lock)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return locks, nil
+}
+
+// Sweep 清理过期锁
+func (s *BoltStore) Sweep(ctx context.Context, now time.Time) (int, error) {
+	removed := 0
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(locksBucket)
+
+		var expired [][]byte
+		err := bucket.ForEach(func(k, v []byte) error {
+			lock, err := decodeLock(v)
+			if err != nil {
+				return err
+			}
+			if lock.Expired(now) {
+				expired = append(expired, k)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, k := range expired {
+			if err := bucket.Delete(k); err != nil {
+				return err
+			}
+		}
+		removed = len(expired)
+		return nil
+	})
+
+	return removed, err
+}
+
+// Close 关闭存储
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
+
+// getLock 读取未过期的锁
+func getLock(bucket *bolt.Bucket, token string, now time.Time) (*Lock, error) {
+	data := bucket.Get([]byte(token))
+	if data == nil {
+		return nil, ErrLockNotFound
+	}
+
+	lock, err := decodeLock(data)
+	if err != nil {
+		return nil, err
+	}
+	if lock.Expired(now) {
+		return nil, ErrLockNotFound
+	}
+
+	return lock, nil
+}
+
+// putLock 写入锁
+func putLock(bucket *bolt.Bucket, lock *Lock) error {
+	data, err := json.Marshal(lock)
+	if err != nil {
+		return fmt.Errorf("failed to encode lock: %w", err)
+	}
+	return bucket.Put([]byte(lock.Token), data)
+}
+
+// decodeLock 解码锁
+func decodeLock(data []byte) (*Lock, error) {
+	var lock Lock
+	if err := json.Unmarshal(data, &lock); err != nil {
+		return nil, fmt.Errorf("failed to decode lock: %w", err)
+	}
+	return &lock, nil
+}
@@ -0,0 +1,170 @@
+package lock
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/yeying-community/webdav/internal/infrastructure/config"
+	"golang.org/x/net/webdav"
+)
+
+// ErrLockNotFound 锁不存在或已过期
+var ErrLockNotFound = errors.New("lock not found")
+
+// Lock 持久化的 WebDAV 锁
+type Lock struct {
+	Token     string        `json:"token"`
+	Username  string        `json:"username"`
+	Directory string        `json:"directory"` // 用户根目录，锁路径相对于该目录
+	Root      string        `json:"root"`
+	ZeroDepth bool          `json:"zero_depth"`
+	OwnerXML  string        `json:"owner_xml,omitempty"`
+	Duration  time.Duration `json:"duration"` // 负数表示不过期
+	CreatedAt time.Time     `json:"created_at"`
+	ExpiresAt time.Time     `json:"expires_at"` // 零值表示不过期
+}
+
+// Store 锁存储
+//
+// Create 必须原子地完成冲突检查与写入，以保证多个实例共享同一存储时不会发放冲突的锁。
+type Store interface {
+	// Create 创建锁，与未过期的锁冲突时返回 webdav.ErrLocked
+	Create(ctx context.Context, lock *Lock, now time.Time) error
+
+	// Get 获取未过期的锁
+	Get(ctx context.Context, token string, now time.Time) (*Lock, error)
+
+	// Refresh 刷新锁的超时时间
+	Refresh(ctx context.Context, token string, duration time.Duration, now time.Time) (*Lock, error)
+
+	// Delete 删除锁
+	Delete(ctx context.Context, token string) error
+
+	// List 列出未过期的锁
+	List(ctx context.Context, now time.Time) ([]*Lock, error)
+
+	// Sweep 清理过期的锁，返回清理数量
+	Sweep(ctx context.Context, now time.Time) (int, error)
+
+	// Close 关闭存储
+	Close() error
+}
+
+// NewStore 根据配置创建锁存储
+func NewStore(cfg config.LockStorageConfig) (Store, error) {
+	switch cfg.Driver {
+	case "", "memory":
+		return NewMemoryStore(), nil
+	case "bolt":
+		return NewBoltStore(cfg.Path)
+	case "redis":
+		return NewRedisStore(cfg.Redis)
+	default:
+		return nil, fmt.Errorf("unsupported locks driver: %s", cfg.Driver)
+	}
+}
+
+// Details 转换为 webdav.LockDetails
+func (l *Lock) Details() webdav.LockDetails {
+	return webdav.LockDetails{
+		Root:      l.Root,
+		Duration:  l.Duration,
+		OwnerXML:  l.OwnerXML,
+		ZeroDepth: l.ZeroDepth,
+	}
+}
+
+// Expired 是否已过期
+func (l *Lock) Expired(now time.Time) bool {
+	return !l.ExpiresAt.IsZero() && !now.Before(l.ExpiresAt)
+}
+
+// refresh 按新的超时时间更新过期时间
+func (l *Lock) refresh(duration time.Duration, now time.Time) {
+	l.Duration = duration
+	l.ExpiresAt = time.Time{}
+	if duration >= 0 {
+		l.ExpiresAt = now.Add(duration)
+	}
+}
+
+// absolutePath 锁在整个存储中的路径，用于跨用户的冲突检查
+func (l *Lock) absolutePath() string {
+	return path.Join("/", l.Directory, l.Root)
+}
+
+// covers 判断锁是否覆盖指定路径（相对于用户根目录）
+func (l *Lock) covers(name string) bool {
+	if name == l.Root {
+		return true
+	}
+	if l.ZeroDepth {
+		return false
+	}
+	return l.Root == "/" || strings.HasPrefix(name, l.Root+"/")
+}
+
+// conflicts 判断两把锁是否冲突（仅支持排他锁）
+func conflicts(a, b *Lock) bool {
+	pa, pb := a.absolutePath(), b.absolutePath()
+	if pa == pb {
+		return true
+	}
+	if !b.ZeroDepth && isAncestor(pb, pa) {
+		return true
+	}
+	return !a.ZeroDepth && isAncestor(pa, pb)
+}
+
+// isAncestor 判断 parent 是否为 child 的祖先路径
+func isAncestor(parent, child string) bool {
+	return parent == "/" || strings.HasPrefix(child, parent+"/")
+}
+
+// newLock 根据锁详情创建锁
+func newLock(username, directory string, details webdav.LockDetails, now time.Time) (*Lock, error) {
+	token, err := generateToken()
+	if err != nil {
+		return nil, err
+	}
+
+	l := &Lock{
+		Token:     token,
+		Username:  username,
+		Directory: directory,
+		Root:      cleanPath(details.Root),
+		ZeroDepth: details.ZeroDepth,
+		OwnerXML:  details.OwnerXML,
+		CreatedAt: now,
+	}
+	l.refresh(details.Duration, now)
+
+	return l, nil
+}
+
+// generateToken 生成 RFC 4918 opaquelocktoken
+func generateToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate lock token: %w", err)
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+
+	h := hex.EncodeToString(b)
+	return fmt.Sprintf("opaquelocktoken:%s-%s-%s-%s-%s", h[0:8], h[8:12], h[12:16], h[16:20], h[20:32]), nil
+}
+
+// cleanPath 规范化锁路径
+func cleanPath(p string) string {
+	if p == "" || p[0] != '/' {
+		p = "/" + p
+	}
+	return path.Clean(p)
+}
@@ -0,0 +1,286 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/net/webdav"
+)
+
+// Manager WebDAV 锁管理器
+//
+// 客户端 LOCK 请求创建的锁写入 Store，可跨重启和多实例共享；
+// webdav.Handler 为没有 If 头的写请求创建的临时锁仅保存在本进程内，
+// 请求结束即释放，避免进程崩溃后在共享存储中遗留永不过期的锁。
+type Manager struct {
+	store     Store
+	logger    *zap.Logger
+	temporary map[string]*Lock
+	held      map[string]bool
+	mu        sync.Mutex
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+// NewManager 创建锁管理器，sweepInterval 大于 0 时定期清理过期锁
+func NewManager(store Store, sweepInterval time.Duration, logger *zap.Logger) *Manager {
+	m := &Manager{
+		store:     store,
+		logger:    logger,
+		temporary: make(map[string]*Lock),
+		held:      make(map[string]bool),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+
+	if sweepInterval > 0 {
+		go m.sweepLoop(sweepInterval)
+	} else {
+		close(m.done)
+	}
+
+	return m
+}
+
+// ForRequest 返回单个请求使用的 LockSystem
+//
+// persistent 为 true 时（LOCK 请求）创建的锁写入存储，否则为请求内的临时锁。
+func (m *Manager) ForRequest(username, directory string, persistent bool) webdav.LockSystem {
+	return &requestLockSystem{
+		manager:    m,
+		username:   username,
+		directory:  directory,
+		persistent: persistent,
+	}
+}
+
+// List 列出存储中未过期的锁
+func (m *Manager) List(ctx context.Context) ([]*Lock, error) {
+	return m.store.List(ctx, time.Now())
+}
+
+// Remove 强制删除锁
+func (m *Manager) Remove(ctx context.Context, token string) error {
+	return m.store.Delete(ctx, token)
+}
+
+// Close 停止清理任务并关闭存储
+func (m *Manager) Close() error {
+	select {
+	case <-m.stop:
+	default:
+		close(m.stop)
+	}
+	<-m.done
+
+	return m.store.Close()
+}
+
+// sweepLoop 定期清理过期锁
+func (m *Manager) sweepLoop(interval time.Duration) {
+	defer close(m.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.stop:
+			return
+		case now := <-ticker.C:
+			removed, err := m.store.Sweep(context.Background(), now)
+			if err != nil {
+				m.logger.Warn("failed to sweep expired locks", zap.Error(err))
+				continue
+			}
+			if removed > 0 {
+				m.logger.Debug("expired locks swept", zap.Int("count", removed))
+			}
+		}
+	}
+}
+
+// find 查找未过期的锁（先查临时锁，再查存储）
+func (m *Manager) find(token string, now time.Time) (*Lock, error) {
+	if l, ok := m.temporary[token]; ok {
+		return l, nil
+	}
+	return m.store.Get(context.Background(), token, now)
+}
+
+// requestLockSystem 绑定到单个用户请求的 LockSystem
+type requestLockSystem struct {
+	manager    *Manager
+	username   string
+	directory  string
+	persistent bool
+}
+
+// Confirm 确认请求持有覆盖 name0/name1 的锁
+func (ls *requestLockSystem) Confirm(now time.Time, name0, name1 string, conditions ...webdav.Condition) (func(), error) {
+	m := ls.manager
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var token0, token1 string
+	if name0 != "" {
+		if token0 = ls.lookup(cleanPath(name0), now, conditions...); token0 == "" {
+			return nil, webdav.ErrConfirmationFailed
+		}
+	}
+	if name1 != "" {
+		if token1 = ls.lookup(cleanPath(name1), now, conditions...); token1 == "" {
+			return nil, webdav.ErrConfirmationFailed
+		}
+	}
+
+	// 同一把锁只持有一次
+	if token1 == token0 {
+		token1 = ""
+	}
+
+	for _, token := range []string{token0, token1} {
+		if token != "" {
+			m.held[token] = true
+		}
+	}
+
+	return func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		delete(m.held, token0)
+		delete(m.held, token1)
+	}, nil
+}
+
+// lookup 返回条件中覆盖 name 且属于当前用户、未被占用的锁令牌
+func (ls *requestLockSystem) lookup(name string, now time.Time, conditions ...webdav.Condition) string {
+	for _, c := range conditions {
+		if c.Token == "" || ls.manager.held[c.Token] {
+			continue
+		}
+
+		l, err := ls.manager.find(c.Token, now)
+		if err != nil {
+			if !errors.Is(err, ErrLockNotFound) {
+				ls.manager.logger.Warn("failed to look up lock", zap.Error(err))
+			}
+			continue
+		}
+
+		if ls.owns(l) && l.covers(name) {
+			return c.Token
+		}
+	}
+	return ""
+}
+
+// Create 创建锁
+func (ls *requestLockSystem) Create(now time.Time, details webdav.LockDetails) (string, error) {
+	l, err := newLock(ls.username, ls.directory, details, now)
+	if err != nil {
+		return "", err
+	}
+
+	m := ls.manager
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, existing := range m.temporary {
+		if conflicts(l, existing) {
+			return "", webdav.ErrLocked
+		}
+	}
+
+	if ls.persistent {
+		if err := m.store.Create(context.Background(), l, now); err != nil {
+			return "", err
+		}
+		return l.Token, nil
+	}
+
+	// 临时锁不写入存储，但仍需与存储中的锁做冲突检查
+	locks, err := m.store.List(context.Background(), now)
+	if err != nil {
+		return "", err
+	}
+	for _, existing := range locks {
+		if conflicts(l, existing) {
+			return "", webdav.ErrLocked
+		}
+	}
+
+	m.temporary[l.Token] = l
+	return l.Token, nil
+}
+
+// Refresh 刷新锁
+func (ls *requestLockSystem) Refresh(now time.Time, token string, duration time.Duration) (webdav.LockDetails, error) {
+	m := ls.manager
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	l, err := m.find(token, now)
+	if err != nil {
+		return webdav.LockDetails{}, mapStoreError(err)
+	}
+	if !ls.owns(l) {
+		return webdav.LockDetails{}, webdav.ErrNoSuchLock
+	}
+	if m.held[token] {
+		return webdav.LockDetails{}, webdav.ErrLocked
+	}
+
+	if _, ok := m.temporary[token]; ok {
+		l.refresh(duration, now)
+		return l.Details(), nil
+	}
+
+	l, err = m.store.Refresh(context.Background(), token, duration, now)
+	if err != nil {
+		return webdav.LockDetails{}, mapStoreError(err)
+	}
+
+	return l.Details(), nil
+}
+
+// Unlock 释放锁
+func (ls *requestLockSystem) Unlock(now time.Time, token string) error {
+	m := ls.manager
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	l, err := m.find(token, now)
+	if err != nil {
+		return mapStoreError(err)
+	}
+	if !ls.owns(l) {
+		return webdav.ErrForbidden
+	}
+	if m.held[token] {
+		return webdav.ErrLocked
+	}
+
+	if _, ok := m.temporary[token]; ok {
+		delete(m.temporary, token)
+		return nil
+	}
+
+	return mapStoreError(m.store.Delete(context.Background(), token))
+}
+
+// owns 锁是否属于当前用户
+func (ls *requestLockSystem) owns(l *Lock) bool {
+	return l.Username == ls.username && l.Directory == ls.directory
+}
+
+// mapStoreError 将存储错误转换为 webdav 错误
+func mapStoreError(err error) error {
+	if errors.Is(err, ErrLockNotFound) {
+		return webdav.ErrNoSuchLock
+	}
+	return err
+}
@@ -0,0 +1,411 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/yeying-community/webdav/internal/infrastructure/config"
+	"go.uber.org/zap"
+	"golang.org/x/net/webdav"
+)
+
+var testNow = time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// storeFactories 各锁存储的构造函数，每次调用返回一个空存储
+func storeFactories(t *testing.T) map[string]func(t *testing.T) Store {
+	return map[string]func(t *testing.T) Store{
+		"memory": func(t *testing.T) Store {
+			return NewMemoryStore()
+		},
+		"bolt": func(t *testing.T) Store {
+			s, err := NewBoltStore(filepath.Join(t.TempDir(), "locks.db"))
+			if err != nil {
+				t.Fatalf("NewBoltStore: %v", err)
+			}
+			t.Cleanup(func() { s.Close() })
+			return s
+		},
+		"redis": func(t *testing.T) Store {
+			return newTestRedisStore(t, miniredis.RunT(t))
+		},
+	}
+}
+
+func newTestRedisStore(t *testing.T, server *miniredis.Miniredis) *RedisStore {
+	t.Helper()
+
+	s, err := NewRedisStore(config.RedisConfig{Address: server.Addr(), Prefix: "webdav:"})
+	if err != nil {
+		t.Fatalf("NewRedisStore: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func testLock(t *testing.T, username, directory, root string, zeroDepth bool, duration time.Duration) *Lock {
+	t.Helper()
+
+	l, err := newLock(username, directory, webdav.LockDetails{Root: root, ZeroDepth: zeroDepth, Duration: duration}, testNow)
+	if err != nil {
+		t.Fatalf("newLock: %v", err)
+	}
+	return l
+}
+
+func TestStoreConflicts(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing *Lock
+		lock     *Lock
+		wantErr  error
+	}{
+		{
+			name:     "same path",
+			existing: testLock(t, "alice", "/alice", "/a", false, time.Minute),
+			lock:     testLock(t, "alice", "/alice", "/a", false, time.Minute),
+			wantErr:  webdav.ErrLocked,
+		},
+		{
+			name:     "descendant of infinite-depth lock",
+			existing: testLock(t, "alice", "/alice", "/a", false, time.Minute),
+			lock:     testLock(t, "alice", "/alice", "/a/b/c", true, time.Minute),
+			wantErr:  webdav.ErrLocked,
+		},
+		{
+			name:     "ancestor with infinite depth",
+			existing: testLock(t, "alice", "/alice", "/a/b", true, time.Minute),
+			lock:     testLock(t, "alice", "/alice", "/a", false, time.Minute),
+			wantErr:  webdav.ErrLocked,
+		},
+		{
+			name:     "child of zero-depth lock",
+			existing: testLock(t, "alice", "/alice", "/a", true, time.Minute),
+			lock:     testLock(t, "alice", "/alice", "/a/b", true, time.Minute),
+		},
+		{
+			name:     "sibling path",
+			existing: testLock(t, "alice", "/alice", "/a", false, time.Minute),
+			lock:     testLock(t, "alice", "/alice", "/ab", false, time.Minute),
+		},
+		{
+			name:     "users sharing a directory",
+			existing: testLock(t, "alice", "/shared", "/doc", false, time.Minute),
+			lock:     testLock(t, "bob", "/shared", "/doc", false, time.Minute),
+			wantErr:  webdav.ErrLocked,
+		},
+		{
+			name:     "different directories",
+			existing: testLock(t, "alice", "/alice", "/doc", false, time.Minute),
+			lock:     testLock(t, "bob", "/bob", "/doc", false, time.Minute),
+		},
+	}
+
+	for name, newStore := range storeFactories(t) {
+		t.Run(name, func(t *testing.T) {
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+					ctx := context.Background()
+					s := newStore(t)
+
+					if err := s.Create(ctx, tt.existing, testNow); err != nil {
+						t.Fatalf("Create existing: %v", err)
+					}
+					err := s.Create(ctx, tt.lock, testNow)
+					if !errors.Is(err, tt.wantErr) {
+						t.Fatalf("Create = %v, want %v", err, tt.wantErr)
+					}
+				})
+			}
+		})
+	}
+}
+
+func TestStoreExpiryAndRefresh(t *testing.T) {
+	for name, newStore := range storeFactories(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			s := newStore(t)
+
+			l := testLock(t, "alice", "/alice", "/doc", false, 10*time.Second)
+			if err := s.Create(ctx, l, testNow); err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+
+			got, err := s.Get(ctx, l.Token, testNow.Add(5*time.Second))
+			if err != nil {
+				t.Fatalf("Get before expiry: %v", err)
+			}
+			if got.Root != "/doc" || got.Username != "alice" {
+				t.Fatalf("Get = %+v", got)
+			}
+
+			// 刷新后从刷新时间重新计时
+			refreshed, err := s.Refresh(ctx, l.Token, 30*time.Second, testNow.Add(8*time.Second))
+			if err != nil {
+				t.Fatalf("Refresh: %v", err)
+			}
+			if want := testNow.Add(38 * time.Second); !refreshed.ExpiresAt.Equal(want) {
+				t.Fatalf("ExpiresAt = %v, want %v", refreshed.ExpiresAt, want)
+			}
+			if _, err := s.Get(ctx, l.Token, testNow.Add(20*time.Second)); err != nil {
+				t.Fatalf("Get after refresh: %v", err)
+			}
+
+			// 过期后不可见，也不能刷新
+			expired := testNow.Add(38 * time.Second)
+			if _, err := s.Get(ctx, l.Token, expired); !errors.Is(err, ErrLockNotFound) {
+				t.Fatalf("Get after expiry = %v, want ErrLockNotFound", err)
+			}
+			if _, err := s.Refresh(ctx, l.Token, time.Minute, expired); !errors.Is(err, ErrLockNotFound) {
+				t.Fatalf("Refresh after expiry = %v, want ErrLockNotFound", err)
+			}
+			if locks, err := s.List(ctx, expired); err != nil || len(locks) != 0 {
+				t.Fatalf("List after expiry = %v, %v", locks, err)
+			}
+
+			// 过期的锁不再冲突
+			again := testLock(t, "bob", "/alice", "/doc", false, time.Minute)
+			if err := s.Create(ctx, again, expired); err != nil {
+				t.Fatalf("Create over expired lock: %v", err)
+			}
+		})
+	}
+}
+
+func TestStoreInfiniteTimeout(t *testing.T) {
+	for name, newStore := range storeFactories(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			s := newStore(t)
+
+			l := testLock(t, "alice", "/alice", "/doc", false, -1)
+			if err := s.Create(ctx, l, testNow); err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+			if _, err := s.Get(ctx, l.Token, testNow.Add(24*365*time.Hour)); err != nil {
+				t.Fatalf("Get = %v, lock without timeout should not expire", err)
+			}
+		})
+	}
+}
+
+func TestStoreDeleteListSweep(t *testing.T) {
+	for name, newStore := range storeFactories(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			s := newStore(t)
+
+			short := testLock(t, "alice", "/alice", "/short", false, time.Second)
+			long := testLock(t, "alice", "/alice", "/long", false, time.Hour)
+			other := testLock(t, "alice", "/alice", "/other", false, time.Hour)
+			for _, l := range []*Lock{short, long, other} {
+				if err := s.Create(ctx, l, testNow); err != nil {
+					t.Fatalf("Create %s: %v", l.Root, err)
+				}
+			}
+
+			if err := s.Delete(ctx, other.Token); err != nil {
+				t.Fatalf("Delete: %v", err)
+			}
+			if err := s.Delete(ctx, other.Token); !errors.Is(err, ErrLockNotFound) {
+				t.Fatalf("second Delete = %v, want ErrLockNotFound", err)
+			}
+
+			later := testNow.Add(time.Minute)
+			locks, err := s.List(ctx, later)
+			if err != nil {
+				t.Fatalf("List: %v", err)
+			}
+			if len(locks) != 1 || locks[0].Token != long.Token {
+				t.Fatalf("List = %v, want only the long lock", locks)
+			}
+
+			removed, err := s.Sweep(ctx, later)
+			if err != nil {
+				t.Fatalf("Sweep: %v", err)
+			}
+			if removed != 1 {
+				t.Fatalf("Sweep removed %d, want 1", removed)
+			}
+			if removed, _ := s.Sweep(ctx, later); removed != 0 {
+				t.Fatalf("second Sweep removed %d, want 0", removed)
+			}
+		})
+	}
+}
+
+func TestBoltStorePersistsAcrossRestart(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "locks.db")
+
+	s, err := NewBoltStore(path)
+	if err != nil {
+		t.Fatalf("NewBoltStore: %v", err)
+	}
+	l := testLock(t, "alice", "/alice", "/doc", false, time.Hour)
+	if err := s.Create(ctx, l, testNow); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	reopened, err := NewBoltStore(path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer reopened.Close()
+
+	got, err := reopened.Get(ctx, l.Token, testNow.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("Get after reopen: %v", err)
+	}
+	if got.Root != "/doc" || !got.ExpiresAt.Equal(l.ExpiresAt) {
+		t.Fatalf("Get after reopen = %+v", got)
+	}
+}
+
+func TestRedisStoreSharedBetweenInstances(t *testing.T) {
+	ctx := context.Background()
+	server := miniredis.RunT(t)
+
+	// 两个实例并发锁定同一路径，只有一个成功
+	const instances = 8
+	stores := make([]*RedisStore, instances)
+	for i := range stores {
+		stores[i] = newTestRedisStore(t, server)
+	}
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		succeeded int
+	)
+	for _, s := range stores {
+		wg.Add(1)
+		go func(s *RedisStore) {
+			defer wg.Done()
+			err := s.Create(ctx, testLock(t, "alice", "/alice", "/doc", false, time.Minute), testNow)
+			if err == nil {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			} else if !errors.Is(err, webdav.ErrLocked) {
+				t.Errorf("Create: %v", err)
+			}
+		}(s)
+	}
+	wg.Wait()
+
+	if succeeded != 1 {
+		t.Fatalf("%d instances acquired the lock, want 1", succeeded)
+	}
+
+	// 另一个实例可以看到并释放该锁
+	locks, err := stores[0].List(ctx, testNow)
+	if err != nil || len(locks) != 1 {
+		t.Fatalf("List = %v, %v", locks, err)
+	}
+	if err := stores[1].Delete(ctx, locks[0].Token); err != nil {
+		t.Fatalf("Delete from another instance: %v", err)
+	}
+}
+
+func TestNewRedisStoreUnavailable(t *testing.T) {
+	server := miniredis.RunT(t)
+	addr := server.Addr()
+	server.Close()
+
+	if _, err := NewRedisStore(config.RedisConfig{Address: addr}); err == nil {
+		t.Fatal("NewRedisStore should fail when the server is unreachable")
+	}
+}
+
+func TestManagerLockLifecycle(t *testing.T) {
+	store := NewMemoryStore()
+	m := NewManager(store, 0, zap.NewNop())
+	defer m.Close()
+
+	alice := m.ForRequest("alice", "/alice", true)
+	bob := m.ForRequest("bob", "/alice", true)
+
+	token, err := alice.Create(testNow, webdav.LockDetails{Root: "/doc", Duration: time.Minute})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	// 其他用户不能加冲突的锁，也不能刷新或释放别人的锁
+	if _, err := bob.Create(testNow, webdav.LockDetails{Root: "/doc/a", Duration: time.Minute}); !errors.Is(err, webdav.ErrLocked) {
+		t.Fatalf("conflicting Create = %v, want ErrLocked", err)
+	}
+	if _, err := bob.Refresh(testNow, token, time.Hour); !errors.Is(err, webdav.ErrNoSuchLock) {
+		t.Fatalf("Refresh by other user = %v, want ErrNoSuchLock", err)
+	}
+	if err := bob.Unlock(testNow, token); !errors.Is(err, webdav.ErrForbidden) {
+		t.Fatalf("Unlock by other user = %v, want ErrForbidden", err)
+	}
+
+	// 持有者确认锁后才能写入
+	if _, err := alice.Confirm(testNow, "/doc/a", ""); !errors.Is(err, webdav.ErrConfirmationFailed) {
+		t.Fatalf("Confirm without token = %v, want ErrConfirmationFailed", err)
+	}
+	release, err := alice.Confirm(testNow, "/doc/a", "", webdav.Condition{Token: token})
+	if err != nil {
+		t.Fatalf("Confirm: %v", err)
+	}
+	release()
+	if _, err := bob.Confirm(testNow, "/doc/a", "", webdav.Condition{Token: token}); !errors.Is(err, webdav.ErrConfirmationFailed) {
+		t.Fatalf("Confirm by other user = %v, want ErrConfirmationFailed", err)
+	}
+
+	// 刷新后按新的超时时间过期
+	if _, err := alice.Refresh(testNow.Add(50*time.Second), token, time.Minute); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if _, err := store.Get(context.Background(), token, testNow.Add(100*time.Second)); err != nil {
+		t.Fatalf("lock expired before refreshed timeout: %v", err)
+	}
+	if _, err := alice.Refresh(testNow.Add(3*time.Minute), token, time.Minute); !errors.Is(err, webdav.ErrNoSuchLock) {
+		t.Fatalf("Refresh after expiry = %v, want ErrNoSuchLock", err)
+	}
+
+	// 过期后其他用户可以加锁
+	if _, err := bob.Create(testNow.Add(3*time.Minute), webdav.LockDetails{Root: "/doc", Duration: time.Minute}); err != nil {
+		t.Fatalf("Create after expiry: %v", err)
+	}
+}
+
+func TestManagerTemporaryLocks(t *testing.T) {
+	store := NewMemoryStore()
+	m := NewManager(store, 0, zap.NewNop())
+	defer m.Close()
+
+	// 写请求的临时锁不写入存储，但与存储中的锁冲突
+	temporary := m.ForRequest("alice", "/alice", false)
+	token, err := temporary.Create(testNow, webdav.LockDetails{Root: "/doc", Duration: -1})
+	if err != nil {
+		t.Fatalf("Create temporary: %v", err)
+	}
+	if locks, _ := store.List(context.Background(), testNow); len(locks) != 0 {
+		t.Fatalf("temporary lock persisted: %v", locks)
+	}
+	if _, err := m.ForRequest("bob", "/alice", true).Create(testNow, webdav.LockDetails{Root: "/doc", Duration: time.Minute}); !errors.Is(err, webdav.ErrLocked) {
+		t.Fatalf("Create over temporary lock = %v, want ErrLocked", err)
+	}
+	if err := temporary.Unlock(testNow, token); err != nil {
+		t.Fatalf("Unlock temporary: %v", err)
+	}
+
+	persistent := m.ForRequest("bob", "/alice", true)
+	if _, err := persistent.Create(testNow, webdav.LockDetails{Root: "/doc", Duration: time.Minute}); err != nil {
+		t.Fatalf("Create persistent: %v", err)
+	}
+	if _, err := temporary.Create(testNow, webdav.LockDetails{Root: "/doc/a", Duration: -1}); !errors.Is(err, webdav.ErrLocked) {
+		t.Fatalf("temporary Create over persistent lock = %v, want ErrLocked", err)
+	}
+}
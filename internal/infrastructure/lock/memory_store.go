@@ -0,0 +1,123 @@
+package lock
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"golang.org/x/net/webdav"
+)
+
+// MemoryStore 内存锁存储（单实例，重启后丢失）
+type MemoryStore struct {
+	locks map[string]*Lock
+	mu    sync.Mutex
+}
+
+// NewMemoryStore 创建内存锁存储
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		locks: make(map[string]*Lock),
+	}
+}
+
+// Create 创建锁
+func (s *MemoryStore) Create(ctx context.Context, lock *Lock, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for token, existing := range s.locks {
+		if existing.Expired(now) {
+			delete(s.locks, token)
+			continue
+		}
+		if conflicts(lock, existing) {
+			return webdav.ErrLocked
+		}
+	}
+
+	copied := *lock
+	s.locks[lock.Token] = &copied
+	return nil
+}
+
+// Get 获取锁
+func (s *MemoryStore) Get(ctx context.Context, token string, now time.Time) (*Lock, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	lock, ok := s.locks[token]
+	if !ok || lock.Expired(now) {
+		return nil, ErrLockNotFound
+	}
+
+	copied := *lock
+	return &copied, nil
+}
+
+// Refresh 刷新锁
+func (s *MemoryStore) Refresh(ctx context.Context, token string, duration time.Duration, now time.Time) (*Lock, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	lock, ok := s.locks[token]
+	if !ok || lock.Expired(now) {
+		return nil, ErrLockNotFound
+	}
+
+	lock.refresh(duration, now)
+
+	copied := *lock
+	return &copied, nil
+}
+
+// Delete 删除锁
+func (s *MemoryStore) Delete(ctx context.Context, token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.locks[token]; !ok {
+		return ErrLockNotFound
+	}
+
+	delete(s.locks, token)
+	return nil
+}
+
+// List 列出锁
+func (s *MemoryStore) List(ctx context.Context, now time.Time) ([]*Lock, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	locks := make([]*Lock, 0, len(s.locks))
+	for _, lock := range s.locks {
+		if lock.Expired(now) {
+			continue
+		}
+		copied := *lock
+		locks = append(locks, &copied)
+	}
+
+	return locks, nil
+}
+
+// Sweep 清理过期锁
+func (s *MemoryStore) Sweep(ctx context.Context, now time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	removed := 0
+	for token, lock := range s.locks {
+		if lock.Expired(now) {
+			delete(s.locks, token)
+			removed++
+		}
+	}
+
+	return removed, nil
+}
+
+// Close 关闭存储
+func (s *MemoryStore) Close() error {
+	return nil
+}
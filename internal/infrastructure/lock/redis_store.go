@@ -0,0 +1,239 @@
+package lock
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/yeying-community/webdav/internal/infrastructure/config"
+	"golang.org/x/net/webdav"
+)
+
+// redisMaxRetries 乐观事务冲突时的最大重试次数
+const redisMaxRetries = 16
+
+// RedisStore Redis 协议共享锁存储（多实例共享）
+//
+// 所有锁保存在同一个 hash 中，写操作使用 WATCH/MULTI 乐观事务，
+// 保证多个实例并发创建锁时冲突检查与写入的原子性。
+type RedisStore struct {
+	client *redis.Client
+	key    string
+}
+
+// NewRedisStore 创建 Redis 锁存储
+func NewRedisStore(cfg config.RedisConfig) (*RedisStore, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr:     cfg.Address,
+		Password: cfg.Password,
+		DB:       cfg.DB,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to connect to redis: %w", err)
+	}
+
+	return &RedisStore{
+		client: client,
+		key:    cfg.Prefix + "locks",
+	}, nil
+}
+
+// Create 创建锁
+func (s *RedisStore) Create(ctx context.Context, lock *Lock, now time.Time) error {
+	data, err := json.Marshal(lock)
+	if err != nil {
+		return fmt.Errorf("failed to encode lock: %w", err)
+	}
+
+	return s.transaction(ctx, func(tx *redis.Tx) error {
+		locks, err := s.load(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		var expired []string
+		for _, existing := range locks {
+			if existing.Expired(now) {
+				expired = append(expired, existing.Token)
+				continue
+			}
+			if conflicts(lock, existing) {
+				return webdav.ErrLocked
+			}
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			if len(expired) > 0 {
+				pipe.HDel(ctx, s.key, expired...)
+			}
+			pipe.HSet(ctx, s.key, lock.Token, data)
+			return nil
+		})
+		return err
+	})
+}
+
+// Get 获取锁
+func (s *RedisStore) Get(ctx context.Context, token string, now time.Time) (*Lock, error) {
+	data, err := s.client.HGet(ctx, s.key, token).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, ErrLockNotFound
+		}
+		return nil, fmt.Errorf("failed to get lock: %w", err)
+	}
+
+	lock, err := decodeLock(data)
+	if err != nil {
+		return nil, err
+	}
+	if lock.Expired(now) {
+		return nil, ErrLockNotFound
+	}
+
+	return lock, nil
+}
+
+// Refresh 刷新锁
+func (s *RedisStore) Refresh(ctx context.Context, token string, duration time.Duration, now time.Time) (*Lock, error) {
+	var lock *Lock
+	err := s.transaction(ctx, func(tx *redis.Tx) error {
+		data, err := tx.HGet(ctx, s.key, token).Bytes()
+		if err != nil {
+			if errors.Is(err, redis.Nil) {
+				return ErrLockNotFound
+			}
+			return err
+		}
+
+		lock, err = decodeLock(data)
+		if err != nil {
+			return err
+		}
+		if lock.Expired(now) {
+			return ErrLockNotFound
+		}
+
+		lock.refresh(duration, now)
+		updated, err := json.Marshal(lock)
+		if err != nil {
+			return fmt.Errorf("failed to encode lock: %w", err)
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.HSet(ctx, s.key, token, updated)
+			return nil
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return lock, nil
+}
+
+// Delete 删除锁
+func (s *RedisStore) Delete(ctx context.Context, token string) error {
+	removed, err := s.client.HDel(ctx, s.key, token).Result()
+	if err != nil {
+		return fmt.Errorf("failed to delete lock: %w", err)
+	}
+	if removed == 0 {
+		return ErrLockNotFound
+	}
+	return nil
+}
+
+// List 列出锁
+func (s *RedisStore) List(ctx context.Context, now time.Time) ([]*Lock, error) {
+	locks, err := s.load(ctx, s.client)
+	if err != nil {
+		return nil, err
+	}
+
+	active := make([]*Lock, 0, len(locks))
+	for _, lock := range locks {
+		if !lock.Expired(now) {
+			active = append(active, lock)
+		}
+	}
+
+	return active, nil
+}
+
+// Sweep 清理过期锁
+func (s *RedisStore) Sweep(ctx context.Context, now time.Time) (int, error) {
+	removed := 0
+	err := s.transaction(ctx, func(tx *redis.Tx) error {
+		locks, err := s.load(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		var expired []string
+		for _, lock := range locks {
+			if lock.Expired(now) {
+				expired = append(expired, lock.Token)
+			}
+		}
+		if len(expired) == 0 {
+			return nil
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.HDel(ctx, s.key, expired...)
+			return nil
+		})
+		if err == nil {
+			removed = len(expired)
+		}
+		return err
+	})
+
+	return removed, err
+}
+
+// Close 关闭存储
+func (s *RedisStore) Close() error {
+	return s.client.Close()
+}
+
+// transaction 在 WATCH 锁 hash 的乐观事务中执行 fn，冲突时重试
+func (s *RedisStore) transaction(ctx context.Context, fn func(tx *redis.Tx) error) error {
+	for i := 0; i < redisMaxRetries; i++ {
+		err := s.client.Watch(ctx, fn, s.key)
+		if errors.Is(err, redis.TxFailedErr) {
+			continue
+		}
+		return err
+	}
+	return errors.New("lock transaction failed: too many concurrent updates")
+}
+
+// load 读取全部锁
+func (s *RedisStore) load(ctx context.Context, cmd redis.Cmdable) ([]*Lock, error) {
+	values, err := cmd.HGetAll(ctx, s.key).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list locks: %w", err)
+	}
+
+	locks := make([]*Lock, 0, len(values))
+	for _, data := range values {
+		lock, err := decodeLock([]byte(data))
+		if err != nil {
+			return nil, err
+		}
+		locks = append(locks, lock)
+	}
+
+	return locks, nil
+}
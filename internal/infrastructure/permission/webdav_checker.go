@@ -4,31 +4,70 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 
 	"github.com/yeying-community/webdav/internal/domain/permission"
 	"github.com/yeying-community/webdav/internal/domain/user"
+	"github.com/yeying-community/webdav/internal/infrastructure/storage"
 	"go.uber.org/zap"
-	"golang.org/x/net/webdav"
 )
 
+// TokenGateVerifier 查询钱包是否满足规则的链上持有条件
+type TokenGateVerifier interface {
+	Holds(ctx context.Context, address string, gate *user.TokenGate) (bool, error)
+}
+
 // WebDAVChecker WebDAV 权限检查器
 type WebDAVChecker struct {
-	fileSystem webdav.FileSystem
+	storage    storage.Driver
+	prefix     string
+	tokenGates TokenGateVerifier
 	logger     *zap.Logger
 }
 
 // NewWebDAVChecker 创建 WebDAV 权限检查器
-func NewWebDAVChecker(fileSystem webdav.FileSystem, logger *zap.Logger) *WebDAVChecker {
+//
+// prefix 为 WebDAV 路由前缀，检查前会从请求路径中去除，使规则基于用户目录内的路径匹配。
+// tokenGates 为 nil 时带有链上持有条件的规则不生效。
+func NewWebDAVChecker(storageDriver storage.Driver, prefix string, tokenGates TokenGateVerifier, logger *zap.Logger) *WebDAVChecker {
 	return &WebDAVChecker{
-		fileSystem: fileSystem,
+		storage:    storageDriver,
+		prefix:     "/" + strings.Trim(prefix, "/"),
+		tokenGates: tokenGates,
 		logger:     logger,
 	}
 }
 
 // Check 检查权限
 func (c *WebDAVChecker) Check(ctx context.Context, u *user.User, path string, op permission.Operation) error {
+	if err := c.CheckRules(ctx, u, path, op); err != nil {
+		return err
+	}
+
+	// 规范化路径
+	path = c.normalizePath(path)
+
+	// 对于创建和写入操作，检查父目录是否存在
+	if op == permission.OperationCreate || op == permission.OperationWrite {
+		if err := c.checkParentDirectory(ctx, u, path); err != nil {
+			return err
+		}
+	}
+
+	c.logger.Debug("permission granted",
+		zap.String("username", u.Username),
+		zap.String("path", path),
+		zap.String("operation", string(op)))
+
+	return nil
+}
+
+// CheckRules 只按用户的规则检查权限
+//
+// 用于 COPY/MOVE 集合中的子路径，目标子路径的父目录在复制过程中才会创建。
+func (c *WebDAVChecker) CheckRules(ctx context.Context, u *user.User, path string, op permission.Operation) error {
 	// 规范化路径
 	path = c.normalizePath(path)
 
@@ -36,7 +75,7 @@ func (c *WebDAVChecker) Check(ctx context.Context, u *user.User, path string, op
 	perm := permission.MapOperationToPermission(op)
 
 	// 检查用户是否有权限
-	if !u.CanAccess(path, perm) {
+	if !u.CanAccessWith(path, perm, c.holds(ctx, u)) {
 		c.logger.Warn("permission denied",
 			zap.String("username", u.Username),
 			zap.String("path", path),
@@ -46,30 +85,56 @@ func (c *WebDAVChecker) Check(ctx context.Context, u *user.User, path string, op
 		return fmt.Errorf("permission denied: %s operation on %s", op, path)
 	}
 
-	// 对于创建和写入操作，检查父目录是否存在
-	if op == permission.OperationCreate || op == permission.OperationWrite {
-		if err := c.checkParentDirectory(path); err != nil {
-			return err
+	return nil
+}
+
+// holds 返回判断用户钱包是否满足持有条件的函数
+//
+// 只有以太坊钱包可以满足持有条件，任意一个关联的钱包满足即可；查询失败时视为不满足。
+func (c *WebDAVChecker) holds(ctx context.Context, u *user.User) func(*user.TokenGate) bool {
+	var addresses []string
+	for _, address := range u.WalletAddresses() {
+		if user.IsValidAddress(address) {
+			addresses = append(addresses, address)
 		}
 	}
+	if c.tokenGates == nil || len(addresses) == 0 {
+		return nil
+	}
 
-	c.logger.Debug("permission granted",
-		zap.String("username", u.Username),
-		zap.String("path", path),
-		zap.String("operation", string(op)))
-
-	return nil
+	return func(gate *user.TokenGate) bool {
+		for _, address := range addresses {
+			ok, err := c.tokenGates.Holds(ctx, address, gate)
+			if err != nil {
+				c.logger.Warn("token gate check failed",
+					zap.String("username", u.Username),
+					zap.String("address", address),
+					zap.String("contract", gate.Contract),
+					zap.Error(err))
+				continue
+			}
+			if ok {
+				return true
+			}
+		}
+		return false
+	}
 }
 
 // checkParentDirectory 检查父目录是否存在
-func (c *WebDAVChecker) checkParentDirectory(path string) error {
+func (c *WebDAVChecker) checkParentDirectory(ctx context.Context, u *user.User, path string) error {
 	dir := filepath.Dir(path)
 	if dir == "." || dir == "/" {
 		return nil
 	}
 
+	fileSystem, err := c.storage.FileSystem(ctx, u.Directory)
+	if err != nil {
+		return fmt.Errorf("failed to open file system: %w", err)
+	}
+
 	// 检查父目录是否存在
-	info, err := c.fileSystem.Stat(context.Background(), dir)
+	info, err := fileSystem.Stat(ctx, dir)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return fmt.Errorf("parent directory does not exist: %s", dir)
@@ -85,14 +150,19 @@ func (c *WebDAVChecker) checkParentDirectory(path string) error {
 }
 
 // normalizePath 规范化路径
-func (c *WebDAVChecker) normalizePath(path string) string {
-	// 移除前导和尾随斜杠
-	path = strings.Trim(path, "/")
-
-	// 确保以 / 开头
-	if !strings.HasPrefix(path, "/") {
-		path = "/" + path
+//
+// 先解析 . 和 .. 段再去除前缀，规则始终基于文件系统实际访问的路径匹配。
+func (c *WebDAVChecker) normalizePath(p string) string {
+	p = path.Clean("/" + p)
+
+	// 移除 WebDAV 前缀
+	if c.prefix != "/" {
+		if p == c.prefix {
+			p = "/"
+		} else if strings.HasPrefix(p, c.prefix+"/") {
+			p = strings.TrimPrefix(p, c.prefix)
+		}
 	}
 
-	return path
+	return p
 }
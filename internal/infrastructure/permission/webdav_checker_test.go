@@ -0,0 +1,53 @@
+package permission
+
+import (
+	"context"
+	"testing"
+
+	"github.com/yeying-community/webdav/internal/domain/permission"
+	"github.com/yeying-community/webdav/internal/domain/user"
+	"github.com/yeying-community/webdav/internal/infrastructure/storage"
+	"go.uber.org/zap"
+)
+
+func TestNormalizePath(t *testing.T) {
+	tests := []struct {
+		prefix string
+		path   string
+		want   string
+	}{
+		{prefix: "/dav", path: "/dav", want: "/"},
+		{prefix: "/dav", path: "/dav/", want: "/"},
+		{prefix: "/dav", path: "/dav/a/b/", want: "/a/b"},
+		{prefix: "/dav", path: "/dav/public/../private/f", want: "/private/f"},
+		{prefix: "/dav", path: "/dav/./a//b", want: "/a/b"},
+		// 解析后离开前缀的路径不能被当作前缀内的路径
+		{prefix: "/dav", path: "/dav/../dav2/f", want: "/dav2/f"},
+		{prefix: "/dav", path: "/davx/f", want: "/davx/f"},
+		{prefix: "/", path: "/a/../../b", want: "/b"},
+		{prefix: "/", path: "a/b", want: "/a/b"},
+	}
+
+	for _, tt := range tests {
+		c := NewWebDAVChecker(storage.NewMemoryDriver(), tt.prefix, nil, zap.NewNop())
+		if got := c.normalizePath(tt.path); got != tt.want {
+			t.Errorf("normalizePath(%q) with prefix %q = %q, want %q", tt.path, tt.prefix, got, tt.want)
+		}
+	}
+}
+
+func TestCheckRulesResolvesDotSegments(t *testing.T) {
+	u := user.NewUser("alice", "/alice")
+	u.Permissions = user.FullPermissions()
+	u.Rules = []*user.Rule{{Path: "/private", Permissions: user.ParsePermissions("R")}}
+
+	c := NewWebDAVChecker(storage.NewMemoryDriver(), "/dav", nil, zap.NewNop())
+	ctx := context.Background()
+
+	if err := c.CheckRules(ctx, u, "/dav/public/../private/f", permission.OperationCreate); err == nil {
+		t.Fatal("create under /private via .. should be denied")
+	}
+	if err := c.CheckRules(ctx, u, "/dav/private/../public/f", permission.OperationCreate); err != nil {
+		t.Fatalf("create under /public via .. should be allowed: %v", err)
+	}
+}
@@ -0,0 +1,98 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// bucketIdleTimeout 令牌桶空闲多久后回收（不短于补满所需时间）
+const bucketIdleTimeout = 10 * time.Minute
+
+// Limiter 按键划分的令牌桶限流器
+type Limiter struct {
+	rate  float64 // 每秒补充的令牌数
+	burst float64
+	idle  time.Duration
+
+	buckets map[string]*bucket
+	mu      sync.Mutex
+	done    chan struct{}
+}
+
+// bucket 令牌桶
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewLimiter 创建限流器，rate 为每秒请求数，burst 为桶容量
+func NewLimiter(rate float64, burst int) *Limiter {
+	l := &Limiter{
+		rate:    rate,
+		burst:   float64(burst),
+		idle:    bucketIdleTimeout,
+		buckets: make(map[string]*bucket),
+		done:    make(chan struct{}),
+	}
+	// 空闲时间至少要够把桶补满，回收才不影响限流结果
+	if refill := time.Duration(float64(burst) / rate * float64(time.Second)); refill > l.idle {
+		l.idle = refill
+	}
+
+	// 启动清理协程
+	go l.cleanupLoop()
+
+	return l
+}
+
+// Allow 消耗一个令牌；令牌不足时返回 false 和需要等待的时间
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+
+	wait := time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	return false, wait
+}
+
+// Close 停止清理协程
+func (l *Limiter) Close() {
+	close(l.done)
+}
+
+// cleanupLoop 定期回收空闲的令牌桶
+func (l *Limiter) cleanupLoop() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			l.mu.Lock()
+			now := time.Now()
+			for key, b := range l.buckets {
+				if now.Sub(b.last) > l.idle {
+					delete(l.buckets, key)
+				}
+			}
+			l.mu.Unlock()
+		case <-l.done:
+			return
+		}
+	}
+}
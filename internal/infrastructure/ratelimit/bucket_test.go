@@ -0,0 +1,58 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiterAllow(t *testing.T) {
+	l := NewLimiter(2, 3)
+	defer l.Close()
+
+	// 桶满时允许 burst 个请求
+	for i := 0; i < 3; i++ {
+		if ok, _ := l.Allow("user:alice"); !ok {
+			t.Fatalf("request %d should be allowed", i+1)
+		}
+	}
+
+	ok, wait := l.Allow("user:alice")
+	if ok {
+		t.Fatal("request beyond burst should be rejected")
+	}
+	// 每秒补充 2 个令牌，约需等待 0.5 秒
+	if wait <= 400*time.Millisecond || wait > 500*time.Millisecond {
+		t.Fatalf("wait = %v, want about 500ms", wait)
+	}
+
+	// 各个键的令牌桶相互独立
+	if ok, _ := l.Allow("user:bob"); !ok {
+		t.Fatal("another key should have its own bucket")
+	}
+}
+
+func TestLimiterRefill(t *testing.T) {
+	l := NewLimiter(100, 1)
+	defer l.Close()
+
+	if ok, _ := l.Allow("ip:10.0.0.1"); !ok {
+		t.Fatal("first request should be allowed")
+	}
+	if ok, _ := l.Allow("ip:10.0.0.1"); ok {
+		t.Fatal("second request should be rejected")
+	}
+	time.Sleep(20 * time.Millisecond)
+	if ok, _ := l.Allow("ip:10.0.0.1"); !ok {
+		t.Fatal("request after refill should be allowed")
+	}
+}
+
+func TestLimiterIdleCoversRefill(t *testing.T) {
+	// 补满需要 1000 秒，空闲回收时间不能更短
+	l := NewLimiter(0.1, 100)
+	defer l.Close()
+
+	if l.idle < 1000*time.Second {
+		t.Fatalf("idle = %v, want at least the refill time", l.idle)
+	}
+}
@@ -0,0 +1,139 @@
+package ratelimit
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// Throttle 认证失败计数器
+//
+// 按键（IP 或用户名）统计连续失败次数，达到阈值后锁定，锁定时长按指数增长。
+// 锁定期间的请求直接拒绝，不再执行密码校验。
+type Throttle struct {
+	threshold  int
+	baseDelay  time.Duration
+	maxDelay   time.Duration
+	resetAfter time.Duration
+
+	entries map[string]*failureEntry
+	mu      sync.Mutex
+	done    chan struct{}
+}
+
+// failureEntry 单个键的失败记录
+type failureEntry struct {
+	failures    int
+	lastFailure time.Time
+	lockedUntil time.Time
+}
+
+// NewThrottle 创建认证失败计数器
+func NewThrottle(threshold int, baseDelay, maxDelay, resetAfter time.Duration) *Throttle {
+	t := &Throttle{
+		threshold:  threshold,
+		baseDelay:  baseDelay,
+		maxDelay:   maxDelay,
+		resetAfter: resetAfter,
+		entries:    make(map[string]*failureEntry),
+		done:       make(chan struct{}),
+	}
+
+	// 启动清理协程
+	go t.cleanupLoop()
+
+	return t
+}
+
+// Locked 返回键的剩余锁定时间，未锁定时返回 0
+func (t *Throttle) Locked(key string) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	entry, ok := t.entries[normalizeKey(key)]
+	if !ok {
+		return 0
+	}
+	if remaining := time.Until(entry.lockedUntil); remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
+// Fail 记录一次失败，返回由此产生的锁定时间
+func (t *Throttle) Fail(key string) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	key = normalizeKey(key)
+
+	entry, ok := t.entries[key]
+	if !ok || now.Sub(entry.lastFailure) > t.resetAfter {
+		entry = &failureEntry{}
+		t.entries[key] = entry
+	}
+	entry.failures++
+	entry.lastFailure = now
+
+	if entry.failures < t.threshold {
+		return 0
+	}
+
+	delay := t.baseDelay
+	for i := t.threshold; i < entry.failures && delay < t.maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > t.maxDelay {
+		delay = t.maxDelay
+	}
+	entry.lockedUntil = now.Add(delay)
+
+	return delay
+}
+
+// Reset 清除键的失败记录
+func (t *Throttle) Reset(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.entries, normalizeKey(key))
+}
+
+// Close 停止清理协程
+func (t *Throttle) Close() {
+	close(t.done)
+}
+
+// cleanupLoop 定期清理过期的失败记录
+func (t *Throttle) cleanupLoop() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			t.cleanup()
+		case <-t.done:
+			return
+		}
+	}
+}
+
+// cleanup 清理已解锁且超过 reset_after 没有失败的记录
+func (t *Throttle) cleanup() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	for key, entry := range t.entries {
+		if now.After(entry.lockedUntil) && now.Sub(entry.lastFailure) > t.resetAfter {
+			delete(t.entries, key)
+		}
+	}
+}
+
+// normalizeKey 用户名不区分大小写
+func normalizeKey(key string) string {
+	return strings.ToLower(key)
+}
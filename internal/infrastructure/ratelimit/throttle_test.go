@@ -0,0 +1,74 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestThrottleBackoff(t *testing.T) {
+	th := NewThrottle(3, time.Minute, 8*time.Minute, time.Hour)
+	defer th.Close()
+
+	// 达到阈值后锁定时间从 base_delay 开始翻倍，不超过 max_delay
+	want := []time.Duration{0, 0, time.Minute, 2 * time.Minute, 4 * time.Minute, 8 * time.Minute, 8 * time.Minute}
+	for i, w := range want {
+		if got := th.Fail("alice"); got != w {
+			t.Fatalf("failure %d: delay = %v, want %v", i+1, got, w)
+		}
+	}
+
+	locked := th.Locked("alice")
+	if locked <= 7*time.Minute || locked > 8*time.Minute {
+		t.Fatalf("Locked = %v, want about 8m", locked)
+	}
+	// 用户名不区分大小写
+	if th.Locked("ALICE") == 0 {
+		t.Fatal("keys should be case-insensitive")
+	}
+	if th.Locked("bob") != 0 {
+		t.Fatal("other keys should not be locked")
+	}
+
+	th.Reset("Alice")
+	if th.Locked("alice") != 0 {
+		t.Fatal("Reset should unlock the key")
+	}
+	if got := th.Fail("alice"); got != 0 {
+		t.Fatalf("first failure after Reset: delay = %v, want 0", got)
+	}
+}
+
+func TestThrottleResetAfter(t *testing.T) {
+	th := NewThrottle(2, time.Minute, time.Hour, 20*time.Millisecond)
+	defer th.Close()
+
+	th.Fail("10.0.0.1")
+	time.Sleep(40 * time.Millisecond)
+
+	// 超过 reset_after 没有失败，计数重新开始
+	if got := th.Fail("10.0.0.1"); got != 0 {
+		t.Fatalf("delay = %v, want 0 after reset_after", got)
+	}
+	if got := th.Fail("10.0.0.1"); got != time.Minute {
+		t.Fatalf("delay = %v, want 1m", got)
+	}
+}
+
+func TestThrottleCleanup(t *testing.T) {
+	th := NewThrottle(1, 10*time.Millisecond, 10*time.Millisecond, 10*time.Millisecond)
+	defer th.Close()
+
+	th.Fail("expired")
+	time.Sleep(30 * time.Millisecond)
+	th.Fail("recent")
+	th.cleanup()
+
+	th.mu.Lock()
+	defer th.mu.Unlock()
+	if _, ok := th.entries["expired"]; ok {
+		t.Fatal("expired entry should be removed")
+	}
+	if _, ok := th.entries["recent"]; !ok {
+		t.Fatal("recent entry should be kept")
+	}
+}
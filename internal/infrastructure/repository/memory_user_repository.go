@@ -2,7 +2,6 @@ package repository
 
 import (
 	"context"
-	"strings"
 	"sync"
 	
 	"github.com/yeying-community/webdav/internal/domain/user"
@@ -13,7 +12,8 @@ import (
 // MemoryUserRepository 内存用户仓储
 type MemoryUserRepository struct {
 	users           map[string]*user.User // username -> user
-	walletAddresses map[string]*user.User // wallet_address -> user
+	walletAddresses map[string]*user.User // 主钱包和关联钱包地址 -> user
+	totpSteps       map[string]int64      // user ID -> 最近一次通过验证的 TOTP 时间步
 	mu              sync.RWMutex
 	passwordHasher  *crypto.PasswordHasher
 }
@@ -23,16 +23,17 @@ func NewMemoryUserRepository(userConfigs []config.UserConfig) *MemoryUserReposit
 	repo := &MemoryUserRepository{
 		users:           make(map[string]*user.User),
 		walletAddresses: make(map[string]*user.User),
+		totpSteps:       make(map[string]int64),
 		passwordHasher:  crypto.NewPasswordHasher(),
 	}
 	
 	// 加载用户配置
 	for _, cfg := range userConfigs {
-		u := repo.createUserFromConfig(cfg)
+		u := newUserFromConfig(cfg, repo.passwordHasher)
 		repo.users[u.Username] = u
 		
-		if u.HasWalletAddress() {
-			repo.walletAddresses[strings.ToLower(u.WalletAddress)] = u
+		for _, address := range u.WalletAddresses() {
+			repo.walletAddresses[address] = u
 		}
 	}
 	
@@ -57,7 +58,7 @@ func (r *MemoryUserRepository) FindByWalletAddress(ctx context.Context, address
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 	
-	address = strings.ToLower(address)
+	address = user.NormalizeWalletID(address)
 	u, ok := r.walletAddresses[address]
 	if !ok {
 		return nil, user.ErrUserNotFound
@@ -66,6 +67,36 @@ func (r *MemoryUserRepository) FindByWalletAddress(ctx context.Context, address
 	return u, nil
 }
 
+// FindByAppPassword 根据应用密码摘要查找用户
+func (r *MemoryUserRepository) FindByAppPassword(ctx context.Context, hash string) (*user.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	
+	for _, u := range r.users {
+		for _, password := range u.AppPasswords {
+			if password.Hash == hash {
+				return u, nil
+			}
+		}
+	}
+	
+	return nil, user.ErrUserNotFound
+}
+
+// FindByCertificate 根据客户端证书指纹查找用户
+func (r *MemoryUserRepository) FindByCertificate(ctx context.Context, fingerprint string) (*user.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	
+	for _, u := range r.users {
+		if u.HasCertificate(fingerprint) {
+			return u, nil
+		}
+	}
+	
+	return nil, user.ErrUserNotFound
+}
+
 // Save 保存用户
 func (r *MemoryUserRepository) Save(ctx context.Context, u *user.User) error {
 	r.mu.Lock()
@@ -76,23 +107,68 @@ func (r *MemoryUserRepository) Save(ctx context.Context, u *user.User) error {
 		return user.ErrDuplicateUsername
 	}
 	
-	// 检查钱包地址是否已存在
-	if u.HasWalletAddress() {
-		address := strings.ToLower(u.WalletAddress)
+	// 检查钱包地址（包括关联钱包）是否已属于其他用户
+	addresses := u.WalletAddresses()
+	for _, address := range addresses {
 		if existing, ok := r.walletAddresses[address]; ok && existing.ID != u.ID {
 			return user.ErrDuplicateAddress
 		}
 	}
 	
+	// 检查证书指纹是否已登记给其他用户
+	for _, fp := range u.Certificates {
+		for _, existing := range r.users {
+			if existing.ID != u.ID && existing.HasCertificate(fp) {
+				return user.ErrDuplicateFingerprint
+			}
+		}
+	}
+	
+	// 移除该用户旧的用户名和钱包地址索引
+	for username, existing := range r.users {
+		if existing.ID == u.ID && username != u.Username {
+			delete(r.users, username)
+		}
+	}
+	for address, existing := range r.walletAddresses {
+		if existing.ID == u.ID {
+			delete(r.walletAddresses, address)
+		}
+	}
+	
 	r.users[u.Username] = u
 	
-	if u.HasWalletAddress() {
-		r.walletAddresses[strings.ToLower(u.WalletAddress)] = u
+	for _, address := range addresses {
+		r.walletAddresses[address] = u
 	}
 	
 	return nil
 }
 
+// UseTOTPStep 记录用户最近一次通过验证的 TOTP 时间步
+func (r *MemoryUserRepository) UseTOTPStep(ctx context.Context, userID string, step int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	
+	found := false
+	for _, u := range r.users {
+		if u.ID == userID {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return user.ErrUserNotFound
+	}
+	
+	if last, ok := r.totpSteps[userID]; ok && step <= last {
+		return user.ErrOTPReused
+	}
+	r.totpSteps[userID] = step
+	
+	return nil
+}
+
 // Delete 删除用户
 func (r *MemoryUserRepository) Delete(ctx context.Context, username string) error {
 	r.mu.Lock()
@@ -104,9 +180,10 @@ func (r *MemoryUserRepository) Delete(ctx context.Context, username string) erro
 	}
 	
 	delete(r.users, username)
+	delete(r.totpSteps, u.ID)
 	
-	if u.HasWalletAddress() {
-		delete(r.walletAddresses, strings.ToLower(u.WalletAddress))
+	for _, address := range u.WalletAddresses() {
+		delete(r.walletAddresses, address)
 	}
 	
 	return nil
@@ -124,45 +201,3 @@ func (r *MemoryUserRepository) List(ctx context.Context) ([]*user.User, error) {
 	
 	return users, nil
 }
-
-// createUserFromConfig 从配置创建用户
-func (r *MemoryUserRepository) createUserFromConfig(cfg config.UserConfig) *user.User {
-	u := user.NewUser(cfg.Username, cfg.Directory)
-	
-	// 设置密码
-	if cfg.Password != "" {
-		// 如果密码已经是加密的，直接使用
-		if strings.HasPrefix(cfg.Password, "{bcrypt}") {
-			u.SetPassword(cfg.Password)
-		} else {
-			// 否则加密密码
-			hashedPassword, err := r.passwordHasher.Hash(cfg.Password)
-			if err == nil {
-				u.SetPassword(hashedPassword)
-			}
-		}
-	}
-	
-	// 设置钱包地址
-	if cfg.WalletAddress != "" {
-		u.SetWalletAddress(cfg.WalletAddress)
-	}
-	
-	// 设置权限
-	if cfg.Permissions != "" {
-		u.Permissions = user.ParsePermissions(cfg.Permissions)
-	}
-	
-	// 设置规则
-	for _, ruleCfg := range cfg.Rules {
-		rule := &user.Rule{
-			Path:        ruleCfg.Path,
-			Permissions: user.ParsePermissions(ruleCfg.Permissions),
-			Regex:       ruleCfg.Regex,
-		}
-		u.Rules = append(u.Rules, rule)
-	}
-	
-	return u
-}
-
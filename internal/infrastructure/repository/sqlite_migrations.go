@@ -0,0 +1,197 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// migration 数据库迁移
+type migration struct {
+	version     int
+	description string
+	statements  []string
+}
+
+// sqliteMigrations 按版本顺序排列的迁移，已发布的迁移不可修改，只能追加
+var sqliteMigrations = []migration{
+	{
+		version:     1,
+		description: "create users and rules",
+		statements: []string{
+			`CREATE TABLE users (
+				id             TEXT PRIMARY KEY,
+				username       TEXT NOT NULL UNIQUE,
+				password       TEXT NOT NULL DEFAULT '',
+				wallet_address TEXT UNIQUE,
+				directory      TEXT NOT NULL DEFAULT '',
+				permissions    TEXT NOT NULL DEFAULT '',
+				created_at     TIMESTAMP NOT NULL,
+				updated_at     TIMESTAMP NOT NULL
+			)`,
+			`CREATE TABLE user_rules (
+				user_id     TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				position    INTEGER NOT NULL,
+				path        TEXT NOT NULL,
+				permissions TEXT NOT NULL DEFAULT '',
+				regex       INTEGER NOT NULL DEFAULT 0,
+				PRIMARY KEY (user_id, position)
+			)`,
+			`CREATE TABLE metadata (
+				key   TEXT PRIMARY KEY,
+				value TEXT NOT NULL
+			)`,
+		},
+	},
+	{
+		version:     2,
+		description: "add user role",
+		statements: []string{
+			`ALTER TABLE users ADD COLUMN role TEXT NOT NULL DEFAULT 'user'`,
+		},
+	},
+	{
+		version:     3,
+		description: "add glob and deny rules",
+		statements: []string{
+			`ALTER TABLE user_rules ADD COLUMN glob INTEGER NOT NULL DEFAULT 0`,
+			`ALTER TABLE user_rules ADD COLUMN deny INTEGER NOT NULL DEFAULT 0`,
+		},
+	},
+	{
+		version:     4,
+		description: "add user quota",
+		statements: []string{
+			`ALTER TABLE users ADD COLUMN quota INTEGER NOT NULL DEFAULT 0`,
+		},
+	},
+	{
+		version:     5,
+		description: "create app passwords",
+		statements: []string{
+			`CREATE TABLE app_passwords (
+				id         TEXT PRIMARY KEY,
+				user_id    TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				name       TEXT NOT NULL,
+				hash       TEXT NOT NULL UNIQUE,
+				read_only  INTEGER NOT NULL DEFAULT 0,
+				paths      TEXT NOT NULL DEFAULT '',
+				expires_at TIMESTAMP,
+				created_at TIMESTAMP NOT NULL
+			)`,
+			`CREATE INDEX idx_app_passwords_user_id ON app_passwords(user_id)`,
+		},
+	},
+	{
+		version:     6,
+		description: "add totp two-factor authentication",
+		statements: []string{
+			`ALTER TABLE users ADD COLUMN totp_secret TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE users ADD COLUMN totp_enabled_at TIMESTAMP`,
+			`ALTER TABLE users ADD COLUMN totp_recovery_codes TEXT NOT NULL DEFAULT ''`,
+		},
+	},
+	{
+		version:     7,
+		description: "create user certificates",
+		statements: []string{
+			`CREATE TABLE user_certificates (
+				fingerprint TEXT PRIMARY KEY,
+				user_id     TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE
+			)`,
+			`CREATE INDEX idx_user_certificates_user_id ON user_certificates(user_id)`,
+		},
+	},
+	{
+		version:     8,
+		description: "add http digest ha1",
+		statements: []string{
+			`ALTER TABLE users ADD COLUMN digest_realm TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE users ADD COLUMN digest_ha1_md5 TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE users ADD COLUMN digest_ha1_sha256 TEXT NOT NULL DEFAULT ''`,
+		},
+	},
+	{
+		version:     9,
+		description: "add token gated rules",
+		statements: []string{
+			`ALTER TABLE user_rules ADD COLUMN token_standard TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE user_rules ADD COLUMN token_contract TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE user_rules ADD COLUMN token_min_balance TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE user_rules ADD COLUMN token_id TEXT NOT NULL DEFAULT ''`,
+		},
+	},
+	{
+		version:     10,
+		description: "add linked identities",
+		statements: []string{
+			`CREATE TABLE user_identities (
+				provider   TEXT NOT NULL,
+				subject    TEXT NOT NULL,
+				user_id    TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				created_at TIMESTAMP NOT NULL,
+				PRIMARY KEY (provider, subject)
+			)`,
+			`CREATE INDEX idx_user_identities_user_id ON user_identities(user_id)`,
+		},
+	},
+	{
+		version:     11,
+		description: "add totp replay protection",
+		statements: []string{
+			`ALTER TABLE users ADD COLUMN totp_last_step INTEGER NOT NULL DEFAULT 0`,
+		},
+	},
+}
+
+// migrate 执行尚未应用的迁移
+func migrate(ctx context.Context, db *sql.DB, migrations []migration) error {
+	if _, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version     INTEGER PRIMARY KEY,
+		description TEXT NOT NULL,
+		applied_at  TIMESTAMP NOT NULL
+	)`); err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	var current int
+	if err := db.QueryRowContext(ctx,
+		`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&current); err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+		if err := applyMigration(ctx, db, m); err != nil {
+			return fmt.Errorf("migration %d (%s): %w", m.version, m.description, err)
+		}
+	}
+
+	return nil
+}
+
+// applyMigration 在事务中应用单个迁移
+func applyMigration(ctx context.Context, db *sql.DB, m migration) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, stmt := range m.statements {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO schema_migrations (version, description, applied_at) VALUES (?, ?, ?)`,
+		m.version, m.description, time.Now().UTC()); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
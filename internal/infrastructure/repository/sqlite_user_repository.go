@@ -0,0 +1,623 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/yeying-community/webdav/internal/domain/user"
+	"github.com/yeying-community/webdav/internal/infrastructure/config"
+	"github.com/yeying-community/webdav/internal/infrastructure/crypto"
+	_ "modernc.org/sqlite"
+)
+
+// metaKeyConfigImported 配置用户已导入标记
+const metaKeyConfigImported = "config_users_imported"
+
+// SQLiteUserRepository SQLite 用户仓储
+type SQLiteUserRepository struct {
+	db             *sql.DB
+	passwordHasher *crypto.PasswordHasher
+}
+
+// NewSQLiteUserRepository 创建 SQLite 用户仓储
+func NewSQLiteUserRepository(path string) (*SQLiteUserRepository, error) {
+	dsn := fmt.Sprintf("file:%s?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)", path)
+
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	// SQLite 只允许单写者，使用单连接避免锁竞争
+	db.SetMaxOpenConns(1)
+
+	if err := migrate(context.Background(), db, sqliteMigrations); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to migrate database: %w", err)
+	}
+
+	return &SQLiteUserRepository{
+		db:             db,
+		passwordHasher: crypto.NewPasswordHasher(),
+	}, nil
+}
+
+// ImportConfigUsers 一次性导入配置文件中的用户，返回导入数量
+//
+// 导入完成后写入标记，之后的启动不再导入，避免覆盖运行时的修改。
+func (r *SQLiteUserRepository) ImportConfigUsers(ctx context.Context, userConfigs []config.UserConfig) (int, error) {
+	var value string
+	err := r.db.QueryRowContext(ctx, `SELECT value FROM metadata WHERE key = ?`, metaKeyConfigImported).Scan(&value)
+	if err == nil {
+		return 0, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("failed to read import marker: %w", err)
+	}
+
+	imported := 0
+	for _, cfg := range userConfigs {
+		if _, err := r.FindByUsername(ctx, cfg.Username); err == nil {
+			continue
+		} else if !errors.Is(err, user.ErrUserNotFound) {
+			return imported, err
+		}
+
+		if err := r.Save(ctx, newUserFromConfig(cfg, r.passwordHasher)); err != nil {
+			return imported, fmt.Errorf("failed to import user %s: %w", cfg.Username, err)
+		}
+		imported++
+	}
+
+	if _, err := r.db.ExecContext(ctx, `INSERT INTO metadata (key, value) VALUES (?, ?)`,
+		metaKeyConfigImported, time.Now().UTC().Format(time.RFC3339)); err != nil {
+		return imported, fmt.Errorf("failed to write import marker: %w", err)
+	}
+
+	return imported, nil
+}
+
+// FindByUsername 根据用户名查找用户
+func (r *SQLiteUserRepository) FindByUsername(ctx context.Context, username string) (*user.User, error) {
+	return r.findOne(ctx, `WHERE username = ?`, username)
+}
+
+// FindByWalletAddress 根据钱包地址查找用户
+func (r *SQLiteUserRepository) FindByWalletAddress(ctx context.Context, address string) (*user.User, error) {
+	address = user.NormalizeWalletID(address)
+	return r.findOne(ctx, `WHERE wallet_address = ?
+		OR id = (SELECT user_id FROM user_identities WHERE provider = ? AND subject = ?)`,
+		address, user.IdentityWallet, address)
+}
+
+// FindByAppPassword 根据应用密码摘要查找用户
+func (r *SQLiteUserRepository) FindByAppPassword(ctx context.Context, hash string) (*user.User, error) {
+	return r.findOne(ctx, `WHERE id = (SELECT user_id FROM app_passwords WHERE hash = ?)`, hash)
+}
+
+// FindByCertificate 根据客户端证书指纹查找用户
+func (r *SQLiteUserRepository) FindByCertificate(ctx context.Context, fingerprint string) (*user.User, error) {
+	return r.findOne(ctx, `WHERE id = (SELECT user_id FROM user_certificates WHERE fingerprint = ?)`, fingerprint)
+}
+
+// Save 保存用户
+func (r *SQLiteUserRepository) Save(ctx context.Context, u *user.User) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// 检查用户名是否已存在
+	var existingID string
+	err = tx.QueryRowContext(ctx, `SELECT id FROM users WHERE username = ?`, u.Username).Scan(&existingID)
+	if err == nil && existingID != u.ID {
+		return user.ErrDuplicateUsername
+	}
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	// 检查钱包地址（包括关联钱包）是否已属于其他用户
+	var wallet sql.NullString
+	if u.HasWalletAddress() {
+		wallet = sql.NullString{String: user.NormalizeWalletID(u.WalletAddress), Valid: true}
+	}
+	for _, address := range u.WalletAddresses() {
+		err = tx.QueryRowContext(ctx, `
+			SELECT id FROM users WHERE wallet_address = ? AND id != ?
+			UNION SELECT user_id FROM user_identities WHERE provider = ? AND subject = ? AND user_id != ?`,
+			address, u.ID, user.IdentityWallet, address, u.ID).Scan(&existingID)
+		if err == nil {
+			return user.ErrDuplicateAddress
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+	}
+
+	permissions := ""
+	if u.Permissions != nil {
+		permissions = u.Permissions.String()
+	}
+
+	role := u.Role
+	if role == "" {
+		role = user.RoleUser
+	}
+
+	// 未确认的注册只保存密钥，启用时间为空
+	var (
+		totpSecret        string
+		totpEnabledAt     sql.NullTime
+		totpRecoveryCodes string
+	)
+	if u.TOTP != nil {
+		totpSecret = u.TOTP.Secret
+		if u.TOTP.Enabled {
+			enabledAt := time.Now()
+			if u.TOTP.EnabledAt != nil {
+				enabledAt = *u.TOTP.EnabledAt
+			}
+			totpEnabledAt = sql.NullTime{Time: enabledAt.UTC(), Valid: true}
+		}
+		totpRecoveryCodes = strings.Join(u.TOTP.RecoveryCodes, "\n")
+	}
+
+	digest := u.Digest
+	if digest == nil {
+		digest = &user.DigestHA1{}
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO users (id, username, password, wallet_address, directory, role, quota, permissions,
+			totp_secret, totp_enabled_at, totp_recovery_codes,
+			digest_realm, digest_ha1_md5, digest_ha1_sha256, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			username = excluded.username,
+			password = excluded.password,
+			wallet_address = excluded.wallet_address,
+			directory = excluded.directory,
+			role = excluded.role,
+			quota = excluded.quota,
+			permissions = excluded.permissions,
+			totp_secret = excluded.totp_secret,
+			totp_enabled_at = excluded.totp_enabled_at,
+			totp_recovery_codes = excluded.totp_recovery_codes,
+			digest_realm = excluded.digest_realm,
+			digest_ha1_md5 = excluded.digest_ha1_md5,
+			digest_ha1_sha256 = excluded.digest_ha1_sha256,
+			updated_at = excluded.updated_at`,
+		u.ID, u.Username, u.Password, wallet, u.Directory, role, u.Quota, permissions,
+		totpSecret, totpEnabledAt, totpRecoveryCodes,
+		digest.Realm, digest.MD5, digest.SHA256,
+		u.CreatedAt.UTC(), u.UpdatedAt.UTC()); err != nil {
+		return fmt.Errorf("failed to save user: %w", err)
+	}
+
+	// 重写规则（保持顺序）
+	if _, err := tx.ExecContext(ctx, `DELETE FROM user_rules WHERE user_id = ?`, u.ID); err != nil {
+		return fmt.Errorf("failed to clear rules: %w", err)
+	}
+	for i, rule := range u.Rules {
+		rulePermissions := ""
+		if rule.Permissions != nil {
+			rulePermissions = rule.Permissions.String()
+		}
+		token := rule.Token
+		if token == nil {
+			token = &user.TokenGate{}
+		}
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO user_rules (user_id, position, path, permissions, regex, glob, deny,
+				token_standard, token_contract, token_min_balance, token_id)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			u.ID, i, rule.Path, rulePermissions, rule.Regex, rule.Glob, rule.Deny,
+			token.Standard, token.Contract, token.MinBalance, token.TokenID); err != nil {
+			return fmt.Errorf("failed to save rule: %w", err)
+		}
+	}
+
+	// 重写应用密码
+	if _, err := tx.ExecContext(ctx, `DELETE FROM app_passwords WHERE user_id = ?`, u.ID); err != nil {
+		return fmt.Errorf("failed to clear app passwords: %w", err)
+	}
+	for _, password := range u.AppPasswords {
+		var expiresAt sql.NullTime
+		if password.ExpiresAt != nil {
+			expiresAt = sql.NullTime{Time: password.ExpiresAt.UTC(), Valid: true}
+		}
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO app_passwords (id, user_id, name, hash, read_only, paths, expires_at, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			password.ID, u.ID, password.Name, password.Hash, password.ReadOnly,
+			strings.Join(password.Paths, "\n"), expiresAt, password.CreatedAt.UTC()); err != nil {
+			return fmt.Errorf("failed to save app password: %w", err)
+		}
+	}
+
+	// 重写客户端证书
+	if _, err := tx.ExecContext(ctx, `DELETE FROM user_certificates WHERE user_id = ?`, u.ID); err != nil {
+		return fmt.Errorf("failed to clear certificates: %w", err)
+	}
+	for _, fp := range u.Certificates {
+		err = tx.QueryRowContext(ctx, `SELECT user_id FROM user_certificates WHERE fingerprint = ?`, fp).Scan(&existingID)
+		if err == nil {
+			return user.ErrDuplicateFingerprint
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO user_certificates (fingerprint, user_id) VALUES (?, ?)`, fp, u.ID); err != nil {
+			return fmt.Errorf("failed to save certificate: %w", err)
+		}
+	}
+
+	// 重写关联身份
+	if _, err := tx.ExecContext(ctx, `DELETE FROM user_identities WHERE user_id = ?`, u.ID); err != nil {
+		return fmt.Errorf("failed to clear identities: %w", err)
+	}
+	for _, identity := range u.Identities {
+		err = tx.QueryRowContext(ctx, `SELECT user_id FROM user_identities WHERE provider = ? AND subject = ?`,
+			identity.Provider, identity.Subject).Scan(&existingID)
+		if err == nil {
+			if identity.Provider == user.IdentityWallet {
+				return user.ErrDuplicateAddress
+			}
+			return user.ErrDuplicateIdentity
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO user_identities (provider, subject, user_id, created_at) VALUES (?, ?, ?, ?)`,
+			identity.Provider, identity.Subject, u.ID, identity.CreatedAt.UTC()); err != nil {
+			return fmt.Errorf("failed to save identity: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// UseTOTPStep 记录用户最近一次通过验证的 TOTP 时间步
+func (r *SQLiteUserRepository) UseTOTPStep(ctx context.Context, userID string, step int64) error {
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE users SET totp_last_step = ? WHERE id = ? AND totp_last_step < ?`,
+		step, userID, step)
+	if err != nil {
+		return fmt.Errorf("failed to record totp step: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected > 0 {
+		return nil
+	}
+
+	// 没有更新时区分用户不存在和验证码已使用
+	var exists int
+	err = r.db.QueryRowContext(ctx, `SELECT 1 FROM users WHERE id = ?`, userID).Scan(&exists)
+	if errors.Is(err, sql.ErrNoRows) {
+		return user.ErrUserNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to find user: %w", err)
+	}
+
+	return user.ErrOTPReused
+}
+
+// Delete 删除用户
+func (r *SQLiteUserRepository) Delete(ctx context.Context, username string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM users WHERE username = ?`, username)
+	if err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return user.ErrUserNotFound
+	}
+
+	return nil
+}
+
+// List 列出所有用户
+func (r *SQLiteUserRepository) List(ctx context.Context) ([]*user.User, error) {
+	users, err := r.query(ctx, `ORDER BY username`)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.loadRules(ctx, users...); err != nil {
+		return nil, err
+	}
+	if err := r.loadAppPasswords(ctx, users...); err != nil {
+		return nil, err
+	}
+	if err := r.loadCertificates(ctx, users...); err != nil {
+		return nil, err
+	}
+	if err := r.loadIdentities(ctx, users...); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// Close 关闭数据库
+func (r *SQLiteUserRepository) Close() error {
+	return r.db.Close()
+}
+
+// findOne 查询单个用户
+func (r *SQLiteUserRepository) findOne(ctx context.Context, where string, args ...interface{}) (*user.User, error) {
+	users, err := r.query(ctx, where+` LIMIT 1`, args...)
+	if err != nil {
+		return nil, err
+	}
+	if len(users) == 0 {
+		return nil, user.ErrUserNotFound
+	}
+
+	if err := r.loadRules(ctx, users[0]); err != nil {
+		return nil, err
+	}
+	if err := r.loadAppPasswords(ctx, users[0]); err != nil {
+		return nil, err
+	}
+	if err := r.loadCertificates(ctx, users[0]); err != nil {
+		return nil, err
+	}
+	if err := r.loadIdentities(ctx, users[0]); err != nil {
+		return nil, err
+	}
+
+	return users[0], nil
+}
+
+// query 查询用户（不含规则、应用密码、证书和关联身份）
+func (r *SQLiteUserRepository) query(ctx context.Context, clause string, args ...interface{}) ([]*user.User, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, username, password, wallet_address, directory, role, quota, permissions,
+			totp_secret, totp_enabled_at, totp_recovery_codes,
+			digest_realm, digest_ha1_md5, digest_ha1_sha256, created_at, updated_at
+		FROM users `+clause, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query users: %w", err)
+	}
+	defer rows.Close()
+
+	var users []*user.User
+	for rows.Next() {
+		var (
+			u                 user.User
+			wallet            sql.NullString
+			permissions       string
+			totpSecret        string
+			totpEnabledAt     sql.NullTime
+			totpRecoveryCodes string
+			digest            user.DigestHA1
+		)
+		if err := rows.Scan(&u.ID, &u.Username, &u.Password, &wallet, &u.Directory,
+			&u.Role, &u.Quota, &permissions, &totpSecret, &totpEnabledAt, &totpRecoveryCodes,
+			&digest.Realm, &digest.MD5, &digest.SHA256,
+			&u.CreatedAt, &u.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		if totpSecret != "" {
+			u.TOTP = &user.TOTP{Secret: totpSecret, Enabled: totpEnabledAt.Valid}
+			if totpEnabledAt.Valid {
+				enabledAt := totpEnabledAt.Time
+				u.TOTP.EnabledAt = &enabledAt
+			}
+			if totpRecoveryCodes != "" {
+				u.TOTP.RecoveryCodes = strings.Split(totpRecoveryCodes, "\n")
+			}
+		}
+		if digest.Realm != "" {
+			u.Digest = &digest
+		}
+		u.WalletAddress = wallet.String
+		u.Permissions = user.ParsePermissions(permissions)
+		u.Rules = make([]*user.Rule, 0)
+		u.AppPasswords = make([]*user.AppPassword, 0)
+		users = append(users, &u)
+	}
+
+	return users, rows.Err()
+}
+
+// loadRules 加载用户规则
+func (r *SQLiteUserRepository) loadRules(ctx context.Context, users ...*user.User) error {
+	if len(users) == 0 {
+		return nil
+	}
+
+	byID := make(map[string]*user.User, len(users))
+	for _, u := range users {
+		byID[u.ID] = u
+	}
+
+	query := `SELECT user_id, path, permissions, regex, glob, deny,
+		token_standard, token_contract, token_min_balance, token_id FROM user_rules`
+	var args []interface{}
+	if len(users) == 1 {
+		query += ` WHERE user_id = ?`
+		args = append(args, users[0].ID)
+	}
+
+	rows, err := r.db.QueryContext(ctx, query+` ORDER BY user_id, position`, args...)
+	if err != nil {
+		return fmt.Errorf("failed to query rules: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			userID, path, permissions string
+			regex, glob, deny         bool
+			token                     user.TokenGate
+		)
+		if err := rows.Scan(&userID, &path, &permissions, &regex, &glob, &deny,
+			&token.Standard, &token.Contract, &token.MinBalance, &token.TokenID); err != nil {
+			return fmt.Errorf("failed to scan rule: %w", err)
+		}
+		u, ok := byID[userID]
+		if !ok {
+			continue
+		}
+		rule := &user.Rule{
+			Path:        path,
+			Permissions: user.ParsePermissions(permissions),
+			Regex:       regex,
+			Glob:        glob,
+			Deny:        deny,
+		}
+		if token.Standard != "" {
+			rule.Token = &token
+		}
+		u.Rules = append(u.Rules, rule)
+	}
+
+	return rows.Err()
+}
+
+// loadAppPasswords 加载用户应用密码
+func (r *SQLiteUserRepository) loadAppPasswords(ctx context.Context, users ...*user.User) error {
+	if len(users) == 0 {
+		return nil
+	}
+
+	byID := make(map[string]*user.User, len(users))
+	for _, u := range users {
+		byID[u.ID] = u
+	}
+
+	query := `SELECT id, user_id, name, hash, read_only, paths, expires_at, created_at FROM app_passwords`
+	var args []interface{}
+	if len(users) == 1 {
+		query += ` WHERE user_id = ?`
+		args = append(args, users[0].ID)
+	}
+
+	rows, err := r.db.QueryContext(ctx, query+` ORDER BY user_id, created_at`, args...)
+	if err != nil {
+		return fmt.Errorf("failed to query app passwords: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			password  user.AppPassword
+			userID    string
+			paths     string
+			expiresAt sql.NullTime
+		)
+		if err := rows.Scan(&password.ID, &userID, &password.Name, &password.Hash,
+			&password.ReadOnly, &paths, &expiresAt, &password.CreatedAt); err != nil {
+			return fmt.Errorf("failed to scan app password: %w", err)
+		}
+		u, ok := byID[userID]
+		if !ok {
+			continue
+		}
+		if paths != "" {
+			password.Paths = strings.Split(paths, "\n")
+		}
+		if expiresAt.Valid {
+			t := expiresAt.Time
+			password.ExpiresAt = &t
+		}
+		u.AppPasswords = append(u.AppPasswords, &password)
+	}
+
+	return rows.Err()
+}
+
+// loadCertificates 加载用户客户端证书指纹
+func (r *SQLiteUserRepository) loadCertificates(ctx context.Context, users ...*user.User) error {
+	if len(users) == 0 {
+		return nil
+	}
+
+	byID := make(map[string]*user.User, len(users))
+	for _, u := range users {
+		byID[u.ID] = u
+	}
+
+	query := `SELECT user_id, fingerprint FROM user_certificates`
+	var args []interface{}
+	if len(users) == 1 {
+		query += ` WHERE user_id = ?`
+		args = append(args, users[0].ID)
+	}
+
+	rows, err := r.db.QueryContext(ctx, query+` ORDER BY user_id, fingerprint`, args...)
+	if err != nil {
+		return fmt.Errorf("failed to query certificates: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var userID, fingerprint string
+		if err := rows.Scan(&userID, &fingerprint); err != nil {
+			return fmt.Errorf("failed to scan certificate: %w", err)
+		}
+		if u, ok := byID[userID]; ok {
+			u.Certificates = append(u.Certificates, fingerprint)
+		}
+	}
+
+	return rows.Err()
+}
+
+// loadIdentities 加载用户关联身份
+func (r *SQLiteUserRepository) loadIdentities(ctx context.Context, users ...*user.User) error {
+	if len(users) == 0 {
+		return nil
+	}
+
+	byID := make(map[string]*user.User, len(users))
+	for _, u := range users {
+		byID[u.ID] = u
+	}
+
+	query := `SELECT user_id, provider, subject, created_at FROM user_identities`
+	var args []interface{}
+	if len(users) == 1 {
+		query += ` WHERE user_id = ?`
+		args = append(args, users[0].ID)
+	}
+
+	rows, err := r.db.QueryContext(ctx, query+` ORDER BY user_id, created_at`, args...)
+	if err != nil {
+		return fmt.Errorf("failed to query identities: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			userID   string
+			identity user.Identity
+		)
+		if err := rows.Scan(&userID, &identity.Provider, &identity.Subject, &identity.CreatedAt); err != nil {
+			return fmt.Errorf("failed to scan identity: %w", err)
+		}
+		if u, ok := byID[userID]; ok {
+			u.Identities = append(u.Identities, &identity)
+		}
+	}
+
+	return rows.Err()
+}
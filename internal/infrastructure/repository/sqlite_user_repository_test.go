@@ -0,0 +1,300 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/yeying-community/webdav/internal/domain/user"
+	"github.com/yeying-community/webdav/internal/infrastructure/config"
+)
+
+// newTestSQLiteRepository 在临时目录创建 SQLite 用户仓储，返回仓储和数据库路径
+func newTestSQLiteRepository(t *testing.T) (*SQLiteUserRepository, string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "users.db")
+	repo, err := NewSQLiteUserRepository(path)
+	if err != nil {
+		t.Fatalf("NewSQLiteUserRepository: %v", err)
+	}
+	t.Cleanup(func() { repo.Close() })
+	return repo, path
+}
+
+// schemaVersions 已应用的迁移版本
+func schemaVersions(t *testing.T, repo *SQLiteUserRepository) []int {
+	t.Helper()
+
+	rows, err := repo.db.Query(`SELECT version FROM schema_migrations ORDER BY version`)
+	if err != nil {
+		t.Fatalf("query schema_migrations: %v", err)
+	}
+	defer rows.Close()
+
+	var versions []int
+	for rows.Next() {
+		var v int
+		if err := rows.Scan(&v); err != nil {
+			t.Fatalf("scan: %v", err)
+		}
+		versions = append(versions, v)
+	}
+	return versions
+}
+
+func TestSQLiteMigrate(t *testing.T) {
+	ctx := context.Background()
+	repo, path := newTestSQLiteRepository(t)
+
+	var want []int
+	for _, m := range sqliteMigrations {
+		want = append(want, m.version)
+	}
+	if got := schemaVersions(t, repo); !reflect.DeepEqual(got, want) {
+		t.Fatalf("fresh database versions = %v, want %v", got, want)
+	}
+	if latest := want[len(want)-1]; latest != 11 {
+		t.Fatalf("latest migration = %d, want 11", latest)
+	}
+
+	if err := repo.Save(ctx, user.NewUser("alice", "/alice")); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	// 已是最新版本时再次迁移不做任何修改
+	if err := migrate(ctx, repo.db, sqliteMigrations); err != nil {
+		t.Fatalf("migrate again: %v", err)
+	}
+	if got := schemaVersions(t, repo); !reflect.DeepEqual(got, want) {
+		t.Fatalf("versions after re-running migrate = %v, want %v", got, want)
+	}
+	repo.Close()
+
+	reopened, err := NewSQLiteUserRepository(path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer reopened.Close()
+	if got := schemaVersions(t, reopened); !reflect.DeepEqual(got, want) {
+		t.Fatalf("versions after reopening = %v, want %v", got, want)
+	}
+	if _, err := reopened.FindByUsername(ctx, "alice"); err != nil {
+		t.Fatalf("FindByUsername after reopening: %v", err)
+	}
+}
+
+func TestSQLiteImportConfigUsersOnce(t *testing.T) {
+	ctx := context.Background()
+	repo, _ := newTestSQLiteRepository(t)
+
+	configs := []config.UserConfig{
+		{Username: "alice", Password: "secret", Directory: "/alice", Permissions: "R"},
+	}
+	imported, err := repo.ImportConfigUsers(ctx, configs)
+	if err != nil || imported != 1 {
+		t.Fatalf("ImportConfigUsers = %d, %v, want 1", imported, err)
+	}
+
+	// 管理员在运行时修改了用户
+	alice, err := repo.FindByUsername(ctx, "alice")
+	if err != nil {
+		t.Fatalf("FindByUsername: %v", err)
+	}
+	if alice.Password == "secret" {
+		t.Fatal("imported password stored in plaintext")
+	}
+	alice.Directory = "/edited"
+	alice.Permissions = user.ParsePermissions("CRUD")
+	if err := repo.Save(ctx, alice); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	// 再次启动时不覆盖修改，也不导入配置中新增的用户
+	configs = append(configs, config.UserConfig{Username: "bob", Password: "secret", Directory: "/bob"})
+	imported, err = repo.ImportConfigUsers(ctx, configs)
+	if err != nil || imported != 0 {
+		t.Fatalf("second ImportConfigUsers = %d, %v, want 0", imported, err)
+	}
+	alice, err = repo.FindByUsername(ctx, "alice")
+	if err != nil {
+		t.Fatalf("FindByUsername: %v", err)
+	}
+	if alice.Directory != "/edited" || alice.Permissions.String() != "CRUD" {
+		t.Fatalf("admin edits overwritten: directory %q, permissions %q", alice.Directory, alice.Permissions.String())
+	}
+	if _, err := repo.FindByUsername(ctx, "bob"); !errors.Is(err, user.ErrUserNotFound) {
+		t.Fatalf("bob: err = %v, want ErrUserNotFound", err)
+	}
+}
+
+func TestSQLiteSaveRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	repo, _ := newTestSQLiteRepository(t)
+
+	now := time.Now().UTC().Truncate(time.Second)
+	expires := now.Add(24 * time.Hour)
+
+	u := user.NewUser("alice", "/alice")
+	u.Password = "$2a$10$abcdefghijklmnopqrstuv"
+	u.Role = user.RoleAdmin
+	u.Quota = 5 << 30
+	u.Permissions = user.ParsePermissions("RU")
+	u.Digest = user.NewDigestHA1("alice", "WebDAV", "secret")
+	if err := u.SetWalletAddress("0x1111111111111111111111111111111111111111"); err != nil {
+		t.Fatalf("SetWalletAddress: %v", err)
+	}
+	if err := u.LinkWallet("0x2222222222222222222222222222222222222222"); err != nil {
+		t.Fatalf("LinkWallet: %v", err)
+	}
+	u.Rules = []*user.Rule{
+		{Path: "^/shared/.*\\.txt$", Permissions: user.ParsePermissions("R"), Regex: true},
+		{Path: "/photos/**/*.jpg", Permissions: user.ParsePermissions("CR"), Glob: true},
+		{Path: "/private", Permissions: user.ParsePermissions(""), Deny: true},
+		{
+			Path:        "/members",
+			Permissions: user.ParsePermissions("CRUD"),
+			Token:       &user.TokenGate{Standard: user.TokenERC20, Contract: "0x3333333333333333333333333333333333333333", MinBalance: "1000"},
+		},
+		{
+			Path:        "/holders",
+			Permissions: user.ParsePermissions("R"),
+			Token:       &user.TokenGate{Standard: user.TokenERC721, Contract: "0x4444444444444444444444444444444444444444", TokenID: "42"},
+		},
+	}
+	u.AppPasswords = []*user.AppPassword{
+		{ID: "ap1", Name: "phone", Hash: user.HashAppPassword("token-1"), ReadOnly: true, Paths: []string{"/photos", "/notes"}, ExpiresAt: &expires, CreatedAt: now},
+		{ID: "ap2", Name: "laptop", Hash: user.HashAppPassword("token-2"), CreatedAt: now},
+	}
+	if err := u.SetCertificates([]string{user.CertificateFingerprint([]byte("certificate"))}); err != nil {
+		t.Fatalf("SetCertificates: %v", err)
+	}
+	u.TOTP = &user.TOTP{Secret: "JBSWY3DPEHPK3PXP", Enabled: true, RecoveryCodes: []string{"hash-1", "hash-2"}, EnabledAt: &now}
+
+	if err := repo.Save(ctx, u); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	got, err := repo.FindByUsername(ctx, "alice")
+	if err != nil {
+		t.Fatalf("FindByUsername: %v", err)
+	}
+
+	if got.ID != u.ID || got.Password != u.Password || got.Role != u.Role || got.Quota != u.Quota ||
+		got.Directory != u.Directory || got.Permissions.String() != "RU" {
+		t.Fatalf("user = %+v, want %+v", got, u)
+	}
+	if !reflect.DeepEqual(got.Digest, u.Digest) {
+		t.Fatalf("digest = %+v, want %+v", got.Digest, u.Digest)
+	}
+	if !reflect.DeepEqual(got.WalletAddresses(), u.WalletAddresses()) {
+		t.Fatalf("wallets = %v, want %v", got.WalletAddresses(), u.WalletAddresses())
+	}
+	if !reflect.DeepEqual(got.Rules, u.Rules) {
+		for i := range got.Rules {
+			t.Logf("rule %d = %+v token %+v", i, got.Rules[i], got.Rules[i].Token)
+		}
+		t.Fatal("rules do not round-trip")
+	}
+	if len(got.AppPasswords) != len(u.AppPasswords) {
+		t.Fatalf("app passwords = %d, want %d", len(got.AppPasswords), len(u.AppPasswords))
+	}
+	for _, want := range u.AppPasswords {
+		var p *user.AppPassword
+		for _, candidate := range got.AppPasswords {
+			if candidate.ID == want.ID {
+				p = candidate
+			}
+		}
+		if p == nil || p.Name != want.Name || p.Hash != want.Hash || p.ReadOnly != want.ReadOnly ||
+			!reflect.DeepEqual(p.Paths, want.Paths) || !p.CreatedAt.Equal(want.CreatedAt) ||
+			(p.ExpiresAt == nil) != (want.ExpiresAt == nil) || (p.ExpiresAt != nil && !p.ExpiresAt.Equal(*want.ExpiresAt)) {
+			t.Fatalf("app password %s = %+v, want %+v", want.ID, p, want)
+		}
+	}
+	if !reflect.DeepEqual(got.Certificates, u.Certificates) {
+		t.Fatalf("certificates = %v, want %v", got.Certificates, u.Certificates)
+	}
+	if got.TOTP == nil || got.TOTP.Secret != u.TOTP.Secret || !got.TOTP.Enabled ||
+		!reflect.DeepEqual(got.TOTP.RecoveryCodes, u.TOTP.RecoveryCodes) ||
+		got.TOTP.EnabledAt == nil || !got.TOTP.EnabledAt.Equal(now) {
+		t.Fatalf("totp = %+v, want %+v", got.TOTP, u.TOTP)
+	}
+
+	// 按各个索引都能找到同一用户
+	lookups := map[string]func() (*user.User, error){
+		"primary wallet": func() (*user.User, error) {
+			return repo.FindByWalletAddress(ctx, "0x1111111111111111111111111111111111111111")
+		},
+		"linked wallet": func() (*user.User, error) {
+			return repo.FindByWalletAddress(ctx, "0x2222222222222222222222222222222222222222")
+		},
+		"app password": func() (*user.User, error) { return repo.FindByAppPassword(ctx, user.HashAppPassword("token-2")) },
+		"certificate":  func() (*user.User, error) { return repo.FindByCertificate(ctx, u.Certificates[0]) },
+	}
+	for name, lookup := range lookups {
+		found, err := lookup()
+		if err != nil || found.ID != u.ID {
+			t.Fatalf("%s: found %v, %v", name, found, err)
+		}
+	}
+
+	// 保存删减后的用户会移除子表中的记录
+	got.Rules = got.Rules[:1]
+	got.AppPasswords = nil
+	got.Certificates = nil
+	got.Identities = nil
+	got.TOTP = nil
+	got.Digest = nil
+	if err := repo.Save(ctx, got); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	got, err = repo.FindByUsername(ctx, "alice")
+	if err != nil {
+		t.Fatalf("FindByUsername: %v", err)
+	}
+	if len(got.Rules) != 1 || len(got.AppPasswords) != 0 || len(got.Certificates) != 0 ||
+		len(got.Identities) != 0 || got.TOTP != nil || got.Digest != nil {
+		t.Fatalf("removed fields came back: %+v", got)
+	}
+	if _, err := repo.FindByAppPassword(ctx, user.HashAppPassword("token-2")); !errors.Is(err, user.ErrUserNotFound) {
+		t.Fatalf("removed app password: err = %v, want ErrUserNotFound", err)
+	}
+}
+
+func TestSQLiteUseTOTPStep(t *testing.T) {
+	ctx := context.Background()
+	repo, _ := newTestSQLiteRepository(t)
+
+	u := user.NewUser("alice", "/alice")
+	u.TOTP = &user.TOTP{Secret: "JBSWY3DPEHPK3PXP", Enabled: true}
+	if err := repo.Save(ctx, u); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	steps := []struct {
+		step    int64
+		wantErr error
+	}{
+		{step: 100},
+		{step: 100, wantErr: user.ErrOTPReused},
+		{step: 99, wantErr: user.ErrOTPReused},
+		{step: 101},
+		{step: 100, wantErr: user.ErrOTPReused},
+	}
+	for _, st := range steps {
+		if err := repo.UseTOTPStep(ctx, u.ID, st.step); !errors.Is(err, st.wantErr) {
+			t.Fatalf("UseTOTPStep(%d) = %v, want %v", st.step, err, st.wantErr)
+		}
+	}
+
+	// 保存用户不会重置已使用的时间步
+	if err := repo.Save(ctx, u); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if err := repo.UseTOTPStep(ctx, u.ID, 101); !errors.Is(err, user.ErrOTPReused) {
+		t.Fatalf("UseTOTPStep after Save = %v, want ErrOTPReused", err)
+	}
+}
@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"github.com/yeying-community/webdav/internal/domain/user"
+	"github.com/yeying-community/webdav/internal/infrastructure/config"
+	"github.com/yeying-community/webdav/internal/infrastructure/crypto"
+)
+
+// newUserFromConfig 从配置创建用户
+func newUserFromConfig(cfg config.UserConfig, passwordHasher *crypto.PasswordHasher) *user.User {
+	u := user.NewUser(cfg.Username, cfg.Directory)
+
+	// 设置密码
+	if cfg.Password != "" {
+		// 如果密码已经是加密的，直接使用
+		if crypto.IsPasswordHash(cfg.Password) {
+			u.SetPassword(cfg.Password)
+		} else {
+			// 否则加密密码
+			hashedPassword, err := passwordHasher.Hash(cfg.Password)
+			if err == nil {
+				u.SetPassword(hashedPassword)
+			}
+		}
+	}
+
+	// 设置钱包地址
+	if cfg.WalletAddress != "" {
+		u.SetWalletAddress(cfg.WalletAddress)
+	}
+	for _, address := range cfg.Wallets {
+		u.LinkWallet(address)
+	}
+
+	// 设置配额
+	if cfg.Quota == "unlimited" {
+		u.Quota = -1
+	} else if quota, err := config.ParseByteSize(cfg.Quota); err == nil {
+		u.Quota = quota
+	}
+
+	// 设置角色
+	if cfg.Role != "" {
+		u.SetRole(cfg.Role)
+	}
+
+	// 设置权限
+	if cfg.Permissions != "" {
+		u.Permissions = user.ParsePermissions(cfg.Permissions)
+	}
+
+	// 设置规则
+	for _, ruleCfg := range cfg.Rules {
+		u.Rules = append(u.Rules, ruleCfg.ToRule())
+	}
+
+	// 设置客户端证书
+	if len(cfg.Certificates) > 0 {
+		u.SetCertificates(cfg.Certificates)
+	}
+
+	return u
+}
@@ -0,0 +1,216 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+
+	"github.com/yeying-community/webdav/internal/infrastructure/config"
+	"go.uber.org/zap"
+	"golang.org/x/net/webdav"
+)
+
+// PropStore 死属性存储
+//
+// root 为用户根目录，name 为相对于根目录的资源路径。
+type PropStore interface {
+	// Props 获取资源的死属性
+	Props(ctx context.Context, root, name string) (map[xml.Name]webdav.Property, error)
+
+	// Patch 修改资源的死属性
+	Patch(ctx context.Context, root, name string, patches []webdav.Proppatch) ([]webdav.Propstat, error)
+
+	// Copy 复制资源的死属性，recursive 为 true 时包括子资源
+	Copy(ctx context.Context, root, src, dst string, recursive bool) error
+
+	// Move 随资源移动死属性（包括子资源）
+	Move(ctx context.Context, root, src, dst string) error
+
+	// Remove 删除资源及其子资源的死属性
+	Remove(ctx context.Context, root, name string) error
+
+	// Close 关闭存储
+	Close() error
+}
+
+// NewPropStore 根据配置创建死属性存储，不需要额外存储时返回 nil
+//
+// auto 模式下本地磁盘优先使用扩展属性（xattr），不支持时退回 sidecar 数据库；
+// 内存驱动自带死属性支持，无需额外存储。
+func NewPropStore(cfg config.WebDAVConfig, driver Driver, logger *zap.Logger) (PropStore, error) {
+	switch cfg.DeadProps.Driver {
+	case "none":
+		return nil, nil
+	case "bolt":
+		return NewBoltPropStore(cfg.DeadProps.Path)
+	case "xattr":
+		local, ok := driver.(*LocalDriver)
+		if !ok {
+			return nil, errors.New("xattr dead props require the local storage driver")
+		}
+		if err := probeXattr(local.baseDir); err != nil {
+			return nil, fmt.Errorf("extended attributes not supported: %w", err)
+		}
+		return NewXattrPropStore(local), nil
+	case "", "auto":
+		switch d := driver.(type) {
+		case *MemoryDriver:
+			return nil, nil
+		case *LocalDriver:
+			err := probeXattr(d.baseDir)
+			if err == nil {
+				return NewXattrPropStore(d), nil
+			}
+			logger.Info("extended attributes not supported, using sidecar database for dead props",
+				zap.String("directory", d.baseDir),
+				zap.Error(err))
+		}
+		if cfg.DeadProps.Path == "" {
+			return nil, errors.New("dead_props.path is required when extended attributes are unavailable")
+		}
+		return NewBoltPropStore(cfg.DeadProps.Path)
+	default:
+		return nil, fmt.Errorf("unsupported dead_props driver: %s", cfg.DeadProps.Driver)
+	}
+}
+
+// deadPropsFileSystem 为文件提供死属性支持的文件系统包装
+//
+// 删除和移动资源时同步删除和移动其死属性。
+type deadPropsFileSystem struct {
+	webdav.FileSystem
+	store PropStore
+	root  string
+}
+
+// NewDeadPropsFileSystem 包装文件系统，使其文件实现 webdav.DeadPropsHolder
+func NewDeadPropsFileSystem(fs webdav.FileSystem, store PropStore, root string) webdav.FileSystem {
+	return &deadPropsFileSystem{FileSystem: fs, store: store, root: root}
+}
+
+// OpenFile 打开文件
+func (fs *deadPropsFileSystem) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (webdav.File, error) {
+	// PROPPATCH 以读写方式打开资源，目录只能以只读方式打开
+	if flag&(os.O_WRONLY|os.O_RDWR) != 0 && flag&(os.O_CREATE|os.O_TRUNC) == 0 {
+		if info, err := fs.FileSystem.Stat(ctx, name); err == nil && info.IsDir() {
+			flag = os.O_RDONLY
+		}
+	}
+
+	f, err := fs.FileSystem.OpenFile(ctx, name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+
+	return &deadPropsFile{File: f, ctx: ctx, fs: fs, name: cleanName(name)}, nil
+}
+
+// RemoveAll 删除资源及其死属性
+func (fs *deadPropsFileSystem) RemoveAll(ctx context.Context, name string) error {
+	if err := fs.FileSystem.RemoveAll(ctx, name); err != nil {
+		return err
+	}
+	return fs.store.Remove(ctx, fs.root, cleanName(name))
+}
+
+// Rename 移动资源及其死属性
+func (fs *deadPropsFileSystem) Rename(ctx context.Context, oldName, newName string) error {
+	if err := fs.FileSystem.Rename(ctx, oldName, newName); err != nil {
+		return err
+	}
+	return fs.store.Move(ctx, fs.root, cleanName(oldName), cleanName(newName))
+}
+
+// deadPropsFile 支持死属性的文件
+type deadPropsFile struct {
+	webdav.File
+	ctx  context.Context
+	fs   *deadPropsFileSystem
+	name string
+}
+
+// DeadProps 返回死属性
+func (f *deadPropsFile) DeadProps() (map[xml.Name]webdav.Property, error) {
+	return f.fs.store.Props(f.ctx, f.fs.root, f.name)
+}
+
+// Patch 修改死属性
+func (f *deadPropsFile) Patch(patches []webdav.Proppatch) ([]webdav.Propstat, error) {
+	return f.fs.store.Patch(f.ctx, f.fs.root, f.name, patches)
+}
+
+// storedProperty 序列化的死属性
+type storedProperty struct {
+	Space    string `json:"space"`
+	Local    string `json:"local"`
+	Lang     string `json:"lang,omitempty"`
+	InnerXML []byte `json:"inner_xml,omitempty"`
+}
+
+// encodeProps 序列化死属性
+func encodeProps(props map[xml.Name]webdav.Property) ([]byte, error) {
+	stored := make([]storedProperty, 0, len(props))
+	for name, prop := range props {
+		stored = append(stored, storedProperty{
+			Space:    name.Space,
+			Local:    name.Local,
+			Lang:     prop.Lang,
+			InnerXML: prop.InnerXML,
+		})
+	}
+
+	data, err := json.Marshal(stored)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode dead props: %w", err)
+	}
+	return data, nil
+}
+
+// decodeProps 反序列化死属性
+func decodeProps(data []byte) (map[xml.Name]webdav.Property, error) {
+	props := make(map[xml.Name]webdav.Property)
+	if len(data) == 0 {
+		return props, nil
+	}
+
+	var stored []storedProperty
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, fmt.Errorf("failed to decode dead props: %w", err)
+	}
+
+	for _, s := range stored {
+		name := xml.Name{Space: s.Space, Local: s.Local}
+		props[name] = webdav.Property{
+			XMLName:  name,
+			Lang:     s.Lang,
+			InnerXML: s.InnerXML,
+		}
+	}
+	return props, nil
+}
+
+// applyPatches 将修改应用到死属性集合，返回 PROPPATCH 结果
+func applyPatches(props map[xml.Name]webdav.Property, patches []webdav.Proppatch) []webdav.Propstat {
+	propstat := webdav.Propstat{Status: http.StatusOK}
+	for _, patch := range patches {
+		for _, prop := range patch.Props {
+			propstat.Props = append(propstat.Props, webdav.Property{XMLName: prop.XMLName})
+			if patch.Remove {
+				delete(props, prop.XMLName)
+				continue
+			}
+			props[prop.XMLName] = prop
+		}
+	}
+	return []webdav.Propstat{propstat}
+}
+
+// propKey 死属性在整个存储中的键
+func propKey(root, name string) string {
+	return path.Join("/", root, name)
+}
@@ -0,0 +1,203 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+	"golang.org/x/net/webdav"
+)
+
+// propsBucket 死属性数据桶
+var propsBucket = []byte("props")
+
+// BoltPropStore sidecar 数据库死属性存储，以资源路径为键
+type BoltPropStore struct {
+	db *bolt.DB
+}
+
+// NewBoltPropStore 创建 sidecar 数据库死属性存储
+func NewBoltPropStore(path string) (*BoltPropStore, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open dead props database: %w", err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(propsBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize dead props database: %w", err)
+	}
+
+	return &BoltPropStore{db: db}, nil
+}
+
+// Props 获取死属性
+func (s *BoltPropStore) Props(ctx context.Context, root, name string) (map[xml.Name]webdav.Property, error) {
+	var props map[xml.Name]webdav.Property
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		props, err = decodeProps(tx.Bucket(propsBucket).Get([]byte(propKey(root, name))))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return props, nil
+}
+
+// Patch 修改死属性
+func (s *BoltPropStore) Patch(ctx context.Context, root, name string, patches []webdav.Proppatch) ([]webdav.Propstat, error) {
+	var result []webdav.Propstat
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(propsBucket)
+		key := []byte(propKey(root, name))
+
+		props, err := decodeProps(bucket.Get(key))
+		if err != nil {
+			return err
+		}
+
+		result = applyPatches(props, patches)
+
+		if len(props) == 0 {
+			return bucket.Delete(key)
+		}
+		data, err := encodeProps(props)
+		if err != nil {
+			return err
+		}
+		return bucket.Put(key, data)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// Copy 复制死属性
+func (s *BoltPropStore) Copy(ctx context.Context, root, src, dst string, recursive bool) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(propsBucket)
+		srcKey, dstKey := propKey(root, src), propKey(root, dst)
+
+		if err := deleteTree(bucket, dstKey); err != nil {
+			return err
+		}
+
+		return forEachInTree(bucket, srcKey, recursive, func(key, value []byte) error {
+			target := dstKey + string(key[len(srcKey):])
+			return bucket.Put([]byte(target), append([]byte(nil), value...))
+		})
+	})
+}
+
+// Move 移动死属性
+func (s *BoltPropStore) Move(ctx context.Context, root, src, dst string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(propsBucket)
+		srcKey, dstKey := propKey(root, src), propKey(root, dst)
+
+		if err := deleteTree(bucket, dstKey); err != nil {
+			return err
+		}
+
+		type entry struct{ key, value []byte }
+		var moved []entry
+		err := forEachInTree(bucket, srcKey, true, func(key, value []byte) error {
+			moved = append(moved, entry{
+				key:   append([]byte(nil), key...),
+				value: append([]byte(nil), value...),
+			})
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, e := range moved {
+			if err := bucket.Delete(e.key); err != nil {
+				return err
+			}
+			target := dstKey + string(e.key[len(srcKey):])
+			if err := bucket.Put([]byte(target), e.value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Remove 删除死属性
+func (s *BoltPropStore) Remove(ctx context.Context, root, name string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return deleteTree(tx.Bucket(propsBucket), propKey(root, name))
+	})
+}
+
+// Close 关闭存储
+func (s *BoltPropStore) Close() error {
+	return s.db.Close()
+}
+
+// forEachInTree 遍历 key 本身及（recursive 时）其子路径的条目
+func forEachInTree(bucket *bolt.Bucket, key string, recursive bool, fn func(key, value []byte) error) error {
+	if value := bucket.Get([]byte(key)); value != nil {
+		if err := fn([]byte(key), value); err != nil {
+			return err
+		}
+	}
+	if !recursive {
+		return nil
+	}
+
+	prefix := []byte(key + "/")
+	if key == "/" {
+		prefix = []byte("/")
+	}
+
+	// 先收集再回调，避免回调中修改桶影响游标
+	var keys, values [][]byte
+	c := bucket.Cursor()
+	for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+		if string(k) == key {
+			continue
+		}
+		keys = append(keys, append([]byte(nil), k...))
+		values = append(values, append([]byte(nil), v...))
+	}
+
+	for i := range keys {
+		if err := fn(keys[i], values[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteTree 删除 key 及其子路径的条目
+func deleteTree(bucket *bolt.Bucket, key string) error {
+	var keys [][]byte
+	err := forEachInTree(bucket, key, true, func(k, _ []byte) error {
+		keys = append(keys, k)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, k := range keys {
+		if err := bucket.Delete(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
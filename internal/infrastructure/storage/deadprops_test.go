@@ -0,0 +1,219 @@
+package storage
+
+import (
+	"context"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.uber.org/zap"
+	"golang.org/x/net/webdav"
+)
+
+// testPropName 测试使用的死属性名
+var testPropName = xml.Name{Space: "urn:test", Local: "color"}
+
+// propStores 需要覆盖的死属性存储实现
+var propStores = map[string]func(t *testing.T, driver *LocalDriver) PropStore{
+	"xattr": func(t *testing.T, driver *LocalDriver) PropStore {
+		if err := probeXattr(driver.baseDir); err != nil {
+			t.Skipf("extended attributes not supported: %v", err)
+		}
+		return NewXattrPropStore(driver)
+	},
+	"bolt": func(t *testing.T, driver *LocalDriver) PropStore {
+		store, err := NewBoltPropStore(filepath.Join(t.TempDir(), "props.db"))
+		if err != nil {
+			t.Fatalf("NewBoltPropStore: %v", err)
+		}
+		t.Cleanup(func() { store.Close() })
+		return store
+	},
+}
+
+// deadPropsFixture 本地磁盘上带死属性存储的用户文件系统
+type deadPropsFixture struct {
+	t       *testing.T
+	store   PropStore
+	fs      webdav.FileSystem
+	handler *webdav.Handler
+}
+
+func newDeadPropsFixture(t *testing.T, newStore func(t *testing.T, driver *LocalDriver) PropStore) *deadPropsFixture {
+	t.Helper()
+
+	driver := NewLocalDriver(t.TempDir(), zap.NewNop())
+	store := newStore(t, driver)
+	base, err := driver.FileSystem(context.Background(), "alice")
+	if err != nil {
+		t.Fatalf("FileSystem: %v", err)
+	}
+	fs := NewDeadPropsFileSystem(base, store, "alice")
+
+	return &deadPropsFixture{
+		t:       t,
+		store:   store,
+		fs:      fs,
+		handler: &webdav.Handler{FileSystem: fs, LockSystem: webdav.NewMemLS()},
+	}
+}
+
+// mkdir 创建目录
+func (f *deadPropsFixture) mkdir(name string) {
+	f.t.Helper()
+
+	if err := f.fs.Mkdir(context.Background(), name, 0755); err != nil {
+		f.t.Fatalf("Mkdir %s: %v", name, err)
+	}
+}
+
+// setProp 以 PROPPATCH 的方式设置资源的死属性
+func (f *deadPropsFixture) setProp(name, value string) {
+	f.t.Helper()
+
+	file, err := f.fs.OpenFile(context.Background(), name, os.O_RDWR, 0)
+	if err != nil {
+		f.t.Fatalf("OpenFile %s: %v", name, err)
+	}
+	defer file.Close()
+
+	patch := webdav.Proppatch{Props: []webdav.Property{{XMLName: testPropName, InnerXML: []byte(value)}}}
+	if _, err := file.(webdav.DeadPropsHolder).Patch([]webdav.Proppatch{patch}); err != nil {
+		f.t.Fatalf("Patch %s: %v", name, err)
+	}
+}
+
+// prop 读取资源的死属性，没有时返回空字符串
+func (f *deadPropsFixture) prop(name string) string {
+	f.t.Helper()
+
+	file, err := f.fs.OpenFile(context.Background(), name, os.O_RDONLY, 0)
+	if err != nil {
+		f.t.Fatalf("OpenFile %s: %v", name, err)
+	}
+	defer file.Close()
+
+	props, err := file.(webdav.DeadPropsHolder).DeadProps()
+	if err != nil {
+		f.t.Fatalf("DeadProps %s: %v", name, err)
+	}
+	return string(props[testPropName].InnerXML)
+}
+
+// do 发送 WebDAV 请求，COPY/MOVE 允许覆盖目标
+func (f *deadPropsFixture) do(method, target, destination string) {
+	f.t.Helper()
+
+	r := httptest.NewRequest(method, target, nil)
+	if destination != "" {
+		r.Header.Set("Destination", destination)
+		r.Header.Set("Overwrite", "T")
+	}
+	w := httptest.NewRecorder()
+	f.handler.ServeHTTP(w, r)
+	if w.Code != http.StatusCreated && w.Code != http.StatusNoContent {
+		f.t.Fatalf("%s %s: status = %d", method, target, w.Code)
+	}
+}
+
+// expectProps 检查资源的死属性
+func (f *deadPropsFixture) expectProps(want map[string]string) {
+	f.t.Helper()
+
+	for name, value := range want {
+		if got := f.prop(name); got != value {
+			f.t.Errorf("prop(%s) = %q, want %q", name, got, value)
+		}
+	}
+}
+
+// newTree 创建 /a、/a/sub、/a/f.txt、/a/sub/g.txt 并设置死属性
+func (f *deadPropsFixture) newTree() {
+	f.t.Helper()
+
+	f.mkdir("/a")
+	f.mkdir("/a/sub")
+	writeTestFile(f.t, f.fs, "/a/f.txt", 1)
+	writeTestFile(f.t, f.fs, "/a/sub/g.txt", 1)
+	f.setProp("/a", "dir")
+	f.setProp("/a/sub", "subdir")
+	f.setProp("/a/f.txt", "file")
+	f.setProp("/a/sub/g.txt", "nested")
+}
+
+func TestDeadPropsMove(t *testing.T) {
+	for name, newStore := range propStores {
+		t.Run(name, func(t *testing.T) {
+			f := newDeadPropsFixture(t, newStore)
+			f.newTree()
+
+			f.do("MOVE", "/a", "/b")
+			f.expectProps(map[string]string{"/b": "dir", "/b/sub": "subdir", "/b/f.txt": "file", "/b/sub/g.txt": "nested"})
+
+			// 原路径上新建的资源不继承移走的死属性
+			f.mkdir("/a")
+			writeTestFile(t, f.fs, "/a/f.txt", 1)
+			f.expectProps(map[string]string{"/a": "", "/a/f.txt": ""})
+
+			// 覆盖移动时目标的死属性被替换
+			writeTestFile(t, f.fs, "/c.txt", 1)
+			f.setProp("/c.txt", "old")
+			f.do("MOVE", "/b/f.txt", "/c.txt")
+			f.expectProps(map[string]string{"/c.txt": "file"})
+		})
+	}
+}
+
+func TestDeadPropsCopy(t *testing.T) {
+	for name, newStore := range propStores {
+		t.Run(name, func(t *testing.T) {
+			f := newDeadPropsFixture(t, newStore)
+			f.newTree()
+
+			// webdav 处理器只复制文件的死属性，目录由 WebDAVService 在复制成功后补齐
+			f.do("COPY", "/a", "/b")
+			if err := f.store.Copy(context.Background(), "alice", "/a", "/b", true); err != nil {
+				t.Fatalf("Copy: %v", err)
+			}
+			f.expectProps(map[string]string{
+				"/a": "dir", "/a/sub": "subdir", "/a/f.txt": "file", "/a/sub/g.txt": "nested",
+				"/b": "dir", "/b/sub": "subdir", "/b/f.txt": "file", "/b/sub/g.txt": "nested",
+			})
+
+			// 副本的死属性独立于源
+			f.setProp("/b/f.txt", "changed")
+			f.expectProps(map[string]string{"/a/f.txt": "file", "/b/f.txt": "changed"})
+
+			// Depth: 0 只复制集合本身
+			f.mkdir("/c")
+			writeTestFile(t, f.fs, "/c/f.txt", 1)
+			if err := f.store.Copy(context.Background(), "alice", "/a", "/c", false); err != nil {
+				t.Fatalf("Copy: %v", err)
+			}
+			f.expectProps(map[string]string{"/c": "dir", "/c/f.txt": ""})
+		})
+	}
+}
+
+func TestDeadPropsDelete(t *testing.T) {
+	for name, newStore := range propStores {
+		t.Run(name, func(t *testing.T) {
+			f := newDeadPropsFixture(t, newStore)
+			f.newTree()
+			writeTestFile(t, f.fs, "/keep.txt", 1)
+			f.setProp("/keep.txt", "kept")
+
+			f.do("DELETE", "/a", "")
+
+			// 同名资源重新创建后没有旧的死属性
+			f.mkdir("/a")
+			f.mkdir("/a/sub")
+			writeTestFile(t, f.fs, "/a/f.txt", 1)
+			writeTestFile(t, f.fs, "/a/sub/g.txt", 1)
+			f.expectProps(map[string]string{"/a": "", "/a/sub": "", "/a/f.txt": "", "/a/sub/g.txt": "", "/keep.txt": "kept"})
+		})
+	}
+}
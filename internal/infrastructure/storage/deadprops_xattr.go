@@ -0,0 +1,146 @@
+package storage
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"golang.org/x/net/webdav"
+)
+
+// xattrName 保存死属性的扩展属性名
+const xattrName = "user.webdav.props"
+
+// XattrPropStore 扩展属性死属性存储
+//
+// 死属性直接保存在文件的扩展属性中，移动和删除时随文件一起处理。
+type XattrPropStore struct {
+	driver *LocalDriver
+	mu     sync.Mutex
+}
+
+// NewXattrPropStore 创建扩展属性死属性存储
+func NewXattrPropStore(driver *LocalDriver) *XattrPropStore {
+	return &XattrPropStore{driver: driver}
+}
+
+// Props 获取死属性
+func (s *XattrPropStore) Props(ctx context.Context, root, name string) (map[xml.Name]webdav.Property, error) {
+	data, err := getXattr(s.osPath(root, name))
+	if err != nil {
+		return nil, err
+	}
+	return decodeProps(data)
+}
+
+// Patch 修改死属性
+func (s *XattrPropStore) Patch(ctx context.Context, root, name string, patches []webdav.Proppatch) ([]webdav.Propstat, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p := s.osPath(root, name)
+	data, err := getXattr(p)
+	if err != nil {
+		return nil, err
+	}
+
+	props, err := decodeProps(data)
+	if err != nil {
+		return nil, err
+	}
+
+	result := applyPatches(props, patches)
+
+	if len(props) == 0 {
+		if err := removeXattr(p); err != nil {
+			return nil, err
+		}
+		return result, nil
+	}
+
+	data, err = encodeProps(props)
+	if err != nil {
+		return nil, err
+	}
+	if err := setXattr(p, data); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// Copy 复制死属性（文件复制时由 webdav 处理器复制，这里补齐目录）
+func (s *XattrPropStore) Copy(ctx context.Context, root, src, dst string, recursive bool) error {
+	srcPath, dstPath := s.osPath(root, src), s.osPath(root, dst)
+
+	if !recursive {
+		return copyXattr(srcPath, dstPath)
+	}
+
+	return filepath.WalkDir(srcPath, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(srcPath, p)
+		if err != nil {
+			return err
+		}
+		return copyXattr(p, filepath.Join(dstPath, rel))
+	})
+}
+
+// Move 扩展属性随文件移动，无需处理
+func (s *XattrPropStore) Move(ctx context.Context, root, src, dst string) error {
+	return nil
+}
+
+// Remove 扩展属性随文件删除，无需处理
+func (s *XattrPropStore) Remove(ctx context.Context, root, name string) error {
+	return nil
+}
+
+// Close 关闭存储
+func (s *XattrPropStore) Close() error {
+	return nil
+}
+
+// osPath 资源对应的本地路径（与 webdav.Dir 的解析方式一致）
+func (s *XattrPropStore) osPath(root, name string) string {
+	return filepath.Join(s.driver.resolve(root), filepath.FromSlash(cleanName(name)))
+}
+
+// copyXattr 复制单个文件的死属性
+func copyXattr(src, dst string) error {
+	data, err := getXattr(src)
+	if err != nil {
+		return err
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	if err := setXattr(dst, data); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// probeXattr 检查目录所在文件系统是否支持扩展属性
+func probeXattr(dir string) error {
+	f, err := os.CreateTemp(dir, ".webdav-xattr-probe-*")
+	if err != nil {
+		return err
+	}
+	name := f.Name()
+	f.Close()
+	defer os.Remove(name)
+
+	if err := setXattr(name, []byte("[]")); err != nil {
+		return err
+	}
+	_, err = getXattr(name)
+	return err
+}
@@ -0,0 +1,36 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/yeying-community/webdav/internal/infrastructure/config"
+	"go.uber.org/zap"
+	"golang.org/x/net/webdav"
+)
+
+// Driver 存储驱动接口
+type Driver interface {
+	// Name 驱动名称
+	Name() string
+
+	// FileSystem 获取以 root 为根目录的文件系统（根目录不存在时自动创建）
+	FileSystem(ctx context.Context, root string) (webdav.FileSystem, error)
+
+	// Close 释放驱动资源
+	Close() error
+}
+
+// NewDriver 根据配置创建存储驱动
+func NewDriver(cfg config.WebDAVConfig, logger *zap.Logger) (Driver, error) {
+	switch cfg.Storage.Driver {
+	case "", "local":
+		return NewLocalDriver(cfg.Directory, logger), nil
+	case "memory":
+		return NewMemoryDriver(), nil
+	case "s3":
+		return NewS3Driver(cfg.Storage.S3, logger)
+	default:
+		return nil, fmt.Errorf("unsupported storage driver: %s", cfg.Storage.Driver)
+	}
+}
@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"go.uber.org/zap"
+	"golang.org/x/net/webdav"
+)
+
+// LocalDriver 本地磁盘存储驱动
+type LocalDriver struct {
+	baseDir string
+	logger  *zap.Logger
+}
+
+// NewLocalDriver 创建本地磁盘存储驱动
+func NewLocalDriver(baseDir string, logger *zap.Logger) *LocalDriver {
+	return &LocalDriver{
+		baseDir: baseDir,
+		logger:  logger,
+	}
+}
+
+// Name 驱动名称
+func (d *LocalDriver) Name() string {
+	return "local"
+}
+
+// FileSystem 获取以 root 为根目录的文件系统
+func (d *LocalDriver) FileSystem(ctx context.Context, root string) (webdav.FileSystem, error) {
+	dir := d.resolve(root)
+
+	if err := d.ensureDirectory(dir); err != nil {
+		return nil, err
+	}
+
+	return webdav.Dir(dir), nil
+}
+
+// Close 释放驱动资源
+func (d *LocalDriver) Close() error {
+	return nil
+}
+
+// resolve 解析根目录的实际路径
+func (d *LocalDriver) resolve(root string) string {
+	if root == "" {
+		return d.baseDir
+	}
+
+	// 如果是绝对路径，直接使用
+	if filepath.IsAbs(root) {
+		return root
+	}
+
+	// 否则拼接到基础目录
+	return filepath.Join(d.baseDir, root)
+}
+
+// ensureDirectory 确保目录存在
+func (d *LocalDriver) ensureDirectory(dir string) error {
+	info, err := os.Stat(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// 创建目录
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				return fmt.Errorf("failed to create directory: %w", err)
+			}
+			d.logger.Info("directory created", zap.String("directory", dir))
+			return nil
+		}
+		return fmt.Errorf("failed to stat directory: %w", err)
+	}
+
+	if !info.IsDir() {
+		return fmt.Errorf("path is not a directory: %s", dir)
+	}
+
+	return nil
+}
@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path"
+	"strings"
+
+	"golang.org/x/net/webdav"
+)
+
+// MemoryDriver 内存存储驱动（数据不持久化，用于开发和测试）
+type MemoryDriver struct {
+	fs webdav.FileSystem
+}
+
+// NewMemoryDriver 创建内存存储驱动
+func NewMemoryDriver() *MemoryDriver {
+	return &MemoryDriver{
+		fs: webdav.NewMemFS(),
+	}
+}
+
+// Name 驱动名称
+func (d *MemoryDriver) Name() string {
+	return "memory"
+}
+
+// FileSystem 获取以 root 为根目录的文件系统
+func (d *MemoryDriver) FileSystem(ctx context.Context, root string) (webdav.FileSystem, error) {
+	root = path.Clean("/" + root)
+
+	// 逐级创建根目录
+	current := "/"
+	for _, segment := range strings.Split(strings.Trim(root, "/"), "/") {
+		if segment == "" {
+			continue
+		}
+		current = path.Join(current, segment)
+		if err := d.fs.Mkdir(ctx, current, 0755); err != nil && !os.IsExist(err) {
+			return nil, err
+		}
+	}
+
+	if root == "/" {
+		return d.fs, nil
+	}
+
+	return &subFileSystem{fs: d.fs, root: root}, nil
+}
+
+// Close 释放驱动资源
+func (d *MemoryDriver) Close() error {
+	return nil
+}
+
+// subFileSystem 将文件系统的子目录作为根目录暴露
+type subFileSystem struct {
+	fs   webdav.FileSystem
+	root string
+}
+
+func (s *subFileSystem) resolve(name string) string {
+	return path.Join(s.root, path.Clean("/"+name))
+}
+
+func (s *subFileSystem) Mkdir(ctx context.Context, name string, perm os.FileMode) error {
+	return s.fs.Mkdir(ctx, s.resolve(name), perm)
+}
+
+func (s *subFileSystem) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (webdav.File, error) {
+	return s.fs.OpenFile(ctx, s.resolve(name), flag, perm)
+}
+
+func (s *subFileSystem) RemoveAll(ctx context.Context, name string) error {
+	// 禁止删除虚拟根目录
+	if path.Clean("/"+name) == "/" {
+		return os.ErrInvalid
+	}
+	return s.fs.RemoveAll(ctx, s.resolve(name))
+}
+
+func (s *subFileSystem) Rename(ctx context.Context, oldName, newName string) error {
+	return s.fs.Rename(ctx, s.resolve(oldName), s.resolve(newName))
+}
+
+func (s *subFileSystem) Stat(ctx context.Context, name string) (os.FileInfo, error) {
+	return s.fs.Stat(ctx, s.resolve(name))
+}
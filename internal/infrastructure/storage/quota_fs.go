@@ -0,0 +1,144 @@
+package storage
+
+import (
+	"context"
+	"encoding/xml"
+	"net/http"
+	"os"
+	"strconv"
+
+	"golang.org/x/net/webdav"
+)
+
+var (
+	// quotaUsedBytesName RFC 4331 已用字节属性
+	quotaUsedBytesName = xml.Name{Space: "DAV:", Local: "quota-used-bytes"}
+
+	// quotaAvailableBytesName RFC 4331 可用字节属性
+	quotaAvailableBytesName = xml.Name{Space: "DAV:", Local: "quota-available-bytes"}
+)
+
+// QuotaFunc 返回已用字节数和配额（配额为 0 表示不限）
+type QuotaFunc func(ctx context.Context) (used, limit int64, err error)
+
+// quotaFileSystem 在 PROPFIND 中提供 RFC 4331 配额属性的文件系统包装
+type quotaFileSystem struct {
+	webdav.FileSystem
+	quota QuotaFunc
+}
+
+// NewQuotaFileSystem 包装文件系统，使集合返回 DAV:quota-used-bytes 和 DAV:quota-available-bytes
+func NewQuotaFileSystem(fs webdav.FileSystem, quota QuotaFunc) webdav.FileSystem {
+	return &quotaFileSystem{FileSystem: fs, quota: quota}
+}
+
+// OpenFile 打开文件，集合附加配额属性
+func (fs *quotaFileSystem) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (webdav.File, error) {
+	f, err := fs.FileSystem.OpenFile(ctx, name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := f.Stat()
+	if err != nil || !info.IsDir() {
+		return f, nil
+	}
+
+	return &quotaFile{File: f, ctx: ctx, quota: fs.quota}, nil
+}
+
+// quotaFile 附加配额属性的集合文件
+type quotaFile struct {
+	webdav.File
+	ctx   context.Context
+	quota QuotaFunc
+}
+
+// DeadProps 返回配额属性以及底层文件的死属性
+func (f *quotaFile) DeadProps() (map[xml.Name]webdav.Property, error) {
+	props := make(map[xml.Name]webdav.Property)
+
+	if holder, ok := f.File.(webdav.DeadPropsHolder); ok {
+		inner, err := holder.DeadProps()
+		if err != nil {
+			return nil, err
+		}
+		for name, prop := range inner {
+			props[name] = prop
+		}
+	}
+
+	used, limit, err := f.quota(f.ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	props[quotaUsedBytesName] = webdav.Property{
+		XMLName:  quotaUsedBytesName,
+		InnerXML: []byte(strconv.FormatInt(used, 10)),
+	}
+
+	// 不限配额时省略可用字节（RFC 4331 允许）
+	if limit > 0 {
+		available := limit - used
+		if available < 0 {
+			available = 0
+		}
+		props[quotaAvailableBytesName] = webdav.Property{
+			XMLName:  quotaAvailableBytesName,
+			InnerXML: []byte(strconv.FormatInt(available, 10)),
+		}
+	}
+
+	return props, nil
+}
+
+// Patch 配额属性为受保护属性，其余属性交给底层文件处理
+func (f *quotaFile) Patch(patches []webdav.Proppatch) ([]webdav.Propstat, error) {
+	var protected []webdav.Property
+	var rest []webdav.Proppatch
+
+	for _, patch := range patches {
+		var props []webdav.Property
+		for _, prop := range patch.Props {
+			if prop.XMLName == quotaUsedBytesName || prop.XMLName == quotaAvailableBytesName {
+				protected = append(protected, webdav.Property{XMLName: prop.XMLName})
+				continue
+			}
+			props = append(props, prop)
+		}
+		if len(props) > 0 {
+			rest = append(rest, webdav.Proppatch{Remove: patch.Remove, Props: props})
+		}
+	}
+
+	var result []webdav.Propstat
+	if len(protected) > 0 {
+		result = append(result, webdav.Propstat{
+			Props:  protected,
+			Status: http.StatusForbidden,
+		})
+	}
+
+	if len(rest) == 0 {
+		return result, nil
+	}
+
+	holder, ok := f.File.(webdav.DeadPropsHolder)
+	if !ok {
+		var props []webdav.Property
+		for _, patch := range rest {
+			for _, prop := range patch.Props {
+				props = append(props, webdav.Property{XMLName: prop.XMLName})
+			}
+		}
+		return append(result, webdav.Propstat{Props: props, Status: http.StatusForbidden}), nil
+	}
+
+	inner, err := holder.Patch(rest)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(result, inner...), nil
+}
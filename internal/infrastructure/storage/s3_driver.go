@@ -0,0 +1,377 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/yeying-community/webdav/internal/infrastructure/config"
+	"go.uber.org/zap"
+	"golang.org/x/net/webdav"
+)
+
+var (
+	errIsDirectory  = errors.New("is a directory")
+	errNotDirectory = errors.New("not a directory")
+	errReadOnlyFile = errors.New("file is not opened for writing")
+)
+
+// S3Driver S3 兼容对象存储驱动
+//
+// 集合（目录）映射为以 "/" 结尾的 key 前缀，空目录使用零字节的目录标记对象表示。
+type S3Driver struct {
+	client   *minio.Client
+	bucket   string
+	prefix   string
+	partSize uint64
+	logger   *zap.Logger
+}
+
+// NewS3Driver 创建 S3 存储驱动
+func NewS3Driver(cfg config.S3Config, logger *zap.Logger) (*S3Driver, error) {
+	lookup := minio.BucketLookupAuto
+	if cfg.PathStyle {
+		lookup = minio.BucketLookupPath
+	}
+
+	client, err := minio.New(cfg.Endpoint, &minio.Options{
+		Creds:        credentials.NewStaticV4(cfg.AccessKey, cfg.SecretKey, ""),
+		Secure:       cfg.UseSSL,
+		Region:       cfg.Region,
+		BucketLookup: lookup,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create s3 client: %w", err)
+	}
+
+	return &S3Driver{
+		client:   client,
+		bucket:   cfg.Bucket,
+		prefix:   strings.Trim(cfg.Prefix, "/"),
+		partSize: cfg.PartSize,
+		logger:   logger,
+	}, nil
+}
+
+// Name 驱动名称
+func (d *S3Driver) Name() string {
+	return "s3"
+}
+
+// FileSystem 获取以 root 为根目录的文件系统
+func (d *S3Driver) FileSystem(ctx context.Context, root string) (webdav.FileSystem, error) {
+	return &s3FileSystem{
+		driver: d,
+		root:   strings.Trim(path.Join(d.prefix, root), "/"),
+	}, nil
+}
+
+// Close 释放驱动资源
+func (d *S3Driver) Close() error {
+	return nil
+}
+
+// s3FileSystem 基于 S3 key 前缀的文件系统
+type s3FileSystem struct {
+	driver *S3Driver
+	root   string
+}
+
+// key 将 WebDAV 路径映射为对象 key
+func (fs *s3FileSystem) key(name string) string {
+	return strings.Trim(path.Join(fs.root, cleanName(name)), "/")
+}
+
+// dirKey 目录对应的 key 前缀
+func (fs *s3FileSystem) dirKey(name string) string {
+	key := fs.key(name)
+	if key == "" {
+		return ""
+	}
+	return key + "/"
+}
+
+// Mkdir 创建目录标记对象
+func (fs *s3FileSystem) Mkdir(ctx context.Context, name string, perm os.FileMode) error {
+	name = cleanName(name)
+	if name == "/" {
+		return &os.PathError{Op: "mkdir", Path: name, Err: os.ErrExist}
+	}
+
+	if _, err := fs.Stat(ctx, name); err == nil {
+		return &os.PathError{Op: "mkdir", Path: name, Err: os.ErrExist}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	if err := fs.checkParent(ctx, name); err != nil {
+		return err
+	}
+
+	_, err := fs.driver.client.PutObject(ctx, fs.driver.bucket, fs.dirKey(name),
+		bytes.NewReader(nil), 0, minio.PutObjectOptions{ContentType: "application/x-directory"})
+	return err
+}
+
+// OpenFile 打开文件
+func (fs *s3FileSystem) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (webdav.File, error) {
+	name = cleanName(name)
+
+	info, err := fs.Stat(ctx, name)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	exists := err == nil
+
+	if exists && flag&os.O_CREATE != 0 && flag&os.O_EXCL != 0 {
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrExist}
+	}
+
+	// 对象不可原地修改：仅在截断或新建时以写模式打开
+	if flag&os.O_TRUNC != 0 || (!exists && flag&os.O_CREATE != 0) {
+		if exists && info.IsDir() {
+			return nil, &os.PathError{Op: "open", Path: name, Err: errIsDirectory}
+		}
+		if !exists {
+			if err := fs.checkParent(ctx, name); err != nil {
+				return nil, err
+			}
+		}
+		return fs.openWriter(ctx, name), nil
+	}
+
+	if !exists {
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+	}
+
+	if info.IsDir() {
+		return &s3DirFile{fs: fs, ctx: ctx, name: name, info: info}, nil
+	}
+
+	obj, err := fs.driver.client.GetObject(ctx, fs.driver.bucket, fs.key(name), minio.GetObjectOptions{})
+	if err != nil {
+		return nil, mapS3Error("open", name, err)
+	}
+
+	return &s3ReadFile{object: obj, info: info}, nil
+}
+
+// RemoveAll 删除对象及其下所有对象
+func (fs *s3FileSystem) RemoveAll(ctx context.Context, name string) error {
+	name = cleanName(name)
+	// 禁止删除虚拟根目录
+	if name == "/" {
+		return os.ErrInvalid
+	}
+
+	keys, err := fs.listKeys(ctx, fs.dirKey(name))
+	if err != nil {
+		return err
+	}
+	keys = append(keys, fs.key(name))
+
+	return fs.removeKeys(ctx, keys)
+}
+
+// Rename 通过服务端复制和删除实现重命名
+func (fs *s3FileSystem) Rename(ctx context.Context, oldName, newName string) error {
+	oldName, newName = cleanName(oldName), cleanName(newName)
+	if oldName == "/" || newName == "/" {
+		return os.ErrInvalid
+	}
+
+	info, err := fs.Stat(ctx, oldName)
+	if err != nil {
+		return err
+	}
+
+	if err := fs.checkParent(ctx, newName); err != nil {
+		return err
+	}
+
+	if !info.IsDir() {
+		if err := fs.copyObject(ctx, fs.key(oldName), fs.key(newName)); err != nil {
+			return err
+		}
+		return fs.removeKeys(ctx, []string{fs.key(oldName)})
+	}
+
+	oldPrefix, newPrefix := fs.dirKey(oldName), fs.dirKey(newName)
+	keys, err := fs.listKeys(ctx, oldPrefix)
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		if err := fs.copyObject(ctx, key, newPrefix+strings.TrimPrefix(key, oldPrefix)); err != nil {
+			return err
+		}
+	}
+
+	return fs.removeKeys(ctx, keys)
+}
+
+// Stat 获取文件信息
+func (fs *s3FileSystem) Stat(ctx context.Context, name string) (os.FileInfo, error) {
+	name = cleanName(name)
+	if name == "/" {
+		return &s3FileInfo{name: "/", dir: true}, nil
+	}
+
+	objInfo, err := fs.driver.client.StatObject(ctx, fs.driver.bucket, fs.key(name), minio.StatObjectOptions{})
+	if err == nil {
+		return newS3FileInfo(path.Base(name), objInfo), nil
+	}
+	if !isS3NotFound(err) {
+		return nil, mapS3Error("stat", name, err)
+	}
+
+	// 目录：存在目录标记或任何以该前缀开头的对象
+	ok, err := fs.hasPrefix(ctx, fs.dirKey(name))
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, &os.PathError{Op: "stat", Path: name, Err: os.ErrNotExist}
+	}
+
+	return &s3FileInfo{name: path.Base(name), dir: true}, nil
+}
+
+// checkParent 检查父目录是否存在
+func (fs *s3FileSystem) checkParent(ctx context.Context, name string) error {
+	parent := path.Dir(name)
+	if parent == "/" {
+		return nil
+	}
+
+	info, err := fs.Stat(ctx, parent)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return &os.PathError{Op: "stat", Path: parent, Err: errNotDirectory}
+	}
+
+	return nil
+}
+
+// openWriter 打开流式上传的写文件
+func (fs *s3FileSystem) openWriter(ctx context.Context, name string) *s3WriteFile {
+	pr, pw := io.Pipe()
+	f := &s3WriteFile{
+		name:    name,
+		pw:      pw,
+		done:    make(chan error, 1),
+		modTime: time.Now(),
+	}
+
+	// 未知长度的流式上传，超过 PartSize 时自动使用分片上传
+	go func() {
+		_, err := fs.driver.client.PutObject(ctx, fs.driver.bucket, fs.key(name), pr, -1, minio.PutObjectOptions{
+			ContentType: mime.TypeByExtension(path.Ext(name)),
+			PartSize:    fs.driver.partSize,
+		})
+		pr.CloseWithError(err)
+		f.done <- err
+	}()
+
+	return f
+}
+
+// hasPrefix 是否存在以 prefix 开头的对象
+func (fs *s3FileSystem) hasPrefix(ctx context.Context, prefix string) (bool, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	for obj := range fs.driver.client.ListObjects(ctx, fs.driver.bucket, minio.ListObjectsOptions{
+		Prefix:    prefix,
+		Recursive: true,
+		MaxKeys:   1,
+	}) {
+		if obj.Err != nil {
+			return false, obj.Err
+		}
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// listKeys 递归列出 prefix 下的所有对象 key
+func (fs *s3FileSystem) listKeys(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+	for obj := range fs.driver.client.ListObjects(ctx, fs.driver.bucket, minio.ListObjectsOptions{
+		Prefix:    prefix,
+		Recursive: true,
+	}) {
+		if obj.Err != nil {
+			return nil, obj.Err
+		}
+		keys = append(keys, obj.Key)
+	}
+	return keys, nil
+}
+
+// removeKeys 批量删除对象
+func (fs *s3FileSystem) removeKeys(ctx context.Context, keys []string) error {
+	objectsCh := make(chan minio.ObjectInfo, len(keys))
+	for _, key := range keys {
+		objectsCh <- minio.ObjectInfo{Key: key}
+	}
+	close(objectsCh)
+
+	var firstErr error
+	for rErr := range fs.driver.client.RemoveObjects(ctx, fs.driver.bucket, objectsCh, minio.RemoveObjectsOptions{}) {
+		if firstErr == nil && !isS3NotFound(rErr.Err) {
+			firstErr = fmt.Errorf("failed to remove %s: %w", rErr.ObjectName, rErr.Err)
+		}
+	}
+	return firstErr
+}
+
+// copyObject 服务端复制对象（大对象自动使用分片复制）
+func (fs *s3FileSystem) copyObject(ctx context.Context, srcKey, dstKey string) error {
+	_, err := fs.driver.client.ComposeObject(ctx,
+		minio.CopyDestOptions{Bucket: fs.driver.bucket, Object: dstKey},
+		minio.CopySrcOptions{Bucket: fs.driver.bucket, Object: srcKey},
+	)
+	if err != nil {
+		return mapS3Error("copy", srcKey, err)
+	}
+	return nil
+}
+
+// cleanName 规范化 WebDAV 路径
+func cleanName(name string) string {
+	return path.Clean("/" + name)
+}
+
+// isS3NotFound 是否为对象不存在错误
+func isS3NotFound(err error) bool {
+	if err == nil {
+		return false
+	}
+	code := minio.ToErrorResponse(err).Code
+	return code == "NoSuchKey" || code == "NotFound"
+}
+
+// mapS3Error 将 S3 错误转换为文件系统错误
+func mapS3Error(op, name string, err error) error {
+	if isS3NotFound(err) {
+		return &os.PathError{Op: op, Path: name, Err: os.ErrNotExist}
+	}
+	if minio.ToErrorResponse(err).Code == "AccessDenied" {
+		return &os.PathError{Op: op, Path: name, Err: os.ErrPermission}
+	}
+	return err
+}
@@ -0,0 +1,679 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/yeying-community/webdav/internal/infrastructure/config"
+	"go.uber.org/zap"
+	"golang.org/x/net/webdav"
+)
+
+const testBucket = "dav"
+
+// fakeObject 内存中的对象
+type fakeObject struct {
+	data    []byte
+	modTime time.Time
+}
+
+func (o *fakeObject) etag() string {
+	sum := md5.Sum(o.data)
+	return hex.EncodeToString(sum[:])
+}
+
+// fakeS3 单个存储桶的 S3 替身，支持驱动用到的对象、列举、批量删除、复制和分片上传接口
+type fakeS3 struct {
+	mu         sync.Mutex
+	objects    map[string]*fakeObject
+	uploads    map[string]map[int][]byte
+	nextUpload int
+	lastParts  int // 最近一次完成的分片上传的分片数
+}
+
+func newFakeS3() *fakeS3 {
+	return &fakeS3{
+		objects: make(map[string]*fakeObject),
+		uploads: make(map[string]map[int][]byte),
+	}
+}
+
+func (s *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rest := strings.TrimPrefix(r.URL.Path, "/")
+	if rest != testBucket && !strings.HasPrefix(rest, testBucket+"/") {
+		s.error(w, http.StatusNotFound, "NoSuchBucket")
+		return
+	}
+	key := strings.TrimPrefix(strings.TrimPrefix(rest, testBucket), "/")
+	q := r.URL.Query()
+
+	if key == "" {
+		switch {
+		case r.Method == http.MethodGet && q.Get("list-type") == "2":
+			s.list(w, q)
+		case r.Method == http.MethodPost && q.Has("delete"):
+			s.deleteObjects(w, r)
+		default:
+			s.error(w, http.StatusNotImplemented, "NotImplemented")
+		}
+		return
+	}
+
+	switch r.Method {
+	case http.MethodHead, http.MethodGet:
+		s.get(w, r, key)
+	case http.MethodPut:
+		switch {
+		case q.Has("uploadId"):
+			s.uploadPart(w, r, q)
+		case r.Header.Get("X-Amz-Copy-Source") != "":
+			s.copy(w, r, key)
+		default:
+			s.objects[key] = &fakeObject{data: readPayload(r), modTime: time.Now().UTC()}
+			w.Header().Set("ETag", `"`+s.objects[key].etag()+`"`)
+		}
+	case http.MethodPost:
+		switch {
+		case q.Has("uploads"):
+			s.nextUpload++
+			id := strconv.Itoa(s.nextUpload)
+			s.uploads[id] = make(map[int][]byte)
+			s.xml(w, struct {
+				XMLName  xml.Name `xml:"InitiateMultipartUploadResult"`
+				Bucket   string
+				Key      string
+				UploadId string
+			}{Bucket: testBucket, Key: key, UploadId: id})
+		case q.Has("uploadId"):
+			s.completeUpload(w, key, q.Get("uploadId"))
+		default:
+			s.error(w, http.StatusNotImplemented, "NotImplemented")
+		}
+	case http.MethodDelete:
+		if q.Has("uploadId") {
+			delete(s.uploads, q.Get("uploadId"))
+		} else {
+			delete(s.objects, key)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		s.error(w, http.StatusMethodNotAllowed, "MethodNotAllowed")
+	}
+}
+
+// get 处理 HEAD 和 GET，支持单个 Range
+func (s *fakeS3) get(w http.ResponseWriter, r *http.Request, key string) {
+	obj, ok := s.objects[key]
+	if !ok {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		s.error(w, http.StatusNotFound, "NoSuchKey")
+		return
+	}
+
+	data := obj.data
+	status := http.StatusOK
+	if rng := r.Header.Get("Range"); rng != "" {
+		start, end := parseRange(rng, int64(len(data)))
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(data)))
+		data = data[start : end+1]
+		status = http.StatusPartialContent
+	}
+
+	w.Header().Set("ETag", `"`+obj.etag()+`"`)
+	w.Header().Set("Last-Modified", obj.modTime.Format(http.TimeFormat))
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.WriteHeader(status)
+	if r.Method == http.MethodGet {
+		w.Write(data)
+	}
+}
+
+// uploadPart 处理 UploadPart 和 UploadPartCopy
+func (s *fakeS3) uploadPart(w http.ResponseWriter, r *http.Request, q url.Values) {
+	parts, ok := s.uploads[q.Get("uploadId")]
+	if !ok {
+		s.error(w, http.StatusNotFound, "NoSuchUpload")
+		return
+	}
+	number, _ := strconv.Atoi(q.Get("partNumber"))
+
+	if r.Header.Get("X-Amz-Copy-Source") == "" {
+		data := readPayload(r)
+		parts[number] = data
+		w.Header().Set("ETag", `"`+(&fakeObject{data: data}).etag()+`"`)
+		return
+	}
+
+	src, ok := s.copySource(r)
+	if !ok {
+		s.error(w, http.StatusNotFound, "NoSuchKey")
+		return
+	}
+	data := src.data
+	if rng := r.Header.Get("X-Amz-Copy-Source-Range"); rng != "" {
+		start, end := parseRange(rng, int64(len(data)))
+		data = data[start : end+1]
+	}
+	parts[number] = append([]byte(nil), data...)
+	s.xml(w, struct {
+		XMLName      xml.Name `xml:"CopyPartResult"`
+		ETag         string
+		LastModified string
+	}{ETag: `"` + (&fakeObject{data: data}).etag() + `"`, LastModified: time.Now().UTC().Format(time.RFC3339)})
+}
+
+// copySource 复制请求的源对象
+func (s *fakeS3) copySource(r *http.Request) (*fakeObject, bool) {
+	source, _ := url.PathUnescape(r.Header.Get("X-Amz-Copy-Source"))
+	source = strings.TrimPrefix(strings.TrimPrefix(source, "/"), testBucket+"/")
+	if i := strings.Index(source, "?"); i >= 0 {
+		source = source[:i]
+	}
+	obj, ok := s.objects[source]
+	return obj, ok
+}
+
+func (s *fakeS3) copy(w http.ResponseWriter, r *http.Request, key string) {
+	src, ok := s.copySource(r)
+	if !ok {
+		s.error(w, http.StatusNotFound, "NoSuchKey")
+		return
+	}
+
+	dst := &fakeObject{data: append([]byte(nil), src.data...), modTime: time.Now().UTC()}
+	s.objects[key] = dst
+	s.xml(w, struct {
+		XMLName      xml.Name `xml:"CopyObjectResult"`
+		ETag         string
+		LastModified string
+	}{ETag: `"` + dst.etag() + `"`, LastModified: dst.modTime.Format(time.RFC3339)})
+}
+
+func (s *fakeS3) completeUpload(w http.ResponseWriter, key, id string) {
+	parts, ok := s.uploads[id]
+	if !ok {
+		s.error(w, http.StatusNotFound, "NoSuchUpload")
+		return
+	}
+
+	numbers := make([]int, 0, len(parts))
+	for n := range parts {
+		numbers = append(numbers, n)
+	}
+	sort.Ints(numbers)
+
+	var data []byte
+	for _, n := range numbers {
+		data = append(data, parts[n]...)
+	}
+	delete(s.uploads, id)
+	s.lastParts = len(numbers)
+
+	obj := &fakeObject{data: data, modTime: time.Now().UTC()}
+	s.objects[key] = obj
+	s.xml(w, struct {
+		XMLName xml.Name `xml:"CompleteMultipartUploadResult"`
+		Bucket  string
+		Key     string
+		ETag    string
+	}{Bucket: testBucket, Key: key, ETag: `"` + obj.etag() + `-1"`})
+}
+
+// list 处理 ListObjectsV2，delimiter 为 / 时合并公共前缀
+func (s *fakeS3) list(w http.ResponseWriter, q url.Values) {
+	type content struct {
+		Key          string
+		LastModified string
+		ETag         string
+		Size         int64
+		StorageClass string
+	}
+	type commonPrefix struct {
+		Prefix string
+	}
+	result := struct {
+		XMLName        xml.Name `xml:"ListBucketResult"`
+		Name           string
+		Prefix         string
+		Delimiter      string `xml:",omitempty"`
+		MaxKeys        int
+		KeyCount       int
+		IsTruncated    bool
+		Contents       []content
+		CommonPrefixes []commonPrefix
+	}{Name: testBucket, Prefix: q.Get("prefix"), Delimiter: q.Get("delimiter"), MaxKeys: 1000}
+
+	if n, err := strconv.Atoi(q.Get("max-keys")); err == nil && n > 0 {
+		result.MaxKeys = n
+	}
+
+	keys := make([]string, 0, len(s.objects))
+	for key := range s.objects {
+		if strings.HasPrefix(key, result.Prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	seen := make(map[string]bool)
+	for _, key := range keys {
+		if result.KeyCount >= result.MaxKeys {
+			break
+		}
+		if result.Delimiter != "" {
+			if i := strings.Index(key[len(result.Prefix):], result.Delimiter); i >= 0 {
+				prefix := key[:len(result.Prefix)+i+1]
+				if !seen[prefix] {
+					seen[prefix] = true
+					result.CommonPrefixes = append(result.CommonPrefixes, commonPrefix{Prefix: prefix})
+					result.KeyCount++
+				}
+				continue
+			}
+		}
+		obj := s.objects[key]
+		result.Contents = append(result.Contents, content{
+			Key:          key,
+			LastModified: obj.modTime.Format("2006-01-02T15:04:05.000Z"),
+			ETag:         `"` + obj.etag() + `"`,
+			Size:         int64(len(obj.data)),
+			StorageClass: "STANDARD",
+		})
+		result.KeyCount++
+	}
+
+	s.xml(w, result)
+}
+
+func (s *fakeS3) deleteObjects(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Objects []struct {
+			Key string
+		} `xml:"Object"`
+	}
+	if err := xml.NewDecoder(bytes.NewReader(readPayload(r))).Decode(&req); err != nil {
+		s.error(w, http.StatusBadRequest, "MalformedXML")
+		return
+	}
+	for _, obj := range req.Objects {
+		delete(s.objects, obj.Key)
+	}
+	s.xml(w, struct {
+		XMLName xml.Name `xml:"DeleteResult"`
+	}{})
+}
+
+func (s *fakeS3) xml(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/xml")
+	xml.NewEncoder(w).Encode(v)
+}
+
+func (s *fakeS3) error(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	xml.NewEncoder(w).Encode(struct {
+		XMLName xml.Name `xml:"Error"`
+		Code    string
+		Message string
+	}{Code: code, Message: code})
+}
+
+// readPayload 读取请求体，解码 aws-chunked 流式签名的分块
+func readPayload(r *http.Request) []byte {
+	if !strings.HasPrefix(r.Header.Get("X-Amz-Content-Sha256"), "STREAMING-") {
+		data, _ := io.ReadAll(r.Body)
+		return data
+	}
+
+	var data []byte
+	br := bufio.NewReader(r.Body)
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			return data
+		}
+		sizeHex := strings.TrimSpace(strings.SplitN(line, ";", 2)[0])
+		size, err := strconv.ParseInt(sizeHex, 16, 64)
+		if err != nil || size == 0 {
+			return data
+		}
+		chunk := make([]byte, size)
+		if _, err := io.ReadFull(br, chunk); err != nil {
+			return data
+		}
+		data = append(data, chunk...)
+		br.ReadString('\n')
+	}
+}
+
+// parseRange 解析 bytes=start-end，end 为闭区间
+func parseRange(header string, size int64) (int64, int64) {
+	spec := strings.TrimPrefix(header, "bytes=")
+	parts := strings.SplitN(spec, "-", 2)
+	if parts[0] == "" {
+		n, _ := strconv.ParseInt(parts[1], 10, 64)
+		return size - n, size - 1
+	}
+	start, _ := strconv.ParseInt(parts[0], 10, 64)
+	end := size - 1
+	if len(parts) == 2 && parts[1] != "" {
+		if e, err := strconv.ParseInt(parts[1], 10, 64); err == nil && e < end {
+			end = e
+		}
+	}
+	return start, end
+}
+
+// newTestS3FileSystem 创建连接到 S3 替身的文件系统，根目录为 tenant/alice
+func newTestS3FileSystem(t *testing.T) (webdav.FileSystem, *fakeS3) {
+	t.Helper()
+
+	fake := newFakeS3()
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	driver, err := NewS3Driver(config.S3Config{
+		Endpoint:  strings.TrimPrefix(server.URL, "http://"),
+		Region:    "us-east-1",
+		Bucket:    testBucket,
+		Prefix:    "tenant",
+		AccessKey: "access",
+		SecretKey: "secret",
+		PathStyle: true,
+		PartSize:  5 << 20,
+	}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewS3Driver: %v", err)
+	}
+
+	fs, err := driver.FileSystem(context.Background(), "/alice")
+	if err != nil {
+		t.Fatalf("FileSystem: %v", err)
+	}
+	return fs, fake
+}
+
+func writeFile(t *testing.T, fs webdav.FileSystem, name string, data []byte) {
+	t.Helper()
+
+	f, err := fs.OpenFile(context.Background(), name, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		t.Fatalf("open %s for writing: %v", name, err)
+	}
+	if _, err := f.Write(data); err != nil {
+		t.Fatalf("write %s: %v", name, err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("close %s: %v", name, err)
+	}
+}
+
+func readFile(t *testing.T, fs webdav.FileSystem, name string) []byte {
+	t.Helper()
+
+	f, err := fs.OpenFile(context.Background(), name, os.O_RDONLY, 0)
+	if err != nil {
+		t.Fatalf("open %s: %v", name, err)
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatalf("read %s: %v", name, err)
+	}
+	return data
+}
+
+func readdirNames(t *testing.T, fs webdav.FileSystem, name string) []string {
+	t.Helper()
+
+	f, err := fs.OpenFile(context.Background(), name, os.O_RDONLY, 0)
+	if err != nil {
+		t.Fatalf("open %s: %v", name, err)
+	}
+	defer f.Close()
+
+	infos, err := f.Readdir(0)
+	if err != nil {
+		t.Fatalf("readdir %s: %v", name, err)
+	}
+	names := make([]string, 0, len(infos))
+	for _, info := range infos {
+		if info.IsDir() {
+			names = append(names, info.Name()+"/")
+		} else {
+			names = append(names, info.Name())
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestS3FileSystemReadWrite(t *testing.T) {
+	ctx := context.Background()
+	fs, fake := newTestS3FileSystem(t)
+
+	if err := fs.Mkdir(ctx, "/docs", 0755); err != nil {
+		t.Fatalf("Mkdir: %v", err)
+	}
+	if _, ok := fake.objects["tenant/alice/docs/"]; !ok {
+		t.Fatalf("directory marker not created, objects: %v", fake.objects)
+	}
+	if err := fs.Mkdir(ctx, "/docs", 0755); !os.IsExist(err) {
+		t.Fatalf("second Mkdir = %v, want ErrExist", err)
+	}
+
+	writeFile(t, fs, "/docs/hello.txt", []byte("hello, world"))
+	if got := string(readFile(t, fs, "/docs/hello.txt")); got != "hello, world" {
+		t.Fatalf("read = %q", got)
+	}
+
+	info, err := fs.Stat(ctx, "/docs/hello.txt")
+	if err != nil {
+		t.Fatalf("Stat file: %v", err)
+	}
+	if info.IsDir() || info.Size() != 12 {
+		t.Fatalf("Stat file = dir %v size %d", info.IsDir(), info.Size())
+	}
+	etag, err := info.(webdav.ETager).ETag(ctx)
+	if err != nil || etag == "" {
+		t.Fatalf("ETag = %q, %v", etag, err)
+	}
+
+	if _, err := fs.Stat(ctx, "/missing"); !os.IsNotExist(err) {
+		t.Fatalf("Stat missing = %v, want ErrNotExist", err)
+	}
+	if _, err := fs.OpenFile(ctx, "/missing/file", os.O_CREATE|os.O_WRONLY, 0644); !os.IsNotExist(err) {
+		t.Fatalf("create under missing parent = %v, want ErrNotExist", err)
+	}
+	if _, err := fs.OpenFile(ctx, "/docs/hello.txt", os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644); !os.IsExist(err) {
+		t.Fatalf("O_EXCL on existing file = %v, want ErrExist", err)
+	}
+	if _, err := fs.OpenFile(ctx, "/docs", os.O_WRONLY|os.O_TRUNC, 0644); err == nil {
+		t.Fatal("opening a directory for writing should fail")
+	}
+}
+
+func TestS3FileSystemImplicitDirectories(t *testing.T) {
+	ctx := context.Background()
+	fs, fake := newTestS3FileSystem(t)
+
+	// 其他工具上传的对象没有目录标记
+	fake.objects["tenant/alice/photos/2024/a.jpg"] = &fakeObject{data: []byte("a"), modTime: time.Now()}
+	fake.objects["tenant/alice/photos/b.jpg"] = &fakeObject{data: []byte("bb"), modTime: time.Now()}
+	fake.objects["tenant/bob/private.txt"] = &fakeObject{data: []byte("bob"), modTime: time.Now()}
+
+	info, err := fs.Stat(ctx, "/photos")
+	if err != nil || !info.IsDir() {
+		t.Fatalf("Stat implicit directory = %v, %v", info, err)
+	}
+
+	if got, want := readdirNames(t, fs, "/photos"), []string{"2024/", "b.jpg"}; !equalStrings(got, want) {
+		t.Fatalf("Readdir /photos = %v, want %v", got, want)
+	}
+	if got, want := readdirNames(t, fs, "/"), []string{"photos/"}; !equalStrings(got, want) {
+		t.Fatalf("Readdir / = %v, want %v (other users' objects must not be visible)", got, want)
+	}
+}
+
+func TestS3FileSystemRangeRead(t *testing.T) {
+	ctx := context.Background()
+	fs, _ := newTestS3FileSystem(t)
+
+	writeFile(t, fs, "/data.bin", []byte("0123456789"))
+
+	f, err := fs.OpenFile(ctx, "/data.bin", os.O_RDONLY, 0)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Seek(6, io.SeekStart); err != nil {
+		t.Fatalf("Seek: %v", err)
+	}
+	buf := make([]byte, 3)
+	if _, err := io.ReadFull(f, buf); err != nil {
+		t.Fatalf("read after seek: %v", err)
+	}
+	if string(buf) != "678" {
+		t.Fatalf("read after seek = %q, want %q", buf, "678")
+	}
+}
+
+func TestS3FileSystemRenameAndRemove(t *testing.T) {
+	ctx := context.Background()
+	fs, fake := newTestS3FileSystem(t)
+
+	if err := fs.Mkdir(ctx, "/src", 0755); err != nil {
+		t.Fatalf("Mkdir: %v", err)
+	}
+	if err := fs.Mkdir(ctx, "/src/sub", 0755); err != nil {
+		t.Fatalf("Mkdir sub: %v", err)
+	}
+	writeFile(t, fs, "/src/a.txt", []byte("a"))
+	writeFile(t, fs, "/src/sub/b.txt", []byte("b"))
+
+	if err := fs.Rename(ctx, "/src", "/dst"); err != nil {
+		t.Fatalf("Rename directory: %v", err)
+	}
+	if _, err := fs.Stat(ctx, "/src"); !os.IsNotExist(err) {
+		t.Fatalf("source still exists after rename: %v", err)
+	}
+	if got := string(readFile(t, fs, "/dst/sub/b.txt")); got != "b" {
+		t.Fatalf("renamed file = %q", got)
+	}
+	if got, want := readdirNames(t, fs, "/dst"), []string{"a.txt", "sub/"}; !equalStrings(got, want) {
+		t.Fatalf("Readdir /dst = %v, want %v", got, want)
+	}
+
+	if err := fs.Rename(ctx, "/dst/a.txt", "/dst/c.txt"); err != nil {
+		t.Fatalf("Rename file: %v", err)
+	}
+	if got := string(readFile(t, fs, "/dst/c.txt")); got != "a" {
+		t.Fatalf("renamed file = %q", got)
+	}
+
+	if err := fs.RemoveAll(ctx, "/dst"); err != nil {
+		t.Fatalf("RemoveAll: %v", err)
+	}
+	for key := range fake.objects {
+		if strings.HasPrefix(key, "tenant/alice/dst") {
+			t.Errorf("object %s left after RemoveAll", key)
+		}
+	}
+	if err := fs.RemoveAll(ctx, "/"); err == nil {
+		t.Error("removing the root should fail")
+	}
+}
+
+func TestS3FileSystemMultipartUpload(t *testing.T) {
+	fs, fake := newTestS3FileSystem(t)
+
+	data := bytes.Repeat([]byte("0123456789abcdef"), (6<<20)/16)
+	writeFile(t, fs, "/large.bin", data)
+
+	if fake.lastParts != 2 {
+		t.Fatalf("uploaded in %d parts, want 2", fake.lastParts)
+	}
+	if got := readFile(t, fs, "/large.bin"); !bytes.Equal(got, data) {
+		t.Fatalf("read %d bytes, want %d", len(got), len(data))
+	}
+}
+
+func TestS3FileSystemWebDAVHandler(t *testing.T) {
+	fs, _ := newTestS3FileSystem(t)
+	handler := &webdav.Handler{FileSystem: fs, LockSystem: webdav.NewMemLS()}
+
+	do := func(method, target, body string, header map[string]string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, target, strings.NewReader(body))
+		for k, v := range header {
+			r.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	if w := do("MKCOL", "/dir", "", nil); w.Code != http.StatusCreated {
+		t.Fatalf("MKCOL = %d", w.Code)
+	}
+	if w := do("PUT", "/dir/file.txt", "content", nil); w.Code != http.StatusCreated {
+		t.Fatalf("PUT = %d", w.Code)
+	}
+	if w := do("GET", "/dir/file.txt", "", map[string]string{"Range": "bytes=2-4"}); w.Code != http.StatusPartialContent || w.Body.String() != "nte" {
+		t.Fatalf("GET range = %d %q", w.Code, w.Body.String())
+	}
+	if w := do("COPY", "/dir", "", map[string]string{"Destination": "/copy"}); w.Code != http.StatusCreated {
+		t.Fatalf("COPY = %d", w.Code)
+	}
+	if w := do("PROPFIND", "/copy", "", map[string]string{"Depth": "1"}); w.Code != http.StatusMultiStatus || !strings.Contains(w.Body.String(), "/copy/file.txt") {
+		t.Fatalf("PROPFIND = %d %s", w.Code, w.Body.String())
+	}
+	if w := do("MOVE", "/copy/file.txt", "", map[string]string{"Destination": "/moved.txt"}); w.Code != http.StatusCreated {
+		t.Fatalf("MOVE = %d", w.Code)
+	}
+	if w := do("GET", "/moved.txt", "", nil); w.Body.String() != "content" {
+		t.Fatalf("GET moved = %d %q", w.Code, w.Body.String())
+	}
+	if w := do("DELETE", "/dir", "", nil); w.Code != http.StatusNoContent {
+		t.Fatalf("DELETE = %d", w.Code)
+	}
+	if w := do("GET", "/dir/file.txt", "", nil); w.Code != http.StatusNotFound {
+		t.Fatalf("GET deleted = %d", w.Code)
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,202 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"golang.org/x/net/webdav"
+)
+
+// s3FileInfo S3 对象信息
+type s3FileInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+	dir     bool
+	etag    string
+}
+
+func newS3FileInfo(name string, info minio.ObjectInfo) *s3FileInfo {
+	return &s3FileInfo{
+		name:    name,
+		size:    info.Size,
+		modTime: info.LastModified,
+		etag:    info.ETag,
+	}
+}
+
+func (fi *s3FileInfo) Name() string       { return fi.name }
+func (fi *s3FileInfo) Size() int64        { return fi.size }
+func (fi *s3FileInfo) ModTime() time.Time { return fi.modTime }
+func (fi *s3FileInfo) IsDir() bool        { return fi.dir }
+func (fi *s3FileInfo) Sys() interface{}   { return nil }
+
+func (fi *s3FileInfo) Mode() os.FileMode {
+	if fi.dir {
+		return os.ModeDir | 0755
+	}
+	return 0644
+}
+
+// ETag 实现 webdav.ETager，直接使用对象存储的 ETag
+func (fi *s3FileInfo) ETag(ctx context.Context) (string, error) {
+	if fi.etag == "" {
+		return "", webdav.ErrNotImplemented
+	}
+	return `"` + fi.etag + `"`, nil
+}
+
+// s3ReadFile 只读对象文件
+//
+// minio.Object 在 Seek 后按需发起 Range 请求，因此 GET 的 Range 请求以流方式返回。
+type s3ReadFile struct {
+	object *minio.Object
+	info   os.FileInfo
+}
+
+func (f *s3ReadFile) Read(p []byte) (int, error) {
+	return f.object.Read(p)
+}
+
+func (f *s3ReadFile) Seek(offset int64, whence int) (int64, error) {
+	return f.object.Seek(offset, whence)
+}
+
+func (f *s3ReadFile) Write(p []byte) (int, error) {
+	return 0, &os.PathError{Op: "write", Path: f.info.Name(), Err: errReadOnlyFile}
+}
+
+func (f *s3ReadFile) Readdir(count int) ([]os.FileInfo, error) {
+	return nil, &os.PathError{Op: "readdir", Path: f.info.Name(), Err: errNotDirectory}
+}
+
+func (f *s3ReadFile) Stat() (os.FileInfo, error) {
+	return f.info, nil
+}
+
+func (f *s3ReadFile) Close() error {
+	return f.object.Close()
+}
+
+// s3WriteFile 流式上传的写文件，关闭时完成上传
+type s3WriteFile struct {
+	name    string
+	pw      *io.PipeWriter
+	done    chan error
+	size    int64
+	modTime time.Time
+}
+
+func (f *s3WriteFile) Write(p []byte) (int, error) {
+	n, err := f.pw.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+func (f *s3WriteFile) Read(p []byte) (int, error) {
+	return 0, &os.PathError{Op: "read", Path: f.name, Err: os.ErrInvalid}
+}
+
+func (f *s3WriteFile) Seek(offset int64, whence int) (int64, error) {
+	return 0, &os.PathError{Op: "seek", Path: f.name, Err: os.ErrInvalid}
+}
+
+func (f *s3WriteFile) Readdir(count int) ([]os.FileInfo, error) {
+	return nil, &os.PathError{Op: "readdir", Path: f.name, Err: errNotDirectory}
+}
+
+func (f *s3WriteFile) Stat() (os.FileInfo, error) {
+	return &s3FileInfo{name: path.Base(f.name), size: f.size, modTime: f.modTime}, nil
+}
+
+func (f *s3WriteFile) Close() error {
+	f.pw.Close()
+	return <-f.done
+}
+
+// s3DirFile 目录文件
+type s3DirFile struct {
+	fs      *s3FileSystem
+	ctx     context.Context
+	name    string
+	info    os.FileInfo
+	entries []os.FileInfo
+	loaded  bool
+}
+
+func (f *s3DirFile) Read(p []byte) (int, error) {
+	return 0, &os.PathError{Op: "read", Path: f.name, Err: errIsDirectory}
+}
+
+func (f *s3DirFile) Seek(offset int64, whence int) (int64, error) {
+	return 0, &os.PathError{Op: "seek", Path: f.name, Err: errIsDirectory}
+}
+
+func (f *s3DirFile) Write(p []byte) (int, error) {
+	return 0, &os.PathError{Op: "write", Path: f.name, Err: errIsDirectory}
+}
+
+func (f *s3DirFile) Stat() (os.FileInfo, error) {
+	return f.info, nil
+}
+
+func (f *s3DirFile) Close() error {
+	return nil
+}
+
+// Readdir 列出目录的直接子项
+func (f *s3DirFile) Readdir(count int) ([]os.FileInfo, error) {
+	if !f.loaded {
+		if err := f.load(); err != nil {
+			return nil, err
+		}
+	}
+
+	if count <= 0 {
+		entries := f.entries
+		f.entries = nil
+		return entries, nil
+	}
+
+	if len(f.entries) == 0 {
+		return nil, io.EOF
+	}
+	if count > len(f.entries) {
+		count = len(f.entries)
+	}
+	entries := f.entries[:count]
+	f.entries = f.entries[count:]
+	return entries, nil
+}
+
+// load 加载目录项
+func (f *s3DirFile) load() error {
+	prefix := f.fs.dirKey(f.name)
+
+	for obj := range f.fs.driver.client.ListObjects(f.ctx, f.fs.driver.bucket, minio.ListObjectsOptions{
+		Prefix: prefix,
+	}) {
+		if obj.Err != nil {
+			return obj.Err
+		}
+		// 跳过目录自身的标记对象
+		if obj.Key == prefix {
+			continue
+		}
+
+		name := strings.TrimSuffix(strings.TrimPrefix(obj.Key, prefix), "/")
+		if strings.HasSuffix(obj.Key, "/") {
+			f.entries = append(f.entries, &s3FileInfo{name: name, dir: true, modTime: obj.LastModified})
+			continue
+		}
+		f.entries = append(f.entries, newS3FileInfo(name, obj))
+	}
+
+	f.loaded = true
+	return nil
+}
@@ -0,0 +1,169 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path"
+	"sync"
+	"time"
+
+	"golang.org/x/net/webdav"
+)
+
+// UsageTracker 存储用量跟踪器
+//
+// 首次访问时遍历文件系统统计用量，之后由写操作按写入或删除的字节数增量更新；
+// 超过 rescanInterval 后在后台重新遍历，以纠正绕过 WebDAV 的修改带来的偏差，
+// 重新统计期间继续返回当前的用量。
+type UsageTracker struct {
+	entries        map[string]*usageEntry
+	rescanInterval time.Duration
+	mu             sync.Mutex
+}
+
+// usageEntry 用量记录
+type usageEntry struct {
+	used      int64
+	scannedAt time.Time
+	scanning  bool // 后台重新统计中
+}
+
+// NewUsageTracker 创建用量跟踪器
+func NewUsageTracker(rescanInterval time.Duration) *UsageTracker {
+	return &UsageTracker{
+		entries:        make(map[string]*usageEntry),
+		rescanInterval: rescanInterval,
+	}
+}
+
+// Usage 获取 key 对应文件系统的已用字节数
+//
+// 只有首次访问时同步遍历文件系统；记录过期时返回当前用量并在后台重新统计。
+func (t *UsageTracker) Usage(ctx context.Context, key string, fs webdav.FileSystem) (int64, error) {
+	t.mu.Lock()
+	entry, ok := t.entries[key]
+	if ok {
+		used := entry.used
+		if !entry.scanning && time.Since(entry.scannedAt) >= t.rescanInterval {
+			entry.scanning = true
+			go t.rescan(key, fs)
+		}
+		t.mu.Unlock()
+		return used, nil
+	}
+	t.mu.Unlock()
+
+	used, err := TreeSize(ctx, fs, "/")
+	if err != nil {
+		return 0, err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// 并发的首次访问或后台统计可能已经写入了记录
+	if entry, ok := t.entries[key]; ok {
+		return entry.used, nil
+	}
+	t.entries[key] = &usageEntry{used: used, scannedAt: time.Now()}
+
+	return used, nil
+}
+
+// Add 增量更新用量（未统计过的 key 忽略，首次访问时会完整统计）
+func (t *UsageTracker) Add(key string, delta int64) {
+	if delta == 0 {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if entry, ok := t.entries[key]; ok {
+		entry.used += delta
+		if entry.used < 0 {
+			entry.used = 0
+		}
+	}
+}
+
+// Tracked 是否已统计过 key 对应文件系统的用量
+func (t *UsageTracker) Tracked(key string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	_, ok := t.entries[key]
+	return ok
+}
+
+// Invalidate 标记用量需要重新统计，用于无法确定写操作影响的字节数时（如复制中途失败）
+func (t *UsageTracker) Invalidate(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if entry, ok := t.entries[key]; ok {
+		entry.scannedAt = time.Time{}
+	}
+}
+
+// rescan 后台重新统计用量
+//
+// 统计期间的写操作可能被遍历计入也可能被遗漏，偏差由下一次统计纠正。
+func (t *UsageTracker) rescan(key string, fs webdav.FileSystem) {
+	used, err := TreeSize(context.Background(), fs, "/")
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	entry, ok := t.entries[key]
+	if !ok {
+		return
+	}
+	entry.scanning = false
+	if err != nil {
+		// 统计失败时保留当前用量，下次访问再重试
+		return
+	}
+	entry.used = used
+	entry.scannedAt = time.Now()
+}
+
+// TreeSize 统计路径（文件或目录树）占用的字节数，不存在时返回 0
+func TreeSize(ctx context.Context, fs webdav.FileSystem, name string) (int64, error) {
+	info, err := fs.Stat(ctx, name)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	if !info.IsDir() {
+		return info.Size(), nil
+	}
+
+	f, err := fs.OpenFile(ctx, name, os.O_RDONLY, 0)
+	if err != nil {
+		return 0, err
+	}
+	children, err := f.Readdir(0)
+	f.Close()
+	if err != nil {
+		return 0, err
+	}
+
+	var total int64
+	for _, child := range children {
+		if !child.IsDir() {
+			total += child.Size()
+			continue
+		}
+		size, err := TreeSize(ctx, fs, path.Join(name, child.Name()))
+		if err != nil {
+			return 0, err
+		}
+		total += size
+	}
+
+	return total, nil
+}
@@ -0,0 +1,145 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/net/webdav"
+)
+
+// blockingFS 遍历目录时阻塞，直到 release 关闭
+type blockingFS struct {
+	webdav.FileSystem
+	blocking atomic.Bool
+	release  chan struct{}
+	walks    atomic.Int32
+}
+
+func (fs *blockingFS) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (webdav.File, error) {
+	f, err := fs.FileSystem.OpenFile(ctx, name, flag, perm)
+	if err != nil || name != "/" {
+		return f, err
+	}
+	fs.walks.Add(1)
+	if fs.blocking.Load() {
+		<-fs.release
+	}
+	return f, nil
+}
+
+func writeTestFile(t *testing.T, fs webdav.FileSystem, name string, size int) {
+	t.Helper()
+
+	f, err := fs.OpenFile(context.Background(), name, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		t.Fatalf("OpenFile %s: %v", name, err)
+	}
+	defer f.Close()
+	if _, err := f.Write(make([]byte, size)); err != nil {
+		t.Fatalf("Write %s: %v", name, err)
+	}
+}
+
+func TestUsageTrackerRescansInBackground(t *testing.T) {
+	ctx := context.Background()
+	base, err := NewMemoryDriver().FileSystem(ctx, "/alice")
+	if err != nil {
+		t.Fatalf("FileSystem: %v", err)
+	}
+	fs := &blockingFS{FileSystem: base, release: make(chan struct{})}
+	writeTestFile(t, fs, "/a", 10)
+
+	tracker := NewUsageTracker(time.Millisecond)
+
+	// 首次访问同步统计
+	if used, err := tracker.Usage(ctx, "alice", fs); err != nil || used != 10 {
+		t.Fatalf("Usage = %d, %v, want 10", used, err)
+	}
+
+	// 绕过 WebDAV 的修改，过期后由后台统计纠正
+	writeTestFile(t, fs, "/b", 5)
+	time.Sleep(5 * time.Millisecond)
+	fs.blocking.Store(true)
+
+	done := make(chan int64)
+	go func() {
+		used, _ := tracker.Usage(ctx, "alice", fs)
+		done <- used
+	}()
+	select {
+	case used := <-done:
+		if used != 10 {
+			t.Fatalf("Usage during rescan = %d, want cached 10", used)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Usage blocked on rescan")
+	}
+
+	// 同一时间只有一次后台统计
+	tracker.Usage(ctx, "alice", fs)
+	tracker.Add("alice", 1)
+	close(fs.release)
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		used, _ := tracker.Usage(ctx, "alice", fs)
+		if used == 15 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Usage = %d after rescan, want 15", used)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if walks := fs.walks.Load(); walks < 2 {
+		t.Fatalf("walks = %d, want initial scan plus background rescan", walks)
+	}
+}
+
+func TestUsageTrackerAddAndInvalidate(t *testing.T) {
+	ctx := context.Background()
+	base, err := NewMemoryDriver().FileSystem(ctx, "/alice")
+	if err != nil {
+		t.Fatalf("FileSystem: %v", err)
+	}
+	fs := &blockingFS{FileSystem: base, release: make(chan struct{})}
+	writeTestFile(t, fs, "/a", 10)
+
+	tracker := NewUsageTracker(time.Hour)
+
+	// 未统计过的 key 忽略增量
+	tracker.Add("alice", 100)
+	if used, _ := tracker.Usage(ctx, "alice", fs); used != 10 {
+		t.Fatalf("Usage = %d, want 10", used)
+	}
+
+	tracker.Add("alice", 7)
+	tracker.Add("alice", -3)
+	if used, _ := tracker.Usage(ctx, "alice", fs); used != 14 {
+		t.Fatalf("Usage = %d, want 14", used)
+	}
+	tracker.Add("alice", -100)
+	if used, _ := tracker.Usage(ctx, "alice", fs); used != 0 {
+		t.Fatalf("Usage = %d, want clamped to 0", used)
+	}
+	if walks := fs.walks.Load(); walks != 1 {
+		t.Fatalf("walks = %d, want 1 within rescan interval", walks)
+	}
+
+	// 标记失效后在后台重新统计
+	tracker.Invalidate("alice")
+	tracker.Usage(ctx, "alice", fs)
+	deadline := time.Now().Add(time.Second)
+	for {
+		if used, _ := tracker.Usage(ctx, "alice", fs); used == 10 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Invalidate did not trigger a rescan")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
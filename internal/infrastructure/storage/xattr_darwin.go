@@ -0,0 +1,6 @@
+package storage
+
+import "golang.org/x/sys/unix"
+
+// errNoXattr 扩展属性不存在
+var errNoXattr = unix.ENOATTR
@@ -0,0 +1,6 @@
+package storage
+
+import "golang.org/x/sys/unix"
+
+// errNoXattr 扩展属性不存在（Linux 上为 ENODATA）
+var errNoXattr = unix.ENODATA
@@ -0,0 +1,23 @@
+//go:build !linux && !darwin
+
+package storage
+
+import "errors"
+
+// errXattrUnsupported 当前平台不支持扩展属性
+var errXattrUnsupported = errors.New("extended attributes are not supported on this platform")
+
+// getXattr 当前平台不支持扩展属性
+func getXattr(p string) ([]byte, error) {
+	return nil, errXattrUnsupported
+}
+
+// setXattr 当前平台不支持扩展属性
+func setXattr(p string, data []byte) error {
+	return errXattrUnsupported
+}
+
+// removeXattr 当前平台不支持扩展属性
+func removeXattr(p string) error {
+	return errXattrUnsupported
+}
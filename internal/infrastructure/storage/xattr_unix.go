@@ -0,0 +1,61 @@
+//go:build linux || darwin
+
+package storage
+
+import (
+	"errors"
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// getXattr 读取死属性扩展属性，不存在时返回空
+func getXattr(p string) ([]byte, error) {
+	for {
+		size, err := unix.Getxattr(p, xattrName, nil)
+		if err != nil {
+			if isNoXattr(err) {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("failed to read xattr: %w", err)
+		}
+		if size == 0 {
+			return nil, nil
+		}
+
+		buf := make([]byte, size)
+		n, err := unix.Getxattr(p, xattrName, buf)
+		if err != nil {
+			// 两次读取之间属性变大，重试
+			if errors.Is(err, unix.ERANGE) {
+				continue
+			}
+			if isNoXattr(err) {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("failed to read xattr: %w", err)
+		}
+		return buf[:n], nil
+	}
+}
+
+// setXattr 写入死属性扩展属性
+func setXattr(p string, data []byte) error {
+	if err := unix.Setxattr(p, xattrName, data, 0); err != nil {
+		return fmt.Errorf("failed to write xattr: %w", err)
+	}
+	return nil
+}
+
+// removeXattr 删除死属性扩展属性
+func removeXattr(p string) error {
+	if err := unix.Removexattr(p, xattrName); err != nil && !isNoXattr(err) {
+		return fmt.Errorf("failed to remove xattr: %w", err)
+	}
+	return nil
+}
+
+// isNoXattr 是否为扩展属性不存在错误
+func isNoXattr(err error) bool {
+	return errors.Is(err, errNoXattr)
+}
@@ -0,0 +1,91 @@
+package dto
+
+import "time"
+
+// RuleDTO 权限规则
+type RuleDTO struct {
+	Path        string        `json:"path"`
+	Permissions string        `json:"permissions"`
+	Regex       bool          `json:"regex"`
+	Glob        bool          `json:"glob"`
+	Deny        bool          `json:"deny"`
+	Token       *TokenGateDTO `json:"token,omitempty"`
+}
+
+// TokenGateDTO 规则的链上持有条件
+type TokenGateDTO struct {
+	Standard   string `json:"standard"`
+	Contract   string `json:"contract"`
+	MinBalance string `json:"min_balance,omitempty"`
+	TokenID    string `json:"token_id,omitempty"`
+}
+
+// CreateUserRequest 创建用户请求
+type CreateUserRequest struct {
+	Username      string     `json:"username"`
+	Password      string     `json:"password,omitempty"`
+	WalletAddress string     `json:"wallet_address,omitempty"`
+	Directory     string     `json:"directory"`
+	Permissions   string     `json:"permissions"`
+	Role          string     `json:"role,omitempty"`
+	Quota         int64      `json:"quota,omitempty"`
+	Rules         []*RuleDTO `json:"rules,omitempty"`
+	Certificates  []string   `json:"certificates,omitempty"` // 客户端证书 SHA-256 指纹
+}
+
+// UpdateUserRequest 更新用户请求（仅更新非空字段）
+type UpdateUserRequest struct {
+	WalletAddress *string   `json:"wallet_address,omitempty"`
+	Directory     *string   `json:"directory,omitempty"`
+	Permissions   *string   `json:"permissions,omitempty"`
+	Role          *string   `json:"role,omitempty"`
+	Quota         *int64    `json:"quota,omitempty"`
+	Certificates  *[]string `json:"certificates,omitempty"`
+}
+
+// SetPasswordRequest 设置密码请求
+type SetPasswordRequest struct {
+	Password string `json:"password"`
+}
+
+// UserResponse 用户详情响应
+type UserResponse struct {
+	ID            string     `json:"id"`
+	Username      string     `json:"username"`
+	WalletAddress string     `json:"wallet_address,omitempty"`
+	Wallets       []string   `json:"wallets"` // 主钱包和关联的钱包
+	Directory     string     `json:"directory"`
+	Role          string     `json:"role"`
+	Quota         int64      `json:"quota"`
+	Permissions   string     `json:"permissions"`
+	HasPassword   bool       `json:"has_password"`
+	TwoFactor     bool       `json:"two_factor"`
+	Rules         []*RuleDTO `json:"rules"`
+	Certificates  []string   `json:"certificates"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// UserListResponse 用户列表响应
+type UserListResponse struct {
+	Users []*UserResponse `json:"users"`
+	Total int             `json:"total"`
+}
+
+// LockResponse WebDAV 锁响应
+type LockResponse struct {
+	Token     string     `json:"token"`
+	Username  string     `json:"username"`
+	Directory string     `json:"directory"`
+	Root      string     `json:"root"`
+	Depth     string     `json:"depth"` // "0" 或 "infinity"
+	Owner     string     `json:"owner,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"` // 为空表示不过期
+}
+
+// LockListResponse WebDAV 锁列表响应
+type LockListResponse struct {
+	Locks []*LockResponse `json:"locks"`
+	Total int             `json:"total"`
+}
@@ -0,0 +1,36 @@
+package dto
+
+import "time"
+
+// CreateAppPasswordRequest 创建应用密码请求
+type CreateAppPasswordRequest struct {
+	Name      string     `json:"name"`
+	ReadOnly  bool       `json:"read_only"`
+	Paths     []string   `json:"paths,omitempty"`      // 为空表示不限制路径
+	ExpiresAt *time.Time `json:"expires_at,omitempty"` // 与 expires_in 二选一，都为空表示不过期
+	ExpiresIn string     `json:"expires_in,omitempty"` // 有效期，如 "720h"
+}
+
+// AppPasswordResponse 应用密码响应（不含明文）
+type AppPasswordResponse struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	ReadOnly  bool       `json:"read_only"`
+	Paths     []string   `json:"paths"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	Expired   bool       `json:"expired"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// CreateAppPasswordResponse 创建应用密码响应，明文只返回这一次
+type CreateAppPasswordResponse struct {
+	AppPasswordResponse
+	Username string `json:"username"`
+	Token    string `json:"token"`
+}
+
+// AppPasswordListResponse 应用密码列表响应
+type AppPasswordListResponse struct {
+	AppPasswords []*AppPasswordResponse `json:"app_passwords"`
+	Total        int                    `json:"total"`
+}
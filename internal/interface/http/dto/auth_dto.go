@@ -1,30 +1,69 @@
 package dto
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 // ChallengeRequest 挑战请求
 type ChallengeRequest struct {
+	Chain   string `json:"chain,omitempty"` // ethereum、solana、bitcoin，为空时使用默认链
 	Address string `json:"address"`
+	Format  string `json:"format,omitempty"` // siwe（默认）或 eip712，eip712 只支持以太坊
 }
 
 // ChallengeResponse 挑战响应
 type ChallengeResponse struct {
-	Nonce     string    `json:"nonce"`
-	Message   string    `json:"message"`
-	ExpiresAt time.Time `json:"expires_at"`
+	Nonce     string          `json:"nonce"`
+	Format    string          `json:"format"`
+	Message   string          `json:"message,omitempty"`    // SIWE 消息，使用 personal_sign 签名
+	TypedData json.RawMessage `json:"typed_data,omitempty"` // EIP-712 类型化数据，使用 eth_signTypedData_v4 签名
+	ExpiresAt time.Time       `json:"expires_at"`
 }
 
 // VerifyRequest 验证请求
 type VerifyRequest struct {
-	Address   string `json:"address"`
-	Signature string `json:"signature"`
+	Chain      string `json:"chain,omitempty"` // 与挑战请求一致
+	Address    string `json:"address"`
+	Message    string `json:"message,omitempty"` // 签名的 SIWE 消息，为空时使用挑战下发的消息；EIP-712 挑战忽略
+	Signature  string `json:"signature"`
+	InviteCode string `json:"invite_code,omitempty"` // 未登记的钱包自助注册时使用
 }
 
 // VerifyResponse 验证响应
 type VerifyResponse struct {
-	Token     string    `json:"token"`
-	ExpiresAt time.Time `json:"expires_at"`
-	User      *UserInfo `json:"user"`
+	Token            string    `json:"token"`
+	ExpiresAt        time.Time `json:"expires_at"`
+	RefreshToken     string    `json:"refresh_token"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+	User             *UserInfo `json:"user"`
+	Registered       bool      `json:"registered,omitempty"` // 本次登录时自助注册了新用户
+}
+
+// RefreshRequest 刷新令牌请求
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// RefreshResponse 刷新令牌响应
+type RefreshResponse struct {
+	Token            string    `json:"token"`
+	ExpiresAt        time.Time `json:"expires_at"`
+	RefreshToken     string    `json:"refresh_token"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+}
+
+// OIDCLoginResponse OIDC 登录响应，token 为身份提供方签发的 ID Token
+type OIDCLoginResponse struct {
+	Token        string    `json:"token"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	User         *UserInfo `json:"user"`
+}
+
+// LogoutRequest 注销请求，访问令牌通过 Authorization 头传递
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token,omitempty"`
 }
 
 // UserInfo 用户信息
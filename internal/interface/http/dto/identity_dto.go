@@ -0,0 +1,25 @@
+package dto
+
+import "time"
+
+// IdentityResponse 登录身份
+type IdentityResponse struct {
+	Type      string     `json:"type"`    // password, wallet, certificate, oidc
+	Subject   string     `json:"subject"` // 用户名、钱包标识（以太坊地址或 CAIP-10 账户标识）、证书指纹或外部身份标识
+	Primary   bool       `json:"primary,omitempty"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+}
+
+// IdentityListResponse 登录身份列表响应
+type IdentityListResponse struct {
+	Identities []*IdentityResponse `json:"identities"`
+	Total      int                 `json:"total"`
+}
+
+// LinkWalletRequest 关联钱包请求，签名来自要关联的钱包
+type LinkWalletRequest struct {
+	Chain     string `json:"chain,omitempty"` // 与挑战请求一致
+	Address   string `json:"address"`
+	Message   string `json:"message,omitempty"` // 签名的 SIWE 消息，为空时使用挑战下发的消息；EIP-712 挑战忽略
+	Signature string `json:"signature"`
+}
@@ -0,0 +1,28 @@
+package dto
+
+import "time"
+
+// TwoFactorStatusResponse 两步验证状态响应
+type TwoFactorStatusResponse struct {
+	Enabled                bool       `json:"enabled"`
+	Pending                bool       `json:"pending"` // 已生成密钥但尚未确认
+	EnabledAt              *time.Time `json:"enabled_at,omitempty"`
+	RecoveryCodesRemaining int        `json:"recovery_codes_remaining"`
+}
+
+// TOTPEnrollResponse TOTP 注册响应
+type TOTPEnrollResponse struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`     // otpauth:// 注册 URI
+	QRCode string `json:"qr_code"` // PNG 二维码 data URL
+}
+
+// TOTPCodeRequest 携带验证码的请求，code 可以是验证码或恢复码
+type TOTPCodeRequest struct {
+	Code string `json:"code"`
+}
+
+// RecoveryCodesResponse 恢复码响应，明文只返回这一次
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
@@ -0,0 +1,555 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yeying-community/webdav/internal/domain/user"
+	"github.com/yeying-community/webdav/internal/infrastructure/crypto"
+	"github.com/yeying-community/webdav/internal/interface/http/dto"
+	"github.com/yeying-community/webdav/internal/interface/http/middleware"
+	"go.uber.org/zap"
+)
+
+// adminUsersPath 用户管理 API 路径
+const adminUsersPath = "/api/admin/users"
+
+// AdminHandler 用户管理处理器
+type AdminHandler struct {
+	userRepo       user.Repository
+	passwordHasher *crypto.PasswordHasher
+	digestRealm    string
+	logger         *zap.Logger
+}
+
+// NewAdminHandler 创建用户管理处理器
+//
+// digestRealm 非空时设置密码同时保存 Digest 认证摘要。
+func NewAdminHandler(
+	userRepo user.Repository,
+	passwordHasher *crypto.PasswordHasher,
+	digestRealm string,
+	logger *zap.Logger,
+) *AdminHandler {
+	return &AdminHandler{
+		userRepo:       userRepo,
+		passwordHasher: passwordHasher,
+		digestRealm:    digestRealm,
+		logger:         logger,
+	}
+}
+
+// HandleUsers 处理用户集合请求
+// GET  /api/admin/users
+// POST /api/admin/users
+func (h *AdminHandler) HandleUsers(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.listUsers(w, r)
+	case http.MethodPost:
+		h.createUser(w, r)
+	default:
+		h.sendError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "Only GET and POST methods are allowed")
+	}
+}
+
+// HandleUser 处理单个用户及其子资源请求
+// GET|PUT|PATCH|DELETE /api/admin/users/{username}
+// PUT                  /api/admin/users/{username}/password
+// DELETE               /api/admin/users/{username}/2fa
+// POST                 /api/admin/users/{username}/rules
+// DELETE               /api/admin/users/{username}/rules/{index}
+func (h *AdminHandler) HandleUser(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, adminUsersPath), "/"), "/")
+	username := parts[0]
+	if username == "" {
+		h.sendError(w, http.StatusNotFound, "NOT_FOUND", "Resource not found")
+		return
+	}
+
+	switch {
+	case len(parts) == 1:
+		switch r.Method {
+		case http.MethodGet:
+			h.getUser(w, r, username)
+		case http.MethodPut, http.MethodPatch:
+			h.updateUser(w, r, username)
+		case http.MethodDelete:
+			h.deleteUser(w, r, username)
+		default:
+			h.sendError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "Only GET, PUT, PATCH and DELETE methods are allowed")
+		}
+
+	case len(parts) == 2 && parts[1] == "password":
+		if r.Method != http.MethodPut && r.Method != http.MethodPost {
+			h.sendError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "Only PUT and POST methods are allowed")
+			return
+		}
+		h.setPassword(w, r, username)
+
+	case len(parts) == 2 && parts[1] == "2fa":
+		if r.Method != http.MethodDelete {
+			h.sendError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "Only DELETE method is allowed")
+			return
+		}
+		h.resetTwoFactor(w, r, username)
+
+	case len(parts) == 2 && parts[1] == "rules":
+		if r.Method != http.MethodPost {
+			h.sendError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "Only POST method is allowed")
+			return
+		}
+		h.addRule(w, r, username)
+
+	case len(parts) == 3 && parts[1] == "rules":
+		if r.Method != http.MethodDelete {
+			h.sendError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "Only DELETE method is allowed")
+			return
+		}
+		h.removeRule(w, r, username, parts[2])
+
+	default:
+		h.sendError(w, http.StatusNotFound, "NOT_FOUND", "Resource not found")
+	}
+}
+
+// listUsers 列出用户
+func (h *AdminHandler) listUsers(w http.ResponseWriter, r *http.Request) {
+	users, err := h.userRepo.List(r.Context())
+	if err != nil {
+		h.logger.Error("failed to list users", zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to list users")
+		return
+	}
+
+	response := dto.UserListResponse{
+		Users: make([]*dto.UserResponse, 0, len(users)),
+		Total: len(users),
+	}
+	for _, u := range users {
+		response.Users = append(response.Users, toUserResponse(u))
+	}
+
+	h.sendJSON(w, http.StatusOK, response)
+}
+
+// getUser 获取用户
+func (h *AdminHandler) getUser(w http.ResponseWriter, r *http.Request, username string) {
+	u, ok := h.findUser(w, r, username)
+	if !ok {
+		return
+	}
+
+	h.sendJSON(w, http.StatusOK, toUserResponse(u))
+}
+
+// createUser 创建用户
+func (h *AdminHandler) createUser(w http.ResponseWriter, r *http.Request) {
+	var req dto.CreateUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendError(w, http.StatusBadRequest, "INVALID_REQUEST", "Invalid request body")
+		return
+	}
+
+	req.Username = strings.TrimSpace(req.Username)
+	if req.Username == "" || strings.Contains(req.Username, "/") {
+		h.sendError(w, http.StatusBadRequest, "INVALID_USERNAME", "Username is required and must not contain '/'")
+		return
+	}
+	if req.Directory == "" {
+		h.sendError(w, http.StatusBadRequest, "MISSING_DIRECTORY", "Directory is required")
+		return
+	}
+
+	u := user.NewUser(req.Username, req.Directory)
+
+	if req.Password != "" {
+		hashed, err := h.passwordHasher.Hash(req.Password)
+		if err != nil {
+			h.logger.Error("failed to hash password", zap.Error(err))
+			h.sendError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to create user")
+			return
+		}
+		u.SetPassword(hashed)
+		if h.digestRealm != "" {
+			u.SetDigestPassword(h.digestRealm, req.Password)
+		}
+	}
+
+	if req.WalletAddress != "" {
+		if !user.IsValidWalletID(req.WalletAddress) {
+			h.sendError(w, http.StatusBadRequest, "INVALID_ADDRESS", "Invalid wallet address")
+			return
+		}
+		u.SetWalletAddress(req.WalletAddress)
+	}
+
+	if req.Role != "" {
+		if err := u.SetRole(req.Role); err != nil {
+			h.sendError(w, http.StatusBadRequest, "INVALID_ROLE", "Role must be 'user' or 'admin'")
+			return
+		}
+	}
+
+	if req.Permissions != "" {
+		u.Permissions = user.ParsePermissions(req.Permissions)
+	}
+
+	u.Quota = req.Quota
+
+	if err := u.SetCertificates(req.Certificates); err != nil {
+		h.sendError(w, http.StatusBadRequest, "INVALID_FINGERPRINT", "Certificates must be SHA-256 fingerprints")
+		return
+	}
+
+	for _, ruleDTO := range req.Rules {
+		if ruleDTO == nil {
+			h.sendError(w, http.StatusBadRequest, "INVALID_RULE", "Rule must not be null")
+			return
+		}
+		rule := toRule(ruleDTO)
+		if err := rule.Validate(); err != nil {
+			h.sendError(w, http.StatusBadRequest, "INVALID_RULE", err.Error())
+			return
+		}
+		u.Rules = append(u.Rules, rule)
+	}
+
+	if !h.saveUser(w, r, u) {
+		return
+	}
+
+	h.logger.Info("user created via admin api",
+		zap.String("username", u.Username),
+		zap.String("by", currentUsername(r)))
+
+	h.sendJSON(w, http.StatusCreated, toUserResponse(u))
+}
+
+// updateUser 更新用户
+func (h *AdminHandler) updateUser(w http.ResponseWriter, r *http.Request, username string) {
+	var req dto.UpdateUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendError(w, http.StatusBadRequest, "INVALID_REQUEST", "Invalid request body")
+		return
+	}
+
+	existing, ok := h.findUser(w, r, username)
+	if !ok {
+		return
+	}
+	u := existing.Clone()
+
+	if req.WalletAddress != nil {
+		if *req.WalletAddress == "" {
+			u.WalletAddress = ""
+		} else {
+			if !user.IsValidWalletID(*req.WalletAddress) {
+				h.sendError(w, http.StatusBadRequest, "INVALID_ADDRESS", "Invalid wallet address")
+				return
+			}
+			u.SetWalletAddress(*req.WalletAddress)
+		}
+	}
+
+	if req.Directory != nil {
+		if *req.Directory == "" {
+			h.sendError(w, http.StatusBadRequest, "MISSING_DIRECTORY", "Directory must not be empty")
+			return
+		}
+		u.Directory = *req.Directory
+	}
+
+	if req.Permissions != nil {
+		u.Permissions = user.ParsePermissions(*req.Permissions)
+	}
+
+	if req.Quota != nil {
+		u.Quota = *req.Quota
+	}
+
+	if req.Certificates != nil {
+		if err := u.SetCertificates(*req.Certificates); err != nil {
+			h.sendError(w, http.StatusBadRequest, "INVALID_FINGERPRINT", "Certificates must be SHA-256 fingerprints")
+			return
+		}
+	}
+
+	if req.Role != nil {
+		// 防止管理员移除自己的管理员角色
+		if *req.Role != user.RoleAdmin && u.Username == currentUsername(r) {
+			h.sendError(w, http.StatusBadRequest, "INVALID_ROLE", "Cannot remove your own admin role")
+			return
+		}
+		if err := u.SetRole(*req.Role); err != nil {
+			h.sendError(w, http.StatusBadRequest, "INVALID_ROLE", "Role must be 'user' or 'admin'")
+			return
+		}
+	}
+
+	if !h.saveUser(w, r, u) {
+		return
+	}
+
+	h.logger.Info("user updated via admin api",
+		zap.String("username", u.Username),
+		zap.String("by", currentUsername(r)))
+
+	h.sendJSON(w, http.StatusOK, toUserResponse(u))
+}
+
+// deleteUser 删除用户
+func (h *AdminHandler) deleteUser(w http.ResponseWriter, r *http.Request, username string) {
+	if username == currentUsername(r) {
+		h.sendError(w, http.StatusBadRequest, "INVALID_REQUEST", "Cannot delete your own account")
+		return
+	}
+
+	if err := h.userRepo.Delete(r.Context(), username); err != nil {
+		if errors.Is(err, user.ErrUserNotFound) {
+			h.sendError(w, http.StatusNotFound, "USER_NOT_FOUND", "User not found")
+			return
+		}
+		h.logger.Error("failed to delete user", zap.String("username", username), zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to delete user")
+		return
+	}
+
+	h.logger.Info("user deleted via admin api",
+		zap.String("username", username),
+		zap.String("by", currentUsername(r)))
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// setPassword 设置用户密码
+func (h *AdminHandler) setPassword(w http.ResponseWriter, r *http.Request, username string) {
+	var req dto.SetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendError(w, http.StatusBadRequest, "INVALID_REQUEST", "Invalid request body")
+		return
+	}
+	if req.Password == "" {
+		h.sendError(w, http.StatusBadRequest, "MISSING_PASSWORD", "Password is required")
+		return
+	}
+
+	existing, ok := h.findUser(w, r, username)
+	if !ok {
+		return
+	}
+	u := existing.Clone()
+
+	hashed, err := h.passwordHasher.Hash(req.Password)
+	if err != nil {
+		h.logger.Error("failed to hash password", zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to set password")
+		return
+	}
+	u.SetPassword(hashed)
+	if h.digestRealm != "" {
+		u.SetDigestPassword(h.digestRealm, req.Password)
+	}
+
+	if !h.saveUser(w, r, u) {
+		return
+	}
+
+	h.logger.Info("password changed via admin api",
+		zap.String("username", u.Username),
+		zap.String("by", currentUsername(r)))
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// resetTwoFactor 重置用户的两步验证（用户丢失验证器和恢复码时）
+func (h *AdminHandler) resetTwoFactor(w http.ResponseWriter, r *http.Request, username string) {
+	existing, ok := h.findUser(w, r, username)
+	if !ok {
+		return
+	}
+	u := existing.Clone()
+	u.TOTP = nil
+
+	if !h.saveUser(w, r, u) {
+		return
+	}
+
+	h.logger.Info("two-factor authentication reset via admin api",
+		zap.String("username", u.Username),
+		zap.String("by", currentUsername(r)))
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// addRule 追加权限规则
+func (h *AdminHandler) addRule(w http.ResponseWriter, r *http.Request, username string) {
+	var req dto.RuleDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendError(w, http.StatusBadRequest, "INVALID_REQUEST", "Invalid request body")
+		return
+	}
+	rule := toRule(&req)
+	if err := rule.Validate(); err != nil {
+		h.sendError(w, http.StatusBadRequest, "INVALID_RULE", err.Error())
+		return
+	}
+
+	existing, ok := h.findUser(w, r, username)
+	if !ok {
+		return
+	}
+	u := existing.Clone()
+	u.Rules = append(u.Rules, rule)
+
+	if !h.saveUser(w, r, u) {
+		return
+	}
+
+	h.sendJSON(w, http.StatusCreated, toUserResponse(u))
+}
+
+// removeRule 删除权限规则
+func (h *AdminHandler) removeRule(w http.ResponseWriter, r *http.Request, username, indexStr string) {
+	existing, ok := h.findUser(w, r, username)
+	if !ok {
+		return
+	}
+
+	index, err := strconv.Atoi(indexStr)
+	if err != nil || index < 0 || index >= len(existing.Rules) {
+		h.sendError(w, http.StatusNotFound, "RULE_NOT_FOUND", "Rule not found")
+		return
+	}
+
+	u := existing.Clone()
+	u.Rules = append(u.Rules[:index], u.Rules[index+1:]...)
+
+	if !h.saveUser(w, r, u) {
+		return
+	}
+
+	h.sendJSON(w, http.StatusOK, toUserResponse(u))
+}
+
+// findUser 查找用户，失败时写入错误响应
+func (h *AdminHandler) findUser(w http.ResponseWriter, r *http.Request, username string) (*user.User, bool) {
+	u, err := h.userRepo.FindByUsername(r.Context(), username)
+	if err != nil {
+		if errors.Is(err, user.ErrUserNotFound) {
+			h.sendError(w, http.StatusNotFound, "USER_NOT_FOUND", "User not found")
+			return nil, false
+		}
+		h.logger.Error("failed to find user", zap.String("username", username), zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to process request")
+		return nil, false
+	}
+	return u, true
+}
+
+// saveUser 保存用户，失败时写入错误响应
+func (h *AdminHandler) saveUser(w http.ResponseWriter, r *http.Request, u *user.User) bool {
+	u.UpdatedAt = time.Now()
+
+	if err := h.userRepo.Save(r.Context(), u); err != nil {
+		switch {
+		case errors.Is(err, user.ErrDuplicateUsername):
+			h.sendError(w, http.StatusConflict, "DUPLICATE_USERNAME", "Username already exists")
+		case errors.Is(err, user.ErrDuplicateAddress):
+			h.sendError(w, http.StatusConflict, "DUPLICATE_ADDRESS", "Wallet address already exists")
+		case errors.Is(err, user.ErrDuplicateFingerprint):
+			h.sendError(w, http.StatusConflict, "DUPLICATE_FINGERPRINT", "Certificate is already registered to another user")
+		default:
+			h.logger.Error("failed to save user", zap.String("username", u.Username), zap.Error(err))
+			h.sendError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to save user")
+		}
+		return false
+	}
+	return true
+}
+
+// currentUsername 当前请求的管理员用户名
+func currentUsername(r *http.Request) string {
+	if u, ok := middleware.GetUserFromContext(r.Context()); ok {
+		return u.Username
+	}
+	return ""
+}
+
+// toUserResponse 转换为用户响应
+func toUserResponse(u *user.User) *dto.UserResponse {
+	response := &dto.UserResponse{
+		ID:            u.ID,
+		Username:      u.Username,
+		WalletAddress: u.WalletAddress,
+		Wallets:       u.WalletAddresses(),
+		Directory:     u.Directory,
+		Role:          u.Role,
+		Quota:         u.Quota,
+		HasPassword:   u.HasPassword(),
+		TwoFactor:     u.HasTwoFactor(),
+		Rules:         make([]*dto.RuleDTO, 0, len(u.Rules)),
+		Certificates:  append([]string{}, u.Certificates...),
+		CreatedAt:     u.CreatedAt,
+		UpdatedAt:     u.UpdatedAt,
+	}
+	if u.Permissions != nil {
+		response.Permissions = u.Permissions.String()
+	}
+	for _, rule := range u.Rules {
+		ruleDTO := &dto.RuleDTO{Path: rule.Path, Regex: rule.Regex, Glob: rule.Glob, Deny: rule.Deny}
+		if rule.Permissions != nil {
+			ruleDTO.Permissions = rule.Permissions.String()
+		}
+		if rule.Token != nil {
+			ruleDTO.Token = &dto.TokenGateDTO{
+				Standard:   rule.Token.Standard,
+				Contract:   rule.Token.Contract,
+				MinBalance: rule.Token.MinBalance,
+				TokenID:    rule.Token.TokenID,
+			}
+		}
+		response.Rules = append(response.Rules, ruleDTO)
+	}
+	return response
+}
+
+// toRule 转换为领域规则
+func toRule(r *dto.RuleDTO) *user.Rule {
+	rule := &user.Rule{
+		Path:        r.Path,
+		Permissions: user.ParsePermissions(r.Permissions),
+		Regex:       r.Regex,
+		Glob:        r.Glob,
+		Deny:        r.Deny,
+	}
+	if r.Token != nil {
+		rule.Token = &user.TokenGate{
+			Standard:   strings.ToLower(r.Token.Standard),
+			Contract:   strings.ToLower(r.Token.Contract),
+			MinBalance: r.Token.MinBalance,
+			TokenID:    r.Token.TokenID,
+		}
+	}
+	return rule
+}
+
+// sendJSON 发送 JSON 响应
+func (h *AdminHandler) sendJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		h.logger.Error("failed to encode response", zap.Error(err))
+	}
+}
+
+// sendError 发送错误响应
+func (h *AdminHandler) sendError(w http.ResponseWriter, status int, code, message string) {
+	response := dto.NewErrorResponse(code, message)
+	h.sendJSON(w, status, response)
+}
@@ -0,0 +1,220 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/yeying-community/webdav/internal/domain/user"
+	"github.com/yeying-community/webdav/internal/infrastructure/crypto"
+	"github.com/yeying-community/webdav/internal/infrastructure/repository"
+	"github.com/yeying-community/webdav/internal/interface/http/dto"
+	"github.com/yeying-community/webdav/internal/interface/http/middleware"
+	"go.uber.org/zap"
+)
+
+// adminTestServer 管理 API 测试环境：root 为管理员，alice 为普通用户
+type adminTestServer struct {
+	repo    user.Repository
+	handler http.Handler
+	root    *user.User
+	alice   *user.User
+}
+
+func newAdminTestServer(t *testing.T) *adminTestServer {
+	t.Helper()
+	ctx := context.Background()
+
+	repo := repository.NewMemoryUserRepository(nil)
+	root := user.NewUser("root", "/")
+	root.Role = user.RoleAdmin
+	alice := user.NewUser("alice", "/alice")
+	for _, u := range []*user.User{root, alice} {
+		if err := repo.Save(ctx, u); err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+	}
+
+	h := NewAdminHandler(repo, crypto.NewPasswordHasher(), "WebDAV", zap.NewNop())
+	mux := http.NewServeMux()
+	mux.HandleFunc(adminUsersPath, h.HandleUsers)
+	mux.HandleFunc(adminUsersPath+"/", h.HandleUser)
+
+	return &adminTestServer{
+		repo:    repo,
+		handler: middleware.NewAdminMiddleware(zap.NewNop()).Handle(mux),
+		root:    root,
+		alice:   alice,
+	}
+}
+
+// do 以 as 的身份发送请求，body 不为 nil 时编码为 JSON
+func (s *adminTestServer) do(as *user.User, method, target string, body interface{}) *httptest.ResponseRecorder {
+	var payload bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&payload).Encode(body)
+	}
+	r := httptest.NewRequest(method, target, &payload)
+	r = r.WithContext(context.WithValue(r.Context(), middleware.UserContextKey, as))
+
+	w := httptest.NewRecorder()
+	s.handler.ServeHTTP(w, r)
+	return w
+}
+
+// errorCode 错误响应中的错误码
+func errorCode(t *testing.T, w *httptest.ResponseRecorder) string {
+	t.Helper()
+
+	var resp dto.ErrorResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode error response %q: %v", w.Body.String(), err)
+	}
+	return resp.Error
+}
+
+func TestAdminRequiresAdminRole(t *testing.T) {
+	s := newAdminTestServer(t)
+
+	requests := []struct {
+		method string
+		target string
+		body   interface{}
+	}{
+		{method: "GET", target: "/api/admin/users"},
+		{method: "POST", target: "/api/admin/users", body: dto.CreateUserRequest{Username: "mallory", Directory: "/m"}},
+		{method: "PATCH", target: "/api/admin/users/alice", body: map[string]string{"role": "admin"}},
+		{method: "PUT", target: "/api/admin/users/root/password", body: dto.SetPasswordRequest{Password: "owned"}},
+		{method: "DELETE", target: "/api/admin/users/root"},
+	}
+	for _, req := range requests {
+		if w := s.do(s.alice, req.method, req.target, req.body); w.Code != http.StatusForbidden {
+			t.Fatalf("%s %s as non-admin: status = %d, want 403", req.method, req.target, w.Code)
+		}
+	}
+
+	// 请求没有产生任何修改
+	alice, _ := s.repo.FindByUsername(context.Background(), "alice")
+	if alice.IsAdmin() {
+		t.Fatal("non-admin promoted themselves")
+	}
+	if _, err := s.repo.FindByUsername(context.Background(), "mallory"); err == nil {
+		t.Fatal("non-admin created a user")
+	}
+}
+
+func TestAdminCannotDemoteOrDeleteSelf(t *testing.T) {
+	s := newAdminTestServer(t)
+
+	w := s.do(s.root, "PATCH", "/api/admin/users/root", map[string]string{"role": "user"})
+	if w.Code != http.StatusBadRequest || errorCode(t, w) != "INVALID_ROLE" {
+		t.Fatalf("demote self: status = %d, body = %s", w.Code, w.Body.String())
+	}
+	w = s.do(s.root, "DELETE", "/api/admin/users/root", nil)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("delete self: status = %d, want 400", w.Code)
+	}
+	root, err := s.repo.FindByUsername(context.Background(), "root")
+	if err != nil || !root.IsAdmin() {
+		t.Fatalf("root = %v, %v, want an admin", root, err)
+	}
+
+	// 其他用户可以正常修改和删除
+	if w := s.do(s.root, "PATCH", "/api/admin/users/alice", map[string]string{"role": "admin"}); w.Code != http.StatusOK {
+		t.Fatalf("promote alice: status = %d", w.Code)
+	}
+	if w := s.do(s.root, "DELETE", "/api/admin/users/alice", nil); w.Code != http.StatusNoContent {
+		t.Fatalf("delete alice: status = %d", w.Code)
+	}
+	if w := s.do(s.root, "DELETE", "/api/admin/users/alice", nil); w.Code != http.StatusNotFound {
+		t.Fatalf("delete missing user: status = %d, want 404", w.Code)
+	}
+}
+
+func TestAdminRules(t *testing.T) {
+	s := newAdminTestServer(t)
+	ctx := context.Background()
+
+	add := []dto.RuleDTO{
+		{Path: "/photos", Permissions: "R"},
+		{Path: `^/notes/.*\.md$`, Permissions: "RU", Regex: true},
+		{Path: "/tmp/**", Permissions: "CRUD", Glob: true},
+	}
+	for _, rule := range add {
+		if w := s.do(s.root, "POST", "/api/admin/users/alice/rules", rule); w.Code != http.StatusCreated {
+			t.Fatalf("add rule %q: status = %d, body = %s", rule.Path, w.Code, w.Body.String())
+		}
+	}
+
+	invalid := []dto.RuleDTO{
+		{Path: "/notes/(", Regex: true},
+		{Path: "/tmp/[abc", Glob: true},
+		{Path: "/both", Regex: true, Glob: true},
+		{Path: ""},
+	}
+	for _, rule := range invalid {
+		w := s.do(s.root, "POST", "/api/admin/users/alice/rules", rule)
+		if w.Code != http.StatusBadRequest || errorCode(t, w) != "INVALID_RULE" {
+			t.Fatalf("invalid rule %+v: status = %d, body = %s", rule, w.Code, w.Body.String())
+		}
+	}
+
+	// 删除中间的规则，其余规则保持顺序
+	w := s.do(s.root, "DELETE", "/api/admin/users/alice/rules/1", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("remove rule: status = %d", w.Code)
+	}
+	for _, target := range []string{"/api/admin/users/alice/rules/5", "/api/admin/users/alice/rules/x"} {
+		if w := s.do(s.root, "DELETE", target, nil); w.Code != http.StatusNotFound {
+			t.Fatalf("DELETE %s: status = %d, want 404", target, w.Code)
+		}
+	}
+
+	alice, err := s.repo.FindByUsername(ctx, "alice")
+	if err != nil {
+		t.Fatalf("FindByUsername: %v", err)
+	}
+	var paths []string
+	for _, rule := range alice.Rules {
+		paths = append(paths, rule.Path)
+	}
+	if strings.Join(paths, " ") != "/photos /tmp/**" || !alice.Rules[1].Glob {
+		t.Fatalf("rules = %v", paths)
+	}
+}
+
+func TestAdminSetPassword(t *testing.T) {
+	s := newAdminTestServer(t)
+	ctx := context.Background()
+
+	if w := s.do(s.root, "PUT", "/api/admin/users/alice/password", dto.SetPasswordRequest{}); w.Code != http.StatusBadRequest {
+		t.Fatalf("empty password: status = %d, want 400", w.Code)
+	}
+	if w := s.do(s.root, "PUT", "/api/admin/users/nobody/password", dto.SetPasswordRequest{Password: "x"}); w.Code != http.StatusNotFound {
+		t.Fatalf("unknown user: status = %d, want 404", w.Code)
+	}
+
+	const password = "correct horse battery staple"
+	if w := s.do(s.root, "PUT", "/api/admin/users/alice/password", dto.SetPasswordRequest{Password: password}); w.Code != http.StatusNoContent {
+		t.Fatalf("set password: status = %d, body = %s", w.Code, w.Body.String())
+	}
+
+	alice, err := s.repo.FindByUsername(ctx, "alice")
+	if err != nil {
+		t.Fatalf("FindByUsername: %v", err)
+	}
+	// 只保存哈希
+	if alice.Password == "" || strings.Contains(alice.Password, password) || !crypto.IsPasswordHash(alice.Password) {
+		t.Fatalf("stored password %q is not a hash", alice.Password)
+	}
+	if err := crypto.NewPasswordHasher().Verify(alice.Password, password); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if alice.Digest == nil || alice.Digest.Realm != "WebDAV" || alice.Digest.SHA256 == "" {
+		t.Fatalf("digest = %+v, want HA1 for realm WebDAV", alice.Digest)
+	}
+}
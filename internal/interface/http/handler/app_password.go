@@ -0,0 +1,233 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/yeying-community/webdav/internal/domain/user"
+	"github.com/yeying-community/webdav/internal/interface/http/dto"
+	"github.com/yeying-community/webdav/internal/interface/http/middleware"
+	"go.uber.org/zap"
+)
+
+// appPasswordsPath 应用密码 API 路径
+const appPasswordsPath = "/api/app-passwords"
+
+// AppPasswordHandler 应用密码处理器，用户管理自己的应用密码
+type AppPasswordHandler struct {
+	userRepo user.Repository
+	logger   *zap.Logger
+}
+
+// NewAppPasswordHandler 创建应用密码处理器
+func NewAppPasswordHandler(userRepo user.Repository, logger *zap.Logger) *AppPasswordHandler {
+	return &AppPasswordHandler{
+		userRepo: userRepo,
+		logger:   logger,
+	}
+}
+
+// HandleAppPasswords 处理应用密码集合请求
+// GET  /api/app-passwords
+// POST /api/app-passwords
+func (h *AppPasswordHandler) HandleAppPasswords(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.listAppPasswords(w, r)
+	case http.MethodPost:
+		h.createAppPassword(w, r)
+	default:
+		h.sendError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "Only GET and POST methods are allowed")
+	}
+}
+
+// HandleAppPassword 处理单个应用密码请求
+// DELETE /api/app-passwords/{id}
+func (h *AppPasswordHandler) HandleAppPassword(w http.ResponseWriter, r *http.Request) {
+	id := strings.Trim(strings.TrimPrefix(r.URL.Path, appPasswordsPath), "/")
+	if id == "" || strings.Contains(id, "/") {
+		h.sendError(w, http.StatusNotFound, "NOT_FOUND", "Resource not found")
+		return
+	}
+	if r.Method != http.MethodDelete {
+		h.sendError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "Only DELETE method is allowed")
+		return
+	}
+
+	u, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+	u = u.Clone()
+
+	if err := u.RemoveAppPassword(id); err != nil {
+		h.sendError(w, http.StatusNotFound, "APP_PASSWORD_NOT_FOUND", "App password not found")
+		return
+	}
+
+	if !h.saveUser(w, r, u) {
+		return
+	}
+
+	h.logger.Info("app password revoked",
+		zap.String("username", u.Username),
+		zap.String("id", id))
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// listAppPasswords 列出应用密码
+func (h *AppPasswordHandler) listAppPasswords(w http.ResponseWriter, r *http.Request) {
+	u, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	response := dto.AppPasswordListResponse{
+		AppPasswords: make([]*dto.AppPasswordResponse, 0, len(u.AppPasswords)),
+		Total:        len(u.AppPasswords),
+	}
+	for _, password := range u.AppPasswords {
+		response.AppPasswords = append(response.AppPasswords, toAppPasswordResponse(password))
+	}
+
+	h.sendJSON(w, http.StatusOK, response)
+}
+
+// createAppPassword 创建应用密码
+func (h *AppPasswordHandler) createAppPassword(w http.ResponseWriter, r *http.Request) {
+	var req dto.CreateAppPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendError(w, http.StatusBadRequest, "INVALID_REQUEST", "Invalid request body")
+		return
+	}
+
+	if strings.TrimSpace(req.Name) == "" {
+		h.sendError(w, http.StatusBadRequest, "MISSING_NAME", "Name is required")
+		return
+	}
+
+	expiresAt := req.ExpiresAt
+	if req.ExpiresIn != "" {
+		if expiresAt != nil {
+			h.sendError(w, http.StatusBadRequest, "INVALID_EXPIRY", "expires_at and expires_in are mutually exclusive")
+			return
+		}
+		ttl, err := time.ParseDuration(req.ExpiresIn)
+		if err != nil || ttl <= 0 {
+			h.sendError(w, http.StatusBadRequest, "INVALID_EXPIRY", "expires_in must be a positive duration such as '720h'")
+			return
+		}
+		t := time.Now().Add(ttl)
+		expiresAt = &t
+	}
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		h.sendError(w, http.StatusBadRequest, "INVALID_EXPIRY", "expires_at must be in the future")
+		return
+	}
+
+	u, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+	u = u.Clone()
+
+	password, token, err := user.NewAppPassword(req.Name, req.ReadOnly, req.Paths, expiresAt)
+	if err != nil {
+		h.logger.Error("failed to create app password", zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to create app password")
+		return
+	}
+	u.AddAppPassword(password)
+
+	if !h.saveUser(w, r, u) {
+		return
+	}
+
+	h.logger.Info("app password created",
+		zap.String("username", u.Username),
+		zap.String("id", password.ID),
+		zap.String("name", password.Name),
+		zap.Bool("read_only", password.ReadOnly),
+		zap.Strings("paths", password.Paths))
+
+	h.sendJSON(w, http.StatusCreated, dto.CreateAppPasswordResponse{
+		AppPasswordResponse: *toAppPasswordResponse(password),
+		Username:            u.Username,
+		Token:               token,
+	})
+}
+
+// currentUser 从仓储加载当前认证用户，失败时写入错误响应
+//
+// 通过应用密码认证的会话不能管理应用密码，避免受限令牌签发不受限的令牌。
+func (h *AppPasswordHandler) currentUser(w http.ResponseWriter, r *http.Request) (*user.User, bool) {
+	authenticated, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		h.sendError(w, http.StatusUnauthorized, "UNAUTHORIZED", "Authentication required")
+		return nil, false
+	}
+	if authenticated.Scope != nil {
+		h.sendError(w, http.StatusForbidden, "FORBIDDEN", "App passwords cannot manage app passwords")
+		return nil, false
+	}
+
+	// 上下文中的用户可能带有本次请求的组映射，重新加载仓储中的实例
+	u, err := h.userRepo.FindByUsername(r.Context(), authenticated.Username)
+	if err != nil {
+		if errors.Is(err, user.ErrUserNotFound) {
+			h.sendError(w, http.StatusNotFound, "USER_NOT_FOUND", "User not found")
+			return nil, false
+		}
+		h.logger.Error("failed to find user", zap.String("username", authenticated.Username), zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to process request")
+		return nil, false
+	}
+	return u, true
+}
+
+// saveUser 保存用户，失败时写入错误响应
+func (h *AppPasswordHandler) saveUser(w http.ResponseWriter, r *http.Request, u *user.User) bool {
+	if err := h.userRepo.Save(r.Context(), u); err != nil {
+		h.logger.Error("failed to save user", zap.String("username", u.Username), zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to save app password")
+		return false
+	}
+	return true
+}
+
+// toAppPasswordResponse 转换为应用密码响应
+func toAppPasswordResponse(p *user.AppPassword) *dto.AppPasswordResponse {
+	paths := p.Paths
+	if paths == nil {
+		paths = []string{}
+	}
+	return &dto.AppPasswordResponse{
+		ID:        p.ID,
+		Name:      p.Name,
+		ReadOnly:  p.ReadOnly,
+		Paths:     paths,
+		ExpiresAt: p.ExpiresAt,
+		Expired:   p.IsExpired(),
+		CreatedAt: p.CreatedAt,
+	}
+}
+
+// sendJSON 发送 JSON 响应
+func (h *AppPasswordHandler) sendJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		h.logger.Error("failed to encode response", zap.Error(err))
+	}
+}
+
+// sendError 发送错误响应
+func (h *AppPasswordHandler) sendError(w http.ResponseWriter, status int, code, message string) {
+	response := dto.NewErrorResponse(code, message)
+	h.sendJSON(w, status, response)
+}
@@ -0,0 +1,336 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	domainAuth "github.com/yeying-community/webdav/internal/domain/auth"
+	"github.com/yeying-community/webdav/internal/domain/user"
+	"github.com/yeying-community/webdav/internal/infrastructure/auth"
+	"github.com/yeying-community/webdav/internal/interface/http/dto"
+	"github.com/yeying-community/webdav/internal/interface/http/middleware"
+	"go.uber.org/zap"
+)
+
+// identitiesPath 登录身份 API 路径
+const identitiesPath = "/api/identities"
+
+// IdentityHandler 登录身份处理器，用户管理关联到自己账户的钱包
+type IdentityHandler struct {
+	userRepo user.Repository
+	web3Auth *auth.Web3Authenticator
+	logger   *zap.Logger
+}
+
+// NewIdentityHandler 创建登录身份处理器
+//
+// web3Auth 为 nil 时只能查看身份，不能关联钱包。
+func NewIdentityHandler(userRepo user.Repository, web3Auth *auth.Web3Authenticator, logger *zap.Logger) *IdentityHandler {
+	return &IdentityHandler{
+		userRepo: userRepo,
+		web3Auth: web3Auth,
+		logger:   logger,
+	}
+}
+
+// Handle 处理登录身份请求
+// GET    /api/identities
+// POST   /api/identities/wallets/challenge
+// POST   /api/identities/wallets
+// DELETE /api/identities/wallets/{address}，非以太坊钱包使用 CAIP-10 账户标识
+func (h *IdentityHandler) Handle(w http.ResponseWriter, r *http.Request) {
+	switch sub := strings.Trim(strings.TrimPrefix(r.URL.Path, identitiesPath), "/"); {
+	case sub == "":
+		if r.Method != http.MethodGet {
+			h.sendError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "Only GET method is allowed")
+			return
+		}
+		h.listIdentities(w, r)
+
+	case sub == "wallets/challenge":
+		if r.Method != http.MethodPost {
+			h.sendError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "Only POST method is allowed")
+			return
+		}
+		h.createLinkChallenge(w, r)
+
+	case sub == "wallets":
+		if r.Method != http.MethodPost {
+			h.sendError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "Only POST method is allowed")
+			return
+		}
+		h.linkWallet(w, r)
+
+	case strings.HasPrefix(sub, "wallets/") && !strings.Contains(sub[len("wallets/"):], "/"):
+		if r.Method != http.MethodDelete {
+			h.sendError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "Only DELETE method is allowed")
+			return
+		}
+		h.unlinkWallet(w, r, sub[len("wallets/"):])
+
+	default:
+		h.sendError(w, http.StatusNotFound, "NOT_FOUND", "Resource not found")
+	}
+}
+
+// listIdentities 列出登录身份
+func (h *IdentityHandler) listIdentities(w http.ResponseWriter, r *http.Request) {
+	u, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	h.sendJSON(w, http.StatusOK, toIdentityListResponse(u))
+}
+
+// createLinkChallenge 为要关联的钱包创建挑战
+func (h *IdentityHandler) createLinkChallenge(w http.ResponseWriter, r *http.Request) {
+	if h.web3Auth == nil {
+		h.sendError(w, http.StatusNotFound, "WEB3_DISABLED", "Web3 authentication is not enabled")
+		return
+	}
+
+	var req dto.ChallengeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendError(w, http.StatusBadRequest, "INVALID_REQUEST", "Invalid request body")
+		return
+	}
+	account, ok := h.resolveAccount(w, req.Chain, req.Address)
+	if !ok {
+		return
+	}
+
+	u, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+	if !h.checkWalletAvailable(w, r, u, account.ID) {
+		return
+	}
+
+	challenge, err := h.web3Auth.CreateLinkChallenge(account, u.Username, req.Format, requestOrigin(r))
+	if errors.Is(err, domainAuth.ErrUnsupportedFormat) {
+		h.sendError(w, http.StatusBadRequest, "UNSUPPORTED_FORMAT", "Challenge format is not supported for this chain")
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to create link challenge", zap.String("wallet", account.ID), zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "CHALLENGE_CREATION_FAILED", "Failed to create challenge")
+		return
+	}
+
+	h.sendJSON(w, http.StatusOK, toChallengeResponse(challenge))
+}
+
+// linkWallet 校验新钱包的签名并关联到当前用户
+func (h *IdentityHandler) linkWallet(w http.ResponseWriter, r *http.Request) {
+	if h.web3Auth == nil {
+		h.sendError(w, http.StatusNotFound, "WEB3_DISABLED", "Web3 authentication is not enabled")
+		return
+	}
+
+	var req dto.LinkWalletRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendError(w, http.StatusBadRequest, "INVALID_REQUEST", "Invalid request body")
+		return
+	}
+	account, ok := h.resolveAccount(w, req.Chain, req.Address)
+	if !ok {
+		return
+	}
+	if req.Signature == "" {
+		h.sendError(w, http.StatusBadRequest, "MISSING_SIGNATURE", "Signature is required")
+		return
+	}
+
+	u, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.web3Auth.VerifyLink(r.Context(), account, u.Username, req.Message, req.Signature, requestOrigin(r)); err != nil {
+		h.logger.Warn("wallet link signature rejected",
+			zap.String("username", u.Username),
+			zap.String("wallet", account.ID),
+			zap.Error(err))
+		if errors.Is(err, domainAuth.ErrChallengeExpired) {
+			h.sendError(w, http.StatusUnauthorized, "CHALLENGE_EXPIRED", "Challenge not found or expired")
+			return
+		}
+		h.sendError(w, http.StatusUnauthorized, "INVALID_SIGNATURE", "Signature verification failed")
+		return
+	}
+
+	u = u.Clone()
+	if err := u.LinkWallet(account.ID); err != nil {
+		if errors.Is(err, user.ErrDuplicateIdentity) {
+			h.sendError(w, http.StatusConflict, "WALLET_ALREADY_LINKED", "Wallet is already linked to this account")
+			return
+		}
+		h.sendError(w, http.StatusBadRequest, "INVALID_ADDRESS", "Invalid wallet address")
+		return
+	}
+
+	if err := h.userRepo.Save(r.Context(), u); err != nil {
+		if errors.Is(err, user.ErrDuplicateAddress) {
+			h.sendError(w, http.StatusConflict, "WALLET_IN_USE", "Wallet is linked to another account")
+			return
+		}
+		h.logger.Error("failed to save user", zap.String("username", u.Username), zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to link wallet")
+		return
+	}
+
+	h.logger.Info("wallet linked",
+		zap.String("username", u.Username),
+		zap.String("wallet", account.ID))
+
+	h.sendJSON(w, http.StatusCreated, toIdentityListResponse(u))
+}
+
+// unlinkWallet 取消关联钱包
+func (h *IdentityHandler) unlinkWallet(w http.ResponseWriter, r *http.Request, address string) {
+	u, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	u = u.Clone()
+	if err := u.UnlinkWallet(address); err != nil {
+		switch {
+		case errors.Is(err, user.ErrIdentityNotFound):
+			h.sendError(w, http.StatusNotFound, "WALLET_NOT_FOUND", "Wallet is not linked to this account")
+		case errors.Is(err, user.ErrLastSignInMethod):
+			h.sendError(w, http.StatusConflict, "LAST_SIGN_IN_METHOD", "Cannot unlink the only way to sign in")
+		default:
+			h.sendError(w, http.StatusBadRequest, "INVALID_REQUEST", err.Error())
+		}
+		return
+	}
+
+	if err := h.userRepo.Save(r.Context(), u); err != nil {
+		h.logger.Error("failed to save user", zap.String("username", u.Username), zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to unlink wallet")
+		return
+	}
+
+	h.logger.Info("wallet unlinked",
+		zap.String("username", u.Username),
+		zap.String("wallet", user.NormalizeWalletID(address)),
+		zap.String("primary_wallet", u.WalletAddress))
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// resolveAccount 解析请求中的链和地址，失败时写入错误响应
+func (h *IdentityHandler) resolveAccount(w http.ResponseWriter, chain, address string) (*auth.WalletAccount, bool) {
+	account, err := h.web3Auth.ResolveAccount(chain, address)
+	if err == nil {
+		return account, true
+	}
+
+	if errors.Is(err, domainAuth.ErrUnsupportedChain) {
+		h.sendError(w, http.StatusBadRequest, "UNSUPPORTED_CHAIN", "Chain is not supported")
+	} else {
+		h.sendError(w, http.StatusBadRequest, "INVALID_ADDRESS", "Invalid wallet address")
+	}
+	return nil, false
+}
+
+// checkWalletAvailable 钱包是否可以关联到当前用户，否则写入错误响应
+func (h *IdentityHandler) checkWalletAvailable(w http.ResponseWriter, r *http.Request, u *user.User, address string) bool {
+	if u.HasWallet(address) {
+		h.sendError(w, http.StatusConflict, "WALLET_ALREADY_LINKED", "Wallet is already linked to this account")
+		return false
+	}
+
+	_, err := h.userRepo.FindByWalletAddress(r.Context(), address)
+	switch {
+	case err == nil:
+		h.sendError(w, http.StatusConflict, "WALLET_IN_USE", "Wallet is linked to another account")
+		return false
+	case !errors.Is(err, user.ErrUserNotFound):
+		h.logger.Error("failed to find user", zap.String("address", address), zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to process request")
+		return false
+	}
+	return true
+}
+
+// currentUser 从仓储加载当前认证用户，失败时写入错误响应
+func (h *IdentityHandler) currentUser(w http.ResponseWriter, r *http.Request) (*user.User, bool) {
+	authenticated, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		h.sendError(w, http.StatusUnauthorized, "UNAUTHORIZED", "Authentication required")
+		return nil, false
+	}
+	if authenticated.Scope != nil {
+		h.sendError(w, http.StatusForbidden, "FORBIDDEN", "App passwords cannot manage sign-in identities")
+		return nil, false
+	}
+
+	u, err := h.userRepo.FindByUsername(r.Context(), authenticated.Username)
+	if err != nil {
+		if errors.Is(err, user.ErrUserNotFound) {
+			h.sendError(w, http.StatusNotFound, "USER_NOT_FOUND", "User not found")
+			return nil, false
+		}
+		h.logger.Error("failed to find user", zap.String("username", authenticated.Username), zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to process request")
+		return nil, false
+	}
+	return u, true
+}
+
+// toIdentityListResponse 转换为登录身份列表响应
+func toIdentityListResponse(u *user.User) dto.IdentityListResponse {
+	identities := make([]*dto.IdentityResponse, 0)
+	if u.HasPassword() {
+		identities = append(identities, &dto.IdentityResponse{Type: "password", Subject: u.Username})
+	}
+	primary := user.NormalizeWalletID(u.WalletAddress)
+	if primary != "" {
+		identities = append(identities, &dto.IdentityResponse{
+			Type:    user.IdentityWallet,
+			Subject: primary,
+			Primary: true,
+		})
+	}
+	for _, identity := range u.Identities {
+		if identity.Provider == user.IdentityWallet && identity.Subject == primary {
+			continue
+		}
+		createdAt := identity.CreatedAt
+		identities = append(identities, &dto.IdentityResponse{
+			Type:      identity.Provider,
+			Subject:   identity.Subject,
+			CreatedAt: &createdAt,
+		})
+	}
+	for _, fp := range u.Certificates {
+		identities = append(identities, &dto.IdentityResponse{Type: "certificate", Subject: fp})
+	}
+
+	return dto.IdentityListResponse{
+		Identities: identities,
+		Total:      len(identities),
+	}
+}
+
+// sendJSON 发送 JSON 响应
+func (h *IdentityHandler) sendJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		h.logger.Error("failed to encode response", zap.Error(err))
+	}
+}
+
+// sendError 发送错误响应
+func (h *IdentityHandler) sendError(w http.ResponseWriter, status int, code, message string) {
+	response := dto.NewErrorResponse(code, message)
+	h.sendJSON(w, status, response)
+}
@@ -0,0 +1,330 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mr-tron/base58"
+	"github.com/yeying-community/webdav/internal/domain/user"
+	"github.com/yeying-community/webdav/internal/infrastructure/auth"
+	"github.com/yeying-community/webdav/internal/infrastructure/config"
+	"github.com/yeying-community/webdav/internal/infrastructure/repository"
+	"github.com/yeying-community/webdav/internal/interface/http/dto"
+	"github.com/yeying-community/webdav/internal/interface/http/middleware"
+	"go.uber.org/zap"
+)
+
+// identityTestHost SIWE 消息中的域名
+const identityTestHost = "dav.example.com"
+
+// solanaKey 由种子派生的 Solana 钱包
+type solanaKey struct {
+	key     ed25519.PrivateKey
+	address string
+}
+
+func newSolanaKey(seed string) *solanaKey {
+	s := sha256.Sum256([]byte(seed))
+	key := ed25519.NewKeyFromSeed(s[:])
+	return &solanaKey{key: key, address: base58.Encode(key.Public().(ed25519.PublicKey))}
+}
+
+// sign 对消息签名
+func (k *solanaKey) sign(message string) string {
+	return base58.Encode(ed25519.Sign(k.key, []byte(message)))
+}
+
+// walletID 仓储中的钱包标识
+func (k *solanaKey) walletID() string {
+	return user.WalletID(user.NamespaceSolana, config.DefaultConfig().Web3.ChainReference(user.ChainSolana), k.address)
+}
+
+// identityTestServer 登录身份 API 测试环境：alice 有密码，bob 有主钱包
+type identityTestServer struct {
+	t       *testing.T
+	repo    user.Repository
+	handler *IdentityHandler
+	alice   *user.User
+	bob     *user.User
+	bobKey  *solanaKey
+}
+
+func newIdentityTestServer(t *testing.T) *identityTestServer {
+	t.Helper()
+	ctx := context.Background()
+
+	cfg := config.Web3Config{
+		JWTSecret:              "test-secret-test-secret-test-secret",
+		TokenExpiration:        time.Minute,
+		RefreshTokenExpiration: time.Hour,
+		SIWE: config.SIWEConfig{
+			Domain:    identityTestHost,
+			URI:       "https://" + identityTestHost,
+			ChainID:   1,
+			Statement: "Sign in to WebDAV.",
+			TTL:       time.Minute,
+			ClockSkew: time.Minute,
+		},
+		Chains: []string{user.ChainSolana},
+	}
+	jwtManager, err := auth.NewJWTManager(cfg)
+	if err != nil {
+		t.Fatalf("NewJWTManager: %v", err)
+	}
+
+	repo := repository.NewMemoryUserRepository(nil)
+	bobKey := newSolanaKey("bob")
+	alice := user.NewUser("alice", "/alice")
+	alice.Password = "{SHA}5en6G6MezRroT3XKqkdPOmY/BfQ="
+	bob := user.NewUser("bob", "/bob")
+	if err := bob.SetWalletAddress(bobKey.walletID()); err != nil {
+		t.Fatalf("SetWalletAddress: %v", err)
+	}
+	for _, u := range []*user.User{alice, bob} {
+		if err := repo.Save(ctx, u); err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+	}
+
+	web3Auth := auth.NewWeb3Authenticator(repo, cfg, jwtManager, nil, auth.NewMemoryRevocationStore(), zap.NewNop())
+	return &identityTestServer{
+		t:       t,
+		repo:    repo,
+		handler: NewIdentityHandler(repo, web3Auth, zap.NewNop()),
+		alice:   alice,
+		bob:     bob,
+		bobKey:  bobKey,
+	}
+}
+
+// do 以 as 的身份发送请求，body 不为 nil 时编码为 JSON
+func (s *identityTestServer) do(as *user.User, method, target string, body interface{}) *httptest.ResponseRecorder {
+	var payload bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&payload).Encode(body)
+	}
+	r := httptest.NewRequest(method, "https://"+identityTestHost+target, &payload)
+	r = r.WithContext(context.WithValue(r.Context(), middleware.UserContextKey, as))
+
+	w := httptest.NewRecorder()
+	s.handler.Handle(w, r)
+	return w
+}
+
+// challenge 以 as 的身份为钱包请求关联挑战，返回待签名的消息
+func (s *identityTestServer) challenge(as *user.User, key *solanaKey) string {
+	s.t.Helper()
+
+	w := s.do(as, http.MethodPost, identitiesPath+"/wallets/challenge", dto.ChallengeRequest{Chain: user.ChainSolana, Address: key.address})
+	if w.Code != http.StatusOK {
+		s.t.Fatalf("challenge: status = %d, body = %s", w.Code, w.Body)
+	}
+	var resp dto.ChallengeResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		s.t.Fatalf("decode challenge: %v", err)
+	}
+	return resp.Message
+}
+
+// link 以 as 的身份提交关联请求
+func (s *identityTestServer) link(as *user.User, key *solanaKey, message, signature string) *httptest.ResponseRecorder {
+	return s.do(as, http.MethodPost, identitiesPath+"/wallets", dto.LinkWalletRequest{
+		Chain:     user.ChainSolana,
+		Address:   key.address,
+		Message:   message,
+		Signature: signature,
+	})
+}
+
+// owner 钱包所属的用户名，未关联时返回空字符串
+func (s *identityTestServer) owner(key *solanaKey) string {
+	s.t.Helper()
+
+	u, err := s.repo.FindByWalletAddress(context.Background(), key.walletID())
+	if errors.Is(err, user.ErrUserNotFound) {
+		return ""
+	}
+	if err != nil {
+		s.t.Fatalf("FindByWalletAddress: %v", err)
+	}
+	return u.Username
+}
+
+func TestIdentityLinkRequiresWalletSignature(t *testing.T) {
+	s := newIdentityTestServer(t)
+	wallet := newSolanaKey("alice-wallet")
+	other := newSolanaKey("other")
+
+	tests := []struct {
+		name string
+		// link 返回提交的消息和签名
+		link     func(message string) (string, string)
+		wantCode string
+	}{
+		{
+			name:     "signed by another wallet",
+			link:     func(message string) (string, string) { return message, other.sign(message) },
+			wantCode: "INVALID_SIGNATURE",
+		},
+		{
+			name: "signature over a different message",
+			link: func(message string) (string, string) {
+				return message, wallet.sign(message + "\n")
+			},
+			wantCode: "INVALID_SIGNATURE",
+		},
+		{
+			name: "message for another domain",
+			link: func(message string) (string, string) {
+				altered := bytes.Replace([]byte(message), []byte(identityTestHost), []byte("evil.example.com"), 1)
+				return string(altered), wallet.sign(string(altered))
+			},
+			wantCode: "INVALID_SIGNATURE",
+		},
+		{
+			name:     "missing signature",
+			link:     func(message string) (string, string) { return message, "" },
+			wantCode: "MISSING_SIGNATURE",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			message, signature := tt.link(s.challenge(s.alice, wallet))
+			w := s.link(s.alice, wallet, message, signature)
+			if w.Code == http.StatusCreated || errorCode(t, w) != tt.wantCode {
+				t.Fatalf("status = %d, body = %s, want %s", w.Code, w.Body, tt.wantCode)
+			}
+			if owner := s.owner(wallet); owner != "" {
+				t.Fatalf("wallet linked to %q after a rejected signature", owner)
+			}
+		})
+	}
+
+	// 签名有效时关联到当前用户
+	message := s.challenge(s.alice, wallet)
+	signature := wallet.sign(message)
+	if w := s.link(s.alice, wallet, message, signature); w.Code != http.StatusCreated {
+		t.Fatalf("link: status = %d, body = %s", w.Code, w.Body)
+	}
+	if owner := s.owner(wallet); owner != "alice" {
+		t.Fatalf("wallet owner = %q, want alice", owner)
+	}
+
+	// 挑战只能使用一次
+	if w := s.link(s.alice, wallet, message, signature); errorCode(t, w) != "CHALLENGE_EXPIRED" {
+		t.Fatalf("replay: status = %d, body = %s", w.Code, w.Body)
+	}
+}
+
+func TestIdentityLinkChallengeBoundToUser(t *testing.T) {
+	s := newIdentityTestServer(t)
+	wallet := newSolanaKey("shared")
+
+	// 为 bob 签发的关联挑战不能用于关联到 alice
+	message := s.challenge(s.bob, wallet)
+	w := s.link(s.alice, wallet, message, wallet.sign(message))
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, body = %s, want 401", w.Code, w.Body)
+	}
+	if owner := s.owner(wallet); owner != "" {
+		t.Fatalf("wallet linked to %q with another user's challenge", owner)
+	}
+}
+
+func TestIdentityLinkWalletOfAnotherUser(t *testing.T) {
+	s := newIdentityTestServer(t)
+
+	// 已关联到 bob 的钱包不能再请求挑战
+	w := s.do(s.alice, http.MethodPost, identitiesPath+"/wallets/challenge", dto.ChallengeRequest{Chain: user.ChainSolana, Address: s.bobKey.address})
+	if w.Code != http.StatusConflict || errorCode(t, w) != "WALLET_IN_USE" {
+		t.Fatalf("challenge: status = %d, body = %s", w.Code, w.Body)
+	}
+
+	// 挑战签发后钱包被其他用户关联，提交时同样拒绝
+	wallet := newSolanaKey("contested")
+	message := s.challenge(s.alice, wallet)
+	carol := user.NewUser("carol", "/carol")
+	if err := carol.SetWalletAddress(wallet.walletID()); err != nil {
+		t.Fatalf("SetWalletAddress: %v", err)
+	}
+	if err := s.repo.Save(context.Background(), carol); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	w = s.link(s.alice, wallet, message, wallet.sign(message))
+	if w.Code != http.StatusConflict || errorCode(t, w) != "WALLET_IN_USE" {
+		t.Fatalf("link: status = %d, body = %s", w.Code, w.Body)
+	}
+	if owner := s.owner(wallet); owner != "carol" {
+		t.Fatalf("wallet owner = %q, want carol", owner)
+	}
+
+	// 已关联到自己的钱包
+	w = s.do(s.bob, http.MethodPost, identitiesPath+"/wallets/challenge", dto.ChallengeRequest{Chain: user.ChainSolana, Address: s.bobKey.address})
+	if w.Code != http.StatusConflict || errorCode(t, w) != "WALLET_ALREADY_LINKED" {
+		t.Fatalf("own wallet: status = %d, body = %s", w.Code, w.Body)
+	}
+}
+
+func TestIdentityUnlinkWallet(t *testing.T) {
+	s := newIdentityTestServer(t)
+	ctx := context.Background()
+	first, second, third := newSolanaKey("first"), newSolanaKey("second"), newSolanaKey("third")
+	for _, key := range []*solanaKey{first, second, third} {
+		message := s.challenge(s.alice, key)
+		if w := s.link(s.alice, key, message, key.sign(message)); w.Code != http.StatusCreated {
+			t.Fatalf("link: status = %d, body = %s", w.Code, w.Body)
+		}
+	}
+	unlink := func(as *user.User, key *solanaKey) *httptest.ResponseRecorder {
+		return s.do(as, http.MethodDelete, identitiesPath+"/wallets/"+key.walletID(), nil)
+	}
+
+	// 不能取消关联其他用户的钱包
+	if w := unlink(s.alice, s.bobKey); w.Code != http.StatusNotFound || errorCode(t, w) != "WALLET_NOT_FOUND" {
+		t.Fatalf("unlink bob's wallet: status = %d, body = %s", w.Code, w.Body)
+	}
+	if owner := s.owner(s.bobKey); owner != "bob" {
+		t.Fatalf("bob's wallet owner = %q, want bob", owner)
+	}
+
+	// 只移除指定的钱包
+	if w := unlink(s.alice, second); w.Code != http.StatusNoContent {
+		t.Fatalf("unlink: status = %d, body = %s", w.Code, w.Body)
+	}
+	if owner := s.owner(second); owner != "" {
+		t.Fatalf("unlinked wallet still belongs to %q", owner)
+	}
+	alice, err := s.repo.FindByUsername(ctx, "alice")
+	if err != nil {
+		t.Fatalf("FindByUsername: %v", err)
+	}
+	if !alice.HasWallet(first.walletID()) || alice.HasWallet(second.walletID()) || !alice.HasWallet(third.walletID()) || !alice.HasPassword() {
+		t.Fatalf("alice identities after unlink = %v", alice.WalletAddresses())
+	}
+	if w := unlink(s.alice, second); w.Code != http.StatusNotFound {
+		t.Fatalf("unlink twice: status = %d", w.Code)
+	}
+
+	// 不能移除唯一的登录方式
+	if w := unlink(s.bob, s.bobKey); w.Code != http.StatusConflict || errorCode(t, w) != "LAST_SIGN_IN_METHOD" {
+		t.Fatalf("unlink last wallet: status = %d, body = %s", w.Code, w.Body)
+	}
+
+	// 应用密码不能管理登录身份
+	scoped := alice.WithScope(&user.Scope{AppPasswordID: "app"})
+	if w := unlink(scoped, first); w.Code != http.StatusForbidden {
+		t.Fatalf("unlink with app password: status = %d", w.Code)
+	}
+	if owner := s.owner(first); owner != "alice" {
+		t.Fatalf("wallet owner = %q, want alice", owner)
+	}
+}
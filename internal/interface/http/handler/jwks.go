@@ -0,0 +1,49 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/yeying-community/webdav/internal/infrastructure/auth"
+	"go.uber.org/zap"
+)
+
+// jwksMaxAge JWKS 响应的缓存时间（秒）
+const jwksMaxAge = 300
+
+// JWKSHandler JWT 公钥集合处理器
+type JWKSHandler struct {
+	jwtManager *auth.JWTManager
+	logger     *zap.Logger
+}
+
+// NewJWKSHandler 创建 JWKS 处理器
+func NewJWKSHandler(jwtManager *auth.JWTManager, logger *zap.Logger) *JWKSHandler {
+	return &JWKSHandler{
+		jwtManager: jwtManager,
+		logger:     logger,
+	}
+}
+
+// Handle 返回用于验证本服务签发令牌的公钥
+// GET /.well-known/jwks.json
+func (h *JWKSHandler) Handle(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age="+strconv.Itoa(jwksMaxAge))
+	w.WriteHeader(http.StatusOK)
+
+	if r.Method == http.MethodHead {
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(h.jwtManager.JWKS()); err != nil {
+		h.logger.Error("failed to encode jwks response", zap.Error(err))
+	}
+}
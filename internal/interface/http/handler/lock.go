@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/yeying-community/webdav/internal/infrastructure/lock"
+	"github.com/yeying-community/webdav/internal/interface/http/dto"
+	"go.uber.org/zap"
+)
+
+// adminLocksPath 锁管理 API 路径
+const adminLocksPath = "/api/admin/locks"
+
+// LockHandler 锁管理处理器
+type LockHandler struct {
+	locks  *lock.Manager
+	logger *zap.Logger
+}
+
+// NewLockHandler 创建锁管理处理器
+func NewLockHandler(locks *lock.Manager, logger *zap.Logger) *LockHandler {
+	return &LockHandler{
+		locks:  locks,
+		logger: logger,
+	}
+}
+
+// HandleLocks 列出锁
+// GET /api/admin/locks[?username=...]
+func (h *LockHandler) HandleLocks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.sendError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "Only GET method is allowed")
+		return
+	}
+
+	locks, err := h.locks.List(r.Context())
+	if err != nil {
+		h.logger.Error("failed to list locks", zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to list locks")
+		return
+	}
+
+	username := r.URL.Query().Get("username")
+
+	sort.Slice(locks, func(i, j int) bool {
+		return locks[i].CreatedAt.Before(locks[j].CreatedAt)
+	})
+
+	response := dto.LockListResponse{
+		Locks: make([]*dto.LockResponse, 0, len(locks)),
+	}
+	for _, l := range locks {
+		if username != "" && l.Username != username {
+			continue
+		}
+		response.Locks = append(response.Locks, toLockResponse(l))
+	}
+	response.Total = len(response.Locks)
+
+	h.sendJSON(w, http.StatusOK, response)
+}
+
+// HandleLock 强制释放锁
+// DELETE /api/admin/locks/{token}
+func (h *LockHandler) HandleLock(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		h.sendError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "Only DELETE method is allowed")
+		return
+	}
+
+	token, err := url.PathUnescape(strings.Trim(strings.TrimPrefix(r.URL.Path, adminLocksPath), "/"))
+	if err != nil || token == "" {
+		h.sendError(w, http.StatusNotFound, "NOT_FOUND", "Resource not found")
+		return
+	}
+
+	if err := h.locks.Remove(r.Context(), token); err != nil {
+		if errors.Is(err, lock.ErrLockNotFound) {
+			h.sendError(w, http.StatusNotFound, "LOCK_NOT_FOUND", "Lock not found")
+			return
+		}
+		h.logger.Error("failed to remove lock", zap.String("token", token), zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to remove lock")
+		return
+	}
+
+	h.logger.Info("lock removed by admin",
+		zap.String("token", token),
+		zap.String("operator", currentUsername(r)))
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// toLockResponse 转换为锁响应
+func toLockResponse(l *lock.Lock) *dto.LockResponse {
+	response := &dto.LockResponse{
+		Token:     l.Token,
+		Username:  l.Username,
+		Directory: l.Directory,
+		Root:      l.Root,
+		Depth:     "infinity",
+		Owner:     l.OwnerXML,
+		CreatedAt: l.CreatedAt,
+	}
+	if l.ZeroDepth {
+		response.Depth = "0"
+	}
+	if !l.ExpiresAt.IsZero() {
+		expiresAt := l.ExpiresAt
+		response.ExpiresAt = &expiresAt
+	}
+	return response
+}
+
+// sendJSON 发送 JSON 响应
+func (h *LockHandler) sendJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		h.logger.Error("failed to encode response", zap.Error(err))
+	}
+}
+
+// sendError 发送错误响应
+func (h *LockHandler) sendError(w http.ResponseWriter, status int, code, message string) {
+	response := dto.NewErrorResponse(code, message)
+	h.sendJSON(w, status, response)
+}
@@ -0,0 +1,140 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	domainAuth "github.com/yeying-community/webdav/internal/domain/auth"
+	"github.com/yeying-community/webdav/internal/domain/user"
+	"github.com/yeying-community/webdav/internal/infrastructure/auth"
+	"github.com/yeying-community/webdav/internal/interface/http/dto"
+	"go.uber.org/zap"
+)
+
+// oidcStateCookie 绑定授权请求与发起登录的浏览器
+const oidcStateCookie = "webdav_oidc_state"
+
+// OIDCHandler OpenID Connect 登录处理器
+type OIDCHandler struct {
+	oidcAuth *auth.OIDCAuthenticator
+	logger   *zap.Logger
+}
+
+// NewOIDCHandler 创建 OIDC 处理器
+func NewOIDCHandler(oidcAuth *auth.OIDCAuthenticator, logger *zap.Logger) *OIDCHandler {
+	return &OIDCHandler{
+		oidcAuth: oidcAuth,
+		logger:   logger,
+	}
+}
+
+// HandleLogin 跳转到身份提供方登录
+// GET /api/auth/oidc/login
+func (h *OIDCHandler) HandleLogin(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.sendError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "Only GET method is allowed")
+		return
+	}
+
+	origin := requestOrigin(r)
+	authURL, state, err := h.oidcAuth.AuthCodeURL(r.Context(), h.oidcAuth.RedirectURL(origin))
+	if err != nil {
+		h.logger.Error("failed to start oidc login", zap.Error(err))
+		h.sendError(w, http.StatusBadGateway, "OIDC_UNAVAILABLE", "Identity provider is unavailable")
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    state,
+		Path:     "/api/auth/oidc/",
+		MaxAge:   600,
+		HttpOnly: true,
+		Secure:   origin.Secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// HandleCallback 处理身份提供方回调
+// GET /api/auth/oidc/callback?code=...&state=...
+func (h *OIDCHandler) HandleCallback(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.sendError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "Only GET method is allowed")
+		return
+	}
+
+	query := r.URL.Query()
+	if errCode := query.Get("error"); errCode != "" {
+		h.logger.Warn("oidc login rejected by provider",
+			zap.String("error", errCode),
+			zap.String("description", query.Get("error_description")))
+		h.sendError(w, http.StatusUnauthorized, "OIDC_LOGIN_FAILED", "Login rejected by identity provider")
+		return
+	}
+
+	state := query.Get("state")
+	code := query.Get("code")
+	if state == "" || code == "" {
+		h.sendError(w, http.StatusBadRequest, "INVALID_REQUEST", "code and state are required")
+		return
+	}
+
+	// state 必须与发起登录时写入的 Cookie 一致，防止登录 CSRF
+	cookie, err := r.Cookie(oidcStateCookie)
+	if err != nil || cookie.Value != state {
+		h.sendError(w, http.StatusBadRequest, "INVALID_STATE", "Login state mismatch")
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Path:     "/api/auth/oidc/",
+		MaxAge:   -1,
+		HttpOnly: true,
+	})
+
+	login, err := h.oidcAuth.Exchange(r.Context(), state, code)
+	if err != nil {
+		switch {
+		case errors.Is(err, domainAuth.ErrChallengeExpired):
+			h.sendError(w, http.StatusBadRequest, "INVALID_STATE", "Login state expired")
+		case errors.Is(err, user.ErrUserNotFound):
+			h.sendError(w, http.StatusForbidden, "USER_NOT_FOUND", "User is not registered")
+		default:
+			h.logger.Warn("oidc login failed", zap.Error(err))
+			h.sendError(w, http.StatusUnauthorized, "OIDC_LOGIN_FAILED", "Login failed")
+		}
+		return
+	}
+
+	response := dto.OIDCLoginResponse{
+		Token:        login.IDToken,
+		ExpiresAt:    login.ExpiresAt,
+		RefreshToken: login.RefreshToken,
+		User: &dto.UserInfo{
+			Username:    login.User.Username,
+			Permissions: permissionStrings(login.User.Permissions),
+		},
+	}
+
+	h.sendJSON(w, http.StatusOK, response)
+}
+
+// sendJSON 发送 JSON 响应
+func (h *OIDCHandler) sendJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		h.logger.Error("failed to encode response", zap.Error(err))
+	}
+}
+
+// sendError 发送错误响应
+func (h *OIDCHandler) sendError(w http.ResponseWriter, status int, code, message string) {
+	response := dto.NewErrorResponse(code, message)
+	h.sendJSON(w, status, response)
+}
@@ -0,0 +1,329 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/yeying-community/webdav/internal/domain/user"
+	"github.com/yeying-community/webdav/internal/infrastructure/crypto"
+	"github.com/yeying-community/webdav/internal/interface/http/dto"
+	"github.com/yeying-community/webdav/internal/interface/http/middleware"
+	"go.uber.org/zap"
+)
+
+// twoFactorPath 两步验证 API 路径
+const twoFactorPath = "/api/2fa"
+
+// qrCodeSize 二维码图片边长（像素）
+const qrCodeSize = 256
+
+// TwoFactorHandler 两步验证处理器，用户管理自己的 TOTP
+type TwoFactorHandler struct {
+	userRepo user.Repository
+	totp     *crypto.TOTP
+	logger   *zap.Logger
+}
+
+// NewTwoFactorHandler 创建两步验证处理器
+func NewTwoFactorHandler(userRepo user.Repository, issuer string, logger *zap.Logger) *TwoFactorHandler {
+	return &TwoFactorHandler{
+		userRepo: userRepo,
+		totp:     crypto.NewTOTP(issuer),
+		logger:   logger,
+	}
+}
+
+// Handle 处理两步验证请求
+// GET    /api/2fa
+// POST   /api/2fa/totp
+// POST   /api/2fa/totp/confirm
+// DELETE /api/2fa/totp
+// POST   /api/2fa/recovery-codes
+func (h *TwoFactorHandler) Handle(w http.ResponseWriter, r *http.Request) {
+	switch sub := strings.Trim(strings.TrimPrefix(r.URL.Path, twoFactorPath), "/"); {
+	case sub == "":
+		if r.Method != http.MethodGet {
+			h.sendError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "Only GET method is allowed")
+			return
+		}
+		h.getStatus(w, r)
+
+	case sub == "totp":
+		switch r.Method {
+		case http.MethodPost:
+			h.enroll(w, r)
+		case http.MethodDelete:
+			h.disable(w, r)
+		default:
+			h.sendError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "Only POST and DELETE methods are allowed")
+		}
+
+	case sub == "totp/confirm":
+		if r.Method != http.MethodPost {
+			h.sendError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "Only POST method is allowed")
+			return
+		}
+		h.confirm(w, r)
+
+	case sub == "recovery-codes":
+		if r.Method != http.MethodPost {
+			h.sendError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "Only POST method is allowed")
+			return
+		}
+		h.regenerateRecoveryCodes(w, r)
+
+	default:
+		h.sendError(w, http.StatusNotFound, "NOT_FOUND", "Resource not found")
+	}
+}
+
+// getStatus 获取两步验证状态
+func (h *TwoFactorHandler) getStatus(w http.ResponseWriter, r *http.Request) {
+	u, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	response := dto.TwoFactorStatusResponse{}
+	if u.TOTP != nil {
+		response.Enabled = u.TOTP.Enabled
+		response.Pending = !u.TOTP.Enabled
+		response.EnabledAt = u.TOTP.EnabledAt
+		response.RecoveryCodesRemaining = len(u.TOTP.RecoveryCodes)
+	}
+
+	h.sendJSON(w, http.StatusOK, response)
+}
+
+// enroll 生成新的 TOTP 密钥，确认前不生效
+func (h *TwoFactorHandler) enroll(w http.ResponseWriter, r *http.Request) {
+	u, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+	if u.HasTwoFactor() {
+		h.sendError(w, http.StatusConflict, "ALREADY_ENABLED", "Two-factor authentication is already enabled")
+		return
+	}
+
+	key, err := h.totp.Generate(u.Username)
+	if err != nil {
+		h.logger.Error("failed to generate totp key", zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to start enrollment")
+		return
+	}
+	qrCode, err := h.totp.QRCode(key.URI, qrCodeSize)
+	if err != nil {
+		h.logger.Error("failed to render qr code", zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to start enrollment")
+		return
+	}
+
+	u = u.Clone()
+	u.TOTP = &user.TOTP{Secret: key.Secret}
+	u.UpdatedAt = time.Now()
+	if !h.saveUser(w, r, u) {
+		return
+	}
+
+	h.logger.Info("totp enrollment started", zap.String("username", u.Username))
+
+	h.sendJSON(w, http.StatusOK, dto.TOTPEnrollResponse{
+		Secret: key.Secret,
+		URI:    key.URI,
+		QRCode: qrCode,
+	})
+}
+
+// confirm 用验证码确认注册，启用两步验证并返回恢复码
+func (h *TwoFactorHandler) confirm(w http.ResponseWriter, r *http.Request) {
+	req, ok := h.decodeCode(w, r)
+	if !ok {
+		return
+	}
+
+	u, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+	if u.TOTP == nil {
+		h.sendError(w, http.StatusConflict, "NOT_ENROLLED", "Start enrollment first")
+		return
+	}
+	if u.TOTP.Enabled {
+		h.sendError(w, http.StatusConflict, "ALREADY_ENABLED", "Two-factor authentication is already enabled")
+		return
+	}
+	if !h.totp.Validate(u.TOTP.Secret, req.Code) {
+		h.sendError(w, http.StatusBadRequest, "INVALID_CODE", "Invalid verification code")
+		return
+	}
+
+	codes, hashes, err := user.NewRecoveryCodes()
+	if err != nil {
+		h.logger.Error("failed to generate recovery codes", zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to enable two-factor authentication")
+		return
+	}
+
+	now := time.Now()
+	u = u.Clone()
+	u.TOTP.Enabled = true
+	u.TOTP.EnabledAt = &now
+	u.TOTP.RecoveryCodes = hashes
+	u.UpdatedAt = now
+	if !h.saveUser(w, r, u) {
+		return
+	}
+
+	h.logger.Info("two-factor authentication enabled", zap.String("username", u.Username))
+
+	h.sendJSON(w, http.StatusOK, dto.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// disable 关闭两步验证，需要验证码或恢复码
+func (h *TwoFactorHandler) disable(w http.ResponseWriter, r *http.Request) {
+	req, ok := h.decodeCode(w, r)
+	if !ok {
+		return
+	}
+
+	u, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+	if u.TOTP == nil {
+		h.sendError(w, http.StatusConflict, "NOT_ENROLLED", "Two-factor authentication is not enabled")
+		return
+	}
+
+	u = u.Clone()
+	if u.TOTP.Enabled && !h.verifyCode(u, req.Code) {
+		h.sendError(w, http.StatusBadRequest, "INVALID_CODE", "Invalid verification code")
+		return
+	}
+
+	u.TOTP = nil
+	u.UpdatedAt = time.Now()
+	if !h.saveUser(w, r, u) {
+		return
+	}
+
+	h.logger.Info("two-factor authentication disabled", zap.String("username", u.Username))
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// regenerateRecoveryCodes 重新生成恢复码，旧恢复码全部作废
+func (h *TwoFactorHandler) regenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	req, ok := h.decodeCode(w, r)
+	if !ok {
+		return
+	}
+
+	u, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+	if !u.HasTwoFactor() {
+		h.sendError(w, http.StatusConflict, "NOT_ENROLLED", "Two-factor authentication is not enabled")
+		return
+	}
+	if !h.totp.Validate(u.TOTP.Secret, req.Code) {
+		h.sendError(w, http.StatusBadRequest, "INVALID_CODE", "Invalid verification code")
+		return
+	}
+
+	codes, hashes, err := user.NewRecoveryCodes()
+	if err != nil {
+		h.logger.Error("failed to generate recovery codes", zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to generate recovery codes")
+		return
+	}
+
+	u = u.Clone()
+	u.TOTP.RecoveryCodes = hashes
+	u.UpdatedAt = time.Now()
+	if !h.saveUser(w, r, u) {
+		return
+	}
+
+	h.logger.Info("recovery codes regenerated", zap.String("username", u.Username))
+
+	h.sendJSON(w, http.StatusOK, dto.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// verifyCode 验证验证码或恢复码，恢复码在 u 上消耗
+func (h *TwoFactorHandler) verifyCode(u *user.User, code string) bool {
+	return h.totp.Validate(u.TOTP.Secret, code) || u.TOTP.UseRecoveryCode(code)
+}
+
+// decodeCode 解析携带验证码的请求体
+func (h *TwoFactorHandler) decodeCode(w http.ResponseWriter, r *http.Request) (*dto.TOTPCodeRequest, bool) {
+	var req dto.TOTPCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendError(w, http.StatusBadRequest, "INVALID_REQUEST", "Invalid request body")
+		return nil, false
+	}
+	if strings.TrimSpace(req.Code) == "" {
+		h.sendError(w, http.StatusBadRequest, "MISSING_CODE", "Verification code is required")
+		return nil, false
+	}
+	return &req, true
+}
+
+// currentUser 从仓储加载当前认证用户，失败时写入错误响应
+//
+// 通过应用密码认证的会话不能管理两步验证。
+func (h *TwoFactorHandler) currentUser(w http.ResponseWriter, r *http.Request) (*user.User, bool) {
+	authenticated, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		h.sendError(w, http.StatusUnauthorized, "UNAUTHORIZED", "Authentication required")
+		return nil, false
+	}
+	if authenticated.Scope != nil {
+		h.sendError(w, http.StatusForbidden, "FORBIDDEN", "App passwords cannot manage two-factor authentication")
+		return nil, false
+	}
+
+	u, err := h.userRepo.FindByUsername(r.Context(), authenticated.Username)
+	if err != nil {
+		if errors.Is(err, user.ErrUserNotFound) {
+			h.sendError(w, http.StatusNotFound, "USER_NOT_FOUND", "User not found")
+			return nil, false
+		}
+		h.logger.Error("failed to find user", zap.String("username", authenticated.Username), zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to process request")
+		return nil, false
+	}
+	return u, true
+}
+
+// saveUser 保存用户，失败时写入错误响应
+func (h *TwoFactorHandler) saveUser(w http.ResponseWriter, r *http.Request, u *user.User) bool {
+	if err := h.userRepo.Save(r.Context(), u); err != nil {
+		h.logger.Error("failed to save user", zap.String("username", u.Username), zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to save two-factor settings")
+		return false
+	}
+	return true
+}
+
+// sendJSON 发送 JSON 响应
+func (h *TwoFactorHandler) sendJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		h.logger.Error("failed to encode response", zap.Error(err))
+	}
+}
+
+// sendError 发送错误响应
+func (h *TwoFactorHandler) sendError(w http.ResponseWriter, status int, code, message string) {
+	response := dto.NewErrorResponse(code, message)
+	h.sendJSON(w, status, response)
+}
@@ -2,9 +2,12 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
 	"strings"
 
+	domainAuth "github.com/yeying-community/webdav/internal/domain/auth"
 	"github.com/yeying-community/webdav/internal/domain/user"
 	"github.com/yeying-community/webdav/internal/infrastructure/auth"
 	"github.com/yeying-community/webdav/internal/interface/http/dto"
@@ -13,33 +16,40 @@ import (
 
 // Web3Handler Web3 认证处理器
 type Web3Handler struct {
-	web3Auth *auth.Web3Authenticator
-	userRepo user.Repository
-	logger   *zap.Logger
+	web3Auth  *auth.Web3Authenticator
+	userRepo  user.Repository
+	registrar *auth.WalletRegistrar
+	logger    *zap.Logger
 }
 
 // NewWeb3Handler 创建 Web3 处理器
+//
+// registrar 为 nil 时只有已登记的钱包可以登录。
 func NewWeb3Handler(
 	web3Auth *auth.Web3Authenticator,
 	userRepo user.Repository,
+	registrar *auth.WalletRegistrar,
 	logger *zap.Logger,
 ) *Web3Handler {
 	return &Web3Handler{
-		web3Auth: web3Auth,
-		userRepo: userRepo,
-		logger:   logger,
+		web3Auth:  web3Auth,
+		userRepo:  userRepo,
+		registrar: registrar,
+		logger:    logger,
 	}
 }
 
 // HandleChallenge 处理挑战请求
-// GET /api/auth/challenge?address=0x123...
+// GET /api/auth/challenge?address=0x123...&chain=ethereum&format=eip712
 func (h *Web3Handler) HandleChallenge(w http.ResponseWriter, r *http.Request) {
-	var address string
+	var chain, address, format string
 
 	// 获取地址参数
 	switch r.Method {
 	case http.MethodGet:
+		chain = r.URL.Query().Get("chain")
 		address = r.URL.Query().Get("address")
+		format = r.URL.Query().Get("format")
 
 	case http.MethodPost:
 		var req dto.ChallengeRequest
@@ -47,7 +57,9 @@ func (h *Web3Handler) HandleChallenge(w http.ResponseWriter, r *http.Request) {
 			h.sendError(w, http.StatusBadRequest, "INVALID_REQUEST", "Invalid request body")
 			return
 		}
+		chain = req.Chain
 		address = req.Address
+		format = req.Format
 
 	default:
 		h.sendError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "Only GET and POST methods are allowed")
@@ -59,45 +71,51 @@ func (h *Web3Handler) HandleChallenge(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 规范化地址
-	address = strings.ToLower(strings.TrimSpace(address))
+	// 解析链和地址
+	account, ok := h.resolveAccount(w, chain, address)
+	if !ok {
+		return
+	}
 
-	// 检查用户是否存在
+	// 检查用户是否存在，未登记的钱包在开放注册时也可以获取挑战
 	ctx := r.Context()
-	u, err := h.userRepo.FindByWalletAddress(ctx, address)
-	if err != nil {
-		if err == user.ErrUserNotFound {
-			h.logger.Info("wallet address not registered", zap.String("address", address))
-			h.sendError(w, http.StatusNotFound, "USER_NOT_FOUND", "Wallet address not registered")
-			return
-		}
-
-		h.logger.Error("failed to find user", zap.String("address", address), zap.Error(err))
+	var username string
+	u, err := h.userRepo.FindByWalletAddress(ctx, account.ID)
+	switch {
+	case err == nil:
+		username = u.Username
+	case err == user.ErrUserNotFound && h.registrar != nil && h.registrar.CanRegister(account.ID):
+		// 签名验证通过后注册
+	case err == user.ErrUserNotFound:
+		h.logger.Info("wallet address not registered", zap.String("wallet", account.ID))
+		h.sendError(w, http.StatusNotFound, "USER_NOT_FOUND", "Wallet address not registered")
+		return
+	default:
+		h.logger.Error("failed to find user", zap.String("wallet", account.ID), zap.Error(err))
 		h.sendError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to process request")
 		return
 	}
 
 	// 创建挑战
-	challenge, err := h.web3Auth.CreateChallenge(address)
+	challenge, err := h.web3Auth.CreateChallenge(account, format, requestOrigin(r))
+	if errors.Is(err, domainAuth.ErrUnsupportedFormat) {
+		h.sendError(w, http.StatusBadRequest, "UNSUPPORTED_FORMAT", "Challenge format is not supported for this chain")
+		return
+	}
 	if err != nil {
-		h.logger.Error("failed to create challenge", zap.String("address", address), zap.Error(err))
+		h.logger.Error("failed to create challenge", zap.String("wallet", account.ID), zap.Error(err))
 		h.sendError(w, http.StatusInternalServerError, "CHALLENGE_CREATION_FAILED", "Failed to create challenge")
 		return
 	}
 
 	h.logger.Info("challenge created",
-		zap.String("address", address),
-		zap.String("username", u.Username),
+		zap.String("wallet", account.ID),
+		zap.String("username", username),
+		zap.String("format", challenge.Format),
 		zap.String("nonce", challenge.Nonce))
 
 	// 返回挑战
-	response := dto.ChallengeResponse{
-		Nonce:     challenge.Nonce,
-		Message:   challenge.Message,
-		ExpiresAt: challenge.ExpiresAt,
-	}
-
-	h.sendJSON(w, http.StatusOK, response)
+	h.sendJSON(w, http.StatusOK, toChallengeResponse(challenge))
 }
 
 // HandleVerify 处理验证请求
@@ -127,54 +145,217 @@ func (h *Web3Handler) HandleVerify(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 规范化地址
-	req.Address = strings.ToLower(strings.TrimSpace(req.Address))
+	// 解析链和地址
+	account, ok := h.resolveAccount(w, req.Chain, req.Address)
+	if !ok {
+		return
+	}
 
-	// 查找用户
+	// 查找用户，未登记的钱包先检查能否注册，签名验证通过后再创建用户
 	ctx := r.Context()
-	u, err := h.userRepo.FindByWalletAddress(ctx, req.Address)
+	u, err := h.userRepo.FindByWalletAddress(ctx, account.ID)
 	if err != nil {
-		if err == user.ErrUserNotFound {
-			h.logger.Info("wallet address not registered", zap.String("address", req.Address))
+		if err == user.ErrUserNotFound && h.registrar != nil {
+			if err := h.registrar.Check(account.ID, req.InviteCode); err != nil {
+				h.sendRegistrationError(w, account.ID, err)
+				return
+			}
+		} else if err == user.ErrUserNotFound {
+			h.logger.Info("wallet address not registered", zap.String("wallet", account.ID))
 			h.sendError(w, http.StatusNotFound, "USER_NOT_FOUND", "Wallet address not registered")
 			return
+		} else {
+			h.logger.Error("failed to find user", zap.String("wallet", account.ID), zap.Error(err))
+			h.sendError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to process request")
+			return
 		}
-
-		h.logger.Error("failed to find user", zap.String("address", req.Address), zap.Error(err))
-		h.sendError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to process request")
-		return
 	}
 
 	// 验证签名并生成 token
-	token, err := h.web3Auth.VerifySignature(ctx, req.Address, req.Signature)
+	tokens, err := h.web3Auth.VerifySignature(ctx, account, req.Message, req.Signature, requestOrigin(r))
 	if err != nil {
 		h.logger.Warn("signature verification failed",
-			zap.String("address", req.Address),
+			zap.String("wallet", account.ID),
 			zap.Error(err))
 		h.sendError(w, http.StatusUnauthorized, "INVALID_SIGNATURE", "Signature verification failed")
 		return
 	}
 
+	registered := false
+	if u == nil {
+		u, err = h.registrar.Register(ctx, account.ID, req.InviteCode)
+		if err != nil {
+			h.sendRegistrationError(w, account.ID, err)
+			return
+		}
+		registered = true
+	}
+
 	h.logger.Info("user authenticated via web3",
-		zap.String("address", req.Address),
+		zap.String("wallet", account.ID),
 		zap.String("username", u.Username))
 
 	// 构建响应
 	response := dto.VerifyResponse{
-		Token:     token.Value,
-		ExpiresAt: token.ExpiresAt,
+		Token:            tokens.Access.Value,
+		ExpiresAt:        tokens.Access.ExpiresAt,
+		RefreshToken:     tokens.Refresh.Value,
+		RefreshExpiresAt: tokens.Refresh.ExpiresAt,
 		User: &dto.UserInfo{
 			Username:      u.Username,
 			WalletAddress: u.WalletAddress,
-			Permissions:   h.getPermissionStrings(u.Permissions),
+			Permissions:   permissionStrings(u.Permissions),
 		},
+		Registered: registered,
 	}
 
 	h.sendJSON(w, http.StatusOK, response)
 }
 
-// getPermissionStrings 获取权限字符串列表
-func (h *Web3Handler) getPermissionStrings(perms *user.Permissions) []string {
+// toChallengeResponse 挑战响应，EIP-712 挑战返回类型化数据而不是文本消息
+func toChallengeResponse(c *domainAuth.Challenge) dto.ChallengeResponse {
+	response := dto.ChallengeResponse{
+		Nonce:     c.Nonce,
+		Format:    c.Format,
+		ExpiresAt: c.ExpiresAt,
+	}
+	if c.Format == domainAuth.ChallengeFormatEIP712 {
+		response.TypedData = json.RawMessage(c.Message)
+	} else {
+		response.Message = c.Message
+	}
+	return response
+}
+
+// resolveAccount 解析请求中的链和地址，失败时发送错误响应
+func (h *Web3Handler) resolveAccount(w http.ResponseWriter, chain, address string) (*auth.WalletAccount, bool) {
+	account, err := h.web3Auth.ResolveAccount(chain, address)
+	if err == nil {
+		return account, true
+	}
+
+	if errors.Is(err, domainAuth.ErrUnsupportedChain) {
+		h.sendError(w, http.StatusBadRequest, "UNSUPPORTED_CHAIN", "Chain is not supported")
+	} else {
+		h.sendError(w, http.StatusBadRequest, "INVALID_ADDRESS", "Invalid wallet address")
+	}
+	return nil, false
+}
+
+// sendRegistrationError 发送注册失败响应
+func (h *Web3Handler) sendRegistrationError(w http.ResponseWriter, address string, err error) {
+	switch {
+	case errors.Is(err, domainAuth.ErrRegistrationNotAllowed):
+		h.logger.Info("wallet registration not allowed", zap.String("address", address))
+		h.sendError(w, http.StatusForbidden, "REGISTRATION_NOT_ALLOWED", "Wallet address is not allowed to register")
+	case errors.Is(err, domainAuth.ErrInvalidInviteCode):
+		h.logger.Warn("invalid invite code", zap.String("address", address))
+		h.sendError(w, http.StatusForbidden, "INVALID_INVITE_CODE", "Invalid invite code")
+	default:
+		h.logger.Error("failed to register wallet", zap.String("address", address), zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "REGISTRATION_FAILED", "Failed to register wallet")
+	}
+}
+
+// HandleRefresh 处理刷新令牌请求
+// POST /api/auth/refresh
+func (h *Web3Handler) HandleRefresh(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.sendError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "Only POST method is allowed")
+		return
+	}
+
+	var req dto.RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Warn("invalid request body", zap.Error(err))
+		h.sendError(w, http.StatusBadRequest, "INVALID_REQUEST", "Invalid request body")
+		return
+	}
+
+	if req.RefreshToken == "" {
+		h.sendError(w, http.StatusBadRequest, "MISSING_REFRESH_TOKEN", "Refresh token is required")
+		return
+	}
+
+	tokens, err := h.web3Auth.Refresh(r.Context(), req.RefreshToken)
+	if err != nil {
+		switch {
+		case errors.Is(err, domainAuth.ErrTokenExpired):
+			h.sendError(w, http.StatusUnauthorized, "TOKEN_EXPIRED", "Refresh token has expired")
+		case errors.Is(err, domainAuth.ErrTokenRevoked):
+			h.sendError(w, http.StatusUnauthorized, "TOKEN_REVOKED", "Refresh token has been revoked")
+		case errors.Is(err, domainAuth.ErrInvalidToken), errors.Is(err, user.ErrUserNotFound):
+			h.sendError(w, http.StatusUnauthorized, "INVALID_TOKEN", "Invalid refresh token")
+		default:
+			h.logger.Error("failed to refresh token", zap.Error(err))
+			h.sendError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to process request")
+		}
+		return
+	}
+
+	response := dto.RefreshResponse{
+		Token:            tokens.Access.Value,
+		ExpiresAt:        tokens.Access.ExpiresAt,
+		RefreshToken:     tokens.Refresh.Value,
+		RefreshExpiresAt: tokens.Refresh.ExpiresAt,
+	}
+
+	h.sendJSON(w, http.StatusOK, response)
+}
+
+// HandleLogout 处理注销请求
+// POST /api/auth/logout
+//
+// 访问令牌通过 Authorization: Bearer 传递，刷新令牌可放在请求体中，
+// 两者所在的令牌家族都会被吊销。
+func (h *Web3Handler) HandleLogout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.sendError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "Only POST method is allowed")
+		return
+	}
+
+	var req dto.LogoutRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+			h.logger.Warn("invalid request body", zap.Error(err))
+			h.sendError(w, http.StatusBadRequest, "INVALID_REQUEST", "Invalid request body")
+			return
+		}
+	}
+
+	var accessToken string
+	if authHeader := r.Header.Get("Authorization"); strings.HasPrefix(authHeader, "Bearer ") {
+		accessToken = strings.TrimSpace(strings.TrimPrefix(authHeader, "Bearer "))
+	}
+
+	if accessToken == "" && req.RefreshToken == "" {
+		h.sendError(w, http.StatusBadRequest, "MISSING_TOKEN", "Access token or refresh token is required")
+		return
+	}
+
+	if err := h.web3Auth.Logout(r.Context(), accessToken, req.RefreshToken); err != nil {
+		if errors.Is(err, domainAuth.ErrInvalidToken) {
+			h.sendError(w, http.StatusUnauthorized, "INVALID_TOKEN", "Invalid token")
+			return
+		}
+		h.logger.Error("failed to logout", zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to process request")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// requestOrigin 获取请求来源站点，用于 SIWE 域名绑定
+func requestOrigin(r *http.Request) auth.RequestOrigin {
+	return auth.RequestOrigin{
+		Host:   r.Host,
+		Secure: r.TLS != nil || strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https"),
+	}
+}
+
+// permissionStrings 获取权限字符串列表
+func permissionStrings(perms *user.Permissions) []string {
 	var permissions []string
 
 	if perms.Create {
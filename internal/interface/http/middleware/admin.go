@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/yeying-community/webdav/internal/interface/http/dto"
+	"go.uber.org/zap"
+)
+
+// AdminMiddleware 管理员权限中间件（需位于认证中间件之后）
+type AdminMiddleware struct {
+	logger *zap.Logger
+}
+
+// NewAdminMiddleware 创建管理员权限中间件
+func NewAdminMiddleware(logger *zap.Logger) *AdminMiddleware {
+	return &AdminMiddleware{
+		logger: logger,
+	}
+}
+
+// Handle 检查当前用户是否为管理员
+func (m *AdminMiddleware) Handle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		u, ok := GetUserFromContext(r.Context())
+		if !ok {
+			m.sendError(w, http.StatusUnauthorized, "UNAUTHORIZED", "Authentication required")
+			return
+		}
+
+		if !u.IsAdmin() {
+			m.logger.Warn("admin access denied",
+				zap.String("username", u.Username),
+				zap.String("method", r.Method),
+				zap.String("path", r.URL.Path))
+			m.sendError(w, http.StatusForbidden, "FORBIDDEN", "Admin role required")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// sendError 发送错误响应
+func (m *AdminMiddleware) sendError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(dto.NewErrorResponse(code, message)); err != nil {
+		m.logger.Error("failed to encode response", zap.Error(err))
+	}
+}
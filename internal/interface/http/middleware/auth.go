@@ -2,11 +2,17 @@ package middleware
 
 import (
 	"context"
+	"errors"
+	"math"
+	"net"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/yeying-community/webdav/internal/domain/auth"
 	"github.com/yeying-community/webdav/internal/domain/user"
+	"github.com/yeying-community/webdav/internal/infrastructure/ratelimit"
 	"go.uber.org/zap"
 )
 
@@ -16,22 +22,40 @@ type contextKey string
 const (
 	// UserContextKey 用户上下文键
 	UserContextKey contextKey = "user"
+
+	// OTPHeader 两步验证码请求头，启用两步验证的用户使用账号密码时需要携带，每个验证码只能使用一次
+	OTPHeader = "X-OTP"
 )
 
+// AuthLimits 认证失败锁定与请求限流，为 nil 的字段不启用
+//
+// 计数器在所有使用认证中间件的路由之间共享。
+type AuthLimits struct {
+	IPThrottle       *ratelimit.Throttle // 按客户端 IP 统计认证失败
+	UsernameThrottle *ratelimit.Throttle // 按用户名统计认证失败
+	RateLimiter      *ratelimit.Limiter  // 按用户（未认证请求按 IP）限流
+	BehindProxy      bool                // 从代理头获取客户端 IP
+}
+
 // AuthMiddleware 认证中间件
 type AuthMiddleware struct {
 	authenticators []auth.Authenticator
 	required       bool
+	limits         AuthLimits
 	logger         *zap.Logger
 }
 
-// NewAuthMiddleware 创建认证中间件
-func NewAuthMiddleware(authenticators []auth.Authenticator, required bool, logger *zap.Logger) *AuthMiddleware {
-	return &AuthMiddleware{
+// NewAuthMiddleware 创建认证中间件，limits 为 nil 时不做锁定和限流
+func NewAuthMiddleware(authenticators []auth.Authenticator, required bool, limits *AuthLimits, logger *zap.Logger) *AuthMiddleware {
+	m := &AuthMiddleware{
 		authenticators: authenticators,
 		required:       required,
 		logger:         logger,
 	}
+	if limits != nil {
+		m.limits = *limits
+	}
+	return m
 }
 
 // Handle 处理认证
@@ -45,6 +69,8 @@ func (m *AuthMiddleware) Handle(next http.Handler) http.Handler {
 			return
 		}
 
+		clientIP := ClientIP(r, m.limits.BehindProxy)
+
 		// 尝试从请求中提取凭证
 		credentials := m.extractCredentials(r)
 
@@ -54,19 +80,58 @@ func (m *AuthMiddleware) Handle(next http.Handler) http.Handler {
 				m.sendUnauthorized(w, "Authentication required")
 				return
 			}
-			// 不需要认证，继续
+			// 不需要认证，按 IP 限流后继续
+			if !m.allow(w, "ip:"+clientIP) {
+				return
+			}
 			next.ServeHTTP(w, r)
 			return
 		}
 
+		// 锁定期间直接拒绝，不再校验密码
+		username := credentialsUsername(credentials)
+		if wait := m.lockedFor(clientIP, username); wait > 0 {
+			m.logger.Warn("authentication locked out",
+				zap.String("client_ip", clientIP),
+				zap.String("username", username),
+				zap.Duration("retry_after", wait))
+			m.sendTooManyRequests(w, wait, "Too many failed authentication attempts")
+			return
+		}
+
 		// 尝试使用所有认证器进行认证
 		u, err := m.authenticate(ctx, credentials)
 		if err != nil {
-			m.logger.Warn("authentication failed", zap.Error(err))
+			m.logger.Warn("authentication failed",
+				zap.String("client_ip", clientIP),
+				zap.Error(err))
+			if countsAsFailure(err) {
+				m.recordFailure(clientIP, username)
+			}
+			if errors.Is(err, auth.ErrStaleNonce) {
+				m.writeChallenges(w, true)
+				http.Error(w, "Stale nonce", http.StatusUnauthorized)
+				return
+			}
+			if errors.Is(err, user.ErrTwoFactorRequired) {
+				// 密码正确但缺少验证码：API 客户端应提示输入验证码，
+				// 验证码只能使用一次，WebDAV 客户端必须改用应用密码
+				w.Header().Set(OTPHeader, "required")
+				m.sendUnauthorized(w, "Two-factor authentication code required in the "+OTPHeader+" header; WebDAV clients must use an app password")
+				return
+			}
 			m.sendUnauthorized(w, "Authentication failed")
 			return
 		}
 
+		if username != "" && m.limits.UsernameThrottle != nil {
+			m.limits.UsernameThrottle.Reset(username)
+		}
+
+		if !m.allow(w, "user:"+u.Username) {
+			return
+		}
+
 		// 将用户信息放入上下文
 		ctx = context.WithValue(ctx, UserContextKey, u)
 		r = r.WithContext(ctx)
@@ -124,20 +189,147 @@ func (m *AuthMiddleware) extractCredentials(r *http.Request) interface{} {
 		return &auth.BasicCredentials{
 			Username: username,
 			Password: password,
+			OTP:      strings.TrimSpace(r.Header.Get(OTPHeader)),
 		}
 	}
 
+	// 3. 尝试 Digest Auth
+	if len(authHeader) > 7 && strings.EqualFold(authHeader[:7], "Digest ") {
+		if creds := parseDigestCredentials(r, authHeader[7:]); creds != nil {
+			return creds
+		}
+	}
+
+	// 4. 尝试 TLS 客户端证书
+	if cert := PeerCertificate(r); cert != nil {
+		return &auth.CertificateCredentials{Certificate: cert}
+	}
+
 	return nil
 }
 
+// lockedFor 客户端 IP 或用户名的剩余锁定时间
+func (m *AuthMiddleware) lockedFor(clientIP, username string) time.Duration {
+	var wait time.Duration
+	if m.limits.IPThrottle != nil {
+		wait = m.limits.IPThrottle.Locked(clientIP)
+	}
+	if username != "" && m.limits.UsernameThrottle != nil {
+		if w := m.limits.UsernameThrottle.Locked(username); w > wait {
+			wait = w
+		}
+	}
+	return wait
+}
+
+// recordFailure 记录认证失败
+func (m *AuthMiddleware) recordFailure(clientIP, username string) {
+	if m.limits.IPThrottle != nil {
+		if delay := m.limits.IPThrottle.Fail(clientIP); delay > 0 {
+			m.logger.Warn("client ip locked out after repeated authentication failures",
+				zap.String("client_ip", clientIP),
+				zap.Duration("duration", delay))
+		}
+	}
+	if username != "" && m.limits.UsernameThrottle != nil {
+		if delay := m.limits.UsernameThrottle.Fail(username); delay > 0 {
+			m.logger.Warn("username locked out after repeated authentication failures",
+				zap.String("username", username),
+				zap.Duration("duration", delay))
+		}
+	}
+}
+
+// allow 检查请求限流，超限时写入 429 响应
+func (m *AuthMiddleware) allow(w http.ResponseWriter, key string) bool {
+	if m.limits.RateLimiter == nil {
+		return true
+	}
+	ok, wait := m.limits.RateLimiter.Allow(key)
+	if !ok {
+		m.logger.Debug("rate limit exceeded", zap.String("key", key))
+		m.sendTooManyRequests(w, wait, "Rate limit exceeded")
+	}
+	return ok
+}
+
+// credentialsUsername 凭证中的用户名，Bearer 凭证没有用户名
+func credentialsUsername(credentials interface{}) string {
+	switch creds := credentials.(type) {
+	case *auth.BasicCredentials:
+		return creds.Username
+	case *auth.DigestCredentials:
+		return creds.Username
+	default:
+		return ""
+	}
+}
+
+// countsAsFailure 是否计入认证失败
+//
+// 过期或已吊销的令牌来自正常客户端，缺少两步验证码或 Digest nonce 失效说明密码正确，
+// 均不计入；错误的验证码计入。
+func countsAsFailure(err error) bool {
+	return !errors.Is(err, auth.ErrTokenExpired) &&
+		!errors.Is(err, auth.ErrTokenRevoked) &&
+		!errors.Is(err, auth.ErrStaleNonce) &&
+		!errors.Is(err, user.ErrTwoFactorRequired)
+}
+
+// sendTooManyRequests 发送限流响应
+func (m *AuthMiddleware) sendTooManyRequests(w http.ResponseWriter, wait time.Duration, message string) {
+	seconds := int(math.Ceil(wait.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	http.Error(w, message, http.StatusTooManyRequests)
+}
+
 // sendUnauthorized 发送未授权响应
 func (m *AuthMiddleware) sendUnauthorized(w http.ResponseWriter, message string) {
-	w.Header().Set("WWW-Authenticate", `Basic realm="WebDAV"`)
+	m.writeChallenges(w, false)
 	http.Error(w, message, http.StatusUnauthorized)
 }
 
+// writeChallenges 写入 Basic 质询和认证器提供的其他质询
+func (m *AuthMiddleware) writeChallenges(w http.ResponseWriter, stale bool) {
+	w.Header().Set("WWW-Authenticate", `Basic realm="WebDAV"`)
+	for _, authenticator := range m.authenticators {
+		if challenger, ok := authenticator.(Challenger); ok {
+			for _, challenge := range challenger.Challenges(stale) {
+				w.Header().Add("WWW-Authenticate", challenge)
+			}
+		}
+	}
+}
+
 // GetUserFromContext 从上下文获取用户
 func GetUserFromContext(ctx context.Context) (*user.User, bool) {
 	u, ok := ctx.Value(UserContextKey).(*user.User)
 	return u, ok
 }
+
+// ClientIP 获取客户端 IP
+//
+// behindProxy 为 true 时信任反向代理设置的头：取 X-Forwarded-For 的最后一项（由最近的代理追加，
+// 客户端无法伪造），其次 X-Real-IP。
+func ClientIP(r *http.Request, behindProxy bool) string {
+	if behindProxy {
+		if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+			parts := strings.Split(xff, ",")
+			if ip := strings.TrimSpace(parts[len(parts)-1]); ip != "" {
+				return ip
+			}
+		}
+		if xri := strings.TrimSpace(r.Header.Get("X-Real-IP")); xri != "" {
+			return xri
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,223 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/yeying-community/webdav/internal/domain/auth"
+	"github.com/yeying-community/webdav/internal/domain/user"
+	"github.com/yeying-community/webdav/internal/infrastructure/ratelimit"
+	"go.uber.org/zap"
+)
+
+// passwordAuthenticator 测试用 Basic 认证器，密码为 "secret" 时成功
+type passwordAuthenticator struct{}
+
+func (passwordAuthenticator) Name() string { return "test" }
+
+func (passwordAuthenticator) CanHandle(credentials interface{}) bool {
+	_, ok := credentials.(*auth.BasicCredentials)
+	return ok
+}
+
+func (passwordAuthenticator) Authenticate(ctx context.Context, credentials interface{}) (*user.User, error) {
+	creds := credentials.(*auth.BasicCredentials)
+	if creds.Password != "secret" {
+		return nil, user.ErrInvalidPassword
+	}
+	return user.NewUser(creds.Username, "/"+creds.Username), nil
+}
+
+// newLimitedAuthHandler 带认证失败锁定的测试处理器
+func newLimitedAuthHandler(limits *AuthLimits) http.Handler {
+	m := NewAuthMiddleware([]auth.Authenticator{passwordAuthenticator{}}, true, limits, zap.NewNop())
+	return m.Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+}
+
+// basicRequest 发送 Basic 认证请求
+func basicRequest(h http.Handler, ip, username, password string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.RemoteAddr = ip + ":1234"
+	r.SetBasicAuth(username, password)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestAuthLockout(t *testing.T) {
+	limits := &AuthLimits{
+		IPThrottle:       ratelimit.NewThrottle(5, time.Minute, time.Hour, time.Hour),
+		UsernameThrottle: ratelimit.NewThrottle(2, 30*time.Second, time.Hour, time.Hour),
+	}
+	defer limits.IPThrottle.Close()
+	defer limits.UsernameThrottle.Close()
+	h := newLimitedAuthHandler(limits)
+
+	// 第二次失败后用户名被锁定 30 秒，锁定期间正确的密码也被拒绝
+	for i := 0; i < 2; i++ {
+		if w := basicRequest(h, "10.0.0.1", "alice", "wrong"); w.Code != http.StatusUnauthorized {
+			t.Fatalf("failure %d: status = %d, want 401", i+1, w.Code)
+		}
+	}
+	w := basicRequest(h, "10.0.0.2", "Alice", "secret")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("locked username: status = %d, want 429", w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "30" {
+		t.Fatalf("Retry-After = %q, want 30", got)
+	}
+
+	// 锁定时长按指数增长
+	limits.UsernameThrottle.Reset("alice")
+	basicRequest(h, "10.0.0.3", "alice", "wrong")
+	basicRequest(h, "10.0.0.3", "alice", "wrong")
+	limits.UsernameThrottle.Fail("alice")
+	if got := basicRequest(h, "10.0.0.3", "alice", "secret").Header().Get("Retry-After"); got != "60" {
+		t.Fatalf("Retry-After after third failure = %q, want 60", got)
+	}
+
+	// 同一 IP 的失败累计到 IP 锁定，其他用户名也被拒绝
+	for i := 0; i < 5; i++ {
+		basicRequest(h, "10.0.0.9", fmt.Sprintf("user%d", i), "wrong")
+	}
+	w = basicRequest(h, "10.0.0.9", "bob", "secret")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "60" {
+		t.Fatalf("locked ip: status = %d, Retry-After = %q", w.Code, w.Header().Get("Retry-After"))
+	}
+}
+
+func TestAuthSuccessResetsUsername(t *testing.T) {
+	limits := &AuthLimits{UsernameThrottle: ratelimit.NewThrottle(3, time.Minute, time.Hour, time.Hour)}
+	defer limits.UsernameThrottle.Close()
+	h := newLimitedAuthHandler(limits)
+
+	basicRequest(h, "10.0.0.1", "alice", "wrong")
+	basicRequest(h, "10.0.0.1", "alice", "wrong")
+	if w := basicRequest(h, "10.0.0.1", "alice", "secret"); w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", w.Code)
+	}
+
+	// 成功登录清除计数，之后两次失败不会锁定
+	basicRequest(h, "10.0.0.1", "alice", "wrong")
+	basicRequest(h, "10.0.0.1", "alice", "wrong")
+	if wait := limits.UsernameThrottle.Locked("alice"); wait != 0 {
+		t.Fatalf("alice locked for %v after a successful login", wait)
+	}
+}
+
+func TestCountsAsFailure(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{err: user.ErrInvalidPassword, want: true},
+		{err: auth.ErrInvalidCredentials, want: true},
+		{err: auth.ErrInvalidToken, want: true},
+		{err: user.ErrInvalidOTP, want: true},
+		{err: user.ErrUserNotFound, want: true},
+		{err: auth.ErrTokenExpired, want: false},
+		{err: auth.ErrTokenRevoked, want: false},
+		{err: auth.ErrStaleNonce, want: false},
+		{err: user.ErrTwoFactorRequired, want: false},
+		{err: fmt.Errorf("wrapped: %w", auth.ErrTokenExpired), want: false},
+	}
+
+	for _, tt := range tests {
+		if got := countsAsFailure(tt.err); got != tt.want {
+			t.Errorf("countsAsFailure(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name        string
+		behindProxy bool
+		remoteAddr  string
+		xff         string
+		xri         string
+		want        string
+	}{
+		{name: "remote address", remoteAddr: "192.0.2.1:5000", want: "192.0.2.1"},
+		{name: "ipv6 remote address", remoteAddr: "[2001:db8::1]:5000", want: "2001:db8::1"},
+		{name: "headers ignored without proxy", remoteAddr: "192.0.2.1:5000", xff: "198.51.100.7", xri: "198.51.100.8", want: "192.0.2.1"},
+		// 客户端可以伪造前面的项，最后一项由最近的代理追加
+		{name: "last forwarded entry", behindProxy: true, remoteAddr: "10.0.0.1:5000", xff: "203.0.113.9, 198.51.100.7", want: "198.51.100.7"},
+		{name: "single forwarded entry", behindProxy: true, remoteAddr: "10.0.0.1:5000", xff: " 198.51.100.7 ", want: "198.51.100.7"},
+		{name: "forwarded before real ip", behindProxy: true, remoteAddr: "10.0.0.1:5000", xff: "198.51.100.7", xri: "198.51.100.8", want: "198.51.100.7"},
+		{name: "real ip fallback", behindProxy: true, remoteAddr: "10.0.0.1:5000", xri: "198.51.100.8", want: "198.51.100.8"},
+		{name: "empty last forwarded entry", behindProxy: true, remoteAddr: "10.0.0.1:5000", xff: "198.51.100.7, ", xri: "198.51.100.8", want: "198.51.100.8"},
+		{name: "remote address fallback", behindProxy: true, remoteAddr: "10.0.0.1:5000", want: "10.0.0.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			if tt.xff != "" {
+				r.Header.Set("X-Forwarded-For", tt.xff)
+			}
+			if tt.xri != "" {
+				r.Header.Set("X-Real-IP", tt.xri)
+			}
+			if got := ClientIP(r, tt.behindProxy); got != tt.want {
+				t.Fatalf("ClientIP = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthNonFailuresDoNotLock(t *testing.T) {
+	tests := []struct {
+		err      error
+		wantLock bool
+	}{
+		{err: auth.ErrTokenExpired},
+		{err: auth.ErrTokenRevoked},
+		{err: user.ErrTwoFactorRequired},
+		{err: auth.ErrInvalidToken, wantLock: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.err.Error(), func(t *testing.T) {
+			limits := &AuthLimits{IPThrottle: ratelimit.NewThrottle(1, time.Minute, time.Hour, time.Hour)}
+			defer limits.IPThrottle.Close()
+			m := NewAuthMiddleware([]auth.Authenticator{&stubAuthenticator{err: tt.err}}, true, limits, zap.NewNop())
+			h := m.Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+			// 阈值为 1，计入失败的错误第一次就会锁定
+			r := httptest.NewRequest("GET", "/", nil)
+			r.Header.Set("Authorization", "Bearer token")
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			if w.Code != http.StatusUnauthorized {
+				t.Fatalf("status = %d, want 401", w.Code)
+			}
+			if locked := limits.IPThrottle.Locked(ClientIP(r, false)) > 0; locked != tt.wantLock {
+				t.Fatalf("locked = %v, want %v", locked, tt.wantLock)
+			}
+		})
+	}
+}
+
+// stubAuthenticator 对 Bearer 凭证返回固定错误
+type stubAuthenticator struct {
+	err error
+}
+
+func (a *stubAuthenticator) Name() string { return "stub" }
+
+func (a *stubAuthenticator) CanHandle(credentials interface{}) bool {
+	_, ok := credentials.(*auth.BearerCredentials)
+	return ok
+}
+
+func (a *stubAuthenticator) Authenticate(ctx context.Context, credentials interface{}) (*user.User, error) {
+	return nil, a.err
+}
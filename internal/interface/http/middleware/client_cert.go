@@ -0,0 +1,126 @@
+package middleware
+
+import (
+	"crypto/x509"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/yeying-community/webdav/internal/domain/user"
+	"go.uber.org/zap"
+)
+
+// CertificateMatcher 判断客户端证书是否属于用户
+type CertificateMatcher interface {
+	Matches(cert *x509.Certificate, u *user.User) bool
+}
+
+// ClientCertMiddleware 客户端证书强制中间件，需放在认证中间件之后
+//
+// 访问指定路径前缀，或指定用户访问任意路径时，必须在 TLS 握手中提供已验证的客户端证书，
+// 并且证书必须属于当前认证的用户。
+//
+// 路径前缀与用户规则一样基于 WebDAV 根目录内的路径（不含 webdav.prefix），
+// COPY/MOVE 的目标路径同样受保护。
+type ClientCertMiddleware struct {
+	requiredUsers map[string]bool
+	requiredPaths []string
+	prefix        string
+	matcher       CertificateMatcher
+	logger        *zap.Logger
+}
+
+// NewClientCertMiddleware 创建客户端证书强制中间件，prefix 为 WebDAV 路由前缀
+func NewClientCertMiddleware(requiredUsers, requiredPaths []string, prefix string, matcher CertificateMatcher, logger *zap.Logger) *ClientCertMiddleware {
+	users := make(map[string]bool, len(requiredUsers))
+	for _, username := range requiredUsers {
+		users[username] = true
+	}
+	return &ClientCertMiddleware{
+		requiredUsers: users,
+		requiredPaths: requiredPaths,
+		prefix:        "/" + strings.Trim(prefix, "/"),
+		matcher:       matcher,
+		logger:        logger,
+	}
+}
+
+// Handle 处理请求
+func (m *ClientCertMiddleware) Handle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "OPTIONS" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		u, authenticated := GetUserFromContext(r.Context())
+		if !m.requestRequired(r) && !(authenticated && m.requiredUsers[u.Username]) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cert := PeerCertificate(r)
+		if cert == nil {
+			m.logger.Warn("client certificate required",
+				zap.String("path", r.URL.Path))
+			http.Error(w, "Client certificate required", http.StatusForbidden)
+			return
+		}
+
+		if authenticated && !m.matcher.Matches(cert, u) {
+			m.logger.Warn("client certificate does not belong to user",
+				zap.String("username", u.Username),
+				zap.String("subject", cert.Subject.String()))
+			http.Error(w, "Client certificate does not belong to the authenticated user", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requestRequired 请求路径或 COPY/MOVE 的目标路径是否要求客户端证书
+func (m *ClientCertMiddleware) requestRequired(r *http.Request) bool {
+	if m.pathRequired(r.URL.Path) {
+		return true
+	}
+
+	destination := r.Header.Get("Destination")
+	if destination == "" || (r.Method != "COPY" && r.Method != "MOVE") {
+		return false
+	}
+	u, err := url.Parse(destination)
+	if err != nil {
+		// 无法解析的目标会被 WebDAV 处理器拒绝，这里按受保护处理
+		return len(m.requiredPaths) > 0
+	}
+	return m.pathRequired(u.Path)
+}
+
+// pathRequired 路径是否要求客户端证书（去除前缀后按路径段前缀匹配）
+func (m *ClientCertMiddleware) pathRequired(p string) bool {
+	p = path.Clean("/" + p)
+	if m.prefix != "/" {
+		if p != m.prefix && !strings.HasPrefix(p, m.prefix+"/") {
+			return false
+		}
+		p = "/" + strings.TrimPrefix(strings.TrimPrefix(p, m.prefix), "/")
+	}
+
+	for _, prefix := range m.requiredPaths {
+		prefix = "/" + strings.Trim(prefix, "/")
+		if prefix == "/" || p == prefix || strings.HasPrefix(p, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// PeerCertificate TLS 握手中已验证的客户端证书，没有时返回 nil
+func PeerCertificate(r *http.Request) *x509.Certificate {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil
+	}
+	return r.TLS.VerifiedChains[0][0]
+}
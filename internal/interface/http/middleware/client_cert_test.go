@@ -0,0 +1,133 @@
+package middleware
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/yeying-community/webdav/internal/domain/user"
+	"go.uber.org/zap"
+)
+
+// newTestCertificate 生成通用名称为 cn 的自签名证书
+func newTestCertificate(t *testing.T, cn string) *x509.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("CreateCertificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("ParseCertificate: %v", err)
+	}
+	return cert
+}
+
+// cnMatcher 通用名称等于用户名时证书属于该用户
+type cnMatcher struct{}
+
+func (cnMatcher) Matches(cert *x509.Certificate, u *user.User) bool {
+	return cert.Subject.CommonName == u.Username
+}
+
+func TestClientCertMiddleware(t *testing.T) {
+	alice := user.NewUser("alice", "/alice")
+	bob := user.NewUser("bob", "/bob")
+	aliceCert := newTestCertificate(t, "alice")
+	bobCert := newTestCertificate(t, "bob")
+
+	tests := []struct {
+		name        string
+		user        *user.User
+		method      string
+		path        string
+		destination string
+		cert        *x509.Certificate
+		want        int
+	}{
+		{name: "unprotected path", user: alice, method: "GET", path: "/dav/public/f", want: http.StatusOK},
+		{name: "required path without certificate", user: alice, method: "GET", path: "/dav/secure/f", want: http.StatusForbidden},
+		{name: "required path root", user: alice, method: "PROPFIND", path: "/dav/secure", want: http.StatusForbidden},
+		{name: "required path with own certificate", user: alice, method: "GET", path: "/dav/secure/f", cert: aliceCert, want: http.StatusOK},
+		{name: "required path with another user's certificate", user: alice, method: "GET", path: "/dav/secure/f", cert: bobCert, want: http.StatusForbidden},
+		{name: "segment prefix only", user: alice, method: "GET", path: "/dav/securely/f", want: http.StatusOK},
+		{name: "dot segments", user: alice, method: "GET", path: "/dav/public/../secure/f", want: http.StatusForbidden},
+		// required_paths 不含 webdav.prefix，与用户规则一致
+		{name: "path outside prefix", user: alice, method: "GET", path: "/secure/f", want: http.StatusOK},
+		{name: "move into required path", user: alice, method: "MOVE", path: "/dav/public/f", destination: "/dav/secure/f", want: http.StatusForbidden},
+		{name: "copy into required path by url", user: alice, method: "COPY", path: "/dav/public/f", destination: "https://dav.example.com/dav/secure/f", want: http.StatusForbidden},
+		{name: "copy into required path via dot segments", user: alice, method: "COPY", path: "/dav/public/f", destination: "/dav/public/../secure/f", want: http.StatusForbidden},
+		{name: "copy into required path with certificate", user: alice, method: "COPY", path: "/dav/public/f", destination: "/dav/secure/f", cert: aliceCert, want: http.StatusOK},
+		{name: "copy within unprotected paths", user: alice, method: "COPY", path: "/dav/public/f", destination: "/dav/public/g", want: http.StatusOK},
+		{name: "destination ignored for other methods", user: alice, method: "GET", path: "/dav/public/f", destination: "/dav/secure/f", want: http.StatusOK},
+		{name: "required user without certificate", user: bob, method: "GET", path: "/dav/public/f", want: http.StatusForbidden},
+		{name: "required user with own certificate", user: bob, method: "GET", path: "/dav/public/f", cert: bobCert, want: http.StatusOK},
+		{name: "required user with another user's certificate", user: bob, method: "GET", path: "/dav/public/f", cert: aliceCert, want: http.StatusForbidden},
+		{name: "options bypasses the check", user: bob, method: "OPTIONS", path: "/dav/secure/f", want: http.StatusOK},
+	}
+
+	m := NewClientCertMiddleware([]string{"bob"}, []string{"/secure/"}, "/dav/", cnMatcher{}, zap.NewNop())
+	handler := m.Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "https://dav.example.com/", nil)
+			r.URL.Path = tt.path
+			if tt.destination != "" {
+				r.Header.Set("Destination", tt.destination)
+			}
+			if tt.cert != nil {
+				r.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{tt.cert}}}
+			}
+			r = r.WithContext(context.WithValue(r.Context(), UserContextKey, tt.user))
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientCertMiddlewareRootPrefix(t *testing.T) {
+	m := NewClientCertMiddleware(nil, []string{"/secure"}, "/", cnMatcher{}, zap.NewNop())
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{path: "/secure", want: true},
+		{path: "/secure/f", want: true},
+		{path: "/public/f", want: false},
+		{path: "/public/../secure", want: true},
+	}
+	for _, tt := range tests {
+		if got := m.pathRequired(tt.path); got != tt.want {
+			t.Errorf("pathRequired(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
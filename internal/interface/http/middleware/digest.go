@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/yeying-community/webdav/internal/domain/auth"
+)
+
+// Challenger 可以发出 WWW-Authenticate 质询的认证器
+//
+// stale 为 true 表示客户端的响应正确但 nonce 已失效，应直接使用新 nonce 重试。
+type Challenger interface {
+	Challenges(stale bool) []string
+}
+
+// parseDigestCredentials 解析 Digest 认证头，格式不正确时返回 nil
+func parseDigestCredentials(r *http.Request, header string) *auth.DigestCredentials {
+	params, ok := parseAuthParams(header)
+	if !ok || params["username"] == "" {
+		return nil
+	}
+
+	return &auth.DigestCredentials{
+		Username:   params["username"],
+		Realm:      params["realm"],
+		Nonce:      params["nonce"],
+		URI:        params["uri"],
+		Response:   params["response"],
+		Algorithm:  params["algorithm"],
+		QOP:        params["qop"],
+		NC:         params["nc"],
+		CNonce:     params["cnonce"],
+		Opaque:     params["opaque"],
+		UserHash:   strings.EqualFold(params["userhash"], "true"),
+		Method:     r.Method,
+		RequestURI: r.RequestURI,
+	}
+}
+
+// parseAuthParams 解析逗号分隔的 auth-param 列表（RFC 7235），参数名统一为小写
+func parseAuthParams(s string) (map[string]string, bool) {
+	params := make(map[string]string)
+	for {
+		s = strings.TrimLeft(s, " \t,")
+		if s == "" {
+			return params, true
+		}
+
+		eq := strings.IndexByte(s, '=')
+		if eq <= 0 {
+			return nil, false
+		}
+		key := strings.ToLower(strings.TrimSpace(s[:eq]))
+		s = strings.TrimLeft(s[eq+1:], " \t")
+
+		var value string
+		if strings.HasPrefix(s, `"`) {
+			var b strings.Builder
+			i := 1
+			for ; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' && i+1 < len(s) {
+					i++
+				}
+				b.WriteByte(s[i])
+			}
+			if i >= len(s) {
+				return nil, false
+			}
+			value = b.String()
+			s = s[i+1:]
+		} else {
+			end := strings.IndexByte(s, ',')
+			if end < 0 {
+				end = len(s)
+			}
+			value = strings.TrimSpace(s[:end])
+			s = s[end:]
+		}
+
+		params[key] = value
+	}
+}
@@ -34,26 +34,24 @@ func (m *PermissionMiddleware) Handle(next http.Handler) http.Handler {
 			return
 		}
 
-		// 映射 HTTP 方法到操作
-		operation := permission.MapHTTPMethodToOperation(r.Method)
-
-		// 检查权限
-		if err := m.checker.Check(ctx, u, r.URL.Path, operation); err != nil {
-			m.logger.Warn("permission denied",
-				zap.String("username", u.Username),
-				zap.String("method", r.Method),
-				zap.String("path", r.URL.Path),
-				zap.String("operation", string(operation)),
-				zap.Error(err))
-			http.Error(w, "Forbidden", http.StatusForbidden)
-			return
+		// 检查源路径所需的全部操作（COPY/MOVE 的目标路径由 WebDAV 服务检查）
+		for _, operation := range permission.MapHTTPMethodToOperations(r.Method) {
+			if err := m.checker.Check(ctx, u, r.URL.Path, operation); err != nil {
+				m.logger.Warn("permission denied",
+					zap.String("username", u.Username),
+					zap.String("method", r.Method),
+					zap.String("path", r.URL.Path),
+					zap.String("operation", string(operation)),
+					zap.Error(err))
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
 		}
 
 		m.logger.Debug("permission granted",
 			zap.String("username", u.Username),
 			zap.String("method", r.Method),
-			zap.String("path", r.URL.Path),
-			zap.String("operation", string(operation)))
+			zap.String("path", r.URL.Path))
 
 		next.ServeHTTP(w, r)
 	})
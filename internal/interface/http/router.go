@@ -15,8 +15,17 @@ import (
 type Router struct {
 	config         *config.Config
 	authenticators []auth.Authenticator
+	authLimits     *middleware.AuthLimits
+	certMatcher    middleware.CertificateMatcher
 	healthHandler  *handler.HealthHandler
 	web3Handler    *handler.Web3Handler
+	jwksHandler    *handler.JWKSHandler
+	oidcHandler    *handler.OIDCHandler
+	appPasswords   *handler.AppPasswordHandler
+	twoFactor      *handler.TwoFactorHandler
+	identities     *handler.IdentityHandler
+	adminHandler   *handler.AdminHandler
+	lockHandler    *handler.LockHandler
 	webdavHandler  *handler.WebDAVHandler
 	logger         *zap.Logger
 }
@@ -25,16 +34,34 @@ type Router struct {
 func NewRouter(
 	cfg *config.Config,
 	authenticators []auth.Authenticator,
+	authLimits *middleware.AuthLimits,
+	certMatcher middleware.CertificateMatcher,
 	healthHandler *handler.HealthHandler,
 	web3Handler *handler.Web3Handler,
+	jwksHandler *handler.JWKSHandler,
+	oidcHandler *handler.OIDCHandler,
+	appPasswords *handler.AppPasswordHandler,
+	twoFactor *handler.TwoFactorHandler,
+	identities *handler.IdentityHandler,
+	adminHandler *handler.AdminHandler,
+	lockHandler *handler.LockHandler,
 	webdavHandler *handler.WebDAVHandler,
 	logger *zap.Logger,
 ) *Router {
 	return &Router{
 		config:         cfg,
 		authenticators: authenticators,
+		authLimits:     authLimits,
+		certMatcher:    certMatcher,
 		healthHandler:  healthHandler,
 		web3Handler:    web3Handler,
+		jwksHandler:    jwksHandler,
+		oidcHandler:    oidcHandler,
+		appPasswords:   appPasswords,
+		twoFactor:      twoFactor,
+		identities:     identities,
+		adminHandler:   adminHandler,
+		lockHandler:    lockHandler,
 		webdavHandler:  webdavHandler,
 		logger:         logger,
 	}
@@ -51,6 +78,47 @@ func (r *Router) Setup() http.Handler {
 	if r.config.Web3.Enabled {
 		mux.HandleFunc("/api/auth/challenge", r.web3Handler.HandleChallenge)
 		mux.HandleFunc("/api/auth/verify", r.web3Handler.HandleVerify)
+		mux.HandleFunc("/api/auth/refresh", r.web3Handler.HandleRefresh)
+		mux.HandleFunc("/api/auth/logout", r.web3Handler.HandleLogout)
+	}
+
+	// OIDC 登录路由（无需认证）
+	if r.oidcHandler != nil {
+		mux.HandleFunc("/api/auth/oidc/login", r.oidcHandler.HandleLogin)
+		mux.HandleFunc("/api/auth/oidc/callback", r.oidcHandler.HandleCallback)
+	}
+
+	// JWT 公钥（无需认证），供其他服务验证本服务签发的令牌
+	if r.jwksHandler != nil {
+		mux.HandleFunc("/.well-known/jwks.json", r.jwksHandler.Handle)
+	}
+
+	// 应用密码路由（需要认证），用户管理自己的应用密码
+	if r.appPasswords != nil {
+		mux.Handle("/api/app-passwords", r.createAuthenticatedHandler(r.appPasswords.HandleAppPasswords))
+		mux.Handle("/api/app-passwords/", r.createAuthenticatedHandler(r.appPasswords.HandleAppPassword))
+	}
+
+	// 两步验证路由（需要认证）
+	if r.twoFactor != nil {
+		mux.Handle("/api/2fa", r.createAuthenticatedHandler(r.twoFactor.Handle))
+		mux.Handle("/api/2fa/", r.createAuthenticatedHandler(r.twoFactor.Handle))
+	}
+
+	// 登录身份路由（需要认证），用户关联和取消关联钱包
+	if r.identities != nil {
+		mux.Handle("/api/identities", r.createAuthenticatedHandler(r.identities.Handle))
+		mux.Handle("/api/identities/", r.createAuthenticatedHandler(r.identities.Handle))
+	}
+
+	// 管理 API 路由（需要管理员认证）
+	if r.adminHandler != nil {
+		mux.Handle("/api/admin/users", r.createAdminHandler(r.adminHandler.HandleUsers))
+		mux.Handle("/api/admin/users/", r.createAdminHandler(r.adminHandler.HandleUser))
+	}
+	if r.lockHandler != nil {
+		mux.Handle("/api/admin/locks", r.createAdminHandler(r.lockHandler.HandleLocks))
+		mux.Handle("/api/admin/locks/", r.createAdminHandler(r.lockHandler.HandleLock))
 	}
 
 	// WebDAV 路由（需要认证）
@@ -77,10 +145,41 @@ func (r *Router) createWebDAVHandler() http.Handler {
 	// 2. 再应用认证中间件（外层）
 	//    - 验证用户身份
 	//    - OPTIONS 请求需要在这里放行
-	authMiddleware := middleware.NewAuthMiddleware(r.authenticators, true, r.logger)
-	handler = authMiddleware.Handle(handler)
+	return r.withAuth(handler)
+}
 
-	return handler
+// createAuthenticatedHandler 创建需要认证的 API 处理器
+func (r *Router) createAuthenticatedHandler(h http.HandlerFunc) http.Handler {
+	return r.withAuth(h)
+}
+
+// withAuth 应用认证中间件和客户端证书强制
+func (r *Router) withAuth(h http.Handler) http.Handler {
+	handler := h
+
+	// 1. 客户端证书强制（内层，需要已认证的用户）
+	if r.certMatcher != nil {
+		clientCerts := r.config.Server.ClientCerts
+		certMiddleware := middleware.NewClientCertMiddleware(
+			clientCerts.RequiredUsers, clientCerts.RequiredPaths, r.config.WebDAV.Prefix, r.certMatcher, r.logger)
+		handler = certMiddleware.Handle(handler)
+	}
+
+	// 2. 认证（外层）
+	authMiddleware := middleware.NewAuthMiddleware(r.authenticators, true, r.authLimits, r.logger)
+	return authMiddleware.Handle(handler)
+}
+
+// createAdminHandler 创建管理 API 处理器（带认证和管理员权限检查）
+func (r *Router) createAdminHandler(h http.HandlerFunc) http.Handler {
+	var handler http.Handler = h
+
+	// 1. 管理员权限检查（内层）
+	adminMiddleware := middleware.NewAdminMiddleware(r.logger)
+	handler = adminMiddleware.Handle(handler)
+
+	// 2. 认证（外层）
+	return r.withAuth(handler)
 }
 
 // applyMiddlewares 应用全局中间件
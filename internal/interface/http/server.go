@@ -2,8 +2,11 @@ package http
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"net/http"
+	"os"
 
 	"github.com/yeying-community/webdav/internal/infrastructure/config"
 	"go.uber.org/zap"
@@ -64,6 +67,12 @@ func (s *Server) start() error {
 
 // startTLS 启动 HTTPS 服务器
 func (s *Server) startTLS() error {
+	tlsConfig, err := s.tlsConfig()
+	if err != nil {
+		return err
+	}
+	s.httpServer.TLSConfig = tlsConfig
+
 	if err := s.httpServer.ListenAndServeTLS(
 		s.config.Server.CertFile,
 		s.config.Server.KeyFile,
@@ -73,6 +82,38 @@ func (s *Server) startTLS() error {
 	return nil
 }
 
+// tlsConfig 创建 TLS 配置，配置了客户端 CA 时验证客户端证书
+func (s *Server) tlsConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	clientCerts := s.config.Server.ClientCerts
+	if clientCerts.CAFile == "" {
+		return tlsConfig, nil
+	}
+
+	pem, err := os.ReadFile(clientCerts.CAFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read client ca file: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in client ca file")
+	}
+	tlsConfig.ClientCAs = pool
+
+	// optional 模式下没有证书的连接仍可使用其他认证方式
+	tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	if clientCerts.Mode == "require" {
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	s.logger.Info("client certificate verification enabled",
+		zap.String("ca_file", clientCerts.CAFile),
+		zap.String("mode", clientCerts.Mode))
+
+	return tlsConfig, nil
+}
+
 // Shutdown 优雅关闭服务器
 func (s *Server) Shutdown(ctx context.Context) error {
 	s.logger.Info("shutting down http server")